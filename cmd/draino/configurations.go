@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/planetlabs/draino/internal/candidate_runner"
+	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
+	"github.com/planetlabs/draino/internal/drain_runner"
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/analyser"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+)
+
+// ConfigurationDefinition describes an additional draino configuration running in the same process as the main one.
+// Each configuration has its own runners, the set of nodes selected by the configurations must be disjoint.
+type ConfigurationDefinition struct {
+	// Name of the configuration, it plays the same role as --config-name
+	Name string `json:"name"`
+	// NodeConditions same format as --node-conditions
+	NodeConditions []string `json:"nodeConditions"`
+	// NodeLabelsExpr same format as --node-label-expr
+	NodeLabelsExpr string `json:"nodeLabelsExpr"`
+	// DrainGroupLabels same format as --drain-group-labels
+	DrainGroupLabels string `json:"drainGroupLabels"`
+
+	suppliedConditions []kubernetes.SuppliedCondition
+}
+
+// configurationsFile is the content of the file given with --additional-configurations-file
+type configurationsFile struct {
+	Configurations []ConfigurationDefinition `json:"configurations"`
+}
+
+// loadConfigurationDefinitions reads and validates the additional configurations.
+// primaryConfigName is the name of the configuration defined by the flags, it cannot be reused.
+func loadConfigurationDefinitions(path, primaryConfigName string) ([]ConfigurationDefinition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configurations file: %v", err)
+	}
+	var file configurationsFile
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse configurations file: %v", err)
+	}
+
+	names := map[string]bool{primaryConfigName: true}
+	expressions := map[string]string{}
+	for i := range file.Configurations {
+		def := &file.Configurations[i]
+		if def.Name == "" {
+			return nil, fmt.Errorf("configuration #%d must have a name", i)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("configuration name %q is used more than once", def.Name)
+		}
+		names[def.Name] = true
+
+		// the configurations must select disjoint nodes, an empty expression selects all of them
+		if def.NodeLabelsExpr == "" {
+			return nil, fmt.Errorf("configuration %q: a node label expression is required", def.Name)
+		}
+		if other, ok := expressions[def.NodeLabelsExpr]; ok {
+			return nil, fmt.Errorf("configuration %q: same node label expression as configuration %q", def.Name, other)
+		}
+		expressions[def.NodeLabelsExpr] = def.Name
+
+		if len(def.NodeConditions) == 0 {
+			return nil, fmt.Errorf("configuration %q: no condition defined", def.Name)
+		}
+		sort.Strings(def.NodeConditions)
+		if def.suppliedConditions, err = kubernetes.ParseConditions(def.NodeConditions); err != nil {
+			return nil, fmt.Errorf("configuration %q: one of the conditions is not correctly formatted: %#v", def.Name, err)
+		}
+	}
+	return file.Configurations, nil
+}
+
+// groupConditionsFile is the content of the file given with --group-conditions-file
+type groupConditionsFile struct {
+	// Groups maps the drain group keys to their conditions, in the --node-conditions format
+	Groups map[string][]string `json:"groups"`
+}
+
+// loadGroupConditions reads the conditions of the drain groups and returns them with the global conditions
+func loadGroupConditions(path string, global []kubernetes.SuppliedCondition) ([]kubernetes.SuppliedCondition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read group conditions file: %v", err)
+	}
+	var file groupConditionsFile
+	if err := yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse group conditions file: %v", err)
+	}
+	return kubernetes.WithGroupConditions(global, file.Groups)
+}
+
+// configurationParameters are the settings that differ between the main configuration and the additional ones
+type configurationParameters struct {
+	globalConfig             kubernetes.GlobalConfig
+	nodeLabelsExpr           string
+	filtersDef               kubernetes.FiltersDefinitions
+	drainBufferConfigMapName string
+	drainGroupLabelKeys      []string
+	groupIndexName           string
+	logger                   logr.Logger
+}
+
+// configurationComponents are the components of a configuration used outside of its runners
+type configurationComponents struct {
+	filtersDef                  kubernetes.FiltersDefinitions
+	drainBuffer                 drainbuffer.DrainBuffer
+	keyGetter                   groups.GroupKeyGetter
+	stabilityPeriodChecker      analyser.StabilityPeriodChecker
+	filterFactory               *filters.FilterFactory
+	simulator                   drain.DrainSimulator
+	nodeSorters                 candidate_runner.NodeSorters
+	drainRunnerFactory          drain_runner.DrainRunnerFactoryInterface
+	drainCandidateRunnerFactory candidate_runner.CandidateRunnerFactoryInterface
+	groupRegistry               *groups.GroupRegistry
+}
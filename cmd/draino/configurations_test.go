@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigurationDefinitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{
+			name: "disjoint configurations",
+			content: `configurations:
+- name: pool-a
+  nodeConditions: [KernelDeadlock]
+  nodeLabelsExpr: metadata.labels.pool == 'a'
+- name: pool-b
+  nodeConditions: [KernelDeadlock]
+  nodeLabelsExpr: metadata.labels.pool == 'b'
+`,
+		},
+		{
+			name: "configuration selecting all the nodes",
+			content: `configurations:
+- name: pool-a
+  nodeConditions: [KernelDeadlock]
+`,
+			expectedErr: `configuration "pool-a": a node label expression is required`,
+		},
+		{
+			name: "configurations with the same expression",
+			content: `configurations:
+- name: pool-a
+  nodeConditions: [KernelDeadlock]
+  nodeLabelsExpr: metadata.labels.pool == 'a'
+- name: pool-a-bis
+  nodeConditions: [KernelDeadlock]
+  nodeLabelsExpr: metadata.labels.pool == 'a'
+`,
+			expectedErr: `configuration "pool-a-bis": same node label expression as configuration "pool-a"`,
+		},
+		{
+			name: "name of the main configuration",
+			content: `configurations:
+- name: main
+  nodeConditions: [KernelDeadlock]
+  nodeLabelsExpr: metadata.labels.pool == 'a'
+`,
+			expectedErr: `configuration name "main" is used more than once`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "configurations.yaml")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+			_, err := loadConfigurationDefinitions(path, "main")
+			if tt.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expectedErr)
+			}
+		})
+	}
+}
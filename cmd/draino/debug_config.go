@@ -0,0 +1,62 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/spf13/pflag"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+// debugConfigExcludedFlags are left out of /debug/config: the access to the clusters and the endpoints that may embed credentials
+var debugConfigExcludedFlags = map[string]bool{
+	"kubeconfig":                true,
+	"master":                    true,
+	"conditions-kubeconfig":     true,
+	"eviction-confirmation-url": true,
+	"drain-approval-endpoint":   true,
+}
+
+// EffectiveConfig is the view of the parsed options exposed on /debug/config.
+// Flags holds the value of every flag, formatted as on the command line, so that a new option is exposed without any change here.
+// The other fields are the values computed from the flags.
+type EffectiveConfig struct {
+	ConfigName               string                         `json:"configName"`
+	Conditions               []kubernetes.SuppliedCondition `json:"conditions"`
+	DrainBufferConfigMapName string                         `json:"drainBufferConfigMapName"`
+	MaxNotReadyNodesBlockers []string                       `json:"maxNotReadyNodesBlockers,omitempty"`
+	MaxPendingPodsBlockers   []string                       `json:"maxPendingPodsBlockers,omitempty"`
+	AdditionalConfigurations []string                       `json:"additionalConfigurations,omitempty"`
+	Flags                    map[string]string              `json:"flags"`
+}
+
+// effectiveConfig must be called after Validate so that the computed values are set
+func (o *Options) effectiveConfig(fs *pflag.FlagSet) EffectiveConfig {
+	sortedKeys := func(m map[string]kubernetes.ComputeBlockStateFunctionFactory) []string {
+		var keys []string
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	var additionalConfigurations []string
+	for _, c := range o.additionalConfigurations {
+		additionalConfigurations = append(additionalConfigurations, c.Name)
+	}
+	flags := map[string]string{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if !debugConfigExcludedFlags[f.Name] {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	return EffectiveConfig{
+		ConfigName:               o.configName,
+		Conditions:               o.suppliedConditions,
+		DrainBufferConfigMapName: o.drainBufferConfigMapName,
+		MaxNotReadyNodesBlockers: sortedKeys(o.maxNotReadyNodesFunctions),
+		MaxPendingPodsBlockers:   sortedKeys(o.maxPendingPodsFunctions),
+		AdditionalConfigurations: additionalConfigurations,
+		Flags:                    flags,
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/planetlabs/draino/internal/cli"
+)
+
+func TestDebugConfigHandler(t *testing.T) {
+	options, fs := optionsFromFlags()
+	assert.NoError(t, fs.Parse([]string{
+		"--config-name=test-config",
+		"--node-conditions=KernelDeadlock",
+		"--drain-buffer=15m",
+		"--max-notready-nodes=10%",
+		"--drain-surge-percentage=20",
+		"--kubeconfig=/etc/kubeconfig",
+	}))
+	assert.NoError(t, options.Validate())
+
+	handlers := &cli.CLIHandlers{}
+	handlers.SetEffectiveConfig(options.effectiveConfig(fs))
+	router := mux.NewRouter()
+	handlers.RegisterRoute(router)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/config", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var got map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	assert.Equal(t, "test-config", got["configName"])
+	assert.Equal(t, "draino-test-config-drain-buffer", got["drainBufferConfigMapName"])
+	assert.Equal(t, []interface{}{"10%"}, got["maxNotReadyNodesBlockers"])
+	conditions, ok := got["conditions"].([]interface{})
+	assert.True(t, ok)
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, "KernelDeadlock", conditions[0].(map[string]interface{})["id"])
+	flags, ok := got["flags"].(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "15m0s", flags["drain-buffer"])
+	assert.Equal(t, "20", flags["drain-surge-percentage"], "every flag is exposed")
+	_, hasKubeconfig := flags["kubeconfig"]
+	assert.False(t, hasKubeconfig)
+}
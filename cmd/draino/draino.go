@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 	"strings"
 	"time"
 
@@ -45,9 +46,11 @@ import (
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
 	"github.com/planetlabs/draino/internal/candidate_runner/sorters"
 	"github.com/planetlabs/draino/internal/cli"
+	drainbudget "github.com/planetlabs/draino/internal/drain_budget"
 	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	"github.com/planetlabs/draino/internal/drain_runner"
 	preprocessor "github.com/planetlabs/draino/internal/drain_runner/pre_processor"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
 	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/analyser"
@@ -56,10 +59,14 @@ import (
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 	drainoklog "github.com/planetlabs/draino/internal/kubernetes/klog"
 	"github.com/planetlabs/draino/internal/limit"
+	"github.com/planetlabs/draino/internal/node_utilization"
 	"github.com/planetlabs/draino/internal/observability"
 	"github.com/planetlabs/draino/internal/protector"
+	"github.com/planetlabs/draino/internal/remote_conditions"
 
+	v1 "k8s.io/api/core/v1"
 	client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/transport"
 	"k8s.io/utils/clock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -95,6 +102,7 @@ func main() {
 		if errOptions := options.Validate(); errOptions != nil {
 			return errOptions
 		}
+		cliHandlers.SetEffectiveConfig(options.effectiveConfig(optFlags))
 
 		zapConfig := zap.NewProductionConfig()
 		zapConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
@@ -120,6 +128,9 @@ func main() {
 			ConfigName:                         options.configName,
 			SuppliedConditions:                 options.suppliedConditions,
 			PVCManagementEnableIfNoEvictionUrl: options.pvcManagementByDefault,
+			DrainNow:                           kubernetes.DrainNowConfig{AnnotationKey: options.drainNowAnnotation, SpotTerminationAnnotationKey: options.spotTerminationAnnotation},
+			TeamLabelKey:                       options.teamLabelKey,
+			ChangeTicketAnnotationKey:          options.changeTicketAnnotation,
 		}
 
 		validationOptions := infraparameters.GetValidateAll()
@@ -138,7 +149,14 @@ func main() {
 			return fmt.Errorf("Failed to add metrics http runner to manager: %v", err)
 		}
 
-		cs, err2 := GetKubernetesClientSet(&cfg.KubeClientConfig)
+		// the error rate of the requests sent with the clientset blocks the drains while the API server is unhealthy, if configured
+		var apiServerErrorRate *kubernetes.APIServerErrorRate
+		var clientSetWrappers []transport.WrapperFunc
+		if options.apiServerHealthBlockThreshold > 0 {
+			apiServerErrorRate = kubernetes.NewAPIServerErrorRate(clock.RealClock{}, kubernetes.DefaultAPIServerErrorRateWindow)
+			clientSetWrappers = append(clientSetWrappers, apiServerErrorRate.WrapTransport)
+		}
+		cs, err2 := GetKubernetesClientSet(&cfg.KubeClientConfig, clientSetWrappers...)
 		if err2 != nil {
 			return err2
 		}
@@ -151,22 +169,27 @@ func main() {
 		pods := kubernetes.NewPodWatch(ctx, cs)
 		statefulSets := kubernetes.NewStatefulsetWatch(ctx, cs)
 		deployments := kubernetes.NewDeploymentWatch(ctx, cs)
+		replicaSets := kubernetes.NewReplicaSetWatch(ctx, cs)
 		persistentVolumes := kubernetes.NewPersistentVolumeWatch(ctx, cs)
 		persistentVolumeClaims := kubernetes.NewPersistentVolumeClaimWatch(ctx, cs)
-		nodes := kubernetes.NewNodeWatch(ctx, cs)
+		nodes := kubernetes.NewNodeWatch(ctx, cs, kubernetes.NewConditionHistoryHandler(options.allSuppliedConditions()))
 		store := &kubernetes.RuntimeObjectStoreImpl{
 			DeploymentStore:            deployments,
+			ReplicaSetStore:            replicaSets,
 			StatefulSetsStore:          statefulSets,
 			PodsStore:                  pods,
 			PersistentVolumeStore:      persistentVolumes,
 			PersistentVolumeClaimStore: persistentVolumeClaims,
 			NodesStore:                 nodes,
+			MaxOwnerChainDepth:         options.ownerChainMaxDepth,
 		}
 
 		filteringOptions := kubernetes.FilterOptions{
 			DoNotEvictPodControlledBy:              options.doNotEvictPodControlledBy,
 			EvictLocalStoragePods:                  options.evictLocalStoragePods,
+			EvictHostPathPods:                      options.evictHostPathPods,
 			ProtectedPodAnnotations:                options.protectedPodAnnotations,
+			ProtectedPodCELExpr:                    options.protectedPodCELExpr,
 			DoNotCandidatePodControlledBy:          options.doNotCandidatePodControlledBy,
 			CandidateLocalStoragePods:              options.candidateLocalStoragePods,
 			ExcludeStatefulSetOnNodeWithoutStorage: options.excludeStatefulSetOnNodeWithoutStorage,
@@ -175,55 +198,97 @@ func main() {
 			ShortLivedPodAnnotations:               options.shortLivedPodAnnotations,
 			NodeLabels:                             options.nodeLabels,
 			NodeLabelsExpr:                         options.nodeLabelsExpr,
+			NodeStatusExpr:                         options.nodeStatusSelector,
 			NodeAndPodsExpr:                        options.nodeAndPodsExpr,
-		}
-
-		filtersDef, err := kubernetes.GenerateFilters(cs, store, zlog, filteringOptions)
-		if err != nil {
-			return err
-		}
-
-		eventRecorderForDrainerActivities, _ := kubernetes.BuildEventRecorderWithAggregationOnEventTypeAndMessage(zapr.NewLogger(zlog), cs, options.eventAggregationPeriod, options.logEvents)
-		drainerAPI := kubernetes.NewAPIDrainer(cs,
-			eventRecorderForDrainerActivities,
-			kubernetes.MaxGracePeriod(options.minEvictionTimeout),
-			kubernetes.EvictionHeadroom(options.evictionHeadroom),
-			kubernetes.WithSkipDrain(options.skipDrain),
-			kubernetes.WithPodFilter(filtersDef.DrainPodFilter),
-			kubernetes.WithStorageClassesAllowingDeletion(options.storageClassesAllowingVolumeDeletion),
-			kubernetes.WithMaxDrainAttemptsBeforeFail(options.maxDrainAttemptsBeforeFail),
-			kubernetes.WithGlobalConfig(globalConfig),
-			kubernetes.WithAPIDrainerLogger(zlog),
-			kubernetes.WithRuntimeObjectStore(store),
-			kubernetes.WithContainerRuntimeClient(mgr.GetClient()),
-		)
+			HonorKarpenterDoNotDisrupt:             options.honorKarpenterDoNotDisrupt,
+			LeaderLeaseNamePattern:                 options.leaderLeaseNamePattern,
+			LeaderPodMaxWait:                       options.leaderPodMaxWait,
+			ProtectSystemNamespaces:                options.protectSystemNamespaces,
+			SystemNamespaces:                       options.systemNamespaces,
+			EnablePercentage:                       options.enablePercentage,
+			ProtectedNodeLabels:                    options.protectedNodeLabels,
+			UtilityPods:                            options.utilityPods,
+			UtilityPodSidecarContainers:            options.utilityPodSidecarContainers,
+		}
+
+		// The PVC deletions are throttled cluster-wide, the limiter is shared by the drainers of all the configurations
+		pvcDeletionLimiter := kubernetes.NewPVCDeletionLimiter(options.pvcDeletionQPS)
+		// The drains are bounded cluster-wide, the limiter is shared by the drain runners of all the groups and configurations
+		var globalDrainLimiter limit.ConcurrencyLimiter
+		if options.globalMaxConcurrentDrains > 0 {
+			globalDrainLimiter = limit.NewConcurrencyLimiter(options.globalMaxConcurrentDrains)
+		}
+		var evictedPodsSnapshotNamespace string
+		if options.snapshotEvictedPods {
+			evictedPodsSnapshotNamespace = cfg.InfraParam.Namespace
+		}
+		// The drain budgets are cluster-wide resources, consumed by the drain runners of all the groups and configurations
+		var drainBudget drainbudget.Consumer
+		if options.drainBudgets {
+			drainBudget = drainbudget.NewConsumer(mgr.GetClient(), clock.RealClock{})
+		}
+		eventRecorderForDrainerActivities, k8sEventRecorderForDrainerActivities := kubernetes.BuildEventRecorderWithAggregationOnEventTypeAndMessage(zapr.NewLogger(zlog), cs, options.eventAggregationPeriod, options.logEvents, options.changeTicketAnnotation)
 
 		indexer, err := index.New(ctx, mgr.GetClient(), mgr.GetCache(), logger)
 		if err != nil {
 			return fmt.Errorf("error while initializing informer: %v\n", err)
 		}
 
-		globalBlocker := kubernetes.NewGlobalBlocker(logger)
+		var globalBlockerOptions []kubernetes.GlobalBlockerOption
+		// the transitions of the global blockers are recorded on the draino pod, its name is the hostname
+		if hostname, err := os.Hostname(); err == nil {
+			drainoPod := &v1.ObjectReference{Kind: "Pod", Namespace: cfg.InfraParam.Namespace, Name: hostname}
+			globalBlockerOptions = append(globalBlockerOptions, kubernetes.WithTransitionEventRecorder(k8sEventRecorderForDrainerActivities, drainoPod))
+		}
+		globalBlocker := kubernetes.NewGlobalBlocker(logger, globalBlockerOptions...)
 		for p, f := range options.maxNotReadyNodesFunctions {
 			globalBlocker.AddBlocker("MaxNotReadyNodes:"+p, f(indexer, logger), options.maxNotReadyNodesPeriod)
 		}
 		for p, f := range options.maxPendingPodsFunctions {
 			globalBlocker.AddBlocker("MaxPendingPods:"+p, f(indexer, logger), options.maxPendingPodsPeriod)
 		}
+		if apiServerErrorRate != nil {
+			globalBlocker.AddBlocker(fmt.Sprintf("APIServerHealth:%v", options.apiServerHealthBlockThreshold), apiServerErrorRate.BlockStateFunc(options.apiServerHealthBlockThreshold), kubernetes.DefaultAPIServerErrorRatePeriod)
+		}
+
+		// The drain rate limiters are tapered as the fraction of NotReady nodes grows, if configured
+		newDrainRateLimiter := func(conditions []kubernetes.SuppliedCondition) limit.TypedRateLimiter {
+			return limit.NewTypedRateLimiter(&clock.RealClock{}, kubernetes.GetRateLimitConfiguration(conditions), options.drainRateLimitQPS, options.drainRateLimitBurst)
+		}
+		if options.drainRateTaperNotReadyPercent > 0 {
+			notReadyFraction := kubernetes.NewNotReadyNodesFraction(indexer, logger, options.maxNotReadyNodesPeriod)
+			if err := mgr.Add(notReadyFraction); err != nil {
+				logger.Error(err, "failed to setup NotReady nodes fraction with controller runtime")
+				return err
+			}
+			taper := limit.LinearTaper(notReadyFraction.Fraction, float64(options.drainRateTaperNotReadyPercent)/100)
+			newDrainRateLimiter = func(conditions []kubernetes.SuppliedCondition) limit.TypedRateLimiter {
+				return limit.NewTaperedTypedRateLimiter(&clock.RealClock{}, kubernetes.GetRateLimitConfiguration(conditions), options.drainRateLimitQPS, options.drainRateLimitBurst, taper)
+			}
+		}
 
-		eventRecorder, _ := kubernetes.BuildEventRecorderWithAggregationOnEventType(logger, cs, options.eventAggregationPeriod, options.excludedPodsPerNodeEstimation, options.logEvents)
-		eventRecorderForDrainRunnerActivities, _ := kubernetes.BuildEventRecorderWithAggregationOnEventTypeAndMessage(logger, cs, options.eventAggregationPeriod, options.logEvents)
+		// The drain buffer is relaxed while the cluster has spare capacity, if configured
+		var pendingPodsPerNode func() (float64, bool)
+		if options.softDrainBufferFactor < 1 {
+			pendingPods := kubernetes.NewPendingPodsPerNode(indexer, logger, options.maxPendingPodsPeriod)
+			if err := mgr.Add(pendingPods); err != nil {
+				logger.Error(err, "failed to setup pending pods per node with controller runtime")
+				return err
+			}
+			pendingPodsPerNode = pendingPods.Ratio
+		}
 
-		persistor := drainbuffer.NewConfigMapPersistor(cs.CoreV1().ConfigMaps(cfg.InfraParam.Namespace), options.drainBufferConfigMapName, cfg.InfraParam.Namespace)
-		drainBuffer := drainbuffer.NewDrainBuffer(ctx, persistor, clock.RealClock{}, mgr.GetLogger(), eventRecorder, indexer, store, options.drainBuffer)
-		// The drain buffer can only be initialized when the manager client cache was started.
-		// Adding a custom runnable to the controller manager will make sure, that the initialization will be started as soon as possible.
-		if err := mgr.Add(getInitDrainBufferRunner(drainBuffer, &logger)); err != nil {
-			logger.Error(err, "cannot setup drain buffer initialization runnable")
-			return err
+		eventRecorder, _ := kubernetes.BuildEventRecorderWithAggregationOnEventType(logger, cs, options.eventAggregationPeriod, options.excludedPodsPerNodeEstimation, options.logEvents, options.changeTicketAnnotation)
+		eventRecorderForDrainRunnerActivities, k8sEventRecorderForDrainRunnerActivities := kubernetes.BuildEventRecorderWithAggregationOnEventTypeAndMessage(logger, cs, options.eventAggregationPeriod, options.logEvents, options.changeTicketAnnotation)
+		if options.emitNodeGroupEvents {
+			eventRecorderForDrainRunnerActivities = kubernetes.NewNodeGroupEventRecorder(eventRecorderForDrainRunnerActivities, k8sEventRecorderForDrainRunnerActivities, options.changeTicketAnnotation)
 		}
 
-		keyGetter := groups.NewGroupKeyFromNodeMetadata(mgr.GetClient(), mgr.GetLogger(), eventRecorder, indexer, store, strings.Split(options.drainGroupLabelKey, ","), []string{groups.DrainGroupAnnotation}, groups.DrainGroupOverrideAnnotation)
+		var groupKeyOptions []groups.GroupKeyOption
+		if options.drainGroupFromCRD != "" {
+			gvr, _ := groups.ParseNodeGroupResource(options.drainGroupFromCRD) // validated with the options
+			groupKeyOptions = append(groupKeyOptions, groups.WithNodeGroupResolver(groups.NewNodeGroupFromOwnerCRD(mgr.GetClient(), mgr.GetLogger(), gvr)))
+		}
 
 		staticRetryStrategy := &drain.StaticRetryStrategy{AlertThreashold: 7, Delay: options.schedulingRetryBackoffDelay}
 		exponentialRetryStrategy := &drain.ExponentialRetryStrategy{AlertThreashold: 7, Delay: options.schedulingRetryBackoffDelay}
@@ -233,91 +298,273 @@ func main() {
 		}
 
 		pvcProtector := protector.NewPVCProtector(store, zlog, globalConfig.PVCManagementEnableIfNoEvictionUrl)
-		stabilityPeriodChecker := analyser.NewStabilityPeriodChecker(ctx, logger, mgr.GetClient(), nil, store, indexer, analyser.StabilityPeriodCheckerConfiguration{}, filtersDef.DrainPodFilter)
-		filterFactory, err := filters.NewFactory(
-			filters.WithLogger(mgr.GetLogger()),
-			filters.WithRetryWall(retryWall),
-			filters.WithRuntimeObjectStore(store),
-			filters.WithPodFilterFunc(filtersDef.CandidatePodFilter),
-			filters.WithNodeLabelsFilterFunction(filtersDef.NodeLabelFilter),
-			filters.WithGlobalConfig(globalConfig),
-			filters.WithStabilityPeriodChecker(stabilityPeriodChecker),
-			filters.WithDrainBuffer(drainBuffer),
-			filters.WithGroupKeyGetter(keyGetter),
-			filters.WithGlobalBlocker(globalBlocker),
-			filters.WithEventRecorder(eventRecorder),
-			filters.WithPVCProtector(pvcProtector),
-		)
-		if err != nil {
-			logger.Error(err, "failed to configure the filters")
-			return err
-		}
 
-		nodeReplacer := preprocessor.NewNodeReplacer(mgr.GetClient(), mgr.GetLogger(), &clock.RealClock{})
-		drainRunnerFactory, err := drain_runner.NewFactory(
-			drain_runner.WithKubeClient(mgr.GetClient()),
-			drain_runner.WithClock(&clock.RealClock{}),
-			drain_runner.WithDrainer(drainerAPI),
-			drain_runner.WithPreprocessors(
-				preprocessor.NewWaitTimePreprocessor(options.waitBeforeDraining),
-				preprocessor.NewNodeReplacementPreProcessor(mgr.GetClient(), options.preprovisioningActivatedByDefault, mgr.GetLogger(), &clock.RealClock{}),
-				preprocessor.NewPreActivitiesPreProcessor(mgr.GetClient(), indexer, store, mgr.GetLogger(), eventRecorderForDrainRunnerActivities, clock.RealClock{}, options.preActivityDefaultTimeout),
-			),
-			drain_runner.WithRerun(options.groupRunnerPeriod),
-			drain_runner.WithRetryWall(retryWall),
-			drain_runner.WithLogger(mgr.GetLogger()),
-			drain_runner.WithSharedIndexInformer(indexer),
-			drain_runner.WithEventRecorder(eventRecorderForDrainRunnerActivities),
-			drain_runner.WithFilter(filterFactory.BuildCandidateFilter()),
-			drain_runner.WithDrainBuffer(drainBuffer),
-			drain_runner.WithGlobalConfig(globalConfig),
-			drain_runner.WithBeforeReplacementDuration(options.durationBeforeReplacement),
-			drain_runner.WithNodeReplacer(nodeReplacer),
-			drain_runner.WithPVCProtector(pvcProtector),
-		)
-		if err != nil {
-			logger.Error(err, "failed to configure the drain_runner")
-			return err
+		var eventExporter eventexporter.EventExporter = &eventexporter.NoopEventExporter{}
+		if len(options.eventExportKafkaBrokers) > 0 {
+			kafkaWriter := eventexporter.NewKafkaWriter(options.eventExportKafkaBrokers, options.eventExportTopic)
+			defer kafkaWriter.Close()
+			// the runners must not wait for the broker, the events are published in the background
+			kafkaExporter := eventexporter.NewAsyncEventExporter(eventexporter.NewKafkaEventExporter(kafkaWriter), options.eventExportQueueSize, logger)
+			if err := mgr.Add(kafkaExporter); err != nil {
+				logger.Error(err, "failed to setup the event exporter with controller runtime")
+				return err
+			}
+			eventExporter = kafkaExporter
+		}
+		if options.auditLogPath != "" {
+			auditLog, errAudit := eventexporter.NewAuditLogEventExporter(options.auditLogPath, options.auditLogMaxSize)
+			if errAudit != nil {
+				logger.Error(errAudit, "failed to open the audit log")
+				return errAudit
+			}
+			eventExporter = eventexporter.NewMultiEventExporter(eventExporter, auditLog)
 		}
 
-		simulationPodFilter := kubernetes.NewPodFilters(filtersDef.DrainPodFilter)
+		nodeReplacer := preprocessor.NewNodeReplacer(mgr.GetClient(), mgr.GetLogger(), &clock.RealClock{})
 		simulationRateLimiter := limit.NewRateLimiter(clock.RealClock{}, cfg.KubeClientConfig.QPS*options.simulationRateLimitingRatio, int(float32(cfg.KubeClientConfig.Burst)*options.simulationRateLimitingRatio))
-		simulator := drain.NewDrainSimulator(context.Background(), mgr.GetClient(), indexer, simulationPodFilter, eventRecorder, simulationRateLimiter, logger, store, globalConfig)
 		pdbAnalyser := analyser.NewPDBAnalyser(ctx, mgr.GetLogger(), indexer, clock.RealClock{}, options.podWarmupDelayExtension)
-		sorters := candidate_runner.NodeSorters{
-			sorters.NewAnnotationPrioritizer(store, indexer, eventRecorder, logger),
-			sorters.NewConditionComparator(globalConfig.SuppliedConditions),
-			pdbAnalyser.CompareNode,
-		}
-
-		drainCandidateRunnerFactory, err := candidate_runner.NewFactory(
-			candidate_runner.WithKubeClient(mgr.GetClient()),
-			candidate_runner.WithClock(&clock.RealClock{}),
-			candidate_runner.WithRerun(options.groupRunnerPeriod),
-			candidate_runner.WithLogger(mgr.GetLogger()),
-			candidate_runner.WithSharedIndexInformer(indexer),
-			candidate_runner.WithEventRecorder(eventRecorder),
-			candidate_runner.WithMaxSimultaneousCandidates(1), // TODO should we move that to something that can be customized per user
-			candidate_runner.WithMaxSimultaneousDrained(5),    // TODO should we move that to something that can be customized per user
-			candidate_runner.WithFilter(filterFactory.BuildCandidateFilter()),
-			candidate_runner.WithDrainSimulator(simulator),
-			candidate_runner.WithNodeSorters(sorters),
-			candidate_runner.WithDryRun(options.dryRun),
-			candidate_runner.WithRetryWall(retryWall),
-			candidate_runner.WithRateLimiter(limit.NewTypedRateLimiter(&clock.RealClock{}, kubernetes.GetRateLimitConfiguration(globalConfig.SuppliedConditions), options.drainRateLimitQPS, options.drainRateLimitBurst)),
-			candidate_runner.WithGlobalConfig(globalConfig),
-			candidate_runner.WithCircuitBreaker(circuitBreakerBasedOnMonitors...),
-		)
-		if err != nil {
-			logger.Error(err, "failed to configure the candidate_runner")
-			return err
+
+		// setupConfiguration builds the runners of a configuration and registers its group registry. The main configuration and
+		// the additional ones share the stores, the blockers, the limiters and the circuit breakers.
+		setupConfiguration := func(params configurationParameters) (*configurationComponents, error) {
+			configLogger, configGlobalConfig, filtersDef := params.logger, params.globalConfig, params.filtersDef
+
+			drainerAPI := kubernetes.NewAPIDrainer(cs,
+				eventRecorderForDrainerActivities,
+				kubernetes.MaxGracePeriod(options.minEvictionTimeout),
+				kubernetes.EvictionHeadroom(options.evictionHeadroom),
+				kubernetes.WithMaxPodGracePeriod(options.maxPodGracePeriod),
+				kubernetes.WithSpotTerminationGracePeriod(options.spotTerminationGracePeriod),
+				kubernetes.WithEvictDryRunFirst(options.evictDryRunFirst),
+				kubernetes.WithWaitForReplacementPod(options.waitForReplacementPod),
+				kubernetes.WithEvictionConfirmation(options.evictionConfirmationKey, options.evictionConfirmationURL, options.evictionConfirmationWait),
+				kubernetes.WithEvictedPodsSnapshot(evictedPodsSnapshotNamespace),
+				kubernetes.WithStuckTerminatingAction(kubernetes.StuckTerminatingAction(options.stuckTerminatingAction), options.stuckTerminatingTimeout),
+				kubernetes.WithSkipDrain(options.skipDrain),
+				kubernetes.WithPodFilter(filtersDef.DrainPodFilter),
+				kubernetes.WithStorageClassesAllowingDeletion(options.storageClassesAllowingVolumeDeletion),
+				kubernetes.WithPVCDeletionLimiter(pvcDeletionLimiter),
+				kubernetes.WithMaxDrainAttemptsBeforeFail(options.maxDrainAttemptsBeforeFail),
+				kubernetes.WithGlobalConfig(configGlobalConfig),
+				kubernetes.WithAPIDrainerLogger(zlog),
+				kubernetes.WithRuntimeObjectStore(store),
+				kubernetes.WithContainerRuntimeClient(mgr.GetClient()),
+			)
+
+			persistor := drainbuffer.NewConfigMapPersistor(cs.CoreV1().ConfigMaps(cfg.InfraParam.Namespace), params.drainBufferConfigMapName, cfg.InfraParam.Namespace)
+			drainBuffer := drainbuffer.NewDrainBuffer(ctx, persistor, clock.RealClock{}, configLogger, eventRecorder, indexer, store, options.drainBuffer)
+			// The drain buffer can only be initialized when the manager client cache was started.
+			// Adding a custom runnable to the controller manager will make sure, that the initialization will be started as soon as possible.
+			if err := mgr.Add(getInitDrainBufferRunner(drainBuffer, &configLogger)); err != nil {
+				return nil, fmt.Errorf("cannot setup drain buffer initialization runnable: %v", err)
+			}
+
+			keyGetter := groups.NewGroupKeyFromNodeMetadata(mgr.GetClient(), configLogger, eventRecorder, indexer, store, params.drainGroupLabelKeys, []string{groups.DrainGroupAnnotation}, groups.DrainGroupOverrideAnnotation, groupKeyOptions...)
+
+			stabilityPeriodChecker := analyser.NewStabilityPeriodChecker(ctx, configLogger, mgr.GetClient(), nil, store, indexer, analyser.StabilityPeriodCheckerConfiguration{}, filtersDef.DrainPodFilter)
+			filterOptions := []filters.WithOption{
+				filters.WithLogger(configLogger),
+				filters.WithRetryWall(retryWall),
+				filters.WithRuntimeObjectStore(store),
+				filters.WithPodFilterFunc(filtersDef.CandidatePodFilter),
+				filters.WithStatefulSetWithoutStoragePodFilter(filtersDef.StatefulSetWithoutStoragePodFilter),
+				filters.WithNodeLabelsFilterFunction(filtersDef.NodeLabelFilter),
+				filters.WithGlobalConfig(configGlobalConfig),
+				filters.WithStabilityPeriodChecker(stabilityPeriodChecker),
+				filters.WithDrainBuffer(drainBuffer),
+				filters.WithGroupKeyGetter(keyGetter),
+				filters.WithGlobalBlocker(globalBlocker),
+				filters.WithEventRecorder(eventRecorder),
+				filters.WithPVCProtector(pvcProtector),
+				filters.WithIgnoreManuallyCordoned(options.ignoreManuallyCordoned),
+				filters.WithRespectTopologySpread(options.respectTopologySpread, indexer),
+				filters.WithMinHealthyNodesPerGroup(options.minHealthyNodesPerGroup),
+				filters.WithRecordonCooldown(options.recordonCooldown, options.recordonConditionPersistence),
+				filters.WithSoftDrainBuffer(pendingPodsPerNode, options.softDrainBufferMaxPending, options.softDrainBufferFactor),
+				filters.WithoutDrainBuffer(options.drainSurgePercentage > 0),
+				filters.WithMinInScopeAge(options.candidateMinInScopeAge, observability.ConfigurationLabelKey, observability.ScopeEntryTimeAnnotationKeyPrefix),
+				filters.WithMaxPods(options.maxPodsForDrain, indexer),
+			}
+			filterFactory, err := filters.NewFactory(filterOptions...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure the filters: %v", err)
+			}
+			shadowFilter, err := buildShadowFilter(options, filterOptions, configGlobalConfig, zlog)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure the shadow filters: %v", err)
+			}
+
+			preprocessors := []preprocessor.DrainPreProcessor{
+				preprocessor.NewWaitTimePreprocessor(options.waitBeforeDraining, configGlobalConfig.DrainNow),
+				preprocessor.NewNodeReplacementPreProcessor(mgr.GetClient(), options.preprovisioningActivatedByDefault, configLogger, &clock.RealClock{}),
+				preprocessor.NewPreActivitiesPreProcessor(mgr.GetClient(), indexer, store, configLogger, eventRecorderForDrainRunnerActivities, clock.RealClock{}, options.preActivityDefaultTimeout),
+			}
+			if options.drainApprovalEndpoint != "" {
+				preprocessors = append(preprocessors, preprocessor.NewDrainApprovalPreProcessor(options.drainApprovalEndpoint, indexer, keyGetter, configGlobalConfig.SuppliedConditions, configLogger, eventRecorderForDrainRunnerActivities, clock.RealClock{}))
+			}
+			drainRunnerFactory, err := drain_runner.NewFactory(
+				drain_runner.WithKubeClient(mgr.GetClient()),
+				drain_runner.WithClock(&clock.RealClock{}),
+				drain_runner.WithDrainer(drainerAPI),
+				drain_runner.WithPreprocessors(preprocessors...),
+				drain_runner.WithRerun(options.drainRunnerPeriod),
+				drain_runner.WithPeriodJitterFactor(options.periodJitterFactor),
+				drain_runner.WithDrainFailureConfirmDelay(options.drainFailureConfirmDelay),
+				drain_runner.WithDrainDeadlineWarningRatio(options.drainDeadlineWarningRatio),
+				drain_runner.WithAbortDrainOnConditionClear(options.abortDrainOnConditionClear),
+				drain_runner.WithQuarantineOnMaxFailures(options.quarantineOnMaxFailures),
+				drain_runner.WithMaxSchedulingFailures(options.maxSchedulingFailures),
+				drain_runner.WithMaxTransientDrainRetries(options.maxTransientRetries),
+				drain_runner.WithDrainSurgePercentage(options.drainSurgePercentage),
+				drain_runner.WithEmitDrainSummary(options.emitDrainSummary),
+				drain_runner.WithWaitReplacementReady(options.waitReplacementReady),
+				drain_runner.WithGlobalDrainLimiter(globalDrainLimiter),
+				drain_runner.WithDrainBudget(drainBudget),
+				drain_runner.WithRetryWall(retryWall),
+				drain_runner.WithLogger(configLogger),
+				drain_runner.WithSharedIndexInformer(indexer),
+				drain_runner.WithRuntimeObjectStore(store),
+				drain_runner.WithEventRecorder(eventRecorderForDrainRunnerActivities),
+				drain_runner.WithFilter(filterFactory.BuildCandidateFilter()),
+				drain_runner.WithDrainBuffer(drainBuffer),
+				drain_runner.WithSoftDrainBuffer(pendingPodsPerNode, options.softDrainBufferMaxPending, options.softDrainBufferFactor),
+				drain_runner.WithGlobalConfig(configGlobalConfig),
+				drain_runner.WithBeforeReplacementDuration(options.durationBeforeReplacement),
+				drain_runner.WithNodeReplacer(nodeReplacer),
+				drain_runner.WithPVCProtector(pvcProtector),
+				drain_runner.WithEventExporter(eventExporter),
+				drain_runner.WithGroupIndexName(params.groupIndexName),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure the drain_runner: %v", err)
+			}
+
+			simulator := drain.NewDrainSimulator(context.Background(), mgr.GetClient(), indexer, kubernetes.NewPodFilters(filtersDef.DrainPodFilter), eventRecorder, simulationRateLimiter, configLogger, store, configGlobalConfig)
+			nodeSorters := candidate_runner.NodeSorters{
+				sorters.NewAnnotationPrioritizer(store, indexer, eventRecorder, configLogger),
+				sorters.NewConditionComparator(configGlobalConfig.SuppliedConditions),
+			}
+			switch options.candidateSortBy {
+			case candidateSortByNewest:
+				nodeSorters = append(nodeSorters, sorters.SortByCreationTimestampDesc)
+			case candidateSortByZoneBalance:
+				nodeSorters = append(nodeSorters, sorters.NewZoneBalanceComparator(nodes, clock.RealClock{}))
+			case candidateSortByPreferred:
+				nodeSorters = append(nodeSorters, sorters.NewDrainPreferredComparator(options.drainPreferredLabelKey))
+			}
+			nodeSorters = append(nodeSorters, pdbAnalyser.CompareNode)
+
+			drainCandidateRunnerFactory, err := candidate_runner.NewFactory(
+				candidate_runner.WithKubeClient(mgr.GetClient()),
+				candidate_runner.WithClock(&clock.RealClock{}),
+				candidate_runner.WithRerun(options.candidateRunnerPeriod),
+				candidate_runner.WithPeriodJitterFactor(options.periodJitterFactor),
+				candidate_runner.WithLogger(configLogger),
+				candidate_runner.WithSharedIndexInformer(indexer),
+				candidate_runner.WithEventRecorder(eventRecorder),
+				candidate_runner.WithMaxSimultaneousCandidates(1), // TODO should we move that to something that can be customized per user
+				candidate_runner.WithMaxSimultaneousDrained(5),    // TODO should we move that to something that can be customized per user
+				candidate_runner.WithFilter(filterFactory.BuildCandidateFilter()),
+				candidate_runner.WithDrainSimulator(simulator),
+				candidate_runner.WithNodeSorters(nodeSorters),
+				candidate_runner.WithDryRun(options.dryRun),
+				candidate_runner.WithRetryWall(retryWall),
+				candidate_runner.WithRateLimiter(newDrainRateLimiter(configGlobalConfig.SuppliedConditions)),
+				candidate_runner.WithGlobalConfig(configGlobalConfig),
+				candidate_runner.WithCircuitBreaker(circuitBreakerBasedOnMonitors...),
+				candidate_runner.WithEventExporter(eventExporter),
+				candidate_runner.WithPassTimeout(options.candidatePassTimeout),
+				candidate_runner.WithShadowFilter(shadowFilter),
+				candidate_runner.WithGroupIndexName(params.groupIndexName),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure the candidate_runner: %v", err)
+			}
+
+			groupRegistry := groups.NewGroupRegistry(ctx, mgr.GetClient(), configLogger, eventRecorder, keyGetter, drainRunnerFactory, drainCandidateRunnerFactory, filtersDef.NodeLabelFilter, store.HasSynced, options.groupRunnerPeriod).
+				WithIndexName(params.groupIndexName)
+			if len(options.groupPriorities) > 0 {
+				groupRegistry.WithScheduler(groups.NewGroupPriorityScheduler(store.Nodes(), keyGetter, options.groupPriorities))
+			}
+			if err = groupRegistry.SetupWithManager(mgr); err != nil {
+				return nil, fmt.Errorf("failed to setup groupRegistry: %v", err)
+			}
+
+			return &configurationComponents{
+				filtersDef:                  filtersDef,
+				drainBuffer:                 drainBuffer,
+				keyGetter:                   keyGetter,
+				stabilityPeriodChecker:      stabilityPeriodChecker,
+				filterFactory:               filterFactory,
+				simulator:                   simulator,
+				nodeSorters:                 nodeSorters,
+				drainRunnerFactory:          drainRunnerFactory,
+				drainCandidateRunnerFactory: drainCandidateRunnerFactory,
+				groupRegistry:               groupRegistry,
+			}, nil
+		}
+
+		// The additional configurations have their own runners, the filters are generated first so that a node selected by
+		// several configurations is excluded from all of them.
+		configurations := []configurationParameters{{
+			globalConfig:             globalConfig,
+			drainBufferConfigMapName: options.drainBufferConfigMapName,
+			drainGroupLabelKeys:      strings.Split(options.drainGroupLabelKey, ","),
+			groupIndexName:           groups.SchedulingGroupIdx,
+			logger:                   mgr.GetLogger(),
+		}}
+		for _, def := range options.additionalConfigurations {
+			configGlobalConfig := globalConfig
+			configGlobalConfig.ConfigName = def.Name
+			configGlobalConfig.SuppliedConditions = def.suppliedConditions
+			configurations = append(configurations, configurationParameters{
+				globalConfig:             configGlobalConfig,
+				nodeLabelsExpr:           def.NodeLabelsExpr,
+				drainBufferConfigMapName: fmt.Sprintf("draino-%s-drain-buffer", def.Name),
+				drainGroupLabelKeys:      strings.Split(def.DrainGroupLabels, ","),
+				groupIndexName:           groups.ConfigurationSchedulingGroupIdx(def.Name),
+				logger:                   mgr.GetLogger().WithValues("config", def.Name),
+			})
+		}
+		nodeLabelFilters := map[string]kubernetes.NodeLabelFilterFunc{}
+		for i := range configurations {
+			configFilteringOptions := filteringOptions
+			if i > 0 {
+				configFilteringOptions.NodeLabels = nil
+				configFilteringOptions.NodeLabelsExpr = configurations[i].nodeLabelsExpr
+			}
+			if configurations[i].filtersDef, err = kubernetes.GenerateFilters(cs, store, zlog, configFilteringOptions); err != nil {
+				return err
+			}
+			nodeLabelFilters[configurations[i].globalConfig.ConfigName] = configurations[i].filtersDef.NodeLabelFilter
+		}
+		if len(configurations) > 1 {
+			disjointFilters := kubernetes.NewDisjointNodeLabelFilters(nodeLabelFilters, zlog)
+			for i := range configurations {
+				configurations[i].filtersDef.NodeLabelFilter = disjointFilters[configurations[i].globalConfig.ConfigName]
+			}
 		}
 
-		groupRegistry := groups.NewGroupRegistry(ctx, mgr.GetClient(), mgr.GetLogger(), eventRecorder, keyGetter, drainRunnerFactory, drainCandidateRunnerFactory, filtersDef.NodeLabelFilter, store.HasSynced, options.groupRunnerPeriod)
-		if err = groupRegistry.SetupWithManager(mgr); err != nil {
-			logger.Error(err, "failed to setup groupRegistry")
-			return err
+		var mainConfiguration *configurationComponents
+		for i, params := range configurations {
+			components, err := setupConfiguration(params)
+			if err != nil {
+				logger.Error(err, "failed to setup configuration", "config", params.globalConfig.ConfigName)
+				return err
+			}
+			if i == 0 {
+				mainConfiguration = components
+				continue
+			}
+			cliHandlers.AddConfiguration(cli.ConfigurationInfo{
+				Name:          params.globalConfig.ConfigName,
+				KeysGetter:    components.groupRegistry,
+				CandidateInfo: components.drainCandidateRunnerFactory.BuildCandidateInfo(),
+				DrainBuffer:   components.drainBuffer,
+			})
 		}
+		filtersDef, drainBuffer, keyGetter, groupRegistry := mainConfiguration.filtersDef, mainConfiguration.drainBuffer, mainConfiguration.keyGetter, mainConfiguration.groupRegistry
+
 		groupFromPod := groups.NewGroupFromPod(mgr.GetClient(), mgr.GetLogger(), keyGetter, filtersDef.DrainPodFilter, store.HasSynced)
 		if err = groupFromPod.SetupWithManager(mgr); err != nil {
 			logger.Error(err, "failed to setup groupFromPod")
@@ -328,14 +575,14 @@ func main() {
 			diagnostics.WithKubeClient(mgr.GetClient()),
 			diagnostics.WithClock(&clock.RealClock{}),
 			diagnostics.WithLogger(mgr.GetLogger()),
-			diagnostics.WithFilter(filterFactory.BuildCandidateFilter()),
-			diagnostics.WithDrainSimulator(simulator),
-			diagnostics.WithNodeSorters(sorters),
+			diagnostics.WithFilter(mainConfiguration.filterFactory.BuildCandidateFilter()),
+			diagnostics.WithDrainSimulator(mainConfiguration.simulator),
+			diagnostics.WithNodeSorters(mainConfiguration.nodeSorters),
 			diagnostics.WithRetryWall(retryWall),
 			diagnostics.WithDrainBuffer(drainBuffer),
 			diagnostics.WithGlobalConfig(globalConfig),
 			diagnostics.WithKeyGetter(keyGetter),
-			diagnostics.WithStabilityPeriodChecker(stabilityPeriodChecker),
+			diagnostics.WithStabilityPeriodChecker(mainConfiguration.stabilityPeriodChecker),
 			diagnostics.WithCircuitBreakers(circuitBreakerBasedOnMonitors...),
 		)
 		if err != nil {
@@ -350,15 +597,24 @@ func main() {
 			return err
 		}
 
-		if errCli := cliHandlers.Initialize(logger, groupRegistry, drainCandidateRunnerFactory.BuildCandidateInfo(), drainRunnerFactory.BuildRunner(), nodeDiagnostician); errCli != nil {
+		cliHandlers.SetExplainer(diagnosticFactory.BuildExplainer())
+		cliHandlers.SetDrainBuffer(drainBuffer)
+		cliHandlers.SetDrainReport(mainConfiguration.simulator, filtersDef.NodeLabelFilter)
+		if errCli := cliHandlers.Initialize(logger, groupRegistry, mainConfiguration.drainCandidateRunnerFactory.BuildCandidateInfo(), mainConfiguration.drainRunnerFactory.BuildDrainInfo(), nodeDiagnostician); errCli != nil {
 			logger.Error(errCli, "Failed to initialize CLIHandlers")
 			return errCli
 		}
 
-		scopeObserver := observability.NewScopeObserver(cs, globalConfig, indexer, store, options.scopeAnalysisPeriod, filtersDef,
+		var scopeSnapshotWriter observability.ScopeSnapshotWriter
+		if options.scopeSnapshotConfigMapName != "" {
+			scopeSnapshotWriter = observability.NewConfigMapScopeSnapshotWriter(cs.CoreV1().ConfigMaps(cfg.InfraParam.Namespace), options.scopeSnapshotConfigMapName)
+		}
+		scopeObserver := observability.NewScopeObserver(cs, globalConfig, indexer, store, options.scopeAnalysisPeriod, options.periodJitterFactor, filtersDef,
 			kubernetes.PodOrControllerHasAnyOfTheAnnotations(store, options.optInPodAnnotations...),
 			kubernetes.PodOrControllerHasAnyOfTheAnnotations(store, options.candidateProtectedPodAnnotations...),
-			zlog, retryWall, keyGetter, groupRegistry, filterFactory.BuildCandidateFilter())
+			zlog, retryWall, keyGetter, groupRegistry, mainConfiguration.filterFactory.BuildCandidateFilter(), scopeSnapshotWriter, options.scopeObserverDryRun, options.scopeObserverServerSideApply,
+			observability.NodeUpdateQueueConfig{MaxRequeues: options.scopeObserverMaxRequeues, MaxBackoff: options.scopeObserverMaxBackoff, RequeuePolicy: options.scopeObserverRequeuePolicy}, options.informerSyncTimeout)
+		cliHandlers.SetScopeAnalysisTrigger(scopeObserver)
 
 		if options.resetScopeLabel == true {
 			err = mgr.Add(&RunOnce{fn: func(context.Context) error { scopeObserver.Reset(); return nil }})
@@ -369,7 +625,8 @@ func main() {
 		}
 
 		mgr.Add(&RunOnce{fn: func(ctx context.Context) error {
-			return kubernetes.Await(ctx, nodes, pods, statefulSets, deployments, persistentVolumes, persistentVolumeClaims)
+			// the manager exits with an error if the stores are not synced before the timeout
+			return kubernetes.AwaitWithSyncTimeout(ctx, store.HasSynced, options.informerSyncTimeout, nodes, pods, statefulSets, deployments, replicaSets, persistentVolumes, persistentVolumeClaims)
 		}})
 		for _, cb := range circuitBreakerBasedOnMonitors {
 			if err := mgr.Add(cb); err != nil {
@@ -395,6 +652,53 @@ func main() {
 			return err
 		}
 
+		if thresholds := options.nodeUtilizationThresholds(); thresholds.IsEnabled() {
+			metricsClient, err := node_utilization.NewMetricsServerClient(mgr.GetConfig())
+			if err != nil {
+				logger.Error(err, "failed to create metrics-server client")
+				return err
+			}
+			if err := mgr.Add(node_utilization.NewConditionUpdater(mgr.GetClient(), metricsClient, logger, clock.RealClock{}, node_utilization.DefaultCheckPeriod, thresholds)); err != nil {
+				logger.Error(err, "failed to setup node utilization condition updater with controller runtime")
+				return err
+			}
+		}
+
+		if options.cleanupReleasedPVs && !options.dryRun {
+			if err := mgr.Add(kubernetes.NewReleasedPVCleaner(mgr.GetClient(), logger, eventRecorder, kubernetes.DefaultReleasedPVCleanupPeriod, options.storageClassesAllowingVolumeDeletion)); err != nil {
+				logger.Error(err, "failed to setup released PV cleaner with controller runtime")
+				return err
+			}
+		}
+
+		if options.orphanPDBCheckPeriod > 0 {
+			if err := mgr.Add(analyser.NewOrphanPDBChecker(indexer, logger, options.orphanPDBCheckPeriod)); err != nil {
+				logger.Error(err, "failed to setup orphan PDB checker with controller runtime")
+				return err
+			}
+		}
+
+		if options.conditionsKubecfg != "" {
+			conditionsConfig, err := kubernetes.BuildConfigFromFlags("", options.conditionsKubecfg)
+			if err != nil {
+				logger.Error(err, "failed to load the conditions kubeconfig")
+				return err
+			}
+			conditionsClient, err := client.NewForConfig(conditionsConfig)
+			if err != nil {
+				logger.Error(err, "failed to create the conditions client")
+				return err
+			}
+			mirroredConditions := append([]kubernetes.SuppliedCondition{}, options.suppliedConditions...)
+			for _, def := range options.additionalConfigurations {
+				mirroredConditions = append(mirroredConditions, def.suppliedConditions...)
+			}
+			if err := mgr.Add(remote_conditions.NewConditionsMirror(conditionsClient, mgr.GetClient(), logger, remote_conditions.DefaultSyncPeriod, mirroredConditions)); err != nil {
+				logger.Error(err, "failed to setup conditions mirror with controller runtime")
+				return err
+			}
+		}
+
 		metrics.DrainoRunning(kubernetes.Component, options.dryRun)
 
 		logger.Info("Starting manager")
@@ -450,6 +754,36 @@ func setupCircuitBreakers(ctx context.Context, mgr manager.Manager, options *Opt
 	return circuitBreakerBasedOnMonitors, nil
 }
 
+// buildShadowFilter returns the candidate filter built with the shadow node label expression and conditions in place of the active ones.
+// It returns nil if the shadow mode is not configured.
+func buildShadowFilter(options *Options, filterOptions []filters.WithOption, globalConfig kubernetes.GlobalConfig, log *zap.Logger) (filters.Filter, error) {
+	if options.shadowNodeLabelsExpr == "" && len(options.shadowSuppliedConditions) == 0 {
+		return nil, nil
+	}
+	// the shadow evaluation must not be visible on the nodes
+	shadowOptions := append(append([]filters.WithOption{}, filterOptions...), filters.WithEventRecorder(kubernetes.NoopEventRecorder{}))
+	if options.shadowNodeLabelsExpr != "" {
+		nodeLabelFilter, err := kubernetes.NewNodeLabelFilter(options.shadowNodeLabelsExpr, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shadow node label expression: %v", err)
+		}
+		if len(options.protectedNodeLabels) > 0 {
+			nodeLabelFilter = kubernetes.NewProtectedNodeLabelsFilter(nodeLabelFilter, options.protectedNodeLabels)
+		}
+		shadowOptions = append(shadowOptions, filters.WithNodeLabelsFilterFunction(nodeLabelFilter))
+	}
+	if len(options.shadowSuppliedConditions) > 0 {
+		shadowGlobalConfig := globalConfig
+		shadowGlobalConfig.SuppliedConditions = options.shadowSuppliedConditions
+		shadowOptions = append(shadowOptions, filters.WithGlobalConfig(shadowGlobalConfig))
+	}
+	factory, err := filters.NewFactory(shadowOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return factory.BuildCandidateFilter(), nil
+}
+
 // launchTracerAndProfiler will initialize and run the tracer and the endpoint for the profiler
 // the function is blocking launch it in a dedicated go-routine
 func launchTracerAndProfiler() {
@@ -461,7 +795,7 @@ func launchTracerAndProfiler() {
 	http.ListenAndServe("localhost:8085", mux) // for go profiler
 }
 
-func GetKubernetesClientSet(config *kubeclient.Config) (*client.Clientset, error) {
+func GetKubernetesClientSet(config *kubeclient.Config, wrappers ...transport.WrapperFunc) (*client.Clientset, error) {
 	if err := k8sclient.DecorateWithRateLimiter(config, "default"); err != nil {
 		return nil, err
 	}
@@ -469,6 +803,9 @@ func GetKubernetesClientSet(config *kubeclient.Config) (*client.Clientset, error
 	if err != nil {
 		return nil, fmt.Errorf("failed create Kubernetes client configuration: %v", err)
 	}
+	for _, wrapper := range wrappers {
+		c.Wrap(wrapper)
+	}
 
 	cs, err := client.NewForConfig(c)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"github.com/go-logr/logr"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/planetlabs/draino/internal/candidate_runner"
 	circuitbreaker "github.com/planetlabs/draino/internal/circuit_breaker"
 	"github.com/planetlabs/draino/internal/drain_runner"
 	"github.com/planetlabs/draino/internal/kubernetes/drain"
@@ -53,13 +54,41 @@ func DrainoLegacyMetrics(ctx context.Context, options *Options, logger logr.Logg
 			Aggregation: view.Count(),
 			TagKeys:     []tag.Key{kubernetes.TagResult, kubernetes.TagReason, kubernetes.TagNodegroupName, kubernetes.TagNodegroupNamePrefix, kubernetes.TagNodegroupNamespace, kubernetes.TagTeam},
 		}
+		evictionAPIVersionUsed = &view.View{
+			Name:        "eviction_api_version_used_total",
+			Measure:     kubernetes.MeasureEvictionAPIVersionUsed,
+			Description: "Number of pods evicted per version of the eviction API.",
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{kubernetes.TagEvictionAPIVersion},
+		}
+		podsEvicted = &view.View{
+			Name:        "pods_evicted_total",
+			Measure:     kubernetes.MeasurePodsEvicted,
+			Description: "Number of pods evicted.",
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{kubernetes.TagNamespace, kubernetes.TagCustomEvictor},
+		}
+		podTerminationLatency = &view.View{
+			Name:        "pod_termination_latency_seconds",
+			Measure:     kubernetes.MeasurePodTerminationLatency,
+			Description: "Time between the eviction of a pod and its deletion.",
+			Aggregation: view.Distribution(1, 5, 10, 30, 60, 120, 300, 600, 1800),
+			TagKeys:     []tag.Key{kubernetes.TagNamespace},
+		}
+		conditionAgeAtCordon = &view.View{
+			Name:        "condition_age_at_cordon_seconds",
+			Measure:     kubernetes.MeasureConditionAgeAtCordon,
+			Description: "Time since the last transition of the offending conditions when the node becomes drain candidate.",
+			Aggregation: view.Distribution(60, 300, 600, 1800, 3600, 7200, 14400, 43200, 86400, 259200, 604800),
+			TagKeys:     []tag.Key{kubernetes.TagConditionType},
+		}
 	)
 
 	if options.noLegacyNodeHandler {
 		// removing: nodesDrained
-		kingpin.FatalIfError(view.Register(nodesDrainScheduled, nodesReplacement, nodesPreprovisioningLatency), "cannot create metrics")
+		kingpin.FatalIfError(view.Register(nodesDrainScheduled, nodesReplacement, nodesPreprovisioningLatency, evictionAPIVersionUsed, podsEvicted, podTerminationLatency, conditionAgeAtCordon), "cannot create metrics")
 	} else {
-		kingpin.FatalIfError(view.Register(nodesDrained, nodesDrainScheduled, nodesReplacement, nodesPreprovisioningLatency), "cannot create metrics")
+		kingpin.FatalIfError(view.Register(nodesDrained, nodesDrainScheduled, nodesReplacement, nodesPreprovisioningLatency, evictionAPIVersionUsed, podsEvicted, podTerminationLatency, conditionAgeAtCordon), "cannot create metrics")
 	}
 
 	promOptions := prometheus.Options{Namespace: kubernetes.Component, Registry: prom.NewRegistry()}
@@ -87,5 +116,6 @@ func DrainoLegacyMetrics(ctx context.Context, options *Options, logger logr.Logg
 
 func DrainoMetrics(promExporter prom.Registerer) {
 	drain_runner.RegisterMetrics(promExporter)
+	candidate_runner.RegisterMetrics(promExporter)
 	circuitbreaker.RegisterMetrics(promExporter)
 }
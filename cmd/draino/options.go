@@ -2,16 +2,24 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
 
+	"github.com/planetlabs/draino/internal/candidate_runner/sorters"
 	circuitbreaker "github.com/planetlabs/draino/internal/circuit_breaker"
+	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/index"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+	"github.com/planetlabs/draino/internal/node_utilization"
+	"github.com/planetlabs/draino/internal/observability"
 )
 
 const (
@@ -20,6 +28,15 @@ const (
 	DefaultSchedulingRetryBackoffDelay = 23 * time.Minute
 )
 
+const (
+	// candidateSortByNewest is the value of --candidate-sort-by draining the most recently created nodes first
+	candidateSortByNewest = "newest"
+	// candidateSortByZoneBalance is the value of --candidate-sort-by draining first the nodes of the zones having the most nodes
+	candidateSortByZoneBalance = "zone-balance"
+	// candidateSortByPreferred is the value of --candidate-sort-by draining first the nodes labeled with --drain-preferred-label-key=true
+	candidateSortByPreferred = "preferred"
+)
+
 // Options collects the program options/parameters
 type Options struct {
 	noLegacyNodeHandler         bool
@@ -27,28 +44,65 @@ type Options struct {
 	listen                      string
 	kubecfg                     string
 	apiserver                   string
+	conditionsKubecfg           string
 	dryRun                      bool
 	minEvictionTimeout          time.Duration
+	minDurationFromObservation  bool
 	evictionHeadroom            time.Duration
+	maxPodGracePeriod           time.Duration
+	spotTerminationAnnotation   string
+	drainNowAnnotation          string
+	spotTerminationGracePeriod  time.Duration
+	teamLabelKey                string
+	changeTicketAnnotation      string
 	drainBuffer                 time.Duration
 	drainBufferConfigMapName    string
+	softDrainBufferFactor       float64
+	softDrainBufferMaxPending   float64
 	schedulingRetryBackoffDelay time.Duration
 	nodeLabels                  []string
 	nodeLabelsExpr              string
+	nodeStatusSelector          string
 	nodeAndPodsExpr             string
+	enablePercentage            int
+	protectedNodeLabels         []string
 
 	// Eviction filtering flags
 	skipDrain                 bool
 	doNotEvictPodControlledBy []string
 	evictLocalStoragePods     bool
+	evictHostPathPods         bool
+	evictDryRunFirst          bool
+	protectSystemNamespaces   bool
+	systemNamespaces          []string
+	waitForReplacementPod     time.Duration
+	evictionConfirmationURL   string
+	evictionConfirmationKey   string
+	evictionConfirmationWait  time.Duration
+	snapshotEvictedPods       bool
+	stuckTerminatingAction    string
+	stuckTerminatingTimeout   time.Duration
 	protectedPodAnnotations   []string
+	protectedPodCELExpr       string
 	drainGroupLabelKey        string
+	drainGroupFromCRD         string
 
 	// Candidate filtering flags
 	doNotCandidatePodControlledBy          []string
 	candidateLocalStoragePods              bool
 	excludeStatefulSetOnNodeWithoutStorage bool
 	candidateProtectedPodAnnotations       []string
+	honorKarpenterDoNotDisrupt             bool
+	leaderLeaseNamePattern                 string
+	leaderPodMaxWait                       time.Duration
+	ignoreManuallyCordoned                 bool
+	respectTopologySpread                  bool
+	minHealthyNodesPerGroup                int
+	maxPodsForDrain                        int
+	recordonCooldown                       time.Duration
+	recordonConditionPersistence           time.Duration
+	candidateMinInScopeAge                 time.Duration
+	groupPriorities                        map[string]int
 
 	maxNotReadyNodes          []string
 	maxNotReadyNodesFunctions map[string]kubernetes.ComputeBlockStateFunctionFactory
@@ -58,12 +112,25 @@ type Options struct {
 	maxPendingPodsFunctions map[string]kubernetes.ComputeBlockStateFunctionFactory
 	maxPendingPodsPeriod    time.Duration
 
+	// fraction of the requests to the API server failing over which the drains are blocked
+	apiServerHealthBlockThreshold float64
+
+	// maximum wait for the informers sync at startup, zero waits forever
+	informerSyncTimeout time.Duration
+
 	maxDrainAttemptsBeforeFail int
 
+	// Maximum number of owners explored above a pod when searching for annotations on its controllers
+	ownerChainMaxDepth int
+
 	// Pod Opt-in flags
 	optInPodAnnotations      []string
 	shortLivedPodAnnotations []string
 
+	// Utility pods flags
+	utilityPods                 string
+	utilityPodSidecarContainers []string
+
 	// NodeReplacement limiter flags
 	maxNodeReplacementPerHour int
 	durationBeforeReplacement time.Duration
@@ -77,11 +144,15 @@ type Options struct {
 	// PV/PVC management
 	storageClassesAllowingVolumeDeletion []string
 	pvcManagementByDefault               bool
+	cleanupReleasedPVs                   bool
+	pvcDeletionQPS                       float32
 
 	// Drain runner rate limiting
 	drainRateLimitQPS   float32
 	drainRateLimitBurst int
 
+	drainRateTaperNotReadyPercent int
+
 	waitBeforeDraining time.Duration
 
 	// Which ratio of the overall kube client rate limiting should be used by the drain simulation
@@ -91,23 +162,75 @@ type Options struct {
 	eventAggregationPeriod        time.Duration
 	excludedPodsPerNodeEstimation int
 	logEvents                     bool
+	emitNodeGroupEvents           bool
 
 	//circuit breaker
 	monitorCircuitBreakerCheckPeriod time.Duration
 	monitorCircuitBreakerMonitorTags map[string]string
 	circuitBreakerRateLimitQPS       float32
 
-	configName          string
-	resetScopeLabel     bool
-	scopeAnalysisPeriod time.Duration
-
-	groupRunnerPeriod       time.Duration
-	podWarmupDelayExtension time.Duration
+	configName                   string
+	resetScopeLabel              bool
+	scopeAnalysisPeriod          time.Duration
+	scopeObserverDryRun          bool
+	scopeObserverMaxBackoff      time.Duration
+	scopeObserverMaxRequeues     int
+	scopeObserverRequeuePolicy   string
+	scopeObserverServerSideApply bool
+	scopeSnapshotConfigMapName   string
+
+	groupRunnerPeriod          time.Duration
+	candidateRunnerPeriod      time.Duration
+	drainRunnerPeriod          time.Duration
+	periodJitterFactor         float64
+	candidatePassTimeout       time.Duration
+	candidateSortBy            string
+	drainPreferredLabelKey     string
+	podWarmupDelayExtension    time.Duration
+	orphanPDBCheckPeriod       time.Duration
+	drainFailureConfirmDelay   time.Duration
+	drainDeadlineWarningRatio  float64
+	abortDrainOnConditionClear bool
+	quarantineOnMaxFailures    bool
+	maxSchedulingFailures      int
+	maxTransientRetries        int
+	drainSurgePercentage       int
+	emitDrainSummary           bool
+	waitReplacementReady       time.Duration
+	globalMaxConcurrentDrains  int
+	drainBudgets               bool
+	drainApprovalEndpoint      string
 
 	klogVerbosity int32
 
+	// drain lifecycle events export
+	eventExportKafkaBrokers []string
+	eventExportTopic        string
+	eventExportQueueSize    int
+	auditLogPath            string
+	auditLogMaxSize         int64
+
+	// drain based on the node utilization reported by metrics-server
+	drainOnNodeCPUAbove               float64
+	drainOnNodeMemoryAbove            float64
+	drainOnNodeUtilizationMinDuration time.Duration
+
+	// drain the nodes with a taint set by another controller
+	triggerTaintKey string
+
 	conditions         []string
 	suppliedConditions []kubernetes.SuppliedCondition
+	// conditions of the drain groups watched in place of the global ones
+	groupConditionsFile string
+
+	// proposed filters evaluated in shadow mode by the candidate runner
+	shadowNodeLabelsExpr     string
+	shadowConditions         []string
+	shadowSuppliedConditions []kubernetes.SuppliedCondition
+
+	// other configurations running in the same process
+	additionalConfigurationsFile string
+	additionalConfigurations     []ConfigurationDefinition
 }
 
 func optionsFromFlags() (*Options, *pflag.FlagSet) {
@@ -118,66 +241,150 @@ func optionsFromFlags() (*Options, *pflag.FlagSet) {
 	fs.BoolVar(&opt.debug, "debug", false, "Run with debug logging.")
 	fs.BoolVar(&opt.dryRun, "dry-run", false, "Emit an event without tainting or draining matching nodes.")
 	fs.BoolVar(&opt.skipDrain, "skip-drain", false, "Whether to skip draining nodes after tainting.")
+	fs.DurationVar(&opt.waitForReplacementPod, "wait-for-replacement-pod", 0, "Before evicting a pod controlled by a replicaset, wait up to this duration for a running and ready pod of the same workload on another node. The replacement of a workload with a single replica must be created after the wait started, by a rollout with a surge for example. 0 disables the wait.")
+	fs.StringVar(&opt.evictionConfirmationURL, "eviction-confirmation-url", "", "Endpoint asked to approve or deny the eviction of the pods carrying the --eviction-confirmation-annotation. Disabled if empty.")
+	fs.StringVar(&opt.evictionConfirmationKey, "eviction-confirmation-annotation", kubernetes.DefaultEvictionConfirmationAnnotationKey, "Pod annotation requesting a confirmation from --eviction-confirmation-url before the eviction of the pod.")
+	fs.DurationVar(&opt.evictionConfirmationWait, "eviction-confirmation-timeout", 5*time.Minute, "Maximum duration to wait for the decision of --eviction-confirmation-url, the pod is evicted after it.")
+	fs.BoolVar(&opt.snapshotEvictedPods, "snapshot-evicted-pods", false, "Before evicting the pods of a node, capture their manifests, redacted and size bounded, in the ConfigMap draino-evicted-pods-<node> of the draino namespace. The last 3 drains of each node are kept.")
+	fs.StringVar(&opt.stuckTerminatingAction, "stuck-terminating-action", string(kubernetes.StuckTerminatingActionWait), "Action taken on an evicted pod still terminating after its grace period and --stuck-terminating-timeout: 'wait' keeps waiting until the eviction times out, 'force' deletes the pod with a zero grace period, 'skip' stops waiting for the pod and goes on with the drain.")
+	fs.DurationVar(&opt.stuckTerminatingTimeout, "stuck-terminating-timeout", kubernetes.DefaultStuckTerminatingTimeout, "Time given to an evicted pod to disappear after its grace period before applying --stuck-terminating-action.")
+	fs.BoolVar(&opt.protectSystemNamespaces, "protect-system-namespaces", false, "Do not drain the nodes hosting pods of the kube-system namespace and of the namespaces set with --system-namespaces. The nodes are not candidate, with the reason reported by the candidate filter.")
+	fs.StringSliceVar(&opt.systemNamespaces, "system-namespaces", []string{}, "Namespaces protected in addition to kube-system with --protect-system-namespaces. May be specified multiple times.")
+	fs.BoolVar(&opt.evictDryRunFirst, "evict-dry-run-first", false, "Issue a dry-run eviction before evicting each pod. The pods whose dry-run fails are skipped and reported while the other pods of the node are evicted.")
 	fs.BoolVar(&opt.evictLocalStoragePods, "evict-emptydir-pods", false, "Evict pods with local storage, i.e. with emptyDir volumes.")
+	fs.BoolVar(&opt.evictHostPathPods, "evict-hostpath-pods", false, "Evict pods with hostPath volumes.")
 	fs.BoolVar(&opt.candidateLocalStoragePods, "candidate-emptydir-pods", true, "Evict pods with local storage, i.e. with emptyDir volumes.")
+	fs.BoolVar(&opt.minDurationFromObservation, "min-duration-from-observation", false, "Measure the delay of the conditions from their first observation by draino instead of their last transition time, so that the conditions get a warm-up after a restart.")
+	fs.BoolVar(&opt.ignoreManuallyCordoned, "ignore-manually-cordoned", false, "Never act on the nodes that were cordoned by someone else than draino.")
+	fs.BoolVar(&opt.respectTopologySpread, "respect-topology-spread", false, "Do not select a node as candidate if its drain would leave a topology domain without replica or break the max skew of the topology spread constraints of its pods.")
 	fs.BoolVar(&opt.preprovisioningActivatedByDefault, "preprovisioning-by-default", false, "Set this flag to activate pre-provisioning by default for all nodes")
+	fs.BoolVar(&opt.cleanupReleasedPVs, "cleanup-released-pvs", false, "Periodically delete the persistent volumes left in Released phase whose storage class is allowed with --storage-class-allows-pv-deletion.")
+	fs.Float32Var(&opt.pvcDeletionQPS, "pvc-deletion-qps", 0, "Maximum number of PVC deletions per second across all the drains, the drains wait for their turn. 0 disables the limit.")
 	fs.BoolVar(&opt.pvcManagementByDefault, "pvc-management-by-default", false, "PVC management is automatically activated for a workload that do not use eviction++")
 	fs.BoolVar(&opt.resetScopeLabel, "reset-config-labels", false, "Reset the scope label on the nodes")
+	fs.BoolVar(&opt.scopeObserverDryRun, "scope-observer-dry-run", false, "Only log the scope labels changes that would be applied on the nodes, without patching them.")
+	fs.IntVar(&opt.scopeObserverMaxRequeues, "scope-observer-max-requeues", observability.DefaultNodeUpdateQueueConfig.MaxRequeues, "Number of failed attempts of a node label update after which the scope observer requeue policy applies.")
+	fs.DurationVar(&opt.scopeObserverMaxBackoff, "scope-observer-max-backoff", observability.DefaultNodeUpdateQueueConfig.MaxBackoff, "Maximum delay between two retries of a failing node label update.")
+	fs.StringVar(&opt.scopeObserverRequeuePolicy, "scope-observer-requeue-policy", observability.DefaultNodeUpdateQueueConfig.RequeuePolicy, "What to do with a node label update that reached the max requeues: 'drop' abandons it until the next scope analysis, 'reset' resets its backoff and keeps retrying.")
+	fs.BoolVar(&opt.scopeObserverServerSideApply, "scope-observer-server-side-apply", false, "Write all the scope labels of a node in a single server-side apply request instead of individual patches.")
 	fs.BoolVar(&opt.noLegacyNodeHandler, "no-legacy-node-handler", false, "Deactivate draino legacy node handler")
 	fs.BoolVar(&opt.logEvents, "log-events", true, "Indicate if events sent to kubernetes should also be logged")
+	fs.BoolVar(&opt.emitNodeGroupEvents, "emit-nodegroup-events", false, "Also record the drain lifecycle events on the nodegroup owning the node.")
 	fs.BoolVar(&opt.excludeStatefulSetOnNodeWithoutStorage, "exclude-sts-on-node-without-storage", true, "To ensure backward compatibility with draino v1, we have to exclude pod of STS running on node without local-storage")
 
 	fs.DurationVar(&opt.minEvictionTimeout, "min-eviction-timeout", kubernetes.DefaultMinEvictionTimeout, "Minimum time we wait to evict a pod. The pod terminationGracePeriod will be used if it is bigger.")
 	fs.DurationVar(&opt.evictionHeadroom, "eviction-headroom", kubernetes.DefaultEvictionOverhead, "Additional time to wait after a pod's termination grace period for it to have been deleted.")
+	fs.DurationVar(&opt.maxPodGracePeriod, "max-pod-grace-period", 0, "Ceiling for the termination grace period given to the evicted pods, regardless of their spec. 0 means no ceiling.")
+	fs.StringVar(&opt.changeTicketAnnotation, "change-ticket-annotation", kubernetes.DefaultChangeTicketAnnotationKey, "Node annotation giving the change ticket, for example JIRA-123, that the drain of the node is linked to. The ticket is added to the node events and to the audit log.")
+	fs.StringVar(&opt.teamLabelKey, "team-label-key", kubernetes.DefaultTeamLabelKey, "Label of the nodes and pods giving the owning team, used to tag the drain metrics. The managed_by_team label of the nodes takes precedence.")
+	fs.StringVar(&opt.drainNowAnnotation, "drain-now-annotation", kubernetes.DefaultDrainNowAnnotationKey, "Annotation set to 'true' on a node for an emergency evacuation. The node is drained right away, bypassing the drain buffer and the candidate gating but respecting the retry wall and the PDBs. The annotation is removed once the node is drained. Disabled if empty.")
+	fs.StringVar(&opt.spotTerminationAnnotation, "spot-termination-annotation", "", "Annotation set by a node agent on a spot node about to be reclaimed. A node holding it is drained right away, bypassing the drain buffer and the candidate gating but respecting the PDBs. Disabled if empty.")
+	fs.DurationVar(&opt.spotTerminationGracePeriod, "spot-termination-grace-period", 30*time.Second, "Ceiling for the termination grace period given to the pods evicted from a node with the spot termination annotation. 0 means no specific ceiling.")
 	fs.DurationVar(&opt.drainBuffer, "drain-buffer", kubernetes.DefaultDrainBuffer, "Delay to respect between end of previous drain (success or error) and a new attempt within a drain-group.")
 	fs.StringVar(&opt.drainBufferConfigMapName, "drain-buffer-configmap-name", "", "The name of the configmap used to persist the drain-buffer values. Default will be draino-<config-name>-drain-buffer.")
+	fs.Float64Var(&opt.softDrainBufferFactor, "soft-drain-buffer-factor", 1, "Fraction of the drain buffer respected while the cluster has spare capacity, see --soft-drain-buffer-max-pending-ratio. 1 disables the relaxation.")
+	fs.Float64Var(&opt.softDrainBufferMaxPending, "soft-drain-buffer-max-pending-ratio", 0, "The cluster is considered to have spare capacity, and the drain buffer is relaxed, while it has at most this number of Pending pods per node.")
 	fs.DurationVar(&opt.schedulingRetryBackoffDelay, "retry-backoff-delay", DefaultSchedulingRetryBackoffDelay, "Additional delay to add between retry schedules.")
 	fs.DurationVar(&opt.maxNotReadyNodesPeriod, "max-notready-nodes-period", kubernetes.DefaultMaxNotReadyNodesPeriod, "Polling period to check all nodes readiness")
+	fs.Float64Var(&opt.apiServerHealthBlockThreshold, "apiserver-health-block-threshold", 0, "Fraction of the requests sent by draino to the API server that failed (unreachable, 429 or 5xx) over the last 5 minutes, above which the drains are blocked until the error rate recovers. Between 0 and 1, 0 disables the check.")
+	fs.DurationVar(&opt.informerSyncTimeout, "informer-sync-timeout", 0, "Maximum wait for the informers sync at startup, draino exits with an error if the informers are not synced in time, for example when RBAC forbids the list of a resource. 0 waits forever.")
 	fs.DurationVar(&opt.maxPendingPodsPeriod, "max-pending-pods-period", kubernetes.DefaultMaxPendingPodsPeriod, "Polling period to check volume of pending pods")
 	fs.DurationVar(&opt.durationBeforeReplacement, "duration-before-replacement", kubernetes.DefaultDurationBeforeReplacement, "Max duration we are waiting for a node with Completed drain status to be removed before asking for replacement.")
 	fs.DurationVar(&opt.preprovisioningTimeout, "preprovisioning-timeout", DefaultPreprovisioningTimeout, "Timeout for a node to be preprovisioned before draining")
 	fs.DurationVar(&opt.preprovisioningCheckPeriod, "preprovisioning-check-period", DefaultPreprovisioningCheckPeriod, "Period to check if a node has been preprovisioned")
 	fs.DurationVar(&opt.scopeAnalysisPeriod, "scope-analysis-period", 5*time.Minute, "Period to run the scope analysis and generate metric")
 	fs.DurationVar(&opt.groupRunnerPeriod, "group-runner-period", 10*time.Second, "Period for running the group runner")
+	fs.DurationVar(&opt.candidateRunnerPeriod, "candidate-runner-period", 0, "Period for running the candidate runner of each group. Defaults to --group-runner-period.")
+	fs.DurationVar(&opt.drainRunnerPeriod, "drain-runner-period", 0, "Period for running the drain runner of each group. Defaults to --group-runner-period.")
+	fs.Float64Var(&opt.periodJitterFactor, "period-jitter-factor", 0, "Randomize the scope analysis and group runner periods in period*(1±factor) to avoid synchronized API calls. The factor must be between 0 and 0.5, 0 disables the jitter.")
+	fs.DurationVar(&opt.candidateMinInScopeAge, "candidate-min-in-scope-age", 0, "Minimum duration a node has to carry the configuration in its scope label before it can become candidate. 0 disables the check.")
+	fs.DurationVar(&opt.recordonCooldown, "recordon-cooldown", 0, "Period after a node recovered from its condition, and lost its candidate status, during which it cannot become candidate again, unless its condition persisted for longer than --recordon-condition-persistence. 0 disables the cooldown.")
+	fs.DurationVar(&opt.recordonConditionPersistence, "recordon-condition-persistence", 0, "Duration for which a condition must persist for the node to become candidate again during the --recordon-cooldown. It must be longer than the cooldown.")
+	fs.StringVar(&opt.candidateSortBy, "candidate-sort-by", "", "Additional order of the drain candidates, applied after the drain priority and the conditions priority. 'newest' drains the most recently created nodes first, 'zone-balance' drains first the nodes of the zones having the most nodes, 'preferred' drains first the nodes labeled with --drain-preferred-label-key=true. Empty keeps the default order.")
+	fs.StringVar(&opt.drainPreferredLabelKey, "drain-preferred-label-key", sorters.DefaultDrainPreferredLabelKey, "Label set to 'true' by the operators on the nodes to drain first with --candidate-sort-by=preferred, for example the nodes flagged for decommission.")
+	fs.DurationVar(&opt.candidatePassTimeout, "candidate-pass-timeout", 0, "Maximum duration of a candidate evaluation pass for a group. The pass is aborted at the deadline and resumed at the next period. 0 means no deadline.")
+	fs.DurationVar(&opt.drainFailureConfirmDelay, "drain-failure-confirm-delay", 0, "Delay after which a failed drain is attempted once more before being recorded as a failure, to absorb API flakiness. 0 records the failure immediately.")
+	fs.StringVar(&opt.drainApprovalEndpoint, "drain-approval-endpoint", "", "URL of an endpoint that must approve the drain of each node. The request and response are JSON, the decision is approve, deny or defer. Disabled if empty.")
+	fs.IntVar(&opt.drainSurgePercentage, "drain-surge-percentage", 0, "Percentage of the nodes of a group that can be draining or drained at the same time. A candidate waits until the group is below the budget, of at least one node. When set, the drains are spaced by this budget instead of the drain buffer. 0 disables the surge model.")
+	fs.BoolVar(&opt.emitDrainSummary, "emit-drain-summary", false, "Emit a DrainSummary event on the node when its drain completes, with the duration of the drain, the number of pods evicted and PVCs deleted, and the number of previous failed attempts.")
+	fs.IntVar(&opt.maxSchedulingFailures, "max-drain-scheduling-failures", 0, "Number of consecutive errors before the drain of a candidate could start after which its candidate status is removed, with a warning event and a retry wall, so that it is not left stranded. 0 disables the limit.")
+	fs.IntVar(&opt.maxTransientRetries, "max-transient-drain-retries", 5, "Number of consecutive transient drain errors of a node, like API server timeouts, that are retried with an exponential backoff without counting in the retry wall. The next transient error is handled as a drain failure. 0 handles the transient errors as drain failures.")
+	fs.BoolVar(&opt.quarantineOnMaxFailures, "quarantine-on-max-failures", false, "Keep cordoned and label with draino/quarantined=true the nodes whose drain failures reach the retry threshold, instead of uncordoning them. Draino ignores these nodes until the label is removed, and then uncordons them.")
+	fs.BoolVar(&opt.drainBudgets, "drain-budgets", false, "Consume the DrainBudget resources selecting a node before its drain. A candidate waits until all of them allow one more drain in their window.")
+	fs.IntVar(&opt.globalMaxConcurrentDrains, "global-max-concurrent-drains", 0, "Maximum number of drains running at the same time across all the groups. A candidate waits for a free slot before its drain starts. 0 disables the limit.")
+	fs.DurationVar(&opt.waitReplacementReady, "wait-replacement-ready", 0, "After the evictions, keep the node draining, up to this duration, until each workload it hosted has a pod passing its readiness gates on another node. The next drain of the group waits as well. 0 disables the wait.")
+	fs.BoolVar(&opt.abortDrainOnConditionClear, "abort-drain-on-condition-clear", false, "Abort the drain and uncordon the node if its offending conditions clear while it is being drained.")
+	fs.Float64Var(&opt.drainDeadlineWarningRatio, "drain-deadline-warning-ratio", 0, "Fraction of the drain timeout after which a warning event is emitted on a node still being drained, for example 0.8. The ratio must be lower than 1, 0 disables the warning.")
+	fs.DurationVar(&opt.orphanPDBCheckPeriod, "orphan-pdb-check-period", 0, "Period to count the PDBs whose selector does not match any pod, reported by the metric orphan_pdb_total. 0 disables the check.")
 	fs.DurationVar(&opt.podWarmupDelayExtension, "pod-warmup-delay-extension", 30*time.Second, "Extra delay given to the pod to complete is warmup phase (all containers have passed their startProbes)")
 	fs.DurationVar(&opt.eventAggregationPeriod, "event-aggregation-period", 15*time.Minute, "Period for event generation on kubernetes object.")
-	fs.DurationVar(&opt.waitBeforeDraining, "wait-before-draining", 30*time.Second, "Time to wait between moving a node in candidate status and starting the actual drain.")
+	fs.DurationVar(&opt.waitBeforeDraining, "wait-before-draining", 30*time.Second, "Time to wait between moving a node in candidate status and starting the actual drain. This can be overridden per node group with the label or annotation node-lifecycle.datadoghq.com/wait-before-draining.")
 	fs.DurationVar(&opt.preActivityDefaultTimeout, "pre-activity-default-timeout", 10*time.Minute, "Default duration to wait, for a pre activity to finish, before aborting the drain. This can be overridden by an annotation.")
 	fs.DurationVar(&opt.monitorCircuitBreakerCheckPeriod, "monitor-check-circuit-breaker-period", 1*time.Minute, "Period for checking the monitors associated with circuit breakers.")
 
+	fs.IntVar(&opt.enablePercentage, "enable-percentage", 100, "Percentage of the nodes in scope, selected by the hash of the node name regardless of the other selectors. Lower it to enable draino gradually on a cluster.")
+	fs.StringSliceVar(&opt.protectedNodeLabels, "protected-node-labels", kubernetes.DefaultProtectedNodeLabels, "Nodes having one of these labels are never in scope, whatever the other selectors. Set it empty to allow draining the control-plane and ingress nodes. May be specified multiple times. KEY[=VALUE]")
 	fs.StringSliceVar(&opt.nodeLabels, "node-label", []string{}, "(Deprecated) Nodes with this label will be eligible for tainting and draining. May be specified multiple times")
 	fs.StringSliceVar(&opt.doNotEvictPodControlledBy, "do-not-evict-pod-controlled-by", []string{"", kubernetes.KindStatefulSet, kubernetes.KindDaemonSet},
 		"Do not evict pods that are controlled by the designated kind, empty VALUE for uncontrolled pods, May be specified multiple times: kind[[.version].group]] examples: StatefulSets StatefulSets.apps StatefulSets.apps.v1")
 	fs.StringSliceVar(&opt.protectedPodAnnotations, "protected-pod-annotation", []string{}, "Protect pods with this annotation from eviction. May be specified multiple times. KEY[=VALUE]")
+	fs.StringVar(&opt.protectedPodCELExpr, "protected-pod-cel-expr", "", "Protect pods matching this CEL expression from eviction. The expression is evaluated over the pod fields in the pod variable, e.g. 'pod.metadata.labels.app == \"db\"'. A pod on which it cannot be evaluated, because of a missing field for example, is not protected.")
 	fs.StringSliceVar(&opt.doNotCandidatePodControlledBy, "do-not-cordon-pod-controlled-by", []string{"", kubernetes.KindStatefulSet}, "Do not make candidate nodes hosting pods that are controlled by the designated kind, empty VALUE for uncontrolled pods, May be specified multiple times. kind[[.version].group]] examples: StatefulSets StatefulSets.apps StatefulSets.apps.v1")
 	fs.StringSliceVar(&opt.candidateProtectedPodAnnotations, "cordon-protected-pod-annotation", []string{}, "Protect nodes hosting pods with this annotation from being candidate. May be specified multiple times. KEY[=VALUE]")
+	fs.BoolVar(&opt.honorKarpenterDoNotDisrupt, "honor-karpenter-do-not-disrupt", true, "Protect pods with the karpenter.sh/do-not-disrupt=true annotation from eviction and their nodes from being candidate. Can be disabled on clusters not running Karpenter.")
+	fs.StringVar(&opt.leaderLeaseNamePattern, "leader-lease-name-pattern", "", "Regular expression matching the names of the leader election leases. A node hosting the pod holding such a lease is not candidate until the leadership moves to another pod. Empty disables the check.")
+	fs.DurationVar(&opt.leaderPodMaxWait, "leader-pod-max-wait", kubernetes.DefaultLeaderPodMaxWait, "Maximum time a leader pod can prevent its node from being candidate.")
 	fs.StringSliceVar(&opt.maxNotReadyNodes, "max-notready-nodes", []string{}, "Maximum number of NotReady nodes in the cluster. When exceeding this value draino stop taking actions. (Value|Value%)")
 	fs.StringSliceVar(&opt.maxPendingPods, "max-pending-pods", []string{}, "Maximum number of Pending Pods in the cluster. When exceeding this value draino stop taking actions. (Value|Value%)")
 	fs.StringSliceVar(&opt.optInPodAnnotations, "opt-in-pod-annotation", []string{}, "Pod filtering out is ignored if the pod holds one of these annotations. In a way, this makes the pod directly eligible for draino eviction. May be specified multiple times. KEY[=VALUE]")
+	fs.StringVar(&opt.utilityPods, "utility-pods", "", "Treatment of the utility pods, whose init containers completed and whose containers, except the --utility-pod-sidecar-containers, all completed successfully: evict them even if they are protected, or ignore them and leave them on the node. In both cases they do not block the node from being candidate. Disabled if empty.")
+	fs.StringSliceVar(&opt.utilityPodSidecarContainers, "utility-pod-sidecar-containers", []string{}, "Names of the sidecar containers that may keep running in a utility pod. May be specified multiple times.")
 	fs.StringSliceVar(&opt.shortLivedPodAnnotations, "short-lived-pod-annotation", []string{}, "Pod that have a short live, just like job; we prefer let them run till the end instead of evicting them; node is cordon. May be specified multiple times. KEY[=VALUE]")
+	fs.StringSliceVar(&opt.eventExportKafkaBrokers, "event-export-kafka-brokers", []string{}, "Kafka brokers to which the drain lifecycle events are published. Export is disabled if empty. May be specified multiple times.")
 	fs.StringSliceVar(&opt.storageClassesAllowingVolumeDeletion, "storage-class-allows-pv-deletion", []string{}, "Storage class for which persistent volume (and associated claim) deletion is allowed. May be specified multiple times.")
 
 	fs.StringVar(&opt.nodeLabelsExpr, "node-label-expr", "", "Nodes that match this expression will be eligible for tainting and draining.")
+	fs.StringVar(&opt.nodeStatusSelector, "node-status-selector", "", "Only the nodes whose status matches this expression are eligible for tainting and draining, in addition to the label selection. The status is available as 'status', for example: status.nodeInfo.osImage startsWith 'Ubuntu'. Disabled if empty.")
+	fs.StringVar(&opt.shadowNodeLabelsExpr, "shadow-node-label-expr", "", "Node label expression evaluated in shadow mode in place of the node label selection: the candidates selected by only one of the active and shadow filters are logged and counted, the shadow selection is never acted on. Disabled if empty.")
+	fs.StringArrayVar(&opt.shadowConditions, "shadow-conditions", nil, "Node conditions evaluated in shadow mode in place of --node-conditions, with the same format. The candidates selected by only one of the active and shadow filters are logged and counted, the shadow selection is never acted on. Disabled if empty.")
 	fs.StringVar(&opt.nodeAndPodsExpr, "node-and-pods-expr", "", "(For now, only log diff with other filters) If a node and its pods match this expression, the node is eligible for tainting and draining. If not, the node is eligible unless any of its pods belongs to a statefulset, and neither the pod nor the statefulset is annotated with node-lifecycle.datadoghq.com/enabled=true.")
 	fs.StringVar(&opt.listen, "listen", ":10002", "Address at which to expose /metrics and /healthz.")
 	fs.StringVar(&opt.kubecfg, "kubeconfig", "", "Path to kubeconfig file. Leave unset to use in-cluster config.")
 	fs.StringVar(&opt.apiserver, "master", "", "Address of Kubernetes API server. Leave unset to use in-cluster config.")
+	fs.StringVar(&opt.conditionsKubecfg, "conditions-kubeconfig", "", "Path to the kubeconfig file of a secondary cluster whose node conditions are copied to the nodes of the same name. Draino still acts with the main client only. Disabled if empty.")
 	fs.StringVar(&opt.drainGroupLabelKey, "drain-group-labels", "", "Comma separated list of label keys to be used to form draining groups. KEY1,KEY2,...")
+	fs.StringVar(&opt.drainGroupFromCRD, "drain-group-from-crd", "", "Resource, as resource.version.group, owning the nodes. The draining group of a node owned by such an object is formed by its namespace and name instead of the labels. Disabled if empty.")
 	fs.StringVar(&opt.configName, "config-name", "", "Name of the draino configuration")
-
+	fs.StringVar(&opt.scopeSnapshotConfigMapName, "scope-snapshot-configmap", "", "Name of the configmap where a summary of the scope per group is written at each scope analysis. Disabled if empty.")
+	fs.StringVar(&opt.groupConditionsFile, "group-conditions-file", "", "Path to a YAML file mapping drain group keys to their own node conditions, in the --node-conditions format. The nodes of these groups are drained for their group conditions in place of --node-conditions.")
+	fs.StringVar(&opt.additionalConfigurationsFile, "additional-configurations-file", "", "Path to a YAML file defining other draino configurations to run in the same process. Each configuration has its own name, conditions, node label expression and drain group labels, and must select nodes not selected by the others.")
+	fs.StringVar(&opt.eventExportTopic, "event-export-topic", "draino-drain-events", "Topic used to publish the drain lifecycle events.")
+	fs.IntVar(&opt.eventExportQueueSize, "event-export-queue-size", 1000, "Number of drain lifecycle events queued while they are published to kafka in the background. The events are dropped while the queue is full.")
+	fs.StringVar(&opt.auditLogPath, "audit-log-path", "", "File to which the drain lifecycle events are appended as JSON lines. The audit log is disabled if empty.")
+	fs.Int64Var(&opt.auditLogMaxSize, "audit-log-max-size", 100*1024*1024, "Size in bytes above which the audit log is rotated. 0 disables the rotation.")
+
+	fs.StringToIntVar(&opt.groupPriorities, "group-priority", map[string]int{}, "Priority of the groups, by group key. A group does not get new candidates while a group with a higher priority has candidate or draining nodes. The groups that are not listed have the priority 0.")
 	fs.StringToStringVar(&opt.monitorCircuitBreakerMonitorTags, "circuit-breaker-monitor-tags", map[string]string{"cluster-autoscaler": "draino-circuit-breaker,cluster-autoscaler"}, "tags on monitors used for circuit breakers based on monitors. The keys are circuit breaker names, and the values are comma-separated lists of tags. Repeat the flag for multiple key-value pairs, i.e., multiple circuit breakers.")
 
 	// We are using some values with json content, so don't use StringSlice: https://github.com/spf13/pflag/issues/370
+	fs.StringVar(&opt.triggerTaintKey, "trigger-taint-key", "", "Key of a taint set by another controller, cluster-autoscaler's ToBeDeletedByClusterAutoscaler for example, that makes the nodes eligible for drain like a condition. Disabled if empty.")
 	fs.StringArrayVar(&opt.conditions, "node-conditions", nil, "A map from condition ID to node condition, when any of these conditions are true a node will be eligible for drain.")
 
 	fs.IntVar(&opt.maxDrainAttemptsBeforeFail, "max-drain-attempts-before-fail", 8, "Maximum number of failed drain attempts before giving-up on draining the node.")
 	fs.IntVar(&opt.maxNodeReplacementPerHour, "max-node-replacement-per-hour", 2, "Maximum number of nodes per hour for which draino can ask replacement.")
+	fs.IntVar(&opt.maxPodsForDrain, "max-pods-for-drain", 0, "Defer the drain of the nodes running more than this many pods, until their pod count drops. 0 disables the check.")
+	fs.IntVar(&opt.minHealthyNodesPerGroup, "min-healthy-nodes-per-group", 0, "Do not make new candidates in a nodegroup that has this many healthy (ready and not handled by draino) nodes or less. 0 disables the check.")
+	fs.IntVar(&opt.ownerChainMaxDepth, "owner-chain-max-depth", kubernetes.DefaultOwnerChainMaxDepth, "Maximum number of owners explored above a pod (replicaset, deployment...) when searching for annotations on its controllers. The deployment of a replicaset is always explored.")
 	fs.IntVar(&opt.excludedPodsPerNodeEstimation, "excluded-pod-per-node-estimation", 5, "Estimation of the number of pods that should be excluded from nodes. Used to compute some event cache size.")
 	fs.Int32Var(&opt.klogVerbosity, "klog-verbosity", 4, "Verbosity to run klog at")
 	// The default is allowing up to 50 drains within one minute
 	fs.Float32Var(&opt.drainRateLimitQPS, "drain-rate-limit-qps", kubernetes.DefaultDrainRateLimitQPS, "Maximum number of node drains per seconds per condition")
 	fs.IntVar(&opt.drainRateLimitBurst, "drain-rate-limit-burst", kubernetes.DefaultDrainRateLimitBurst, "Maximum number of parallel drains within a timeframe")
+	fs.IntVar(&opt.drainRateTaperNotReadyPercent, "drain-rate-taper-notready-percent", 0, "Percentage of NotReady nodes at which the drain rate reaches zero. The drain rate is reduced linearly as the percentage of NotReady nodes grows. 0 disables the taper.")
 	fs.Float32Var(&opt.simulationRateLimitingRatio, "drain-sim-rate-limit-ratio", 0.7, "Which ratio of the overall kube client rate limiting should be used by the drain simulation. 1.0 means that it will use the same.")
+	fs.Float64Var(&opt.drainOnNodeCPUAbove, "drain-on-node-cpu-above", 0, "Nodes whose CPU usage, in percent of the allocatable, stays above this value are drained. The usage is read from metrics-server. 0 disables the check.")
+	fs.Float64Var(&opt.drainOnNodeMemoryAbove, "drain-on-node-memory-above", 0, "Nodes whose memory usage, in percent of the allocatable, stays above this value are drained. The usage is read from metrics-server. 0 disables the check.")
+	fs.DurationVar(&opt.drainOnNodeUtilizationMinDuration, "drain-on-node-utilization-min-duration", 30*time.Minute, "Minimum duration the node usage has to stay above the threshold before the node is drained.")
 	fs.Float32Var(&opt.circuitBreakerRateLimitQPS, "circuit-breaker-rate-limit-qps", circuitbreaker.DefaultRateLimitQPS, "Maximum number of drain attempts when circuit breaker is half-open")
 
 	return &opt, &fs
@@ -187,6 +394,29 @@ func (o *Options) Validate() error {
 	if o.configName == "" {
 		return fmt.Errorf("--config-name must be defined and not empty")
 	}
+	switch o.candidateSortBy {
+	case "", candidateSortByNewest, candidateSortByZoneBalance, candidateSortByPreferred:
+	default:
+		return fmt.Errorf("--candidate-sort-by must be empty, '%s', '%s' or '%s'", candidateSortByNewest, candidateSortByZoneBalance, candidateSortByPreferred)
+	}
+	if o.candidateSortBy == candidateSortByPreferred && o.drainPreferredLabelKey == "" {
+		return fmt.Errorf("--drain-preferred-label-key must not be empty with --candidate-sort-by=%s", candidateSortByPreferred)
+	}
+	if _, err := kubernetes.ParseStuckTerminatingAction(o.stuckTerminatingAction); err != nil {
+		return fmt.Errorf("--stuck-terminating-action: %w", err)
+	}
+	if o.changeTicketAnnotation == "" {
+		return fmt.Errorf("--change-ticket-annotation must not be empty")
+	}
+	if o.teamLabelKey == "" {
+		return fmt.Errorf("--team-label-key must not be empty")
+	}
+	if o.softDrainBufferFactor < 0 || o.softDrainBufferFactor > 1 {
+		return fmt.Errorf("--soft-drain-buffer-factor must be between 0 and 1")
+	}
+	if o.softDrainBufferMaxPending < 0 {
+		return fmt.Errorf("--soft-drain-buffer-max-pending-ratio must be positive")
+	}
 	var err error
 
 	// If the drain buffer config name is not set, we'll reuse the configName
@@ -222,18 +452,171 @@ func (o *Options) Validate() error {
 		o.maxPendingPodsFunctions[p] = factoryComputeBlockStateForPods(max, percent)
 	}
 
+	if o.apiServerHealthBlockThreshold < 0 || o.apiServerHealthBlockThreshold >= 1 {
+		return fmt.Errorf("apiserver health block threshold should be between 0 and 1")
+	}
+
+	if o.informerSyncTimeout < 0 {
+		return fmt.Errorf("informer sync timeout cannot be negative")
+	}
+
+	if o.drainOnNodeCPUAbove < 0 || o.drainOnNodeMemoryAbove < 0 {
+		return fmt.Errorf("node utilization thresholds cannot be negative")
+	}
+	// The utilization thresholds are reflected as node conditions, these conditions must be supplied like the others.
+	// The flag values are left untouched so that Validate can be called more than once.
+	conditions := append([]string{}, o.conditions...)
+	conditions = append(conditions, o.nodeUtilizationThresholds().Conditions(o.drainOnNodeUtilizationMinDuration)...)
+	// The trigger taint is supplied as a condition too, it can be the only one
+	if o.triggerTaintKey != "" {
+		if o.triggerTaintKey == k8sclient.DrainoTaintKey {
+			return fmt.Errorf("--trigger-taint-key cannot be the taint of draino")
+		}
+		if triggerTaintCondition := kubernetes.TriggerTaintCondition(o.triggerTaintKey); !slices.Contains(conditions, triggerTaintCondition) {
+			conditions = append(conditions, triggerTaintCondition)
+		}
+	}
+
 	// Check that conditions are defined and well formatted
-	if len(o.conditions) == 0 {
+	if len(conditions) == 0 {
 		return fmt.Errorf("no condition defined")
 	}
 	// Sanitize user input
-	sort.Strings(o.conditions)
-	if o.suppliedConditions, err = kubernetes.ParseConditions(o.conditions); err != nil {
+	sort.Strings(conditions)
+	if o.suppliedConditions, err = kubernetes.ParseConditions(conditions); err != nil {
 		return fmt.Errorf("one of the conditions is not correctly formatted: %#v", err)
 	}
+	if o.groupConditionsFile != "" {
+		if o.suppliedConditions, err = loadGroupConditions(o.groupConditionsFile, o.suppliedConditions); err != nil {
+			return err
+		}
+	}
+	if len(o.shadowConditions) > 0 {
+		sort.Strings(o.shadowConditions)
+		if o.shadowSuppliedConditions, err = kubernetes.ParseConditions(o.shadowConditions); err != nil {
+			return fmt.Errorf("one of the shadow conditions is not correctly formatted: %#v", err)
+		}
+	}
+	if o.additionalConfigurationsFile != "" {
+		if o.additionalConfigurations, err = loadConfigurationDefinitions(o.additionalConfigurationsFile, o.configName); err != nil {
+			return err
+		}
+		if len(o.nodeLabels) == 0 && o.nodeLabelsExpr == "" {
+			return fmt.Errorf("the main configuration must restrict the nodes with --node-label or --node-label-expr when additional configurations are defined")
+		}
+	}
+	if o.minDurationFromObservation {
+		o.suppliedConditions = kubernetes.WithMinDurationFromObservation(o.suppliedConditions)
+		o.shadowSuppliedConditions = kubernetes.WithMinDurationFromObservation(o.shadowSuppliedConditions)
+		for i := range o.additionalConfigurations {
+			o.additionalConfigurations[i].suppliedConditions = kubernetes.WithMinDurationFromObservation(o.additionalConfigurations[i].suppliedConditions)
+		}
+	}
+	if o.periodJitterFactor < 0 || o.periodJitterFactor > utils.MaxPeriodJitterFactor {
+		return fmt.Errorf("period jitter factor should be between 0 and %v", utils.MaxPeriodJitterFactor)
+	}
+	if o.maxPodsForDrain < 0 {
+		return fmt.Errorf("max pods for drain cannot be negative")
+	}
+	if o.minHealthyNodesPerGroup < 0 {
+		return fmt.Errorf("min healthy nodes per group cannot be negative")
+	}
+	if o.candidateMinInScopeAge < 0 {
+		return fmt.Errorf("candidate min in scope age cannot be negative")
+	}
+	if o.recordonCooldown < 0 {
+		return fmt.Errorf("recordon cooldown cannot be negative")
+	}
+	if o.recordonCooldown > 0 && o.recordonConditionPersistence <= o.recordonCooldown {
+		return fmt.Errorf("--recordon-condition-persistence must be longer than --recordon-cooldown")
+	}
+	if o.drainRateLimitBurst < 1 {
+		return fmt.Errorf("drain rate limit burst must be at least 1, the drains would be blocked forever")
+	}
+	if o.drainRateTaperNotReadyPercent < 0 || o.drainRateTaperNotReadyPercent > 100 {
+		return fmt.Errorf("drain rate taper NotReady percent must be between 0 and 100")
+	}
+	if o.drainFailureConfirmDelay < 0 {
+		return fmt.Errorf("drain failure confirm delay cannot be negative")
+	}
+	if o.drainApprovalEndpoint != "" {
+		if u, err := url.Parse(o.drainApprovalEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("drain approval endpoint must be an http or https URL")
+		}
+	}
+	if o.drainDeadlineWarningRatio < 0 || o.drainDeadlineWarningRatio >= 1 {
+		return fmt.Errorf("drain deadline warning ratio must be between 0 and 1")
+	}
+	if o.scopeObserverMaxRequeues < 1 {
+		return fmt.Errorf("scope observer max requeues must be at least 1")
+	}
+	if o.scopeObserverMaxBackoff <= 0 {
+		return fmt.Errorf("scope observer max backoff must be positive")
+	}
+	if o.scopeObserverRequeuePolicy != observability.NodeUpdateRequeuePolicyDrop && o.scopeObserverRequeuePolicy != observability.NodeUpdateRequeuePolicyReset {
+		return fmt.Errorf("scope observer requeue policy must be %q or %q", observability.NodeUpdateRequeuePolicyDrop, observability.NodeUpdateRequeuePolicyReset)
+	}
+	if o.enablePercentage < 1 || o.enablePercentage > 100 {
+		return fmt.Errorf("enable percentage must be between 1 and 100")
+	}
+	if o.waitForReplacementPod < 0 {
+		return fmt.Errorf("wait for replacement pod cannot be negative")
+	}
+	if o.evictionConfirmationURL != "" && o.evictionConfirmationKey == "" {
+		return fmt.Errorf("eviction confirmation annotation cannot be empty")
+	}
+	if o.evictionConfirmationWait <= 0 {
+		return fmt.Errorf("eviction confirmation timeout must be positive")
+	}
+	if o.globalMaxConcurrentDrains < 0 {
+		return fmt.Errorf("global max concurrent drains cannot be negative")
+	}
+	if o.maxSchedulingFailures < 0 {
+		return fmt.Errorf("max drain scheduling failures cannot be negative")
+	}
+	if o.maxTransientRetries < 0 {
+		return fmt.Errorf("max transient drain retries cannot be negative")
+	}
+	if o.drainSurgePercentage < 0 || o.drainSurgePercentage > 100 {
+		return fmt.Errorf("drain surge percentage must be between 0 and 100")
+	}
+	if o.utilityPods != "" && o.utilityPods != kubernetes.UtilityPodsEvict && o.utilityPods != kubernetes.UtilityPodsIgnore {
+		return fmt.Errorf("utility pods treatment must be %s or %s", kubernetes.UtilityPodsEvict, kubernetes.UtilityPodsIgnore)
+	}
+	if o.waitReplacementReady < 0 {
+		return fmt.Errorf("wait replacement ready cannot be negative")
+	}
+	if o.orphanPDBCheckPeriod < 0 {
+		return fmt.Errorf("orphan pdb check period cannot be negative")
+	}
+	if o.leaderPodMaxWait < 0 {
+		return fmt.Errorf("leader pod max wait cannot be negative")
+	}
+	if o.maxPodGracePeriod < 0 {
+		return fmt.Errorf("max pod grace period cannot be negative")
+	}
+	// the grace period is given to the pods in seconds, a shorter ceiling would kill them immediately
+	if o.maxPodGracePeriod > 0 && o.maxPodGracePeriod < time.Second {
+		return fmt.Errorf("max pod grace period should be at least 1s")
+	}
+	if o.spotTerminationGracePeriod < 0 {
+		return fmt.Errorf("spot termination grace period cannot be negative")
+	}
 	if o.groupRunnerPeriod < time.Second {
 		return fmt.Errorf("group runner period should be at least 1s")
 	}
+	if o.candidateRunnerPeriod == 0 {
+		o.candidateRunnerPeriod = o.groupRunnerPeriod
+	}
+	if o.candidateRunnerPeriod < time.Second {
+		return fmt.Errorf("candidate runner period should be at least 1s")
+	}
+	if o.drainRunnerPeriod == 0 {
+		o.drainRunnerPeriod = o.groupRunnerPeriod
+	}
+	if o.drainRunnerPeriod < time.Second {
+		return fmt.Errorf("drain runner period should be at least 1s")
+	}
 	if o.podWarmupDelayExtension < time.Second {
 		return fmt.Errorf("pod warmup delay extension should be at least 1s")
 	}
@@ -241,6 +624,27 @@ func (o *Options) Validate() error {
 	if o.monitorCircuitBreakerCheckPeriod < 30*time.Second {
 		return fmt.Errorf("monitor polling for circuit breaker seems to be too aggressive")
 	}
+	if o.pvcDeletionQPS < 0 {
+		return fmt.Errorf("--pvc-deletion-qps must be positive")
+	}
+	if o.cleanupReleasedPVs && len(o.storageClassesAllowingVolumeDeletion) == 0 {
+		return fmt.Errorf("--storage-class-allows-pv-deletion must be defined when --cleanup-released-pvs is set")
+	}
+	if o.drainGroupFromCRD != "" {
+		if _, err := groups.ParseNodeGroupResource(o.drainGroupFromCRD); err != nil {
+			return fmt.Errorf("--drain-group-from-crd: %w", err)
+		}
+	}
+	if o.auditLogMaxSize < 0 {
+		return fmt.Errorf("audit log max size must be positive")
+	}
+	if len(o.eventExportKafkaBrokers) > 0 && o.eventExportTopic == "" {
+		return fmt.Errorf("--event-export-topic must be defined when exporting events to kafka")
+	}
+	if len(o.eventExportKafkaBrokers) > 0 && o.eventExportQueueSize <= 0 {
+		return fmt.Errorf("--event-export-queue-size must be positive when exporting events to kafka")
+	}
+
 	for k, tags := range o.monitorCircuitBreakerMonitorTags {
 		if k == "" {
 			return fmt.Errorf("circuit breaker cannot have an empty name")
@@ -256,3 +660,16 @@ func (o *Options) Validate() error {
 	}
 	return nil
 }
+
+func (o *Options) nodeUtilizationThresholds() node_utilization.Thresholds {
+	return node_utilization.Thresholds{CPUPercent: o.drainOnNodeCPUAbove, MemoryPercent: o.drainOnNodeMemoryAbove}
+}
+
+// allSuppliedConditions returns the conditions of the main configuration, of the additional configurations and of the shadow filter
+func (o *Options) allSuppliedConditions() []kubernetes.SuppliedCondition {
+	conditions := append([]kubernetes.SuppliedCondition{}, o.suppliedConditions...)
+	for _, def := range o.additionalConfigurations {
+		conditions = append(conditions, def.suppliedConditions...)
+	}
+	return append(conditions, o.shadowSuppliedConditions...)
+}
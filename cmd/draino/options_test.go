@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsRunnerPeriods(t *testing.T) {
+	tests := []struct {
+		name              string
+		args              []string
+		expectedCandidate time.Duration
+		expectedDrain     time.Duration
+	}{
+		{
+			name:              "default to the group runner period",
+			args:              []string{"--group-runner-period=20s"},
+			expectedCandidate: 20 * time.Second,
+			expectedDrain:     20 * time.Second,
+		},
+		{
+			name:              "separate periods",
+			args:              []string{"--group-runner-period=20s", "--candidate-runner-period=1m", "--drain-runner-period=5s"},
+			expectedCandidate: time.Minute,
+			expectedDrain:     5 * time.Second,
+		},
+		{
+			name:              "only the candidate runner is slowed down",
+			args:              []string{"--candidate-runner-period=1m"},
+			expectedCandidate: time.Minute,
+			expectedDrain:     10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, fs := optionsFromFlags()
+			assert.NoError(t, fs.Parse(append([]string{"--config-name=test-config", "--node-conditions=KernelDeadlock"}, tt.args...)))
+			assert.NoError(t, options.Validate())
+			assert.Equal(t, tt.expectedCandidate, options.candidateRunnerPeriod)
+			assert.Equal(t, tt.expectedDrain, options.drainRunnerPeriod)
+		})
+	}
+
+	options, fs := optionsFromFlags()
+	assert.NoError(t, fs.Parse([]string{"--config-name=test-config", "--node-conditions=KernelDeadlock", "--drain-runner-period=100ms"}))
+	assert.Error(t, options.Validate(), "the drain runner period should be at least 1s")
+}
+
+func TestOptionsValidateTwice(t *testing.T) {
+	options, fs := optionsFromFlags()
+	assert.NoError(t, fs.Parse([]string{"--config-name=test-config", "--node-conditions=KernelDeadlock", "--drain-on-node-cpu-above=90"}))
+	assert.NoError(t, options.Validate())
+	conditions := options.suppliedConditions
+	assert.Len(t, conditions, 2, "the utilization threshold should be supplied as a condition")
+
+	assert.NoError(t, options.Validate())
+	assert.Equal(t, conditions, options.suppliedConditions)
+}
+
+func TestOptionsMaxPodGracePeriod(t *testing.T) {
+	for _, value := range []string{"0", "1s", "10m"} {
+		options, fs := optionsFromFlags()
+		assert.NoError(t, fs.Parse([]string{"--config-name=test-config", "--node-conditions=KernelDeadlock", "--max-pod-grace-period=" + value}))
+		assert.NoError(t, options.Validate(), value)
+	}
+
+	options, fs := optionsFromFlags()
+	assert.NoError(t, fs.Parse([]string{"--config-name=test-config", "--node-conditions=KernelDeadlock", "--max-pod-grace-period=500ms"}))
+	assert.Error(t, options.Validate(), "a ceiling below 1s would be truncated to 0s")
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
 	circuitbreaker "github.com/planetlabs/draino/internal/circuit_breaker"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
+	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/limit"
 
 	corev1 "k8s.io/api/core/v1"
@@ -37,6 +39,9 @@ type Config struct {
 	filter              filters.Filter
 	rateLimiter         limit.TypedRateLimiter
 	suppliedCondition   []kubernetes.SuppliedCondition
+	drainNow            kubernetes.DrainNowConfig
+	teamLabelKey        string
+	changeTicketKey     string
 	circuitBreakers     []circuitbreaker.NamedCircuitBreaker
 
 	// With defaults
@@ -46,6 +51,11 @@ type Config struct {
 	maxSimultaneousDrained    int
 	dryRun                    bool
 	nodeIteratorFactory       NodeIteratorFactory
+	eventExporter             eventexporter.EventExporter
+	passTimeout               time.Duration
+	groupIndexName            string
+	periodJitterFactor        float64
+	shadowFilter              filters.Filter
 }
 
 // NewConfig returns a pointer to a new drain runner configuration
@@ -59,6 +69,8 @@ func NewConfig() *Config {
 		nodeIteratorFactory: func(nodes []*corev1.Node, sorters NodeSorters) scheduler.ItemProvider[*corev1.Node] {
 			return scheduler.NewSortingTreeWithInitialization(nodes, sorters)
 		},
+		eventExporter:  &eventexporter.NoopEventExporter{},
+		groupIndexName: groups.SchedulingGroupIdx,
 	}
 }
 
@@ -116,6 +128,13 @@ func WithRerun(rerun time.Duration) WithOption {
 	}
 }
 
+// WithPeriodJitterFactor randomizes the rerun period in rerun*(1±factor) to spread the activity of the groups
+func WithPeriodJitterFactor(factor float64) WithOption {
+	return func(conf *Config) {
+		conf.periodJitterFactor = factor
+	}
+}
+
 func WithClock(c clock.Clock) WithOption {
 	return func(conf *Config) {
 		conf.clock = c
@@ -191,6 +210,9 @@ func WithRateLimiter(limiter limit.TypedRateLimiter) WithOption {
 func WithGlobalConfig(globalConfig kubernetes.GlobalConfig) WithOption {
 	return func(conf *Config) {
 		conf.suppliedCondition = globalConfig.SuppliedConditions
+		conf.drainNow = globalConfig.DrainNow
+		conf.teamLabelKey = globalConfig.TeamLabelKey
+		conf.changeTicketKey = globalConfig.ChangeTicketAnnotationKey
 	}
 }
 
@@ -199,3 +221,31 @@ func WithCircuitBreaker(circuitBreaker ...circuitbreaker.NamedCircuitBreaker) Wi
 		conf.circuitBreakers = append(conf.circuitBreakers, circuitBreaker...)
 	}
 }
+
+// WithPassTimeout bounds the duration of a single candidate evaluation pass, 0 means no deadline
+func WithPassTimeout(timeout time.Duration) WithOption {
+	return func(conf *Config) {
+		conf.passTimeout = timeout
+	}
+}
+
+func WithEventExporter(exporter eventexporter.EventExporter) WithOption {
+	return func(conf *Config) {
+		conf.eventExporter = exporter
+	}
+}
+
+// WithGroupIndexName configures the index used to list the nodes of a group
+func WithGroupIndexName(indexName string) WithOption {
+	return func(conf *Config) {
+		conf.groupIndexName = indexName
+	}
+}
+
+// WithShadowFilter configures a proposed filter evaluated next to the active one. The difference in the selected nodes is
+// logged and reported by metrics, the shadow selection is never acted on. nil disables the shadow mode.
+func WithShadowFilter(filter filters.Filter) WithOption {
+	return func(conf *Config) {
+		conf.shadowFilter = filter
+	}
+}
@@ -46,8 +46,16 @@ func (factory *CandidateRunnerFactory) build() *candidateRunner {
 		filter:                    factory.conf.filter,
 		retryWall:                 factory.conf.retryWall,
 		suppliedConditions:        factory.conf.suppliedCondition,
+		drainNow:                  factory.conf.drainNow,
+		teamLabelKey:              factory.conf.teamLabelKey,
+		changeTicketKey:           factory.conf.changeTicketKey,
 		circuitBreakers:           factory.conf.circuitBreakers,
 		rateLimiter:               factory.conf.rateLimiter,
+		eventExporter:             factory.conf.eventExporter,
+		passTimeout:               factory.conf.passTimeout,
+		groupIndexName:            factory.conf.groupIndexName,
+		periodJitterFactor:        factory.conf.periodJitterFactor,
+		shadowFilter:              factory.conf.shadowFilter,
 	}
 }
 func (factory *CandidateRunnerFactory) BuildRunner() groups.Runner {
@@ -76,6 +76,16 @@ func Test_NewNodeWithConditionFilter(t *testing.T) {
 			},
 		},
 	}
+	n5 := &corev1.Node{
+		Spec: corev1.NodeSpec{
+			Taints: []corev1.Taint{
+				{
+					Key:    "ToBeDeletedByClusterAutoscaler",
+					Effect: corev1.TaintEffectNoSchedule,
+				},
+			},
+		},
+	}
 	tests := []struct {
 		name       string
 		conditions []kubernetes.SuppliedCondition
@@ -116,6 +126,17 @@ func Test_NewNodeWithConditionFilter(t *testing.T) {
 			nodes: []*corev1.Node{n1, n2, n3, n4},
 			want:  []*corev1.Node{n1, n3},
 		},
+		{
+			name: "matching trigger taint",
+			conditions: []kubernetes.SuppliedCondition{
+				{
+					Type:  kubernetes.TriggerTaintConditionID,
+					Taint: "ToBeDeletedByClusterAutoscaler",
+				},
+			},
+			nodes: []*corev1.Node{n1, n2, n3, n4, n5},
+			want:  []*corev1.Node{n5},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -2,6 +2,7 @@ package filters
 
 import (
 	"errors"
+	"time"
 
 	"github.com/go-logr/logr"
 	"k8s.io/utils/clock"
@@ -11,6 +12,7 @@ import (
 	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/analyser"
 	"github.com/planetlabs/draino/internal/kubernetes/drain"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
 	"github.com/planetlabs/draino/internal/protector"
 )
 
@@ -34,7 +36,27 @@ type Config struct {
 	eventRecorder          kubernetes.EventRecorder
 
 	// With defaults
-	clock clock.Clock
+	clock                   clock.Clock
+	ignoreManuallyCordoned  bool
+	respectTopologySpread   bool
+	minHealthyNodesPerGroup int
+	recordonCooldown        time.Duration
+	recordonPersistence     time.Duration
+	minInScopeAge           time.Duration
+	configLabelKey          string
+	scopeEntryTimePrefix    string
+	maxPods                 int
+	podIndexer              index.PodIndexer
+
+	// softDrainBuffer relaxes the drain buffer while the cluster has spare capacity, it is disabled if pendingPodsPerNode is nil
+	pendingPodsPerNode    func() (float64, bool)
+	maxPendingPodsPerNode float64
+	softDrainBufferFactor float64
+	// withoutDrainBuffer removes the drain buffer filter, the drains are spaced by the surge budget of the drain runner instead
+	withoutDrainBuffer bool
+
+	// Optional
+	statefulSetWithoutStoragePodFilter kubernetes.PodFilterFunc
 }
 
 // NewConfig returns a pointer to a new drain runner configuration
@@ -85,6 +107,9 @@ func (conf *Config) Validate() error {
 	if conf.pvcProtector == nil {
 		return errors.New("pvc protector is not set")
 	}
+	if (conf.maxPods > 0 || conf.respectTopologySpread) && conf.podIndexer == nil {
+		return errors.New("pod indexer is not set")
+	}
 
 	return nil
 }
@@ -133,6 +158,25 @@ func WithPodFilterFunc(f kubernetes.PodFilterFunc) WithOption {
 	}
 }
 
+// WithMinInScopeAge configures the minimum duration a node has to carry the configuration in its scope label before being candidate.
+// configLabelKey is the key of the scope label maintained by the scope observer, the configuration name appended to entryTimeAnnotationKeyPrefix
+// is the key of the annotation holding the time at which the node entered the scope. 0 disables the check.
+func WithMinInScopeAge(minAge time.Duration, configLabelKey, entryTimeAnnotationKeyPrefix string) WithOption {
+	return func(conf *Config) {
+		conf.minInScopeAge = minAge
+		conf.configLabelKey = configLabelKey
+		conf.scopeEntryTimePrefix = entryTimeAnnotationKeyPrefix
+	}
+}
+
+// WithStatefulSetWithoutStoragePodFilter configures the pod filter used to keep the nodes without local storage hosting StatefulSet pods
+// out of candidates. Nil disables the filter.
+func WithStatefulSetWithoutStoragePodFilter(f kubernetes.PodFilterFunc) WithOption {
+	return func(conf *Config) {
+		conf.statefulSetWithoutStoragePodFilter = f
+	}
+}
+
 func WithStabilityPeriodChecker(checker analyser.StabilityPeriodChecker) WithOption {
 	return func(conf *Config) {
 		conf.stabilityPeriodChecker = checker
@@ -169,3 +213,60 @@ func WithPVCProtector(pvcProtector protector.PVCProtector) WithOption {
 
 	}
 }
+
+// WithMinHealthyNodesPerGroup excludes the nodes of the nodegroups having that many healthy nodes or less. Zero disables the filter.
+func WithMinHealthyNodesPerGroup(min int) WithOption {
+	return func(conf *Config) {
+		conf.minHealthyNodesPerGroup = min
+	}
+}
+
+// WithRecordonCooldown prevents a node from becoming candidate again during that period after it recovered from its condition,
+// unless its condition persisted for longer than persistence. Zero disables the filter.
+func WithRecordonCooldown(cooldown, persistence time.Duration) WithOption {
+	return func(conf *Config) {
+		conf.recordonCooldown = cooldown
+		conf.recordonPersistence = persistence
+	}
+}
+
+// WithRespectTopologySpread excludes the nodes whose drain would leave a topology domain without replica or break the max skew
+// of the topology spread constraints of their pods. The pods are listed with the pod indexer.
+func WithRespectTopologySpread(respect bool, podIndexer index.PodIndexer) WithOption {
+	return func(conf *Config) {
+		conf.respectTopologySpread = respect
+		conf.podIndexer = podIndexer
+	}
+}
+
+// WithIgnoreManuallyCordoned excludes the nodes cordoned by someone else than draino
+func WithIgnoreManuallyCordoned(ignore bool) WithOption {
+	return func(conf *Config) {
+		conf.ignoreManuallyCordoned = ignore
+	}
+}
+
+// WithoutDrainBuffer removes the drain buffer from the filters, when the drains are spaced by a surge budget instead
+func WithoutDrainBuffer(without bool) WithOption {
+	return func(conf *Config) {
+		conf.withoutDrainBuffer = without
+	}
+}
+
+// WithSoftDrainBuffer relaxes the drain buffer to the given factor while there are at most maxPendingPodsPerNode Pending pods per node.
+// A nil pendingPodsPerNode function disables the relaxation.
+func WithSoftDrainBuffer(pendingPodsPerNode func() (float64, bool), maxPendingPodsPerNode float64, factor float64) WithOption {
+	return func(conf *Config) {
+		conf.pendingPodsPerNode = pendingPodsPerNode
+		conf.maxPendingPodsPerNode = maxPendingPodsPerNode
+		conf.softDrainBufferFactor = factor
+	}
+}
+
+// WithMaxPods defers the nodes running more than maxPods pods, counted with the pod indexer. Zero disables the filter.
+func WithMaxPods(maxPods int, podIndexer index.PodIndexer) WithOption {
+	return func(conf *Config) {
+		conf.maxPods = maxPods
+		conf.podIndexer = podIndexer
+	}
+}
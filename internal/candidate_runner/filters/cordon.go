@@ -0,0 +1,23 @@
+package filters
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+// NewManuallyCordonedFilter filters out the nodes that were cordoned by someone else than draino.
+// Draino never cordons a node without setting its NLA taint, so an unschedulable node without that taint was cordoned manually.
+// The nodes quarantined after repeated drain failures are cordoned by draino too, they are rejected earlier by the quarantined filter.
+func NewManuallyCordonedFilter() Filter {
+	return FilterFromFunction("manually_cordoned",
+		func(ctx context.Context, n *v1.Node) bool {
+			if !n.Spec.Unschedulable {
+				return true
+			}
+			_, hasNLATaint := k8sclient.GetNLATaint(n)
+			return hasNLATaint
+		})
+}
@@ -0,0 +1,48 @@
+package filters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func TestNewManuallyCordonedFilter(t *testing.T) {
+	schedulable := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "schedulable"}}
+	drainoCordoned := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{Name: "draino-cordoned"},
+		Spec: corev1.NodeSpec{
+			Unschedulable: true,
+			Taints:        []corev1.Taint{*k8sclient.CreateNLATaint(k8sclient.TaintDraining, time.Now())},
+		},
+	}
+	adminCordoned := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{Name: "admin-cordoned"},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}
+	tests := []struct {
+		name     string
+		nodes    []*corev1.Node
+		wantKeep []*corev1.Node
+	}{
+		{
+			name:     "skip the nodes cordoned by an admin",
+			nodes:    []*corev1.Node{schedulable, drainoCordoned, adminCordoned},
+			wantKeep: []*corev1.Node{schedulable, drainoCordoned},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewManuallyCordonedFilter()
+			gotKeep := f.Filter(context.Background(), tt.nodes)
+			if !reflect.DeepEqual(gotKeep, tt.wantKeep) {
+				t.Errorf("manuallyCordoned() gotKeep = %v, want %v", gotKeep, tt.wantKeep)
+			}
+		})
+	}
+}
@@ -14,7 +14,6 @@ func NewDrainBufferFilter(drainBuffer drainbuffer.DrainBuffer, clock clock.Clock
 		"drain_buffer",
 		func(ctx context.Context, n *v1.Node) (bool, string) {
 			nextDrain, err := drainBuffer.NextDrain(groupKeyGetter.GetGroupKey(n))
-
 			if err != nil {
 				return false, "drain buffer was not initialized yet"
 			}
@@ -23,7 +22,17 @@ func NewDrainBufferFilter(drainBuffer drainbuffer.DrainBuffer, clock clock.Clock
 				return true, ""
 			}
 
+			// the bypass is consumed by the drain runner when the node starts draining
+			if drainbuffer.GetBypassCount(n) > 0 {
+				return true, ""
+			}
+
 			return false, "drain buffer is not respected"
 		},
 	)
 }
+
+// NewSoftDrainBufferFilter relaxes the drain buffer while the cluster has spare capacity, see drainbuffer.NewSoftDrainBuffer
+func NewSoftDrainBufferFilter(drainBuffer drainbuffer.DrainBuffer, clock clock.Clock, groupKeyGetter groups.GroupKeyGetter, pendingPodsPerNode func() (float64, bool), maxPendingPodsPerNode float64, factor float64) Filter {
+	return NewDrainBufferFilter(drainbuffer.NewSoftDrainBuffer(drainBuffer, pendingPodsPerNode, maxPendingPodsPerNode, factor), clock, groupKeyGetter)
+}
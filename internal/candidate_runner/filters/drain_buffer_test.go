@@ -0,0 +1,94 @@
+package filters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
+	"github.com/planetlabs/draino/internal/groups"
+)
+
+type fakeDrainBuffer struct {
+	drainbuffer.DrainBuffer
+	nextDrain   time.Time
+	lastDrain   time.Time
+	drainBuffer time.Duration
+}
+
+func (f *fakeDrainBuffer) NextDrain(groups.GroupKey) (time.Time, error) {
+	return f.nextDrain, nil
+}
+
+func (f *fakeDrainBuffer) LastDrain(groups.GroupKey) (time.Time, time.Duration, error) {
+	return f.lastDrain, f.drainBuffer, nil
+}
+
+type fakeGroupKeyGetter struct {
+	groups.GroupKeyGetter
+}
+
+func (fakeGroupKeyGetter) GetGroupKey(*corev1.Node) groups.GroupKey {
+	return "group"
+}
+
+func TestDrainBufferFilter(t *testing.T) {
+	now := time.Now()
+	newNode := func(bypass string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+		if bypass != "" {
+			node.Annotations = map[string]string{drainbuffer.BypassAnnotation: bypass}
+		}
+		return node
+	}
+	tests := []struct {
+		name      string
+		nextDrain time.Time
+		node      *corev1.Node
+		want      bool
+	}{
+		{name: "drain buffer respected", nextDrain: now.Add(-time.Minute), node: newNode(""), want: true},
+		{name: "drain buffer not respected", nextDrain: now.Add(time.Minute), node: newNode(""), want: false},
+		{name: "bypass left", nextDrain: now.Add(time.Minute), node: newNode("2"), want: true},
+		{name: "no bypass left", nextDrain: now.Add(time.Minute), node: newNode("0"), want: false},
+		{name: "invalid bypass", nextDrain: now.Add(time.Minute), node: newNode("twice"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewDrainBufferFilter(&fakeDrainBuffer{nextDrain: tt.nextDrain}, testingclock.NewFakeClock(now), fakeGroupKeyGetter{})
+			assert.Equal(t, tt.want, f.FilterNode(context.Background(), tt.node).Keep)
+		})
+	}
+}
+
+func TestSoftDrainBufferFilter(t *testing.T) {
+	now := time.Now()
+	// the group was drained 5 minutes ago with a 10 minutes drain buffer
+	buffer := &fakeDrainBuffer{lastDrain: now.Add(-5 * time.Minute), drainBuffer: 10 * time.Minute, nextDrain: now.Add(5 * time.Minute)}
+	tests := []struct {
+		name               string
+		buffer             *fakeDrainBuffer
+		pendingPodsPerNode float64
+		unknown            bool
+		factor             float64
+		want               bool
+	}{
+		{name: "high capacity relaxes the drain buffer", buffer: buffer, pendingPodsPerNode: 0.1, factor: 0.25, want: true},
+		{name: "high capacity with a factor too high", buffer: buffer, pendingPodsPerNode: 0.1, factor: 0.75, want: false},
+		{name: "low capacity keeps the drain buffer strict", buffer: buffer, pendingPodsPerNode: 2, factor: 0.25, want: false},
+		{name: "group never drained", buffer: &fakeDrainBuffer{}, pendingPodsPerNode: 0.1, factor: 0.25, want: true},
+		{name: "unknown capacity keeps the drain buffer strict", buffer: buffer, unknown: true, factor: 0.25, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pendingPodsPerNode := func() (float64, bool) { return tt.pendingPodsPerNode, !tt.unknown }
+			f := NewSoftDrainBufferFilter(tt.buffer, testingclock.NewFakeClock(now), fakeGroupKeyGetter{}, pendingPodsPerNode, 0.5, tt.factor)
+			assert.Equal(t, tt.want, f.FilterNode(context.Background(), &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}).Keep)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package filters
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+// drainNowBypassFilter keeps the nodes that have the drain-now annotation, the other nodes are evaluated by the wrapped filter
+type drainNowBypassFilter struct {
+	filter   Filter
+	drainNow kubernetes.DrainNowConfig
+}
+
+// NewDrainNowBypassFilter wraps a gating filter that must not apply to the nodes requested for an immediate drain
+func NewDrainNowBypassFilter(filter Filter, drainNow kubernetes.DrainNowConfig) Filter {
+	return &drainNowBypassFilter{filter: filter, drainNow: drainNow}
+}
+
+func (d *drainNowBypassFilter) Name() string {
+	return d.filter.Name()
+}
+
+func (d *drainNowBypassFilter) Filter(ctx context.Context, nodes []*v1.Node) (keep []*v1.Node) {
+	others := make([]*v1.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if !d.drainNow.IsRequested(n) {
+			others = append(others, n)
+		}
+	}
+	if len(others) == len(nodes) {
+		return d.filter.Filter(ctx, nodes)
+	}
+
+	kept := map[string]bool{}
+	for _, n := range d.filter.Filter(ctx, others) {
+		kept[n.Name] = true
+	}
+	// preserve the original order of the nodes
+	for _, n := range nodes {
+		if kept[n.Name] || d.drainNow.IsRequested(n) {
+			keep = append(keep, n)
+		}
+	}
+	return keep
+}
+
+func (d *drainNowBypassFilter) FilterNode(ctx context.Context, n *v1.Node) FilterOutput {
+	if !d.drainNow.IsRequested(n) {
+		return d.filter.FilterNode(ctx, n)
+	}
+	return FilterOutput{
+		Keep: true,
+		Checks: []CheckOutput{
+			{
+				FilterName: d.filter.Name(),
+				Keep:       true,
+				Reason:     "bypassed by immediate drain request",
+			},
+		},
+	}
+}
+
+var _ Filter = &drainNowBypassFilter{}
@@ -0,0 +1,28 @@
+package filters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestDrainNowBypassFilter(t *testing.T) {
+	drainNow := map[string]string{kubernetes.DefaultDrainNowAnnotationKey: kubernetes.DrainNowAnnotationValue}
+	n1 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}
+	n2 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n2", Annotations: drainNow}}
+	n3 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n3", Annotations: map[string]string{kubernetes.DefaultDrainNowAnnotationKey: "false"}}}
+
+	gating := FilterFromFunction("gating", func(ctx context.Context, n *corev1.Node) bool { return false })
+	f := NewDrainNowBypassFilter(gating, kubernetes.DrainNowConfig{AnnotationKey: kubernetes.DefaultDrainNowAnnotationKey})
+
+	assert.Equal(t, "gating", f.Name())
+	assert.Equal(t, []*corev1.Node{n2}, f.Filter(context.Background(), []*corev1.Node{n1, n2, n3}))
+	assert.False(t, f.FilterNode(context.Background(), n1).Keep)
+	assert.True(t, f.FilterNode(context.Background(), n2).Keep)
+	assert.False(t, f.FilterNode(context.Background(), n3).Keep)
+}
@@ -0,0 +1,26 @@
+package filters
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+// NewDrainWindowFilter rejects the nodes outside the drain window requested on the node. A node waiting for its window
+// does not hold the candidate slot of its group, and a candidate whose window closes is released.
+// An invalid window is ignored here, it is reported by the drain runner.
+func NewDrainWindowFilter(clock clock.Clock) Filter {
+	return FilterFromFunctionWithReason(
+		"drain_window",
+		func(ctx context.Context, n *corev1.Node) (bool, string) {
+			window, hasWindow, err := kubernetes.GetNodeDrainWindow(n)
+			if err != nil || !hasWindow || window.Contains(clock.Now()) {
+				return true, ""
+			}
+			return false, "outside_drain_window"
+		},
+	)
+}
@@ -0,0 +1,58 @@
+package filters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestNewDrainWindowFilter(t *testing.T) {
+	setClockForTest()
+	newNode := func(window string) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node"}}
+		if window != "" {
+			n.Annotations = map[string]string{kubernetes.DrainWindowAnnotationKey: window}
+		}
+		return n
+	}
+	closedDay := clockInTest.Now().UTC().Add(48 * time.Hour).Weekday().String()[:3]
+
+	tests := []struct {
+		name     string
+		node     *corev1.Node
+		wantKeep bool
+	}{
+		{
+			name:     "no drain window",
+			node:     newNode(""),
+			wantKeep: true,
+		},
+		{
+			name:     "inside the drain window",
+			node:     newNode("Sun-Sat:00:00-24:00"),
+			wantKeep: true,
+		},
+		{
+			name:     "outside the drain window",
+			node:     newNode(closedDay + ":00:00-24:00"),
+			wantKeep: false,
+		},
+		{
+			name:     "invalid drain window",
+			node:     newNode("someday"),
+			wantKeep: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewDrainWindowFilter(clockInTest)
+			assert.Equal(t, tt.wantKeep, filter.FilterNode(context.Background(), tt.node).Keep)
+		})
+	}
+}
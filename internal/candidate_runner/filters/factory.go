@@ -24,16 +24,52 @@ func (factory *FilterFactory) BuildCandidateFilter() Filter {
 		logger: factory.conf.logger.WithName("CandidateFilter"),
 	}
 
+	drainBufferFilter := NewDrainBufferFilter(factory.conf.drainBuffer, factory.conf.clock, factory.conf.groupKeyGetter)
+	if factory.conf.pendingPodsPerNode != nil {
+		drainBufferFilter = NewSoftDrainBufferFilter(factory.conf.drainBuffer, factory.conf.clock, factory.conf.groupKeyGetter, factory.conf.pendingPodsPerNode, factory.conf.maxPendingPodsPerNode, factory.conf.softDrainBufferFactor)
+	}
+
 	f.filters = []Filter{
-		NewNodeWithConditionFilter(factory.conf.globalConfig.SuppliedConditions),
+		// nodes being deleted are removed first, there is no need to run the other filters on them
+		NewNodeTerminatingFilter(),
+		// quarantined nodes are left to humans, whatever their annotations
+		NewQuarantinedNodeFilter(),
+		// the gating filters do not apply to the nodes requested for an immediate drain
+		NewDrainNowBypassFilter(NewNodeWithConditionFilter(factory.conf.globalConfig.SuppliedConditions), factory.conf.globalConfig.DrainNow),
 		NewNodeWithLabelFilter(factory.conf.nodeLabelFilterFunc),
 		NewPodFilter(*factory.conf.logger, factory.conf.podFilterFunc, factory.conf.objectsStore),
 		NewRetryWallFilter(factory.conf.clock, factory.conf.retryWall),
-		NewNodeTerminatingFilter(),
-		NewStabilityPeriodFilter(factory.conf.stabilityPeriodChecker, factory.conf.clock),
-		NewDrainBufferFilter(factory.conf.drainBuffer, factory.conf.clock, factory.conf.groupKeyGetter),
-		NewGlobalBlockerFilter(factory.conf.globalBlocker),
+		NewDrainNowBypassFilter(NewDrainWindowFilter(factory.conf.clock), factory.conf.globalConfig.DrainNow),
+		NewDrainNowBypassFilter(NewStabilityPeriodFilter(factory.conf.stabilityPeriodChecker, factory.conf.clock), factory.conf.globalConfig.DrainNow),
+	}
+	// the drains are spaced by the surge budget of the drain runner instead of the drain buffer
+	if !factory.conf.withoutDrainBuffer {
+		f.filters = append(f.filters, NewDrainNowBypassFilter(drainBufferFilter, factory.conf.globalConfig.DrainNow))
+	}
+	f.filters = append(f.filters,
+		NewDrainNowBypassFilter(NewGlobalBlockerFilter(factory.conf.globalBlocker), factory.conf.globalConfig.DrainNow),
 		NewPVCBoundFilter(factory.conf.pvcProtector, factory.conf.eventRecorder),
+	)
+	if factory.conf.statefulSetWithoutStoragePodFilter != nil {
+		f.filters = append(f.filters, NewStatefulSetWithoutStorageFilter(*factory.conf.logger, factory.conf.statefulSetWithoutStoragePodFilter, factory.conf.objectsStore))
+	}
+	if factory.conf.respectTopologySpread {
+		f.filters = append(f.filters, NewTopologySpreadFilter(*factory.conf.logger, factory.conf.objectsStore, factory.conf.podIndexer))
+	}
+	if factory.conf.ignoreManuallyCordoned {
+		f.filters = append(f.filters, NewManuallyCordonedFilter())
+	}
+	if factory.conf.minHealthyNodesPerGroup > 0 {
+		f.filters = append(f.filters, NewDrainNowBypassFilter(NewMinHealthyNodesPerGroupFilter(factory.conf.objectsStore.Nodes(), factory.conf.minHealthyNodesPerGroup), factory.conf.globalConfig.DrainNow))
+	}
+	if factory.conf.recordonCooldown > 0 {
+		f.filters = append(f.filters, NewDrainNowBypassFilter(NewRecordonCooldownFilter(factory.conf.clock, factory.conf.recordonCooldown, factory.conf.recordonPersistence, factory.conf.globalConfig.SuppliedConditions), factory.conf.globalConfig.DrainNow))
+	}
+	if factory.conf.minInScopeAge > 0 {
+		f.filters = append(f.filters, NewDrainNowBypassFilter(NewMinInScopeAgeFilter(factory.conf.clock, factory.conf.minInScopeAge, factory.conf.configLabelKey, factory.conf.scopeEntryTimePrefix+factory.conf.globalConfig.ConfigName, factory.conf.globalConfig.ConfigName), factory.conf.globalConfig.DrainNow))
+	}
+	if factory.conf.maxPods > 0 {
+		f.filters = append(f.filters, NewDrainNowBypassFilter(NewMaxPodsFilter(factory.conf.podIndexer, factory.conf.maxPods), factory.conf.globalConfig.DrainNow))
 	}
 	return f
 }
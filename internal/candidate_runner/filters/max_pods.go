@@ -0,0 +1,34 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+)
+
+// NewMaxPodsFilter defers the nodes running more than maxPods pods, the dense nodes are drained once their pod count dropped.
+// The pods that already completed are not counted.
+func NewMaxPodsFilter(podIndexer index.PodIndexer, maxPods int) Filter {
+	return FilterFromFunctionWithReason(
+		"max_pods",
+		func(ctx context.Context, n *corev1.Node) (bool, string) {
+			pods, err := podIndexer.GetPodsByNode(ctx, n.Name)
+			if err != nil {
+				return false, "index_error"
+			}
+			count := 0
+			for _, p := range pods {
+				if p.Status.Phase != corev1.PodSucceeded && p.Status.Phase != corev1.PodFailed {
+					count++
+				}
+			}
+			if count > maxPods {
+				return false, fmt.Sprintf("node has %d pods, maximum is %d", count, maxPods)
+			}
+			return true, ""
+		},
+	)
+}
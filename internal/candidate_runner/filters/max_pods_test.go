@@ -0,0 +1,85 @@
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type podsByNodeIndexer struct {
+	pods map[string][]*corev1.Pod
+	err  error
+}
+
+func (i *podsByNodeIndexer) GetPodsByNode(ctx context.Context, nodeName string) ([]*corev1.Pod, error) {
+	return i.pods[nodeName], i.err
+}
+
+func (i *podsByNodeIndexer) GetPodsByPhase(ctx context.Context, phase corev1.PodPhase) ([]*corev1.Pod, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (i *podsByNodeIndexer) GetPodCount(ctx context.Context) (int, error) {
+	return 0, errors.New("not implemented")
+}
+
+func TestMaxPodsFilter(t *testing.T) {
+	newPods := func(count int, phase corev1.PodPhase) []*corev1.Pod {
+		pods := make([]*corev1.Pod, count)
+		for i := range pods {
+			pods[i] = &corev1.Pod{ObjectMeta: v1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i)}, Status: corev1.PodStatus{Phase: phase}}
+		}
+		return pods
+	}
+
+	tests := []struct {
+		name     string
+		pods     []*corev1.Pod
+		err      error
+		wantKeep bool
+	}{
+		{
+			name:     "no pod",
+			wantKeep: true,
+		},
+		{
+			name:     "below the threshold",
+			pods:     newPods(5, corev1.PodRunning),
+			wantKeep: true,
+		},
+		{
+			name:     "at the threshold",
+			pods:     newPods(10, corev1.PodRunning),
+			wantKeep: true,
+		},
+		{
+			name:     "above the threshold",
+			pods:     newPods(11, corev1.PodRunning),
+			wantKeep: false,
+		},
+		{
+			name:     "completed pods are not counted",
+			pods:     append(newPods(8, corev1.PodRunning), newPods(5, corev1.PodSucceeded)...),
+			wantKeep: true,
+		},
+		{
+			name:     "index error",
+			err:      errors.New("index error"),
+			wantKeep: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node"}}
+			indexer := &podsByNodeIndexer{pods: map[string][]*corev1.Pod{node.Name: tt.pods}, err: tt.err}
+			f := NewMaxPodsFilter(indexer, 10)
+			assert.Equal(t, tt.wantKeep, f.FilterNode(context.Background(), node).Keep)
+			assert.Equal(t, tt.wantKeep, len(f.Filter(context.Background(), []*corev1.Node{node})) == 1)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+type nodeGroupRef struct {
+	namespace, name string
+}
+
+func getNodeGroupRef(n *v1.Node) (nodeGroupRef, bool) {
+	ref := nodeGroupRef{namespace: n.Labels[kubernetes.LabelKeyNodeGroupNamespace], name: n.Labels[kubernetes.LabelKeyNodeGroupName]}
+	return ref, ref.name != ""
+}
+
+// minHealthyNodesPerGroupFilter filters out the nodes of the nodegroups that have minHealthyNodes healthy nodes or less.
+// A node is healthy if it is ready, schedulable and not handled by draino yet. The nodes without nodegroup label are kept.
+type minHealthyNodesPerGroupFilter struct {
+	store           kubernetes.NodeStore
+	minHealthyNodes int
+}
+
+var _ Filter = &minHealthyNodesPerGroupFilter{}
+
+func NewMinHealthyNodesPerGroupFilter(store kubernetes.NodeStore, minHealthyNodes int) Filter {
+	return &minHealthyNodesPerGroupFilter{store: store, minHealthyNodes: minHealthyNodes}
+}
+
+func (f *minHealthyNodesPerGroupFilter) Name() string {
+	return "min_healthy_nodes_per_group"
+}
+
+// Filter counts the healthy nodes only once for the whole list
+func (f *minHealthyNodesPerGroupFilter) Filter(ctx context.Context, nodes []*v1.Node) (keep []*v1.Node) {
+	healthyNodes := f.countHealthyNodes()
+	keep = make([]*v1.Node, 0, len(nodes))
+	for _, n := range nodes {
+		if ok, _ := f.check(n, healthyNodes); ok {
+			keep = append(keep, n)
+		}
+	}
+	return
+}
+
+func (f *minHealthyNodesPerGroupFilter) FilterNode(ctx context.Context, n *v1.Node) FilterOutput {
+	keep, reason := f.check(n, f.countHealthyNodes())
+	return FilterOutput{
+		Keep:   keep,
+		Checks: []CheckOutput{{FilterName: f.Name(), Keep: keep, Reason: reason}},
+	}
+}
+
+func (f *minHealthyNodesPerGroupFilter) check(n *v1.Node, healthyNodes map[nodeGroupRef]int) (bool, string) {
+	ref, ok := getNodeGroupRef(n)
+	if !ok {
+		return true, ""
+	}
+	healthy := healthyNodes[ref]
+	// the node already picked by draino is not counted by countHealthyNodes, it is counted as before its NLA taint so that it is not filtered out by its own drain
+	if !isHealthyNode(n) && isHealthyBeforeDraino(n) {
+		healthy++
+	}
+	if healthy <= f.minHealthyNodes {
+		return false, fmt.Sprintf("nodegroup %s/%s has %d healthy nodes, minimum is %d", ref.namespace, ref.name, healthy, f.minHealthyNodes)
+	}
+	return true, ""
+}
+
+func (f *minHealthyNodesPerGroupFilter) countHealthyNodes() map[nodeGroupRef]int {
+	healthyNodes := map[nodeGroupRef]int{}
+	for _, n := range f.store.ListNodes() {
+		if ref, ok := getNodeGroupRef(n); ok && isHealthyNode(n) {
+			healthyNodes[ref]++
+		}
+	}
+	return healthyNodes
+}
+
+func isHealthyNode(n *v1.Node) bool {
+	if n.Spec.Unschedulable {
+		return false
+	}
+	if _, hasNLATaint := k8sclient.GetNLATaint(n); hasNLATaint {
+		return false
+	}
+	ready, err := kubernetes.GetReadinessState(n)
+	return err == nil && ready
+}
+
+// isHealthyBeforeDraino tells if the node was healthy before draino tainted it, draino cordons the nodes it drains
+func isHealthyBeforeDraino(n *v1.Node) bool {
+	if _, hasNLATaint := k8sclient.GetNLATaint(n); !hasNLATaint {
+		return false
+	}
+	ready, err := kubernetes.GetReadinessState(n)
+	return err == nil && ready
+}
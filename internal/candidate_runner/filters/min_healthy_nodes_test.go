@@ -0,0 +1,75 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func TestNewMinHealthyNodesPerGroupFilter(t *testing.T) {
+	newNode := func(group string, i int, ready bool, taintValue k8sclient.DrainTaintValue) *corev1.Node {
+		node := &corev1.Node{
+			ObjectMeta: v1.ObjectMeta{Name: fmt.Sprintf("%s-%d", group, i), Labels: map[string]string{}},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+		}
+		if group != "" {
+			node.Labels[kubernetes.LabelKeyNodeGroupName] = group
+			node.Labels[kubernetes.LabelKeyNodeGroupNamespace] = "team"
+		}
+		if !ready {
+			node.Status.Conditions[0].Status = corev1.ConditionFalse
+		}
+		if taintValue != "" {
+			node.Spec.Taints = []corev1.Taint{*k8sclient.CreateNLATaint(taintValue, time.Now())}
+		}
+		return node
+	}
+
+	// group "below": 1 healthy node, a not ready one and a candidate
+	below := []*corev1.Node{newNode("below", 0, true, ""), newNode("below", 1, false, ""), newNode("below", 2, true, k8sclient.TaintDrainCandidate)}
+	// group "at": 2 healthy nodes
+	at := []*corev1.Node{newNode("at", 0, true, ""), newNode("at", 1, true, "")}
+	// group "above": 3 healthy nodes
+	above := []*corev1.Node{newNode("above", 0, true, ""), newNode("above", 1, true, ""), newNode("above", 2, true, "")}
+	// group "draining": 2 healthy nodes and a candidate that was healthy before its NLA taint
+	draining := []*corev1.Node{newNode("draining", 0, true, ""), newNode("draining", 1, true, ""), newNode("draining", 2, true, k8sclient.TaintDrainCandidate)}
+	draining[2].Spec.Unschedulable = true
+	noGroup := newNode("", 0, true, "")
+
+	var objects []runtime.Object
+	var nodes []*corev1.Node
+	for _, group := range [][]*corev1.Node{below, at, above, draining, {noGroup}} {
+		for _, n := range group {
+			objects = append(objects, n)
+			nodes = append(nodes, n)
+		}
+	}
+
+	store, closingFunc := kubernetes.RunStoreForTest(context.Background(), fake.NewSimpleClientset(objects...))
+	defer closingFunc()
+	f := NewMinHealthyNodesPerGroupFilter(store.Nodes(), 2)
+
+	assert.Equal(t, append(above, draining[2], noGroup), f.Filter(context.Background(), nodes))
+
+	output := f.FilterNode(context.Background(), at[0])
+	assert.False(t, output.Keep)
+	assert.Equal(t, "nodegroup team/at has 2 healthy nodes, minimum is 2", output.Checks[0].Reason)
+	assert.True(t, f.FilterNode(context.Background(), above[0]).Keep)
+
+	// the re-check of the candidate by the drain runner does not count it as unhealthy
+	assert.True(t, f.FilterNode(context.Background(), draining[2]).Keep)
+	assert.False(t, f.FilterNode(context.Background(), draining[0]).Keep)
+	output = f.FilterNode(context.Background(), below[2])
+	assert.False(t, output.Keep)
+	assert.Equal(t, "nodegroup team/below has 2 healthy nodes, minimum is 2", output.Checks[0].Reason)
+}
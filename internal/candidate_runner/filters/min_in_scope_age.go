@@ -0,0 +1,42 @@
+package filters
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+)
+
+// NewMinInScopeAgeFilter rejects the nodes that have not carried the configuration name in their scope label for at least minAge.
+// The time at which the node entered the scope is read from the annotation maintained by the scope observer, so that it survives the restarts.
+// The nodes without the annotation are deferred until the scope observer sets it.
+func NewMinInScopeAgeFilter(clock clock.Clock, minAge time.Duration, configLabelKey, entryTimeAnnotationKey, configName string) Filter {
+	return FilterFromFunctionWithReason(
+		"min_in_scope_age",
+		func(ctx context.Context, n *corev1.Node) (bool, string) {
+			if !hasConfiguration(n, configLabelKey, configName) {
+				return false, "not_in_scope"
+			}
+			since, err := time.Parse(time.RFC3339, n.Annotations[entryTimeAnnotationKey])
+			if err != nil {
+				return false, "unknown_scope_entry_time"
+			}
+			if clock.Now().Sub(since) < minAge {
+				return false, "recently_in_scope"
+			}
+			return true, ""
+		},
+	)
+}
+
+// hasConfiguration returns true if the configuration is part of the dot separated list of configurations in the label
+func hasConfiguration(n *corev1.Node, configLabelKey, configName string) bool {
+	for _, config := range strings.Split(n.Labels[configLabelKey], ".") {
+		if config == configName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package filters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestMinInScopeAgeFilter(t *testing.T) {
+	const labelKey = "node-lifecycle.datadoghq.com/draino-configuration"
+	const annotationKey = "draino/scope_entry_time.draino1"
+	now := time.Now().Truncate(time.Second)
+	newNode := func(name, configs string, entryTime time.Time) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{labelKey: configs}, Annotations: map[string]string{}}}
+		if !entryTime.IsZero() {
+			n.Annotations[annotationKey] = entryTime.Format(time.RFC3339)
+		}
+		return n
+	}
+	clock := testclock.NewFakeClock(now)
+	filter := NewMinInScopeAgeFilter(clock, 10*time.Minute, labelKey, annotationKey, "draino1")
+
+	older := newNode("older", "other.draino1", now.Add(-10*time.Minute))
+	fresh := newNode("fresh", "draino1", now.Add(-time.Minute))
+	assert.Equal(t, []*corev1.Node{older}, filter.Filter(context.Background(), []*corev1.Node{older, fresh}), "the older node is eligible, the fresh one is deferred")
+
+	output := filter.FilterNode(context.Background(), fresh)
+	assert.False(t, output.Keep)
+	assert.Equal(t, "recently_in_scope", output.Checks[0].Reason)
+
+	unknown := newNode("unknown", "draino1", time.Time{})
+	output = filter.FilterNode(context.Background(), unknown)
+	assert.False(t, output.Keep, "the scope observer did not record the entry time yet")
+	assert.Equal(t, "unknown_scope_entry_time", output.Checks[0].Reason)
+
+	outOfScope := newNode("older", "other", now.Add(-time.Hour))
+	assert.Empty(t, filter.Filter(context.Background(), []*corev1.Node{outOfScope}), "not in scope")
+
+	// a new filter, as after a restart, keeps the age of the nodes
+	restarted := NewMinInScopeAgeFilter(testclock.NewFakeClock(now), 10*time.Minute, labelKey, annotationKey, "draino1")
+	assert.True(t, restarted.FilterNode(context.Background(), older).Keep)
+}
@@ -19,7 +19,7 @@ func NewPVCBoundFilter(protector protector.PVCProtector, eventRecorder kubernete
 			}
 			if len(podsAssociatedWithPV) > 0 {
 				pods := strings.Join(utils.GetPodNames(podsAssociatedWithPV), "; ")
-				eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, kubernetes.EventReasonPendingPodWithLocalPV, "Pod(s) "+pods+" associated with local PV on that node")
+				eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, kubernetes.EventReasonPendingPodWithLocalPV.String(), "Pod(s) "+pods+" associated with local PV on that node")
 				return false, "pvcProtection triggered for pods: " + pods
 			}
 			return true, ""
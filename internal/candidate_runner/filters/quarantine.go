@@ -0,0 +1,17 @@
+package filters
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+// NewQuarantinedNodeFilter filters out the nodes quarantined after repeated drain failures, they wait for a human investigation
+func NewQuarantinedNodeFilter() Filter {
+	return FilterFromFunction("quarantined",
+		func(ctx context.Context, n *v1.Node) bool {
+			return !kubernetes.IsQuarantined(n)
+		})
+}
@@ -0,0 +1,45 @@
+package filters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestNewQuarantinedNodeFilter(t *testing.T) {
+	n1, n2 := &corev1.Node{}, &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{
+			Labels: map[string]string{kubernetes.QuarantinedLabelKey: kubernetes.QuarantinedLabelValue},
+		},
+	}
+	n3 := &corev1.Node{
+		ObjectMeta: v1.ObjectMeta{
+			Labels: map[string]string{kubernetes.QuarantinedLabelKey: "false"},
+		},
+	}
+	tests := []struct {
+		name     string
+		nodes    []*corev1.Node
+		wantKeep []*corev1.Node
+	}{
+		{
+			name:     "filter out quarantined nodes",
+			nodes:    []*corev1.Node{n1, n2, n3},
+			wantKeep: []*corev1.Node{n1, n3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewQuarantinedNodeFilter()
+			gotKeep := f.Filter(context.Background(), tt.nodes)
+			if !reflect.DeepEqual(gotKeep, tt.wantKeep) {
+				t.Errorf("quarantined filter gotKeep = %v, want %v", gotKeep, tt.wantKeep)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package filters
+
+import (
+	"context"
+	"time"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+)
+
+// NewRecordonCooldownFilter rejects the nodes that recovered from their condition less than cooldown ago,
+// unless one of their offending conditions has been present for longer than persistence, which is longer than the cooldown.
+// A condition that was cleared and came back within the cooldown is considered as flapping.
+func NewRecordonCooldownFilter(clock clock.Clock, cooldown, persistence time.Duration, conditions []kubernetes.SuppliedCondition) Filter {
+	return FilterFromFunctionWithReason(
+		"recordon_cooldown",
+		func(ctx context.Context, n *corev1.Node) (bool, string) {
+			lastUncordon, ok := kubernetes.GetLastUncordonTime(n)
+			if !ok {
+				return true, ""
+			}
+			now := clock.Now()
+			if now.Sub(lastUncordon) >= cooldown {
+				return true, ""
+			}
+			for _, c := range kubernetes.GetNodeOffendingConditions(n, conditions) {
+				for _, nc := range n.Status.Conditions {
+					if nc.Type == c.Type && nc.Status == c.Status && now.Sub(nc.LastTransitionTime.Time) >= persistence {
+						return true, ""
+					}
+				}
+			}
+			return false, "in_cooldown"
+		},
+	)
+}
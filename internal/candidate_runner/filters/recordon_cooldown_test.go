@@ -0,0 +1,73 @@
+package filters
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestNewRecordonCooldownFilter(t *testing.T) {
+	setClockForTest()
+	now := clockInTest.Now()
+	conditions := []kubernetes.SuppliedCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}}
+	newNode := func(lastUncordon *time.Time, conditionSince time.Time) *corev1.Node {
+		n := &corev1.Node{
+			ObjectMeta: v1.ObjectMeta{Name: "node", Annotations: map[string]string{}},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{
+					{Type: "KernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: v1.Time{Time: conditionSince}},
+				},
+			},
+		}
+		if lastUncordon != nil {
+			n.Annotations[kubernetes.LastUncordonAnnotationKey] = lastUncordon.Format(time.RFC3339)
+		}
+		return n
+	}
+	uncordonedRecently := now.Add(-10 * time.Minute)
+	uncordonedLongAgo := now.Add(-2 * time.Hour)
+
+	tests := []struct {
+		name     string
+		node     *corev1.Node
+		wantKeep bool
+	}{
+		{
+			name:     "never uncordoned",
+			node:     newNode(nil, now.Add(-time.Minute)),
+			wantKeep: true,
+		},
+		{
+			name:     "flapping condition within the cooldown",
+			node:     newNode(&uncordonedRecently, now.Add(-5*time.Minute)),
+			wantKeep: false,
+		},
+		{
+			name:     "condition present for longer than the cooldown but not persistent",
+			node:     newNode(&uncordonedRecently, now.Add(-90*time.Minute)),
+			wantKeep: false,
+		},
+		{
+			name:     "persistent condition within the cooldown",
+			node:     newNode(&uncordonedRecently, now.Add(-3*time.Hour)),
+			wantKeep: true,
+		},
+		{
+			name:     "cooldown elapsed",
+			node:     newNode(&uncordonedLongAgo, now.Add(-time.Minute)),
+			wantKeep: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewRecordonCooldownFilter(clockInTest, time.Hour, 2*time.Hour, conditions)
+			assert.Equal(t, tt.wantKeep, filter.FilterNode(context.Background(), tt.node).Keep)
+		})
+	}
+}
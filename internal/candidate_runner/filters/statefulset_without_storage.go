@@ -0,0 +1,37 @@
+package filters
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+// NewStatefulSetWithoutStorageFilter keeps the draino v1 behavior: a node without local storage (see kubernetes.IsNodeWithoutLocalStorage)
+// is not candidate while it hosts a pod of a StatefulSet. The podFilter decides which pods are blocking, it usually ignores the completed and opted-in pods.
+func NewStatefulSetWithoutStorageFilter(logger logr.Logger, podFilter kubernetes.PodFilterFunc, objectsStore kubernetes.RuntimeObjectStore) Filter {
+	return FilterFromFunctionWithReason("statefulset_without_storage",
+		func(ctx context.Context, n *v1.Node) (bool, string) {
+			if !kubernetes.IsNodeWithoutLocalStorage(n) {
+				return true, ""
+			}
+			pods, err := objectsStore.Pods().ListPodsForNode(n.Name)
+			if err != nil {
+				logger.Error(err, "failed to list pod for node", "node", n.Name)
+				return false, "store_error"
+			}
+			for _, pod := range pods {
+				ok, reason, err := podFilter(*pod)
+				if err != nil {
+					logger.Error(err, "failed to run pod filter", "node", n.Name, "pod", pod.Name, "namespace", pod.Namespace)
+					return false, "filter_error"
+				}
+				if !ok {
+					return false, reason
+				}
+			}
+			return true, ""
+		})
+}
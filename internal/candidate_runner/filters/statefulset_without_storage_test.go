@@ -0,0 +1,84 @@
+package filters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestStatefulSetWithoutStorageFilter(t *testing.T) {
+	newNode := func(name string, labels map[string]string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	newPod := func(name, node, ownerKind string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{
+				Name:            name,
+				Namespace:       "ns",
+				OwnerReferences: []v1.OwnerReference{{Kind: ownerKind, Name: "owner", Controller: func() *bool { b := true; return &b }()}},
+			},
+			Spec:   corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	withoutStorage := map[string]string{"nodegroups.datadoghq.com/local-storage": "false"}
+	withStorage := map[string]string{"nodegroups.datadoghq.com/local-storage": "true"}
+	lifecycleEnabled := map[string]string{"nodegroups.datadoghq.com/local-storage": "false", "node-lifecycle.datadoghq.com/enabled": "true"}
+
+	tests := []struct {
+		name    string
+		node    *corev1.Node
+		pod     *corev1.Pod
+		wantOut bool
+	}{
+		{
+			name:    "sts pod on node without storage",
+			node:    newNode("n1", withoutStorage),
+			pod:     newPod("p1", "n1", "StatefulSet", corev1.PodRunning),
+			wantOut: true,
+		},
+		{
+			name: "sts pod on node with storage",
+			node: newNode("n1", withStorage),
+			pod:  newPod("p1", "n1", "StatefulSet", corev1.PodRunning),
+		},
+		{
+			name: "sts pod on node without storage managed by node lifecycle",
+			node: newNode("n1", lifecycleEnabled),
+			pod:  newPod("p1", "n1", "StatefulSet", corev1.PodRunning),
+		},
+		{
+			name: "replicaset pod on node without storage",
+			node: newNode("n1", withoutStorage),
+			pod:  newPod("p1", "n1", "ReplicaSet", corev1.PodRunning),
+		},
+		{
+			name: "completed sts pod on node without storage",
+			node: newNode("n1", withoutStorage),
+			pod:  newPod("p1", "n1", "StatefulSet", corev1.PodSucceeded),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, closingFunc := kubernetes.RunStoreForTest(context.Background(), fake.NewSimpleClientset([]runtime.Object{tt.node, tt.pod}...))
+			defer closingFunc()
+			podFilter := kubernetes.NewPodFiltersIgnoreCompletedPods(kubernetes.NewPodFiltersNoStatefulSetOnNodeWithoutDisk(store))
+			f := NewStatefulSetWithoutStorageFilter(zapr.NewLogger(zap.NewNop()), podFilter, store)
+			got := f.Filter(context.Background(), []*corev1.Node{tt.node})
+			if tt.wantOut {
+				assert.Empty(t, got)
+			} else {
+				assert.Equal(t, []*corev1.Node{tt.node}, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package filters
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+)
+
+// NewTopologySpreadFilter filters out the nodes hosting pods with topology spread constraints when draining the node would
+// leave the topology domain of the node without any matching pod, or would make the skew between the domains exceed maxSkew.
+// The matching pods of the other nodes are counted in their own domain, the pods of the drained node are removed from its domain.
+// Like the scheduler, only the nodes matching the node affinity of the pod are counted unless its nodeAffinityPolicy is Ignore,
+// and the skew is not checked while the number of domains is below minDomains. The hostname constraints are ignored: their
+// domain disappears with the node, and the evicted pods are rescheduled in the remaining domains.
+func NewTopologySpreadFilter(logger logr.Logger, objectsStore kubernetes.RuntimeObjectStore, podIndexer index.PodIndexer) Filter {
+	return FilterFromFunctionWithReason("topology_spread",
+		func(ctx context.Context, n *v1.Node) (bool, string) {
+			pods, err := podIndexer.GetPodsByNode(ctx, n.Name)
+			if err != nil {
+				logger.Error(err, "failed to list pod for node", "node", n.Name)
+				return false, "index_error"
+			}
+			for _, pod := range pods {
+				if isTerminatedPod(pod) {
+					continue
+				}
+				for _, constraint := range pod.Spec.TopologySpreadConstraints {
+					ok, reason, err := checkTopologySpreadConstraint(ctx, objectsStore, podIndexer, n, pod, constraint)
+					if err != nil {
+						logger.Error(err, "failed to check topology spread constraint", "node", n.Name, "pod", pod.Name, "namespace", pod.Namespace)
+						return false, "topology_error"
+					}
+					if !ok {
+						return false, reason
+					}
+				}
+			}
+			return true, ""
+		})
+}
+
+func checkTopologySpreadConstraint(ctx context.Context, objectsStore kubernetes.RuntimeObjectStore, podIndexer index.PodIndexer, node *v1.Node, pod *v1.Pod, constraint v1.TopologySpreadConstraint) (bool, string, error) {
+	if constraint.TopologyKey == v1.LabelHostname {
+		return true, "", nil
+	}
+	domain, ok := node.Labels[constraint.TopologyKey]
+	if !ok {
+		return true, "", nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+	if err != nil {
+		return false, "", err
+	}
+	honorNodeAffinity := constraint.NodeAffinityPolicy == nil || *constraint.NodeAffinityPolicy == v1.NodeInclusionPolicyHonor
+
+	// count the matching pods per domain, ignoring the pods of the drained node
+	counts := map[string]int{domain: 0}
+	for _, other := range objectsStore.Nodes().ListNodes() {
+		otherDomain, ok := other.Labels[constraint.TopologyKey]
+		if !ok || other.Name == node.Name {
+			continue
+		}
+		if honorNodeAffinity && !matchesNodeAffinity(pod, other) {
+			continue
+		}
+		if _, ok := counts[otherDomain]; !ok {
+			counts[otherDomain] = 0
+		}
+		otherPods, err := podIndexer.GetPodsByNode(ctx, other.Name)
+		if err != nil {
+			return false, "", err
+		}
+		for _, p := range otherPods {
+			if p.Namespace == pod.Namespace && !isTerminatedPod(p) && selector.Matches(labels.Set(p.Labels)) {
+				counts[otherDomain]++
+			}
+		}
+	}
+
+	if counts[domain] == 0 {
+		return false, fmt.Sprintf("pod %s/%s would leave no replica in %s=%s", pod.Namespace, pod.Name, constraint.TopologyKey, domain), nil
+	}
+	// below minDomains the scheduler considers the global minimum to be zero, the drain cannot increase the skew
+	if constraint.MinDomains != nil && len(counts) < int(*constraint.MinDomains) {
+		return true, "", nil
+	}
+	for otherDomain, count := range counts {
+		if count-counts[domain] > int(constraint.MaxSkew) {
+			return false, fmt.Sprintf("pod %s/%s would exceed the max skew %d between %s=%s and %s=%s", pod.Namespace, pod.Name, constraint.MaxSkew, constraint.TopologyKey, domain, constraint.TopologyKey, otherDomain), nil
+		}
+	}
+	return true, "", nil
+}
+
+// matchesNodeAffinity tells if the node matches the node selector and the required node affinity of the pod.
+// The field selectors of the node affinity are ignored.
+func matchesNodeAffinity(pod *v1.Pod, node *v1.Node) bool {
+	if !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil || pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	// the terms are ORed
+	for _, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchesNodeSelectorTerm(term, node) {
+			return true
+		}
+	}
+	return false
+}
+
+var nodeSelectorOperators = map[v1.NodeSelectorOperator]selection.Operator{
+	v1.NodeSelectorOpIn:           selection.In,
+	v1.NodeSelectorOpNotIn:        selection.NotIn,
+	v1.NodeSelectorOpExists:       selection.Exists,
+	v1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	v1.NodeSelectorOpGt:           selection.GreaterThan,
+	v1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+func matchesNodeSelectorTerm(term v1.NodeSelectorTerm, node *v1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		// an empty term matches no objects
+		return false
+	}
+	selector := labels.NewSelector()
+	for _, expr := range term.MatchExpressions {
+		op, ok := nodeSelectorOperators[expr.Operator]
+		if !ok {
+			return false
+		}
+		requirement, err := labels.NewRequirement(expr.Key, op, expr.Values)
+		if err != nil {
+			return false
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector.Matches(labels.Set(node.Labels))
+}
+
+func isTerminatedPod(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
@@ -0,0 +1,150 @@
+package filters
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestTopologySpreadFilter(t *testing.T) {
+	const zoneKey = "topology.kubernetes.io/zone"
+	newNode := func(name, zone string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: name, Labels: map[string]string{zoneKey: zone}}}
+	}
+	newPod := func(name, node string, spread bool) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"app": "svc"}},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		if spread {
+			pod.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{{
+				MaxSkew:           1,
+				TopologyKey:       zoneKey,
+				WhenUnsatisfiable: corev1.DoNotSchedule,
+				LabelSelector:     &v1.LabelSelector{MatchLabels: map[string]string{"app": "svc"}},
+			}}
+		}
+		return pod
+	}
+
+	withLabel := func(node *corev1.Node, key, value string) *corev1.Node {
+		node.Labels[key] = value
+		return node
+	}
+	withNodeSelector := func(pod *corev1.Pod, key, value string) *corev1.Pod {
+		pod.Spec.NodeSelector = map[string]string{key: value}
+		return pod
+	}
+	withNodeAffinityPolicy := func(pod *corev1.Pod, policy corev1.NodeInclusionPolicy) *corev1.Pod {
+		pod.Spec.TopologySpreadConstraints[0].NodeAffinityPolicy = &policy
+		return pod
+	}
+	withMinDomains := func(pod *corev1.Pod, minDomains int32) *corev1.Pod {
+		pod.Spec.TopologySpreadConstraints[0].MinDomains = &minDomains
+		return pod
+	}
+	withTopologyKey := func(pod *corev1.Pod, key string) *corev1.Pod {
+		pod.Spec.TopologySpreadConstraints[0].TopologyKey = key
+		return pod
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		wantOut bool
+	}{
+		{
+			name: "only node of its zone",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), newNode("n-b", "b"), newNode("n-c", "c"),
+				newPod("p-a", "n-a", true), newPod("p-b", "n-b", true), newPod("p-c", "n-c", true),
+			},
+			wantOut: true,
+		},
+		{
+			name: "another replica in the zone",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), newNode("n-a2", "a"), newNode("n-b", "b"), newNode("n-c", "c"),
+				newPod("p-a", "n-a", true), newPod("p-a2", "n-a2", true), newPod("p-b", "n-b", true), newPod("p-c", "n-c", true),
+			},
+		},
+		{
+			name: "drain would exceed the max skew",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), newNode("n-a2", "a"), newNode("n-b", "b"),
+				newPod("p-a", "n-a", true), newPod("p-a1", "n-a", true), newPod("p-a2", "n-a2", true),
+				newPod("p-b1", "n-b", true), newPod("p-b2", "n-b", true), newPod("p-b3", "n-b", true),
+			},
+			wantOut: true,
+		},
+		{
+			name: "nodes excluded by the node affinity of the pod are not counted",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), withLabel(newNode("n-a2", "a"), "pool", "default"), withLabel(newNode("n-b", "b"), "pool", "default"), withLabel(newNode("n-c", "c"), "pool", "other"),
+				withNodeSelector(newPod("p-a", "n-a", true), "pool", "default"), newPod("p-a2", "n-a2", true), newPod("p-b", "n-b", true),
+				newPod("p-c1", "n-c", true), newPod("p-c2", "n-c", true), newPod("p-c3", "n-c", true),
+			},
+		},
+		{
+			name: "nodes excluded by the node affinity of the pod are counted with the Ignore policy",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), withLabel(newNode("n-a2", "a"), "pool", "default"), withLabel(newNode("n-b", "b"), "pool", "default"), withLabel(newNode("n-c", "c"), "pool", "other"),
+				withNodeAffinityPolicy(withNodeSelector(newPod("p-a", "n-a", true), "pool", "default"), corev1.NodeInclusionPolicyIgnore), newPod("p-a2", "n-a2", true), newPod("p-b", "n-b", true),
+				newPod("p-c1", "n-c", true), newPod("p-c2", "n-c", true), newPod("p-c3", "n-c", true),
+			},
+			wantOut: true,
+		},
+		{
+			name: "skew not checked below min domains",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), newNode("n-a2", "a"), newNode("n-b", "b"),
+				withMinDomains(newPod("p-a", "n-a", true), 3), newPod("p-a2", "n-a2", true),
+				newPod("p-b1", "n-b", true), newPod("p-b2", "n-b", true), newPod("p-b3", "n-b", true),
+			},
+		},
+		{
+			name: "hostname constraints are ignored",
+			objects: []runtime.Object{
+				withLabel(newNode("n-a", "a"), corev1.LabelHostname, "n-a"), withLabel(newNode("n-b", "b"), corev1.LabelHostname, "n-b"),
+				withTopologyKey(newPod("p-a", "n-a", true), corev1.LabelHostname), withTopologyKey(newPod("p-b", "n-b", true), corev1.LabelHostname),
+			},
+		},
+		{
+			name: "pods without topology spread constraints",
+			objects: []runtime.Object{
+				newNode("n-a", "a"), newNode("n-b", "b"), newNode("n-c", "c"),
+				newPod("p-a", "n-a", false), newPod("p-b", "n-b", false), newPod("p-c", "n-c", false),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, closingFunc := kubernetes.RunStoreForTest(context.Background(), fake.NewSimpleClientset(tt.objects...))
+			defer closingFunc()
+			indexer := &podsByNodeIndexer{pods: map[string][]*corev1.Pod{}}
+			for _, obj := range tt.objects {
+				if pod, ok := obj.(*corev1.Pod); ok {
+					indexer.pods[pod.Spec.NodeName] = append(indexer.pods[pod.Spec.NodeName], pod)
+				}
+			}
+			node := tt.objects[0].(*corev1.Node)
+			f := NewTopologySpreadFilter(zapr.NewLogger(zap.NewNop()), store, indexer)
+			got := f.Filter(context.Background(), []*corev1.Node{node})
+			if tt.wantOut {
+				assert.Empty(t, got)
+			} else {
+				assert.Equal(t, []*corev1.Node{node}, got)
+			}
+		})
+	}
+}
@@ -31,6 +31,8 @@ type DataInfo struct {
 	LastSimulationRejections         []string      // Nodes that were rejected by the drain simulation during the candidate evaluation
 	LastConditionRateLimitRejections []string      // Nodes that were rejected because of missing condition rate limiting budget
 	LastRunRateLimited               bool          // Indicates if the last run was stopped because of client side rate limiting
+	LastRunTimedOut                  bool          // Indicates if the last run was stopped because the pass deadline was reached
+	LastRunEvaluatedCount            int           // How many nodes were evaluated by the drain simulation during the last run
 	CurrentCandidates                []string      // Nodes that are currently in candidate state always: len(CurrentCandidates) <= CandidateSlots
 	CurrentDrained                   []string      // Nodes that are currently in drained state always: len(CurrentDrained) <= DrainedSlots
 	CircuitBreakersOk                bool          // Indecates if circuit breakers are ok
@@ -0,0 +1,47 @@
+package candidate_runner
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/metrics"
+)
+
+const (
+	// TagSelection tells which filter selected the node: ShadowSelectionActiveOnly or ShadowSelectionShadowOnly
+	TagSelection = "selection"
+
+	ShadowSelectionActiveOnly = "active_only"
+	ShadowSelectionShadowOnly = "shadow_only"
+)
+
+var (
+	Metrics = struct {
+		ShadowFilterDiff *prometheus.GaugeVec
+	}{
+		ShadowFilterDiff: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "draino_shadow_filter_diff_nodes",
+			Help: "Number of nodes selected by only one of the active and shadow filters during the last candidate pass.",
+		}, []string{metrics.TagGroupKey, TagSelection}),
+	}
+	registerOnce sync.Once
+)
+
+func RegisterMetrics(reg prometheus.Registerer) {
+	registerOnce.Do(func() {
+		values := reflect.ValueOf(Metrics)
+		for i := 0; i < values.NumField(); i++ {
+			collector := values.Field(i).Interface().(prometheus.Collector)
+			reg.MustRegister(collector)
+		}
+	})
+}
+
+// ReportShadowFilterDiff records the number of nodes selected by only one of the active and shadow filters for the group
+func ReportShadowFilterDiff(key groups.GroupKey, activeOnly, shadowOnly int) {
+	Metrics.ShadowFilterDiff.WithLabelValues(string(key), ShadowSelectionActiveOnly).Set(float64(activeOnly))
+	Metrics.ShadowFilterDiff.WithLabelValues(string(key), ShadowSelectionShadowOnly).Set(float64(shadowOnly))
+}
@@ -2,7 +2,9 @@ package candidate_runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,9 +13,11 @@ import (
 
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
 	circuitbreaker "github.com/planetlabs/draino/internal/circuit_breaker"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 	"github.com/planetlabs/draino/internal/kubernetes/utils"
 	"github.com/planetlabs/draino/internal/limit"
+	"github.com/planetlabs/draino/internal/metrics"
 	"github.com/planetlabs/draino/internal/scheduler"
 
 	"github.com/go-logr/logr"
@@ -32,6 +36,10 @@ import (
 const (
 	drainRetryFailedAnnotationKey    = "draino/drain-retry-failed"
 	drainRetryRestartAnnotationValue = "restart"
+
+	// MaxSimultaneousCandidatesKey can be set as label or annotation on the nodes (usually propagated from the node group)
+	// to override the max simultaneous candidates of the group. It is read at each pass, so there is no need to restart draino.
+	MaxSimultaneousCandidatesKey = "node-lifecycle.datadoghq.com/max-simultaneous-candidates"
 )
 
 // Make sure that the drain runner is implementing the group runner interface
@@ -55,6 +63,21 @@ type candidateRunner struct {
 	rateLimiter         limit.TypedRateLimiter
 	suppliedConditions  []kubernetes.SuppliedCondition
 	circuitBreakers     []circuitbreaker.NamedCircuitBreaker
+	eventExporter       eventexporter.EventExporter
+	passTimeout         time.Duration
+	groupIndexName      string
+	periodJitterFactor  float64
+	// shadowFilter is evaluated on the same nodes as filter to report the difference of selection, nil if disabled
+	shadowFilter filters.Filter
+	// drainNow tells which annotation requests the immediate drain of a node
+	drainNow kubernetes.DrainNowConfig
+	// teamLabelKey is the label giving the team of the nodes in the metrics
+	teamLabelKey string
+	// changeTicketKey is the node annotation giving the change ticket of the exported drain events
+	changeTicketKey string
+	// drainNowBlocked holds the reasons reported for the immediate drains blocked by the drain simulation, so that the event is
+	// only emitted when they change
+	drainNowBlocked map[string]string
 
 	maxSimultaneousCandidates int
 	maxSimultaneousDrained    int
@@ -73,7 +96,7 @@ type slotsInfo struct {
 }
 
 func (runner *candidateRunner) GetNodes(ctx context.Context, key groups.GroupKey) ([]*corev1.Node, error) {
-	return index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, groups.SchedulingGroupIdx, string(key))
+	return index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, runner.groupIndexName, string(key))
 }
 
 func (runner *candidateRunner) Run(info *groups.RunnerInfo) error {
@@ -83,10 +106,17 @@ func (runner *candidateRunner) Run(info *groups.RunnerInfo) error {
 	go runner.runCleanupWithContext(ctx, info)
 
 	// run an endless loop until there are no drain candidates left
-	wait.UntilWithContext(ctx, func(ctx context.Context) {
+	utils.JitterUntilWithContext(ctx, func(ctx context.Context) {
 		span, ctx := tracer.StartSpanFromContext(ctx, "EvaluateCandidates")
 		defer span.Finish()
 
+		// the pass is aborted at the deadline, the remaining nodes will be evaluated during the next pass
+		if runner.passTimeout > 0 {
+			var cancelPass context.CancelFunc
+			ctx, cancelPass = context.WithTimeout(ctx, runner.passTimeout)
+			defer cancelPass()
+		}
+
 		start := runner.clock.Now()
 
 		var dataInfo, previousDataInfo DataInfo
@@ -120,12 +150,24 @@ func (runner *candidateRunner) Run(info *groups.RunnerInfo) error {
 			return
 		}
 
+		maxCandidates := runner.getMaxSimultaneousCandidates(nodes)
+		dataInfo.CandidateSlots = maxCandidates
+
 		// filter nodes that are already candidate or drained
-		nodes, slotsInfo := runner.checkAlreadyCandidatesOrDrained(nodes)
+		nodes, slotsInfo := runner.checkAlreadyCandidatesOrDrained(nodes, maxCandidates)
 		dataInfo.CurrentCandidates = utils.NodesNames(slotsInfo.alreadyCandidateNodes)
 		dataInfo.CurrentDrained = utils.NodesNames(slotsInfo.alreadyDrainedNodes)
+
+		// nodes requested for an immediate drain do not consume candidate slots
+		nodes = runner.processDrainNowNodes(ctx, info.Key, nodes, &dataInfo)
+		if info.Scheduler != nil {
+			if schedulable, reason := info.Scheduler.IsSchedulable(info.Key); !schedulable {
+				runner.logger.Info("Group is waiting for its turn to get new candidates", "reason", reason)
+				return
+			}
+		}
 		if slotsInfo.maxCandidateReached {
-			runner.logger.Info("Max candidate already reached", "count", runner.maxSimultaneousCandidates, "nodes", strings.Join(utils.NodesNames(slotsInfo.alreadyCandidateNodes), ","))
+			runner.logger.Info("Max candidate already reached", "count", maxCandidates, "nodes", strings.Join(utils.NodesNames(slotsInfo.alreadyCandidateNodes), ","))
 			return
 		}
 		if slotsInfo.maxDrainedReached {
@@ -133,15 +175,18 @@ func (runner *candidateRunner) Run(info *groups.RunnerInfo) error {
 			return
 		}
 		// make sure the number of nodes with any taint do not exceed maxSimultaneousDrained
-		remainCandidateSlot := min(runner.maxSimultaneousCandidates-len(slotsInfo.alreadyCandidateNodes), runner.maxSimultaneousDrained-len(slotsInfo.alreadyCandidateNodes)-len(slotsInfo.alreadyDrainedNodes))
+		remainCandidateSlot := min(maxCandidates-len(slotsInfo.alreadyCandidateNodes), runner.maxSimultaneousDrained-len(slotsInfo.alreadyCandidateNodes)-len(slotsInfo.alreadyDrainedNodes))
 		if remainCandidateSlot <= 0 {
-			runner.logger.Info("No more candidate slots", "maxCandidates", runner.maxSimultaneousCandidates, "numCandidates", len(slotsInfo.alreadyCandidateNodes), "candidateNodes", strings.Join(utils.NodesNames(slotsInfo.alreadyCandidateNodes), ","), "maxDrained", runner.maxSimultaneousDrained, "numDrained", len(slotsInfo.alreadyDrainedNodes), "drainedNodes", strings.Join(utils.NodesNames(slotsInfo.alreadyDrainedNodes), ","))
+			runner.logger.Info("No more candidate slots", "maxCandidates", maxCandidates, "numCandidates", len(slotsInfo.alreadyCandidateNodes), "candidateNodes", strings.Join(utils.NodesNames(slotsInfo.alreadyCandidateNodes), ","), "maxDrained", runner.maxSimultaneousDrained, "numDrained", len(slotsInfo.alreadyDrainedNodes), "drainedNodes", strings.Join(utils.NodesNames(slotsInfo.alreadyDrainedNodes), ","))
 			return
 		}
 
-		evaluatedCount := len(nodes)
+		evaluatedNodes := nodes
 		nodes = runner.filter.Filter(ctx, nodes)
-		dataInfo.FilteredOutCount = evaluatedCount - len(nodes)
+		dataInfo.FilteredOutCount = len(evaluatedNodes) - len(nodes)
+		if runner.shadowFilter != nil {
+			runner.compareShadowFilter(ctx, info.Key, evaluatedNodes, nodes)
+		}
 
 		// The circuit breaker should be call once for the group: in case of half-open it will consume a token
 		// and we do want this token to apply for the entire group and not for each node.
@@ -150,56 +195,8 @@ func (runner *candidateRunner) Run(info *groups.RunnerInfo) error {
 		dataInfo.CircuitBreakersOk = cbOk
 
 		// TODO think about adding tracing to the tree iterator/expander
-		var candidatesName []string
 		nodeProvider := runner.GetNodeIterator(nodes)
-	groupIteration:
-		for node, ok := nodeProvider.Next(); ok; node, ok = nodeProvider.Next() {
-			logForNode := runner.logger.WithValues("node", node.Name)
-			// check that the node can be drained
-			canDrain, reasons, errDrainSimulation := runner.drainSimulator.SimulateDrain(ctx, node)
-			if len(errDrainSimulation) > 0 {
-				for _, e := range errDrainSimulation {
-					if k8sclient.IsClientSideRateLimiting(e) {
-						dataInfo.LastRunRateLimited = true
-						logForNode.Info("Not exploring the group further: simulation rate limited")
-						break groupIteration
-					}
-				}
-				dataInfo.LastSimulationRejections = append(dataInfo.LastSimulationRejections, node.Name)
-				logForNode.Error(errDrainSimulation[0], "Failed to simulate drain")
-				continue
-			}
-			if !canDrain {
-				dataInfo.LastSimulationRejections = append(dataInfo.LastSimulationRejections, node.Name)
-				logForNode.Info("Rejected by drain simulation", "reason", strings.Join(reasons, ";"))
-				continue
-			}
-
-			// Check if one of the condition rate limiters has capacity
-			if !runner.hasConditionRateLimitingCapacity(node) {
-				dataInfo.LastConditionRateLimitRejections = append(dataInfo.LastConditionRateLimitRejections, node.Name)
-				logForNode.V(logs.ZapDebug).Info("No rate limiter has capacity")
-				continue
-			}
-
-			candidatesName = append(candidatesName, node.Name)
-
-			if cbOk {
-				if !runner.dryRun {
-					logForNode.Info("Adding drain candidate taint")
-					if _, errTaint := k8sclient.AddNLATaint(ctx, runner.client, node, runner.clock.Now(), k8sclient.TaintDrainCandidate); errTaint != nil {
-						logForNode.Error(errTaint, "Failed to taint node")
-						continue // let's try next node, maybe this one has a problem
-					}
-				} else {
-					logForNode.Info("Dry-Run: skip adding drain candidate taint")
-				}
-				remainCandidateSlot--
-				if remainCandidateSlot <= 0 {
-					break
-				}
-			}
-		}
+		candidatesName, remainCandidateSlot := runner.evaluateCandidates(ctx, info.Key, nodeProvider, cbOk, remainCandidateSlot, &dataInfo)
 		if len(candidatesName) > 0 {
 			dataInfo.LastCandidates = candidatesName
 			dataInfo.LastCandidatesTime = runner.clock.Now()
@@ -209,10 +206,182 @@ func (runner *candidateRunner) Run(info *groups.RunnerInfo) error {
 		runner.logger.V(logs.ZapDebug).Info("Remain slot after drain candidate analysis", "count", remainCandidateSlot)
 		dataInfo.CurrentCandidates = append(dataInfo.CurrentCandidates, candidatesName...)
 
-	}, runner.runEvery)
+	}, runner.runEvery, runner.periodJitterFactor)
 	return nil
 }
 
+// compareShadowFilter runs the shadow filter on the nodes evaluated by the active filter and reports the nodes selected by only one of them
+func (runner *candidateRunner) compareShadowFilter(ctx context.Context, key groups.GroupKey, evaluatedNodes, activeNodes []*corev1.Node) {
+	shadowNodes := runner.shadowFilter.Filter(ctx, evaluatedNodes)
+	activeOnly, shadowOnly := diffNodeNames(activeNodes, shadowNodes)
+	ReportShadowFilterDiff(key, len(activeOnly), len(shadowOnly))
+	if len(activeOnly) > 0 || len(shadowOnly) > 0 {
+		runner.logger.Info("Shadow filter selection differs", "activeOnly", strings.Join(activeOnly, ","), "shadowOnly", strings.Join(shadowOnly, ","))
+	}
+}
+
+// diffNodeNames returns the names of the nodes that are only in a and only in b
+func diffNodeNames(a, b []*corev1.Node) (onlyA, onlyB []string) {
+	inA := map[string]bool{}
+	for _, n := range a {
+		inA[n.Name] = true
+	}
+	inB := map[string]bool{}
+	for _, n := range b {
+		inB[n.Name] = true
+		if !inA[n.Name] {
+			onlyB = append(onlyB, n.Name)
+		}
+	}
+	for _, n := range a {
+		if !inB[n.Name] {
+			onlyA = append(onlyA, n.Name)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// evaluateCandidates iterates over the nodes and taints the ones that can be drained until there is no candidate slot left.
+// The iteration stops as soon as the context is done.
+func (runner *candidateRunner) evaluateCandidates(ctx context.Context, key groups.GroupKey, nodeProvider scheduler.ItemProvider[*corev1.Node], cbOk bool, remainCandidateSlot int, dataInfo *DataInfo) (candidatesName []string, remainingSlots int) {
+	evaluatedCount := 0
+	defer func() {
+		dataInfo.LastRunEvaluatedCount = evaluatedCount
+	}()
+
+	for node, ok := nodeProvider.Next(); ok; node, ok = nodeProvider.Next() {
+		if runner.isPassAborted(ctx, evaluatedCount, dataInfo) {
+			break
+		}
+		evaluatedCount++
+
+		logForNode := runner.logger.WithValues("node", node.Name)
+		// check that the node can be drained
+		canDrain, reasons, errDrainSimulation := runner.drainSimulator.SimulateDrain(ctx, node)
+		if runner.isPassAborted(ctx, evaluatedCount, dataInfo) {
+			break
+		}
+		if len(errDrainSimulation) > 0 {
+			for _, e := range errDrainSimulation {
+				if k8sclient.IsClientSideRateLimiting(e) {
+					dataInfo.LastRunRateLimited = true
+					logForNode.Info("Not exploring the group further: simulation rate limited")
+					return candidatesName, remainCandidateSlot
+				}
+			}
+			dataInfo.LastSimulationRejections = append(dataInfo.LastSimulationRejections, node.Name)
+			logForNode.Error(errDrainSimulation[0], "Failed to simulate drain")
+			continue
+		}
+		if !canDrain {
+			dataInfo.LastSimulationRejections = append(dataInfo.LastSimulationRejections, node.Name)
+			logForNode.Info("Rejected by drain simulation", "reason", strings.Join(reasons, ";"))
+			continue
+		}
+
+		// Check if one of the condition rate limiters has capacity
+		if !runner.hasConditionRateLimitingCapacity(node) {
+			dataInfo.LastConditionRateLimitRejections = append(dataInfo.LastConditionRateLimitRejections, node.Name)
+			logForNode.V(logs.ZapDebug).Info("No rate limiter has capacity")
+			continue
+		}
+
+		candidatesName = append(candidatesName, node.Name)
+
+		if cbOk {
+			if !runner.dryRun {
+				logForNode.Info("Adding drain candidate taint")
+				if _, errTaint := k8sclient.AddNLATaint(ctx, runner.client, node, runner.clock.Now(), k8sclient.TaintDrainCandidate); errTaint != nil {
+					logForNode.Error(errTaint, "Failed to taint node")
+					continue // let's try next node, maybe this one has a problem
+				}
+				kubernetes.RecordConditionAgeAtCordon(ctx, node, kubernetes.GetNodeOffendingConditions(node, runner.suppliedConditions), runner.clock.Now())
+				runner.exportEvent(ctx, eventexporter.DrainEventScheduled, node, key)
+				metrics.IncCandidatesCreated(string(key), kubernetes.GetNodeTagsValues(node, runner.teamLabelKey).Team)
+			} else {
+				logForNode.Info("Dry-Run: skip adding drain candidate taint")
+			}
+			remainCandidateSlot--
+			if remainCandidateSlot <= 0 {
+				break
+			}
+		}
+	}
+	return candidatesName, remainCandidateSlot
+}
+
+// processDrainNowNodes makes candidate the nodes that have the drain-now annotation, regardless of the candidate slots, circuit breakers and rate limiters.
+// The drain simulation is still run so that PDBs are respected. It returns the nodes that don't have the annotation.
+func (runner *candidateRunner) processDrainNowNodes(ctx context.Context, key groups.GroupKey, nodes []*corev1.Node, dataInfo *DataInfo) []*corev1.Node {
+	others := make([]*corev1.Node, 0, len(nodes))
+	blocked := map[string]string{}
+	defer func() { runner.drainNowBlocked = blocked }()
+	for _, node := range nodes {
+		if !runner.drainNow.IsRequested(node) {
+			others = append(others, node)
+			continue
+		}
+
+		logForNode := runner.logger.WithValues("node", node.Name, "annotation", runner.drainNow.AnnotationKey)
+		if filterOutput := runner.filter.FilterNode(ctx, node); !filterOutput.Keep {
+			logForNode.Info("Immediate drain requested but the node is filtered out", "rejections", filterOutput.OnlyFailingChecks().Checks)
+			continue
+		}
+
+		canDrain, reasons, errDrainSimulation := runner.drainSimulator.SimulateDrain(ctx, node)
+		if len(errDrainSimulation) > 0 {
+			dataInfo.LastSimulationRejections = append(dataInfo.LastSimulationRejections, node.Name)
+			logForNode.Error(errDrainSimulation[0], "Immediate drain requested but the drain simulation failed")
+			continue
+		}
+		if !canDrain {
+			dataInfo.LastSimulationRejections = append(dataInfo.LastSimulationRejections, node.Name)
+			reason := strings.Join(reasons, ";")
+			logForNode.Info("Immediate drain requested but rejected by drain simulation", "reason", reason)
+			if runner.drainNowBlocked[node.Name] != reason {
+				runner.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, kubernetes.EventReasonDrainNowBlocked.String(), "Immediate drain requested but blocked by the drain simulation: %s", reason)
+			}
+			blocked[node.Name] = reason
+			continue
+		}
+
+		if runner.dryRun {
+			logForNode.Info("Dry-Run: immediate drain requested, skip adding drain candidate taint")
+			continue
+		}
+		logForNode.Info("Immediate drain requested, bypassing candidate gating")
+		if _, errTaint := k8sclient.AddNLATaint(ctx, runner.client, node, runner.clock.Now(), k8sclient.TaintDrainCandidate); errTaint != nil {
+			logForNode.Error(errTaint, "Failed to taint node")
+			continue
+		}
+		kubernetes.RecordConditionAgeAtCordon(ctx, node, kubernetes.GetNodeOffendingConditions(node, runner.suppliedConditions), runner.clock.Now())
+		runner.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, kubernetes.EventReasonDrainNowRequested.String(), "Immediate drain requested, bypassing candidate gating")
+		runner.exportEvent(ctx, eventexporter.DrainEventScheduled, node, key)
+		metrics.IncCandidatesCreated(string(key), kubernetes.GetNodeTagsValues(node, runner.teamLabelKey).Team)
+		dataInfo.CurrentCandidates = append(dataInfo.CurrentCandidates, node.Name)
+	}
+	return others
+}
+
+// isPassAborted returns true if the context of the pass is done, either because of the pass deadline or because the group runner is stopped
+func (runner *candidateRunner) isPassAborted(ctx context.Context, evaluatedCount int, dataInfo *DataInfo) bool {
+	err := ctx.Err()
+	if err == nil {
+		return false
+	}
+	dataInfo.LastRunTimedOut = errors.Is(err, context.DeadlineExceeded)
+	runner.logger.Info("Aborting candidate pass", "reason", err.Error(), "evaluatedNodes", evaluatedCount)
+	return true
+}
+
+// exportEvent publishes the drain lifecycle event, failures are only logged
+func (runner *candidateRunner) exportEvent(ctx context.Context, eventType eventexporter.DrainEventType, node *corev1.Node, key groups.GroupKey) {
+	event := eventexporter.DrainEvent{Type: eventType, Node: node.Name, GroupKey: string(key), Timestamp: runner.clock.Now(), ChangeTicket: kubernetes.GetNodeChangeTicket(node, runner.changeTicketKey)}
+	if err := runner.eventExporter.Export(ctx, event); err != nil {
+		runner.logger.Error(err, "Failed to export drain event", "node", node.Name, "type", eventType)
+	}
+}
+
 func (runner *candidateRunner) areCircuitBreakersOk() bool {
 	for _, cb := range runner.circuitBreakers {
 		switch cb.State() {
@@ -239,10 +408,33 @@ func (runner *candidateRunner) hasConditionRateLimitingCapacity(node *corev1.Nod
 	return false
 }
 
-// checkAlreadyCandidates keep only the nodes that are not candidate. If maxSimultaneousCandidates>0 or maxSimultaneousDrained>0, then we check against the max. If max is reached a nil slice is returned and the associated boolean returned is true
-func (runner *candidateRunner) checkAlreadyCandidatesOrDrained(nodes []*corev1.Node) ([]*corev1.Node, slotsInfo) {
+// getMaxSimultaneousCandidates returns the max simultaneous candidates of the group, overridden by the MaxSimultaneousCandidatesKey
+// label or annotation of the nodes. If the nodes hold different values, the lowest one is used. Invalid values are ignored.
+func (runner *candidateRunner) getMaxSimultaneousCandidates(nodes []*corev1.Node) int {
+	max, overridden := 0, false
+	for _, n := range nodes {
+		values, found := kubernetes.GetExactMetadata(n, MaxSimultaneousCandidatesKey)
+		if !found || len(values) == 0 {
+			continue
+		}
+		value, err := strconv.Atoi(values[0].Value)
+		if err != nil || value < 0 {
+			continue
+		}
+		if !overridden || value < max {
+			max, overridden = value, true
+		}
+	}
+	if !overridden {
+		return runner.maxSimultaneousCandidates
+	}
+	return max
+}
+
+// checkAlreadyCandidates keep only the nodes that are not candidate. If maxCandidates>0 or maxSimultaneousDrained>0, then we check against the max. If max is reached a nil slice is returned and the associated boolean returned is true
+func (runner *candidateRunner) checkAlreadyCandidatesOrDrained(nodes []*corev1.Node, maxCandidates int) ([]*corev1.Node, slotsInfo) {
 	remainingNodes := make([]*corev1.Node, 0, len(nodes)) // high probability that all nodes are to be kept
-	alreadyCandidateNodes := make([]*corev1.Node, 0, runner.maxSimultaneousCandidates)
+	alreadyCandidateNodes := make([]*corev1.Node, 0, maxCandidates)
 	alreadyDrainedNodes := make([]*corev1.Node, 0, runner.maxSimultaneousDrained)
 	for _, n := range nodes {
 		if taint, hasTaint := k8sclient.GetNLATaint(n); !hasTaint {
@@ -262,8 +454,8 @@ func (runner *candidateRunner) checkAlreadyCandidatesOrDrained(nodes []*corev1.N
 				}
 			} else {
 				alreadyCandidateNodes = append(alreadyCandidateNodes, n)
-				if runner.maxSimultaneousCandidates > 0 {
-					if len(alreadyCandidateNodes) >= runner.maxSimultaneousCandidates {
+				if maxCandidates > 0 {
+					if len(alreadyCandidateNodes) >= maxCandidates {
 						return nil, slotsInfo{
 							alreadyCandidateNodes: alreadyCandidateNodes,
 							alreadyDrainedNodes:   alreadyDrainedNodes,
@@ -348,7 +540,7 @@ func (runner *candidateRunner) runCleanupWithContext(ctx context.Context, info *
 			info.Data.Set(CandidateRunnerInfoCleanupKey, dataInfo)
 		}()
 
-		nodes, err := index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, groups.SchedulingGroupIdx, string(info.Key))
+		nodes, err := index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, runner.groupIndexName, string(info.Key))
 		// in case of an error we'll just try it again
 		if err != nil {
 			runner.logger.Error(err, "cannot get nodes for group")
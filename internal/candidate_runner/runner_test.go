@@ -1,15 +1,30 @@
 package candidate_runner
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
+	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+	"github.com/planetlabs/draino/internal/limit"
+	"github.com/planetlabs/draino/internal/metrics"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/util/taints"
 	"k8s.io/utils/clock"
 	testing2 "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 var clockInTest clock.Clock
@@ -100,7 +115,7 @@ func Test_candidateRunner_checkAlreadyCandidatesOrDrained(t *testing.T) {
 				maxSimultaneousCandidates: tt.maxCandidate,
 				maxSimultaneousDrained:    tt.maxDrained,
 			}
-			gotRemainingNodes, slotsInfo := runner.checkAlreadyCandidatesOrDrained(tt.nodes)
+			gotRemainingNodes, slotsInfo := runner.checkAlreadyCandidatesOrDrained(tt.nodes, tt.maxCandidate)
 			if !reflect.DeepEqual(gotRemainingNodes, tt.wantRemainingNodes) {
 				t.Errorf("checkAlreadyCandidates() gotRemainingNodes = %v, want %v", gotRemainingNodes, tt.wantRemainingNodes)
 			}
@@ -119,3 +134,243 @@ func Test_candidateRunner_checkAlreadyCandidatesOrDrained(t *testing.T) {
 		})
 	}
 }
+
+func Test_candidateRunner_getMaxSimultaneousCandidates(t *testing.T) {
+	setClockForTest()
+	candidate := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "candidate"}}
+	candidate, _, _ = taints.AddOrUpdateTaint(candidate, k8sclient.CreateNLATaint(k8sclient.TaintDrainCandidate, clockInTest.Now()))
+	other := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	nodes := []*corev1.Node{candidate, other}
+	setBudget := func(value string) {
+		for _, n := range nodes {
+			n.Annotations = map[string]string{MaxSimultaneousCandidatesKey: value}
+		}
+	}
+	runner := &candidateRunner{maxSimultaneousCandidates: 1}
+
+	// default budget: the group is full
+	assert.Equal(t, 1, runner.getMaxSimultaneousCandidates(nodes))
+	_, slots := runner.checkAlreadyCandidatesOrDrained(nodes, runner.getMaxSimultaneousCandidates(nodes))
+	assert.True(t, slots.maxCandidateReached)
+
+	// the operator raises the budget: a new candidate can be selected
+	setBudget("3")
+	assert.Equal(t, 3, runner.getMaxSimultaneousCandidates(nodes))
+	remaining, slots := runner.checkAlreadyCandidatesOrDrained(nodes, runner.getMaxSimultaneousCandidates(nodes))
+	assert.False(t, slots.maxCandidateReached)
+	assert.Equal(t, []*corev1.Node{other}, remaining)
+
+	// the operator lowers the budget again
+	setBudget("1")
+	_, slots = runner.checkAlreadyCandidatesOrDrained(nodes, runner.getMaxSimultaneousCandidates(nodes))
+	assert.True(t, slots.maxCandidateReached)
+
+	// the lowest value wins when the nodes disagree
+	other.Annotations[MaxSimultaneousCandidatesKey] = "2"
+	candidate.Annotations[MaxSimultaneousCandidatesKey] = "4"
+	assert.Equal(t, 2, runner.getMaxSimultaneousCandidates(nodes))
+
+	// invalid values are ignored
+	setBudget("many")
+	assert.Equal(t, 1, runner.getMaxSimultaneousCandidates(nodes))
+}
+
+// slowSimulator blocks the simulation of the given node until the context is done
+type slowSimulator struct {
+	blockingNode string
+	simulated    []string
+}
+
+func (s *slowSimulator) SimulateDrain(ctx context.Context, node *corev1.Node) (bool, []string, []error) {
+	s.simulated = append(s.simulated, node.Name)
+	if node.Name == s.blockingNode {
+		<-ctx.Done()
+		return false, nil, []error{ctx.Err()}
+	}
+	return true, nil, nil
+}
+
+func (s *slowSimulator) SimulatePodDrain(context.Context, *corev1.Pod) (bool, string, error) {
+	return true, "", nil
+}
+
+type sliceNodeProvider struct {
+	nodes []*corev1.Node
+}
+
+func (p *sliceNodeProvider) Next() (*corev1.Node, bool) {
+	if len(p.nodes) == 0 {
+		return nil, false
+	}
+	n := p.nodes[0]
+	p.nodes = p.nodes[1:]
+	return n, true
+}
+
+func Test_candidateRunner_evaluateCandidates_PassTimeout(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-0"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+	}
+	simulator := &slowSimulator{blockingNode: "node-1"}
+	runner := &candidateRunner{
+		logger:         logr.Discard(),
+		clock:          setClockForTest(),
+		drainSimulator: simulator,
+		dryRun:         true,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var dataInfo DataInfo
+	// circuit breakers are not ok so the nodes are only evaluated
+	candidates, remainingSlots := runner.evaluateCandidates(ctx, "group", &sliceNodeProvider{nodes: nodes}, false, 1, &dataInfo)
+
+	assert.Empty(t, candidates)
+	assert.Equal(t, 1, remainingSlots)
+	assert.True(t, dataInfo.LastRunTimedOut)
+	assert.Equal(t, 2, dataInfo.LastRunEvaluatedCount)
+	assert.Equal(t, []string{"node-0", "node-1"}, simulator.simulated)
+	assert.Empty(t, dataInfo.LastSimulationRejections, "the node aborted by the deadline should not be reported as rejected")
+}
+
+func Test_candidateRunner_evaluateCandidates_SimulationBeforeTaint(t *testing.T) {
+	conditions, err := kubernetes.ParseConditions([]string{"KernelDeadlock"})
+	assert.NoError(t, err)
+	newNode := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}}},
+		}
+	}
+	nodes := []*corev1.Node{newNode("undrainable"), newNode("drainable")}
+	var objects []client.Object
+	for _, n := range nodes {
+		objects = append(objects, n.DeepCopy())
+	}
+	kclient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+	runner := &candidateRunner{
+		client:             kclient,
+		logger:             logr.Discard(),
+		clock:              setClockForTest(),
+		eventExporter:      &eventexporter.NoopEventExporter{},
+		drainSimulator:     &pdbSimulator{blockedNodes: map[string]bool{"undrainable": true}},
+		suppliedConditions: conditions,
+		rateLimiter:        limit.NewTypedRateLimiter(setClockForTest(), nil, 100, 100),
+	}
+
+	var dataInfo DataInfo
+	candidates, remainingSlots := runner.evaluateCandidates(context.Background(), "group", &sliceNodeProvider{nodes: nodes}, true, 2, &dataInfo)
+
+	assert.Equal(t, []string{"drainable"}, candidates)
+	assert.Equal(t, 1, remainingSlots)
+	assert.Equal(t, []string{"undrainable"}, dataInfo.LastSimulationRejections)
+	// the candidate taint cordons the node, it is only set once the drain simulation confirmed that the node is drainable
+	for name, expectedTaint := range map[string]bool{"undrainable": false, "drainable": true} {
+		var node corev1.Node
+		assert.NoError(t, kclient.Get(context.Background(), types.NamespacedName{Name: name}, &node))
+		_, found := k8sclient.GetNLATaint(&node)
+		assert.Equal(t, expectedTaint, found, "node %s", name)
+	}
+}
+
+func Test_candidateRunner_compareShadowFilter(t *testing.T) {
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-0", Labels: map[string]string{"active": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"active": "true", "shadow": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-2", Labels: map[string]string{"shadow": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-3", Labels: map[string]string{"shadow": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node-4"}},
+	}
+	labelFilter := func(label string) filters.Filter {
+		return filters.FilterFromFunction(label, func(ctx context.Context, n *corev1.Node) bool {
+			return n.Labels[label] == "true"
+		})
+	}
+	runner := &candidateRunner{
+		logger:       logr.Discard(),
+		filter:       labelFilter("active"),
+		shadowFilter: labelFilter("shadow"),
+	}
+
+	activeNodes := runner.filter.Filter(context.Background(), nodes)
+	runner.compareShadowFilter(context.Background(), "shadow-group", nodes, activeNodes)
+
+	activeOnly, shadowOnly := diffNodeNames(activeNodes, runner.shadowFilter.Filter(context.Background(), nodes))
+	assert.Equal(t, []string{"node-0"}, activeOnly)
+	assert.Equal(t, []string{"node-2", "node-3"}, shadowOnly)
+	assert.Equal(t, 1.0, testutil.ToFloat64(Metrics.ShadowFilterDiff.WithLabelValues("shadow-group", ShadowSelectionActiveOnly)))
+	assert.Equal(t, 2.0, testutil.ToFloat64(Metrics.ShadowFilterDiff.WithLabelValues("shadow-group", ShadowSelectionShadowOnly)))
+
+	// same selection: the diff is reset
+	runner.shadowFilter = labelFilter("active")
+	runner.compareShadowFilter(context.Background(), "shadow-group", nodes, activeNodes)
+	assert.Equal(t, 0.0, testutil.ToFloat64(Metrics.ShadowFilterDiff.WithLabelValues("shadow-group", ShadowSelectionActiveOnly)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(Metrics.ShadowFilterDiff.WithLabelValues("shadow-group", ShadowSelectionShadowOnly)))
+}
+
+// pdbSimulator rejects the drain of the nodes hosting pods protected by a PDB
+type pdbSimulator struct {
+	blockedNodes map[string]bool
+}
+
+func (s *pdbSimulator) SimulateDrain(ctx context.Context, node *corev1.Node) (bool, []string, []error) {
+	if s.blockedNodes[node.Name] {
+		return false, []string{"pdb"}, nil
+	}
+	return true, nil, nil
+}
+
+func (s *pdbSimulator) SimulatePodDrain(context.Context, *corev1.Pod) (bool, string, error) {
+	return true, "", nil
+}
+
+func Test_candidateRunner_processDrainNowNodes(t *testing.T) {
+	drainNow := map[string]string{kubernetes.DefaultDrainNowAnnotationKey: kubernetes.DrainNowAnnotationValue}
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "regular"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "drain-now", Annotations: drainNow}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "drain-now-pdb", Annotations: drainNow}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "spot-terminating", Annotations: map[string]string{"spot.example.com/termination-notice": ""}}},
+	}
+	var objects []client.Object
+	for _, n := range nodes {
+		objects = append(objects, n.DeepCopy())
+	}
+	kclient := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+	runner := &candidateRunner{
+		client:         kclient,
+		logger:         logr.Discard(),
+		clock:          setClockForTest(),
+		eventRecorder:  kubernetes.NoopEventRecorder{},
+		eventExporter:  &eventexporter.NoopEventExporter{},
+		filter:         filters.FilterFromFunction("always_true", func(ctx context.Context, n *corev1.Node) bool { return true }),
+		drainSimulator: &pdbSimulator{blockedNodes: map[string]bool{"drain-now-pdb": true}},
+		drainNow: kubernetes.DrainNowConfig{
+			AnnotationKey:                kubernetes.DefaultDrainNowAnnotationKey,
+			SpotTerminationAnnotationKey: "spot.example.com/termination-notice",
+		},
+	}
+
+	createdBefore := testutil.ToFloat64(metrics.CandidateMetrics.Created.WithLabelValues("group", ""))
+	var dataInfo DataInfo
+	others := runner.processDrainNowNodes(context.Background(), "group", nodes, &dataInfo)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.CandidateMetrics.Created.WithLabelValues("group", ""))-createdBefore)
+
+	assert.Equal(t, []string{"regular"}, utils.NodesNames(others))
+	assert.Equal(t, []string{"drain-now", "spot-terminating"}, dataInfo.CurrentCandidates)
+	assert.Equal(t, []string{"drain-now-pdb"}, dataInfo.LastSimulationRejections)
+	for name, expectedTaint := range map[string]bool{"regular": false, "drain-now": true, "drain-now-pdb": false, "spot-terminating": true} {
+		var node corev1.Node
+		assert.NoError(t, kclient.Get(context.Background(), types.NamespacedName{Name: name}, &node))
+		taint, found := k8sclient.GetNLATaint(&node)
+		assert.Equal(t, expectedTaint, found, "node %s", name)
+		if expectedTaint {
+			assert.Equal(t, k8sclient.TaintDrainCandidate, taint.Value)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package sorters
 
 import (
-	"github.com/planetlabs/draino/internal/kubernetes"
+	"sort"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
 )
 
 func Test_conditionsComparator_compareConditionsPriorities(t *testing.T) {
@@ -97,3 +103,37 @@ func Test_conditionsComparator_compareConditionsPriorities(t *testing.T) {
 		})
 	}
 }
+
+func TestNewConditionComparator(t *testing.T) {
+	conditions, err := kubernetes.ParseConditions([]string{
+		"Low=True,weight=1",
+		"Medium=True,weight=5",
+		"High=True,weight=10",
+	})
+	assert.NoError(t, err)
+
+	newNode := func(name string, conditionTypes ...v1.NodeConditionType) *v1.Node {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		for _, c := range conditionTypes {
+			node.Status.Conditions = append(node.Status.Conditions, v1.NodeCondition{Type: c, Status: v1.ConditionTrue})
+		}
+		return node
+	}
+	nodes := []*v1.Node{
+		newNode("no-condition"),
+		newNode("low", "Low"),
+		newNode("low-and-medium", "Low", "Medium"),
+		newNode("high", "High"),
+		newNode("medium", "Medium"),
+		newNode("low-and-high", "Low", "High"),
+	}
+
+	less := NewConditionComparator(conditions)
+	sort.SliceStable(nodes, func(i, j int) bool { return less(nodes[i], nodes[j]) })
+
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	assert.Equal(t, []string{"low-and-high", "high", "low-and-medium", "medium", "low", "no-condition"}, names)
+}
@@ -0,0 +1,14 @@
+package sorters
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// SortByCreationTimestampDesc puts the most recently created nodes first, so that a scale-down disrupts the long-lived pods as little as possible.
+// The sorting tree does not keep the initial order of equal nodes, the nodes created at the same time are ordered by name so that the order is stable across passes.
+func SortByCreationTimestampDesc(n1, n2 *v1.Node) bool {
+	if !n1.CreationTimestamp.Equal(&n2.CreationTimestamp) {
+		return n1.CreationTimestamp.After(n2.CreationTimestamp.Time)
+	}
+	return n1.Name < n2.Name
+}
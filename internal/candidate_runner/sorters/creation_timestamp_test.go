@@ -0,0 +1,66 @@
+package sorters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/scheduler"
+)
+
+func TestSortByCreationTimestampDesc(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	newNode := func(name string, age time.Duration) *corev1.Node {
+		return &corev1.Node{ObjectMeta: meta.ObjectMeta{Name: name, CreationTimestamp: meta.NewTime(now.Add(-age))}}
+	}
+
+	tests := []struct {
+		name    string
+		nodes   []*corev1.Node
+		sorters []scheduler.LessFunc[*corev1.Node]
+		want    []string
+	}{
+		{
+			name:    "newest first",
+			nodes:   []*corev1.Node{newNode("old", 48*time.Hour), newNode("new", time.Hour), newNode("mid", 24*time.Hour)},
+			sorters: []scheduler.LessFunc[*corev1.Node]{SortByCreationTimestampDesc},
+			want:    []string{"new", "mid", "old"},
+		},
+		{
+			name:    "same creation time is ordered by name",
+			nodes:   []*corev1.Node{newNode("d", time.Hour), newNode("c", time.Hour), newNode("a", 48*time.Hour), newNode("b", time.Hour)},
+			sorters: []scheduler.LessFunc[*corev1.Node]{SortByCreationTimestampDesc},
+			want:    []string{"b", "c", "d", "a"},
+		},
+		{
+			name:    "name order wins over the next sorter",
+			nodes:   []*corev1.Node{newNode("a", time.Hour), newNode("b", time.Hour)},
+			sorters: []scheduler.LessFunc[*corev1.Node]{SortByCreationTimestampDesc, func(n1, n2 *corev1.Node) bool { return n1.Name > n2.Name }},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "composed after another sorter",
+			nodes:   []*corev1.Node{newNode("b-old", 48*time.Hour), newNode("a-old", 48*time.Hour), newNode("b-new", time.Hour), newNode("a-new", time.Hour)},
+			sorters: []scheduler.LessFunc[*corev1.Node]{func(n1, n2 *corev1.Node) bool { return n1.Name[0] < n2.Name[0] }, SortByCreationTimestampDesc},
+			want:    []string{"a-new", "a-old", "b-new", "b-old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := scheduler.NewSortingTreeWithInitialization(tt.nodes, tt.sorters)
+			var got []string
+			for n, ok := tree.Next(); ok; n, ok = tree.Next() {
+				got = append(got, n.Name)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	n1, n2 := newNode("n1", time.Hour), newNode("n2", time.Hour)
+	assert.True(t, SortByCreationTimestampDesc(n1, n2), "nodes created at the same time are ordered by name")
+	assert.False(t, SortByCreationTimestampDesc(n2, n1), "nodes created at the same time are ordered by name")
+	assert.False(t, SortByCreationTimestampDesc(n1, n1))
+}
@@ -0,0 +1,20 @@
+package sorters
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// DefaultDrainPreferredLabelKey is the label set by the operators on the nodes to drain first, for example the nodes flagged for decommission
+const DefaultDrainPreferredLabelKey = "draino/drain-preferred"
+
+// NewDrainPreferredComparator puts first the nodes having the label set to "true".
+// The preferred nodes are equal between them: the next sorters, or the initial order of the nodes, decide between them.
+func NewDrainPreferredComparator(labelKey string) func(n1, n2 *v1.Node) bool {
+	return func(n1, n2 *v1.Node) bool {
+		return isDrainPreferred(n1, labelKey) && !isDrainPreferred(n2, labelKey)
+	}
+}
+
+func isDrainPreferred(n *v1.Node, labelKey string) bool {
+	return n.Labels[labelKey] == "true"
+}
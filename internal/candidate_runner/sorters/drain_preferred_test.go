@@ -0,0 +1,40 @@
+package sorters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/scheduler"
+)
+
+func TestDrainPreferredComparator(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	newNode := func(name string, age time.Duration, preferred string) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: meta.ObjectMeta{Name: name, CreationTimestamp: meta.NewTime(now.Add(-age)), Labels: map[string]string{}}}
+		if preferred != "" {
+			n.Labels[DefaultDrainPreferredLabelKey] = preferred
+		}
+		return n
+	}
+	preferred := NewDrainPreferredComparator(DefaultDrainPreferredLabelKey)
+
+	nodes := []*corev1.Node{
+		newNode("new", time.Hour, ""),
+		newNode("old-preferred", 48*time.Hour, "true"),
+		newNode("mid", 24*time.Hour, "false"),
+		newNode("older-preferred", 72*time.Hour, "true"),
+	}
+	tree := scheduler.NewSortingTreeWithInitialization(nodes, []scheduler.LessFunc[*corev1.Node]{preferred, SortByCreationTimestampDesc})
+	var got []string
+	for n, ok := tree.Next(); ok; n, ok = tree.Next() {
+		got = append(got, n.Name)
+	}
+	assert.Equal(t, []string{"old-preferred", "older-preferred", "new", "mid"}, got, "the preferred nodes first regardless of their age")
+
+	assert.False(t, preferred(nodes[1], nodes[3]), "preferred nodes are equal")
+	assert.False(t, preferred(nodes[0], nodes[2]), "only the value true marks a node as preferred")
+}
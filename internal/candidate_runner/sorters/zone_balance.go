@@ -0,0 +1,49 @@
+package sorters
+
+import (
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+// zoneCountsTTL bounds the age of the zone counts, they are shared by all the comparisons of a candidate pass
+const zoneCountsTTL = 10 * time.Second
+
+type zoneBalanceComparator struct {
+	store kubernetes.NodeStore
+	clock clock.Clock
+
+	sync.Mutex
+	counts    map[string]int
+	countedAt time.Time
+}
+
+// NewZoneBalanceComparator puts first the nodes of the zones having the most nodes, so that the scale-downs balance the zones.
+// The zones are given by the topology.kubernetes.io/zone label, the nodes without zone come last.
+func NewZoneBalanceComparator(store kubernetes.NodeStore, clock clock.Clock) func(n1, n2 *v1.Node) bool {
+	c := &zoneBalanceComparator{store: store, clock: clock}
+	return func(n1, n2 *v1.Node) bool {
+		counts := c.getZoneCounts()
+		return counts[n1.Labels[v1.LabelTopologyZone]] > counts[n2.Labels[v1.LabelTopologyZone]]
+	}
+}
+
+func (c *zoneBalanceComparator) getZoneCounts() map[string]int {
+	c.Lock()
+	defer c.Unlock()
+	if c.counts != nil && c.clock.Since(c.countedAt) < zoneCountsTTL {
+		return c.counts
+	}
+	c.counts = map[string]int{}
+	for _, n := range c.store.ListNodes() {
+		if zone, ok := n.Labels[v1.LabelTopologyZone]; ok && zone != "" {
+			c.counts[zone]++
+		}
+	}
+	c.countedAt = c.clock.Now()
+	return c.counts
+}
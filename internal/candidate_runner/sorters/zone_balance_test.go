@@ -0,0 +1,71 @@
+package sorters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/scheduler"
+)
+
+type staticNodeStore struct {
+	nodes []*corev1.Node
+}
+
+var _ kubernetes.NodeStore = &staticNodeStore{}
+
+func (s *staticNodeStore) HasSynced() bool { return true }
+
+func (s *staticNodeStore) Get(name string) (*corev1.Node, error) {
+	for _, n := range s.nodes {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *staticNodeStore) ListNodes() []*corev1.Node { return s.nodes }
+
+func TestZoneBalanceComparator(t *testing.T) {
+	newNode := func(name, zone string) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: meta.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		if zone != "" {
+			n.Labels[corev1.LabelTopologyZone] = zone
+		}
+		return n
+	}
+	// zone a has 3 nodes, zone b 2 and zone c 1
+	store := &staticNodeStore{nodes: []*corev1.Node{
+		newNode("a-1", "a"), newNode("a-2", "a"), newNode("a-3", "a"),
+		newNode("b-1", "b"), newNode("b-2", "b"),
+		newNode("c-1", "c"),
+		newNode("no-zone", ""),
+	}}
+	clock := clocktesting.NewFakeClock(time.Now())
+	byName := func(n1, n2 *corev1.Node) bool { return n1.Name < n2.Name }
+
+	candidates := []*corev1.Node{store.nodes[6], store.nodes[5], store.nodes[3], store.nodes[0]}
+	sortCandidates := func() []string {
+		tree := scheduler.NewSortingTreeWithInitialization(candidates, []scheduler.LessFunc[*corev1.Node]{NewZoneBalanceComparator(store, clock), byName})
+		var got []string
+		for n, ok := tree.Next(); ok; n, ok = tree.Next() {
+			got = append(got, n.Name)
+		}
+		return got
+	}
+	assert.Equal(t, []string{"a-1", "b-1", "c-1", "no-zone"}, sortCandidates(), "the nodes of the larger zones first, the nodes without zone last")
+
+	// zone c becomes the largest one
+	for _, name := range []string{"c-2", "c-3", "c-4"} {
+		store.nodes = append(store.nodes, newNode(name, "c"))
+	}
+	assert.Equal(t, []string{"c-1", "a-1", "b-1", "no-zone"}, sortCandidates())
+
+	assert.False(t, NewZoneBalanceComparator(store, clock)(store.nodes[0], store.nodes[1]), "nodes of the same zone are equal")
+}
@@ -122,7 +122,25 @@ func (h *CLICommands) buildNodeCmd() *cobra.Command {
 		},
 	}
 
-	nodeCmd.AddCommand(nodeDiagnosticsCmd)
+	nodeSchedulesCmd := &cobra.Command{
+		Use:        "schedules",
+		SuggestFor: []string{"schedules"},
+		Args:       cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return h.cmdNodeSchedules()
+		},
+	}
+
+	nodeExplainCmd := &cobra.Command{
+		Use:        "explain",
+		SuggestFor: []string{"explain"},
+		Args:       cobra.ExactArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return h.cmdNodeExplain()
+		},
+	}
+
+	nodeCmd.AddCommand(nodeDiagnosticsCmd, nodeSchedulesCmd, nodeExplainCmd)
 	return nodeCmd
 }
 
@@ -164,6 +182,79 @@ func (h *CLICommands) cmdNodeDiagnostics() error {
 	return nil
 }
 
+func (h *CLICommands) cmdNodeExplain() error {
+	params := url.Values{}
+	params.Add("node-name", h.nodeName)
+	b, err := ReadFromURL("http://" + *h.ServerAddr + "/nodes/explain?" + params.Encode())
+	if err != nil {
+		return err
+	}
+
+	if h.outputFormat == FormatJSON {
+		fmt.Printf("%s", string(b))
+		return nil
+	}
+
+	var result diagnostics.NodeExplanation
+	if err := json.Unmarshal(b, &result); err != nil {
+		return err
+	}
+	for _, e := range result.Errors {
+		fmt.Printf("Error: %v\n", e)
+	}
+
+	table := table.NewTable([]string{"Stage", "Pass", "Reason"},
+		func(obj interface{}) []string {
+			item := obj.(diagnostics.ExplainStep)
+			return []string{
+				item.Stage,
+				strconv.FormatBool(item.Pass),
+				item.Reason,
+			}
+		})
+
+	for _, s := range result.Steps {
+		table.Add(s)
+	}
+	h.tableOutputParams.Apply(table)
+	table.Display(os.Stdout)
+	return nil
+}
+
+func (h *CLICommands) cmdNodeSchedules() error {
+	b, err := ReadFromURL("http://" + *h.ServerAddr + "/nodes/schedules")
+	if err != nil {
+		return err
+	}
+
+	if h.outputFormat == FormatJSON {
+		fmt.Printf("%s", string(b))
+		return nil
+	}
+
+	var result []drain_runner.ScheduledDrain
+	if err := json.Unmarshal(b, &result); err != nil {
+		return err
+	}
+
+	table := table.NewTable([]string{"Node", "Scheduled", "Failed"},
+		func(obj interface{}) []string {
+			item := obj.(drain_runner.ScheduledDrain)
+			return []string{
+				item.NodeName,
+				h.outputDurationOrTimestamp(item.ScheduledTime),
+				strconv.Itoa(item.FailedCount),
+			}
+		})
+
+	for _, s := range result {
+		table.Add(s)
+	}
+	h.tableOutputParams.Apply(table)
+	table.Display(os.Stdout)
+	return nil
+}
+
 func (h *CLICommands) cmdGroupGraphLast() error {
 	params := url.Values{}
 	params.Add("group-name", h.groupName)
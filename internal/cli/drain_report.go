@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+)
+
+// drainReportConcurrency bounds the number of drain simulations running at the same time for /cluster/drain-report
+const drainReportConcurrency = 8
+
+// ClusterDrainReport is the drainability of all the nodes in scope returned by /cluster/drain-report
+type ClusterDrainReport struct {
+	NodeCount      int           `json:"nodeCount"`
+	DrainableCount int           `json:"drainableCount"`
+	BlockedNodes   []BlockedNode `json:"blockedNodes,omitempty"`
+	BlockingPDBs   []BlockingPDB `json:"blockingPDBs,omitempty"`
+}
+
+// BlockedNode is a node whose drain simulation failed
+type BlockedNode struct {
+	Node    string   `json:"node"`
+	Group   string   `json:"group"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// BlockingPDB is a PDB rejecting the eviction of pods, with the number of nodes it blocks
+type BlockingPDB struct {
+	PDB          string `json:"pdb"`
+	BlockedNodes int    `json:"blockedNodes"`
+}
+
+// SetDrainReport sets the drain simulator and the scope filter used by /cluster/drain-report
+func (c *CLIHandlers) SetDrainReport(simulator drain.DrainSimulator, nodeLabelFilter kubernetes.NodeLabelFilterFunc) {
+	c.drainSimulator = simulator
+	c.nodeLabelFilter = nodeLabelFilter
+}
+
+// handleClusterDrainReport simulates the drain of all the nodes in scope and returns the cluster wide report
+func (h *CLIHandlers) handleClusterDrainReport(writer http.ResponseWriter, request *http.Request) {
+	h.logger.Info("handleClusterDrainReport", "path", request.URL.Path)
+
+	if h.drainSimulator == nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	report, err := h.getClusterDrainReport(request.Context())
+	if err != nil {
+		h.logger.Error(err, "failed to compute drain report")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		h.logger.Error(err, "failed to marshal drain report")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(data)
+}
+
+func (h *CLIHandlers) getClusterDrainReport(ctx context.Context) (ClusterDrainReport, error) {
+	var nodes []*corev1.Node
+	groupOfNode := map[string]string{}
+	for key := range h.keysGetter.GetRunnerInfo() {
+		groupNodes, err := h.candidateInfo.GetNodes(ctx, key)
+		if err != nil {
+			return ClusterDrainReport{}, err
+		}
+		for _, n := range groupNodes {
+			if _, ok := groupOfNode[n.Name]; ok {
+				continue
+			}
+			if h.nodeLabelFilter != nil && !h.nodeLabelFilter(n) {
+				continue
+			}
+			groupOfNode[n.Name] = string(key)
+			nodes = append(nodes, n)
+		}
+	}
+
+	// The simulations are spread over a bounded number of workers, so that the report does not exhaust the simulation rate limiter at once.
+	// They don't emit events nor record metrics, the report can be requested as often as needed.
+	results := make([]BlockedNode, len(nodes))
+	simulations := make([]drain.DrainSimulationReport, len(nodes))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < drainReportConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				simulations[i] = h.drainSimulator.ReportDrain(ctx, nodes[i])
+				reasons := simulations[i].Reasons
+				for _, err := range simulations[i].Errors {
+					reasons = append(reasons, err.Error())
+				}
+				results[i] = BlockedNode{Node: nodes[i].Name, Group: groupOfNode[nodes[i].Name], Reasons: reasons}
+			}
+		}()
+	}
+	for i := range nodes {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	report := ClusterDrainReport{NodeCount: len(nodes)}
+	pdbNodes := map[string]int{}
+	for i := range nodes {
+		if simulations[i].CanDrain {
+			report.DrainableCount++
+			continue
+		}
+		report.BlockedNodes = append(report.BlockedNodes, results[i])
+		// the PDBs are listed once per node
+		for _, pdb := range simulations[i].BlockingPDBs {
+			pdbNodes[pdb]++
+		}
+	}
+	for pdb, count := range pdbNodes {
+		report.BlockingPDBs = append(report.BlockingPDBs, BlockingPDB{PDB: pdb, BlockedNodes: count})
+	}
+	sort.Slice(report.BlockedNodes, func(i, j int) bool { return report.BlockedNodes[i].Node < report.BlockedNodes[j].Node })
+	sort.Slice(report.BlockingPDBs, func(i, j int) bool {
+		if report.BlockingPDBs[i].BlockedNodes != report.BlockingPDBs[j].BlockedNodes {
+			return report.BlockingPDBs[i].BlockedNodes > report.BlockingPDBs[j].BlockedNodes
+		}
+		return strings.Compare(report.BlockingPDBs[i].PDB, report.BlockingPDBs[j].PDB) < 0
+	})
+	return report, nil
+}
@@ -8,17 +8,47 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/planetlabs/draino/internal/candidate_runner"
 	"github.com/planetlabs/draino/internal/diagnostics"
+	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	"github.com/planetlabs/draino/internal/drain_runner"
 	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 	"net/http"
+	"sort"
+	"time"
 )
 
+// recentFailuresWindow is the period over which the drain failures of a group are counted by /groups/metrics
+const recentFailuresWindow = 24 * time.Hour
+
 type CLIHandlers struct {
 	keysGetter    groups.RunnerInfoGetter
 	candidateInfo candidate_runner.CandidateInfo
 	drainInfo     drain_runner.DrainInfo
 	diagnostics   diagnostics.Diagnostician
 	logger        logr.Logger
+
+	effectiveConfig interface{}
+	scopeAnalysis   ScopeAnalysisTrigger
+	explainer       diagnostics.Explainer
+	drainBuffer     drainbuffer.DrainBuffer
+	drainSimulator  drain.DrainSimulator
+	nodeLabelFilter kubernetes.NodeLabelFilterFunc
+	configurations  []ConfigurationInfo
+}
+
+// ConfigurationInfo gives access to the groups of an additional configuration, they have their own runners and drain buffer
+type ConfigurationInfo struct {
+	Name          string
+	KeysGetter    groups.RunnerInfoGetter
+	CandidateInfo candidate_runner.CandidateInfo
+	DrainBuffer   drainbuffer.DrainBuffer
+}
+
+// ScopeAnalysisTrigger requests an immediate analysis of the scope, it returns false if an analysis is already pending
+type ScopeAnalysisTrigger interface {
+	TriggerAnalysis() bool
 }
 
 func (c *CLIHandlers) Initialize(logger logr.Logger,
@@ -36,14 +66,51 @@ func (c *CLIHandlers) Initialize(logger logr.Logger,
 	return nil
 }
 
+// SetEffectiveConfig sets the configuration returned by /debug/config. It must be safe to marshal and free of secrets.
+func (c *CLIHandlers) SetEffectiveConfig(config interface{}) {
+	c.effectiveConfig = config
+}
+
+// SetScopeAnalysisTrigger sets the scope observer triggered by /scope/analyze
+func (c *CLIHandlers) SetScopeAnalysisTrigger(trigger ScopeAnalysisTrigger) {
+	c.scopeAnalysis = trigger
+}
+
+// SetExplainer sets the explainer used by /nodes/explain
+func (c *CLIHandlers) SetExplainer(explainer diagnostics.Explainer) {
+	c.explainer = explainer
+}
+
+// SetDrainBuffer sets the drain buffer of the main configuration read by /groups/metrics
+func (c *CLIHandlers) SetDrainBuffer(drainBuffer drainbuffer.DrainBuffer) {
+	c.drainBuffer = drainBuffer
+}
+
+// AddConfiguration adds the groups of an additional configuration to /groups/metrics
+func (c *CLIHandlers) AddConfiguration(info ConfigurationInfo) {
+	c.configurations = append(c.configurations, info)
+}
+
 func (c *CLIHandlers) RegisterRoute(m *mux.Router) {
 	sg := m.PathPrefix("/groups").Subrouter() //Handler(groupRouter)
 	sg.HandleFunc("/list", c.handleGroupsList)
 	sg.HandleFunc("/nodes", c.handleGroupsNodes)
 	sg.HandleFunc("/graph/last", c.handleGroupsGraphLast)
+	sg.HandleFunc("/metrics", c.handleGroupsMetrics)
 
 	sn := m.PathPrefix("/nodes").Subrouter() //Handler(groupRouter)
 	sn.HandleFunc("/diagnostics", c.handleNodesDiagnostics)
+	sn.HandleFunc("/schedules", c.handleNodesSchedules)
+	sn.HandleFunc("/explain", c.handleNodesExplain)
+
+	sd := m.PathPrefix("/debug").Subrouter()
+	sd.HandleFunc("/config", c.handleDebugConfig)
+
+	ss := m.PathPrefix("/scope").Subrouter()
+	ss.HandleFunc("/analyze", c.handleScopeAnalyze)
+
+	sc := m.PathPrefix("/cluster").Subrouter()
+	sc.HandleFunc("/drain-report", c.handleClusterDrainReport)
 }
 
 // handleGroupsList list all groups
@@ -65,6 +132,102 @@ func (h *CLIHandlers) handleGroupsList(writer http.ResponseWriter, request *http
 	writer.Write(data)
 }
 
+// GroupMetrics is the operational state of a group returned by /groups/metrics
+type GroupMetrics struct {
+	// Configuration is empty for the groups of the main configuration
+	Configuration               string     `json:"configuration,omitempty"`
+	Group                       string     `json:"group"`
+	CandidateCount              int        `json:"candidateCount"`
+	InFlightDrains              int        `json:"inFlightDrains"`
+	LastDrainTime               *time.Time `json:"lastDrainTime,omitempty"`
+	DrainBufferRemainingSeconds float64    `json:"drainBufferRemainingSeconds"`
+	RecentFailureCount          int        `json:"recentFailureCount"`
+	// Error is set if the metrics of the group could not be computed, the other fields are partial
+	Error string `json:"error,omitempty"`
+}
+
+// handleGroupsMetrics returns the operational state of all the active groups, sorted by configuration and group.
+// A group whose metrics cannot be computed is returned with its error, it does not fail the other groups.
+func (h *CLIHandlers) handleGroupsMetrics(writer http.ResponseWriter, request *http.Request) {
+	h.logger.Info("handleGroupsMetrics", "path", request.URL.Path)
+
+	now := time.Now()
+	configurations := append([]ConfigurationInfo{{KeysGetter: h.keysGetter, CandidateInfo: h.candidateInfo, DrainBuffer: h.drainBuffer}}, h.configurations...)
+	result := []GroupMetrics{}
+	for _, configuration := range configurations {
+		for key, info := range configuration.KeysGetter.GetRunnerInfo() {
+			metrics, err := getGroupMetrics(request.Context(), configuration, key, info, now)
+			if err != nil {
+				h.logger.Error(err, "failed to compute group metrics", "configuration", configuration.Name, "group", key)
+				metrics.Error = err.Error()
+			}
+			result = append(result, metrics)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Configuration != result[j].Configuration {
+			return result[i].Configuration < result[j].Configuration
+		}
+		return result[i].Group < result[j].Group
+	})
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error(err, "failed to marshal group metrics")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(data)
+}
+
+func getGroupMetrics(ctx context.Context, configuration ConfigurationInfo, key groups.GroupKey, info groups.RunnerInfo, now time.Time) (GroupMetrics, error) {
+	metrics := GroupMetrics{Configuration: configuration.Name, Group: string(key)}
+	if info.Data != nil {
+		if raw, ok := info.Data.Get(candidate_runner.CandidateRunnerInfoKey); ok {
+			if candidateDataInfo, ok := raw.(candidate_runner.DataInfo); ok {
+				metrics.CandidateCount = len(candidateDataInfo.CurrentCandidates)
+			}
+		}
+	}
+
+	nodes, err := configuration.CandidateInfo.GetNodes(ctx, key)
+	if err != nil {
+		return metrics, err
+	}
+	for _, n := range nodes {
+		if taint, ok := k8sclient.GetNLATaint(n); ok && taint.Value == k8sclient.TaintDraining {
+			metrics.InFlightDrains++
+		}
+		// A corrupted history is not counted, it is replaced at the next drain attempt
+		history, _ := kubernetes.GetDrainHistory(n)
+		for _, entry := range history {
+			if entry.Result == kubernetes.FailedStr && now.Sub(entry.Timestamp) <= recentFailuresWindow {
+				metrics.RecentFailureCount++
+			}
+		}
+	}
+
+	drainBuffer := configuration.DrainBuffer
+	if drainBuffer != nil && drainBuffer.IsReady() {
+		lastDrain, _, err := drainBuffer.LastDrain(key)
+		if err != nil {
+			return metrics, err
+		}
+		if !lastDrain.IsZero() {
+			metrics.LastDrainTime = &lastDrain
+		}
+		nextDrain, err := drainBuffer.NextDrain(key)
+		if err != nil {
+			return metrics, err
+		}
+		if nextDrain.After(now) {
+			metrics.DrainBufferRemainingSeconds = nextDrain.Sub(now).Seconds()
+		}
+	}
+	return metrics, nil
+}
+
 // handleGroupsNodes display the list of nodes (and diagnostics) associated with a group
 func (h *CLIHandlers) handleGroupsNodes(writer http.ResponseWriter, request *http.Request) {
 	groupName := request.URL.Query().Get("group-name")
@@ -163,3 +326,82 @@ func (h *CLIHandlers) handleNodesDiagnostics(writer http.ResponseWriter, request
 	writer.WriteHeader(http.StatusOK)
 	writer.Write(data)
 }
+
+// handleNodesExplain traces the node through the decision pipeline
+func (h *CLIHandlers) handleNodesExplain(writer http.ResponseWriter, request *http.Request) {
+	nodeName := request.URL.Query().Get("node-name")
+	h.logger.Info("handleNodesExplain", "path", request.URL.Path, "nodeName", nodeName)
+
+	if h.explainer == nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	result := h.explainer.ExplainNode(request.Context(), nodeName)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error(err, "failed to marshal explain result")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(data)
+}
+
+// handleNodesSchedules list all the nodes waiting to be drained
+func (h *CLIHandlers) handleNodesSchedules(writer http.ResponseWriter, request *http.Request) {
+	h.logger.Info("handleNodesSchedules", "path", request.URL.Path)
+
+	schedules, err := h.drainInfo.ListSchedules(request.Context())
+	if err != nil {
+		h.logger.Error(err, "failed to list schedules")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(schedules)
+	if err != nil {
+		h.logger.Error(err, "failed to marshal schedules")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(data)
+}
+
+// handleDebugConfig returns the effective configuration of draino
+func (h *CLIHandlers) handleDebugConfig(writer http.ResponseWriter, request *http.Request) {
+	h.logger.Info("handleDebugConfig", "path", request.URL.Path)
+
+	if h.effectiveConfig == nil {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data, err := json.Marshal(h.effectiveConfig)
+	if err != nil {
+		h.logger.Error(err, "failed to marshal effective config")
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(data)
+}
+
+// handleScopeAnalyze triggers an immediate scope analysis. The request is accepted even if an analysis is already pending.
+func (h *CLIHandlers) handleScopeAnalyze(writer http.ResponseWriter, request *http.Request) {
+	h.logger.Info("handleScopeAnalyze", "path", request.URL.Path)
+
+	if request.Method != http.MethodPost {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.scopeAnalysis == nil {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !h.scopeAnalysis.TriggerAnalysis() {
+		h.logger.Info("scope analysis already pending")
+	}
+	writer.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/candidate_runner"
+	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+)
+
+// fakeScopeAnalysisTrigger counts the requested passes, the merge of the pending requests is tested on the scope observer
+type fakeScopeAnalysisTrigger struct {
+	passes int
+}
+
+func (f *fakeScopeAnalysisTrigger) TriggerAnalysis() bool {
+	f.passes++
+	return true
+}
+
+func TestCLIHandlers_handleScopeAnalyze(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		trigger        *fakeScopeAnalysisTrigger
+		calls          int
+		expectedStatus int
+		expectedPasses int
+	}{
+		{
+			name:           "runs one analysis pass",
+			method:         http.MethodPost,
+			trigger:        &fakeScopeAnalysisTrigger{},
+			calls:          1,
+			expectedStatus: http.StatusAccepted,
+			expectedPasses: 1,
+		},
+		{
+			name:           "only accepts POST",
+			method:         http.MethodGet,
+			trigger:        &fakeScopeAnalysisTrigger{},
+			calls:          1,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedPasses: 0,
+		},
+		{
+			name:           "no scope observer",
+			method:         http.MethodPost,
+			calls:          1,
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &CLIHandlers{logger: logr.Discard()}
+			if tt.trigger != nil {
+				h.SetScopeAnalysisTrigger(tt.trigger)
+			}
+			router := mux.NewRouter()
+			h.RegisterRoute(router)
+
+			for i := 0; i < tt.calls; i++ {
+				recorder := httptest.NewRecorder()
+				router.ServeHTTP(recorder, httptest.NewRequest(tt.method, "/scope/analyze", nil))
+				assert.Equal(t, tt.expectedStatus, recorder.Code)
+			}
+			if tt.trigger != nil {
+				assert.Equal(t, tt.expectedPasses, tt.trigger.passes)
+			}
+		})
+	}
+}
+
+type fakeRunnerInfoGetter map[groups.GroupKey]groups.RunnerInfo
+
+func (f fakeRunnerInfoGetter) GetRunnerInfo() map[groups.GroupKey]groups.RunnerInfo {
+	return f
+}
+
+type fakeCandidateInfo struct {
+	candidate_runner.CandidateInfo
+	nodes map[groups.GroupKey][]*corev1.Node
+	errs  map[groups.GroupKey]error
+}
+
+func (f *fakeCandidateInfo) GetNodes(_ context.Context, key groups.GroupKey) ([]*corev1.Node, error) {
+	return f.nodes[key], f.errs[key]
+}
+
+type fakeDrainBuffer struct {
+	drainbuffer.DrainBuffer
+	lastDrains map[groups.GroupKey]time.Time
+	buffer     time.Duration
+}
+
+func (f *fakeDrainBuffer) IsReady() bool { return true }
+
+func (f *fakeDrainBuffer) LastDrain(key groups.GroupKey) (time.Time, time.Duration, error) {
+	if f.lastDrains[key].IsZero() {
+		return time.Time{}, 0, nil
+	}
+	return f.lastDrains[key], f.buffer, nil
+}
+
+func (f *fakeDrainBuffer) NextDrain(key groups.GroupKey) (time.Time, error) {
+	if f.lastDrains[key].IsZero() {
+		return time.Time{}, nil
+	}
+	return f.lastDrains[key].Add(f.buffer), nil
+}
+
+func TestCLIHandlers_handleGroupsMetrics(t *testing.T) {
+	now := time.Now()
+	newNode := func(name string, taint k8sclient.DrainTaintValue, failures ...time.Time) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if taint != "" {
+			node.Spec.Taints = []corev1.Taint{*k8sclient.CreateNLATaint(taint, now)}
+		}
+		var history []kubernetes.DrainHistoryEntry
+		for _, failure := range failures {
+			history = append(history, kubernetes.DrainHistoryEntry{Timestamp: failure, Result: kubernetes.FailedStr})
+		}
+		if len(history) > 0 {
+			value, _ := json.Marshal(history)
+			node.Annotations = map[string]string{kubernetes.DrainHistoryAnnotationKey: string(value)}
+		}
+		return node
+	}
+	withCandidates := func(candidates ...string) *utils.DataMap {
+		data := utils.NewDataMap()
+		data.Set(candidate_runner.CandidateRunnerInfoKey, candidate_runner.DataInfo{CurrentCandidates: candidates})
+		return data
+	}
+
+	h := &CLIHandlers{logger: logr.Discard()}
+	h.keysGetter = fakeRunnerInfoGetter{
+		"group-a": {Key: "group-a", Data: withCandidates("a-1", "a-2")},
+		"group-b": {Key: "group-b", Data: withCandidates()},
+	}
+	h.candidateInfo = &fakeCandidateInfo{nodes: map[groups.GroupKey][]*corev1.Node{
+		"group-a": {
+			newNode("a-1", k8sclient.TaintDrainCandidate),
+			newNode("a-2", k8sclient.TaintDraining, now.Add(-time.Hour), now.Add(-48*time.Hour)),
+			newNode("a-3", ""),
+		},
+		"group-b": {
+			newNode("b-1", "", now.Add(-2*time.Hour), now.Add(-time.Hour)),
+		},
+	}}
+	lastDrain := now.Add(-2 * time.Minute).UTC().Truncate(time.Second)
+	h.SetDrainBuffer(&fakeDrainBuffer{lastDrains: map[groups.GroupKey]time.Time{"group-a": lastDrain}, buffer: 10 * time.Minute})
+	// the additional configuration has its own groups and drain buffer, one of its groups cannot be read
+	h.AddConfiguration(ConfigurationInfo{
+		Name: "batch",
+		KeysGetter: fakeRunnerInfoGetter{
+			"group-a": {Key: "group-a", Data: withCandidates()},
+			"group-c": {Key: "group-c", Data: withCandidates()},
+		},
+		CandidateInfo: &fakeCandidateInfo{
+			nodes: map[groups.GroupKey][]*corev1.Node{"group-a": {newNode("batch-a-1", "")}},
+			errs:  map[groups.GroupKey]error{"group-c": errors.New("index not ready")},
+		},
+		DrainBuffer: &fakeDrainBuffer{lastDrains: map[groups.GroupKey]time.Time{}, buffer: 10 * time.Minute},
+	})
+
+	router := mux.NewRouter()
+	h.RegisterRoute(router)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/groups/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var result []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Len(t, result, 4)
+
+	groupA, groupB, batchGroupA, batchGroupC := result[0], result[1], result[2], result[3]
+	assert.NotContains(t, groupA, "configuration")
+	assert.NotContains(t, groupA, "error")
+	assert.Equal(t, "group-a", groupA["group"])
+	assert.Equal(t, float64(2), groupA["candidateCount"])
+	assert.Equal(t, float64(1), groupA["inFlightDrains"])
+	assert.Equal(t, lastDrain.Format(time.RFC3339), groupA["lastDrainTime"])
+	assert.InDelta(t, 8*60, groupA["drainBufferRemainingSeconds"], 5)
+	assert.Equal(t, float64(1), groupA["recentFailureCount"], "the failures older than the window are not counted")
+
+	assert.Equal(t, "group-b", groupB["group"])
+	assert.Equal(t, float64(0), groupB["candidateCount"])
+	assert.Equal(t, float64(0), groupB["inFlightDrains"])
+	assert.NotContains(t, groupB, "lastDrainTime")
+	assert.Equal(t, float64(0), groupB["drainBufferRemainingSeconds"])
+	assert.Equal(t, float64(2), groupB["recentFailureCount"])
+
+	assert.Equal(t, "batch", batchGroupA["configuration"])
+	assert.Equal(t, "group-a", batchGroupA["group"])
+	assert.NotContains(t, batchGroupA, "lastDrainTime", "the drain buffer of the configuration is used")
+
+	assert.Equal(t, "batch", batchGroupC["configuration"])
+	assert.Equal(t, "group-c", batchGroupC["group"])
+	assert.Equal(t, "index not ready", batchGroupC["error"])
+}
+
+type fakeDrainSimulator struct {
+	drain.DrainSimulator
+	reasons map[string][]string
+	pdbs    map[string][]string
+}
+
+func (f *fakeDrainSimulator) ReportDrain(_ context.Context, node *corev1.Node) drain.DrainSimulationReport {
+	reasons := f.reasons[node.Name]
+	return drain.DrainSimulationReport{CanDrain: len(reasons) == 0, Reasons: reasons, BlockingPDBs: f.pdbs[node.Name]}
+}
+
+func TestCLIHandlers_handleClusterDrainReport(t *testing.T) {
+	newNode := func(name string, inScope bool) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"in-scope": strconv.FormatBool(inScope)}}}
+	}
+
+	h := &CLIHandlers{logger: logr.Discard()}
+	h.keysGetter = fakeRunnerInfoGetter{
+		"group-a": {Key: "group-a"},
+		"group-b": {Key: "group-b"},
+	}
+	h.candidateInfo = &fakeCandidateInfo{nodes: map[groups.GroupKey][]*corev1.Node{
+		"group-a": {newNode("a-1", true), newNode("a-2", true), newNode("a-3", false)},
+		"group-b": {newNode("b-1", true), newNode("b-2", true)},
+	}}
+	h.SetDrainReport(&fakeDrainSimulator{reasons: map[string][]string{
+		"a-2": {"Cannot drain pod 'default/foo-1', because: PDB 'foo-pdb' does not allow any disruptions"},
+		"a-3": {"Cannot drain pod 'default/foo-2', because: PDB 'foo-pdb' does not allow any disruptions"},
+		"b-1": {
+			"Cannot drain pod 'default/foo-3', because: PDB 'foo-pdb' does not allow any disruptions, reason: lockness[test]",
+			"Cannot drain pod 'team/bar-1', because: Eviction dry run was not successful: forbidden",
+		},
+	}, pdbs: map[string][]string{
+		"a-2": {"default/foo-pdb"},
+		"a-3": {"default/foo-pdb"},
+		"b-1": {"default/foo-pdb"},
+	}}, func(o interface{}) bool { return o.(*corev1.Node).Labels["in-scope"] == "true" })
+
+	router := mux.NewRouter()
+	h.RegisterRoute(router)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cluster/drain-report", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var report ClusterDrainReport
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, 4, report.NodeCount, "the nodes out of scope are not simulated")
+	assert.Equal(t, 2, report.DrainableCount)
+	assert.Equal(t, []BlockedNode{
+		{Node: "a-2", Group: "group-a", Reasons: []string{"Cannot drain pod 'default/foo-1', because: PDB 'foo-pdb' does not allow any disruptions"}},
+		{Node: "b-1", Group: "group-b", Reasons: []string{
+			"Cannot drain pod 'default/foo-3', because: PDB 'foo-pdb' does not allow any disruptions, reason: lockness[test]",
+			"Cannot drain pod 'team/bar-1', because: Eviction dry run was not successful: forbidden",
+		}},
+	}, report.BlockedNodes)
+	assert.Equal(t, []BlockingPDB{{PDB: "default/foo-pdb", BlockedNodes: 2}}, report.BlockingPDBs)
+}
+
+func TestCLIHandlers_handleClusterDrainReport_NoSimulator(t *testing.T) {
+	h := &CLIHandlers{logger: logr.Discard()}
+	router := mux.NewRouter()
+	h.RegisterRoute(router)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/cluster/drain-report", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
@@ -0,0 +1,122 @@
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+)
+
+// Names of the stages reported by ExplainNode, in the order they are evaluated
+const (
+	ExplainStageScope           = "scope"
+	ExplainStageConditions      = "conditions"
+	ExplainStageFilters         = "filters"
+	ExplainStageDrainBuffer     = "drain_buffer"
+	ExplainStageStabilityPeriod = "stability_period"
+	ExplainStageSimulation      = "simulation"
+)
+
+// explainStageFilterNames maps the stages that are decided by a single candidate filter to the name of that filter
+var explainStageFilterNames = map[string]string{
+	ExplainStageScope:           "labels",
+	ExplainStageConditions:      "conditions",
+	ExplainStageDrainBuffer:     "drain_buffer",
+	ExplainStageStabilityPeriod: "stability_period",
+}
+
+// Explainer walks a node through the decision pipeline and reports the outcome of every stage
+type Explainer interface {
+	ExplainNode(ctx context.Context, nodeName string) NodeExplanation
+}
+
+// ExplainStep is the outcome of one stage of the decision pipeline
+type ExplainStep struct {
+	Stage  string `json:"stage"`
+	Pass   bool   `json:"pass"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// NodeExplanation is the ordered trace of the decision pipeline for a node
+type NodeExplanation struct {
+	Node   string        `json:"node"`
+	Steps  []ExplainStep `json:"steps,omitempty"`
+	Errors []interface{} `json:"errors,omitempty"`
+}
+
+var _ Explainer = &Diagnostics{}
+
+// ExplainNode runs all the stages of the decision pipeline against the node, it does not stop at the first rejection so that the trace is complete
+func (diag *Diagnostics) ExplainNode(ctx context.Context, nodeName string) NodeExplanation {
+	var node v1.Node
+	if err := diag.client.Get(ctx, types.NamespacedName{Name: nodeName}, &node); err != nil {
+		return NodeExplanation{Node: nodeName, Errors: []interface{}{err.Error()}}
+	}
+
+	checks := map[string]filters.CheckOutput{}
+	var otherChecks []filters.CheckOutput
+	isStageFilter := map[string]bool{}
+	for _, name := range explainStageFilterNames {
+		isStageFilter[name] = true
+	}
+	for _, c := range diag.filter.FilterNode(ctx, &node).Checks {
+		if isStageFilter[c.FilterName] {
+			checks[c.FilterName] = c
+			continue
+		}
+		otherChecks = append(otherChecks, c)
+	}
+
+	explanation := NodeExplanation{Node: node.Name}
+	explanation.Steps = append(explanation.Steps,
+		explainFromCheck(ExplainStageScope, checks, "node is out of scope"),
+		explainFromCheck(ExplainStageConditions, checks, "no offending condition"),
+		explainFromChecks(otherChecks),
+		explainFromCheck(ExplainStageDrainBuffer, checks, "drain buffer not elapsed"),
+		explainFromCheck(ExplainStageStabilityPeriod, checks, "stability period not elapsed"),
+		diag.explainSimulation(ctx, &node),
+	)
+	return explanation
+}
+
+func explainFromCheck(stage string, checks map[string]filters.CheckOutput, defaultRejectionReason string) ExplainStep {
+	check, ok := checks[explainStageFilterNames[stage]]
+	if !ok {
+		return ExplainStep{Stage: stage, Pass: true, Reason: "not evaluated"}
+	}
+	step := ExplainStep{Stage: stage, Pass: check.Keep, Reason: check.Reason}
+	if !step.Pass && step.Reason == "" {
+		step.Reason = defaultRejectionReason
+	}
+	return step
+}
+
+func explainFromChecks(checks []filters.CheckOutput) ExplainStep {
+	step := ExplainStep{Stage: ExplainStageFilters, Pass: true}
+	var reasons []string
+	for _, c := range checks {
+		if c.Keep {
+			continue
+		}
+		step.Pass = false
+		if c.Reason == "" {
+			reasons = append(reasons, c.FilterName)
+			continue
+		}
+		reasons = append(reasons, fmt.Sprintf("%s: %s", c.FilterName, c.Reason))
+	}
+	step.Reason = strings.Join(reasons, "; ")
+	return step
+}
+
+func (diag *Diagnostics) explainSimulation(ctx context.Context, node *v1.Node) ExplainStep {
+	canDrain, reasons, errs := diag.drainSimulator.SimulateDrain(ctx, node)
+	for _, err := range errs {
+		reasons = append(reasons, err.Error())
+	}
+	return ExplainStep{Stage: ExplainStageSimulation, Pass: canDrain, Reason: strings.Join(reasons, "; ")}
+}
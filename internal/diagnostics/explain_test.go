@@ -0,0 +1,123 @@
+package diagnostics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+)
+
+func TestDiagnostics_ExplainNode(t *testing.T) {
+	testLabels := map[string]string{"app": "foo"}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "foo-node"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-pod", Namespace: "default", Labels: testLabels},
+		Spec:       corev1.PodSpec{NodeName: "foo-node"},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	maxUnavailable := intstr.FromInt(2)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-pdb", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: testLabels},
+			MaxUnavailable: &maxUnavailable,
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DesiredHealthy: 2, CurrentHealthy: 1},
+	}
+
+	tests := []struct {
+		name           string
+		objects        []runtime.Object
+		expectedSteps  []ExplainStep
+		expectedErrors bool
+	}{
+		{
+			name:    "pdb blocks the simulation",
+			objects: []runtime.Object{node, pod, pdb},
+			expectedSteps: []ExplainStep{
+				{Stage: ExplainStageScope, Pass: true},
+				{Stage: ExplainStageConditions, Pass: true, Reason: "not evaluated"},
+				{Stage: ExplainStageFilters, Pass: false, Reason: "pods: blocked"},
+				{Stage: ExplainStageDrainBuffer, Pass: true, Reason: "not evaluated"},
+				{Stage: ExplainStageStabilityPeriod, Pass: false, Reason: "stability period not elapsed"},
+				{Stage: ExplainStageSimulation, Pass: false, Reason: "Cannot drain pod 'default/foo-pod', because: PDB 'foo-pdb' does not allow any disruptions"},
+			},
+		},
+		{
+			name:    "empty node can be drained",
+			objects: []runtime.Object{node},
+			expectedSteps: []ExplainStep{
+				{Stage: ExplainStageScope, Pass: true},
+				{Stage: ExplainStageConditions, Pass: true, Reason: "not evaluated"},
+				{Stage: ExplainStageFilters, Pass: false, Reason: "pods: blocked"},
+				{Stage: ExplainStageDrainBuffer, Pass: true, Reason: "not evaluated"},
+				{Stage: ExplainStageStabilityPeriod, Pass: false, Reason: "stability period not elapsed"},
+				{Stage: ExplainStageSimulation, Pass: true},
+			},
+		},
+		{
+			name:           "unknown node",
+			objects:        []runtime.Object{},
+			expectedErrors: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := make(chan struct{})
+			defer close(ch)
+			simulator, err := drain.NewFakeDrainSimulator(&drain.FakeSimulatorOptions{
+				Chan:      ch,
+				Objects:   tt.objects,
+				PodFilter: func(p corev1.Pod) (bool, string, error) { return true, "", nil },
+			})
+			assert.NoError(t, err)
+
+			diag := &Diagnostics{
+				client:         fake.NewFakeClient(tt.objects...),
+				logger:         logr.Discard(),
+				clock:          clock.RealClock{},
+				drainSimulator: simulator,
+				filter: &explainTestFilter{checks: []filters.CheckOutput{
+					{FilterName: "labels", Keep: true},
+					{FilterName: "pods", Keep: false, Reason: "blocked"},
+					{FilterName: "stability_period", Keep: false},
+				}},
+			}
+
+			explanation := diag.ExplainNode(context.Background(), "foo-node")
+			assert.Equal(t, "foo-node", explanation.Node)
+			assert.Equal(t, tt.expectedSteps, explanation.Steps)
+			assert.Equal(t, tt.expectedErrors, len(explanation.Errors) > 0)
+		})
+	}
+}
+
+type explainTestFilter struct {
+	checks []filters.CheckOutput
+}
+
+func (f *explainTestFilter) Name() string {
+	return "explain_test"
+}
+
+func (f *explainTestFilter) Filter(ctx context.Context, nodes []*corev1.Node) []*corev1.Node {
+	return nodes
+}
+
+func (f *explainTestFilter) FilterNode(ctx context.Context, n *corev1.Node) filters.FilterOutput {
+	return filters.FilterOutput{Keep: false, Checks: f.checks}
+}
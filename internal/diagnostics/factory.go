@@ -40,3 +40,8 @@ func (factory *Factory) build() *Diagnostics {
 func (factory *Factory) BuildDiagnostician() Diagnostician {
 	return factory.build()
 }
+
+// BuildExplainer returns the explainer that traces a node through the decision pipeline
+func (factory *Factory) BuildExplainer() Explainer {
+	return factory.build()
+}
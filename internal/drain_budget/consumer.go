@@ -0,0 +1,206 @@
+package drain_budget
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Consumer consumes the drain budgets selecting a node before its drain
+type Consumer interface {
+	// TryConsume records the drain of the node in all the budgets selecting it. It returns false, with the name of the
+	// exhausted budget, if one of them doesn't allow one more drain in its window. A node already recorded in the window of
+	// a budget doesn't consume it again, so that the requeue of a drain attempt is not counted twice.
+	TryConsume(ctx context.Context, node *corev1.Node) (allowed bool, exhausted string, err error)
+	// Release removes the drain of the node from the budgets selecting it, when the drain could not start after TryConsume
+	Release(ctx context.Context, node *corev1.Node) error
+}
+
+type consumer struct {
+	kclient client.Client
+	clock   clock.Clock
+}
+
+var _ Consumer = &consumer{}
+
+func NewConsumer(kclient client.Client, clock clock.Clock) Consumer {
+	return &consumer{kclient: kclient, clock: clock}
+}
+
+func (c *consumer) TryConsume(ctx context.Context, node *corev1.Node) (bool, string, error) {
+	budgets, err := c.getNodeBudgets(ctx, node)
+	if err != nil {
+		return false, "", err
+	}
+
+	// all the budgets are checked first, so that an exhausted budget doesn't let the others be consumed
+	now := c.clock.Now()
+	for _, budget := range budgets {
+		if !hasNodeDrain(budget, node.Name, now) && !hasRoom(budget, now) {
+			return false, budget.Name, nil
+		}
+	}
+
+	var consumed []string
+	for _, budget := range budgets {
+		allowed, added := false, false
+		// the update fails on conflict if another drain consumed the budget meanwhile, the budget is read and checked again
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			current, err := c.get(ctx, budget.Name)
+			if err != nil {
+				return err
+			}
+			if hasNodeDrain(current, node.Name, now) {
+				allowed, added = true, false
+				return nil
+			}
+			if allowed = hasRoom(current, now); !allowed {
+				return nil
+			}
+			current.Status.Drains = append(drainsInWindow(current, now), DrainBudgetConsumption{Node: node.Name, Time: metav1.NewTime(now)})
+			added = true
+			return c.update(ctx, current)
+		})
+		if err == nil && added {
+			consumed = append(consumed, budget.Name)
+		}
+		if err != nil || !allowed {
+			// the budgets consumed before this one are rolled back, the drain doesn't start
+			for _, name := range consumed {
+				if errRelease := c.release(ctx, name, node.Name); errRelease != nil {
+					return false, "", fmt.Errorf("cannot roll back drain budget %s: %w", name, errRelease)
+				}
+			}
+			if err != nil {
+				return false, "", fmt.Errorf("cannot consume drain budget %s: %w", budget.Name, err)
+			}
+			return false, budget.Name, nil
+		}
+	}
+	return true, "", nil
+}
+
+func (c *consumer) Release(ctx context.Context, node *corev1.Node) error {
+	budgets, err := c.getNodeBudgets(ctx, node)
+	if err != nil {
+		return err
+	}
+	for _, budget := range budgets {
+		if err := c.release(ctx, budget.Name, node.Name); err != nil {
+			return fmt.Errorf("cannot release drain budget %s: %w", budget.Name, err)
+		}
+	}
+	return nil
+}
+
+// release removes the drains of the node from the window of the budget
+func (c *consumer) release(ctx context.Context, name, nodeName string) error {
+	now := c.clock.Now()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := c.get(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !hasNodeDrain(current, nodeName, now) {
+			return nil
+		}
+		var drains []DrainBudgetConsumption
+		for _, drain := range drainsInWindow(current, now) {
+			if drain.Node != nodeName {
+				drains = append(drains, drain)
+			}
+		}
+		current.Status.Drains = drains
+		return c.update(ctx, current)
+	})
+}
+
+// getNodeBudgets returns the budgets selecting the node
+func (c *consumer) getNodeBudgets(ctx context.Context, node *corev1.Node) ([]*DrainBudget, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(GroupVersionKind.GroupVersion().WithKind(GroupVersionKind.Kind + "List"))
+	if err := c.kclient.List(ctx, list); err != nil {
+		return nil, err
+	}
+	var budgets []*DrainBudget
+	for i := range list.Items {
+		budget, err := fromUnstructured(&list.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		selector := labels.Everything()
+		if budget.Spec.NodeSelector != nil {
+			if selector, err = metav1.LabelSelectorAsSelector(budget.Spec.NodeSelector); err != nil {
+				return nil, fmt.Errorf("invalid node selector in drain budget %s: %w", budget.Name, err)
+			}
+		}
+		if selector.Matches(labels.Set(node.Labels)) {
+			budgets = append(budgets, budget)
+		}
+	}
+	// the budgets are always consumed in the same order
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].Name < budgets[j].Name })
+	return budgets, nil
+}
+
+func (c *consumer) get(ctx context.Context, name string) (*DrainBudget, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(GroupVersionKind)
+	if err := c.kclient.Get(ctx, client.ObjectKey{Name: name}, obj); err != nil {
+		return nil, err
+	}
+	return fromUnstructured(obj)
+}
+
+func (c *consumer) update(ctx context.Context, budget *DrainBudget) error {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(budget)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetGroupVersionKind(GroupVersionKind)
+	return c.kclient.Update(ctx, obj)
+}
+
+func fromUnstructured(obj *unstructured.Unstructured) (*DrainBudget, error) {
+	var budget DrainBudget
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &budget); err != nil {
+		return nil, fmt.Errorf("cannot decode drain budget %s: %w", obj.GetName(), err)
+	}
+	return &budget, nil
+}
+
+// drainsInWindow returns the drains of the budget started less than a window ago
+func drainsInWindow(budget *DrainBudget, now time.Time) []DrainBudgetConsumption {
+	var drains []DrainBudgetConsumption
+	for _, drain := range budget.Status.Drains {
+		if now.Sub(drain.Time.Time) < budget.Spec.Window.Duration {
+			drains = append(drains, drain)
+		}
+	}
+	return drains
+}
+
+func hasRoom(budget *DrainBudget, now time.Time) bool {
+	return len(drainsInWindow(budget, now)) < budget.Spec.MaxDrains
+}
+
+// hasNodeDrain tells if a drain of the node is recorded in the window of the budget
+func hasNodeDrain(budget *DrainBudget, nodeName string, now time.Time) bool {
+	for _, drain := range drainsInWindow(budget, now) {
+		if drain.Node == nodeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package drain_budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConsumer_TryConsume(t *testing.T) {
+	newBudget := func(name string, selector *metav1.LabelSelector, maxDrains int) runtime.Object {
+		budget := &DrainBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       DrainBudgetSpec{NodeSelector: selector, MaxDrains: maxDrains, Window: metav1.Duration{Duration: time.Hour}},
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(budget)
+		assert.NoError(t, err)
+		obj := &unstructured.Unstructured{Object: content}
+		obj.SetGroupVersionKind(GroupVersionKind)
+		return obj
+	}
+	newNode := func(name, pool string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": pool}}}
+	}
+
+	kclient := fake.NewClientBuilder().WithRuntimeObjects(
+		newBudget("stateful", &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "stateful"}}, 2),
+		newBudget("cluster", nil, 3),
+	).Build()
+	clock := clocktesting.NewFakeClock(time.Now())
+	budgets := NewConsumer(kclient, clock)
+	ctx := context.Background()
+
+	tryConsume := func(node *corev1.Node) (bool, string) {
+		allowed, exhausted, err := budgets.TryConsume(ctx, node)
+		assert.NoError(t, err)
+		return allowed, exhausted
+	}
+
+	allowed, _ := tryConsume(newNode("stateful-1", "stateful"))
+	assert.True(t, allowed)
+	allowed, _ = tryConsume(newNode("stateful-2", "stateful"))
+	assert.True(t, allowed)
+	allowed, exhausted := tryConsume(newNode("stateful-3", "stateful"))
+	assert.False(t, allowed, "the stateful budget allows 2 drains")
+	assert.Equal(t, "stateful", exhausted)
+
+	allowed, _ = tryConsume(newNode("web-1", "web"))
+	assert.True(t, allowed, "the stateful budget does not select the node, the blocked drain did not consume the cluster budget")
+	allowed, exhausted = tryConsume(newNode("web-2", "web"))
+	assert.False(t, allowed, "the cluster budget allows 3 drains")
+	assert.Equal(t, "cluster", exhausted)
+
+	budget, err := budgets.(*consumer).get(ctx, "cluster")
+	assert.NoError(t, err)
+	assert.Len(t, budget.Status.Drains, 3)
+	assert.Equal(t, "web-1", budget.Status.Drains[2].Node)
+
+	allowed, _ = tryConsume(newNode("web-1", "web"))
+	assert.True(t, allowed, "a node already recorded in the window doesn't consume the budget again")
+	budget, err = budgets.(*consumer).get(ctx, "cluster")
+	assert.NoError(t, err)
+	assert.Len(t, budget.Status.Drains, 3)
+
+	assert.NoError(t, budgets.Release(ctx, newNode("web-1", "web")))
+	budget, err = budgets.(*consumer).get(ctx, "cluster")
+	assert.NoError(t, err)
+	assert.Len(t, budget.Status.Drains, 2, "the released drain gives back its slot")
+
+	clock.Step(time.Hour)
+	allowed, _ = tryConsume(newNode("stateful-3", "stateful"))
+	assert.True(t, allowed, "the drains are out of the window")
+	budget, err = budgets.(*consumer).get(ctx, "stateful")
+	assert.NoError(t, err)
+	assert.Len(t, budget.Status.Drains, 1, "the drains out of the window are pruned")
+}
+
+func TestConsumer_TryConsumeRollback(t *testing.T) {
+	newBudget := func(name string, maxDrains int, drains ...DrainBudgetConsumption) *unstructured.Unstructured {
+		budget := &DrainBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       DrainBudgetSpec{MaxDrains: maxDrains, Window: metav1.Duration{Duration: time.Hour}},
+			Status:     DrainBudgetStatus{Drains: drains},
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(budget)
+		assert.NoError(t, err)
+		obj := &unstructured.Unstructured{Object: content}
+		obj.SetGroupVersionKind(GroupVersionKind)
+		return obj
+	}
+
+	now := time.Now()
+	kclient := fake.NewClientBuilder().WithRuntimeObjects(newBudget("a", 1), newBudget("b", 1)).Build()
+	budgets := NewConsumer(kclient, clocktesting.NewFakeClock(now))
+	ctx := context.Background()
+
+	// budget b is exhausted by another drain after the first check, as if a concurrent drain consumed it
+	c := budgets.(*consumer)
+	exhaust := newBudget("b", 1, DrainBudgetConsumption{Node: "other", Time: metav1.NewTime(now)})
+	current, err := c.get(ctx, "b")
+	assert.NoError(t, err)
+	exhaust.SetResourceVersion(current.ResourceVersion)
+	hook := &exhaustingClient{Client: kclient, budget: "b", exhaust: exhaust}
+	c.kclient = hook
+
+	allowed, exhausted, err := budgets.TryConsume(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}})
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, "b", exhausted)
+
+	budget, err := c.get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Empty(t, budget.Status.Drains, "the consumption of budget a is rolled back")
+}
+
+// exhaustingClient updates a budget right before it is read for the first time during its consumption
+type exhaustingClient struct {
+	client.Client
+	budget  string
+	exhaust *unstructured.Unstructured
+	done    bool
+}
+
+func (e *exhaustingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if key.Name == e.budget && !e.done {
+		e.done = true
+		if err := e.Client.Update(ctx, e.exhaust); err != nil {
+			return err
+		}
+	}
+	return e.Client.Get(ctx, key, obj, opts...)
+}
@@ -0,0 +1,38 @@
+package drain_budget
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersionKind of the DrainBudget custom resource, see helm/draino/crds/drainbudgets.yaml
+var GroupVersionKind = schema.GroupVersionKind{Group: "draino.datadoghq.com", Version: "v1alpha1", Kind: "DrainBudget"}
+
+// DrainBudget limits the number of drains started in a sliding window for the nodes it selects.
+// The drains consuming the budget are recorded in its status.
+type DrainBudget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DrainBudgetSpec   `json:"spec"`
+	Status DrainBudgetStatus `json:"status,omitempty"`
+}
+
+type DrainBudgetSpec struct {
+	// NodeSelector selects the nodes whose drains consume the budget. An empty selector selects all the nodes.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
+	// MaxDrains is the number of drains allowed in the window
+	MaxDrains int `json:"maxDrains"`
+	// Window is the duration of the sliding window
+	Window metav1.Duration `json:"window"`
+}
+
+type DrainBudgetStatus struct {
+	// Drains are the drains started in the window, the older ones are pruned when the budget is consumed
+	Drains []DrainBudgetConsumption `json:"drains,omitempty"`
+}
+
+type DrainBudgetConsumption struct {
+	Node string      `json:"node"`
+	Time metav1.Time `json:"time"`
+}
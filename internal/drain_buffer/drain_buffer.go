@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
@@ -30,6 +31,10 @@ type DrainBuffer interface {
 	// It will return a zero time if the next drain can be done immediately
 	// It will return an error in case the initialization was not executed yet
 	NextDrain(groups.GroupKey) (time.Time, error)
+	// LastDrain returns the time of the last drain of the given group and the drain buffer applied after it
+	// It will return a zero time if the group was not drained recently
+	// It will return an error in case the initialization was not executed yet
+	LastDrain(groups.GroupKey) (time.Time, time.Duration, error)
 	// Initialize loads the existing data from the persistent backend
 	Initialize(context.Context) error
 	// IsReady returns true if the initialization was finished successfully
@@ -72,8 +77,20 @@ const (
 	eventDrainBufferBadConfiguration = "DrainBufferBadConfiguration"
 
 	CustomDrainBufferAnnotation = "draino/drain-buffer"
+	// BypassAnnotation gives the number of times the node can be drained without respecting the drain buffer of its group.
+	// One bypass is consumed each time the node starts draining while the drain buffer is not respected.
+	BypassAnnotation = "draino/drain-buffer-bypass"
 )
 
+// GetBypassCount returns the number of drain buffer bypasses left to the node, 0 if the annotation is missing or invalid
+func GetBypassCount(node *v1.Node) int {
+	count, err := strconv.Atoi(node.Annotations[BypassAnnotation])
+	if err != nil || count < 0 {
+		return 0
+	}
+	return count
+}
+
 // GetDrainBufferConfigurationDetails retrieve all the drain configuration details
 func (buffer *drainBufferImpl) GetDrainBufferConfigurationDetails(ctx context.Context, node *v1.Node) (*kubernetes.MetadataSearch[time.Duration], error) {
 	return kubernetes.SearchAnnotationFromNodeAndThenPodOrController(ctx, buffer.podIndexer, nil, buffer.store, time.ParseDuration, CustomDrainBufferAnnotation, node, false, false)
@@ -167,6 +184,21 @@ func (buffer *drainBufferImpl) NextDrain(key groups.GroupKey) (time.Time, error)
 	return entry.LastDrain.Add(entry.DrainBuffer), nil
 }
 
+func (buffer *drainBufferImpl) LastDrain(key groups.GroupKey) (time.Time, time.Duration, error) {
+	if !buffer.IsReady() {
+		return time.Time{}, 0, errors.New("drain buffer is not initialized")
+	}
+
+	buffer.RLock()
+	defer buffer.RUnlock()
+
+	entry, ok := buffer.cache[key]
+	if !ok {
+		return time.Time{}, 0, nil
+	}
+	return entry.LastDrain, entry.DrainBuffer, nil
+}
+
 func (buffer *drainBufferImpl) Initialize(ctx context.Context) error {
 	if buffer.isInitialized {
 		return nil
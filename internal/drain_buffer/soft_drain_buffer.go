@@ -0,0 +1,40 @@
+package drainbuffer
+
+import (
+	"time"
+
+	"github.com/planetlabs/draino/internal/groups"
+)
+
+var _ DrainBuffer = &softDrainBuffer{}
+
+// softDrainBuffer relaxes the drain buffer while the cluster has spare capacity
+type softDrainBuffer struct {
+	DrainBuffer
+	pendingPodsPerNode    func() (float64, bool)
+	maxPendingPodsPerNode float64
+	factor                float64
+}
+
+// NewSoftDrainBuffer relaxes the drain buffer while the cluster has spare capacity: as long as there are at most
+// maxPendingPodsPerNode Pending pods per node, only the given factor of the drain buffer is respected.
+// The drain buffer stays strict while the number of Pending pods per node is unknown.
+func NewSoftDrainBuffer(buffer DrainBuffer, pendingPodsPerNode func() (float64, bool), maxPendingPodsPerNode float64, factor float64) DrainBuffer {
+	return &softDrainBuffer{
+		DrainBuffer:           buffer,
+		pendingPodsPerNode:    pendingPodsPerNode,
+		maxPendingPodsPerNode: maxPendingPodsPerNode,
+		factor:                factor,
+	}
+}
+
+func (buffer *softDrainBuffer) NextDrain(key groups.GroupKey) (time.Time, error) {
+	if ratio, known := buffer.pendingPodsPerNode(); !known || ratio > buffer.maxPendingPodsPerNode {
+		return buffer.DrainBuffer.NextDrain(key)
+	}
+	lastDrain, drainBuffer, err := buffer.DrainBuffer.LastDrain(key)
+	if err != nil || lastDrain.IsZero() {
+		return time.Time{}, err
+	}
+	return lastDrain.Add(time.Duration(float64(drainBuffer) * buffer.factor)), nil
+}
@@ -0,0 +1,105 @@
+package drain_plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+// ErrPlanNotApproved is returned when the execution of a plan is requested before its approval
+var ErrPlanNotApproved = errors.New("plan is not approved, set the annotation " + ApprovedAnnotationKey + "=true on its configmap")
+
+// Executor follows an approved plan: the nodes are handed over to draino one after the other, in the order of the plan
+type Executor struct {
+	Client    client.Client
+	Namespace string
+	Logger    logr.Logger
+	// PollPeriod is the period of the checks of the drain of the node of the current step
+	PollPeriod time.Duration
+	// StepTimeout is the maximum time given to draino to drain the node of a step
+	StepTimeout time.Duration
+}
+
+// Execute runs the pending steps of the plan. The progress is persisted after each step so that an interrupted execution resumes where it stopped.
+func (e *Executor) Execute(ctx context.Context, planName string) error {
+	plan, approved, err := Load(ctx, e.Client, e.Namespace, planName)
+	if err != nil {
+		return err
+	}
+	if !approved {
+		return ErrPlanNotApproved
+	}
+
+	for i := range plan.Steps {
+		step := &plan.Steps[i]
+		if step.Status == StepStatusDone || step.Status == StepStatusSkipped || step.Status == StepStatusFailed {
+			continue
+		}
+		logger := e.Logger.WithValues("plan", plan.Name, "order", step.Order, "node", step.Node)
+		logger.Info("executing step")
+		e.executeStep(ctx, step)
+		logger.Info("step executed", "status", step.Status, "message", step.Message)
+		if err := Save(ctx, e.Client, e.Namespace, plan); err != nil {
+			return fmt.Errorf("cannot save the progress of plan %s: %w", plan.Name, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (e *Executor) executeStep(ctx context.Context, step *Step) {
+	var node corev1.Node
+	if err := e.Client.Get(ctx, types.NamespacedName{Name: step.Node}, &node); err != nil {
+		if apierrors.IsNotFound(err) {
+			step.Status, step.Message = StepStatusSkipped, "node not found"
+			return
+		}
+		step.Status, step.Message = StepStatusFailed, err.Error()
+		return
+	}
+
+	// a node already tainted by draino is not tainted again, the step waits for the end of its drain
+	if _, found := k8sclient.GetNLATaint(&node); !found {
+		if _, err := k8sclient.AddNLATaint(ctx, e.Client, &node, time.Now(), k8sclient.TaintDrainCandidate); err != nil {
+			step.Status, step.Message = StepStatusFailed, fmt.Sprintf("cannot add the drain candidate taint: %v", err)
+			return
+		}
+	}
+	step.Status = StepStatusDraining
+
+	err := wait.PollImmediate(e.PollPeriod, e.StepTimeout, func() (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		var current corev1.Node
+		if err := e.Client.Get(ctx, types.NamespacedName{Name: step.Node}, &current); err != nil {
+			if apierrors.IsNotFound(err) {
+				step.Message = "node deleted"
+				return true, nil
+			}
+			return false, nil
+		}
+		if taint, found := k8sclient.GetNLATaint(&current); found && taint.Value == string(k8sclient.TaintDrained) {
+			step.Message = "node drained"
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		step.Status, step.Message = StepStatusFailed, fmt.Sprintf("node not drained: %v", err)
+		return
+	}
+	step.Status = StepStatusDone
+}
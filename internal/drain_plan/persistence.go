@@ -0,0 +1,73 @@
+package drain_plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapPrefix is the prefix of the name of the configmaps holding the plans
+	ConfigMapPrefix = "draino-drain-plan-"
+	// ConfigMapDataKey is the key of the plan in the configmap data
+	ConfigMapDataKey = "plan.json"
+	// ApprovedAnnotationKey is set to "true" on the configmap by the operators once the plan is reviewed, the execution refuses unapproved plans
+	ApprovedAnnotationKey = "draino/drain-plan-approved"
+)
+
+// ConfigMapName returns the name of the configmap holding the plan
+func ConfigMapName(planName string) string {
+	return ConfigMapPrefix + planName
+}
+
+// Save creates or updates the configmap holding the plan.
+// A new plan is never approved: updating the plan during its execution keeps the approval.
+func Save(ctx context.Context, kclient client.Client, namespace string, plan Plan) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+
+	var cm corev1.ConfigMap
+	err = kclient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ConfigMapName(plan.Name)}, &cm)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err != nil {
+		cm = corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ConfigMapName(plan.Name),
+				Namespace: namespace,
+			},
+			Data: map[string]string{ConfigMapDataKey: string(data)},
+		}
+		return kclient.Create(ctx, &cm)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[ConfigMapDataKey] = string(data)
+	return kclient.Update(ctx, &cm)
+}
+
+// Load reads the plan from its configmap and tells if the plan was approved
+func Load(ctx context.Context, kclient client.Client, namespace, planName string) (Plan, bool, error) {
+	var cm corev1.ConfigMap
+	if err := kclient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ConfigMapName(planName)}, &cm); err != nil {
+		return Plan{}, false, err
+	}
+	data, ok := cm.Data[ConfigMapDataKey]
+	if !ok {
+		return Plan{}, false, fmt.Errorf("configmap %s/%s has no key %s", namespace, cm.Name, ConfigMapDataKey)
+	}
+	var plan Plan
+	if err := json.Unmarshal([]byte(data), &plan); err != nil {
+		return Plan{}, false, fmt.Errorf("cannot parse plan %s: %w", planName, err)
+	}
+	return plan, cm.Annotations[ApprovedAnnotationKey] == "true", nil
+}
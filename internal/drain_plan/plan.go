@@ -0,0 +1,105 @@
+package drain_plan
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/candidate_runner"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+	"github.com/planetlabs/draino/internal/scheduler"
+)
+
+// StepStatus is the progress of the execution of a step of the plan
+type StepStatus string
+
+const (
+	// StepStatusPending is the status of a step that was not executed yet
+	StepStatusPending StepStatus = "pending"
+	// StepStatusSkipped is the status of a step whose node was blocked by the simulation or gone at execution time
+	StepStatusSkipped StepStatus = "skipped"
+	// StepStatusDraining is the status of a step whose node was handed over to draino
+	StepStatusDraining StepStatus = "draining"
+	// StepStatusDone is the status of a step whose node was drained or deleted
+	StepStatusDone StepStatus = "done"
+	// StepStatusFailed is the status of a step whose node was not drained before the step timeout
+	StepStatusFailed StepStatus = "failed"
+)
+
+// Plan is an ordered list of nodes to drain, reviewed by the operators before its execution
+type Plan struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Spacing is the estimated time between the start of two consecutive steps
+	Spacing time.Duration `json:"spacing"`
+	Steps   []Step        `json:"steps"`
+}
+
+// Step is the drain of one node of the plan
+type Step struct {
+	Order          int        `json:"order"`
+	Node           string     `json:"node"`
+	Group          string     `json:"group,omitempty"`
+	EstimatedStart time.Time  `json:"estimatedStart"`
+	Blocked        bool       `json:"blocked,omitempty"`
+	Reasons        []string   `json:"reasons,omitempty"`
+	Status         StepStatus `json:"status"`
+	Message        string     `json:"message,omitempty"`
+}
+
+// GenerateOptions are the inputs of the planning phase
+type GenerateOptions struct {
+	Name string
+	// Sorters are the sorters of the candidate runner, the first sorter has the highest priority
+	Sorters candidate_runner.NodeSorters
+	// Simulator flags the steps whose drain simulation fails. The simulation is skipped if nil.
+	Simulator drain.DrainSimulator
+	// Start is the estimated start of the first step
+	Start time.Time
+	// Spacing is the estimated time between the start of two consecutive steps
+	Spacing time.Duration
+}
+
+// Generate orders the nodes with the sorters and simulates their drain.
+// The nodes blocked by the simulation keep their rank, they are skipped at execution time.
+func Generate(ctx context.Context, nodes []*corev1.Node, opts GenerateOptions) Plan {
+	plan := Plan{
+		Name:      opts.Name,
+		CreatedAt: opts.Start,
+		Spacing:   opts.Spacing,
+	}
+
+	// the sorting tree sorts the slice in place, the caller keeps its order
+	items := make([]*corev1.Node, len(nodes))
+	copy(items, nodes)
+	tree := scheduler.NewSortingTreeWithInitialization(items, opts.Sorters)
+
+	estimatedStart := opts.Start
+	for node, ok := tree.Next(); ok; node, ok = tree.Next() {
+		step := Step{
+			Order:  len(plan.Steps) + 1,
+			Node:   node.Name,
+			Group:  node.Annotations[kubernetes.DrainGroupAnnotationKey],
+			Status: StepStatusPending,
+		}
+		if opts.Simulator != nil {
+			drainable, reasons, errs := opts.Simulator.SimulateDrain(ctx, node)
+			for _, err := range errs {
+				reasons = append(reasons, err.Error())
+			}
+			if !drainable {
+				step.Blocked = true
+				step.Reasons = reasons
+				step.Status = StepStatusSkipped
+				plan.Steps = append(plan.Steps, step)
+				continue
+			}
+		}
+		step.EstimatedStart = estimatedStart
+		estimatedStart = estimatedStart.Add(opts.Spacing)
+		plan.Steps = append(plan.Steps, step)
+	}
+	return plan
+}
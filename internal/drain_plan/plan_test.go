@@ -0,0 +1,110 @@
+package drain_plan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/candidate_runner"
+	"github.com/planetlabs/draino/internal/candidate_runner/sorters"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+)
+
+type fakeSimulator struct {
+	drain.DrainSimulator
+	blocked map[string]string
+}
+
+func (f *fakeSimulator) SimulateDrain(_ context.Context, node *corev1.Node) (bool, []string, []error) {
+	if reason, ok := f.blocked[node.Name]; ok {
+		return false, []string{reason}, nil
+	}
+	return true, nil, nil
+}
+
+func TestGenerate(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	conditions := []kubernetes.SuppliedCondition{
+		{Type: "KernelDeadlock", Status: corev1.ConditionTrue, Priority: 10},
+		{Type: "OutOfDisk", Status: corev1.ConditionTrue, Priority: 0},
+	}
+	newNode := func(name string, age time.Duration, preferred bool, conditionTypes ...corev1.NodeConditionType) *corev1.Node {
+		n := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age)), Labels: map[string]string{}}}
+		if preferred {
+			n.Labels[sorters.DefaultDrainPreferredLabelKey] = "true"
+		}
+		for _, c := range conditionTypes {
+			n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{Type: c, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))})
+		}
+		return n
+	}
+	nodes := []*corev1.Node{
+		newNode("old-disk", 48*time.Hour, false, "OutOfDisk"),
+		newNode("new-disk", time.Hour, false, "OutOfDisk"),
+		newNode("old-deadlock", 48*time.Hour, false, "KernelDeadlock"),
+		newNode("old-disk-preferred", 72*time.Hour, true, "OutOfDisk"),
+	}
+
+	tests := []struct {
+		name      string
+		sorters   candidate_runner.NodeSorters
+		simulator drain.DrainSimulator
+		wantOrder []string
+		wantStart map[string]time.Duration
+	}{
+		{
+			name:      "conditions priority then newest",
+			sorters:   candidate_runner.NodeSorters{sorters.NewConditionComparator(conditions), sorters.SortByCreationTimestampDesc},
+			wantOrder: []string{"old-deadlock", "new-disk", "old-disk", "old-disk-preferred"},
+			wantStart: map[string]time.Duration{"old-deadlock": 0, "new-disk": 10 * time.Minute, "old-disk": 20 * time.Minute, "old-disk-preferred": 30 * time.Minute},
+		},
+		{
+			name:      "drain preferred first",
+			sorters:   candidate_runner.NodeSorters{sorters.NewDrainPreferredComparator(sorters.DefaultDrainPreferredLabelKey), sorters.NewConditionComparator(conditions), sorters.SortByCreationTimestampDesc},
+			wantOrder: []string{"old-disk-preferred", "old-deadlock", "new-disk", "old-disk"},
+			wantStart: map[string]time.Duration{"old-disk-preferred": 0, "old-deadlock": 10 * time.Minute, "new-disk": 20 * time.Minute, "old-disk": 30 * time.Minute},
+		},
+		{
+			name:      "blocked nodes keep their rank without estimated start",
+			sorters:   candidate_runner.NodeSorters{sorters.NewConditionComparator(conditions), sorters.SortByCreationTimestampDesc},
+			simulator: &fakeSimulator{blocked: map[string]string{"new-disk": "PDB 'ns/pdb' does not allow any disruptions"}},
+			wantOrder: []string{"old-deadlock", "new-disk", "old-disk", "old-disk-preferred"},
+			wantStart: map[string]time.Duration{"old-deadlock": 0, "old-disk": 10 * time.Minute, "old-disk-preferred": 20 * time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Generate(context.Background(), nodes, GenerateOptions{
+				Name:      "test",
+				Sorters:   tt.sorters,
+				Simulator: tt.simulator,
+				Start:     now,
+				Spacing:   10 * time.Minute,
+			})
+
+			var order []string
+			for i, step := range plan.Steps {
+				assert.Equal(t, i+1, step.Order)
+				order = append(order, step.Node)
+				if offset, ok := tt.wantStart[step.Node]; ok {
+					assert.False(t, step.Blocked, step.Node)
+					assert.Equal(t, StepStatusPending, step.Status, step.Node)
+					assert.Equal(t, now.Add(offset), step.EstimatedStart, step.Node)
+					continue
+				}
+				assert.True(t, step.Blocked, step.Node)
+				assert.Equal(t, StepStatusSkipped, step.Status, step.Node)
+				assert.True(t, step.EstimatedStart.IsZero(), step.Node)
+				assert.NotEmpty(t, step.Reasons, step.Node)
+			}
+			assert.Equal(t, tt.wantOrder, order)
+		})
+	}
+
+	assert.Equal(t, "old-disk", nodes[0].Name, "the nodes of the caller are not reordered")
+}
@@ -6,12 +6,16 @@ import (
 
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
 	preprocessor "github.com/planetlabs/draino/internal/drain_runner/pre_processor"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/limit"
 	"github.com/planetlabs/draino/internal/protector"
 
 	"github.com/go-logr/logr"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	drainbudget "github.com/planetlabs/draino/internal/drain_budget"
 	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/drain"
@@ -34,23 +38,47 @@ type Config struct {
 	drainBuffer         drainbuffer.DrainBuffer
 	nodeReplacer        *preprocessor.NodeReplacer
 	suppliedCondition   []kubernetes.SuppliedCondition
+	drainNow            kubernetes.DrainNowConfig
+	teamLabelKey        string
+	changeTicketKey     string
 	pvcProtector        protector.PVCProtector
 
 	// With defaults
-	clock         clock.Clock
-	preprocessors []preprocessor.DrainPreProcessor
-	rerunEvery    time.Duration
+	clock          clock.Clock
+	preprocessors  []preprocessor.DrainPreProcessor
+	rerunEvery     time.Duration
+	eventExporter  eventexporter.EventExporter
+	groupIndexName string
 
 	// Options
+	runtimeObjectStore                         kubernetes.RuntimeObjectStore
 	durationWithDrainedStatusBeforeReplacement time.Duration
+	periodJitterFactor                         float64
+	drainFailureConfirmDelay                   time.Duration
+	drainDeadlineWarningRatio                  float64
+	abortDrainOnConditionClear                 bool
+	quarantineOnMaxFailures                    bool
+	waitReplacementReady                       time.Duration
+	globalDrainLimiter                         limit.ConcurrencyLimiter
+	drainBudget                                drainbudget.Consumer
+	maxSchedulingFailures                      int
+	maxTransientDrainRetries                   int
+	drainSurgePercentage                       int
+	emitDrainSummary                           bool
+	// softDrainBuffer relaxes the drain buffer while the cluster has spare capacity, it is disabled if pendingPodsPerNode is nil
+	pendingPodsPerNode    func() (float64, bool)
+	maxPendingPodsPerNode float64
+	softDrainBufferFactor float64
 }
 
 // NewConfig returns a pointer to a new drain runner configuration
 func NewConfig() *Config {
 	return &Config{
-		clock:         clock.RealClock{},
-		preprocessors: make([]preprocessor.DrainPreProcessor, 0),
-		rerunEvery:    time.Second,
+		clock:          clock.RealClock{},
+		preprocessors:  make([]preprocessor.DrainPreProcessor, 0),
+		rerunEvery:     time.Second,
+		eventExporter:  &eventexporter.NoopEventExporter{},
+		groupIndexName: groups.SchedulingGroupIdx,
 	}
 }
 
@@ -89,6 +117,12 @@ func (conf *Config) Validate() error {
 	if conf.pvcProtector == nil {
 		return errors.New("pvcProtector should be set")
 	}
+	if conf.drainDeadlineWarningRatio < 0 || conf.drainDeadlineWarningRatio >= 1 {
+		return errors.New("drain deadline warning ratio should be between 0 and 1")
+	}
+	if conf.waitReplacementReady < 0 {
+		return errors.New("wait replacement ready should not be negative")
+	}
 	if conf.durationWithDrainedStatusBeforeReplacement == 0 {
 		return errors.New("options should be set")
 	}
@@ -120,6 +154,13 @@ func WithRerun(rerun time.Duration) WithOption {
 	}
 }
 
+// WithPeriodJitterFactor randomizes the rerun period in rerun*(1±factor) to spread the activity of the groups
+func WithPeriodJitterFactor(factor float64) WithOption {
+	return func(conf *Config) {
+		conf.periodJitterFactor = factor
+	}
+}
+
 func WithClock(c clock.Clock) WithOption {
 	return func(conf *Config) {
 		conf.clock = c
@@ -171,6 +212,9 @@ func WithNodeReplacer(nodeReplacer *preprocessor.NodeReplacer) WithOption {
 func WithGlobalConfig(globalConfig kubernetes.GlobalConfig) WithOption {
 	return func(conf *Config) {
 		conf.suppliedCondition = globalConfig.SuppliedConditions
+		conf.drainNow = globalConfig.DrainNow
+		conf.teamLabelKey = globalConfig.TeamLabelKey
+		conf.changeTicketKey = globalConfig.ChangeTicketAnnotationKey
 	}
 }
 
@@ -185,3 +229,119 @@ func WithPVCProtector(pvcProtector protector.PVCProtector) WithOption {
 		conf.pvcProtector = pvcProtector
 	}
 }
+
+// WithRuntimeObjectStore is used to resolve the deployment owning the replicasets of the workloads awaiting a replacement
+func WithRuntimeObjectStore(store kubernetes.RuntimeObjectStore) WithOption {
+	return func(conf *Config) {
+		conf.runtimeObjectStore = store
+	}
+}
+
+func WithEventExporter(exporter eventexporter.EventExporter) WithOption {
+	return func(conf *Config) {
+		conf.eventExporter = exporter
+	}
+}
+
+// WithGroupIndexName configures the index used to list the nodes of a group
+func WithGroupIndexName(indexName string) WithOption {
+	return func(conf *Config) {
+		conf.groupIndexName = indexName
+	}
+}
+
+// WithDrainFailureConfirmDelay configures the delay after which a failed drain is attempted once more before being recorded as a failure.
+// 0 records the failure immediately.
+func WithDrainFailureConfirmDelay(delay time.Duration) WithOption {
+	return func(conf *Config) {
+		conf.drainFailureConfirmDelay = delay
+	}
+}
+
+// WithDrainDeadlineWarningRatio configures the fraction of the drain timeout after which a warning event is emitted on a node still being drained.
+// 0 disables the warning.
+func WithDrainDeadlineWarningRatio(ratio float64) WithOption {
+	return func(conf *Config) {
+		conf.drainDeadlineWarningRatio = ratio
+	}
+}
+
+// WithAbortDrainOnConditionClear configures the runner to abort the drain and uncordon the node if its offending conditions clear during the drain
+func WithAbortDrainOnConditionClear(abort bool) WithOption {
+	return func(conf *Config) {
+		conf.abortDrainOnConditionClear = abort
+	}
+}
+
+// WithQuarantineOnMaxFailures configures the runner to keep cordoned and label the nodes whose drain failures reach the retry threshold
+func WithQuarantineOnMaxFailures(quarantine bool) WithOption {
+	return func(conf *Config) {
+		conf.quarantineOnMaxFailures = quarantine
+	}
+}
+
+// WithWaitReplacementReady configures the runner to wait, up to the given timeout, for the workloads of a node to have a ready replacement
+// on another node before marking the node as drained. 0 disables the wait.
+func WithWaitReplacementReady(timeout time.Duration) WithOption {
+	return func(conf *Config) {
+		conf.waitReplacementReady = timeout
+	}
+}
+
+// WithGlobalDrainLimiter configures the limiter shared by the runners of all the groups to bound the number of drains running at the same time.
+// nil disables the limit.
+func WithGlobalDrainLimiter(limiter limit.ConcurrencyLimiter) WithOption {
+	return func(conf *Config) {
+		conf.globalDrainLimiter = limiter
+	}
+}
+
+// WithDrainBudget configures the runner to consume the DrainBudget resources selecting the node before its drain.
+// nil disables the budgets.
+func WithDrainBudget(budget drainbudget.Consumer) WithOption {
+	return func(conf *Config) {
+		conf.drainBudget = budget
+	}
+}
+
+// WithMaxSchedulingFailures configures the runner to remove the candidate status of a node, with a retry wall, after the given number
+// of consecutive errors before its drain could start. 0 disables the limit.
+func WithMaxSchedulingFailures(max int) WithOption {
+	return func(conf *Config) {
+		conf.maxSchedulingFailures = max
+	}
+}
+
+// WithMaxTransientDrainRetries configures the number of consecutive transient drain errors of a node that are requeued with
+// a backoff, without retry wall. The next transient error is handled as a drain failure. 0 doesn't requeue the transient errors.
+func WithMaxTransientDrainRetries(max int) WithOption {
+	return func(conf *Config) {
+		conf.maxTransientDrainRetries = max
+	}
+}
+
+// WithDrainSurgePercentage configures the runner to defer the drain of a candidate while the given percentage of the nodes of its group
+// are draining or drained. 0 disables the limit.
+func WithDrainSurgePercentage(percentage int) WithOption {
+	return func(conf *Config) {
+		conf.drainSurgePercentage = percentage
+	}
+}
+
+// WithEmitDrainSummary configures the runner to emit a summary event on the node when its drain completes
+func WithEmitDrainSummary(emit bool) WithOption {
+	return func(conf *Config) {
+		conf.emitDrainSummary = emit
+	}
+}
+
+// WithSoftDrainBuffer relaxes the drain buffer to the given factor while there are at most maxPendingPodsPerNode Pending pods per node.
+// It must match the candidate filters so that the drain buffer bypasses are only consumed when the relaxed drain buffer is not respected.
+// A nil pendingPodsPerNode function disables the relaxation.
+func WithSoftDrainBuffer(pendingPodsPerNode func() (float64, bool), maxPendingPodsPerNode float64, factor float64) WithOption {
+	return func(conf *Config) {
+		conf.pendingPodsPerNode = pendingPodsPerNode
+		conf.maxPendingPodsPerNode = maxPendingPodsPerNode
+		conf.softDrainBufferFactor = factor
+	}
+}
@@ -1,6 +1,7 @@
 package drain_runner
 
 import (
+	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	"github.com/planetlabs/draino/internal/groups"
 )
 
@@ -9,9 +10,14 @@ type DrainRunnerFactory struct {
 	conf *Config
 }
 
+type DrainRunnerFactoryInterface interface {
+	groups.RunnerFactory
+	BuildDrainInfo() DrainInfo
+}
+
 // NewFactory will return a new group runner factory for the drain runner.
 // It will return an error if the given configuration is invalid or incomplete.
-func NewFactory(withOptions ...WithOption) (groups.RunnerFactory, error) {
+func NewFactory(withOptions ...WithOption) (DrainRunnerFactoryInterface, error) {
 	conf := NewConfig()
 	for _, opt := range withOptions {
 		opt(conf)
@@ -25,21 +31,43 @@ func NewFactory(withOptions ...WithOption) (groups.RunnerFactory, error) {
 }
 
 func (factory *DrainRunnerFactory) build() *drainRunner {
+	drainBuffer := factory.conf.drainBuffer
+	if factory.conf.pendingPodsPerNode != nil {
+		drainBuffer = drainbuffer.NewSoftDrainBuffer(drainBuffer, factory.conf.pendingPodsPerNode, factory.conf.maxPendingPodsPerNode, factory.conf.softDrainBufferFactor)
+	}
 	return &drainRunner{
-		client:              factory.conf.kubeClient,
-		logger:              *factory.conf.logger,
-		clock:               factory.conf.clock,
-		retryWall:           factory.conf.retryWall,
-		drainer:             factory.conf.drainer,
-		sharedIndexInformer: factory.conf.sharedIndexInformer,
-		runEvery:            factory.conf.rerunEvery,
-		eventRecorder:       factory.conf.eventRecorder,
-		filter:              factory.conf.filter,
-		drainBuffer:         factory.conf.drainBuffer,
-		nodeReplacer:        factory.conf.nodeReplacer,
-		suppliedConditions:  factory.conf.suppliedCondition,
-		preprocessors:       factory.conf.preprocessors,
-		pvcProtector:        factory.conf.pvcProtector,
+		client:                     factory.conf.kubeClient,
+		logger:                     *factory.conf.logger,
+		clock:                      factory.conf.clock,
+		retryWall:                  factory.conf.retryWall,
+		drainer:                    factory.conf.drainer,
+		sharedIndexInformer:        factory.conf.sharedIndexInformer,
+		runEvery:                   factory.conf.rerunEvery,
+		eventRecorder:              factory.conf.eventRecorder,
+		filter:                     factory.conf.filter,
+		drainBuffer:                drainBuffer,
+		nodeReplacer:               factory.conf.nodeReplacer,
+		suppliedConditions:         factory.conf.suppliedCondition,
+		drainNow:                   factory.conf.drainNow,
+		teamLabelKey:               factory.conf.teamLabelKey,
+		changeTicketKey:            factory.conf.changeTicketKey,
+		preprocessors:              factory.conf.preprocessors,
+		pvcProtector:               factory.conf.pvcProtector,
+		runtimeObjectStore:         factory.conf.runtimeObjectStore,
+		eventExporter:              factory.conf.eventExporter,
+		groupIndexName:             factory.conf.groupIndexName,
+		periodJitterFactor:         factory.conf.periodJitterFactor,
+		drainFailureConfirmDelay:   factory.conf.drainFailureConfirmDelay,
+		drainDeadlineWarningRatio:  factory.conf.drainDeadlineWarningRatio,
+		abortDrainOnConditionClear: factory.conf.abortDrainOnConditionClear,
+		quarantineOnMaxFailures:    factory.conf.quarantineOnMaxFailures,
+		waitReplacementReady:       factory.conf.waitReplacementReady,
+		globalDrainLimiter:         factory.conf.globalDrainLimiter,
+		drainBudget:                factory.conf.drainBudget,
+		maxSchedulingFailures:      factory.conf.maxSchedulingFailures,
+		maxTransientDrainRetries:   factory.conf.maxTransientDrainRetries,
+		drainSurgePercentage:       factory.conf.drainSurgePercentage,
+		emitDrainSummary:           factory.conf.emitDrainSummary,
 
 		durationWithDrainedStatusBeforeReplacement: factory.conf.durationWithDrainedStatusBeforeReplacement,
 	}
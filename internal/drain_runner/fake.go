@@ -14,12 +14,16 @@ import (
 	"k8s.io/utils/clock"
 
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+	drainbudget "github.com/planetlabs/draino/internal/drain_budget"
 	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	preprocessor "github.com/planetlabs/draino/internal/drain_runner/pre_processor"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
+	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/drain"
 	"github.com/planetlabs/draino/internal/kubernetes/index"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/limit"
 )
 
 type FakeOptions struct {
@@ -37,6 +41,22 @@ type FakeOptions struct {
 
 	Drainer       kubernetes.Drainer
 	RetryStrategy drain.RetryStrategy
+	EventExporter eventexporter.EventExporter
+	EventRecorder kubernetes.EventRecorder
+
+	DrainFailureConfirmDelay   time.Duration
+	DrainDeadlineWarningRatio  float64
+	AbortDrainOnConditionClear bool
+	QuarantineOnMaxFailures    bool
+	WaitReplacementReady       time.Duration
+	GlobalDrainLimiter         limit.ConcurrencyLimiter
+	DrainBudget                drainbudget.Consumer
+	MaxSchedulingFailures      int
+	MaxTransientDrainRetries   int
+	DrainSurgePercentage       int
+	EmitDrainSummary           bool
+	SuppliedConditions         []kubernetes.SuppliedCondition
+	DrainNow                   kubernetes.DrainNowConfig
 }
 
 func (opts *FakeOptions) ApplyDefaults() error {
@@ -54,6 +74,9 @@ func (opts *FakeOptions) ApplyDefaults() error {
 		discard := logr.Discard()
 		opts.Logger = &discard
 	}
+	if opts.MaxTransientDrainRetries == 0 {
+		opts.MaxTransientDrainRetries = 5
+	}
 	if opts.RerunEvery == 0 {
 		opts.RerunEvery = 5 * time.Millisecond
 	}
@@ -72,6 +95,12 @@ func (opts *FakeOptions) ApplyDefaults() error {
 	if opts.Filter == nil {
 		opts.Filter = filters.FilterFromFunction("always_true", func(ctx context.Context, n *v1.Node) bool { return true })
 	}
+	if opts.EventExporter == nil {
+		opts.EventExporter = &eventexporter.NoopEventExporter{}
+	}
+	if opts.EventRecorder == nil {
+		opts.EventRecorder = &kubernetes.NoopEventRecorder{}
+	}
 	if opts.DrainBuffer == nil {
 		fakeClient := fake.NewSimpleClientset()
 		configMapClient := fakeClient.CoreV1().ConfigMaps("default")
@@ -108,18 +137,33 @@ func NewFakeRunner(opts *FakeOptions) (*drainRunner, error) {
 	}
 
 	return &drainRunner{
-		client:              opts.ClientWrapper.GetManagerClient(),
-		logger:              *opts.Logger,
-		clock:               opts.Clock,
-		retryWall:           retryWall,
-		sharedIndexInformer: fakeIndexer,
-		drainer:             opts.Drainer,
-		runEvery:            opts.RerunEvery,
-		preprocessors:       opts.Preprocessors,
-		eventRecorder:       &kubernetes.NoopEventRecorder{},
-		filter:              opts.Filter,
-		drainBuffer:         opts.DrainBuffer,
-		nodeReplacer:        opts.NodeReplacer,
+		client:                     opts.ClientWrapper.GetManagerClient(),
+		logger:                     *opts.Logger,
+		clock:                      opts.Clock,
+		retryWall:                  retryWall,
+		sharedIndexInformer:        fakeIndexer,
+		drainer:                    opts.Drainer,
+		runEvery:                   opts.RerunEvery,
+		preprocessors:              opts.Preprocessors,
+		eventRecorder:              opts.EventRecorder,
+		filter:                     opts.Filter,
+		drainBuffer:                opts.DrainBuffer,
+		nodeReplacer:               opts.NodeReplacer,
+		eventExporter:              opts.EventExporter,
+		groupIndexName:             groups.SchedulingGroupIdx,
+		drainFailureConfirmDelay:   opts.DrainFailureConfirmDelay,
+		drainDeadlineWarningRatio:  opts.DrainDeadlineWarningRatio,
+		abortDrainOnConditionClear: opts.AbortDrainOnConditionClear,
+		quarantineOnMaxFailures:    opts.QuarantineOnMaxFailures,
+		waitReplacementReady:       opts.WaitReplacementReady,
+		globalDrainLimiter:         opts.GlobalDrainLimiter,
+		drainBudget:                opts.DrainBudget,
+		maxSchedulingFailures:      opts.MaxSchedulingFailures,
+		maxTransientDrainRetries:   opts.MaxTransientDrainRetries,
+		drainSurgePercentage:       opts.DrainSurgePercentage,
+		emitDrainSummary:           opts.EmitDrainSummary,
+		suppliedConditions:         opts.SuppliedConditions,
+		drainNow:                   opts.DrainNow,
 
 		durationWithDrainedStatusBeforeReplacement: time.Hour,
 	}, nil
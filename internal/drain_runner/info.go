@@ -1,8 +1,14 @@
 package drain_runner
 
 import (
+	"context"
 	"encoding/json"
+	"sort"
 	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 )
 
 const (
@@ -22,5 +28,54 @@ func (d *DataInfo) Import(i interface{}) error {
 	return json.Unmarshal(b, d)
 }
 
+// ScheduledDrain describes a node that was selected as drain candidate and that is waiting for the drain runner
+type ScheduledDrain struct {
+	NodeName string `json:"nodeName"`
+	// ScheduledTime is the earliest time the drain runner will pick the node: the time it became a candidate, or the retry wall timestamp after a failed drain.
+	// The drain buffer of the group can still delay the drain.
+	ScheduledTime time.Time `json:"scheduledTime"`
+	// FailedCount is the number of failed drain attempts recorded on the node
+	FailedCount int `json:"failedCount"`
+}
+
 type DrainInfo interface {
+	// ListSchedules returns all the pending drains sorted by scheduled time
+	ListSchedules(ctx context.Context) ([]ScheduledDrain, error)
+}
+
+var _ DrainInfo = &drainRunner{}
+
+func (runner *drainRunner) ListSchedules(ctx context.Context) ([]ScheduledDrain, error) {
+	var nodes corev1.NodeList
+	if err := runner.client.List(ctx, &nodes); err != nil {
+		return nil, err
+	}
+
+	schedules := make([]ScheduledDrain, 0)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		taint, exist := k8sclient.GetNLATaint(node)
+		if !exist || taint.Value != k8sclient.TaintDrainCandidate {
+			continue
+		}
+		schedule := ScheduledDrain{
+			NodeName:    node.Name,
+			FailedCount: runner.retryWall.GetDrainRetryAttemptsCount(node),
+		}
+		if taint.TimeAdded != nil {
+			schedule.ScheduledTime = taint.TimeAdded.Time
+		}
+		if retryTime := runner.retryWall.GetRetryWallTimestamp(node); retryTime.After(schedule.ScheduledTime) {
+			schedule.ScheduledTime = retryTime
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].ScheduledTime.Equal(schedules[j].ScheduledTime) {
+			return schedules[i].NodeName < schedules[j].NodeName
+		}
+		return schedules[i].ScheduledTime.Before(schedules[j].ScheduledTime)
+	})
+	return schedules, nil
 }
@@ -23,7 +23,7 @@ var (
 		PreProcessorFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "pre_processor_failures",
 			Help: "Number of failures per nodegroup",
-		}, []string{metrics.TagPreProcessor, metrics.TagReason, metrics.TagNodegroupName, metrics.TagNodegroupNamespace, metrics.TagGroupKey, metrics.TagService}),
+		}, []string{metrics.TagPreProcessor, metrics.TagReason, metrics.TagNodegroupName, metrics.TagNodegroupNamespace, metrics.TagGroupKey, metrics.TagTeam, metrics.TagService}),
 	}
 	registerOnce sync.Once
 )
@@ -45,8 +45,8 @@ const (
 	DrainedNodeResultFailed    DrainNodesResult = "failed"
 )
 
-func CounterDrainedNodes(node *core.Node, result DrainNodesResult, conditions []kubernetes.SuppliedCondition, failureReason kubernetes.FailureCause) {
-	values := kubernetes.GetNodeTagsValues(node)
+func CounterDrainedNodes(node *core.Node, result DrainNodesResult, conditions []kubernetes.SuppliedCondition, failureReason kubernetes.FailureCause, teamLabelKey string) {
+	values := kubernetes.GetNodeTagsValues(node, teamLabelKey)
 	conditionIDs := append(kubernetes.GetConditionIDs(conditions), metrics.TagConditionAnyValue)
 
 	for _, c := range conditionIDs {
@@ -55,9 +55,9 @@ func CounterDrainedNodes(node *core.Node, result DrainNodesResult, conditions []
 	}
 }
 
-func CounterPreProcessorFailures(node *core.Node, preProcName, reason, drainGroup string) {
-	values := kubernetes.GetNodeTagsValues(node)
-	Metrics.PreProcessorFailures.WithLabelValues(preProcName, reason, values.NgName, values.NgNamespace, drainGroup, values.Service).Add(1)
+func CounterPreProcessorFailures(node *core.Node, preProcName, reason, drainGroup, teamLabelKey string) {
+	values := kubernetes.GetNodeTagsValues(node, teamLabelKey)
+	Metrics.PreProcessorFailures.WithLabelValues(preProcName, reason, values.NgName, values.NgNamespace, drainGroup, values.Team, values.Service).Add(1)
 }
 
 const (
@@ -0,0 +1,34 @@
+package drain_runner
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/metrics"
+)
+
+func TestDrainMetrics_Team(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name: "foo-node",
+		Labels: map[string]string{
+			"example.com/owner":                   "compute",
+			kubernetes.LabelKeyNodeGroupName:      "ng",
+			kubernetes.LabelKeyNodeGroupNamespace: "ns",
+		},
+	}}
+
+	drained := Metrics.DrainedNodes.WithLabelValues(string(DrainedNodeResultSucceeded), "", metrics.TagConditionAnyValue, "ng", "ng", "ns", "compute", "")
+	drainedBefore := testutil.ToFloat64(drained)
+	CounterDrainedNodes(node, DrainedNodeResultSucceeded, nil, "", "example.com/owner")
+	assert.Equal(t, float64(1), testutil.ToFloat64(drained)-drainedBefore)
+
+	failures := Metrics.PreProcessorFailures.WithLabelValues("pre", "timeout", "ng", "ns", "my-key", "compute", "")
+	failuresBefore := testutil.ToFloat64(failures)
+	CounterPreProcessorFailures(node, "pre", "timeout", "my-key", "example.com/owner")
+	assert.Equal(t, float64(1), testutil.ToFloat64(failures)-failuresBefore)
+}
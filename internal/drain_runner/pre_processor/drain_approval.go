@@ -0,0 +1,209 @@
+package pre_processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/clock"
+
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+const (
+	PreProcessNotDoneReasonDenied PreProcessNotDoneReason = "drain_denied"
+
+	eventDrainApprovalDenied   = "DrainApprovalDenied"
+	eventDrainApprovalDeferred = "DrainApprovalDeferred"
+
+	drainApprovalRequestTimeout = 20 * time.Second
+)
+
+// DrainApprovalDecision is the answer of the approval endpoint
+type DrainApprovalDecision string
+
+const (
+	// DrainApprovalApprove lets the drain proceed
+	DrainApprovalApprove DrainApprovalDecision = "approve"
+	// DrainApprovalDeny aborts the drain attempt, the node is requeued behind the retry wall
+	DrainApprovalDeny DrainApprovalDecision = "deny"
+	// DrainApprovalDefer keeps the node candidate without asking again before DeferUntil
+	DrainApprovalDefer DrainApprovalDecision = "defer"
+)
+
+// DrainApprovalRequest is posted as JSON to the approval endpoint before draining a node
+type DrainApprovalRequest struct {
+	Node       string   `json:"node"`
+	Group      string   `json:"group"`
+	Conditions []string `json:"conditions"`
+	// Pods are the pods of the node as namespace/name
+	Pods []string `json:"pods"`
+}
+
+// DrainApprovalResponse is the JSON answer of the approval endpoint
+type DrainApprovalResponse struct {
+	Decision   DrainApprovalDecision `json:"decision"`
+	Reason     string                `json:"reason,omitempty"`
+	DeferUntil *time.Time            `json:"deferUntil,omitempty"`
+}
+
+// DrainApprovalPreProcessor asks an external endpoint to approve the drain of each node.
+// The node is drained once approved. A deny aborts the drain attempt and a defer keeps the node waiting until the given time.
+// Errors while calling the endpoint keep the node waiting: the drain is never started without approval.
+// An approval is valid until the node loses its candidate status, so that the endpoint is not called again while the other pre-processors are pending.
+type DrainApprovalPreProcessor struct {
+	endpoint           string
+	httpClient         *http.Client
+	podIndexer         index.PodIndexer
+	keyGetter          groups.GroupKeyGetter
+	suppliedConditions []kubernetes.SuppliedCondition
+	logger             logr.Logger
+	eventRecorder      kubernetes.EventRecorder
+	clock              clock.Clock
+
+	sync.Mutex
+	deferredUntil map[string]time.Time
+	// approvedCandidacy is the time the candidate taint was added when the drain of the node was approved
+	approvedCandidacy map[string]time.Time
+}
+
+func NewDrainApprovalPreProcessor(endpoint string, podIndexer index.PodIndexer, keyGetter groups.GroupKeyGetter, suppliedConditions []kubernetes.SuppliedCondition, logger logr.Logger, eventRecorder kubernetes.EventRecorder, clock clock.Clock) DrainPreProcessor {
+	return &DrainApprovalPreProcessor{
+		endpoint:           endpoint,
+		httpClient:         &http.Client{Timeout: drainApprovalRequestTimeout},
+		podIndexer:         podIndexer,
+		keyGetter:          keyGetter,
+		suppliedConditions: suppliedConditions,
+		logger:             logger.WithName("DrainApprovalPreProcessor"),
+		eventRecorder:      eventRecorder,
+		clock:              clock,
+		deferredUntil:      map[string]time.Time{},
+		approvedCandidacy:  map[string]time.Time{},
+	}
+}
+
+func (_ *DrainApprovalPreProcessor) GetName() string {
+	return "DrainApprovalPreProcessor"
+}
+
+func (pre *DrainApprovalPreProcessor) IsDone(ctx context.Context, node *corev1.Node) (bool, PreProcessNotDoneReason, error) {
+	var candidacy time.Time
+	if taint, exist := k8sclient.GetNLATaint(node); exist && taint.TimeAdded != nil {
+		candidacy = taint.TimeAdded.Time
+	}
+	pre.Lock()
+	until, deferred := pre.deferredUntil[node.Name]
+	approvedCandidacy, approved := pre.approvedCandidacy[node.Name]
+	pre.Unlock()
+	if approved && approvedCandidacy.Equal(candidacy) {
+		return true, "", nil
+	}
+	if deferred && pre.clock.Now().Before(until) {
+		return false, PreProcessNotDoneReasonProcessing, nil
+	}
+
+	request, err := pre.buildRequest(ctx, node)
+	if err != nil {
+		return false, PreProcessNotDoneReasonProcessing, err
+	}
+	response, err := pre.askApproval(ctx, request)
+	if err != nil {
+		return false, PreProcessNotDoneReasonProcessing, err
+	}
+
+	logger := pre.logger.WithValues("node", node.Name, "decision", response.Decision, "reason", response.Reason)
+	switch response.Decision {
+	case DrainApprovalApprove:
+		logger.Info("drain approved")
+		pre.forget(node.Name)
+		pre.Lock()
+		pre.approvedCandidacy[node.Name] = candidacy
+		pre.Unlock()
+		return true, "", nil
+	case DrainApprovalDeny:
+		logger.Info("drain denied")
+		pre.forget(node.Name)
+		pre.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, eventDrainApprovalDenied, "Drain denied by the approval endpoint: %s", response.Reason)
+		return false, PreProcessNotDoneReasonDenied, nil
+	case DrainApprovalDefer:
+		if response.DeferUntil == nil {
+			return false, PreProcessNotDoneReasonProcessing, fmt.Errorf("approval endpoint deferred the drain without deferUntil")
+		}
+		logger.Info("drain deferred", "until", *response.DeferUntil)
+		pre.Lock()
+		pre.deferredUntil[node.Name] = *response.DeferUntil
+		pre.Unlock()
+		pre.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeNormal, eventDrainApprovalDeferred, "Drain deferred by the approval endpoint until %s: %s", response.DeferUntil.Format(time.RFC3339), response.Reason)
+		return false, PreProcessNotDoneReasonProcessing, nil
+	}
+	return false, PreProcessNotDoneReasonProcessing, fmt.Errorf("unknown decision '%s' from the approval endpoint", response.Decision)
+}
+
+func (pre *DrainApprovalPreProcessor) Reset(_ context.Context, node *corev1.Node) error {
+	pre.forget(node.Name)
+	return nil
+}
+
+func (pre *DrainApprovalPreProcessor) forget(nodeName string) {
+	pre.Lock()
+	defer pre.Unlock()
+	delete(pre.deferredUntil, nodeName)
+	delete(pre.approvedCandidacy, nodeName)
+}
+
+func (pre *DrainApprovalPreProcessor) buildRequest(ctx context.Context, node *corev1.Node) (*DrainApprovalRequest, error) {
+	pods, err := pre.podIndexer.GetPodsByNode(ctx, node.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &DrainApprovalRequest{
+		Node:       node.Name,
+		Group:      string(pre.keyGetter.GetGroupKey(node)),
+		Conditions: []string{},
+		Pods:       make([]string, 0, len(pods)),
+	}
+	for _, condition := range kubernetes.GetNodeOffendingConditions(node, pre.suppliedConditions) {
+		request.Conditions = append(request.Conditions, string(condition.Type))
+	}
+	for _, pod := range pods {
+		request.Pods = append(request.Pods, pod.Namespace+"/"+pod.Name)
+	}
+	return request, nil
+}
+
+func (pre *DrainApprovalPreProcessor) askApproval(ctx context.Context, request *DrainApprovalRequest) (*DrainApprovalResponse, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pre.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := pre.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("approval endpoint request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("approval endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var response DrainApprovalResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("cannot decode the approval endpoint response: %w", err)
+	}
+	return &response, nil
+}
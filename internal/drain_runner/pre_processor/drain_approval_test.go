@@ -0,0 +1,155 @@
+package pre_processor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+type fakePodIndexer struct {
+	index.PodIndexer
+	pods []*corev1.Pod
+}
+
+func (f *fakePodIndexer) GetPodsByNode(context.Context, string) ([]*corev1.Pod, error) {
+	return f.pods, nil
+}
+
+type fakeGroupKeyGetter struct {
+	groups.GroupKeyGetter
+}
+
+func (fakeGroupKeyGetter) GetGroupKey(*corev1.Node) groups.GroupKey {
+	return "my-group"
+}
+
+func TestDrainApprovalPreProcessor(t *testing.T) {
+	now := time.Now()
+	deferUntil := now.Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		Name     string
+		Response DrainApprovalResponse
+		Status   int
+
+		ExpectedIsDone bool
+		ExpectedReason PreProcessNotDoneReason
+		ExpectedError  bool
+		// ExpectedCalls is the number of calls to the endpoint after a second evaluation of the node
+		ExpectedCalls int
+	}{
+		{
+			Name:           "Should proceed with the drain once approved",
+			Response:       DrainApprovalResponse{Decision: DrainApprovalApprove},
+			ExpectedIsDone: true,
+			ExpectedCalls:  1,
+		},
+		{
+			Name:           "Should abort the drain attempt if denied",
+			Response:       DrainApprovalResponse{Decision: DrainApprovalDeny, Reason: "change freeze"},
+			ExpectedReason: PreProcessNotDoneReasonDenied,
+			ExpectedCalls:  2,
+		},
+		{
+			Name:           "Should wait without asking again if deferred",
+			Response:       DrainApprovalResponse{Decision: DrainApprovalDefer, DeferUntil: &deferUntil},
+			ExpectedReason: PreProcessNotDoneReasonProcessing,
+			ExpectedCalls:  1,
+		},
+		{
+			Name:           "Should wait if the endpoint fails",
+			Status:         http.StatusInternalServerError,
+			ExpectedReason: PreProcessNotDoneReasonProcessing,
+			ExpectedError:  true,
+			ExpectedCalls:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var requests []DrainApprovalRequest
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				var request DrainApprovalRequest
+				assert.NoError(t, json.NewDecoder(req.Body).Decode(&request))
+				requests = append(requests, request)
+				if tt.Status != 0 {
+					res.WriteHeader(tt.Status)
+					return
+				}
+				assert.NoError(t, json.NewEncoder(res).Encode(tt.Response))
+			}))
+			defer server.Close()
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo-node"},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{*k8sclient.CreateNLATaint(k8sclient.TaintDrainCandidate, now)}},
+				Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}}},
+			}
+			pods := &fakePodIndexer{pods: []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "foo-pod", Namespace: "ns"}}}}
+			conditions := []kubernetes.SuppliedCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}}
+			pre := NewDrainApprovalPreProcessor(server.URL, pods, fakeGroupKeyGetter{}, conditions, logr.Discard(), kubernetes.NoopEventRecorder{}, testingclock.NewFakeClock(now))
+
+			isDone, reason, err := pre.IsDone(context.Background(), node)
+			assert.Equal(t, tt.ExpectedIsDone, isDone)
+			assert.Equal(t, tt.ExpectedReason, reason)
+			if tt.ExpectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			_, _, _ = pre.IsDone(context.Background(), node)
+			assert.Len(t, requests, tt.ExpectedCalls)
+			assert.Equal(t, DrainApprovalRequest{Node: "foo-node", Group: "my-group", Conditions: []string{"KernelDeadlock"}, Pods: []string{"ns/foo-pod"}}, requests[0])
+		})
+	}
+}
+
+func TestDrainApprovalPreProcessor_DeferUntil(t *testing.T) {
+	now := time.Now()
+	deferUntil := now.Add(time.Hour)
+	decision := DrainApprovalResponse{Decision: DrainApprovalDefer, DeferUntil: &deferUntil}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+		assert.NoError(t, json.NewEncoder(res).Encode(decision))
+	}))
+	defer server.Close()
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo-node"},
+		Spec:       corev1.NodeSpec{Taints: []corev1.Taint{*k8sclient.CreateNLATaint(k8sclient.TaintDrainCandidate, now)}},
+	}
+	fakeClock := testingclock.NewFakeClock(now)
+	pre := NewDrainApprovalPreProcessor(server.URL, &fakePodIndexer{}, fakeGroupKeyGetter{}, nil, logr.Discard(), kubernetes.NoopEventRecorder{}, fakeClock)
+
+	isDone, _, err := pre.IsDone(context.Background(), node)
+	assert.NoError(t, err)
+	assert.False(t, isDone)
+
+	fakeClock.Step(30 * time.Minute)
+	isDone, _, err = pre.IsDone(context.Background(), node)
+	assert.NoError(t, err)
+	assert.False(t, isDone)
+	assert.Equal(t, 1, calls, "the endpoint should not be called before the end of the deferral")
+
+	decision = DrainApprovalResponse{Decision: DrainApprovalApprove}
+	fakeClock.Step(time.Hour)
+	isDone, _, err = pre.IsDone(context.Background(), node)
+	assert.NoError(t, err)
+	assert.True(t, isDone)
+	assert.Equal(t, 2, calls)
+}
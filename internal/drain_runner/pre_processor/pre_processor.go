@@ -7,9 +7,13 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 
+	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 )
 
+// WaitBeforeDrainingKey can be set as label or annotation on the nodes (usually propagated from the node group) to override the default wait time for the group
+const WaitBeforeDrainingKey = "node-lifecycle.datadoghq.com/wait-before-draining"
+
 type PreProcessNotDoneReason string
 
 const (
@@ -29,12 +33,15 @@ type DrainPreProcessor interface {
 }
 
 // WaitTimePreprocessor is a preprocessor used to wait for a certain amount of time before draining a node.
+// The default duration can be overridden per node group using the WaitBeforeDrainingKey label or annotation.
+// The nodes requested for an immediate drain don't wait.
 type WaitTimePreprocessor struct {
-	waitFor time.Duration
+	waitFor  time.Duration
+	drainNow kubernetes.DrainNowConfig
 }
 
-func NewWaitTimePreprocessor(waitFor time.Duration) DrainPreProcessor {
-	return &WaitTimePreprocessor{waitFor}
+func NewWaitTimePreprocessor(waitFor time.Duration, drainNow kubernetes.DrainNowConfig) DrainPreProcessor {
+	return &WaitTimePreprocessor{waitFor: waitFor, drainNow: drainNow}
 }
 
 func (_ *WaitTimePreprocessor) GetName() string {
@@ -46,6 +53,10 @@ func (_ *WaitTimePreprocessor) Reset(context.Context, *corev1.Node) error {
 }
 
 func (pre *WaitTimePreprocessor) IsDone(ctx context.Context, node *corev1.Node) (bool, PreProcessNotDoneReason, error) {
+	if pre.drainNow.IsRequested(node) {
+		return true, "", nil
+	}
+
 	taint, exist := k8sclient.GetNLATaint(node)
 	if !exist {
 		return false, "", fmt.Errorf("'%s' doesn't have a NLA taint", node.Name)
@@ -60,6 +71,19 @@ func (pre *WaitTimePreprocessor) IsDone(ctx context.Context, node *corev1.Node)
 		return false, PreProcessNotDoneReasonProcessing, fmt.Errorf("found 'drain-candidate' taint without timeAdded field set")
 	}
 
-	waitUntil := taint.TimeAdded.Add(pre.waitFor)
+	waitUntil := taint.TimeAdded.Add(pre.getWaitFor(node))
 	return waitUntil.Before(time.Now()), PreProcessNotDoneReasonProcessing, nil
 }
+
+// getWaitFor returns the wait duration configured for the node group, or the default one if there is no valid override.
+func (pre *WaitTimePreprocessor) getWaitFor(node *corev1.Node) time.Duration {
+	values, found := kubernetes.GetExactMetadata(node, WaitBeforeDrainingKey)
+	if !found || len(values) == 0 {
+		return pre.waitFor
+	}
+	waitFor, err := time.ParseDuration(values[0].Value)
+	if err != nil || waitFor < 0 {
+		return pre.waitFor
+	}
+	return waitFor
+}
@@ -0,0 +1,75 @@
+package pre_processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func TestWaitTimePreprocessor(t *testing.T) {
+	createNode := func(taintAge time.Duration, labels map[string]string) *corev1.Node {
+		timeAdded := metav1.NewTime(time.Now().Add(-taintAge))
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: labels},
+			Spec: corev1.NodeSpec{
+				Taints: []corev1.Taint{{Key: k8sclient.DrainoTaintKey, Value: string(k8sclient.TaintDrainCandidate), Effect: corev1.TaintEffectNoSchedule, TimeAdded: &timeAdded}},
+			},
+		}
+	}
+	tests := []struct {
+		Name           string
+		Node           *corev1.Node
+		ExpectedIsDone bool
+	}{
+		{
+			Name:           "Default group should wait for the default duration",
+			Node:           createNode(10*time.Second, nil),
+			ExpectedIsDone: false,
+		},
+		{
+			Name:           "Default group should proceed after the default duration",
+			Node:           createNode(31*time.Second, nil),
+			ExpectedIsDone: true,
+		},
+		{
+			Name:           "Group with a custom delay should defer the drain",
+			Node:           createNode(31*time.Second, map[string]string{WaitBeforeDrainingKey: "5m"}),
+			ExpectedIsDone: false,
+		},
+		{
+			Name:           "Group with a custom delay should proceed after the custom duration",
+			Node:           createNode(6*time.Minute, map[string]string{WaitBeforeDrainingKey: "5m"}),
+			ExpectedIsDone: true,
+		},
+		{
+			Name:           "Invalid custom delay should fallback on default duration",
+			Node:           createNode(31*time.Second, map[string]string{WaitBeforeDrainingKey: "not-a-duration"}),
+			ExpectedIsDone: true,
+		},
+		{
+			Name: "Immediate drain request should not wait",
+			Node: func() *corev1.Node {
+				n := createNode(10*time.Second, nil)
+				n.Annotations = map[string]string{kubernetes.DefaultDrainNowAnnotationKey: kubernetes.DrainNowAnnotationValue}
+				return n
+			}(),
+			ExpectedIsDone: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			pre := NewWaitTimePreprocessor(30*time.Second, kubernetes.DrainNowConfig{AnnotationKey: kubernetes.DefaultDrainNowAnnotationKey})
+			isDone, _, err := pre.IsDone(context.Background(), tt.Node)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ExpectedIsDone, isDone)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package drain_runner
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/apis/core"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+// handlePendingReplacements completes the drain of the nodes whose workloads have a ready replacement on another node.
+// A node waiting for longer than waitReplacementReady is marked as drained anyway.
+func (runner *drainRunner) handlePendingReplacements(ctx context.Context, info *groups.RunnerInfo) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "HandlePendingReplacements")
+	defer span.Finish()
+
+	draining, _, err := runner.getNodesForNLATaint(ctx, info.Key, []k8sclient.DrainTaintValue{k8sclient.TaintDraining})
+	if err != nil {
+		runner.logger.Error(err, "cannot get draining nodes for group")
+		return
+	}
+
+	for _, n := range draining {
+		pending, exist, err := kubernetes.GetPendingReplacements(n)
+		if !exist {
+			continue
+		}
+		logger := runner.logger.WithValues("node", n.Name)
+		if err != nil {
+			logger.Error(err, "ignoring invalid pending replacements annotation")
+			if err := runner.completeDrain(ctx, info, n, nil); err != nil {
+				logger.Error(err, "failed to complete drain")
+			}
+			continue
+		}
+
+		summary := &drainSummary{started: pending.Since, podsEvicted: pending.PodsEvicted, pvcsDeleted: pending.PVCsDeleted}
+		if taint, exist := k8sclient.GetNLATaint(n); exist && taint.TimeAdded != nil {
+			summary.started = taint.TimeAdded.Time
+		}
+		missing, err := runner.missingReplacements(ctx, n, pending.Workloads, summary.started)
+		if err != nil {
+			logger.Error(err, "cannot check the replacement of the workloads")
+			continue
+		}
+		if len(missing) > 0 && runner.clock.Since(pending.Since) < runner.waitReplacementReady {
+			logger.Info("waiting for the replacement of the workloads to be ready", "workloads", missing)
+			if len(missing) < len(pending.Workloads) {
+				if err := kubernetes.SetPendingReplacements(ctx, runner.client, n, kubernetes.PendingReplacements{Since: pending.Since, Workloads: missing, PodsEvicted: pending.PodsEvicted, PVCsDeleted: pending.PVCsDeleted}); err != nil {
+					logger.Error(err, "failed to update pending replacements annotation")
+				}
+			}
+			continue
+		}
+		if len(missing) > 0 {
+			runner.eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, kubernetes.EventReasonReplacementNotReady.String(), "Completing drain without ready replacement for %v", missing)
+		}
+		if err := runner.completeDrain(ctx, info, n, summary); err != nil {
+			logger.Error(err, "failed to complete drain")
+		}
+	}
+}
+
+// awaitReplacements completes the drain if all the workloads already have a ready replacement.
+// Otherwise the node keeps its `draining` taint, and the workloads are recorded on the node to be checked by handlePendingReplacements.
+func (runner *drainRunner) awaitReplacements(ctx context.Context, info *groups.RunnerInfo, candidate *corev1.Node, workloads []string, summary *drainSummary) error {
+	missing, err := runner.missingReplacements(ctx, candidate, workloads, summary.started)
+	if err != nil {
+		// the check is done again at the next iteration
+		runner.logger.Error(err, "cannot check the replacement of the workloads", "node", candidate.Name)
+		missing = workloads
+	}
+	if len(missing) == 0 {
+		return runner.completeDrain(ctx, info, candidate, summary)
+	}
+	runner.logger.Info("pods evicted, waiting for the replacement of the workloads to be ready", "node", candidate.Name, "workloads", missing)
+	return kubernetes.SetPendingReplacements(ctx, runner.client, candidate, kubernetes.PendingReplacements{Since: runner.clock.Now(), Workloads: missing, PodsEvicted: summary.podsEvicted, PVCsDeleted: summary.pvcsDeleted})
+}
+
+// getNodeWorkloads returns the sorted workloads of the pods running on the node
+func (runner *drainRunner) getNodeWorkloads(ctx context.Context, node *corev1.Node) []string {
+	pods, err := index.GetFromIndex[corev1.Pod](ctx, runner.sharedIndexInformer, index.PodsByNodeNameIdx, node.Name)
+	if err != nil {
+		// without the list of workloads, the drain is completed as soon as the pods are evicted
+		runner.logger.Error(err, "cannot get pods of node, the replacements will not be awaited", "node", node.Name)
+		return nil
+	}
+	set := map[string]struct{}{}
+	for _, pod := range pods {
+		if workload, ok := kubernetes.GetPodWorkload(pod, runner.runtimeObjectStore); ok {
+			set[workload] = struct{}{}
+		}
+	}
+	workloads := make([]string, 0, len(set))
+	for workload := range set {
+		workloads = append(workloads, workload)
+	}
+	sort.Strings(workloads)
+	return workloads
+}
+
+// missingReplacements returns the workloads that have no running pod passing its readiness gates on another node, created since
+// the start of the drain. The pods that existed before the drain are not replacements: they are the other replicas of the workload.
+func (runner *drainRunner) missingReplacements(ctx context.Context, node *corev1.Node, workloads []string, drainStart time.Time) ([]string, error) {
+	// the creation timestamps have a precision of a second
+	since := drainStart.Truncate(time.Second)
+	ready := map[string]bool{}
+	listed := map[string]bool{}
+	for _, workload := range workloads {
+		namespace, _, _ := strings.Cut(workload, "/")
+		if listed[namespace] {
+			continue
+		}
+		listed[namespace] = true
+		var pods corev1.PodList
+		if err := runner.client.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if pod.Spec.NodeName == node.Name || pod.DeletionTimestamp != nil || pod.CreationTimestamp.Time.Before(since) || !kubernetes.IsPodReadyWithGates(pod) {
+				continue
+			}
+			if podWorkload, ok := kubernetes.GetPodWorkload(pod, runner.runtimeObjectStore); ok {
+				ready[podWorkload] = true
+			}
+		}
+	}
+	var missing []string
+	for _, workload := range workloads {
+		if !ready[workload] {
+			missing = append(missing, workload)
+		}
+	}
+	return missing, nil
+}
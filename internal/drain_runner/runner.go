@@ -2,7 +2,11 @@ package drain_runner
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -12,19 +16,22 @@ import (
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/kubernetes/pkg/apis/core"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+	drainbudget "github.com/planetlabs/draino/internal/drain_budget"
 	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	preprocessor "github.com/planetlabs/draino/internal/drain_runner/pre_processor"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
 	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/kubernetes"
 	"github.com/planetlabs/draino/internal/kubernetes/drain"
 	"github.com/planetlabs/draino/internal/kubernetes/index"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+	"github.com/planetlabs/draino/internal/limit"
 	"github.com/planetlabs/draino/internal/metrics"
 	"github.com/planetlabs/draino/internal/protector"
 )
@@ -33,6 +40,12 @@ import (
 // TODO is this a good value? ==> probably not because that depends on the terminationGracePeriod of the pods. See getGracePeriodWithEvictionHeadRoom and getMinEvictionTimeoutWithEvictionHeadRoom
 const DrainTimeout = 10 * time.Minute
 
+// conditionClearCheckPeriod is the period to check if a node being drained still has offending conditions
+const conditionClearCheckPeriod = 10 * time.Second
+
+// errConditionCleared is returned by the drain of a node that lost its offending conditions
+var errConditionCleared = errors.New("drain aborted because the node has no more offending conditions")
+
 // Make sure that the drain runner is implementing the group runner interface
 var _ groups.Runner = &drainRunner{}
 
@@ -49,9 +62,45 @@ type drainRunner struct {
 	filter              filters.Filter
 	drainBuffer         drainbuffer.DrainBuffer
 	suppliedConditions  []kubernetes.SuppliedCondition
-	nodeReplacer        *preprocessor.NodeReplacer
-	pvcProtector        protector.PVCProtector
-	preprocessors       []preprocessor.DrainPreProcessor
+	drainNow            kubernetes.DrainNowConfig
+	// teamLabelKey is the label giving the team of the nodes in the metrics
+	teamLabelKey string
+	// changeTicketKey is the node annotation giving the change ticket of the exported drain events
+	changeTicketKey string
+	nodeReplacer    *preprocessor.NodeReplacer
+	pvcProtector    protector.PVCProtector
+	// runtimeObjectStore resolves the deployment of the replicasets, it can be nil
+	runtimeObjectStore kubernetes.RuntimeObjectStore
+	preprocessors      []preprocessor.DrainPreProcessor
+	eventExporter      eventexporter.EventExporter
+	groupIndexName     string
+	periodJitterFactor float64
+	// drainFailureConfirmDelay is the delay before attempting a failed drain once more, to absorb API flakiness. 0 disables the confirmation.
+	drainFailureConfirmDelay time.Duration
+	// drainDeadlineWarningRatio is the fraction of DrainTimeout after which a warning event is emitted on the node. 0 disables the warning.
+	drainDeadlineWarningRatio float64
+	// abortDrainOnConditionClear cancels the drain and uncordons the node if its offending conditions clear during the drain
+	abortDrainOnConditionClear bool
+	// quarantineOnMaxFailures keeps cordoned and labels the nodes whose drain failures reach the retry threshold, instead of uncordoning them
+	quarantineOnMaxFailures bool
+	// waitReplacementReady keeps the node draining, up to this duration, until the workloads it hosted have a ready replacement elsewhere. 0 disables the wait.
+	waitReplacementReady time.Duration
+	// globalDrainLimiter is shared by the runners of all the groups to bound the number of drains running at the same time. nil disables the limit.
+	globalDrainLimiter limit.ConcurrencyLimiter
+	// drainBudget consumes the DrainBudget resources selecting the node before its drain. nil disables the budgets.
+	drainBudget drainbudget.Consumer
+	// maxSchedulingFailures is the number of consecutive errors, while the node is still candidate, after which its candidate status is removed. 0 disables the limit.
+	maxSchedulingFailures int
+	// schedulingFailures counts the consecutive scheduling failures per candidate
+	schedulingFailures map[string]int
+	// maxTransientDrainRetries is the number of consecutive transient drain errors requeued with a backoff before the error is handled as a drain failure
+	maxTransientDrainRetries int
+	// transientFailures tracks the consecutive transient drain errors per candidate
+	transientFailures map[string]transientFailure
+	// drainSurgePercentage is the percentage of the nodes of a group that can be draining or drained at the same time. 0 disables the limit.
+	drainSurgePercentage int
+	// emitDrainSummary emits a summary event on the node when its drain completes
+	emitDrainSummary bool
 
 	durationWithDrainedStatusBeforeReplacement time.Duration
 }
@@ -62,7 +111,7 @@ func (runner *drainRunner) Run(info *groups.RunnerInfo) error {
 	runner.logger = runner.logger.WithValues("groupKey", info.Key)
 
 	// run an endless loop until there are no drain candidates left
-	wait.UntilWithContext(ctx, func(ctx context.Context) {
+	utils.JitterUntilWithContext(ctx, func(ctx context.Context) {
 		if !runner.drainBuffer.IsReady() {
 			runner.logger.Info("pausing drain runner until drain buffer is properly initialized")
 			return
@@ -81,15 +130,17 @@ func (runner *drainRunner) Run(info *groups.RunnerInfo) error {
 
 		// Can't be done asynchronously, must be done in sequence with `handleGroup/handleCandidate` because these
 		// are the function dealing with the taint lifecycle
+		runner.handlePendingReplacements(ctx, info)
 		runner.handleLeftOverDraining(ctx, info)
 		runner.handlePendingDrainedNodes(ctx, info)
 		runner.handlePVCProtection(ctx, info)
+		runner.handleReleasedQuarantines(ctx, info)
 
 		if emptyGroup := runner.handleGroup(ctx, info); emptyGroup {
 			cancel()
 			return
 		}
-	}, runner.runEvery)
+	}, runner.runEvery, runner.periodJitterFactor)
 	return nil
 }
 
@@ -112,6 +163,10 @@ func (runner *drainRunner) handleLeftOverDraining(ctx context.Context, info *gro
 		runner.logger.Info("Found some nodes that were stuck in draining", "count", len(draining))
 	}
 	for _, n := range draining {
+		// The evictions of these nodes are done, they are waiting for the replacement of their workloads
+		if _, pending := n.Annotations[kubernetes.PendingReplacementsAnnotationKey]; pending {
+			continue
+		}
 		updatedNode, errRetryWall := runner.updateRetryWallOnCandidate(ctx, n, "Node stuck in draining (controller restart?)", info.Key)
 		if errRetryWall != nil {
 			// we just log the error, it will come back at next iteration
@@ -121,7 +176,8 @@ func (runner *drainRunner) handleLeftOverDraining(ctx context.Context, info *gro
 			runner.logger.Error(err, "Failed to remove taint on node left over in 'draining'", "node", n.Name)
 			return
 		}
-		CounterDrainedNodes(n, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(n, runner.suppliedConditions), "stuck_in_draining")
+		runner.exportEvent(ctx, eventexporter.DrainEventUncordoned, n, info.Key, "stuck in draining")
+		CounterDrainedNodes(n, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(n, runner.suppliedConditions), "stuck_in_draining", runner.teamLabelKey)
 	}
 }
 
@@ -156,10 +212,14 @@ func (runner *drainRunner) handlePendingDrainedNodes(ctx context.Context, info *
 
 		logger := runner.logger.WithValues("node", n.Name)
 		logger.Info("pro-actively replacing too old drained node")
+		_, replacementRequested := n.Labels[kubernetes.NodeLabelKeyReplaceRequest]
 		if err := runner.nodeReplacer.TriggerNodeReplacement(ctx, n); err != nil {
 			logger.Error(err, "failed to trigger node replacement")
 			continue
 		}
+		if !replacementRequested {
+			runner.exportEvent(ctx, eventexporter.DrainEventReplacementRequested, n, info.Key, "drained for too long")
+		}
 		if isDone, reason := runner.nodeReplacer.IsDone(n); !isDone {
 			logger.Info("failed to replace node", "reason", reason)
 		}
@@ -182,13 +242,105 @@ func (runner *drainRunner) handleGroup(ctx context.Context, info *groups.RunnerI
 	}
 
 	for _, candidate := range candidates {
-		if err := runner.handleCandidate(ctx, info, candidate); err != nil {
+		err := runner.handleCandidate(ctx, info, candidate)
+		if err != nil {
 			runner.logger.Error(err, "error during candidate evaluation", "node", candidate.Name)
 		}
+		runner.checkSchedulingFailures(ctx, info, candidate, err)
 	}
 	return
 }
 
+// transientFailure is the count of the consecutive transient drain errors of a candidate, and the time of its next drain attempt
+type transientFailure struct {
+	attempts    int
+	nextAttempt time.Time
+}
+
+const (
+	transientRetryBaseDelay = 30 * time.Second
+	transientRetryMaxDelay  = 10 * time.Minute
+)
+
+// recordTransientFailure counts one more transient drain error for the candidate. The next attempt is delayed by an exponential
+// backoff, starting at transientRetryBaseDelay and bounded by transientRetryMaxDelay.
+func (runner *drainRunner) recordTransientFailure(candidate *corev1.Node, previous transientFailure) transientFailure {
+	if runner.transientFailures == nil {
+		runner.transientFailures = map[string]transientFailure{}
+	}
+	delay := transientRetryBaseDelay
+	for i := 0; i < previous.attempts && delay < transientRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > transientRetryMaxDelay {
+		delay = transientRetryMaxDelay
+	}
+	failure := transientFailure{attempts: previous.attempts + 1, nextAttempt: runner.clock.Now().Add(delay)}
+	runner.transientFailures[candidate.Name] = failure
+	return failure
+}
+
+// drainSchedulingError is an error returned by handleCandidate before the drain could start
+type drainSchedulingError struct {
+	err error
+}
+
+func (e *drainSchedulingError) Error() string { return e.err.Error() }
+
+func (e *drainSchedulingError) Unwrap() error { return e.err }
+
+// schedulingError marks an error returned before the drain could start, nil stays nil
+func schedulingError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &drainSchedulingError{err: err}
+}
+
+// checkSchedulingFailures counts the consecutive errors of a node that is still candidate returned before its drain could start.
+// Once the limit is reached, the candidate status is removed with a retry wall so that the node is not left stranded.
+func (runner *drainRunner) checkSchedulingFailures(ctx context.Context, info *groups.RunnerInfo, candidate *corev1.Node, errCandidate error) {
+	if runner.maxSchedulingFailures <= 0 {
+		return
+	}
+	if runner.schedulingFailures == nil {
+		runner.schedulingFailures = map[string]int{}
+	}
+	var errScheduling *drainSchedulingError
+	if !errors.As(errCandidate, &errScheduling) {
+		// no error, or an error of the drain itself that is handled by the retry wall or the transient requeue
+		delete(runner.schedulingFailures, candidate.Name)
+		return
+	}
+	node, err := runner.refreshNode(ctx, candidate)
+	if err != nil {
+		delete(runner.schedulingFailures, candidate.Name)
+		return
+	}
+	if taint, exist := k8sclient.GetNLATaint(node); !exist || taint.Value != k8sclient.TaintDrainCandidate {
+		delete(runner.schedulingFailures, candidate.Name)
+		return
+	}
+	runner.schedulingFailures[candidate.Name]++
+	if runner.schedulingFailures[candidate.Name] < runner.maxSchedulingFailures {
+		return
+	}
+
+	delete(runner.schedulingFailures, candidate.Name)
+	runner.logger.Info("Removing candidate status after repeated scheduling failures", "node", node.Name, "failures", runner.maxSchedulingFailures)
+	runner.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, kubernetes.EventReasonDrainSchedulingFailed.String(), "Candidate status removed after %d consecutive failures to start the drain: %v", runner.maxSchedulingFailures, errCandidate)
+	runner.exportEvent(ctx, eventexporter.DrainEventUncordoned, node, info.Key, "scheduling failures: "+errCandidate.Error())
+	runner.resetPreProcessors(ctx, node, info.Key)
+	newNode, err := runner.updateRetryWallOnCandidate(ctx, node, fmt.Sprintf("scheduling failed %d times: %v", runner.maxSchedulingFailures, errCandidate), info.Key)
+	if err != nil {
+		runner.logger.Error(err, "Failed to update retry wall", "node", node.Name)
+		return
+	}
+	if _, err := k8sclient.RemoveNLATaint(ctx, runner.client, newNode); err != nil {
+		runner.logger.Error(err, "Failed to remove candidate taint after repeated scheduling failures", "node", node.Name)
+	}
+}
+
 // handleCandidate look at the candidate and attempt a drain
 // while being under processing the node gets the `draining` taint
 // at the end of the function, the node should be left with either `drained` taint or no taint and a retryWall set.
@@ -201,86 +353,293 @@ func (runner *drainRunner) handleCandidate(ctx context.Context, info *groups.Run
 
 	loggerForNode := runner.logger.WithValues("node", candidate.Name)
 
+	// Draining a node that is being deleted is pointless, the candidate status is removed without any retry wall
+	if candidate.DeletionTimestamp != nil && !candidate.DeletionTimestamp.IsZero() {
+		loggerForNode.Info("Node is being deleted, removing candidate status", "deletionTimestamp", candidate.DeletionTimestamp.Time)
+		runner.exportEvent(ctx, eventexporter.DrainEventUncordoned, candidate, info.Key, "node is being deleted")
+		runner.resetPreProcessors(ctx, candidate, info.Key)
+		_, errRmTaint := k8sclient.RemoveNLATaint(ctx, runner.client, candidate)
+		return schedulingError(errRmTaint)
+	}
+
 	// Check if the node is still candidate before processing
 	filterOutput := runner.filter.FilterNode(ctx, candidate)
 	if !filterOutput.Keep {
 		loggerForNode.Info("Removing candidate status", "rejections", filterOutput.OnlyFailingChecks().Checks)
+		runner.exportEvent(ctx, eventexporter.DrainEventUncordoned, candidate, info.Key, formatRejections(filterOutput))
 		runner.resetPreProcessors(ctx, candidate, info.Key)
+		// Only a recovery starts the recordon cooldown, a candidate rejected for any other reason, like the drain buffer, keeps its condition
+		if len(kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions)) == 0 {
+			candidate = runner.recordLastUncordon(ctx, candidate)
+		}
 		_, errRmTaint := k8sclient.RemoveNLATaint(ctx, runner.client, candidate)
-		return errRmTaint
+		return schedulingError(errRmTaint)
 	}
 
 	// Checking pre-activities
 	kubernetes.LogrForVerboseNode(runner.logger, candidate, "Node is candidate for drain, checking pre-activities")
+	_, replacementRequested := candidate.Labels[kubernetes.NodeLabelKeyReplaceRequest]
 	allPreprocessorsDone, shouldAbort, reason := runner.checkPreprocessors(ctx, candidate, info.Key)
+	// the pre-processors patch the candidate in place, a new replacement label means that the replacement was requested by a pre-processor
+	if _, hasLabel := candidate.Labels[kubernetes.NodeLabelKeyReplaceRequest]; hasLabel && !replacementRequested {
+		runner.exportEvent(ctx, eventexporter.DrainEventReplacementRequested, candidate, info.Key, "pre-provisioning")
+	}
 	if shouldAbort {
-		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainFailed, "Error while waiting for pre conditions: %s", reason)
+		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainFailed.String(), "Error while waiting for pre conditions: %s", reason)
+		runner.exportEvent(ctx, eventexporter.DrainEventFailed, candidate, info.Key, "pre-conditions failed "+reason)
+		candidate = runner.recordDrainHistory(ctx, candidate, kubernetes.FailedStr, "pre-conditions failed "+reason)
 		runner.resetPreProcessors(ctx, candidate, info.Key)
-		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "pre-processing")
+		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "pre-processing", runner.teamLabelKey)
 		newNode, err := runner.updateRetryWallOnCandidate(ctx, candidate, fmt.Sprintf("pre-conditions failed %s", reason), info.Key)
 		if err != nil {
-			return err
+			return schedulingError(err)
 		}
 		_, err = k8sclient.RemoveNLATaint(ctx, runner.client, newNode)
-		return err
+		return schedulingError(err)
 	}
 	if !allPreprocessorsDone {
 		loggerForNode.Info("waiting for preprocessors to be done before draining", "node", candidate.Name)
 		return nil
 	}
 
+	// The node keeps its candidate status until the backoff following a transient drain error is over
+	if failure, ok := runner.transientFailures[candidate.Name]; ok && runner.clock.Now().Before(failure.nextAttempt) {
+		loggerForNode.Info("deferring drain until the backoff of the transient drain errors is over", "attempts", failure.attempts, "nextAttempt", failure.nextAttempt)
+		return nil
+	}
+
+	// The node keeps its candidate status until the surge budget of its group allows one more unavailable node
+	if runner.drainSurgePercentage > 0 {
+		unavailable, budget, err := runner.getSurgeUsage(ctx, info.Key)
+		if err != nil {
+			return schedulingError(err)
+		}
+		if unavailable >= budget {
+			loggerForNode.Info("deferring drain until the surge budget of the group allows one more drain", "unavailable", unavailable, "budget", budget)
+			return nil
+		}
+	}
+
+	// The node keeps its candidate status until a drain slot is free across all the groups
+	if runner.globalDrainLimiter != nil {
+		if !runner.globalDrainLimiter.TryAcquire() {
+			loggerForNode.Info("deferring drain until the number of drains running across all the groups is below the limit")
+			return nil
+		}
+		defer runner.globalDrainLimiter.Release()
+	}
+
+	// The node keeps its candidate status until all the drain budgets selecting it allow one more drain
+	if runner.drainBudget != nil {
+		allowed, exhausted, err := runner.drainBudget.TryConsume(ctx, candidate)
+		if err != nil {
+			return schedulingError(err)
+		}
+		if !allowed {
+			loggerForNode.Info("deferring drain until the drain budget allows one more drain", "budget", exhausted)
+			return nil
+		}
+	}
+
+	bypassedNode, err := runner.consumeDrainBufferBypass(ctx, candidate, info.Key)
+	if err != nil {
+		runner.releaseDrainBudget(ctx, candidate)
+		return schedulingError(err)
+	}
+	candidate = bypassedNode
+
+	// The drain window is enforced by the candidate filter, an invalid one is reported once, when the drain starts
+	if _, _, errWindow := kubernetes.GetNodeDrainWindow(candidate); errWindow != nil {
+		loggerForNode.Error(errWindow, "ignoring invalid drain window")
+		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainWindowInvalid.String(), "Ignoring drain window: %v", errWindow)
+	}
+
 	loggerForNode.Info("start draining")
+	drainStart := runner.clock.Now()
 	// Draining a node is a blocking operation. This makes sure that one drain does not affect the other by taking PDB budget.
-	candidate, err := k8sclient.AddNLATaint(ctx, runner.client, candidate, runner.clock.Now(), k8sclient.TaintDraining)
+	drainingNode, err := k8sclient.AddNLATaint(ctx, runner.client, candidate, drainStart, k8sclient.TaintDraining)
 	if err != nil {
-		return err
+		runner.releaseDrainBudget(ctx, candidate)
+		return schedulingError(err)
+	}
+	candidate = drainingNode
+	runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeNormal, kubernetes.EventReasonDrainStarting.String(), "Draining node")
+	runner.exportEvent(ctx, eventexporter.DrainEventStarted, candidate, info.Key, "")
+
+	// The workloads must be captured before the evictions, the pods are gone from the node after the drain
+	var workloads []string
+	if runner.waitReplacementReady > 0 {
+		workloads = runner.getNodeWorkloads(ctx, candidate)
 	}
-	runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeNormal, kubernetes.EventReasonDrainStarting, "Draining node")
 
-	err = runner.drainCandidate(ctx, info, candidate)
+	drainCtx, drainStats := kubernetes.ContextWithDrainStats(ctx)
+	err = runner.drainCandidate(drainCtx, info, candidate)
+	previousTransientFailure := runner.transientFailures[candidate.Name]
+	delete(runner.transientFailures, candidate.Name)
+	summary := &drainSummary{started: drainStart, podsEvicted: drainStats.PodsEvicted(), pvcsDeleted: drainStats.PVCsDeleted()}
 	var errRefresh error
 	candidate, errRefresh = runner.refreshNode(ctx, candidate)
 	if errRefresh != nil {
 		if apierrors.IsNotFound(errRefresh) {
 			loggerForNode.Info("node has been deleted while we were waiting for the drain to complete")
-			CounterDrainedNodes(candidate, DrainedNodeResultSucceeded, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "node_deleted")
+			CounterDrainedNodes(candidate, DrainedNodeResultSucceeded, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "node_deleted", runner.teamLabelKey)
 			return nil
 		}
 		loggerForNode.Error(errRefresh, "failed to refresh node after drain")
-		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "node_refresh")
+		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "node_refresh", runner.teamLabelKey)
 		return errRefresh
 	}
+	if errors.Is(err, errConditionCleared) {
+		// The node recovered, it is uncordoned without any retry wall
+		loggerForNode.Info("offending conditions cleared during the drain, uncordoning the node")
+		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "condition_cleared", runner.teamLabelKey)
+		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeNormal, kubernetes.EventReasonDrainAborted.String(), "Drain aborted, the node has no more offending conditions")
+		runner.exportEvent(ctx, eventexporter.DrainEventUncordoned, candidate, info.Key, err.Error())
+		candidate = runner.recordDrainHistory(ctx, candidate, kubernetes.FailedStr, err.Error())
+		runner.resetPreProcessors(ctx, candidate, info.Key)
+		candidate = runner.recordLastUncordon(ctx, candidate)
+		_, errRmTaint := k8sclient.RemoveNLATaint(ctx, runner.client, candidate)
+		return errRmTaint
+	}
+	if err != nil && kubernetes.IsTransientDrainError(err) && previousTransientFailure.attempts < runner.maxTransientDrainRetries {
+		// The node stays candidate and is picked up again after a backoff, the attempt doesn't count in the retry wall.
+		// Once the retries are exhausted, the error is handled as any other drain failure.
+		failure := runner.recordTransientFailure(candidate, previousTransientFailure)
+		loggerForNode.Error(err, "transient error during drain, requeuing the node", "attempts", failure.attempts, "nextAttempt", failure.nextAttempt)
+		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "transient", runner.teamLabelKey)
+		if failure.attempts == 1 {
+			// the following retries of the same drain are only logged, so that the events and the history are not flooded
+			runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainFailed.String(), "Drain failed with a transient error, will retry: %v", err)
+			candidate = runner.recordDrainHistory(ctx, candidate, kubernetes.FailedStr, "transient error "+err.Error())
+		}
+		if _, errTaint := k8sclient.AddNLATaint(ctx, runner.client, candidate, runner.clock.Now(), k8sclient.TaintDrainCandidate); errTaint != nil {
+			loggerForNode.Error(errTaint, "Failed to set back 'drain-candidate' taint following transient drain failure")
+			return errTaint
+		}
+		return err
+	}
 	if err != nil {
 		failureCause := kubernetes.GetFailureCause(err)
 		if failureCause == "" {
 			loggerForNode.Error(err, "error doesn't map to a failure cause")
 			failureCause = "undefined"
 		}
-		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), failureCause)
+		CounterDrainedNodes(candidate, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), failureCause, runner.teamLabelKey)
 		loggerForNode.Error(err, "failed to drain node", "failure_cause", failureCause)
-		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainFailed, "Drain failed: %v", err)
+		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainFailed.String(), "Drain failed: %v", err)
+		runner.exportEvent(ctx, eventexporter.DrainEventFailed, candidate, info.Key, err.Error())
+		candidate = runner.recordDrainHistory(ctx, candidate, kubernetes.FailedStr, err.Error())
 		runner.resetPreProcessors(ctx, candidate, info.Key)
 		updatedNode, errRetryWall := runner.updateRetryWallOnCandidate(ctx, candidate, err.Error(), info.Key)
 		if errRetryWall != nil {
 			loggerForNode.Error(errRetryWall, "Failed to remove taint following drain failure")
 			return errRetryWall
 		}
+		if runner.quarantineOnMaxFailures && runner.retryWall.IsAboveAlertingThreshold(updatedNode) {
+			var errQuarantine error
+			if updatedNode, errQuarantine = runner.quarantine(ctx, updatedNode); errQuarantine != nil {
+				loggerForNode.Error(errQuarantine, "Failed to quarantine node following drain failure")
+				return errQuarantine
+			}
+		}
 		if _, errTaint := k8sclient.RemoveNLATaint(ctx, runner.client, updatedNode); errTaint != nil {
 			loggerForNode.Error(errTaint, "Failed to remove taint following drain failure")
 			return errTaint
 		}
 		return err
 	}
-	if candidate, err = k8sclient.AddNLATaint(ctx, runner.client, candidate, runner.clock.Now(), k8sclient.TaintDrained); err != nil {
+	if len(workloads) > 0 {
+		return runner.awaitReplacements(ctx, info, candidate, workloads, summary)
+	}
+	return runner.completeDrain(ctx, info, candidate, summary)
+}
+
+// drainSummary is what a drain did, reported with --emit-drain-summary when the drain completes
+type drainSummary struct {
+	started     time.Time
+	podsEvicted int
+	pvcsDeleted int
+}
+
+// completeDrain marks the node as drained once all its pods are evicted (and replaced if waitReplacementReady is set).
+// The summary is nil if the stats of the drain are not known.
+func (runner *drainRunner) completeDrain(ctx context.Context, info *groups.RunnerInfo, candidate *corev1.Node, summary *drainSummary) error {
+	loggerForNode := runner.logger.WithValues("node", candidate.Name)
+	candidate, err := k8sclient.AddNLATaint(ctx, runner.client, candidate, runner.clock.Now(), k8sclient.TaintDrained)
+	if err != nil {
 		loggerForNode.Error(err, "Failed to add 'drained' taint")
 		return err
 	}
-	CounterDrainedNodes(candidate, DrainedNodeResultSucceeded, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "")
-	runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeNormal, kubernetes.EventReasonDrainSucceeded, "Drained node")
+	if _, pending := candidate.Annotations[kubernetes.PendingReplacementsAnnotationKey]; pending {
+		if err := k8sclient.PatchDeleteNodeAnnotationKeyCR(ctx, runner.client, candidate, kubernetes.PendingReplacementsAnnotationKey); err != nil {
+			loggerForNode.Error(err, "Failed to remove pending replacements annotation")
+		}
+	}
+	CounterDrainedNodes(candidate, DrainedNodeResultSucceeded, kubernetes.GetNodeOffendingConditions(candidate, runner.suppliedConditions), "", runner.teamLabelKey)
+	metrics.IncCandidatesDrained(string(info.Key), kubernetes.GetNodeTagsValues(candidate, runner.teamLabelKey).Team)
+	runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeNormal, kubernetes.EventReasonDrainSucceeded.String(), "Drained node")
+	if runner.emitDrainSummary && summary != nil {
+		runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeNormal, kubernetes.EventReasonDrainSummary.String(), "Drain completed in %s: %d pods evicted, %d PVCs deleted, %d previous failed attempts",
+			runner.clock.Since(summary.started).Round(time.Second), summary.podsEvicted, summary.pvcsDeleted, runner.retryWall.GetDrainRetryAttemptsCount(candidate))
+	}
+	runner.exportEvent(ctx, eventexporter.DrainEventSucceeded, candidate, info.Key, "")
+	runner.recordDrainHistory(ctx, candidate, kubernetes.CompletedStr, "")
+	// the immediate drain request is fulfilled, the node must not be drained again when it is back in scope
+	if runner.drainNow.HasAnnotation(candidate) {
+		if err := k8sclient.PatchDeleteNodeAnnotationKeyCR(ctx, runner.client, candidate, runner.drainNow.AnnotationKey); err != nil {
+			loggerForNode.Error(err, "Failed to remove the drain-now annotation")
+		}
+	}
 	runner.logger.Info("successfully drained node", "node", candidate.Name)
 	return nil
 }
 
+// exportEvent publishes the drain lifecycle event, failures are only logged
+func (runner *drainRunner) exportEvent(ctx context.Context, eventType eventexporter.DrainEventType, node *corev1.Node, key groups.GroupKey, message string) {
+	event := eventexporter.DrainEvent{Type: eventType, Node: node.Name, GroupKey: string(key), Message: message, Timestamp: runner.clock.Now(), ChangeTicket: kubernetes.GetNodeChangeTicket(node, runner.changeTicketKey)}
+	if err := runner.eventExporter.Export(ctx, event); err != nil {
+		runner.logger.Error(err, "Failed to export drain event", "node", node.Name, "type", eventType)
+	}
+}
+
+// formatRejections returns the failing checks of the filter as "<filter>: <reason>" separated by ";"
+func formatRejections(output filters.FilterOutput) string {
+	var rejections []string
+	for _, c := range output.OnlyFailingChecks().Checks {
+		if c.Reason == "" {
+			rejections = append(rejections, c.FilterName)
+			continue
+		}
+		rejections = append(rejections, c.FilterName+": "+c.Reason)
+	}
+	return strings.Join(rejections, ";")
+}
+
+// recordDrainHistory adds the attempt to the drain history annotation of the node, failures are only logged.
+// It returns the patched node, or the given one if the history could not be recorded.
+func (runner *drainRunner) recordDrainHistory(ctx context.Context, node *corev1.Node, result, reason string) *corev1.Node {
+	entry := kubernetes.DrainHistoryEntry{Timestamp: runner.clock.Now(), Result: result, Reason: reason}
+	patched, err := kubernetes.RecordDrainHistory(ctx, runner.client, node, entry)
+	if err != nil {
+		runner.logger.Error(err, "Failed to record drain history", "node", node.Name)
+		return node
+	}
+	return patched
+}
+
+// recordLastUncordon sets the last uncordon time annotation of a node that recovered from its condition, failures are only logged.
+// It returns the patched node, or the given one if the annotation could not be set.
+func (runner *drainRunner) recordLastUncordon(ctx context.Context, node *corev1.Node) *corev1.Node {
+	var annotationPatch k8sclient.AnnotationPatch
+	annotationPatch.Metadata.Annotations = map[string]string{kubernetes.LastUncordonAnnotationKey: runner.clock.Now().Format(time.RFC3339)}
+	patched, err := k8sclient.PatchNodeCRWithResult(ctx, runner.client, node, annotationPatch)
+	if err != nil {
+		runner.logger.Error(err, "Failed to record last uncordon time", "node", node.Name)
+		return node
+	}
+	return patched
+}
+
 func (runner *drainRunner) checkPreprocessors(ctx context.Context, candidate *corev1.Node, groupKey groups.GroupKey) (allDone bool, shouldAbort bool, abortReason string) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "CheckDrainPreprocessors")
 	defer span.Finish()
@@ -295,7 +654,7 @@ func (runner *drainRunner) checkPreprocessors(ctx context.Context, candidate *co
 			continue
 		}
 		if reason != "" && reason != preprocessor.PreProcessNotDoneReasonProcessing {
-			CounterPreProcessorFailures(candidate, pre.GetName(), string(reason), string(groupKey))
+			CounterPreProcessorFailures(candidate, pre.GetName(), string(reason), string(groupKey), runner.teamLabelKey)
 			runner.logger.Info("cannot finish pre-processing node, aborting", "node", candidate.Name, "preprocessor", pre.GetName(), "reason", reason)
 			shouldAbort = true
 			abortReason = string(reason)
@@ -338,6 +697,13 @@ func (runner *drainRunner) drainCandidate(ctx context.Context, info *groups.Runn
 	// TODO on what is running in the node, there could be long terminationGracePeriod on pods.
 	drainContext, cancel := context.WithTimeout(ctx, DrainTimeout)
 	defer cancel()
+	if runner.drainDeadlineWarningRatio > 0 {
+		go runner.warnOnDrainDeadline(drainContext, candidate)
+	}
+	conditionCleared := make(chan struct{})
+	if runner.abortDrainOnConditionClear {
+		go runner.abortDrainOnConditionCleared(drainContext, cancel, candidate, conditionCleared)
+	}
 
 	// We must capture the drainBuffer configuration before starting the drain
 	// because the values can be stored on the node OR on the pods. So we have to read from the pods
@@ -350,6 +716,16 @@ func (runner *drainRunner) drainCandidate(ctx context.Context, info *groups.Runn
 	kubernetes.LogrForVerboseNode(runner.logger, candidate, "drainBuffer configuration", "drainBuffer", drainBuffer)
 
 	err = runner.drainer.Drain(drainContext, candidate)
+	if err != nil && runner.drainFailureConfirmDelay > 0 && !kubernetes.IsTransientDrainError(err) {
+		err = runner.confirmDrainFailure(drainContext, candidate, err)
+	}
+	if err != nil {
+		select {
+		case <-conditionCleared:
+			err = errConditionCleared
+		default:
+		}
+	}
 	// We can ignore the error as it's only fired when the drain buffer is not initialized.
 	// This cannot happen as the main loop of the drain runner will be blocked in that case.
 	_ = runner.drainBuffer.StoreDrainAttempt(info.Key, drainBuffer)
@@ -361,6 +737,139 @@ func (runner *drainRunner) drainCandidate(ctx context.Context, info *groups.Runn
 	return nil
 }
 
+// quarantine cordons and labels a node that failed to drain too many times. Its drain schedule is removed by the caller and
+// the candidate filters ignore it until a human removes the label.
+func (runner *drainRunner) quarantine(ctx context.Context, node *corev1.Node) (*corev1.Node, error) {
+	runner.logger.Info("quarantining node after repeated drain failures", "node", node.Name, "failures", runner.retryWall.GetDrainRetryAttemptsCount(node))
+	// The node is cordoned and labelled in a single patch before its NLA taint is removed, so that no pod is scheduled in between
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      map[string]string{kubernetes.QuarantinedLabelKey: kubernetes.QuarantinedLabelValue},
+			"annotations": map[string]string{kubernetes.QuarantineCordonAnnotationKey: "true"},
+		},
+		"spec": map[string]interface{}{"unschedulable": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	patched, err := k8sclient.PatchNodeCRWithResult(ctx, runner.client, node, client.RawPatch(types.MergePatchType, patch))
+	if err != nil {
+		return nil, err
+	}
+	runner.eventRecorder.NodeEventf(ctx, patched, core.EventTypeWarning, kubernetes.EventReasonNodeQuarantined.String(), "Node quarantined after %d drain failures: it stays cordoned until the %s label is removed", runner.retryWall.GetDrainRetryAttemptsCount(patched), kubernetes.QuarantinedLabelKey)
+	return patched, nil
+}
+
+// handleReleasedQuarantines uncordons the nodes cordoned by the quarantine once their quarantine label was removed.
+// Without it, the nodes would stay cordoned without NLA taint, and would be ignored as manually cordoned.
+func (runner *drainRunner) handleReleasedQuarantines(ctx context.Context, info *groups.RunnerInfo) {
+	span, ctx := tracer.StartSpanFromContext(ctx, "HandleReleasedQuarantines")
+	defer span.Finish()
+
+	nodes, err := index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, runner.groupIndexName, string(info.Key))
+	if err != nil {
+		runner.logger.Error(err, "cannot get nodes for group")
+		return
+	}
+	for _, n := range nodes {
+		if !kubernetes.IsQuarantineReleased(n) {
+			continue
+		}
+		runner.logger.Info("uncordoning node released from quarantine", "node", n.Name)
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]interface{}{kubernetes.QuarantineCordonAnnotationKey: nil}},
+			"spec":     map[string]interface{}{"unschedulable": false},
+		})
+		if err != nil {
+			runner.logger.Error(err, "cannot build the uncordon patch", "node", n.Name)
+			continue
+		}
+		if _, err := k8sclient.PatchNodeCRWithResult(ctx, runner.client, n, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			runner.logger.Error(err, "failed to uncordon node released from quarantine", "node", n.Name)
+		}
+	}
+}
+
+// consumeDrainBufferBypass decrements the drain buffer bypasses of a node about to be drained while the drain buffer of its group is not respected.
+// The drain buffer is the one of the candidate filters, relaxed by the soft drain buffer if configured, so that a drain it already allows keeps the bypasses.
+// The nodes requested for an immediate drain ignore the drain buffer without consuming their bypasses.
+// It returns the patched node, so that the following updates of the candidate are not rejected as conflicting.
+func (runner *drainRunner) consumeDrainBufferBypass(ctx context.Context, candidate *corev1.Node, key groups.GroupKey) (*corev1.Node, error) {
+	if runner.drainNow.IsRequested(candidate) {
+		return candidate, nil
+	}
+	count := drainbuffer.GetBypassCount(candidate)
+	if count == 0 {
+		return candidate, nil
+	}
+	nextDrain, err := runner.drainBuffer.NextDrain(key)
+	if err != nil || !nextDrain.After(runner.clock.Now()) {
+		return candidate, nil
+	}
+	runner.logger.Info("consuming a drain buffer bypass", "node", candidate.Name, "remaining", count-1, "next_drain", nextDrain)
+	var annotationPatch k8sclient.AnnotationPatch
+	annotationPatch.Metadata.Annotations = map[string]string{drainbuffer.BypassAnnotation: strconv.Itoa(count - 1)}
+	return k8sclient.PatchNodeCRWithResult(ctx, runner.client, candidate, annotationPatch)
+}
+
+// releaseDrainBudget gives back the drain budgets consumed for a drain that could not start
+func (runner *drainRunner) releaseDrainBudget(ctx context.Context, candidate *corev1.Node) {
+	if runner.drainBudget == nil {
+		return
+	}
+	if err := runner.drainBudget.Release(ctx, candidate); err != nil {
+		runner.logger.Error(err, "failed to release the drain budget", "node", candidate.Name)
+	}
+}
+
+// warnOnDrainDeadline emits a warning event on the node if the drain is still running once the configured fraction of DrainTimeout has elapsed.
+// It is started for each drain attempt and returns as soon as the drain context is done, so the event is emitted at most once per attempt.
+func (runner *drainRunner) warnOnDrainDeadline(ctx context.Context, candidate *corev1.Node) {
+	warnAfter := time.Duration(float64(DrainTimeout) * runner.drainDeadlineWarningRatio)
+	select {
+	case <-ctx.Done():
+		return
+	case <-runner.clock.After(warnAfter):
+	}
+	runner.logger.Info("drain is approaching its deadline", "node", candidate.Name, "elapsed", warnAfter, "timeout", DrainTimeout)
+	runner.eventRecorder.NodeEventf(ctx, candidate, core.EventTypeWarning, kubernetes.EventReasonDrainDeadlineApproaching.String(), "Drain still running after %v, it will time out in %v", warnAfter, DrainTimeout-warnAfter)
+}
+
+// abortDrainOnConditionCleared periodically checks the offending conditions of the node being drained.
+// If they are all cleared, the conditionCleared channel is closed and the drain is cancelled so that the remaining pods are not evicted.
+func (runner *drainRunner) abortDrainOnConditionCleared(ctx context.Context, cancelDrain context.CancelFunc, candidate *corev1.Node, conditionCleared chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-runner.clock.After(conditionClearCheckPeriod):
+		}
+		node, err := runner.refreshNode(ctx, candidate)
+		if err != nil {
+			runner.logger.Error(err, "failed to refresh node while checking its conditions during the drain", "node", candidate.Name)
+			continue
+		}
+		if len(kubernetes.GetNodeOffendingConditions(node, runner.suppliedConditions)) == 0 {
+			runner.logger.Info("node has no more offending conditions, aborting the drain", "node", candidate.Name)
+			close(conditionCleared)
+			cancelDrain()
+			return
+		}
+	}
+}
+
+// confirmDrainFailure attempts the drain once more after the confirmation delay, so that a brief API blip does not count as a drain failure.
+// The first error is returned if the context is done before the end of the delay.
+func (runner *drainRunner) confirmDrainFailure(ctx context.Context, candidate *corev1.Node, firstErr error) error {
+	runner.logger.Info("drain failed, attempting once more before recording the failure", "node", candidate.Name, "delay", runner.drainFailureConfirmDelay, "error", firstErr.Error())
+	select {
+	case <-ctx.Done():
+		return firstErr
+	case <-runner.clock.After(runner.drainFailureConfirmDelay):
+	}
+	return runner.drainer.Drain(ctx, candidate)
+}
+
 func (runner *drainRunner) updateRetryWallOnCandidate(ctx context.Context, candidate *corev1.Node, reason string, groupKey groups.GroupKey) (*corev1.Node, error) {
 	span, ctx := tracer.StartSpanFromContext(ctx, "ResetFailedCandidate")
 	defer span.Finish()
@@ -371,7 +880,7 @@ func (runner *drainRunner) updateRetryWallOnCandidate(ctx context.Context, candi
 		return nil, err
 	}
 	rw := runner.retryWall.GetRetryWallTimestamp(newNode)
-	runner.eventRecorder.NodeEventf(ctx, newNode, core.EventTypeWarning, kubernetes.EventReasonDrainFailed, "Drain failed: next attempt after %v", rw)
+	runner.eventRecorder.NodeEventf(ctx, newNode, core.EventTypeWarning, kubernetes.EventReasonDrainFailed.String(), "Drain failed: next attempt after %v", rw)
 	// We saw the following error here "the object has been modified; please apply your changes to the latest version and try again"
 	// In order to fix it, SetNewRetryWallTimestamp is returning the new version of the node.
 	// This will not remove the error completely, but the amount of occurrences should be very low.
@@ -380,7 +889,7 @@ func (runner *drainRunner) updateRetryWallOnCandidate(ctx context.Context, candi
 
 // getNodesForNLATaint return nodes that match the taint. The boolean is set to true if some nodes are still present in the group, regardless of the taint.
 func (runner *drainRunner) getNodesForNLATaint(ctx context.Context, key groups.GroupKey, taintValues []k8sclient.DrainTaintValue) ([]*corev1.Node, bool, error) {
-	nodes, err := index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, groups.SchedulingGroupIdx, string(key))
+	nodes, err := index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, runner.groupIndexName, string(key))
 	if err != nil {
 		return nil, false, err
 	}
@@ -402,6 +911,25 @@ func (runner *drainRunner) getNodesForNLATaint(ctx context.Context, key groups.G
 	return candidates, len(nodes) > 0, nil
 }
 
+// getSurgeUsage returns the number of nodes of the group that are draining or drained, and the surge budget of the group:
+// drainSurgePercentage of its nodes, rounded down, with a minimum of one node so that the small groups can still be drained.
+func (runner *drainRunner) getSurgeUsage(ctx context.Context, key groups.GroupKey) (unavailable int, budget int, err error) {
+	nodes, err := index.GetFromIndex[corev1.Node](ctx, runner.sharedIndexInformer, runner.groupIndexName, string(key))
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, node := range nodes {
+		if taint, exist := k8sclient.GetNLATaint(node); exist && (taint.Value == k8sclient.TaintDraining || taint.Value == k8sclient.TaintDrained) {
+			unavailable++
+		}
+	}
+	budget = len(nodes) * runner.drainSurgePercentage / 100
+	if budget < 1 {
+		budget = 1
+	}
+	return unavailable, budget, nil
+}
+
 func (runner *drainRunner) handlePVCProtection(ctx context.Context, info *groups.RunnerInfo) {
 	// not taking `draining` on purpose because this is not a "stable" state.
 	// not taking `drainCandidate` because the case is already tackled in at filtering time in this runner (filtering + taint removal)
@@ -423,8 +951,9 @@ func (runner *drainRunner) handlePVCProtection(ctx context.Context, info *groups
 				runner.logger.Error(err, "failed to remove taint", "node", node.Name)
 				continue
 			}
-			runner.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, kubernetes.EventReasonPendingPodWithLocalPV, "Pod "+pods[0].Namespace+"/"+pods[0].Name+" needs that node due to local PV, removing taint from the node")
-			CounterDrainedNodes(node, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(node, runner.suppliedConditions), "pvc_protection")
+			runner.exportEvent(ctx, eventexporter.DrainEventUncordoned, node, info.Key, "pod "+pods[0].Namespace+"/"+pods[0].Name+" needs the node due to local PV")
+			runner.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, kubernetes.EventReasonPendingPodWithLocalPV.String(), "Pod "+pods[0].Namespace+"/"+pods[0].Name+" needs that node due to local PV, removing taint from the node")
+			CounterDrainedNodes(node, DrainedNodeResultFailed, kubernetes.GetNodeOffendingConditions(node, runner.suppliedConditions), "pvc_protection", runner.teamLabelKey)
 		}
 	}
 }
@@ -2,28 +2,45 @@ package drain_runner
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-logr/zapr"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
 	cachecr "sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/planetlabs/draino/internal/candidate_runner/filters"
+	drainbuffer "github.com/planetlabs/draino/internal/drain_buffer"
 	preprocessor "github.com/planetlabs/draino/internal/drain_runner/pre_processor"
+	eventexporter "github.com/planetlabs/draino/internal/event_exporter"
 	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/limit"
+	"github.com/planetlabs/draino/internal/metrics"
 )
 
 type failDrainer struct {
@@ -32,6 +49,74 @@ type failDrainer struct {
 
 func (d *failDrainer) Drain(ctx context.Context, n *v1.Node) error { return errors.New("myerr") }
 
+type errDrainer struct {
+	kubernetes.NoopDrainer
+	err error
+}
+
+func (d *errDrainer) Drain(ctx context.Context, n *v1.Node) error { return d.err }
+
+// flakyDrainer fails the first drain attempt only
+type flakyDrainer struct {
+	kubernetes.NoopDrainer
+	attempts int
+}
+
+func (d *flakyDrainer) Drain(ctx context.Context, n *v1.Node) error {
+	d.attempts++
+	if d.attempts == 1 {
+		return errors.New("api blip")
+	}
+	return nil
+}
+
+// blockingDrainer blocks the drain until it is released
+type blockingDrainer struct {
+	kubernetes.NoopDrainer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDrainer) Drain(ctx context.Context, n *v1.Node) error {
+	close(d.started)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.release:
+		return nil
+	}
+}
+
+// stepDrainer evicts one pod each time it is told to, until all the pods are evicted or the drain is cancelled
+type stepDrainer struct {
+	kubernetes.NoopDrainer
+	pods    int
+	evicted int
+	next    chan struct{}
+}
+
+func (d *stepDrainer) Drain(ctx context.Context, n *v1.Node) error {
+	for d.evicted < d.pods {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.next:
+			d.evicted++
+		}
+	}
+	return nil
+}
+
+// blockedDrainBuffer reports that the next drain of the group is only possible in an hour
+type blockedDrainBuffer struct {
+	drainbuffer.DrainBuffer
+	clock clock.Clock
+}
+
+func (b *blockedDrainBuffer) NextDrain(groups.GroupKey) (time.Time, error) {
+	return b.clock.Now().Add(time.Hour), nil
+}
+
 type testPreprocessor struct {
 	isDone bool
 }
@@ -58,6 +143,7 @@ func TestDrainRunner(t *testing.T) {
 		Preprocessors []preprocessor.DrainPreProcessor
 		Drainer       kubernetes.Drainer
 		Filter        filters.Filter
+		ConfirmDelay  time.Duration
 
 		ShoulHaveTaint  bool
 		ExpectedTaint   k8sclient.DrainTaintValue
@@ -80,6 +166,60 @@ func TestDrainRunner(t *testing.T) {
 			ShoulHaveTaint:  false,
 			ExpectedRetries: 1,
 		},
+		{
+			Name:            "Should requeue the node on transient drain error without counting the attempt",
+			Key:             "my-key",
+			Node:            createNode("my-key", k8sclient.TaintDrainCandidate),
+			Drainer:         &errDrainer{err: apierrors.NewTimeoutError("eviction", 1)},
+			ShoulHaveTaint:  true,
+			ExpectedTaint:   k8sclient.TaintDrainCandidate,
+			ExpectedRetries: 0,
+		},
+		{
+			Name:            "Should count permanent drain error as an attempt",
+			Key:             "my-key",
+			Node:            createNode("my-key", k8sclient.TaintDrainCandidate),
+			Drainer:         &errDrainer{err: apierrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "my-pod", errors.New("not allowed"))},
+			ShoulHaveTaint:  false,
+			ExpectedRetries: 1,
+		},
+		{
+			Name:            "Should confirm the drain failure with a second attempt",
+			Key:             "my-key",
+			Node:            createNode("my-key", k8sclient.TaintDrainCandidate),
+			Drainer:         &flakyDrainer{},
+			ConfirmDelay:    time.Millisecond,
+			ShoulHaveTaint:  true,
+			ExpectedTaint:   k8sclient.TaintDrained,
+			ExpectedRetries: 0,
+		},
+		{
+			Name:            "Should record the drain failure if the second attempt fails",
+			Key:             "my-key",
+			Node:            createNode("my-key", k8sclient.TaintDrainCandidate),
+			Drainer:         &failDrainer{},
+			ConfirmDelay:    time.Millisecond,
+			ShoulHaveTaint:  false,
+			ExpectedRetries: 1,
+		},
+		{
+			Name: "Should skip terminating node and remove its candidate status",
+			Key:  "my-key",
+			Node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "foo-node",
+					Labels:            map[string]string{"key": "my-key"},
+					DeletionTimestamp: &metav1.Time{Time: time.Now()},
+					Finalizers:        []string{"test-finalizer"},
+				},
+				Spec: corev1.NodeSpec{
+					Taints: []corev1.Taint{*k8sclient.CreateNLATaint(k8sclient.TaintDrainCandidate, time.Now())},
+				},
+			},
+			Drainer:         &failDrainer{},
+			ShoulHaveTaint:  false,
+			ExpectedRetries: 0,
+		},
 		{
 			Name:            "Should ignore node without taint",
 			Key:             "my-key",
@@ -117,6 +257,25 @@ func TestDrainRunner(t *testing.T) {
 			ExpectedTaint:   k8sclient.TaintDrained,
 			ExpectedRetries: 0,
 		},
+		{
+			Name:            "Should release the candidate while the node is outside its drain window",
+			Key:             "my-key",
+			Node:            createNodeWithDrainWindow("my-key", k8sclient.TaintDrainCandidate, closedDrainWindow()),
+			Filter:          filters.NewDrainWindowFilter(clock.RealClock{}),
+			Drainer:         &kubernetes.NoopDrainer{},
+			ShoulHaveTaint:  false,
+			ExpectedRetries: 0,
+		},
+		{
+			Name:            "Should drain the node inside its drain window",
+			Key:             "my-key",
+			Node:            createNodeWithDrainWindow("my-key", k8sclient.TaintDrainCandidate, "Sun-Sat:00:00-24:00"),
+			Filter:          filters.NewDrainWindowFilter(clock.RealClock{}),
+			Drainer:         &kubernetes.NoopDrainer{},
+			ShoulHaveTaint:  true,
+			ExpectedTaint:   k8sclient.TaintDrained,
+			ExpectedRetries: 0,
+		},
 		{
 			Name: "Should remove taint if opted out",
 			Key:  "my-key",
@@ -158,6 +317,8 @@ func TestDrainRunner(t *testing.T) {
 				Preprocessors: tt.Preprocessors,
 				Drainer:       tt.Drainer,
 				Filter:        tt.Filter,
+
+				DrainFailureConfirmDelay: tt.ConfirmDelay,
 			})
 			assert.NoError(t, err, "failed to create fake drain runner")
 
@@ -201,3 +362,1110 @@ func createNode(key string, taintVal k8sclient.DrainTaintValue) *corev1.Node {
 		},
 	}
 }
+
+func createNodeWithDrainWindow(key string, taintVal k8sclient.DrainTaintValue, window string) *corev1.Node {
+	node := createNode(key, taintVal)
+	node.Annotations = map[string]string{kubernetes.DrainWindowAnnotationKey: window}
+	return node
+}
+
+// closedDrainWindow returns a window covering only the day after tomorrow
+func closedDrainWindow() string {
+	return time.Now().UTC().Add(48 * time.Hour).Weekday().String()[:3] + ":00:00-24:00"
+}
+
+func TestDrainRunner_ListSchedules(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	newNode := func(name string, taintVal k8sclient.DrainTaintValue, taintTime time.Time) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{*k8sclient.CreateNLATaint(taintVal, taintTime)}},
+		}
+	}
+	failedNode := newNode("node-failed", k8sclient.TaintDrainCandidate, now.Add(-time.Hour))
+
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{
+			newNode("node-candidate", k8sclient.TaintDrainCandidate, now.Add(-time.Minute)),
+			failedNode,
+			newNode("node-draining", k8sclient.TaintDraining, now.Add(-time.Minute)),
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-untainted"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		RetryStrategy: &drain.StaticRetryStrategy{Delay: time.Hour, AlertThreashold: 5},
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	_, err = runner.retryWall.SetNewRetryWallTimestamp(context.Background(), failedNode, "drain failed", now)
+	assert.NoError(t, err)
+
+	expected := []ScheduledDrain{
+		{NodeName: "node-candidate", ScheduledTime: now.Add(-time.Minute), FailedCount: 0},
+		{NodeName: "node-failed", ScheduledTime: now.Add(time.Hour), FailedCount: 1},
+	}
+	assert.Eventually(t, func() bool {
+		schedules, err := runner.ListSchedules(context.Background())
+		if err != nil || len(schedules) != len(expected) {
+			return false
+		}
+		for i := range expected {
+			if schedules[i].NodeName != expected[i].NodeName || !schedules[i].ScheduledTime.Equal(expected[i].ScheduledTime) || schedules[i].FailedCount != expected[i].FailedCount {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestScheduledDrain_JSON(t *testing.T) {
+	data, err := json.Marshal(ScheduledDrain{NodeName: "node-1", ScheduledTime: time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC), FailedCount: 2})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"nodeName":"node-1","scheduledTime":"2023-01-01T12:00:00Z","failedCount":2}`, string(data))
+}
+
+func TestDrainRunner_AuditLog(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{createNode("my-key", k8sclient.TaintDrainCandidate)},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := eventexporter.NewAuditLogEventExporter(path, 0)
+	assert.NoError(t, err)
+
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Drainer:       &kubernetes.NoopDrainer{},
+		EventExporter: auditLog,
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	drainedBefore := testutil.ToFloat64(metrics.CandidateMetrics.Drained.WithLabelValues("my-key", ""))
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+	runner.handleGroup(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"})
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CandidateMetrics.Drained.WithLabelValues("my-key", ""))-drainedBefore)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var actions []eventexporter.DrainEventType
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry eventexporter.AuditEntry
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "foo-node", entry.Node)
+		assert.Equal(t, "my-key", entry.Group)
+		assert.Equal(t, eventexporter.AuditLogActor, entry.Actor)
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []eventexporter.DrainEventType{eventexporter.DrainEventStarted, eventexporter.DrainEventSucceeded}, actions)
+}
+
+func TestDrainRunner_AuditLogStuckInDraining(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{createNode("my-key", k8sclient.TaintDraining)},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditLog, err := eventexporter.NewAuditLogEventExporter(path, 0)
+	assert.NoError(t, err)
+
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		EventExporter: auditLog,
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	runner.handleLeftOverDraining(context.Background(), &groups.RunnerInfo{Context: context.Background(), Key: "my-key"})
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var entry eventexporter.AuditEntry
+	assert.NoError(t, json.Unmarshal(data, &entry))
+	assert.Equal(t, "foo-node", entry.Node)
+	assert.Equal(t, eventexporter.DrainEventUncordoned, entry.Action)
+}
+
+func TestDrainRunner_DrainDeadlineWarning(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	node := createNode("my-key", k8sclient.TaintDraining)
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{node},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	recorder := record.NewFakeRecorder(10)
+	drainer := &blockingDrainer{started: make(chan struct{}), release: make(chan struct{})}
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Clock:         fakeClock,
+		Drainer:       drainer,
+		EventRecorder: kubernetes.NewEventRecorder(recorder),
+
+		DrainDeadlineWarningRatio: 0.8,
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- runner.drainCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"}, node)
+	}()
+	<-drainer.started
+	assert.Eventually(t, fakeClock.HasWaiters, time.Second, 5*time.Millisecond, "the deadline warning should be waiting")
+
+	fakeClock.Step(7 * time.Minute)
+	assert.Never(t, func() bool { return len(recorder.Events) > 0 }, 50*time.Millisecond, 5*time.Millisecond, "no warning before 80% of the timeout")
+
+	fakeClock.Step(2 * time.Minute)
+	assert.Eventually(t, func() bool { return len(recorder.Events) > 0 }, time.Second, 5*time.Millisecond, "warning after 80% of the timeout")
+
+	fakeClock.Step(30 * time.Second)
+	close(drainer.release)
+	assert.NoError(t, <-done)
+
+	assert.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning "+kubernetes.EventReasonDrainDeadlineApproaching.String())
+}
+
+func TestDrainRunner_EmptyNode(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	node := createNode("my-key", k8sclient.TaintDrainCandidate)
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{node},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// the API drainer reads the node from its own client, where the node is already tainted 'draining'
+	cs := fake.NewSimpleClientset(createNode("my-key", k8sclient.TaintDraining))
+	drainer := kubernetes.NewAPIDrainer(cs, kubernetes.NoopEventRecorder{}, kubernetes.WithContainerRuntimeClient(wrapper.GetManagerClient()))
+	recorder := record.NewFakeRecorder(10)
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Drainer:       drainer,
+		EventRecorder: kubernetes.NewEventRecorder(recorder),
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	// A node without pods to evict is drained in a single pass
+	ctx := context.Background()
+	assert.NoError(t, runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"}, node))
+
+	var n corev1.Node
+	assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &n))
+	taint, _ := k8sclient.GetNLATaint(&n)
+	assert.Equal(t, k8sclient.TaintDrained, taint.Value)
+	assert.Len(t, recorder.Events, 2)
+	assert.Contains(t, <-recorder.Events, "Normal "+kubernetes.EventReasonDrainStarting.String())
+	assert.Contains(t, <-recorder.Events, "Normal "+kubernetes.EventReasonDrainSucceeded.String())
+}
+
+func TestDrainRunner_LifecycleEventReasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		drainer  kubernetes.Drainer
+		expected []kubernetes.EventReason
+	}{
+		{
+			name:     "drain succeeded",
+			drainer:  &kubernetes.NoopDrainer{},
+			expected: []kubernetes.EventReason{kubernetes.EventReasonDrainStarting, kubernetes.EventReasonDrainSucceeded},
+		},
+		{
+			name:     "drain failed",
+			drainer:  &failDrainer{},
+			expected: []kubernetes.EventReason{kubernetes.EventReasonDrainStarting, kubernetes.EventReasonDrainFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testLogger := zapr.NewLogger(zap.NewNop())
+			node := createNode("my-key", k8sclient.TaintDrainCandidate)
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: []runtime.Object{node},
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			recorder := record.NewFakeRecorder(10)
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:          ch,
+				ClientWrapper: wrapper,
+				Drainer:       tt.drainer,
+				EventRecorder: kubernetes.NewEventRecorder(recorder),
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			ctx := context.Background()
+			_ = runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"}, node)
+
+			var reasons []kubernetes.EventReason
+			for len(recorder.Events) > 0 {
+				reasons = append(reasons, kubernetes.EventReason(strings.Fields(<-recorder.Events)[1]))
+			}
+			if assert.NotEmpty(t, reasons) {
+				assert.Equal(t, tt.expected[0], reasons[0])
+			}
+			assert.Subset(t, tt.expected, reasons, "only the expected transitions")
+			assert.Contains(t, reasons, tt.expected[len(tt.expected)-1])
+			assert.Subset(t, kubernetes.AllEventReasons(), reasons, "the reasons are part of the enum")
+		})
+	}
+}
+
+func TestDrainRunner_AbortDrainOnConditionClear(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	node := createNode("my-key", k8sclient.TaintDrainCandidate)
+	node.Status.Conditions = []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}}
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{node},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	drainer := &stepDrainer{pods: 3, next: make(chan struct{})}
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Clock:         fakeClock,
+		Drainer:       drainer,
+
+		AbortDrainOnConditionClear: true,
+		SuppliedConditions:         []kubernetes.SuppliedCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}},
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"}, node)
+	}()
+	drainer.next <- struct{}{}
+
+	// The condition is still there: the drain goes on
+	assert.Eventually(t, fakeClock.HasWaiters, time.Second, 5*time.Millisecond, "the condition check should be waiting")
+	fakeClock.Step(conditionClearCheckPeriod)
+	drainer.next <- struct{}{}
+	// the node must be refreshed by the condition check before the condition clears
+	assert.Eventually(t, fakeClock.HasWaiters, time.Second, 5*time.Millisecond, "the condition check should be waiting again")
+
+	// The condition clears while the drain is in progress
+	var current corev1.Node
+	assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &current))
+	current.Status.Conditions[0].Status = corev1.ConditionFalse
+	assert.NoError(t, wrapper.GetManagerClient().Update(ctx, &current))
+	assert.Eventually(t, fakeClock.HasWaiters, time.Second, 5*time.Millisecond, "the condition check should be waiting")
+	fakeClock.Step(conditionClearCheckPeriod)
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, 2, drainer.evicted, "no eviction after the condition cleared")
+
+	assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &current))
+	_, hasTaint := k8sclient.GetNLATaint(&current)
+	assert.False(t, hasTaint, "the node should be uncordoned")
+	assert.Contains(t, current.Annotations, kubernetes.LastUncordonAnnotationKey)
+}
+
+func TestDrainRunner_LastUncordonOnRecovery(t *testing.T) {
+	tests := []struct {
+		Name               string
+		ConditionStatus    corev1.ConditionStatus
+		ExpectLastUncordon bool
+	}{
+		{Name: "Should record the uncordon of a recovered node", ConditionStatus: corev1.ConditionFalse, ExpectLastUncordon: true},
+		{Name: "Should not record the uncordon of a node rejected with its condition", ConditionStatus: corev1.ConditionTrue, ExpectLastUncordon: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			testLogger := zapr.NewLogger(zap.NewNop())
+			node := createNode("my-key", k8sclient.TaintDrainCandidate)
+			node.Status.Conditions = []corev1.NodeCondition{{Type: "KernelDeadlock", Status: tt.ConditionStatus}}
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: []runtime.Object{node},
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:               ch,
+				ClientWrapper:      wrapper,
+				Filter:             filters.FilterFromFunction("always_false", func(ctx context.Context, n *corev1.Node) bool { return false }),
+				SuppliedConditions: []kubernetes.SuppliedCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue}},
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			err = runner.handleCandidate(context.Background(), &groups.RunnerInfo{Context: context.Background(), Key: "my-key"}, node)
+			assert.NoError(t, err)
+
+			var current corev1.Node
+			assert.NoError(t, wrapper.GetManagerClient().Get(context.Background(), types.NamespacedName{Name: node.Name}, &current))
+			_, hasTaint := k8sclient.GetNLATaint(&current)
+			assert.False(t, hasTaint, "the candidate status should be removed")
+			_, hasLastUncordon := current.Annotations[kubernetes.LastUncordonAnnotationKey]
+			assert.Equal(t, tt.ExpectLastUncordon, hasLastUncordon)
+		})
+	}
+}
+
+func TestDrainRunner_DrainBufferBypass(t *testing.T) {
+	tests := []struct {
+		Name           string
+		Bypass         string
+		DrainNow       bool
+		SoftBuffer     bool
+		ExpectedBypass string
+		ExpectedTaint  k8sclient.DrainTaintValue
+	}{
+		{Name: "Should consume one bypass", Bypass: "2", ExpectedBypass: "1", ExpectedTaint: k8sclient.TaintDrained},
+		{Name: "Should consume the last bypass", Bypass: "1", ExpectedBypass: "0", ExpectedTaint: k8sclient.TaintDrained},
+		{Name: "Should not drain without bypass", Bypass: "0", ExpectedBypass: "0"},
+		{Name: "Should not consume a bypass for an immediate drain", Bypass: "1", DrainNow: true, ExpectedBypass: "1", ExpectedTaint: k8sclient.TaintDrained},
+		{Name: "Should not consume a bypass when the soft drain buffer is respected", Bypass: "1", SoftBuffer: true, ExpectedBypass: "1", ExpectedTaint: k8sclient.TaintDrained},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			testLogger := zapr.NewLogger(zap.NewNop())
+			node := createNode("my-key", k8sclient.TaintDrainCandidate)
+			node.Annotations = map[string]string{drainbuffer.BypassAnnotation: tt.Bypass}
+			if tt.DrainNow {
+				node.Annotations[kubernetes.DefaultDrainNowAnnotationKey] = kubernetes.DrainNowAnnotationValue
+			}
+			keyGetter := groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, "")
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: []runtime.Object{node},
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, keyGetter)
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			persistor := drainbuffer.NewConfigMapPersistor(fake.NewSimpleClientset().CoreV1().ConfigMaps("default"), "fake-buffer", "default")
+			buffer := drainbuffer.NewDrainBuffer(context.Background(), persistor, fakeClock, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, kubernetes.DefaultDrainBuffer)
+			var blocked drainbuffer.DrainBuffer = &blockedDrainBuffer{DrainBuffer: buffer, clock: fakeClock}
+			if tt.SoftBuffer {
+				// the group was never drained, the relaxed drain buffer allows the drain
+				blocked = drainbuffer.NewSoftDrainBuffer(blocked, func() (float64, bool) { return 0, true }, 1, 0.5)
+			}
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:          ch,
+				ClientWrapper: wrapper,
+				Clock:         fakeClock,
+				Filter:        filters.NewDrainBufferFilter(blocked, fakeClock, keyGetter),
+				DrainBuffer:   blocked,
+				DrainNow:      kubernetes.DrainNowConfig{AnnotationKey: kubernetes.DefaultDrainNowAnnotationKey},
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			err = runner.handleCandidate(context.Background(), &groups.RunnerInfo{Context: context.Background(), Key: "my-key"}, node)
+			assert.NoError(t, err)
+
+			var drained corev1.Node
+			assert.NoError(t, wrapper.GetManagerClient().Get(context.Background(), types.NamespacedName{Name: node.Name}, &drained))
+			assert.Equal(t, tt.ExpectedBypass, drained.Annotations[drainbuffer.BypassAnnotation])
+			taint, _ := k8sclient.GetNLATaint(&drained)
+			if tt.ExpectedTaint == "" {
+				assert.Nil(t, taint, "the candidate status should be removed")
+				return
+			}
+			_, hasDrainNow := drained.Annotations[kubernetes.DefaultDrainNowAnnotationKey]
+			assert.False(t, hasDrainNow, "the immediate drain request is removed once the node is drained")
+			assert.Equal(t, tt.ExpectedTaint, taint.Value)
+		})
+	}
+}
+
+func TestDrainRunner_QuarantineOnMaxFailures(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Quarantine bool
+		Threshold  int
+
+		ExpectedQuarantine bool
+	}{
+		{Name: "Should uncordon and ignore the failure when the quarantine is disabled", Quarantine: false, Threshold: 1, ExpectedQuarantine: false},
+		{Name: "Should quarantine the node reaching the threshold", Quarantine: true, Threshold: 1, ExpectedQuarantine: true},
+		{Name: "Should not quarantine the node below the threshold", Quarantine: true, Threshold: 2, ExpectedQuarantine: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			testLogger := zapr.NewLogger(zap.NewNop())
+			node := createNode("my-key", k8sclient.TaintDrainCandidate)
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: []runtime.Object{node},
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:          ch,
+				ClientWrapper: wrapper,
+				Drainer:       &failDrainer{},
+				RetryStrategy: &drain.StaticRetryStrategy{Delay: time.Second, AlertThreashold: tt.Threshold},
+
+				QuarantineOnMaxFailures: tt.Quarantine,
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			err = runner.handleCandidate(context.Background(), &groups.RunnerInfo{Context: context.Background(), Key: "my-key"}, node)
+			assert.Error(t, err)
+
+			var failed corev1.Node
+			assert.NoError(t, wrapper.GetManagerClient().Get(context.Background(), types.NamespacedName{Name: node.Name}, &failed))
+			_, hasTaint := k8sclient.GetNLATaint(&failed)
+			assert.False(t, hasTaint, "the drain schedule should be removed")
+			assert.Equal(t, tt.ExpectedQuarantine, failed.Spec.Unschedulable)
+			assert.Equal(t, tt.ExpectedQuarantine, kubernetes.IsQuarantined(&failed))
+			if !tt.ExpectedQuarantine {
+				return
+			}
+
+			// the node is uncordoned once the quarantine label is removed
+			delete(failed.Labels, kubernetes.QuarantinedLabelKey)
+			assert.NoError(t, wrapper.GetManagerClient().Update(context.Background(), &failed))
+			assert.Eventually(t, func() bool {
+				runner.handleReleasedQuarantines(context.Background(), &groups.RunnerInfo{Context: context.Background(), Key: "my-key"})
+				var released corev1.Node
+				if err := wrapper.GetManagerClient().Get(context.Background(), types.NamespacedName{Name: node.Name}, &released); err != nil {
+					return false
+				}
+				_, cordonAnnotation := released.Annotations[kubernetes.QuarantineCordonAnnotationKey]
+				return !released.Spec.Unschedulable && !cordonAnnotation
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestDrainRunner_WaitReplacementReady(t *testing.T) {
+	now := time.Now()
+	newPod := func(name, nodeName string, ready, gatePassed bool) *corev1.Pod {
+		isController := true
+		readyStatus, gateStatus := corev1.ConditionFalse, corev1.ConditionFalse
+		if ready {
+			readyStatus = corev1.ConditionTrue
+		}
+		if gatePassed {
+			gateStatus = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(now),
+				OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "my-rs", Controller: &isController}},
+			},
+			Spec: corev1.PodSpec{
+				NodeName:       nodeName,
+				ReadinessGates: []corev1.PodReadinessGate{{ConditionType: "my-gate"}},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: readyStatus},
+					{Type: "my-gate", Status: gateStatus},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		Name        string
+		Replacement *corev1.Pod
+		Elapsed     time.Duration
+
+		ExpectedTaint   k8sclient.DrainTaintValue
+		ExpectedPending bool
+	}{
+		{
+			Name:          "Should complete the drain when the replacement is ready",
+			Replacement:   newPod("replacement", "other-node", true, true),
+			ExpectedTaint: k8sclient.TaintDrained,
+		},
+		{
+			Name:            "Should keep the drain pending while the replacement is not ready",
+			Replacement:     newPod("replacement", "other-node", false, false),
+			ExpectedTaint:   k8sclient.TaintDraining,
+			ExpectedPending: true,
+		},
+		{
+			Name:            "Should keep the drain pending while the readiness gate of the replacement is not passed",
+			Replacement:     newPod("replacement", "other-node", true, false),
+			ExpectedTaint:   k8sclient.TaintDraining,
+			ExpectedPending: true,
+		},
+		{
+			Name:            "Should keep the drain pending without replacement",
+			ExpectedTaint:   k8sclient.TaintDraining,
+			ExpectedPending: true,
+		},
+		{
+			Name: "Should keep the drain pending when the ready pod existed before the drain",
+			Replacement: func() *corev1.Pod {
+				pod := newPod("sibling", "other-node", true, true)
+				pod.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+				return pod
+			}(),
+			ExpectedTaint:   k8sclient.TaintDraining,
+			ExpectedPending: true,
+		},
+		{
+			Name:          "Should complete the drain after the timeout",
+			Replacement:   newPod("replacement", "other-node", false, false),
+			Elapsed:       time.Hour,
+			ExpectedTaint: k8sclient.TaintDrained,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			testLogger := zapr.NewLogger(zap.NewNop())
+			node := createNode("my-key", k8sclient.TaintDrainCandidate)
+			objects := []runtime.Object{node, newPod("evicted", node.Name, true, true)}
+			if tt.Replacement != nil {
+				objects = append(objects, tt.Replacement)
+			}
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: objects,
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			fakeClock := testingclock.NewFakeClock(now)
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:          ch,
+				ClientWrapper: wrapper,
+				Clock:         fakeClock,
+
+				WaitReplacementReady: 10 * time.Minute,
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			ctx := context.Background()
+			info := &groups.RunnerInfo{Context: ctx, Key: "my-key"}
+			assert.NoError(t, runner.handleCandidate(ctx, info, node))
+
+			fakeClock.Step(tt.Elapsed)
+			assert.Eventually(t, func() bool {
+				runner.handlePendingReplacements(ctx, info)
+				runner.handleLeftOverDraining(ctx, info)
+				var current corev1.Node
+				if err := wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &current); err != nil {
+					return false
+				}
+				taint, hasTaint := k8sclient.GetNLATaint(&current)
+				_, pending := current.Annotations[kubernetes.PendingReplacementsAnnotationKey]
+				return hasTaint && taint.Value == tt.ExpectedTaint && pending == tt.ExpectedPending
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+// concurrencyDrainer records the highest number of drains running at the same time, the drains are blocked until released
+type concurrencyDrainer struct {
+	kubernetes.NoopDrainer
+	mu         sync.Mutex
+	running    int
+	maxRunning int
+	release    chan struct{}
+}
+
+func (d *concurrencyDrainer) Drain(ctx context.Context, n *v1.Node) error {
+	d.mu.Lock()
+	d.running++
+	if d.running > d.maxRunning {
+		d.maxRunning = d.running
+	}
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.running--
+		d.mu.Unlock()
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.release:
+		return nil
+	}
+}
+
+func (d *concurrencyDrainer) getRunning() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.running
+}
+
+func TestDrainRunner_GlobalDrainLimiter(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	groupKeys := []string{"group-a", "group-b", "group-c"}
+	var nodes []*corev1.Node
+	var objects []runtime.Object
+	for _, key := range groupKeys {
+		node := createNode(key, k8sclient.TaintDrainCandidate)
+		node.Name = "node-" + key
+		nodes = append(nodes, node)
+		objects = append(objects, node)
+	}
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: objects,
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	drainer := &concurrencyDrainer{release: make(chan struct{})}
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Drainer:       drainer,
+
+		GlobalDrainLimiter: limit.NewConcurrencyLimiter(2),
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan string, len(nodes))
+	for i := range nodes {
+		node, key := nodes[i], groupKeys[i]
+		go func() {
+			assert.NoError(t, runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: groups.GroupKey(key)}, node))
+			done <- node.Name
+		}()
+	}
+
+	// Each group wants to drain its node, only two drains can run at the same time
+	assert.Eventually(t, func() bool { return drainer.getRunning() == 2 }, time.Second, 5*time.Millisecond)
+	deferred := <-done
+	assert.Never(t, func() bool { return drainer.getRunning() > 2 }, 50*time.Millisecond, 5*time.Millisecond)
+	close(drainer.release)
+	<-done
+	<-done
+	assert.Equal(t, 2, drainer.maxRunning)
+
+	getTaint := func(name string) k8sclient.DrainTaintValue {
+		var n corev1.Node
+		assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: name}, &n))
+		taint, _ := k8sclient.GetNLATaint(&n)
+		return taint.Value
+	}
+	for _, node := range nodes {
+		if node.Name == deferred {
+			assert.Equal(t, k8sclient.TaintDrainCandidate, getTaint(node.Name), "the deferred node should stay candidate")
+		} else {
+			assert.Equal(t, k8sclient.TaintDrained, getTaint(node.Name))
+		}
+	}
+
+	// A slot is free again, the deferred node is drained at the next iteration
+	for i, node := range nodes {
+		if node.Name == deferred {
+			assert.NoError(t, runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: groups.GroupKey(groupKeys[i])}, node))
+		}
+	}
+	assert.Equal(t, k8sclient.TaintDrained, getTaint(deferred))
+}
+
+// failingDrainBudget fails to consume the budget, the drain cannot start
+type failingDrainBudget struct{}
+
+func (failingDrainBudget) TryConsume(context.Context, *corev1.Node) (bool, string, error) {
+	return false, "", errors.New("budget unavailable")
+}
+
+func (failingDrainBudget) Release(context.Context, *corev1.Node) error {
+	return nil
+}
+
+func TestDrainRunner_MaxSchedulingFailures(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	node := createNode("my-key", k8sclient.TaintDrainCandidate)
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{node},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	recorder := record.NewFakeRecorder(10)
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Drainer:       &kubernetes.NoopDrainer{},
+		EventRecorder: kubernetes.NewEventRecorder(recorder),
+
+		DrainBudget:           failingDrainBudget{},
+		MaxSchedulingFailures: 3,
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	ctx := context.Background()
+	info := &groups.RunnerInfo{Context: ctx, Key: "my-key"}
+	getNode := func() *corev1.Node {
+		var n corev1.Node
+		assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &n))
+		return &n
+	}
+
+	for i := 0; i < 2; i++ {
+		runner.handleGroup(ctx, info)
+		taint, _ := k8sclient.GetNLATaint(getNode())
+		assert.Equal(t, k8sclient.TaintDrainCandidate, taint.Value, "the node stays candidate below the limit")
+		assert.Empty(t, recorder.Events)
+	}
+
+	// The third consecutive failure removes the candidate status
+	runner.handleGroup(ctx, info)
+	n := getNode()
+	_, hasTaint := k8sclient.GetNLATaint(n)
+	assert.False(t, hasTaint, "the node should not be stranded as candidate")
+	assert.False(t, runner.retryWall.GetRetryWallTimestamp(n).IsZero(), "a retry wall should be set")
+	assert.Contains(t, <-recorder.Events, "Warning "+kubernetes.EventReasonDrainSchedulingFailed.String())
+	assert.Empty(t, runner.schedulingFailures, "the count is reset")
+}
+
+func TestDrainRunner_MaxSchedulingFailuresIgnoresDrainErrors(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	node := createNode("my-key", k8sclient.TaintDrainCandidate)
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{node},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Drainer:       &errDrainer{err: apierrors.NewTimeoutError("eviction", 1)},
+
+		MaxSchedulingFailures: 2,
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	ctx := context.Background()
+	info := &groups.RunnerInfo{Context: ctx, Key: "my-key"}
+	for i := 0; i < 3; i++ {
+		runner.handleGroup(ctx, info)
+	}
+	var n corev1.Node
+	assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &n))
+	taint, _ := k8sclient.GetNLATaint(&n)
+	assert.Equal(t, k8sclient.TaintDrainCandidate, taint.Value, "the transient drain errors are requeued, not counted as scheduling failures")
+	assert.True(t, runner.retryWall.GetRetryWallTimestamp(&n).IsZero())
+	assert.Empty(t, runner.schedulingFailures)
+}
+
+func TestDrainRunner_TransientDrainRetries(t *testing.T) {
+	testLogger := zapr.NewLogger(zap.NewNop())
+	node := createNode("my-key", k8sclient.TaintDrainCandidate)
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+		Objects: []runtime.Object{node},
+		Indexes: []k8sclient.WithIndex{
+			func(_ client.Client, cache cachecr.Cache) error {
+				return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	recorder := record.NewFakeRecorder(10)
+	drainer := &countingDrainer{err: apierrors.NewTimeoutError("eviction", 1)}
+	ch := make(chan struct{})
+	defer close(ch)
+	runner, err := NewFakeRunner(&FakeOptions{
+		Chan:          ch,
+		ClientWrapper: wrapper,
+		Clock:         fakeClock,
+		Drainer:       drainer,
+		EventRecorder: kubernetes.NewEventRecorder(recorder),
+
+		MaxTransientDrainRetries: 2,
+	})
+	assert.NoError(t, err, "failed to create fake drain runner")
+
+	ctx := context.Background()
+	info := &groups.RunnerInfo{Context: ctx, Key: "my-key"}
+	getNode := func() *corev1.Node {
+		var n corev1.Node
+		assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: node.Name}, &n))
+		return &n
+	}
+
+	runner.handleGroup(ctx, info)
+	assert.Equal(t, 1, drainer.calls)
+	assert.Contains(t, <-recorder.Events, "Warning "+kubernetes.EventReasonDrainFailed.String())
+
+	// the backoff is not over, the drain is not attempted
+	runner.handleGroup(ctx, info)
+	assert.Equal(t, 1, drainer.calls)
+
+	// the second transient error is only logged
+	fakeClock.Step(transientRetryBaseDelay)
+	runner.handleGroup(ctx, info)
+	assert.Equal(t, 2, drainer.calls)
+	for len(recorder.Events) > 0 {
+		assert.NotContains(t, <-recorder.Events, kubernetes.EventReasonDrainFailed.String(), "only the first retry emits an event")
+	}
+	taint, _ := k8sclient.GetNLATaint(getNode())
+	assert.Equal(t, k8sclient.TaintDrainCandidate, taint.Value)
+	assert.True(t, runner.retryWall.GetRetryWallTimestamp(getNode()).IsZero())
+
+	// the retries are exhausted, the next transient error is a drain failure
+	fakeClock.Step(2 * transientRetryBaseDelay)
+	runner.handleGroup(ctx, info)
+	assert.Equal(t, 3, drainer.calls)
+	n := getNode()
+	_, hasTaint := k8sclient.GetNLATaint(n)
+	assert.False(t, hasTaint)
+	assert.False(t, runner.retryWall.GetRetryWallTimestamp(n).IsZero(), "a retry wall should be set")
+	assert.Empty(t, runner.transientFailures)
+}
+
+// countingDrainer counts the drain attempts, all of them failing with the given error
+type countingDrainer struct {
+	kubernetes.NoopDrainer
+	err   error
+	calls int
+}
+
+func (d *countingDrainer) Drain(ctx context.Context, n *v1.Node) error {
+	d.calls++
+	return d.err
+}
+
+func TestDrainRunner_DrainSurgePercentage(t *testing.T) {
+	tests := []struct {
+		Name                 string
+		DrainSurgePercentage int
+		Unavailable          []k8sclient.DrainTaintValue
+		ExpectedTaint        k8sclient.DrainTaintValue
+	}{
+		{
+			Name:                 "Should drain when no other node of the group is unavailable",
+			DrainSurgePercentage: 20,
+			ExpectedTaint:        k8sclient.TaintDrained,
+		},
+		{
+			Name:                 "Should keep the candidate status when the surge budget is used",
+			DrainSurgePercentage: 20,
+			Unavailable:          []k8sclient.DrainTaintValue{k8sclient.TaintDraining},
+			ExpectedTaint:        k8sclient.TaintDrainCandidate,
+		},
+		{
+			Name:                 "Should drain when the surge budget allows one more node",
+			DrainSurgePercentage: 40,
+			Unavailable:          []k8sclient.DrainTaintValue{k8sclient.TaintDrained},
+			ExpectedTaint:        k8sclient.TaintDrained,
+		},
+		{
+			Name:                 "Should count both the draining and the drained nodes",
+			DrainSurgePercentage: 40,
+			Unavailable:          []k8sclient.DrainTaintValue{k8sclient.TaintDraining, k8sclient.TaintDrained},
+			ExpectedTaint:        k8sclient.TaintDrainCandidate,
+		},
+	}
+
+	testLogger := zapr.NewLogger(zap.NewNop())
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			// the group has 5 nodes: the candidate, the unavailable nodes and healthy nodes
+			candidate := createNode("my-key", k8sclient.TaintDrainCandidate)
+			objects := []runtime.Object{candidate}
+			for i := 0; i < 4; i++ {
+				var taint k8sclient.DrainTaintValue
+				if i < len(tt.Unavailable) {
+					taint = tt.Unavailable[i]
+				}
+				node := createNode("my-key", taint)
+				node.Name = fmt.Sprintf("other-node-%d", i)
+				objects = append(objects, node)
+			}
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: objects,
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:          ch,
+				ClientWrapper: wrapper,
+				Drainer:       &kubernetes.NoopDrainer{},
+
+				DrainSurgePercentage: tt.DrainSurgePercentage,
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			ctx := context.Background()
+			assert.NoError(t, runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"}, candidate))
+
+			var n corev1.Node
+			assert.NoError(t, wrapper.GetManagerClient().Get(ctx, types.NamespacedName{Name: candidate.Name}, &n))
+			taint, _ := k8sclient.GetNLATaint(&n)
+			assert.Equal(t, tt.ExpectedTaint, taint.Value)
+		})
+	}
+}
+
+// evictingDrainer evicts the given number of pods and deletes the given number of PVCs, through the stats of the drain
+type evictingDrainer struct {
+	kubernetes.NoopDrainer
+	pods, pvcs int
+}
+
+func (d *evictingDrainer) Drain(ctx context.Context, n *v1.Node) error {
+	if stats := kubernetes.DrainStatsFromContext(ctx); stats != nil {
+		stats.AddPodsEvicted(d.pods)
+		stats.AddPVCsDeleted(d.pvcs)
+	}
+	return nil
+}
+
+func TestDrainRunner_EmitDrainSummary(t *testing.T) {
+	for _, emit := range []bool{true, false} {
+		t.Run(fmt.Sprintf("emit=%v", emit), func(t *testing.T) {
+			testLogger := zapr.NewLogger(zap.NewNop())
+			node := createNode("my-key", k8sclient.TaintDrainCandidate)
+			wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{
+				Objects: []runtime.Object{node},
+				Indexes: []k8sclient.WithIndex{
+					func(_ client.Client, cache cachecr.Cache) error {
+						return groups.InitSchedulingGroupIndexer(cache, groups.NewGroupKeyFromNodeMetadata(nil, testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"key"}, nil, ""))
+					},
+				},
+			})
+			assert.NoError(t, err)
+
+			recorder := record.NewFakeRecorder(10)
+			ch := make(chan struct{})
+			defer close(ch)
+			runner, err := NewFakeRunner(&FakeOptions{
+				Chan:             ch,
+				ClientWrapper:    wrapper,
+				Drainer:          &evictingDrainer{pods: 3, pvcs: 1},
+				EventRecorder:    kubernetes.NewEventRecorder(recorder),
+				EmitDrainSummary: emit,
+			})
+			assert.NoError(t, err, "failed to create fake drain runner")
+
+			ctx := context.Background()
+			assert.NoError(t, runner.handleCandidate(ctx, &groups.RunnerInfo{Context: ctx, Key: "my-key"}, node))
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			if !emit {
+				assert.Len(t, events, 2, "only the drain starting and succeeded events")
+				return
+			}
+			if assert.Len(t, events, 3) {
+				assert.Equal(t, "Normal "+kubernetes.EventReasonDrainSummary.String()+" Drain completed in 0s: 3 pods evicted, 1 PVCs deleted, 0 previous failed attempts", events[2])
+			}
+		})
+	}
+}
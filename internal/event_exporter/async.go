@@ -0,0 +1,54 @@
+package event_exporter
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+)
+
+// ErrExportQueueFull is returned when the event is dropped because the queue of the asynchronous exporter is full
+var ErrExportQueueFull = errors.New("event export queue is full, event dropped")
+
+// AsyncEventExporter queues the events and publishes them in the background, so that a slow backend does not stall the runners.
+// It must be started, for example with the controller runtime manager.
+type AsyncEventExporter struct {
+	exporter EventExporter
+	queue    chan DrainEvent
+	logger   logr.Logger
+}
+
+var _ EventExporter = &AsyncEventExporter{}
+
+// NewAsyncEventExporter returns an exporter queueing at most queueSize events for the given exporter
+func NewAsyncEventExporter(exporter EventExporter, queueSize int, logger logr.Logger) *AsyncEventExporter {
+	return &AsyncEventExporter{
+		exporter: exporter,
+		queue:    make(chan DrainEvent, queueSize),
+		logger:   logger,
+	}
+}
+
+// Export queues the event without blocking. The event is dropped if the queue is full.
+func (a *AsyncEventExporter) Export(_ context.Context, event DrainEvent) error {
+	select {
+	case a.queue <- event:
+		return nil
+	default:
+		return ErrExportQueueFull
+	}
+}
+
+// Start publishes the queued events until the context is done
+func (a *AsyncEventExporter) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-a.queue:
+			if err := a.exporter.Export(ctx, event); err != nil {
+				a.logger.Error(err, "failed to export drain event", "node", event.Node, "type", event.Type)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package event_exporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingExporter struct {
+	sync.Mutex
+	events []DrainEvent
+}
+
+func (r *recordingExporter) Export(_ context.Context, event DrainEvent) error {
+	r.Lock()
+	defer r.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *recordingExporter) count() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.events)
+}
+
+func TestAsyncEventExporter(t *testing.T) {
+	recorder := &recordingExporter{}
+	exporter := NewAsyncEventExporter(recorder, 1, logr.Discard())
+
+	// nothing publishes the events yet, the second one does not fit in the queue
+	require.NoError(t, exporter.Export(context.Background(), DrainEvent{Type: DrainEventStarted, Node: "node-1"}))
+	assert.ErrorIs(t, exporter.Export(context.Background(), DrainEvent{Type: DrainEventSucceeded, Node: "node-1"}), ErrExportQueueFull)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go exporter.Start(ctx)
+	assert.Eventually(t, func() bool { return recorder.count() == 1 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, exporter.Export(context.Background(), DrainEvent{Type: DrainEventSucceeded, Node: "node-1"}))
+	assert.Eventually(t, func() bool { return recorder.count() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, DrainEventSucceeded, recorder.events[1].Type)
+}
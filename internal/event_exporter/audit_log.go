@@ -0,0 +1,129 @@
+package event_exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// AuditLogActor is the actor recorded in the audit entries, all the actions are taken by draino
+	AuditLogActor = "draino"
+	// AuditLogBackups is the number of rotated files kept next to the audit log: <path>.1 is the most recent one
+	AuditLogBackups = 5
+)
+
+// AuditEntry is the line written in the audit log for each event
+type AuditEntry struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Action    DrainEventType `json:"action"`
+	Node      string         `json:"node"`
+	Group     string         `json:"group,omitempty"`
+	Actor     string         `json:"actor"`
+	Reason    string         `json:"reason,omitempty"`
+	// ChangeTicket is the change record the action is linked to
+	ChangeTicket string `json:"changeTicket,omitempty"`
+}
+
+// auditLogEventExporter appends the events as JSON lines to a file. The file is rotated once it would grow above maxSize bytes.
+type auditLogEventExporter struct {
+	sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+var _ EventExporter = &auditLogEventExporter{}
+
+// NewAuditLogEventExporter opens, or creates, the audit log. A zero maxSize disables the rotation.
+func NewAuditLogEventExporter(path string, maxSize int64) (EventExporter, error) {
+	a := &auditLogEventExporter{path: path, maxSize: maxSize}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *auditLogEventExporter) open() error {
+	file, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("cannot open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot stat audit log: %w", err)
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// rotate shifts the existing backups, moves the current file to <path>.1 and opens a new file
+func (a *auditLogEventExporter) rotate() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	for i := AuditLogBackups - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(a.path, a.path+".1"); err != nil {
+		return err
+	}
+	return a.open()
+}
+
+func (a *auditLogEventExporter) Export(_ context.Context, event DrainEvent) error {
+	line, err := json.Marshal(AuditEntry{
+		Timestamp: event.Timestamp,
+		Action:    event.Type,
+		Node:      event.Node,
+		Group:     event.GroupKey,
+		Actor:     AuditLogActor,
+		Reason:    event.Message,
+
+		ChangeTicket: event.ChangeTicket,
+	})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.Lock()
+	defer a.Unlock()
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(line)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			return fmt.Errorf("cannot rotate audit log: %w", err)
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+// multiEventExporter publishes the events to all the exporters
+type multiEventExporter []EventExporter
+
+var _ EventExporter = multiEventExporter{}
+
+// NewMultiEventExporter returns an exporter publishing to all the given exporters
+func NewMultiEventExporter(exporters ...EventExporter) EventExporter {
+	return multiEventExporter(exporters)
+}
+
+func (m multiEventExporter) Export(ctx context.Context, event DrainEvent) error {
+	var errs []error
+	for _, exporter := range m {
+		if err := exporter.Export(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,80 @@
+package event_exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAuditEntries(t *testing.T, path string) []AuditEntry {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.NoError(t, scanner.Err())
+	return entries
+}
+
+func TestAuditLogEventExporter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	exporter, err := NewAuditLogEventExporter(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Export(context.Background(), DrainEvent{Type: DrainEventScheduled, Node: "node-1", GroupKey: "group-1", Timestamp: now}))
+	require.NoError(t, exporter.Export(context.Background(), DrainEvent{Type: DrainEventFailed, Node: "node-1", GroupKey: "group-1", Message: "pdb", Timestamp: now, ChangeTicket: "JIRA-123"}))
+
+	entries := readAuditEntries(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, DrainEventScheduled, entries[0].Action)
+	assert.Equal(t, "node-1", entries[0].Node)
+	assert.Equal(t, "group-1", entries[0].Group)
+	assert.Equal(t, AuditLogActor, entries[0].Actor)
+	assert.True(t, now.Equal(entries[0].Timestamp))
+	assert.Equal(t, DrainEventFailed, entries[1].Action)
+	assert.Equal(t, "pdb", entries[1].Reason)
+	assert.Empty(t, entries[0].ChangeTicket)
+	assert.Equal(t, "JIRA-123", entries[1].ChangeTicket)
+
+	// the entries are appended to the existing file
+	exporter, err = NewAuditLogEventExporter(path, 0)
+	require.NoError(t, err)
+	require.NoError(t, exporter.Export(context.Background(), DrainEvent{Type: DrainEventUncordoned, Node: "node-1", Timestamp: now}))
+	assert.Len(t, readAuditEntries(t, path), 3)
+}
+
+func TestAuditLogEventExporter_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	event := DrainEvent{Type: DrainEventStarted, Node: "node-1", GroupKey: "group-1", Timestamp: time.Now()}
+	line, err := json.Marshal(AuditEntry{Timestamp: event.Timestamp, Action: event.Type, Node: event.Node, Group: event.GroupKey, Actor: AuditLogActor})
+	require.NoError(t, err)
+
+	// two entries per file
+	exporter, err := NewAuditLogEventExporter(path, int64(2*(len(line)+1)))
+	require.NoError(t, err)
+	for i := 0; i < 2*(AuditLogBackups+2); i++ {
+		require.NoError(t, exporter.Export(context.Background(), event))
+	}
+
+	assert.Len(t, readAuditEntries(t, path), 2)
+	for i := 1; i <= AuditLogBackups; i++ {
+		assert.Len(t, readAuditEntries(t, fmt.Sprintf("%s.%d", path, i)), 2)
+	}
+	_, err = os.Stat(fmt.Sprintf("%s.%d", path, AuditLogBackups+1))
+	assert.True(t, os.IsNotExist(err))
+}
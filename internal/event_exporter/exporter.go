@@ -0,0 +1,45 @@
+package event_exporter
+
+import (
+	"context"
+	"time"
+)
+
+// DrainEventType is the step of the drain lifecycle that is exported
+type DrainEventType string
+
+const (
+	DrainEventScheduled DrainEventType = "scheduled"
+	DrainEventStarted   DrainEventType = "started"
+	DrainEventSucceeded DrainEventType = "succeeded"
+	DrainEventFailed    DrainEventType = "failed"
+	// DrainEventUncordoned is published when the candidate status of the node is removed without drain
+	DrainEventUncordoned DrainEventType = "uncordoned"
+	// DrainEventReplacementRequested is published when draino requests the replacement of the node
+	DrainEventReplacementRequested DrainEventType = "replacement_requested"
+)
+
+// DrainEvent is the payload published for each transition of the drain lifecycle
+type DrainEvent struct {
+	Type      DrainEventType `json:"type"`
+	Node      string         `json:"node"`
+	GroupKey  string         `json:"groupKey"`
+	Message   string         `json:"message,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+	// ChangeTicket is the change record the drain of the node is linked to, empty if none
+	ChangeTicket string `json:"changeTicket,omitempty"`
+}
+
+// EventExporter publishes the drain lifecycle events to an external system
+type EventExporter interface {
+	Export(ctx context.Context, event DrainEvent) error
+}
+
+// NoopEventExporter is used when no exporter is configured
+type NoopEventExporter struct{}
+
+var _ EventExporter = &NoopEventExporter{}
+
+func (_ *NoopEventExporter) Export(context.Context, DrainEvent) error {
+	return nil
+}
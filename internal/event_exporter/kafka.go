@@ -0,0 +1,44 @@
+package event_exporter
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaProducer is the subset of the kafka writer used by the exporter
+type KafkaProducer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// kafkaEventExporter publishes the events as JSON messages. The node name is used as message key to keep the events of a node ordered.
+type kafkaEventExporter struct {
+	producer KafkaProducer
+}
+
+var _ EventExporter = &kafkaEventExporter{}
+
+func NewKafkaEventExporter(producer KafkaProducer) EventExporter {
+	return &kafkaEventExporter{producer: producer}
+}
+
+// NewKafkaWriter returns a producer publishing to the given topic
+func NewKafkaWriter(brokers []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		BatchTimeout: 10 * time.Millisecond, // the events are rare, we don't want to wait for a batch to be filled
+	}
+}
+
+func (k *kafkaEventExporter) Export(ctx context.Context, event DrainEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return k.producer.WriteMessages(ctx, kafka.Message{Key: []byte(event.Node), Value: value, Time: event.Timestamp})
+}
@@ -0,0 +1,44 @@
+package event_exporter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProducer struct {
+	messages []kafka.Message
+}
+
+func (f *fakeProducer) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+func TestKafkaEventExporter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	lifecycle := []DrainEventType{DrainEventScheduled, DrainEventStarted, DrainEventSucceeded, DrainEventFailed}
+
+	producer := &fakeProducer{}
+	exporter := NewKafkaEventExporter(producer)
+	for _, eventType := range lifecycle {
+		err := exporter.Export(context.Background(), DrainEvent{Type: eventType, Node: "node-1", GroupKey: "group-1", Timestamp: now})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, producer.messages, len(lifecycle))
+	for i, msg := range producer.messages {
+		assert.Equal(t, "node-1", string(msg.Key))
+		var event DrainEvent
+		require.NoError(t, json.Unmarshal(msg.Value, &event))
+		assert.Equal(t, lifecycle[i], event.Type)
+		assert.Equal(t, "node-1", event.Node)
+		assert.Equal(t, "group-1", event.GroupKey)
+		assert.True(t, now.Equal(event.Timestamp))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/planetlabs/draino/internal/kubernetes"
 	v1 "k8s.io/api/core/v1"
 	cachek "k8s.io/client-go/tools/cache"
 	cachecr "sigs.k8s.io/controller-runtime/pkg/cache"
@@ -15,16 +16,30 @@ const (
 
 // InitSchedulingGroupIndexer prepare an index with all the nodes for a given scheduling group
 func InitSchedulingGroupIndexer(cache cachecr.Cache, keyGetter GroupKeyGetter) error {
+	return InitNamedSchedulingGroupIndexer(cache, SchedulingGroupIdx, keyGetter, nil)
+}
+
+// InitNamedSchedulingGroupIndexer prepare an index under the given name, so that several configurations
+// with different group keys can index the same nodes. If a node filter is given, only the matching nodes are indexed.
+func InitNamedSchedulingGroupIndexer(cache cachecr.Cache, indexName string, keyGetter GroupKeyGetter, nodeFilter kubernetes.NodeLabelFilterFunc) error {
 	informer, err := cache.GetInformer(context.Background(), &v1.Node{})
 	if err != nil {
 		return err
 	}
 	return informer.AddIndexers(map[string]cachek.IndexFunc{
-		SchedulingGroupIdx: func(obj interface{}) ([]string, error) {
+		indexName: func(obj interface{}) ([]string, error) {
 			if n, ok := obj.(*v1.Node); ok {
+				if nodeFilter != nil && !nodeFilter(n) {
+					return nil, nil
+				}
 				return []string{string(keyGetter.GetGroupKey(n))}, nil
 			}
-			return nil, fmt.Errorf("Expecting node object in SchedulingGroupIdx indexer")
+			return nil, fmt.Errorf("Expecting node object in %s indexer", indexName)
 		},
 	})
 }
+
+// ConfigurationSchedulingGroupIdx returns the name of the scheduling group index of an additional configuration
+func ConfigurationSchedulingGroupIdx(configName string) string {
+	return SchedulingGroupIdx + ":" + configName
+}
@@ -15,7 +15,7 @@ import (
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 )
 
-const DrainGroupAnnotationKey = "node-lifecycle.datadoghq.com/drain-group"
+const DrainGroupAnnotationKey = kubernetes.DrainGroupAnnotationKey
 
 const (
 	DrainGroupAnnotation         = "draino/drain-group"          // this one adds subgroup to the default group (subgroup creation)
@@ -40,12 +40,13 @@ type GroupKeyFromMetadata struct {
 	store                      kubernetes.RuntimeObjectStore
 	eventRecorder              kubernetes.EventRecorder
 	logger                     logr.Logger
+	nodeGroupResolver          NodeGroupResolver
 }
 
 var _ GroupKeyGetter = &GroupKeyFromMetadata{}
 
-func NewGroupKeyFromNodeMetadata(client client.Client, logger logr.Logger, eventRecorder kubernetes.EventRecorder, podIndexer index.PodIndexer, store kubernetes.RuntimeObjectStore, labelsKeys, annotationKeys []string, groupOverrideAnnotationKey string) GroupKeyGetter {
-	return &GroupKeyFromMetadata{
+func NewGroupKeyFromNodeMetadata(client client.Client, logger logr.Logger, eventRecorder kubernetes.EventRecorder, podIndexer index.PodIndexer, store kubernetes.RuntimeObjectStore, labelsKeys, annotationKeys []string, groupOverrideAnnotationKey string, opts ...GroupKeyOption) GroupKeyGetter {
+	g := &GroupKeyFromMetadata{
 		kclient:                    client,
 		labelsKeys:                 labelsKeys,
 		annotationKeys:             annotationKeys,
@@ -55,6 +56,10 @@ func NewGroupKeyFromNodeMetadata(client client.Client, logger logr.Logger, event
 		eventRecorder:              eventRecorder,
 		logger:                     logger.WithName("GroupKeyGetter"),
 	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
 }
 
 func getValueOrEmpty(m map[string]string, keys []string) (values []string) {
@@ -172,6 +177,13 @@ func (g *GroupKeyFromMetadata) GetGroupKey(node *v1.Node) GroupKey {
 		return override
 	}
 
+	// the resolver replaces the labels and annotations when it knows the group of the node
+	if g.nodeGroupResolver != nil {
+		if resolved, ok := g.nodeGroupResolver.ResolveNodeGroup(node); ok {
+			return GroupKey(strings.Join(resolved, GroupKeySeparator))
+		}
+	}
+
 	// let's build the groups values from labels and annotations
 	values = append(getValueOrEmpty(node.Labels, g.labelsKeys), getValueOrEmpty(node.Annotations, g.annotationKeys)...)
 
@@ -0,0 +1,114 @@
+package groups
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const nodeGroupResolutionTimeout = 10 * time.Second
+
+// NodeGroupResolver returns the values used as group key for the node. The boolean is false if the node is not resolved,
+// in that case the group key is built from the labels and annotations.
+type NodeGroupResolver interface {
+	ResolveNodeGroup(node *v1.Node) ([]string, bool)
+}
+
+// GroupKeyOption configures optional behaviors of the GroupKeyFromMetadata
+type GroupKeyOption func(g *GroupKeyFromMetadata)
+
+// WithNodeGroupResolver replaces the label and annotation values of the group key with the values returned by the resolver.
+// The group overrides still take precedence.
+func WithNodeGroupResolver(resolver NodeGroupResolver) GroupKeyOption {
+	return func(g *GroupKeyFromMetadata) {
+		g.nodeGroupResolver = resolver
+	}
+}
+
+// ParseNodeGroupResource parses a resource in the format "resource.version.group", for example "nodegroups.v1alpha1.datadoghq.com"
+func ParseNodeGroupResource(value string) (schema.GroupVersionResource, error) {
+	gvr, _ := schema.ParseResourceArg(value)
+	if gvr == nil || gvr.Version == "" || gvr.Group == "" {
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q: expecting resource.version.group", value)
+	}
+	return *gvr, nil
+}
+
+type nodeGroupRef struct {
+	namespace, name string
+}
+
+// NodeGroupFromOwnerCRD resolves the group of a node by following its owner reference to an object of the given resource.
+// The group key is "<namespace>#<name>" of the owner. The owners are cached by UID.
+type NodeGroupFromOwnerCRD struct {
+	kclient client.Client
+	gvr     schema.GroupVersionResource
+	logger  logr.Logger
+
+	sync.Mutex
+	owners map[types.UID]nodeGroupRef
+}
+
+var _ NodeGroupResolver = &NodeGroupFromOwnerCRD{}
+
+func NewNodeGroupFromOwnerCRD(kclient client.Client, logger logr.Logger, gvr schema.GroupVersionResource) *NodeGroupFromOwnerCRD {
+	return &NodeGroupFromOwnerCRD{
+		kclient: kclient,
+		gvr:     gvr,
+		logger:  logger.WithName("NodeGroupFromOwnerCRD"),
+		owners:  map[types.UID]nodeGroupRef{},
+	}
+}
+
+func (r *NodeGroupFromOwnerCRD) ResolveNodeGroup(node *v1.Node) ([]string, bool) {
+	for _, ref := range node.OwnerReferences {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil || gv != r.gvr.GroupVersion() {
+			continue
+		}
+		owner, err := r.getOwner(gv.WithKind(ref.Kind), ref.UID, ref.Name)
+		if err != nil {
+			r.logger.Error(err, "failed to resolve the node group", "node", node.Name, "owner", ref.Name)
+			return nil, false
+		}
+		return []string{owner.namespace, owner.name}, true
+	}
+	return nil, false
+}
+
+// getOwner lists the objects of the owner kind and returns the one with the given UID. The owner reference of a cluster scoped node
+// has no namespace, so it cannot be read directly.
+func (r *NodeGroupFromOwnerCRD) getOwner(gvk schema.GroupVersionKind, uid types.UID, name string) (nodeGroupRef, error) {
+	r.Lock()
+	owner, ok := r.owners[uid]
+	r.Unlock()
+	if ok {
+		return owner, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), nodeGroupResolutionTimeout)
+	defer cancel()
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.kclient.List(ctx, list); err != nil {
+		return nodeGroupRef{}, err
+	}
+	for _, item := range list.Items {
+		if item.GetUID() == uid && item.GetName() == name {
+			owner = nodeGroupRef{namespace: item.GetNamespace(), name: item.GetName()}
+			r.Lock()
+			r.owners[uid] = owner
+			r.Unlock()
+			return owner, nil
+		}
+	}
+	return nodeGroupRef{}, fmt.Errorf("%s %s not found", gvk.Kind, name)
+}
@@ -0,0 +1,88 @@
+package groups
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestParseNodeGroupResource(t *testing.T) {
+	gvr, err := ParseNodeGroupResource("nodegroups.v1alpha1.datadoghq.com")
+	assert.NoError(t, err)
+	assert.Equal(t, schema.GroupVersionResource{Group: "datadoghq.com", Version: "v1alpha1", Resource: "nodegroups"}, gvr)
+
+	_, err = ParseNodeGroupResource("nodegroups")
+	assert.Error(t, err)
+}
+
+func TestGroupKeyFromMetadata_GetGroupKeyFromCRD(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "datadoghq.com", Version: "v1alpha1", Resource: "nodegroups"}
+	newNodeGroup := func(namespace, name, uid string) *unstructured.Unstructured {
+		ng := &unstructured.Unstructured{}
+		ng.SetGroupVersionKind(gvr.GroupVersion().WithKind("NodeGroup"))
+		ng.SetNamespace(namespace)
+		ng.SetName(name)
+		ng.SetUID(types.UID(uid))
+		return ng
+	}
+	newNode := func(owners ...meta.OwnerReference) *v1.Node {
+		return &v1.Node{ObjectMeta: meta.ObjectMeta{Name: "node", Labels: map[string]string{"zone": "a"}, OwnerReferences: owners}}
+	}
+	ownedBy := func(apiVersion, name, uid string) meta.OwnerReference {
+		return meta.OwnerReference{APIVersion: apiVersion, Kind: "NodeGroup", Name: name, UID: types.UID(uid)}
+	}
+
+	tests := []struct {
+		name string
+		node *v1.Node
+		want GroupKey
+	}{
+		{
+			name: "node owned by a nodegroup",
+			node: newNode(ownedBy("datadoghq.com/v1alpha1", "ng-1", "uid-1")),
+			want: "team-a#ng-1",
+		},
+		{
+			name: "same name in another namespace",
+			node: newNode(ownedBy("datadoghq.com/v1alpha1", "ng-1", "uid-2")),
+			want: "team-b#ng-1",
+		},
+		{
+			name: "node without owner uses the labels",
+			node: newNode(),
+			want: "a",
+		},
+		{
+			name: "owner of another resource uses the labels",
+			node: newNode(ownedBy("other.io/v1", "ng-1", "uid-1")),
+			want: "a",
+		},
+		{
+			name: "missing nodegroup uses the labels",
+			node: newNode(ownedBy("datadoghq.com/v1alpha1", "ng-2", "uid-3")),
+			want: "a",
+		},
+	}
+
+	kclient := fake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{
+		newNodeGroup("team-a", "ng-1", "uid-1"),
+		newNodeGroup("team-b", "ng-1", "uid-2"),
+	}...).Build()
+	resolver := NewNodeGroupFromOwnerCRD(kclient, logr.Discard(), gvr)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGroupKeyFromNodeMetadata(nil, logr.Discard(), kubernetes.NoopEventRecorder{}, nil, nil, []string{"zone"}, nil, "", WithNodeGroupResolver(resolver))
+			assert.Equal(t, tt.want, g.GetGroupKey(tt.node))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package groups
+
+import (
+	"fmt"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+// GroupScheduler tells the group registry in which order the groups get new candidates
+type GroupScheduler interface {
+	// IsSchedulable returns false, with the reason, if the group must wait before getting new candidates
+	IsSchedulable(key GroupKey) (bool, string)
+}
+
+// groupPriorityScheduler holds back the groups while a group with a higher priority has active drains.
+// A drain is active while the node is candidate or draining. The groups without priority have the priority 0.
+type groupPriorityScheduler struct {
+	store      kubernetes.NodeStore
+	keyGetter  GroupKeyGetter
+	priorities map[string]int
+}
+
+var _ GroupScheduler = &groupPriorityScheduler{}
+
+func NewGroupPriorityScheduler(store kubernetes.NodeStore, keyGetter GroupKeyGetter, priorities map[string]int) GroupScheduler {
+	return &groupPriorityScheduler{store: store, keyGetter: keyGetter, priorities: priorities}
+}
+
+func (s *groupPriorityScheduler) IsSchedulable(key GroupKey) (bool, string) {
+	priority := s.priorities[string(key)]
+	for _, n := range s.store.ListNodes() {
+		taint, ok := k8sclient.GetNLATaint(n)
+		if !ok || (taint.Value != k8sclient.TaintDrainCandidate && taint.Value != k8sclient.TaintDraining) {
+			continue
+		}
+		if group := s.keyGetter.GetGroupKey(n); s.priorities[string(group)] > priority {
+			return false, fmt.Sprintf("group %s has a higher priority and active drains", group)
+		}
+	}
+	return true, ""
+}
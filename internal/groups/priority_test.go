@@ -0,0 +1,83 @@
+package groups
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func TestGroupPriorityScheduler(t *testing.T) {
+	newNode := func(group string, i int, taintValue k8sclient.DrainTaintValue) *corev1.Node {
+		node := &corev1.Node{
+			ObjectMeta: v1.ObjectMeta{Name: fmt.Sprintf("%s-%d", group, i), Labels: map[string]string{"group": group}},
+		}
+		if taintValue != "" {
+			node.Spec.Taints = []corev1.Taint{*k8sclient.CreateNLATaint(taintValue, time.Now())}
+		}
+		return node
+	}
+	keyGetter := NewGroupKeyFromNodeMetadata(nil, logr.Discard(), kubernetes.NoopEventRecorder{}, nil, nil, []string{"group"}, nil, "")
+	priorities := map[string]int{"high": 2, "medium": 1}
+
+	tests := []struct {
+		name            string
+		nodes           []*corev1.Node
+		wantSchedulable []GroupKey
+	}{
+		{
+			name:            "no active drain",
+			nodes:           []*corev1.Node{newNode("high", 0, ""), newNode("medium", 0, ""), newNode("low", 0, "")},
+			wantSchedulable: []GroupKey{"high", "medium", "low"},
+		},
+		{
+			name:            "high priority group draining gates the lower priority groups",
+			nodes:           []*corev1.Node{newNode("high", 0, k8sclient.TaintDraining), newNode("high", 1, ""), newNode("medium", 0, ""), newNode("low", 0, "")},
+			wantSchedulable: []GroupKey{"high"},
+		},
+		{
+			name:            "medium priority group with a candidate only gates the low priority group",
+			nodes:           []*corev1.Node{newNode("high", 0, ""), newNode("medium", 0, k8sclient.TaintDrainCandidate), newNode("low", 0, "")},
+			wantSchedulable: []GroupKey{"high", "medium"},
+		},
+		{
+			name:            "drained nodes are not active drains",
+			nodes:           []*corev1.Node{newNode("high", 0, k8sclient.TaintDrained), newNode("low", 0, "")},
+			wantSchedulable: []GroupKey{"high", "low"},
+		},
+		{
+			name:            "low priority group drains do not gate the higher priority groups",
+			nodes:           []*corev1.Node{newNode("high", 0, ""), newNode("low", 0, k8sclient.TaintDraining)},
+			wantSchedulable: []GroupKey{"high", "low"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []runtime.Object
+			for _, n := range tt.nodes {
+				objects = append(objects, n)
+			}
+			store, closingFunc := kubernetes.RunStoreForTest(context.Background(), fake.NewSimpleClientset(objects...))
+			defer closingFunc()
+
+			scheduler := NewGroupPriorityScheduler(store.Nodes(), keyGetter, priorities)
+			var schedulable []GroupKey
+			for _, key := range []GroupKey{"high", "medium", "low"} {
+				if ok, _ := scheduler.IsSchedulable(key); ok {
+					schedulable = append(schedulable, key)
+				}
+			}
+			assert.Equal(t, tt.wantSchedulable, schedulable)
+		})
+	}
+}
@@ -41,6 +41,7 @@ type GroupRegistry struct {
 	groupDrainCandidateRunner *GroupsRunner
 
 	nodeFilteringFunc kubernetes.NodeLabelFilterFunc
+	indexName         string
 
 	hasSyncedFunc func() bool
 }
@@ -95,10 +96,23 @@ func NewGroupRegistry(
 		groupDrainCandidateRunner: NewGroupsRunner(ctx, drainCandidateFactory, logger, DrainCandidateRunnerName, maxRandomRunnerStartDelay),
 		eventRecorder:             eventRecorder,
 		nodeFilteringFunc:         nodeFilteringFunc,
+		indexName:                 SchedulingGroupIdx,
 		hasSyncedFunc:             hasSyncedFunc,
 	}
 }
 
+// WithIndexName sets the name of the scheduling group index, it must be unique per configuration running in the process
+func (r *GroupRegistry) WithIndexName(indexName string) *GroupRegistry {
+	r.indexName = indexName
+	return r
+}
+
+// WithScheduler sets the scheduler ordering the groups that get new candidates
+func (r *GroupRegistry) WithScheduler(scheduler GroupScheduler) *GroupRegistry {
+	r.groupDrainCandidateRunner.scheduler = scheduler
+	return r
+}
+
 // Reconcile register the node in the reverse index per ProviderIP
 func (r *GroupRegistry) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	if !r.hasSyncedFunc() {
@@ -147,7 +161,12 @@ func (r *GroupRegistry) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 // SetupWithManager setups the controller with goroutine and predicates
 func (r *GroupRegistry) SetupWithManager(mgr ctrl.Manager) error {
 
-	InitSchedulingGroupIndexer(mgr.GetCache(), r.keyGetter)
+	// the default index contains all the nodes, the index of an additional configuration only contains the nodes in its scope
+	var indexNodeFilter kubernetes.NodeLabelFilterFunc
+	if r.indexName != SchedulingGroupIdx {
+		indexNodeFilter = r.nodeFilteringFunc
+	}
+	InitNamedSchedulingGroupIndexer(mgr.GetCache(), r.indexName, r.keyGetter, indexNodeFilter)
 
 	return ctrl.NewControllerManagedBy(mgr).WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
 		For(&v1.Node{}).
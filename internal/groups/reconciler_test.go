@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-logr/zapr"
 	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 	"go.uber.org/zap"
 
@@ -46,6 +47,17 @@ func (t *TestRunnerFactory) Run(r *RunnerInfo) error {
 	return nil
 }
 
+// runs returns a copy of the run counts, safe to compare while the runners are starting
+func (t *TestRunnerFactory) runs() map[GroupKey]int {
+	t.RLock()
+	defer t.RUnlock()
+	runs := map[GroupKey]int{}
+	for k, v := range t.runCount {
+		runs[k] = v
+	}
+	return runs
+}
+
 func (t *TestRunnerFactory) BuildRunner() Runner {
 	return t
 }
@@ -122,17 +134,90 @@ func TestNewGroupRegistry(t *testing.T) {
 				gr.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: n.Name}})
 			}
 
-			// wait for the runs
-			time.Sleep(time.Second)
-
 			testFactory := tt.drainCandidateFactory.(*TestRunnerFactory)
-			assert.Equal(t, tt.runCount, testFactory.runCount)
+			assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(tt.runCount, testFactory.runs()) }, 5*time.Second, 10*time.Millisecond)
 			assert.Equal(t, len(tt.runCount), gr.groupDrainCandidateRunner.countRunners())
 			testFactory.Stop()
 
 			// wait for the cleanup
-			time.Sleep(100 * time.Millisecond)
-			assert.Equal(t, 0, gr.groupDrainCandidateRunner.countRunners())
+			assert.Eventually(t, func() bool { return gr.groupDrainCandidateRunner.countRunners() == 0 }, 5*time.Second, 10*time.Millisecond)
 		})
 	}
 }
+
+func TestGroupRegistry_MultipleConfigurations(t *testing.T) {
+	RegisterMetrics(prometheus.NewRegistry())
+	testLogger := zapr.NewLogger(zap.NewNop())
+	newNode := func(name, pool, zone string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: meta.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: meta.Time{Time: time.Now().Add(-time.Hour)},
+				Labels:            map[string]string{"pool": pool, "zone": zone},
+			},
+		}
+	}
+	nodes := []runtime.Object{
+		newNode("node-a-z1", "a", "z1"),
+		newNode("node-a-z2", "a", "z2"),
+		newNode("node-b-z1", "b", "z1"),
+	}
+	poolFilter := func(pool string) kubernetes.NodeLabelFilterFunc {
+		return func(o interface{}) bool { return o.(*corev1.Node).Labels["pool"] == pool }
+	}
+
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{Objects: nodes})
+	assert.NoError(t, err, "cannot initialize client wrapper")
+	indexer, err := index.New(context.Background(), wrapper.GetManagerClient(), wrapper.GetCache(), testLogger)
+	assert.NoError(t, err, "cannot initialize indexer")
+
+	type configuration struct {
+		indexName             string
+		drainCandidateFactory *TestRunnerFactory
+		registry              *GroupRegistry
+	}
+	configurations := map[string]*configuration{}
+	for _, pool := range []string{"a", "b"} {
+		keyGetter := NewGroupKeyFromNodeMetadata(wrapper.GetManagerClient(), testLogger, kubernetes.NoopEventRecorder{}, nil, nil, []string{"zone"}, nil, "")
+		c := &configuration{indexName: ConfigurationSchedulingGroupIdx(pool), drainCandidateFactory: NewTestRunnerFactory()}
+		c.registry = NewGroupRegistry(context.Background(), wrapper.GetManagerClient(), testLogger, nil, keyGetter, NewTestRunnerFactory(), c.drainCandidateFactory, poolFilter(pool), func() bool { return true }, 0).
+			WithIndexName(c.indexName)
+		assert.NoError(t, InitNamedSchedulingGroupIndexer(wrapper.GetCache(), c.indexName, keyGetter, poolFilter(pool)))
+		configurations[pool] = c
+	}
+
+	ch := make(chan struct{})
+	defer close(ch)
+	wrapper.Start(ch)
+
+	// both configurations see all the nodes
+	for _, c := range configurations {
+		for _, o := range nodes {
+			n := o.(*corev1.Node)
+			c.registry.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Name: n.Name}})
+		}
+	}
+
+	expected := map[string]struct {
+		runCount map[GroupKey]int
+		z1Nodes  []string
+	}{
+		"a": {runCount: map[GroupKey]int{"z1": 1, "z2": 1}, z1Nodes: []string{"node-a-z1"}},
+		"b": {runCount: map[GroupKey]int{"z1": 1}, z1Nodes: []string{"node-b-z1"}},
+	}
+	for pool, c := range configurations {
+		assert.Eventually(t, func() bool { return assert.ObjectsAreEqual(expected[pool].runCount, c.drainCandidateFactory.runs()) }, 5*time.Second, 10*time.Millisecond,
+			"configuration %s", pool)
+
+		// the same group key is resolved to the nodes of the configuration only
+		z1Nodes, err := index.GetFromIndex[corev1.Node](context.Background(), indexer, c.indexName, "z1")
+		assert.NoError(t, err)
+		var names []string
+		for _, n := range z1Nodes {
+			names = append(names, n.Name)
+		}
+		assert.Equal(t, expected[pool].z1Nodes, names, "configuration %s", pool)
+
+		c.drainCandidateFactory.Stop()
+	}
+}
@@ -16,6 +16,8 @@ type RunnerInfo struct {
 	Context context.Context
 	Key     GroupKey
 	Data    *utils.DataMap
+	// Scheduler tells if the group can get new candidates, nil if the groups are independent
+	Scheduler GroupScheduler
 }
 
 // Runner is in charge of a set of nodes for a given group
@@ -35,6 +37,7 @@ type GroupsRunner struct {
 	factory             RunnerFactory
 	logger              logr.Logger
 	maxRandomStartDelay time.Duration
+	scheduler           GroupScheduler
 }
 
 func NewGroupsRunner(ctx context.Context, factory RunnerFactory, logger logr.Logger, groupName string, maxRandomStartDelay time.Duration) *GroupsRunner {
@@ -68,9 +71,10 @@ func (g *GroupsRunner) RunForGroup(key GroupKey) {
 func (g *GroupsRunner) runForGroup(key GroupKey) *RunnerInfo {
 	ctx, cancel := context.WithCancel(g.parentContext)
 	r := &RunnerInfo{
-		Key:     key,
-		Context: ctx,
-		Data:    utils.NewDataMap(),
+		Key:       key,
+		Context:   ctx,
+		Data:      utils.NewDataMap(),
+		Scheduler: g.scheduler,
 	}
 	go func(runInfo *RunnerInfo, cancel context.CancelFunc) {
 		defer cancel()
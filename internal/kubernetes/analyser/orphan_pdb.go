@@ -0,0 +1,68 @@
+package analyser
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+)
+
+var (
+	MeasureOrphanPDB = stats.Int64("draino/orphan_pdb_total", "Number of PDBs whose selector does not match any pod.", stats.UnitDimensionless)
+)
+
+// OrphanPDBChecker periodically counts the PDBs that do not protect any pod.
+// Such PDBs are usually leftovers of deleted applications, they are reported so that the operators can clean them up.
+type OrphanPDBChecker struct {
+	pdbIndexer index.PDBIndexer
+	logger     logr.Logger
+	period     time.Duration
+}
+
+var _ manager.Runnable = &OrphanPDBChecker{}
+
+func NewOrphanPDBChecker(pdbIndexer index.PDBIndexer, logger logr.Logger, period time.Duration) *OrphanPDBChecker {
+	return &OrphanPDBChecker{
+		pdbIndexer: pdbIndexer,
+		logger:     logger.WithName("OrphanPDBChecker"),
+		period:     period,
+	}
+}
+
+func (c *OrphanPDBChecker) Start(ctx context.Context) error {
+	orphanPDBView := &view.View{
+		Name:        "orphan_pdb_total",
+		Measure:     MeasureOrphanPDB,
+		Description: "Number of PDBs whose selector does not match any pod",
+		Aggregation: view.LastValue(),
+	}
+	if err := view.Register(orphanPDBView); err != nil {
+		return err
+	}
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		count, err := c.check(ctx)
+		if err != nil {
+			c.logger.Error(err, "Failed to look for orphan PDBs")
+			return
+		}
+		stats.Record(ctx, MeasureOrphanPDB.M(int64(count)))
+	}, c.period)
+	return nil
+}
+
+func (c *OrphanPDBChecker) check(ctx context.Context) (int, error) {
+	orphans, err := c.pdbIndexer.GetOrphanPDBs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, pdb := range orphans {
+		c.logger.Info("PDB selector does not match any pod", "namespace", pdb.Namespace, "name", pdb.Name)
+	}
+	return len(orphans), nil
+}
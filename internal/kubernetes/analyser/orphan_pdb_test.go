@@ -0,0 +1,52 @@
+package analyser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/zapr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func TestOrphanPDBChecker(t *testing.T) {
+	labels := map[string]string{"app": "foo"}
+	objects := []runtime.Object{
+		createNode("my-node"),
+		createPod("foo", "default", "my-node", true, labels),
+		createPDB("matching-pdb", "default", labels),
+		createPDB("orphan-pdb", "default", map[string]string{"app": "deleted"}),
+		createPDB("other-namespace-pdb", "kube-system", labels),
+	}
+
+	testLogger := zapr.NewLogger(zap.NewNop())
+	wrapper, err := k8sclient.NewFakeClient(k8sclient.FakeConf{Objects: objects})
+	assert.NoError(t, err)
+
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	indexer, err := index.New(ctx, wrapper.GetManagerClient(), wrapper.GetCache(), testLogger)
+	assert.NoError(t, err)
+
+	ch := make(chan struct{})
+	defer close(ch)
+	wrapper.Start(ch)
+
+	checker := NewOrphanPDBChecker(indexer, testLogger, time.Minute)
+	count, err := checker.check(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	orphans, err := indexer.GetOrphanPDBs(ctx)
+	assert.NoError(t, err)
+	var names []string
+	for _, pdb := range orphans {
+		names = append(names, pdb.Namespace+"/"+pdb.Name)
+	}
+	assert.ElementsMatch(t, []string{"default/orphan-pdb", "kube-system/other-namespace-pdb"}, names)
+}
@@ -0,0 +1,117 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	// DefaultAPIServerErrorRateWindow is the period over which the error rate of the requests to the API server is computed
+	DefaultAPIServerErrorRateWindow = 5 * time.Minute
+	// DefaultAPIServerErrorRatePeriod is the period of the check of the API server error rate by the global blocker
+	DefaultAPIServerErrorRatePeriod = 30 * time.Second
+
+	// apiServerErrorRateMinRequests is the number of requests under which the error rate is not significant
+	apiServerErrorRateMinRequests = 20
+)
+
+type requestsCount struct {
+	total, errors int
+}
+
+// APIServerErrorRate tracks the fraction of the requests sent by draino to the API server that failed over a sliding window.
+// A request fails if the API server cannot be reached, is overloaded (429) or returns a server error (5xx).
+type APIServerErrorRate struct {
+	sync.Mutex
+	clock  clock.Clock
+	window time.Duration
+	// counts of the requests per second
+	counts map[int64]*requestsCount
+}
+
+func NewAPIServerErrorRate(clock clock.Clock, window time.Duration) *APIServerErrorRate {
+	return &APIServerErrorRate{
+		clock:  clock,
+		window: window,
+		counts: map[int64]*requestsCount{},
+	}
+}
+
+// WrapTransport is a transport.WrapperFunc that records the result of the requests, to be set on the rest.Config of the clientset
+func (r *APIServerErrorRate) WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &errorRateRoundTripper{rt: rt, errorRate: r}
+}
+
+// Record counts a request in the current second
+func (r *APIServerErrorRate) Record(failed bool) {
+	r.Lock()
+	defer r.Unlock()
+	now := r.clock.Now().Unix()
+	c, ok := r.counts[now]
+	if !ok {
+		c = &requestsCount{}
+		r.counts[now] = c
+		r.prune(now)
+	}
+	c.total++
+	if failed {
+		c.errors++
+	}
+}
+
+// Rate returns the error rate over the window and the number of requests it is computed from
+func (r *APIServerErrorRate) Rate() (rate float64, requests int) {
+	r.Lock()
+	defer r.Unlock()
+	r.prune(r.clock.Now().Unix())
+	failed := 0
+	for _, c := range r.counts {
+		requests += c.total
+		failed += c.errors
+	}
+	if requests == 0 {
+		return 0, 0
+	}
+	return float64(failed) / float64(requests), requests
+}
+
+func (r *APIServerErrorRate) prune(now int64) {
+	oldest := now - int64(r.window/time.Second)
+	for second := range r.counts {
+		if second <= oldest {
+			delete(r.counts, second)
+		}
+	}
+}
+
+// BlockStateFunc returns the check blocking the drains while the error rate exceeds the threshold. The drains resume once the
+// failed requests left the window. The rate is ignored until enough requests were sent to be significant.
+func (r *APIServerErrorRate) BlockStateFunc(threshold float64) ComputeBlockStateFunction {
+	return func() bool {
+		rate, requests := r.Rate()
+		return requests >= apiServerErrorRateMinRequests && rate > threshold
+	}
+}
+
+type errorRateRoundTripper struct {
+	rt        http.RoundTripper
+	errorRate *APIServerErrorRate
+}
+
+func (t *errorRateRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		// the requests cancelled by draino itself, the watches being closed for example, say nothing about the API server health
+		if !errors.Is(err, context.Canceled) {
+			t.errorRate.Record(true)
+		}
+		return resp, err
+	}
+	t.errorRate.Record(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestAPIServerErrorRate(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	errorRate := NewAPIServerErrorRate(fakeClock, DefaultAPIServerErrorRateWindow)
+
+	var status int
+	var errTransport error
+	rt := errorRate.WrapTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		if errTransport != nil {
+			return nil, errTransport
+		}
+		return &http.Response{StatusCode: status}, nil
+	}))
+	send := func(count int) {
+		for i := 0; i < count; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "https://apiserver/api/v1/nodes", nil)
+			_, _ = rt.RoundTrip(req)
+			fakeClock.Step(time.Second)
+		}
+	}
+
+	g := NewGlobalBlocker(logr.Discard())
+	assert.NoError(t, g.AddBlocker("APIServerHealth:0.5", errorRate.BlockStateFunc(0.5), DefaultAPIServerErrorRatePeriod))
+	check := func() bool {
+		g.check(g.blockers[0])
+		blocked, _ := g.IsBlocked()
+		return blocked
+	}
+
+	status = http.StatusOK
+	send(30)
+	assert.False(t, check(), "healthy API server")
+
+	status = http.StatusInternalServerError
+	send(10)
+	assert.False(t, check(), "a quarter of the requests failed")
+
+	status = http.StatusTooManyRequests
+	send(30)
+	rate, _ := errorRate.Rate()
+	assert.Greater(t, rate, 0.5)
+	assert.True(t, check(), "overloaded API server")
+
+	errTransport = context.Canceled
+	send(100)
+	assert.True(t, check(), "the cancelled requests are ignored")
+
+	errTransport = nil
+	status = http.StatusOK
+	send(5)
+	assert.True(t, check(), "the failed requests are still in the window")
+
+	fakeClock.Step(DefaultAPIServerErrorRateWindow)
+	send(30)
+	assert.False(t, check(), "recovered once the failed requests left the window")
+
+	errTransport = errors.New("connection refused")
+	send(40)
+	assert.True(t, check(), "unreachable API server")
+}
+
+func TestAPIServerErrorRate_notEnoughRequests(t *testing.T) {
+	errorRate := NewAPIServerErrorRate(testingclock.NewFakeClock(time.Now()), DefaultAPIServerErrorRateWindow)
+	for i := 0; i < apiServerErrorRateMinRequests-1; i++ {
+		errorRate.Record(true)
+	}
+	assert.False(t, errorRate.BlockStateFunc(0.5)(), "too few requests to be significant")
+	errorRate.Record(true)
+	assert.True(t, errorRate.BlockStateFunc(0.5)())
+}
@@ -3,12 +3,14 @@ package kubernetes
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/planetlabs/draino/internal/kubernetes/index"
 
@@ -51,16 +53,56 @@ type GlobalBlocksRunner struct {
 	blockers []*blocker
 	started  bool
 	logger   logr.Logger
+
+	// the transitions of the blockers are recorded as events on eventObject, if set
+	eventRecorder record.EventRecorder
+	eventObject   *corev1.ObjectReference
+}
+
+// GlobalBlockerOption configures a GlobalBlocksRunner.
+type GlobalBlockerOption func(g *GlobalBlocksRunner)
+
+// WithTransitionEventRecorder records an event on the given object each time a blocker starts or stops blocking the drains
+func WithTransitionEventRecorder(eventRecorder record.EventRecorder, object *corev1.ObjectReference) GlobalBlockerOption {
+	return func(g *GlobalBlocksRunner) {
+		g.eventRecorder = eventRecorder
+		g.eventObject = object
+	}
 }
 
-func NewGlobalBlocker(logger logr.Logger) *GlobalBlocksRunner {
-	return &GlobalBlocksRunner{
+func NewGlobalBlocker(logger logr.Logger, options ...GlobalBlockerOption) *GlobalBlocksRunner {
+	g := &GlobalBlocksRunner{
 		logger: logger,
 	}
+	for _, o := range options {
+		o(g)
+	}
+	return g
 }
 
 var (
-	MeasureBlocker = stats.Int64("draino/global_block", "GlobalBlock indicator.", stats.UnitDimensionless)
+	MeasureBlocker       = stats.Int64("draino/global_block", "GlobalBlock indicator.", stats.UnitDimensionless)
+	MeasureBlockerActive = stats.Int64("draino/global_blocker_active", "1 while the global blocker is blocking the drains.", stats.UnitDimensionless)
+
+	tagBlock, _   = tag.NewKey("block")
+	tagBlocker, _ = tag.NewKey("blocker")
+
+	blockerViews = []*view.View{
+		{
+			Name:        "global_block",
+			Measure:     MeasureBlocker,
+			Description: "State of global blocks",
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{tagBlock},
+		},
+		{
+			Name:        "global_blocker_active",
+			Measure:     MeasureBlockerActive,
+			Description: "1 while the global blocker is blocking the drains, per blocker",
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{tagBlocker},
+		},
+	}
 )
 
 func (g *GlobalBlocksRunner) Start(ctx context.Context) error {
@@ -81,15 +123,7 @@ func (g *GlobalBlocksRunner) Run(stopCh <-chan struct{}) {
 		return
 	}
 
-	tagBlock, _ := tag.NewKey("block")
-	blockerView := &view.View{
-		Name:        "global_block",
-		Measure:     MeasureBlocker,
-		Description: "State of global blocks",
-		Aggregation: view.LastValue(),
-		TagKeys:     []tag.Key{tagBlock},
-	}
-	view.Register(blockerView)
+	view.Register(blockerViews...)
 	g.Lock()
 	defer g.Unlock()
 	g.started = true
@@ -101,15 +135,7 @@ func (g *GlobalBlocksRunner) Run(stopCh <-chan struct{}) {
 			defer wg.Done()
 			wait.Until(
 				func() {
-					// Perform Check
-					b.updateBlockState()
-					val := int64(0)
-					if b.blockState {
-						val = 1
-					}
-					// Observability
-					tag, _ := tag.New(context.Background(), tag.Upsert(tagBlock, b.name))
-					stats.Record(tag, MeasureBlocker.M(val))
+					g.check(b)
 				},
 				b.period,
 				stopCh)
@@ -118,6 +144,34 @@ func (g *GlobalBlocksRunner) Run(stopCh <-chan struct{}) {
 	wg.Wait()
 }
 
+// check updates the state of the blocker and reports it. A change of state is logged and recorded as an event.
+func (g *GlobalBlocksRunner) check(b *blocker) {
+	previous := b.blockState
+	b.updateBlockState()
+	val := int64(0)
+	if b.blockState {
+		val = 1
+	}
+	// Observability
+	tags, _ := tag.New(context.Background(), tag.Upsert(tagBlock, b.name))
+	stats.Record(tags, MeasureBlocker.M(val))
+	tags, _ = tag.New(context.Background(), tag.Upsert(tagBlocker, b.name))
+	stats.Record(tags, MeasureBlockerActive.M(val))
+
+	if previous == b.blockState {
+		return
+	}
+	g.logger.Info("Global blocker state changed", "blocker", b.name, "blocked", b.blockState)
+	if g.eventRecorder == nil || g.eventObject == nil {
+		return
+	}
+	if b.blockState {
+		g.eventRecorder.Eventf(g.eventObject, corev1.EventTypeWarning, EventReasonGlobalBlockerActivated.String(), "Drains paused by the global blocker %s", b.name)
+	} else {
+		g.eventRecorder.Eventf(g.eventObject, corev1.EventTypeNormal, EventReasonGlobalBlockerDeactivated.String(), "Drains resumed, the global blocker %s is released", b.name)
+	}
+}
+
 func (g *GlobalBlocksRunner) AddBlocker(name string, checkFunc ComputeBlockStateFunction, period time.Duration) error {
 	g.Lock()
 	defer g.Unlock()
@@ -160,12 +214,7 @@ func MaxNotReadyNodesCheckFunc(max int, percent bool, idx *index.Indexer, logger
 			return false
 		}
 
-		notReadyCount := 0
-		for _, n := range nodes {
-			if ready, _ := GetReadinessState(n); !ready {
-				notReadyCount++
-			}
-		}
+		notReadyCount := countNotReadyNodes(nodes)
 		blocked := false
 		if percent {
 			blocked = math.Ceil(100*float64(notReadyCount)/float64(len(nodes))) > float64(max)
@@ -176,6 +225,117 @@ func MaxNotReadyNodesCheckFunc(max int, percent bool, idx *index.Indexer, logger
 	}
 }
 
+func countNotReadyNodes(nodes []*corev1.Node) int {
+	notReadyCount := 0
+	for _, n := range nodes {
+		if ready, _ := GetReadinessState(n); !ready {
+			notReadyCount++
+		}
+	}
+	return notReadyCount
+}
+
+// NotReadyNodesFraction periodically computes the fraction of NotReady nodes in the cluster, so that it can be read without listing the nodes
+type NotReadyNodesFraction struct {
+	sync.RWMutex
+	idx      *index.Indexer
+	logger   logr.Logger
+	period   time.Duration
+	fraction float64
+}
+
+func NewNotReadyNodesFraction(idx *index.Indexer, logger logr.Logger, period time.Duration) *NotReadyNodesFraction {
+	return &NotReadyNodesFraction{
+		idx:    idx,
+		logger: logger,
+		period: period,
+	}
+}
+
+func (f *NotReadyNodesFraction) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, f.update, f.period)
+	return nil
+}
+
+func (f *NotReadyNodesFraction) update(context.Context) {
+	nodes, err := f.idx.GetAllNodes()
+	if err != nil {
+		f.logger.Error(err, "cannot list nodes to compute the NotReady fraction")
+		return
+	}
+	fraction := 0.
+	if len(nodes) > 0 {
+		fraction = float64(countNotReadyNodes(nodes)) / float64(len(nodes))
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.fraction = fraction
+}
+
+// Fraction returns the last computed fraction of NotReady nodes, between 0 and 1
+func (f *NotReadyNodesFraction) Fraction() float64 {
+	f.RLock()
+	defer f.RUnlock()
+	return f.fraction
+}
+
+// PendingPodsPerNode periodically computes the number of Pending pods per node in the cluster, an estimation of the lack of spare capacity
+type PendingPodsPerNode struct {
+	sync.RWMutex
+	idx    *index.Indexer
+	logger logr.Logger
+	period time.Duration
+	ratio  float64
+	known  bool
+}
+
+func NewPendingPodsPerNode(idx *index.Indexer, logger logr.Logger, period time.Duration) *PendingPodsPerNode {
+	return &PendingPodsPerNode{
+		idx:    idx,
+		logger: logger,
+		period: period,
+	}
+}
+
+func (p *PendingPodsPerNode) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, p.update, p.period)
+	return nil
+}
+
+func (p *PendingPodsPerNode) update(ctx context.Context) {
+	ratio, err := p.compute(ctx)
+	if err != nil {
+		p.logger.Error(err, "cannot compute the pending pods per node")
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.ratio = ratio
+	p.known = err == nil
+}
+
+func (p *PendingPodsPerNode) compute(ctx context.Context) (float64, error) {
+	nodes, err := p.idx.GetAllNodes()
+	if err != nil {
+		return 0, fmt.Errorf("cannot list nodes: %w", err)
+	}
+	pendingPods, err := p.idx.GetPodsByPhase(ctx, corev1.PodPending)
+	if err != nil {
+		return 0, fmt.Errorf("cannot list pending pods: %w", err)
+	}
+	if len(nodes) == 0 {
+		return 0, nil
+	}
+	return float64(len(pendingPods)) / float64(len(nodes)), nil
+}
+
+// Ratio returns the last computed number of Pending pods per node
+// It returns false if the ratio was not computed yet or if the last computation failed.
+func (p *PendingPodsPerNode) Ratio() (float64, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.ratio, p.known
+}
+
 func MaxPendingPodsCheckFunc(max int, percent bool, idx *index.Indexer, logger logr.Logger) ComputeBlockStateFunction {
 	return func() bool {
 		totalPodCount, err := idx.GetPodCount(context.Background())
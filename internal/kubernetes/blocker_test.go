@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	core "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGlobalBlocksRunner_transitions(t *testing.T) {
+	assert.NoError(t, view.Register(blockerViews...))
+	defer view.Unregister(blockerViews...)
+
+	recorder := record.NewFakeRecorder(10)
+	g := NewGlobalBlocker(logr.Discard(), WithTransitionEventRecorder(recorder, &core.ObjectReference{Kind: "Pod", Namespace: "draino", Name: "draino-0"}))
+	blocked := false
+	assert.NoError(t, g.AddBlocker("MaxNotReadyNodes:10%", func() bool { return blocked }, time.Minute))
+	b := g.blockers[0]
+
+	gaugeValue := func() float64 {
+		rows, err := view.RetrieveData("global_blocker_active")
+		assert.NoError(t, err)
+		for _, row := range rows {
+			if assert.Contains(t, row.Tags, tag.Tag{Key: tagBlocker, Value: "MaxNotReadyNodes:10%"}) {
+				return row.Data.(*view.LastValueData).Value
+			}
+		}
+		return -1
+	}
+
+	g.check(b)
+	assert.Equal(t, 0., gaugeValue())
+	assert.Empty(t, recorder.Events, "no transition")
+
+	blocked = true
+	g.check(b)
+	assert.Equal(t, 1., gaugeValue())
+	isBlocked, blocker := g.IsBlocked()
+	assert.True(t, isBlocked)
+	assert.Equal(t, "MaxNotReadyNodes:10%", blocker)
+	assert.Equal(t, "Warning GlobalBlockerActivated Drains paused by the global blocker MaxNotReadyNodes:10%", <-recorder.Events)
+
+	g.check(b)
+	assert.Empty(t, recorder.Events, "still blocked, no transition")
+
+	blocked = false
+	g.check(b)
+	assert.Equal(t, 0., gaugeValue())
+	assert.Equal(t, "Normal GlobalBlockerDeactivated Drains resumed, the global blocker MaxNotReadyNodes:10% is released", <-recorder.Events)
+}
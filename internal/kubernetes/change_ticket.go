@@ -0,0 +1,36 @@
+package kubernetes
+
+import (
+	"regexp"
+	"strings"
+
+	core "k8s.io/api/core/v1"
+)
+
+// DefaultChangeTicketAnnotationKey is the node annotation linking its drain to a change record, for example draino/change-ticket=JIRA-123
+const DefaultChangeTicketAnnotationKey = "draino/change-ticket"
+
+// changeTicketRegexp bounds the ticket IDs accepted, they are used in the events, the metrics and the audit log
+var changeTicketRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]{0,63}$`)
+
+// GetNodeChangeTicket returns the change ticket given by the annotationKey annotation of the node, empty if the node has no ticket
+// or an invalid one. DefaultChangeTicketAnnotationKey is used if the annotationKey is empty.
+func GetNodeChangeTicket(node *core.Node, annotationKey string) string {
+	if annotationKey == "" {
+		annotationKey = DefaultChangeTicketAnnotationKey
+	}
+	value := strings.TrimSpace(node.Annotations[annotationKey])
+	if !changeTicketRegexp.MatchString(value) {
+		return ""
+	}
+	return value
+}
+
+// withChangeTicket appends the change ticket of the node, if any, to the event message
+func withChangeTicket(node *core.Node, annotationKey, messageFmt string, args []interface{}) (string, []interface{}) {
+	ticket := GetNodeChangeTicket(node, annotationKey)
+	if ticket == "" {
+		return messageFmt, args
+	}
+	return messageFmt + " [change ticket %s]", append(args, ticket)
+}
@@ -0,0 +1,22 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetNodeChangeTicket(t *testing.T) {
+	node := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node", Annotations: map[string]string{
+		DefaultChangeTicketAnnotationKey: "JIRA-123",
+		"example.com/change":             " CHG-42 ",
+		"example.com/invalid":            "not a ticket",
+	}}}
+
+	assert.Equal(t, "JIRA-123", GetNodeChangeTicket(node, ""), "the default annotation is used when the key is not configured")
+	assert.Equal(t, "CHG-42", GetNodeChangeTicket(node, "example.com/change"))
+	assert.Equal(t, "", GetNodeChangeTicket(node, "example.com/invalid"))
+	assert.Equal(t, "", GetNodeChangeTicket(node, "example.com/missing"))
+}
@@ -0,0 +1,130 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// MaxConditionWindow is the longest rolling window accepted for a condition, it is also the retention of the condition history
+const MaxConditionWindow = 24 * time.Hour
+
+// conditionTransition is a status change of a node condition observed by draino
+type conditionTransition struct {
+	time   time.Time
+	status core.ConditionStatus
+}
+
+// conditionHistory keeps the transitions of the node conditions observed by draino, so that a condition can be evaluated on a rolling window.
+// The kubelet and the node problem detector only report the last transition, the history is built from the successive observations of the nodes.
+// The transitions happening between two observations of a node are missed.
+type conditionHistory struct {
+	sync.Mutex
+	transitions map[string][]conditionTransition
+	lastCleanup time.Time
+}
+
+func newConditionHistory() *conditionHistory {
+	return &conditionHistory{transitions: map[string][]conditionTransition{}}
+}
+
+// observe records the condition of the node if its status changed and returns the known transitions of the condition
+func (h *conditionHistory) observe(nodeName string, condition core.NodeCondition, now time.Time) []conditionTransition {
+	h.Lock()
+	defer h.Unlock()
+
+	key := nodeName + "/" + string(condition.Type)
+	transitions := h.transitions[key]
+	if len(transitions) == 0 || transitions[len(transitions)-1].status != condition.Status {
+		transitions = append(transitions, conditionTransition{time: condition.LastTransitionTime.Time, status: condition.Status})
+	}
+	// The transitions that ended before the retention are useless
+	for len(transitions) > 1 && transitions[1].time.Before(now.Add(-MaxConditionWindow)) {
+		transitions = transitions[1:]
+	}
+	h.transitions[key] = transitions
+
+	if now.Sub(h.lastCleanup) > MaxConditionWindow {
+		h.cleanup(now)
+	}
+	return append([]conditionTransition(nil), transitions...)
+}
+
+// transitionsWith returns the known transitions of the condition of the node, followed by its current status if it was not recorded yet.
+// The history is not modified, a nil history only gives the current status.
+func (h *conditionHistory) transitionsWith(nodeName string, condition core.NodeCondition) []conditionTransition {
+	var transitions []conditionTransition
+	if h != nil {
+		h.Lock()
+		transitions = append(transitions, h.transitions[nodeName+"/"+string(condition.Type)]...)
+		h.Unlock()
+	}
+	if len(transitions) == 0 || transitions[len(transitions)-1].status != condition.Status {
+		transitions = append(transitions, conditionTransition{time: condition.LastTransitionTime.Time, status: condition.Status})
+	}
+	return transitions
+}
+
+// cleanup forgets the conditions, of deleted nodes for example, that did not change during the retention.
+// Nothing is lost: the next observation of such condition records its last transition again.
+func (h *conditionHistory) cleanup(now time.Time) {
+	for key, transitions := range h.transitions {
+		if transitions[len(transitions)-1].time.Before(now.Add(-MaxConditionWindow)) {
+			delete(h.transitions, key)
+		}
+	}
+	h.lastCleanup = now
+}
+
+// statusFraction returns the fraction of the window, ending now, during which the condition had the given status
+func statusFraction(transitions []conditionTransition, status core.ConditionStatus, window time.Duration, now time.Time) float64 {
+	start := now.Add(-window)
+	var matching time.Duration
+	for i, transition := range transitions {
+		if transition.status != status {
+			continue
+		}
+		begin, end := transition.time, now
+		if i+1 < len(transitions) {
+			end = transitions[i+1].time
+		}
+		if begin.Before(start) {
+			begin = start
+		}
+		if end.After(begin) {
+			matching += end.Sub(begin)
+		}
+	}
+	return float64(matching) / float64(window)
+}
+
+// NewConditionHistoryHandler returns the node event handler recording the transitions of the conditions with a Window in their history.
+// It must be registered on the node informer so that all the observations of the nodes are recorded.
+func NewConditionHistoryHandler(conditions []SuppliedCondition) cache.ResourceEventHandler {
+	var windowed []SuppliedCondition
+	for _, c := range conditions {
+		if c.history != nil {
+			windowed = append(windowed, c)
+		}
+	}
+	record := func(obj interface{}) {
+		node, ok := obj.(*core.Node)
+		if !ok {
+			return
+		}
+		now := time.Now()
+		for _, c := range windowed {
+			for _, nodeCondition := range node.Status.Conditions {
+				if c.matchesType(nodeCondition.Type) {
+					c.history.observe(node.Name, nodeCondition, now)
+				}
+			}
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    record,
+		UpdateFunc: func(_, newObj interface{}) { record(newObj) },
+	}
+}
@@ -0,0 +1,120 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditionHistory_FlappingCondition(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		// flaps are the durations of the successive True and False periods of the condition, starting with True
+		flaps            []time.Duration
+		expectedFraction float64
+		expectOffending  bool
+	}{
+		{
+			name:             "mostly true",
+			flaps:            []time.Duration{10 * time.Minute, 2 * time.Minute, 10 * time.Minute, 3 * time.Minute, 5 * time.Minute},
+			expectedFraction: 25. / 30.,
+			expectOffending:  true,
+		},
+		{
+			name:             "flapping evenly",
+			flaps:            []time.Duration{5 * time.Minute, 5 * time.Minute, 5 * time.Minute, 5 * time.Minute, 5 * time.Minute, 5 * time.Minute},
+			expectedFraction: 15. / 30.,
+			expectOffending:  false,
+		},
+		{
+			name:             "true before the window",
+			flaps:            []time.Duration{40 * time.Minute, 10 * time.Minute, 20 * time.Minute},
+			expectedFraction: 20. / 30.,
+			expectOffending:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := newConditionHistory()
+			now := start
+			status := core.ConditionTrue
+			var transitions []conditionTransition
+			for _, flap := range tt.flaps {
+				condition := core.NodeCondition{Type: "Noisy", Status: status, LastTransitionTime: meta.NewTime(now)}
+				// the node is observed several times during each period
+				for elapsed := time.Duration(0); elapsed < flap; elapsed += time.Minute {
+					transitions = history.observe("node", condition, now.Add(elapsed))
+				}
+				now = now.Add(flap)
+				if status == core.ConditionTrue {
+					status = core.ConditionFalse
+				} else {
+					status = core.ConditionTrue
+				}
+			}
+
+			fraction := statusFraction(transitions, core.ConditionTrue, 30*time.Minute, now)
+			assert.InDelta(t, tt.expectedFraction, fraction, 0.0001)
+			assert.Equal(t, tt.expectOffending, fraction >= 0.8)
+		})
+	}
+}
+
+func TestConditionHistory_Retention(t *testing.T) {
+	history := newConditionHistory()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	history.observe("node", core.NodeCondition{Type: "Noisy", Status: core.ConditionTrue, LastTransitionTime: meta.NewTime(now.Add(-30 * time.Hour))}, now)
+	history.observe("node", core.NodeCondition{Type: "Noisy", Status: core.ConditionFalse, LastTransitionTime: meta.NewTime(now.Add(-26 * time.Hour))}, now)
+	transitions := history.observe("node", core.NodeCondition{Type: "Noisy", Status: core.ConditionTrue, LastTransitionTime: meta.NewTime(now.Add(-time.Hour))}, now)
+	assert.Equal(t, []conditionTransition{{time: now.Add(-26 * time.Hour), status: core.ConditionFalse}, {time: now.Add(-time.Hour), status: core.ConditionTrue}}, transitions)
+
+	history.observe("deleted-node", core.NodeCondition{Type: "Noisy", Status: core.ConditionTrue, LastTransitionTime: meta.NewTime(now)}, now)
+	history.cleanup(now.Add(25 * time.Hour))
+	assert.NotContains(t, history.transitions, "deleted-node/Noisy")
+}
+
+func TestOffendingConditions_Window(t *testing.T) {
+	conditions, err := ParseConditions([]string{`Noisy={"window":"30m","minFraction":0.8}`})
+	assert.NoError(t, err)
+
+	newNode := func(name string, status core.ConditionStatus, since time.Duration) *core.Node {
+		return &core.Node{
+			ObjectMeta: meta.ObjectMeta{Name: name},
+			Status: core.NodeStatus{Conditions: []core.NodeCondition{
+				{Type: "Noisy", Status: status, LastTransitionTime: meta.NewTime(time.Now().Add(-since))},
+			}},
+		}
+	}
+	assert.Len(t, GetNodeOffendingConditions(newNode("window-enough", core.ConditionTrue, 25*time.Minute), conditions), 1)
+	assert.Empty(t, GetNodeOffendingConditions(newNode("window-not-enough", core.ConditionTrue, 20*time.Minute), conditions))
+	assert.Empty(t, GetNodeOffendingConditions(newNode("window-false", core.ConditionFalse, time.Hour), conditions))
+}
+
+func TestConditionHistoryHandler(t *testing.T) {
+	conditions, err := ParseConditions([]string{`Noisy={"window":"30m","minFraction":0.5}`, "Ready"})
+	assert.NoError(t, err)
+	handler := NewConditionHistoryHandler(conditions)
+
+	newNode := func(status core.ConditionStatus, since time.Duration) *core.Node {
+		return &core.Node{
+			ObjectMeta: meta.ObjectMeta{Name: "node"},
+			Status: core.NodeStatus{Conditions: []core.NodeCondition{
+				{Type: "Noisy", Status: status, LastTransitionTime: meta.NewTime(time.Now().Add(-since))},
+			}},
+		}
+	}
+	// true for 20m, then false for 5m and true again for the last 5m
+	handler.OnAdd(newNode(core.ConditionTrue, 30*time.Minute))
+	handler.OnUpdate(nil, newNode(core.ConditionFalse, 10*time.Minute))
+
+	node := newNode(core.ConditionTrue, 5*time.Minute)
+	assert.Len(t, GetNodeOffendingConditions(node, conditions), 1, "the recorded transitions are used")
+	assert.Len(t, conditions[0].history.transitions["node/Noisy"], 2, "the evaluation does not record the transitions")
+
+	handler.OnUpdate(nil, node)
+	assert.Len(t, conditions[0].history.transitions["node/Noisy"], 3)
+}
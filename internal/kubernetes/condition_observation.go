@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+)
+
+// WithMinDurationFromObservation returns a copy of the conditions whose Delay starts when draino first observes the condition with
+// its status, instead of its LastTransitionTime, so that a long-standing condition gets the same warm-up after a restart of the controller.
+func WithMinDurationFromObservation(conditions []SuppliedCondition) []SuppliedCondition {
+	result := make([]SuppliedCondition, len(conditions))
+	for i, condition := range conditions {
+		condition.fromObservation = true
+		result[i] = condition
+	}
+	return result
+}
+
+// conditionObservation is the status of a node condition and the time draino first observed it
+type conditionObservation struct {
+	status   core.ConditionStatus
+	since    time.Time
+	lastSeen time.Time
+}
+
+// conditionObservations keeps in memory when draino first observed the current status of the node conditions.
+// It is lost on restart, which gives a fresh warm-up to all the conditions.
+type conditionObservations struct {
+	sync.Mutex
+	observations map[string]conditionObservation
+	lastCleanup  time.Time
+}
+
+// firstObservedConditions is used by the offending conditions computation for the conditions measured from their observation
+var firstObservedConditions = newConditionObservations()
+
+// firstObservedTaints records when draino first observed the trigger taints, Kubernetes only sets the TimeAdded of the NoExecute taints
+var firstObservedTaints = newConditionObservations()
+
+func newConditionObservations() *conditionObservations {
+	return &conditionObservations{observations: map[string]conditionObservation{}}
+}
+
+// since returns the time from which the condition of the node is considered to have its current status.
+// It is the first observation of that status by draino, or the LastTransitionTime if the condition changed after it.
+func (o *conditionObservations) since(nodeName string, condition core.NodeCondition, now time.Time) time.Time {
+	o.Lock()
+	defer o.Unlock()
+
+	key := nodeName + "/" + string(condition.Type)
+	observation, found := o.observations[key]
+	if !found || observation.status != condition.Status {
+		observation = conditionObservation{status: condition.Status, since: now}
+	}
+	// The condition flapped between two observations
+	if condition.LastTransitionTime.Time.After(observation.since) {
+		observation.since = condition.LastTransitionTime.Time
+	}
+	observation.lastSeen = now
+	o.observations[key] = observation
+
+	if now.Sub(o.lastCleanup) > MaxConditionWindow {
+		o.cleanup(now)
+	}
+	return observation.since
+}
+
+// cleanup forgets the conditions, of deleted nodes for example, that were not observed during the retention
+func (o *conditionObservations) cleanup(now time.Time) {
+	for key, observation := range o.observations {
+		if observation.lastSeen.Before(now.Add(-MaxConditionWindow)) {
+			delete(o.observations, key)
+		}
+	}
+	o.lastCleanup = now
+}
+
+// conditionStartTime returns the time from which the Delay of the condition is measured
+func conditionStartTime(nodeName string, condition core.NodeCondition, fromObservation bool, now time.Time) time.Time {
+	if !fromObservation {
+		return condition.LastTransitionTime.Time
+	}
+	return firstObservedConditions.since(nodeName, condition, now)
+}
+
+// taintStartTime returns the time from which the Delay of a trigger taint is measured: the time the taint was added if known,
+// its first observation by draino otherwise. The absence of the taint, a nil taint, is recorded too so that a taint added again starts over.
+func taintStartTime(nodeName, taintKey string, taint *core.Taint, fromObservation bool, now time.Time) time.Time {
+	observed := core.NodeCondition{Type: core.NodeConditionType(taintKey), Status: core.ConditionFalse}
+	if taint != nil {
+		observed.Status = core.ConditionTrue
+		if taint.TimeAdded != nil {
+			if !fromObservation {
+				return taint.TimeAdded.Time
+			}
+			observed.LastTransitionTime = *taint.TimeAdded
+		}
+	}
+	return firstObservedTaints.since(nodeName, observed, now)
+}
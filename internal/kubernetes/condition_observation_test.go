@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditionObservations_Since(t *testing.T) {
+	observations := newConditionObservations()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	condition := core.NodeCondition{Type: "KernelDeadlock", Status: core.ConditionTrue, LastTransitionTime: meta.NewTime(now.Add(-48 * time.Hour))}
+
+	assert.Equal(t, now, observations.since("node", condition, now), "a long-standing condition starts at its first observation")
+	assert.Equal(t, now, observations.since("node", condition, now.Add(time.Minute)), "the first observation is kept")
+
+	condition.Status = core.ConditionFalse
+	assert.Equal(t, now.Add(2*time.Minute), observations.since("node", condition, now.Add(2*time.Minute)), "a new status restarts the observation")
+
+	// the condition flapped back and forth between two observations
+	condition.LastTransitionTime = meta.NewTime(now.Add(4 * time.Minute))
+	assert.Equal(t, now.Add(4*time.Minute), observations.since("node", condition, now.Add(5*time.Minute)), "a transition after the observation is used")
+
+	observations.since("deleted-node", condition, now)
+	observations.cleanup(now.Add(25 * time.Hour))
+	assert.NotContains(t, observations.observations, "deleted-node/KernelDeadlock")
+}
+
+func TestOffendingConditions_MinDurationFromObservation(t *testing.T) {
+	conditions, err := ParseConditions([]string{`KernelDeadlock={"delay":"10m"}`})
+	assert.NoError(t, err)
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: "node"},
+		Status: core.NodeStatus{Conditions: []core.NodeCondition{
+			{Type: "KernelDeadlock", Status: core.ConditionTrue, LastTransitionTime: meta.NewTime(time.Now().Add(-48 * time.Hour))},
+		}},
+	}
+	defer func() { firstObservedConditions = newConditionObservations() }()
+
+	tests := []struct {
+		name                       string
+		minDurationFromObservation bool
+		observedFor                time.Duration
+		expectOffending            bool
+	}{
+		{name: "from transition time, the condition is offending right after the restart", minDurationFromObservation: false, expectOffending: true},
+		{name: "from observation, the condition waits for its delay after the restart", minDurationFromObservation: true, expectOffending: false},
+		{name: "from observation, the condition is offending once observed for its delay", minDurationFromObservation: true, observedFor: 15 * time.Minute, expectOffending: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suppliedConditions := conditions
+			if tt.minDurationFromObservation {
+				suppliedConditions = WithMinDurationFromObservation(conditions)
+			}
+			// the restart of the controller forgets all the observations
+			firstObservedConditions = newConditionObservations()
+			if tt.observedFor > 0 {
+				firstObservedConditions.since(node.Name, node.Status.Conditions[0], time.Now().Add(-tt.observedFor))
+			}
+			assert.Equal(t, tt.expectOffending, len(GetNodeOffendingConditions(node, suppliedConditions)) == 1)
+		})
+	}
+}
+
+func TestTaintStartTime(t *testing.T) {
+	defer func() { firstObservedTaints = newConditionObservations() }()
+	firstObservedTaints = newConditionObservations()
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	noSchedule := &core.Taint{Key: "ToBeDeletedByClusterAutoscaler", Effect: core.TaintEffectNoSchedule}
+	noExecute := &core.Taint{Key: "node.kubernetes.io/unreachable", Effect: core.TaintEffectNoExecute, TimeAdded: &meta.Time{Time: now.Add(-time.Hour)}}
+
+	assert.Equal(t, now, taintStartTime("node", noSchedule.Key, noSchedule, false, now), "a taint without addition time starts at its first observation")
+	assert.Equal(t, now, taintStartTime("node", noSchedule.Key, noSchedule, false, now.Add(time.Minute)), "the first observation is kept")
+	taintStartTime("node", noSchedule.Key, nil, false, now.Add(2*time.Minute))
+	assert.Equal(t, now.Add(3*time.Minute), taintStartTime("node", noSchedule.Key, noSchedule, false, now.Add(3*time.Minute)), "a taint added again starts over")
+
+	assert.Equal(t, now.Add(-time.Hour), taintStartTime("node", noExecute.Key, noExecute, false, now), "the addition time is used when known")
+	assert.Equal(t, now, taintStartTime("node", noExecute.Key, noExecute, true, now), "the addition time is ignored when measured from the observation")
+}
@@ -1,11 +1,15 @@
 package kubernetes
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/planetlabs/draino/internal/limit"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 
 	core "k8s.io/api/core/v1"
 )
@@ -14,6 +18,18 @@ const DefaultExpectedResolutionTime = time.Hour * 24 * 7
 const DefaultDrainRateLimitQPS = float32(1. / 60.)
 const DefaultDrainRateLimitBurst = 1
 
+// TriggerTaintConditionID is the ID of the condition supplied for the --trigger-taint-key flag
+const TriggerTaintConditionID = "TriggerTaint"
+
+// MirroredConditionTypePrefix prefixes the type of the conditions copied from the nodes of a secondary cluster, so that they don't
+// conflict with the conditions reported in the cluster, by node-problem-detector for example. They are evaluated like the conditions they mirror.
+const MirroredConditionTypePrefix = "Mirrored"
+
+// MirroredConditionType returns the type of the mirror of a condition
+func MirroredConditionType(conditionType core.NodeConditionType) core.NodeConditionType {
+	return MirroredConditionTypePrefix + conditionType
+}
+
 // SuppliedCondition defines the condition will be watched.
 type SuppliedCondition struct {
 	// ID is a unique identifier for this condition, must be
@@ -25,6 +41,7 @@ type SuppliedCondition struct {
 	Status core.ConditionStatus   `json:"conditionStatus"`
 	// Draino starts acting on a node with this condition after Delay has elapsed.
 	// If a node has multiple conditions, the smallest Delay is applied. Default is 0.
+	// The Delay is measured from the LastTransitionTime of the condition, or from its first observation by draino with --min-duration-from-observation.
 	Delay    string `json:"delay,omitempty"`
 	Priority int    `json:"priority,omitempty"` // higher value first in priority, default is 0, negative value are accepted
 	// ExpectedResolutionTime is the duration given to draino and cluster-autoscaler (for
@@ -34,6 +51,14 @@ type SuppliedCondition struct {
 	// attention (metric->monitor->SLO). A higher priority is typically associated
 	// with a lower time limit. Default is 7 days for now.
 	ExpectedResolutionTime string `json:"expectedResolutionTime"`
+	// Window and MinFraction are used for the noisy conditions instead of Delay: the condition is offending if it holds and
+	// had the Status for at least MinFraction (between 0 and 1) of the last Window. The fraction is computed from the
+	// transitions of the condition observed by draino. The Window cannot exceed MaxConditionWindow.
+	Window      string  `json:"window,omitempty"`
+	MinFraction float64 `json:"minFraction,omitempty"`
+	// Taint is the key of a taint set by another controller, cluster-autoscaler for example. If set, the condition is offending
+	// while the node has this taint, instead of a node condition. The Delay is measured from the time the taint was added, if known.
+	Taint string `json:"taint,omitempty"`
 
 	// Rate Limiting
 	RateLimitQPS   *float32 `json:"rateLimitQPS,omitempty"`
@@ -41,15 +66,46 @@ type SuppliedCondition struct {
 
 	parsedDelay                  time.Duration
 	parsedExpectedResolutionTime time.Duration
+	parsedWindow                 time.Duration
+	// group restricts the condition to the nodes of that drain group, see WithGroupConditions. Empty for the global conditions.
+	group string
+	// fromObservation measures the Delay from the first observation of the condition by draino, see WithMinDurationFromObservation
+	fromObservation bool
+	// history holds the transitions of the conditions with a Window, recorded by NewConditionHistoryHandler. It is shared by the copies of the condition.
+	history *conditionHistory
+}
+
+// matchesType tells if a node condition has the type of the supplied condition, or is its mirror
+func (c SuppliedCondition) matchesType(conditionType core.NodeConditionType) bool {
+	return c.Type == conditionType || MirroredConditionType(c.Type) == conditionType
 }
 
 func GetNodeOffendingConditions(n *core.Node, suppliedConditions []SuppliedCondition) []SuppliedCondition {
 	var conditions []SuppliedCondition
-	for _, suppliedCondition := range suppliedConditions {
+	now := time.Now()
+	for _, suppliedCondition := range conditionsForNode(n, suppliedConditions) {
+		if suppliedCondition.Taint != "" {
+			taint, found := getTaint(n, suppliedCondition.Taint)
+			if since := taintStartTime(n.Name, suppliedCondition.Taint, taint, suppliedCondition.fromObservation, now); found && now.Sub(since) >= suppliedCondition.parsedDelay {
+				conditions = append(conditions, suppliedCondition)
+			}
+			continue
+		}
 		for _, nodeCondition := range n.Status.Conditions {
-			if suppliedCondition.Type == nodeCondition.Type &&
+			if suppliedCondition.parsedWindow > 0 {
+				if !suppliedCondition.matchesType(nodeCondition.Type) {
+					continue
+				}
+				transitions := suppliedCondition.history.transitionsWith(n.Name, nodeCondition)
+				if suppliedCondition.Status == nodeCondition.Status &&
+					statusFraction(transitions, suppliedCondition.Status, suppliedCondition.parsedWindow, now) >= suppliedCondition.MinFraction {
+					conditions = append(conditions, suppliedCondition)
+				}
+				continue
+			}
+			if suppliedCondition.matchesType(nodeCondition.Type) &&
 				suppliedCondition.Status == nodeCondition.Status &&
-				time.Since(nodeCondition.LastTransitionTime.Time) >= suppliedCondition.parsedDelay {
+				now.Sub(conditionStartTime(n.Name, nodeCondition, suppliedCondition.fromObservation, now)) >= suppliedCondition.parsedDelay {
 				conditions = append(conditions, suppliedCondition)
 			}
 		}
@@ -58,8 +114,12 @@ func GetNodeOffendingConditions(n *core.Node, suppliedConditions []SuppliedCondi
 }
 
 func IsOverdue(n *core.Node, suppliedCondition SuppliedCondition) bool {
+	if suppliedCondition.Taint != "" {
+		taint, found := getTaint(n, suppliedCondition.Taint)
+		return found && taint.TimeAdded != nil && time.Since(taint.TimeAdded.Time) >= suppliedCondition.parsedExpectedResolutionTime
+	}
 	for _, nodeCondition := range n.Status.Conditions {
-		if suppliedCondition.Type == nodeCondition.Type &&
+		if suppliedCondition.matchesType(nodeCondition.Type) &&
 			suppliedCondition.Status == nodeCondition.Status &&
 			time.Since(nodeCondition.LastTransitionTime.Time) >= suppliedCondition.parsedExpectedResolutionTime {
 			return true
@@ -68,6 +128,31 @@ func IsOverdue(n *core.Node, suppliedCondition SuppliedCondition) bool {
 	return false
 }
 
+// RecordConditionAgeAtCordon records, for each of the given offending conditions, the time elapsed since its last transition,
+// or since the taint was added for the taint conditions. The taints without addition time are not recorded.
+func RecordConditionAgeAtCordon(ctx context.Context, n *core.Node, conditions []SuppliedCondition, now time.Time) {
+	for _, suppliedCondition := range conditions {
+		var since time.Time
+		if suppliedCondition.Taint != "" {
+			if taint, found := getTaint(n, suppliedCondition.Taint); found && taint.TimeAdded != nil {
+				since = taint.TimeAdded.Time
+			}
+		} else {
+			for _, nodeCondition := range n.Status.Conditions {
+				if suppliedCondition.matchesType(nodeCondition.Type) {
+					since = nodeCondition.LastTransitionTime.Time
+					break
+				}
+			}
+		}
+		if since.IsZero() {
+			continue
+		}
+		tags, _ := tag.New(ctx, tag.Upsert(TagConditionType, string(suppliedCondition.Type)))
+		stats.Record(tags, MeasureConditionAgeAtCordon.M(now.Sub(since).Seconds()))
+	}
+}
+
 func GetConditionIDs(conditions []SuppliedCondition) []string {
 	result := make([]string, len(conditions))
 	for i := range conditions {
@@ -86,8 +171,15 @@ func ParseConditions(conditions []string) ([]SuppliedCondition, error) {
 		}
 		var id string = ts[0]
 		var condition SuppliedCondition
-		if err := json.Unmarshal([]byte(ts[1]), &condition); err != nil {
-			return nil, err
+		if strings.HasPrefix(strings.TrimSpace(ts[1]), "{") {
+			if err := json.Unmarshal([]byte(ts[1]), &condition); err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			if condition, err = parseShortCondition(ts[1]); err != nil {
+				return nil, fmt.Errorf("condition %s: %w", id, err)
+			}
 		}
 		condition.ID = id
 		if condition.Type == "" {
@@ -107,15 +199,83 @@ func ParseConditions(conditions []string) ([]SuppliedCondition, error) {
 		} else {
 			condition.parsedExpectedResolutionTime = DefaultExpectedResolutionTime
 		}
+		if condition.Window != "" {
+			var errParse error
+			if condition.parsedWindow, errParse = time.ParseDuration(condition.Window); errParse != nil {
+				return nil, errParse
+			}
+			if condition.parsedWindow <= 0 || condition.parsedWindow > MaxConditionWindow {
+				return nil, fmt.Errorf("condition %s: window must be positive and at most %v", id, MaxConditionWindow)
+			}
+			if condition.Delay != "" {
+				return nil, fmt.Errorf("condition %s: delay and window cannot be combined", id)
+			}
+			if condition.MinFraction <= 0 || condition.MinFraction > 1 {
+				return nil, fmt.Errorf("condition %s: minFraction must be greater than 0 and at most 1", id)
+			}
+			condition.history = newConditionHistory()
+		} else if condition.MinFraction != 0 {
+			return nil, fmt.Errorf("condition %s: minFraction requires a window", id)
+		}
+		if condition.Taint != "" && condition.Window != "" {
+			return nil, fmt.Errorf("condition %s: taint and window cannot be combined", id)
+		}
 		if condition.Status == "" {
 			condition.Status = core.ConditionTrue
 		}
+		// a rate limiter without burst never gives a token
+		if condition.RateLimitBurst != nil && *condition.RateLimitBurst < 1 {
+			return nil, fmt.Errorf("condition %s: rateLimitBurst must be at least 1", id)
+		}
 
 		parsed[i] = condition
 	}
 	return parsed, nil
 }
 
+// parseShortCondition parses the short condition format Status[,Delay][,weight=N], for example Ready=False,5m,weight=10.
+// The weight is the priority of the condition, the nodes with the heaviest offending condition are drained first.
+func parseShortCondition(spec string) (SuppliedCondition, error) {
+	var condition SuppliedCondition
+	for i, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		switch {
+		case strings.HasPrefix(field, "weight="):
+			weight, err := strconv.Atoi(strings.TrimPrefix(field, "weight="))
+			if err != nil {
+				return condition, fmt.Errorf("invalid weight %q", field)
+			}
+			condition.Priority = weight
+		case i == 0:
+			switch status := core.ConditionStatus(field); status {
+			case core.ConditionTrue, core.ConditionFalse, core.ConditionUnknown:
+				condition.Status = status
+			default:
+				return condition, fmt.Errorf("invalid condition status %q", field)
+			}
+		case i == 1:
+			condition.Delay = field
+		default:
+			return condition, fmt.Errorf("unexpected field %q", field)
+		}
+	}
+	return condition, nil
+}
+
+// TriggerTaintCondition returns the condition, in the --node-conditions format, triggering the drain of the nodes with the given taint
+func TriggerTaintCondition(taintKey string) string {
+	return fmt.Sprintf(`%s={"taint":%q}`, TriggerTaintConditionID, taintKey)
+}
+
+func getTaint(n *core.Node, key string) (*core.Taint, bool) {
+	for i := range n.Spec.Taints {
+		if n.Spec.Taints[i].Key == key {
+			return &n.Spec.Taints[i], true
+		}
+	}
+	return nil, false
+}
+
 func GetRateLimitConfiguration(conditions []SuppliedCondition) map[string]limit.RateLimiterConfiguration {
 	m := map[string]limit.RateLimiterConfiguration{}
 	for _, c := range conditions {
@@ -126,17 +286,3 @@ func GetRateLimitConfiguration(conditions []SuppliedCondition) map[string]limit.
 	}
 	return m
 }
-
-func parseConditionsFromAnnotation(n *core.Node) ([]SuppliedCondition, error) {
-	if n.Annotations == nil {
-		return nil, nil
-	}
-	if n.Annotations[drainoConditionsAnnotationKey] == "" {
-		return nil, nil
-	}
-	var conditions []SuppliedCondition
-	if err := json.Unmarshal([]byte(n.Annotations[drainoConditionsAnnotationKey]), &conditions); err != nil {
-		return nil, err
-	}
-	return conditions, nil
-}
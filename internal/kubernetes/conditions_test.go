@@ -1,10 +1,14 @@
 package kubernetes
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -133,6 +137,54 @@ func TestOffendingConditions(t *testing.T) {
 				{ID: "Cool", Type: "Cool", Status: core.ConditionUnknown, parsedDelay: 14 * time.Minute, Delay: "14m", Priority: 99, parsedExpectedResolutionTime: DefaultExpectedResolutionTime},
 			},
 		},
+		{
+			name: "MatchingTriggerTaint",
+			obj: &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: nodeName},
+				Spec: core.NodeSpec{Taints: []core.Taint{
+					{Key: "ToBeDeletedByClusterAutoscaler", Effect: core.TaintEffectNoSchedule},
+				}},
+			},
+			conditions: []string{TriggerTaintCondition("ToBeDeletedByClusterAutoscaler")},
+			expected: []SuppliedCondition{
+				{ID: TriggerTaintConditionID, Type: TriggerTaintConditionID, Status: core.ConditionTrue, Taint: "ToBeDeletedByClusterAutoscaler", parsedExpectedResolutionTime: DefaultExpectedResolutionTime},
+			},
+		},
+		{
+			name: "OtherTaint",
+			obj: &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: nodeName},
+				Spec: core.NodeSpec{Taints: []core.Taint{
+					{Key: "DeletionCandidateOfClusterAutoscaler", Effect: core.TaintEffectPreferNoSchedule},
+				}},
+			},
+			conditions: []string{TriggerTaintCondition("ToBeDeletedByClusterAutoscaler")},
+			expected:   nil,
+		},
+		{
+			name: "TriggerTaintDurationIsNotEnough",
+			obj: &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: nodeName},
+				Spec: core.NodeSpec{Taints: []core.Taint{
+					{Key: "ToBeDeletedByClusterAutoscaler", Effect: core.TaintEffectNoSchedule},
+				}},
+			},
+			conditions: []string{`Tainted={"taint":"ToBeDeletedByClusterAutoscaler","delay":"10m"}`},
+			expected:   nil,
+		},
+		{
+			name: "NoExecuteTriggerTaintDurationIsEnough",
+			obj: &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: nodeName},
+				Spec: core.NodeSpec{Taints: []core.Taint{
+					{Key: "node.kubernetes.io/unreachable", Effect: core.TaintEffectNoExecute, TimeAdded: &meta.Time{Time: time.Now().Add(-15 * time.Minute)}},
+				}},
+			},
+			conditions: []string{`Tainted={"taint":"node.kubernetes.io/unreachable","delay":"10m"}`},
+			expected: []SuppliedCondition{
+				{ID: "Tainted", Type: "Tainted", Status: core.ConditionTrue, Taint: "node.kubernetes.io/unreachable", Delay: "10m", parsedDelay: 10 * time.Minute, parsedExpectedResolutionTime: DefaultExpectedResolutionTime},
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -150,3 +202,36 @@ func TestOffendingConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordConditionAgeAtCordon(t *testing.T) {
+	conditionAgeView := &view.View{
+		Name:        "test_condition_age_at_cordon",
+		Measure:     MeasureConditionAgeAtCordon,
+		Aggregation: view.Distribution(60, 3600),
+		TagKeys:     []tag.Key{TagConditionType},
+	}
+	assert.NoError(t, view.Register(conditionAgeView))
+	defer view.Unregister(conditionAgeView)
+
+	now := time.Now()
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Status: core.NodeStatus{Conditions: []core.NodeCondition{
+			{Type: "Cool", Status: core.ConditionTrue, LastTransitionTime: meta.NewTime(now.Add(-10 * time.Minute))},
+		}},
+	}
+	suppliedConditions, err := ParseConditions([]string{"Cool"})
+	assert.NoError(t, err)
+
+	RecordConditionAgeAtCordon(context.Background(), node, GetNodeOffendingConditions(node, suppliedConditions), now)
+
+	rows, err := view.RetrieveData(conditionAgeView.Name)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, []tag.Tag{{Key: TagConditionType, Value: "Cool"}}, rows[0].Tags)
+		distribution := rows[0].Data.(*view.DistributionData)
+		assert.Equal(t, int64(1), distribution.Count)
+		assert.Equal(t, (10 * time.Minute).Seconds(), distribution.Min, "the age should be measured from the last transition of the condition")
+		assert.Equal(t, []int64{0, 1, 0}, distribution.CountPerBucket)
+	}
+}
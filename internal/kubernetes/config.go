@@ -3,9 +3,12 @@ package kubernetes
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"time"
 
 	"go.uber.org/zap"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 )
 
 type GlobalConfig struct {
@@ -20,11 +23,21 @@ type GlobalConfig struct {
 
 	// SuppliedConditions List of conditions that the controller should react on
 	SuppliedConditions []SuppliedCondition
+
+	// DrainNow tells which annotation requests the immediate drain of a node
+	DrainNow DrainNowConfig
+
+	// TeamLabelKey is the label of the nodes and pods giving their team, used to tag the metrics. DefaultTeamLabelKey if empty.
+	TeamLabelKey string
+
+	// ChangeTicketAnnotationKey is the node annotation giving the change ticket its drain is linked to. DefaultChangeTicketAnnotationKey if empty.
+	ChangeTicketAnnotationKey string
 }
 
 type FilterOptions struct {
 	DoNotEvictPodControlledBy              []string
 	EvictLocalStoragePods                  bool
+	EvictHostPathPods                      bool
 	ProtectedPodAnnotations                []string
 	DoNotCandidatePodControlledBy          []string
 	CandidateLocalStoragePods              bool
@@ -35,6 +48,25 @@ type FilterOptions struct {
 	NodeLabels                             []string
 	NodeLabelsExpr                         string
 	NodeAndPodsExpr                        string
+	HonorKarpenterDoNotDisrupt             bool
+	LeaderLeaseNamePattern                 string
+	LeaderPodMaxWait                       time.Duration
+	// ProtectSystemNamespaces prevents the nodes hosting pods of DefaultSystemNamespaces and SystemNamespaces from being candidate
+	ProtectSystemNamespaces bool
+	// SystemNamespaces are protected in addition to DefaultSystemNamespaces
+	SystemNamespaces []string
+	// ProtectedPodCELExpr protects from eviction the pods matching this CEL expression
+	ProtectedPodCELExpr string
+	// EnablePercentage restricts the scope to this percentage of the nodes, selected by the hash of their name. 0 or 100 disables the restriction.
+	EnablePercentage int
+	// ProtectedNodeLabels exclude from the scope the nodes having one of these labels, given as KEY or KEY=VALUE
+	ProtectedNodeLabels []string
+	// UtilityPods is the treatment of the utility pods, UtilityPodsEvict or UtilityPodsIgnore. Disabled if empty.
+	UtilityPods string
+	// UtilityPodSidecarContainers are the names of the containers ignored when checking if a pod is a utility pod
+	UtilityPodSidecarContainers []string
+	// NodeStatusExpr restricts the scope to the nodes whose status matches this expression. Disabled if empty.
+	NodeStatusExpr string
 }
 
 type FiltersDefinitions struct {
@@ -42,6 +74,8 @@ type FiltersDefinitions struct {
 	CandidatePodFilter PodFilterFunc
 	// DrainPodFilter, Should the pod be drained ?
 	DrainPodFilter PodFilterFunc
+	// StatefulSetWithoutStoragePodFilter, Should the StatefulSet pod block the node without local storage for being candidate ? Nil if disabled.
+	StatefulSetWithoutStoragePodFilter PodFilterFunc
 
 	// NodeLabelFilter, Is the node eligible (label checks only) ?
 	NodeLabelFilter NodeLabelFilterFunc
@@ -54,6 +88,9 @@ func GenerateFilters(cs *kubernetes.Clientset, store RuntimeObjectStore, log *za
 	if !options.EvictLocalStoragePods {
 		pf = append(pf, LocalStoragePodFilter)
 	}
+	if !options.EvictHostPathPods {
+		pf = append(pf, HostPathPodFilter)
+	}
 
 	apiResources, err := GetAPIResourcesForGVK(cs, options.DoNotEvictPodControlledBy, log)
 	if err != nil {
@@ -69,11 +106,18 @@ func GenerateFilters(cs *kubernetes.Clientset, store RuntimeObjectStore, log *za
 		}
 		pf = append(pf, NewPodControlledByFilter(apiResources))
 	}
-	systemKnownAnnotations := []string{
-		// https://github.com/kubernetes/autoscaler/blob/master/cluster-autoscaler/FAQ.md#what-types-of-pods-can-prevent-ca-from-removing-a-node
-		"cluster-autoscaler.kubernetes.io/safe-to-evict=false",
+	systemKnownAnnotations := []string{ClusterAutoscalerSafeToEvictAnnotation}
+	if options.HonorKarpenterDoNotDisrupt {
+		systemKnownAnnotations = append(systemKnownAnnotations, KarpenterDoNotDisruptAnnotation)
 	}
 	pf = append(pf, UnprotectedPodFilter(store, false, append(systemKnownAnnotations, options.ProtectedPodAnnotations...)...))
+	if options.ProtectedPodCELExpr != "" {
+		protectedPodCELFilter, err := NewProtectedPodCELFilter(options.ProtectedPodCELExpr, log)
+		if err != nil {
+			return FiltersDefinitions{}, err
+		}
+		pf = append(pf, protectedPodCELFilter)
+	}
 
 	// Candidate Filtering
 	podFilterCandidate := []PodFilterFunc{}
@@ -94,14 +138,34 @@ func GenerateFilters(cs *kubernetes.Clientset, store RuntimeObjectStore, log *za
 		}
 		podFilterCandidate = append(podFilterCandidate, NewPodControlledByFilter(apiResourcesPodControllerBy))
 	}
-	podFilterCandidate = append(podFilterCandidate, UnprotectedPodFilter(store, true, options.CandidateProtectedPodAnnotations...))
+	candidateProtectedAnnotations := append([]string{}, options.CandidateProtectedPodAnnotations...)
+	if options.HonorKarpenterDoNotDisrupt {
+		candidateProtectedAnnotations = append(candidateProtectedAnnotations, KarpenterDoNotDisruptAnnotation)
+	}
+	podFilterCandidate = append(podFilterCandidate, UnprotectedPodFilter(store, true, candidateProtectedAnnotations...))
 
-	// To maintain compatibility with draino v1 version we have to exclude pods from STS running on node without local-storage
-	if options.ExcludeStatefulSetOnNodeWithoutStorage {
-		podFilterCandidate = append(podFilterCandidate, NewPodFiltersNoStatefulSetOnNodeWithoutDisk(store))
+	// Nodes hosting pods of the system namespaces are not candidate, with the reason reported by the candidate filter
+	podFilterCandidate = append(podFilterCandidate, systemNamespacePodFilters(options)...)
+
+	// Nodes hosting the leader of an application are not candidate until the leadership moves to another pod
+	if options.LeaderLeaseNamePattern != "" {
+		leaseNamePattern, err := regexp.Compile(options.LeaderLeaseNamePattern)
+		if err != nil {
+			return FiltersDefinitions{}, fmt.Errorf("failed to parse leader lease name pattern: %v", err)
+		}
+		podFilterCandidate = append(podFilterCandidate, NewLeaderPodFilter(NewCachedLeaseListFunc(cs), leaseNamePattern, options.LeaderPodMaxWait, clock.RealClock{}))
 	}
 
 	consolidatedOptInAnnotations := append(options.OptInPodAnnotations, options.ShortLivedPodAnnotations...)
+
+	// To maintain compatibility with draino v1 version we have to exclude pods from STS running on node without local-storage.
+	// This is done by a dedicated candidate filter, so that the rejection is clearly identified.
+	var statefulSetWithoutStoragePodFilter PodFilterFunc
+	if options.ExcludeStatefulSetOnNodeWithoutStorage {
+		statefulSetWithoutStoragePodFilter = NewPodFiltersIgnoreCompletedPods(
+			NewPodFiltersWithOptInFirst(
+				PodOrControllerHasAnyOfTheAnnotations(store, consolidatedOptInAnnotations...), NewPodFiltersNoStatefulSetOnNodeWithoutDisk(store)))
+	}
 	drainerSkipPodFilter := NewPodFiltersIgnoreCompletedPods(
 		NewPodFiltersIgnoreShortLivedPods(
 			NewPodFiltersWithOptInFirst(PodOrControllerHasAnyOfTheAnnotations(store, consolidatedOptInAnnotations...), NewPodFilters(pf...)),
@@ -111,6 +175,20 @@ func GenerateFilters(cs *kubernetes.Clientset, store RuntimeObjectStore, log *za
 		NewPodFiltersWithOptInFirst(
 			PodOrControllerHasAnyOfTheAnnotations(store, consolidatedOptInAnnotations...), NewPodFilters(podFilterCandidate...)))
 
+	// The utility pods are effectively done, they don't block the node from being candidate
+	switch options.UtilityPods {
+	case "":
+	case UtilityPodsEvict, UtilityPodsIgnore:
+		log.Info("utility pods handled", zap.String("treatment", options.UtilityPods), zap.Strings("sidecars", options.UtilityPodSidecarContainers))
+		drainerSkipPodFilter = NewPodFiltersUtilityPods(drainerSkipPodFilter, options.UtilityPodSidecarContainers, options.UtilityPods == UtilityPodsEvict)
+		podFilteringFunc = NewPodFiltersUtilityPods(podFilteringFunc, options.UtilityPodSidecarContainers, true)
+		if statefulSetWithoutStoragePodFilter != nil {
+			statefulSetWithoutStoragePodFilter = NewPodFiltersUtilityPods(statefulSetWithoutStoragePodFilter, options.UtilityPodSidecarContainers, true)
+		}
+	default:
+		return FiltersDefinitions{}, fmt.Errorf("unknown utility pods treatment %q", options.UtilityPods)
+	}
+
 	// Node filtering
 	if len(options.NodeLabels) > 0 {
 		log.Info("node labels", zap.Any("labels", options.NodeLabels))
@@ -128,6 +206,20 @@ func GenerateFilters(cs *kubernetes.Clientset, store RuntimeObjectStore, log *za
 	if err != nil {
 		return FiltersDefinitions{}, fmt.Errorf("Failed to parse node label expression: %v", err)
 	}
+	if options.NodeStatusExpr != "" {
+		log.Info("scope restricted by node status", zap.String("expr", options.NodeStatusExpr))
+		if nodeLabelFilterFunc, err = NewNodeStatusFilter(nodeLabelFilterFunc, options.NodeStatusExpr, log); err != nil {
+			return FiltersDefinitions{}, fmt.Errorf("failed to parse node status expression: %v", err)
+		}
+	}
+	if options.EnablePercentage > 0 && options.EnablePercentage < 100 {
+		log.Info("scope restricted to a percentage of the nodes", zap.Int("percentage", options.EnablePercentage))
+		nodeLabelFilterFunc = NewNodePercentageFilter(nodeLabelFilterFunc, options.EnablePercentage)
+	}
+	if len(options.ProtectedNodeLabels) > 0 {
+		log.Info("nodes with protected labels excluded from the scope", zap.Strings("labels", options.ProtectedNodeLabels))
+		nodeLabelFilterFunc = NewProtectedNodeLabelsFilter(nodeLabelFilterFunc, options.ProtectedNodeLabels)
+	}
 
 	nodeAndPodsFilterFunc, err := NewNodeAndPodsFilter(options.NodeAndPodsExpr, log)
 	if err != nil {
@@ -135,9 +227,19 @@ func GenerateFilters(cs *kubernetes.Clientset, store RuntimeObjectStore, log *za
 	}
 
 	return FiltersDefinitions{
-		CandidatePodFilter: podFilteringFunc,
-		DrainPodFilter:     drainerSkipPodFilter,
-		NodeLabelFilter:    nodeLabelFilterFunc,
-		NodeAndPodsFilter:  nodeAndPodsFilterFunc,
+		CandidatePodFilter:                 podFilteringFunc,
+		DrainPodFilter:                     drainerSkipPodFilter,
+		StatefulSetWithoutStoragePodFilter: statefulSetWithoutStoragePodFilter,
+		NodeLabelFilter:                    nodeLabelFilterFunc,
+		NodeAndPodsFilter:                  nodeAndPodsFilterFunc,
 	}, nil
 }
+
+// systemNamespacePodFilters returns the filter rejecting the pods of the system namespaces when their protection is enabled
+func systemNamespacePodFilters(options FilterOptions) []PodFilterFunc {
+	if !options.ProtectSystemNamespaces {
+		return nil
+	}
+	namespaces := append(append([]string{}, DefaultSystemNamespaces...), options.SystemNamespaces...)
+	return []PodFilterFunc{NewSystemNamespacePodFilter(namespaces...)}
+}
@@ -46,16 +46,16 @@ const (
 	SimulationFailed    SimulationResult = "failed"
 )
 
-func CounterSimulatedNodes(node *core.Node, result SimulationResult) {
-	values := kubernetes.GetNodeTagsValues(node)
+func CounterSimulatedNodes(node *core.Node, result SimulationResult, teamLabelKey string) {
+	values := kubernetes.GetNodeTagsValues(node, teamLabelKey)
 
 	tags := []string{string(result), values.NgName, kubernetes.GetNodeGroupNamePrefix(values.NgName), values.NgNamespace, values.Team, values.Service}
 	Metrics.SimulatedNodes.WithLabelValues(tags...).Add(1)
 }
 
-func CounterSimulatedPods(pod *core.Pod, node *core.Node, result SimulationResult, evictionURL bool) {
-	podValues := kubernetes.GetPodTagsValues(pod)
-	nodeValues := kubernetes.GetNodeTagsValues(node)
+func CounterSimulatedPods(pod *core.Pod, node *core.Node, result SimulationResult, evictionURL bool, teamLabelKey string) {
+	podValues := kubernetes.GetPodTagsValues(pod, teamLabelKey)
+	nodeValues := kubernetes.GetNodeTagsValues(node, teamLabelKey)
 	team := podValues.Team
 	if team == "" {
 		team = nodeValues.Team
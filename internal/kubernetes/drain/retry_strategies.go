@@ -1,7 +1,9 @@
 package drain
 
 import (
+	"fmt"
 	"math"
+	"strconv"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
@@ -11,6 +13,8 @@ import (
 
 const (
 	CustomRetryBackoffDelayAnnotation = "draino/retry-delay"
+	// CustomRetryThresholdAnnotation sets the alert threshold of the retry strategy of the node. It takes precedence over kubernetes.CustomRetryMaxAttemptAnnotation.
+	CustomRetryThresholdAnnotation = "draino/retry-threshold"
 )
 
 type RetryStrategy interface {
@@ -22,6 +26,13 @@ type RetryStrategy interface {
 	GetAlertThreashold() int
 }
 
+// ParameterizedRetryStrategy is a strategy that can be built again with the delay and alert threshold set on a node
+type ParameterizedRetryStrategy interface {
+	RetryStrategy
+	// WithParameters returns a strategy of the same kind using the given delay and alert threshold
+	WithParameters(delay time.Duration, alertThreashold int) RetryStrategy
+}
+
 // StaticRetryStrategy is a very simple strategy, which always returns the same delay
 type StaticRetryStrategy struct {
 	AlertThreashold int
@@ -42,6 +53,10 @@ func (strategy *StaticRetryStrategy) GetAlertThreashold() int {
 	return strategy.AlertThreashold
 }
 
+func (strategy *StaticRetryStrategy) WithParameters(delay time.Duration, alertThreashold int) RetryStrategy {
+	return &StaticRetryStrategy{AlertThreashold: alertThreashold, Delay: delay}
+}
+
 // ExponentialRetryStrategy is using the exponential backoff algorithm
 // retry 0 -> 0 delay
 // retry 1 -> 1 delay
@@ -74,6 +89,10 @@ func (strategy *ExponentialRetryStrategy) GetAlertThreashold() int {
 	return strategy.AlertThreashold
 }
 
+func (strategy *ExponentialRetryStrategy) WithParameters(delay time.Duration, alertThreashold int) RetryStrategy {
+	return &ExponentialRetryStrategy{AlertThreashold: alertThreashold, Delay: delay}
+}
+
 // NodeAnnotationRetryStrategy is parsing specific node annotations and using their values to take delay decisions.
 // If only one annotation is set it will use the given default strategy as fallback for the others
 type NodeAnnotationRetryStrategy struct {
@@ -82,9 +101,17 @@ type NodeAnnotationRetryStrategy struct {
 	DefaultStrategy RetryStrategy
 }
 
-var _ RetryStrategy = &NodeAnnotationRetryStrategy{}
+var (
+	_ RetryStrategy              = &NodeAnnotationRetryStrategy{}
+	_ ParameterizedRetryStrategy = &StaticRetryStrategy{}
+	_ ParameterizedRetryStrategy = &ExponentialRetryStrategy{}
+)
 
-func buildNodeAnnotationRetryStrategy(node *v1.Node, defaultStrategy RetryStrategy) (strategy RetryStrategy, funcErr error) {
+// buildNodeAnnotationRetryStrategy applies the delay and the alert threshold set on the node to the default strategy.
+// If the strategy is pinned with NodeRetryStrategyAnnotation, a ParameterizedRetryStrategy is built again with the node parameters,
+// so that an exponential strategy keeps growing from the node delay. Otherwise the node delay is used for every retry, as it
+// always was for the nodes carrying CustomRetryBackoffDelayAnnotation. The invalid values are ignored and reported in the error.
+func buildNodeAnnotationRetryStrategy(node *v1.Node, defaultStrategy RetryStrategy, pinned bool) (strategy RetryStrategy, funcErr error) {
 	nodeRetryStrategy := &NodeAnnotationRetryStrategy{DefaultStrategy: defaultStrategy}
 
 	attempts, useDefault, err := kubernetes.GetNodeRetryMaxAttempt(node)
@@ -95,17 +122,35 @@ func buildNodeAnnotationRetryStrategy(node *v1.Node, defaultStrategy RetryStrate
 		nodeRetryStrategy.AlertThreashold = &alertThreashold
 	}
 
+	if val, exist := node.Annotations[CustomRetryThresholdAnnotation]; exist {
+		threshold, err := strconv.Atoi(val)
+		if err != nil || threshold <= 0 {
+			funcErr = fmt.Errorf("%s must be a positive integer, ignoring the value '%s'", CustomRetryThresholdAnnotation, val)
+		} else {
+			nodeRetryStrategy.AlertThreashold = &threshold
+		}
+	}
+
 	if val, exist := node.Annotations[CustomRetryBackoffDelayAnnotation]; exist {
 		durationValue, err := time.ParseDuration(val)
 		if err != nil {
 			funcErr = err
+		} else if durationValue <= 0 {
+			funcErr = fmt.Errorf("%s must be a positive duration, ignoring the value '%s'", CustomRetryBackoffDelayAnnotation, val)
 		} else {
 			nodeRetryStrategy.Delay = &durationValue
 		}
 	}
 
-	strategy = nodeRetryStrategy
-	return
+	parameterized, ok := defaultStrategy.(ParameterizedRetryStrategy)
+	if !ok || !pinned || (nodeRetryStrategy.Delay == nil && nodeRetryStrategy.AlertThreashold == nil) {
+		return nodeRetryStrategy, funcErr
+	}
+	delay := defaultStrategy.GetDelay(1)
+	if nodeRetryStrategy.Delay != nil {
+		delay = *nodeRetryStrategy.Delay
+	}
+	return parameterized.WithParameters(delay, nodeRetryStrategy.GetAlertThreashold()), funcErr
 }
 
 func (_ *NodeAnnotationRetryStrategy) GetName() string {
@@ -123,7 +123,7 @@ func TestNodeAnnotationRetryStrategy(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
-			strategy, err := buildNodeAnnotationRetryStrategy(tt.Node, tt.DefaultStrategy)
+			strategy, err := buildNodeAnnotationRetryStrategy(tt.Node, tt.DefaultStrategy, false)
 
 			if tt.ShouldReturnError {
 				assert.Error(t, err)
@@ -16,7 +16,8 @@ import (
 )
 
 const (
-	// NodeRetryStrategyAnnotation annotation used to override the retry strategy on a node
+	// NodeRetryStrategyAnnotation annotation used to override the retry strategy on a node. The value is the name of a registered
+	// strategy, either its full name like "ExponentialRetryStrategy" or its short name like "exponential".
 	NodeRetryStrategyAnnotation string = "draino/retry-strategy"
 	// RetryWallConditionType the condition type used to save the drain failure count
 	RetryWallConditionType corev1.NodeConditionType = "DrainFailure"
@@ -79,9 +80,15 @@ func (wall *retryWallImpl) registerRetryStrategies(strategies ...RetryStrategy)
 	}
 	for _, strategy := range strategies {
 		wall.strategies[strategy.GetName()] = strategy
+		wall.strategies[retryStrategyShortName(strategy)] = strategy
 	}
 }
 
+// retryStrategyShortName returns the name of the strategy without the RetryStrategy suffix, in lower case: "ExponentialRetryStrategy" is "exponential"
+func retryStrategyShortName(strategy RetryStrategy) string {
+	return strings.ToLower(strings.TrimSuffix(strategy.GetName(), "RetryStrategy"))
+}
+
 func (wall *retryWallImpl) SetNewRetryWallTimestamp(ctx context.Context, node *corev1.Node, reason string, now time.Time) (*corev1.Node, error) {
 	retryCount, _, err := wall.getRetry(node)
 	if err != nil {
@@ -138,17 +145,18 @@ func (wall *retryWallImpl) IsAboveAlertingThreshold(node *corev1.Node) bool {
 
 func (wall *retryWallImpl) getStrategyFromNode(node *corev1.Node) RetryStrategy {
 	defaultStrategy := wall.defaultStrategy
+	pinned := false
 
 	if val, ok := node.Annotations[NodeRetryStrategyAnnotation]; ok {
 		strategy, ok := wall.strategies[val]
 		if ok {
-			defaultStrategy = strategy
+			defaultStrategy, pinned = strategy, true
 		} else {
 			wall.logger.Error(fmt.Errorf("cannot find node strategy '%s' in retry wall", val), "falling back to default retry strategy", "node", node.GetName(), "strategy", val, "default_strategy", defaultStrategy.GetName())
 		}
 	}
 
-	nodeAnnotationStrategy, err := buildNodeAnnotationRetryStrategy(node, defaultStrategy)
+	nodeAnnotationStrategy, err := buildNodeAnnotationRetryStrategy(node, defaultStrategy, pinned)
 	if err != nil {
 		wall.logger.Error(err, "node contains invalid retry wall configruation", "node", node.GetName(), "annotations", node.GetAnnotations())
 	}
@@ -224,3 +224,95 @@ func TestRetryWall_Unserialization(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryWall_getStrategyFromNode(t *testing.T) {
+	static := &StaticRetryStrategy{Delay: time.Minute, AlertThreashold: 7}
+	exponential := &ExponentialRetryStrategy{Delay: time.Minute, AlertThreashold: 7}
+	tests := []struct {
+		Name              string
+		Strategies        []RetryStrategy // static and exponential, in this order, if empty
+		Annotations       map[string]string
+		ExpectedThreshold int
+		ExpectedDelays    []time.Duration // delays of the retries 1, 2 and 3
+	}{
+		{
+			Name:              "default strategy",
+			ExpectedThreshold: 7,
+			ExpectedDelays:    []time.Duration{time.Minute, time.Minute, time.Minute},
+		},
+		{
+			Name:              "delay without strategy is used for every retry",
+			Strategies:        []RetryStrategy{exponential, static},
+			Annotations:       map[string]string{CustomRetryBackoffDelayAnnotation: "2m"},
+			ExpectedThreshold: 7,
+			ExpectedDelays:    []time.Duration{2 * time.Minute, 2 * time.Minute, 2 * time.Minute},
+		},
+		{
+			Name:              "strategy selected by short name",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "exponential"},
+			ExpectedThreshold: 7,
+			ExpectedDelays:    []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute},
+		},
+		{
+			Name:              "strategy selected by full name",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "ExponentialRetryStrategy"},
+			ExpectedThreshold: 7,
+			ExpectedDelays:    []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute},
+		},
+		{
+			Name:              "static strategy with parameters",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "static", CustomRetryBackoffDelayAnnotation: "2m", CustomRetryThresholdAnnotation: "5"},
+			ExpectedThreshold: 5,
+			ExpectedDelays:    []time.Duration{2 * time.Minute, 2 * time.Minute, 2 * time.Minute},
+		},
+		{
+			Name:              "exponential strategy with parameters",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "exponential", CustomRetryBackoffDelayAnnotation: "2m", CustomRetryThresholdAnnotation: "5"},
+			ExpectedThreshold: 5,
+			ExpectedDelays:    []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute},
+		},
+		{
+			Name:              "unknown strategy falls back to the default one",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "linear", CustomRetryThresholdAnnotation: "5"},
+			ExpectedThreshold: 5,
+			ExpectedDelays:    []time.Duration{time.Minute, time.Minute, time.Minute},
+		},
+		{
+			Name:              "malformed delay is ignored",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "exponential", CustomRetryBackoffDelayAnnotation: "2minutes", CustomRetryThresholdAnnotation: "5"},
+			ExpectedThreshold: 5,
+			ExpectedDelays:    []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute},
+		},
+		{
+			Name:              "negative delay is ignored",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "exponential", CustomRetryBackoffDelayAnnotation: "-2m"},
+			ExpectedThreshold: 7,
+			ExpectedDelays:    []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute},
+		},
+		{
+			Name:              "malformed threshold is ignored",
+			Annotations:       map[string]string{NodeRetryStrategyAnnotation: "exponential", CustomRetryBackoffDelayAnnotation: "2m", CustomRetryThresholdAnnotation: "0"},
+			ExpectedThreshold: 7,
+			ExpectedDelays:    []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			strategies := tt.Strategies
+			if len(strategies) == 0 {
+				strategies = []RetryStrategy{static, exponential}
+			}
+			wall, err := NewRetryWall(fake.NewClientBuilder().Build(), logr.Discard(), strategies...)
+			assert.NoError(t, err)
+
+			node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "foo-node", Annotations: tt.Annotations}}
+			strategy := wall.(*retryWallImpl).getStrategyFromNode(node)
+
+			assert.Equal(t, tt.ExpectedThreshold, strategy.GetAlertThreashold())
+			for i, delay := range tt.ExpectedDelays {
+				assert.Equal(t, delay, strategy.GetDelay(i+1), "retry %d", i+1)
+			}
+		})
+	}
+}
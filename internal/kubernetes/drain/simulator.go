@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/compute-go/logs"
 	"github.com/go-logr/logr"
+	"golang.org/x/exp/slices"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
@@ -34,17 +35,32 @@ const (
 
 	eventDrainSimulationFailed    = "DrainSimulationFailed"
 	eventEvictionSimulationFailed = "EvictionSimulationFailed"
+
+	nodeReasonFmt       = "Cannot drain pod '%s/%s', because: %v"
+	pdbBlockedReasonFmt = "PDB '%s' does not allow any disruptions"
 )
 
 type DrainSimulator interface {
 	// SimulateDrain will simulate a drain for the given node.
 	// This means that it will perform an eviction simulation of all pods running on the node.
 	SimulateDrain(context.Context, *corev1.Node) (canEvict bool, reasons []string, err []error)
+	// ReportDrain simulates the drain of the given node like SimulateDrain, without emitting events nor recording metrics,
+	// so that it can be called for reporting. The PDBs blocking the evictions are returned with the reasons.
+	ReportDrain(context.Context, *corev1.Node) DrainSimulationReport
 	// SimulatePodDrain will simulate a drain of the given pod.
 	// Before calling the API server it will make sure that some of the obvious problems are not given.
 	SimulatePodDrain(context.Context, *corev1.Pod) (canEvict bool, reason string, err error)
 }
 
+// DrainSimulationReport is the result of the drain simulation of a node returned by ReportDrain
+type DrainSimulationReport struct {
+	CanDrain bool
+	Reasons  []string
+	Errors   []error
+	// BlockingPDBs are the PDBs, as "<namespace>/<name>", that do not allow the eviction of pods of the node
+	BlockingPDBs []string
+}
+
 type drainSimulatorImpl struct {
 	pdbIndexer         index.PDBIndexer
 	podIndexer         index.PodIndexer
@@ -63,6 +79,8 @@ type simulationResult struct {
 	result bool
 	reason string
 	err    error
+	// blockingPDB is the PDB, as "<namespace>/<name>", that does not allow the eviction of the pod
+	blockingPDB string
 }
 
 var _ DrainSimulator = &drainSimulatorImpl{}
@@ -98,12 +116,22 @@ func NewDrainSimulator(
 }
 
 func (sim *drainSimulatorImpl) SimulateDrain(ctx context.Context, node *corev1.Node) (bool, []string, []error) {
+	report := sim.simulateDrain(ctx, node, true)
+	return report.CanDrain, report.Reasons, report.Errors
+}
+
+func (sim *drainSimulatorImpl) ReportDrain(ctx context.Context, node *corev1.Node) DrainSimulationReport {
+	return sim.simulateDrain(ctx, node, false)
+}
+
+// simulateDrain simulates the drain of the node, the events and the metrics are only recorded withSideEffects
+func (sim *drainSimulatorImpl) simulateDrain(ctx context.Context, node *corev1.Node, withSideEffects bool) DrainSimulationReport {
 	span, ctx := tracer.StartSpanFromContext(ctx, "SimulateNodeDrain")
 	defer span.Finish()
 
 	pods, err := sim.podIndexer.GetPodsByNode(ctx, node.GetName())
 	if err != nil {
-		return false, nil, []error{err}
+		return DrainSimulationReport{Errors: []error{err}}
 	}
 
 	// stable pod sort for stable reason / error construction
@@ -115,40 +143,49 @@ func (sim *drainSimulatorImpl) SimulateDrain(ctx context.Context, node *corev1.N
 
 	// As we are  caching the positive results for one minute and negative ones for three minutes, we might make a lot of unneeded API calls
 	// As an optimization we are iterating over all pods and check if at least one has a negative cache entry, before simulating the drain for all the pods.
-	var reasons []string
-	var errors []error
+	var report DrainSimulationReport
 	for _, pod := range pods {
 		if res, exist := sim.podResultCache.Get(createCacheKey(pod), time.Now()); exist && !res.result {
-			reasons = append(reasons, sim.nodeReasonFromPodReason(pod, res.reason))
-			if res.err != nil {
-				errors = append(errors, res.err)
-			}
+			report.add(sim.nodeReasonFromPodReason(pod, res.reason), res)
 		}
 	}
-	if len(reasons) > 0 || len(errors) > 0 {
-		sim.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, eventDrainSimulationFailed, "Drain simulation failed: "+strings.Join(reasons, "; "))
-		return false, reasons, errors
+	if len(report.Reasons) > 0 || len(report.Errors) > 0 {
+		if withSideEffects {
+			sim.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, eventDrainSimulationFailed, "Drain simulation failed: "+strings.Join(report.Reasons, "; "))
+		}
+		return report
 	}
 
 	for _, pod := range pods {
-		canEvict, reason, err := sim.SimulatePodDrain(ctx, pod)
-		if !canEvict {
-			reasons = append(reasons, sim.nodeReasonFromPodReason(pod, reason))
-			if err != nil {
-				errors = append(errors, err)
-			}
+		res := sim.simulatePodDrain(ctx, pod, withSideEffects)
+		if !res.result {
+			report.add(sim.nodeReasonFromPodReason(pod, res.reason), res)
+		}
+		if withSideEffects {
+			CounterSimulatedPods(pod, node, simResult(res.result), sim.usesOperatorAPI(pod), sim.globalConfig.TeamLabelKey)
 		}
-		CounterSimulatedPods(pod, node, simResult(canEvict), sim.usesOperatorAPI(pod))
 	}
 
 	// 0 reasons means the simulation succeeded
-	CounterSimulatedNodes(node, simResult(len(reasons) == 0))
-	if len(reasons) > 0 {
-		sim.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, eventDrainSimulationFailed, "Drain simulation failed: "+strings.Join(reasons, "; "))
-		return false, reasons, errors
+	report.CanDrain = len(report.Reasons) == 0
+	if withSideEffects {
+		CounterSimulatedNodes(node, simResult(report.CanDrain), sim.globalConfig.TeamLabelKey)
+		if !report.CanDrain {
+			sim.eventRecorder.NodeEventf(ctx, node, corev1.EventTypeWarning, eventDrainSimulationFailed, "Drain simulation failed: "+strings.Join(report.Reasons, "; "))
+		}
 	}
+	return report
+}
 
-	return true, nil, errors
+// add records the negative result of the simulation of a pod
+func (report *DrainSimulationReport) add(reason string, res simulationResult) {
+	report.Reasons = append(report.Reasons, reason)
+	if res.err != nil {
+		report.Errors = append(report.Errors, res.err)
+	}
+	if res.blockingPDB != "" && !slices.Contains(report.BlockingPDBs, res.blockingPDB) {
+		report.BlockingPDBs = append(report.BlockingPDBs, res.blockingPDB)
+	}
 }
 
 func simResult(canDrain bool) SimulationResult {
@@ -159,48 +196,54 @@ func simResult(canDrain bool) SimulationResult {
 }
 
 func (sim *drainSimulatorImpl) nodeReasonFromPodReason(pod *corev1.Pod, reason string) string {
-	return fmt.Sprintf("Cannot drain pod '%s/%s', because: %v", pod.GetNamespace(), pod.GetName(), reason)
+	return fmt.Sprintf(nodeReasonFmt, pod.GetNamespace(), pod.GetName(), reason)
 }
 
 func (sim *drainSimulatorImpl) SimulatePodDrain(ctx context.Context, pod *corev1.Pod) (bool, string, error) {
+	res := sim.simulatePodDrain(ctx, pod, true)
+	return res.result, res.reason, res.err
+}
+
+// simulatePodDrain simulates the eviction of the pod, the events are only emitted withSideEffects
+func (sim *drainSimulatorImpl) simulatePodDrain(ctx context.Context, pod *corev1.Pod, withSideEffects bool) simulationResult {
 	span, ctx := tracer.StartSpanFromContext(ctx, "SimulatePodDrain")
 	defer span.Finish()
 
 	if res, exist := sim.podResultCache.Get(createCacheKey(pod), time.Now()); exist {
-		return res.result, res.reason, res.err
+		return res
 	}
 
 	passes, reason, err := sim.skipPodFilter(*pod)
 	if err != nil {
-		return false, reason, err
+		return simulationResult{result: false, reason: reason, err: err}
 	}
 	if !passes {
 		// If the pod does not pass the filter, it means that it will be accepted by default
-		sim.writePodCache(pod, true, reason, nil)
-		return true, reason, nil
+		sim.writePodCache(pod, simulationResult{result: true, reason: reason})
+		return simulationResult{result: true, reason: reason}
 	}
 
 	// if using eviction++ but not opted-in for dry-run, pass simulation early
 	// once all teams are opted-in and dry-run is required, this can be removed
 	if !sim.operatorAPIDryRunEnabled(pod) {
-		return true, "", nil
+		return simulationResult{result: true}
 	}
 
 	// if eviction++ is used, skip pdb checks
 	if !sim.usesOperatorAPI(pod) {
-		if passes, reason, err := sim.checkPDBs(ctx, pod); !passes {
-			return passes, reason, err
+		if res := sim.checkPDBs(ctx, pod, withSideEffects); !res.result {
+			return res
 		}
 	}
 
 	if !sim.rateLimiter.TryAccept() {
 		sim.logger.V(logs.ZapDebug).Info("Drain simulation aborted due to rate limiting.")
-		return false, "simulation rate limit", &k8sclient.ClientSideRateLimit{}
+		return simulationResult{result: false, reason: "simulation rate limit", err: &k8sclient.ClientSideRateLimit{}}
 	}
 
 	var node corev1.Node
 	if err := sim.client.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, &node); err != nil {
-		return false, "node not fouund", err
+		return simulationResult{result: false, reason: "node not fouund", err: err}
 	}
 	// do a dry-run eviction call
 	evictionDryRunRes, err := sim.simulateAPIEviction(ctx, pod, &node)
@@ -214,47 +257,54 @@ func (sim *drainSimulatorImpl) SimulatePodDrain(ctx context.Context, pod *corev1
 		if apierrors.IsTooManyRequests(err) {
 			err = nil
 		}
-		sim.writePodCache(pod, false, reason, err)
-		sim.eventRecorder.PodEventf(ctx, pod, corev1.EventTypeWarning, eventEvictionSimulationFailed, reason)
-		return false, reason, err
+		res := simulationResult{result: false, reason: reason, err: err}
+		sim.writePodCache(pod, res)
+		if withSideEffects {
+			sim.eventRecorder.PodEventf(ctx, pod, corev1.EventTypeWarning, eventEvictionSimulationFailed, reason)
+		}
+		return res
 	}
 
-	sim.writePodCache(pod, true, "", nil)
-	return true, "", nil
+	sim.writePodCache(pod, simulationResult{result: true})
+	return simulationResult{result: true}
 }
 
-func (sim *drainSimulatorImpl) checkPDBs(ctx context.Context, pod *corev1.Pod) (bool, string, error) {
-	var reason string
+func (sim *drainSimulatorImpl) checkPDBs(ctx context.Context, pod *corev1.Pod, withSideEffects bool) simulationResult {
 	pdbs, err := sim.pdbIndexer.GetPDBsForPods(ctx, []*corev1.Pod{pod})
 	if err != nil {
-		return false, "failed to fetch pod PDB", err
+		return simulationResult{result: false, reason: "failed to fetch pod PDB", err: err}
 	}
 
 	// If there is more than one PDB associated to the given pod, the eviction will fail for sure due to the APIServer behaviour.
 	podKey := index.GeneratePodIndexKey(pod.GetName(), pod.GetNamespace())
 	if len(pdbs[podKey]) > 1 {
-		reason = fmt.Sprintf("Pod has more than one associated PDB: %s", strings.Join(utils.GetPDBNames(pdbs[podKey]), ";"))
-		sim.writePodCache(pod, false, reason, nil)
-		sim.eventRecorder.PodEventf(ctx, pod, corev1.EventTypeWarning, eventEvictionSimulationFailed, reason)
-		return false, reason, nil
+		res := simulationResult{result: false, reason: fmt.Sprintf("Pod has more than one associated PDB: %s", strings.Join(utils.GetPDBNames(pdbs[podKey]), ";"))}
+		sim.writePodCache(pod, res)
+		if withSideEffects {
+			sim.eventRecorder.PodEventf(ctx, pod, corev1.EventTypeWarning, eventEvictionSimulationFailed, res.reason)
+		}
+		return res
 	}
 
 	// If there is a matching PDB, check if it would allow disruptions
 	if len(pdbs[podKey]) == 1 {
 		pdb := pdbs[podKey][0]
 		if analyser.IsPDBBlockedByPod(ctx, pod, pdb) {
-			reason = fmt.Sprintf("PDB '%s' does not allow any disruptions", pdb.GetName())
+			reason := fmt.Sprintf(pdbBlockedReasonFmt, pdb.GetName())
 			if pdb.Annotations != nil {
 				if budgetCutReason, ok := pdb.Annotations[BudgetCutReasonAnnotationKey]; ok {
 					reason = fmt.Sprintf("%s, reason: %s", reason, budgetCutReason)
 				}
 			}
-			sim.writePodCache(pod, false, reason, nil)
-			sim.eventRecorder.PodEventf(ctx, pod, corev1.EventTypeWarning, eventEvictionSimulationFailed, reason)
-			return false, reason, nil
+			res := simulationResult{result: false, reason: reason, blockingPDB: pdb.GetNamespace() + "/" + pdb.GetName()}
+			sim.writePodCache(pod, res)
+			if withSideEffects {
+				sim.eventRecorder.PodEventf(ctx, pod, corev1.EventTypeWarning, eventEvictionSimulationFailed, reason)
+			}
+			return res
 		}
 	}
-	return true, "", nil
+	return simulationResult{result: true}
 }
 
 func (sim *drainSimulatorImpl) simulateAPIEviction(ctx context.Context, pod *corev1.Pod, node *corev1.Node) (bool, error) {
@@ -319,12 +369,12 @@ func (sim *drainSimulatorImpl) simulateWithOperatorAPI(ctx context.Context, evic
 	return true, nil
 }
 
-func (sim *drainSimulatorImpl) writePodCache(pod *corev1.Pod, result bool, reason string, err error) {
+func (sim *drainSimulatorImpl) writePodCache(pod *corev1.Pod, res simulationResult) {
 	ttl := NegativeCacheResTTL
-	if result {
+	if res.result {
 		ttl = PositiveCacheResTTL
 	}
-	sim.podResultCache.AddCustomTTL(createCacheKey(pod), simulationResult{result: result, reason: reason, err: err}, ttl)
+	sim.podResultCache.AddCustomTTL(createCacheKey(pod), res, ttl)
 }
 
 func createCacheKey(pod *corev1.Pod) string {
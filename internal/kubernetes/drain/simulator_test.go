@@ -268,3 +268,30 @@ func intStrPtr(val int) *intstr.IntOrString {
 	res := intstr.FromInt(val)
 	return &res
 }
+
+func TestSimulator_ReportDrain(t *testing.T) {
+	testLabels := map[string]string{
+		"app": "foo",
+	}
+	node := corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "foo-node"}}
+	ch := make(chan struct{})
+	defer close(ch)
+	simulator, err := NewFakeDrainSimulator(
+		&FakeSimulatorOptions{
+			Chan: ch,
+			Objects: []runtime.Object{
+				&node,
+				createPod(createPodOpts{Name: "foo-pod1", Labels: testLabels, NodeName: "foo-node"}),
+				createPod(createPodOpts{Name: "foo-pod2", Labels: testLabels, NodeName: "foo-node"}),
+				createPDB(createPDBOpts{Name: "foo-pdb", Labels: testLabels, Des: 2, Healthy: 1}),
+			},
+			PodFilter: noopPodFilter,
+		},
+	)
+	assert.NoError(t, err)
+
+	report := simulator.ReportDrain(context.Background(), &node)
+	assert.False(t, report.CanDrain)
+	assert.Len(t, report.Reasons, 2)
+	assert.Equal(t, []string{"default/foo-pdb"}, report.BlockingPDBs, "the PDB is reported once")
+}
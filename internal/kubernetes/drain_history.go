@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DrainHistoryAnnotationKey holds the JSON list of the last drain attempts done on the node
+	DrainHistoryAnnotationKey = "draino/drain-history"
+	// DrainHistoryMaxEntries is the number of attempts kept in the history, the oldest ones are dropped first
+	DrainHistoryMaxEntries = 10
+	// drainHistoryMaxReasonLength keeps the annotation well under the 256KB limit of the annotations, even with long error messages
+	drainHistoryMaxReasonLength = 512
+)
+
+// DrainHistoryEntry is a single drain attempt recorded in the node history
+type DrainHistoryEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Result    string    `json:"result"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// GetDrainHistory returns the drain attempts recorded on the node, from the oldest to the newest
+func GetDrainHistory(n *core.Node) ([]DrainHistoryEntry, error) {
+	value, ok := n.Annotations[DrainHistoryAnnotationKey]
+	if !ok || value == "" {
+		return nil, nil
+	}
+	var history []DrainHistoryEntry
+	if err := json.Unmarshal([]byte(value), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// AppendDrainHistory adds the entry at the end of the history and drops the oldest entries above DrainHistoryMaxEntries
+func AppendDrainHistory(history []DrainHistoryEntry, entry DrainHistoryEntry) []DrainHistoryEntry {
+	if len(entry.Reason) > drainHistoryMaxReasonLength {
+		// cut before the rune crossing the limit so that the reason stays valid UTF-8
+		cut := drainHistoryMaxReasonLength
+		for cut > 0 && !utf8.RuneStart(entry.Reason[cut]) {
+			cut--
+		}
+		entry.Reason = entry.Reason[:cut]
+	}
+	history = append(history, entry)
+	if len(history) > DrainHistoryMaxEntries {
+		history = history[len(history)-DrainHistoryMaxEntries:]
+	}
+	return history
+}
+
+// drainHistoryAnnotationValue returns the annotation value of the node history including the new entry.
+// A corrupted history is replaced rather than blocking the recording of new attempts.
+func drainHistoryAnnotationValue(n *core.Node, entry DrainHistoryEntry) (string, error) {
+	history, err := GetDrainHistory(n)
+	if err != nil {
+		history = nil
+	}
+	value, err := json.Marshal(AppendDrainHistory(history, entry))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// RecordDrainHistory patches the node annotation to add the given attempt to the drain history, it returns the patched node.
+// The patch holds the resource version of the node so that a concurrent update of the history conflicts instead of being overwritten,
+// the history is then read again from the latest version of the node.
+func RecordDrainHistory(ctx context.Context, c client.Client, n *core.Node, entry DrainHistoryEntry) (*core.Node, error) {
+	node := n
+	var patched *core.Node
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		value, err := drainHistoryAnnotationValue(node, entry)
+		if err != nil {
+			return err
+		}
+		updated := node.DeepCopy()
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[DrainHistoryAnnotationKey] = value
+		if err := c.Patch(ctx, updated, client.MergeFromWithOptions(node, client.MergeFromWithOptimisticLock{})); err != nil {
+			if apierrors.IsConflict(err) {
+				var latest core.Node
+				if errGet := c.Get(ctx, client.ObjectKeyFromObject(node), &latest); errGet != nil {
+					return errGet
+				}
+				node = &latest
+			}
+			return err
+		}
+		patched = updated
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
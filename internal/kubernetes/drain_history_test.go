@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAppendDrainHistory(t *testing.T) {
+	start := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var history []DrainHistoryEntry
+	for i := 0; i < DrainHistoryMaxEntries+3; i++ {
+		history = AppendDrainHistory(history, DrainHistoryEntry{Timestamp: start.Add(time.Duration(i) * time.Minute), Result: FailedStr})
+	}
+
+	assert.Len(t, history, DrainHistoryMaxEntries)
+	// the 3 oldest entries must have been dropped
+	assert.True(t, start.Add(3*time.Minute).Equal(history[0].Timestamp))
+	assert.True(t, start.Add(time.Duration(DrainHistoryMaxEntries+2)*time.Minute).Equal(history[len(history)-1].Timestamp))
+
+	history = AppendDrainHistory(nil, DrainHistoryEntry{Timestamp: start, Result: FailedStr, Reason: strings.Repeat("x", 2*drainHistoryMaxReasonLength)})
+	assert.Len(t, history[0].Reason, drainHistoryMaxReasonLength)
+
+	// the 2 bytes runes would be split at the limit
+	history = AppendDrainHistory(nil, DrainHistoryEntry{Timestamp: start, Result: FailedStr, Reason: "x" + strings.Repeat("é", drainHistoryMaxReasonLength)})
+	assert.Len(t, history[0].Reason, drainHistoryMaxReasonLength-1)
+	assert.True(t, utf8.ValidString(history[0].Reason))
+}
+
+func TestRecordDrainHistory(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		concurrentEntry string
+		expectedResults []string
+	}{
+		{
+			name:            "no history",
+			expectedResults: []string{CompletedStr},
+		},
+		{
+			name:            "existing history",
+			annotations:     map[string]string{DrainHistoryAnnotationKey: `[{"timestamp":"2023-01-01T11:00:00Z","result":"Failed","reason":"pdb"}]`},
+			expectedResults: []string{FailedStr, CompletedStr},
+		},
+		{
+			name:            "corrupted history is replaced",
+			annotations:     map[string]string{DrainHistoryAnnotationKey: `not-json`},
+			expectedResults: []string{CompletedStr},
+		},
+		{
+			name:            "history updated concurrently is kept",
+			annotations:     map[string]string{DrainHistoryAnnotationKey: `[{"timestamp":"2023-01-01T11:00:00Z","result":"Failed","reason":"pdb"}]`},
+			concurrentEntry: FailedStr,
+			expectedResults: []string{FailedStr, FailedStr, CompletedStr},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node-1", Annotations: tt.annotations}}
+			kclient := fake.NewFakeClient(node)
+			if tt.concurrentEntry != "" {
+				// the given node is now outdated
+				_, err := RecordDrainHistory(context.Background(), kclient, node, DrainHistoryEntry{Timestamp: now.Add(-time.Minute), Result: tt.concurrentEntry})
+				assert.NoError(t, err)
+			}
+
+			_, err := RecordDrainHistory(context.Background(), kclient, node, DrainHistoryEntry{Timestamp: now, Result: CompletedStr})
+			assert.NoError(t, err)
+
+			var updated core.Node
+			assert.NoError(t, kclient.Get(context.Background(), client.ObjectKeyFromObject(node), &updated))
+			raw := updated.Annotations[DrainHistoryAnnotationKey]
+			assert.True(t, json.Valid([]byte(raw)), "history annotation is not valid JSON: %s", raw)
+
+			history, err := GetDrainHistory(&updated)
+			assert.NoError(t, err)
+			results := make([]string, 0, len(history))
+			for _, entry := range history {
+				results = append(results, entry.Result)
+			}
+			assert.Equal(t, tt.expectedResults, results)
+			assert.True(t, now.Equal(history[len(history)-1].Timestamp))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package kubernetes
+
+import core "k8s.io/api/core/v1"
+
+const (
+	// DefaultDrainNowAnnotationKey is the default annotation that can be set to "true" on a node for an emergency evacuation.
+	// The node is drained right away, bypassing the candidate gating (conditions, stability period, drain buffer, slots) and the
+	// wait before draining. The retry wall, the drain simulation and the eviction API still apply.
+	DefaultDrainNowAnnotationKey = "draino.planet.com/drain-now"
+	DrainNowAnnotationValue      = "true"
+)
+
+// DrainNowConfig tells which annotations request the immediate drain of a node
+type DrainNowConfig struct {
+	// AnnotationKey is the annotation set to "true" on a node for an emergency evacuation. The request is disabled if empty.
+	AnnotationKey string
+	// SpotTerminationAnnotationKey is the annotation set by a node agent on a spot node that is about to be reclaimed by the cloud
+	// provider. A node holding it, whatever its value, is drained right away as if it had the drain-now annotation.
+	// The recognition of the termination notice is disabled if empty.
+	SpotTerminationAnnotationKey string
+}
+
+// HasAnnotation returns true if the node holds the annotation requesting an immediate drain
+func (c DrainNowConfig) HasAnnotation(node *core.Node) bool {
+	return c.AnnotationKey != "" && node != nil && node.Annotations[c.AnnotationKey] == DrainNowAnnotationValue
+}
+
+// IsRequested returns true if the node holds the annotation requesting an immediate drain or a spot termination notice
+func (c DrainNowConfig) IsRequested(node *core.Node) bool {
+	return c.HasAnnotation(node) || c.IsSpotTerminationNoticed(node)
+}
+
+// IsSpotTerminationNoticed returns true if the node holds the spot termination notice annotation
+func (c DrainNowConfig) IsSpotTerminationNoticed(node *core.Node) bool {
+	return hasSpotTerminationNotice(c.SpotTerminationAnnotationKey, node)
+}
+
+func hasSpotTerminationNotice(annotationKey string, node *core.Node) bool {
+	if annotationKey == "" || node == nil {
+		return false
+	}
+	_, ok := node.Annotations[annotationKey]
+	return ok
+}
@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type drainStatsKey struct{}
+
+// DrainStats counts what the drain did on the node. The drainers fill the stats found in the context of the drain,
+// the evictions run concurrently so the counters are atomic.
+type DrainStats struct {
+	podsEvicted atomic.Int32
+	pvcsDeleted atomic.Int32
+}
+
+// ContextWithDrainStats returns a context collecting the stats of the drains run with it
+func ContextWithDrainStats(ctx context.Context) (context.Context, *DrainStats) {
+	stats := &DrainStats{}
+	return context.WithValue(ctx, drainStatsKey{}, stats), stats
+}
+
+// DrainStatsFromContext returns the stats to fill, nil if the caller doesn't collect them
+func DrainStatsFromContext(ctx context.Context) *DrainStats {
+	stats, _ := ctx.Value(drainStatsKey{}).(*DrainStats)
+	return stats
+}
+
+// PodsEvicted is the number of pods evicted by the drain
+func (s *DrainStats) PodsEvicted() int {
+	return int(s.podsEvicted.Load())
+}
+
+// PVCsDeleted is the number of PVCs deleted after the eviction of their pods
+func (s *DrainStats) PVCsDeleted() int {
+	return int(s.pvcsDeleted.Load())
+}
+
+// AddPodsEvicted counts pods evicted by the drain
+func (s *DrainStats) AddPodsEvicted(n int) {
+	s.podsEvicted.Add(int32(n))
+}
+
+// AddPVCsDeleted counts PVCs deleted by the drain
+func (s *DrainStats) AddPVCsDeleted(n int) {
+	s.pvcsDeleted.Add(int32(n))
+}
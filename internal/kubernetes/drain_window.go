@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// DrainWindowAnnotationKey defers the drain of the node until the time is inside the window, for example "Mon-Fri:09:00-17:00".
+	// The days are a range or a comma separated list and the times are expressed in UTC. A window ending before its start spans midnight.
+	DrainWindowAnnotationKey = "draino/drain-window"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// DrainWindow is a weekly time window
+type DrainWindow struct {
+	Days  [7]bool
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseDrainWindow parses a window in the format "<days>:<HH:MM>-<HH:MM>"
+func ParseDrainWindow(value string) (*DrainWindow, error) {
+	idx := strings.Index(value, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid drain window %q: expecting <days>:<HH:MM>-<HH:MM>", value)
+	}
+	var window DrainWindow
+	if err := parseWindowDays(value[:idx], &window.Days); err != nil {
+		return nil, fmt.Errorf("invalid drain window %q: %w", value, err)
+	}
+	times := strings.Split(value[idx+1:], "-")
+	if len(times) != 2 {
+		return nil, fmt.Errorf("invalid drain window %q: expecting <HH:MM>-<HH:MM>", value)
+	}
+	var err error
+	if window.Start, err = parseTimeOfDay(times[0]); err != nil {
+		return nil, fmt.Errorf("invalid drain window %q: %w", value, err)
+	}
+	if window.End, err = parseTimeOfDay(times[1]); err != nil {
+		return nil, fmt.Errorf("invalid drain window %q: %w", value, err)
+	}
+	if window.Start == window.End {
+		return nil, fmt.Errorf("invalid drain window %q: empty time range", value)
+	}
+	return &window, nil
+}
+
+func parseWindowDays(value string, days *[7]bool) error {
+	for _, item := range strings.Split(value, ",") {
+		bounds := strings.Split(item, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid days %q", item)
+		}
+		first, ok := weekdays[strings.ToLower(strings.TrimSpace(bounds[0]))]
+		if !ok {
+			return fmt.Errorf("unknown day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdays[strings.ToLower(strings.TrimSpace(bounds[1]))]; !ok {
+				return fmt.Errorf("unknown day %q", bounds[1])
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q: expecting HH:MM", value)
+	}
+	hours, errH := strconv.Atoi(parts[0])
+	minutes, errM := strconv.Atoi(parts[1])
+	if errH != nil || errM != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time %q: expecting HH:MM", value)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// Contains returns true if the given time is inside the window
+func (w *DrainWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	timeOfDay := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	if w.Start < w.End {
+		return w.Days[t.Weekday()] && timeOfDay >= w.Start && timeOfDay < w.End
+	}
+	// the window spans midnight, the part after midnight belongs to the previous day
+	previousDay := (t.Weekday() + 6) % 7
+	return (w.Days[t.Weekday()] && timeOfDay >= w.Start) || (w.Days[previousDay] && timeOfDay < w.End)
+}
+
+// GetNodeDrainWindow returns the drain window set on the node, if any
+func GetNodeDrainWindow(node *core.Node) (*DrainWindow, bool, error) {
+	value, ok := node.GetAnnotations()[DrainWindowAnnotationKey]
+	if !ok {
+		return nil, false, nil
+	}
+	window, err := ParseDrainWindow(value)
+	if err != nil {
+		return nil, false, err
+	}
+	return window, true, nil
+}
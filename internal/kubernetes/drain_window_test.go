@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDrainWindow(t *testing.T) {
+	// 2023-03-15 is a Wednesday
+	wednesday := func(hour, minute int) time.Time {
+		return time.Date(2023, 3, 15, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		at      time.Time
+		inside  bool
+		wantErr bool
+	}{
+		{name: "inside business hours", value: "Mon-Fri:09:00-17:00", at: wednesday(10, 0), inside: true},
+		{name: "before business hours", value: "Mon-Fri:09:00-17:00", at: wednesday(8, 59), inside: false},
+		{name: "end is excluded", value: "Mon-Fri:09:00-17:00", at: wednesday(17, 0), inside: false},
+		{name: "day not listed", value: "Mon,Tue:09:00-17:00", at: wednesday(10, 0), inside: false},
+		{name: "range over the week end", value: "Sat-Wed:09:00-17:00", at: wednesday(10, 0), inside: true},
+		{name: "window spanning midnight", value: "Tue:22:00-02:00", at: wednesday(1, 0), inside: true},
+		{name: "window spanning midnight, after its end", value: "Tue:22:00-02:00", at: wednesday(3, 0), inside: false},
+		{name: "whole day", value: "Wed:00:00-24:00", at: wednesday(23, 59), inside: true},
+		{name: "unknown day", value: "Someday:09:00-17:00", wantErr: true},
+		{name: "missing times", value: "Mon-Fri", wantErr: true},
+		{name: "invalid time", value: "Mon-Fri:09:00-25:00", wantErr: true},
+		{name: "empty range", value: "Mon-Fri:09:00-09:00", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := ParseDrainWindow(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.inside, window.Contains(tt.at))
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,6 +39,7 @@ import (
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
 
 	"github.com/DataDog/go-service-authn/pkg/serviceauthentication/authnclient"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
 	"go.uber.org/zap"
 	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
@@ -45,6 +47,7 @@ import (
 	core "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -55,6 +58,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 // Default pod eviction settings.
@@ -69,9 +73,12 @@ const (
 	DefaultPVCRecreateTimeout           = 3 * time.Minute
 	DefaultPodDeletePeriodWaitingForPVC = 10 * time.Second
 	awaitPVCDeletionTimeout             = time.Minute
+	replacementPodPollPeriod            = 5 * time.Second
 
 	KindDaemonSet   = "DaemonSet"
 	KindStatefulSet = "StatefulSet"
+	KindReplicaSet  = "ReplicaSet"
+	KindDeployment  = "Deployment"
 
 	ConditionDrainedScheduled = "DrainScheduled"
 	DefaultSkipDrain          = false
@@ -90,17 +97,13 @@ const (
 	NodeLabelValueReplaceDone      = "done"
 	NodeLabelValueReplaceFailed    = "failed"
 
-	eventReasonEvictionStarting      = "EvictionStarting"
-	eventReasonEvictionSucceeded     = "EvictionSucceeded"
-	eventReasonEvictionFailed        = "EvictionFailed"
-	eventReasonEvictionAttemptFailed = "EvictionAttemptFailed"
-
-	eventReasonBadValueForAnnotation = "BadValueForAnnotation"
-
 	EvictionAPIURLAnnotationKeyDeprecated    = "draino/eviction-api-url"
 	EvictionAPIURLAnnotationKey              = "node-lifecycle.datadoghq.com/eviction-api-url"
 	EvictionAPIDryRunSupportedAnnotationKey  = "node-lifecycle.datadoghq.com/eviction-api-dry-run-supported"
 	EvictionAPIDryRunSupportedAnnotationTrue = "true"
+
+	EvictionAPIVersionV1      = "v1"
+	EvictionAPIVersionV1beta1 = "v1beta1"
 )
 
 type nodeMutatorFn func(*core.Node)
@@ -125,6 +128,17 @@ func (e EvictionEndpointError) Error() string {
 	return msg
 }
 
+func (e EvictionEndpointError) Cause() FailureCause {
+	cause := "eviction_endpoint"
+	if e.IsRequestTimeout {
+		cause += "_request_timeout"
+	}
+	if e.StatusCode > 0 {
+		cause += fmt.Sprintf("_%d", e.StatusCode)
+	}
+	return FailureCause(cause)
+}
+
 type AudienceNotFoundError struct {
 	Audience string
 }
@@ -133,6 +147,10 @@ func (e AudienceNotFoundError) Error() string {
 	return fmt.Sprintf("Emissary requested a token for the %s audience from Vault, but Vault couldn't find the audience. Audiences must be created in [vault-config](https://github.com/DataDog/vault-config/blob/main/terraform/oidc-token-provider.tf#L2-L4) for now.", e.Audience)
 }
 
+func (e AudienceNotFoundError) Cause() FailureCause {
+	return AudienceNotFound
+}
+
 type NodePreprovisioningTimeoutError struct {
 }
 
@@ -140,6 +158,10 @@ func (e NodePreprovisioningTimeoutError) Error() string {
 	return "timed out waiting for node pre-provisioning"
 }
 
+func (e NodePreprovisioningTimeoutError) Cause() FailureCause {
+	return NodePreprovisioning
+}
+
 type NodeHasNotDrainingTaintError struct {
 	NodeName string
 }
@@ -150,18 +172,75 @@ func (e NodeHasNotDrainingTaintError) Error() string {
 
 type PodEvictionTimeoutError struct {
 	isEvictionPP bool
+	// blockedByPDB is set when the last eviction attempt was refused by the API server because of a disruption budget
+	blockedByPDB bool
 }
 
 func (e PodEvictionTimeoutError) Error() string {
 	msg := "timed out waiting for eviction;"
 	if e.isEvictionPP {
 		msg += " eviction++ endpoint was not able to finish request in time."
+	} else if e.blockedByPDB {
+		msg += " the eviction was refused by a disruption budget."
 	} else {
 		msg += " most likely related to missing disruption budget."
 	}
 	return msg
 }
 
+func (e PodEvictionTimeoutError) Cause() FailureCause {
+	if e.blockedByPDB {
+		return PodDisruptionBudgetBlocked
+	}
+	if e.isEvictionPP {
+		return PodEvictionTimeout + "_evictionpp"
+	}
+	return PodEvictionTimeout + "_kubeapi"
+}
+
+// disruptionBudgetCause is the cause set by the eviction API when a disruption budget refuses the eviction, see policy.DisruptionBudgetCause
+const disruptionBudgetCause meta.CauseType = "DisruptionBudget"
+
+// isDisruptionBudgetError tells apart the 429 of the eviction API refusing the eviction because of a disruption budget from the 429
+// of the API Priority and Fairness or of any other throttling. The API servers older than 1.26 don't set the cause, only the message.
+func isDisruptionBudgetError(err error) bool {
+	if !apierrors.IsTooManyRequests(err) {
+		return false
+	}
+	return apierrors.HasStatusCause(err, disruptionBudgetCause) || strings.Contains(err.Error(), "disruption budget")
+}
+
+// PodEvictionDryRunError is returned when the dry-run eviction of a pod fails, the real eviction is not attempted
+type PodEvictionDryRunError struct {
+	Pod string
+	Err error
+}
+
+func (e PodEvictionDryRunError) Error() string {
+	return fmt.Sprintf("dry-run eviction failed for pod %s: %v", e.Pod, e.Err)
+}
+
+func (e PodEvictionDryRunError) Unwrap() error {
+	return e.Err
+}
+
+func (e PodEvictionDryRunError) Cause() FailureCause {
+	return EvictionDryRunFailed
+}
+
+// ReplacementPodTimeoutError is returned when no running replacement of the pod is found on another node before the timeout
+type ReplacementPodTimeoutError struct {
+	Pod string
+}
+
+func (e ReplacementPodTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for a running replacement of pod %s on another node", e.Pod)
+}
+
+func (e ReplacementPodTimeoutError) Cause() FailureCause {
+	return ReplacementPodTimeout
+}
+
 type OverlappingDisruptionBudgetsError struct {
 }
 
@@ -169,6 +248,10 @@ func (e OverlappingDisruptionBudgetsError) Error() string {
 	return "overlapping pod disruption budgets"
 }
 
+func (e OverlappingDisruptionBudgetsError) Cause() FailureCause {
+	return OverlappingPodDisruptionBudgets
+}
+
 type PodDeletionTimeoutError struct {
 }
 
@@ -176,6 +259,10 @@ func (e PodDeletionTimeoutError) Error() string {
 	return "timed out waiting for pod to be deleted (stuck terminating, check finalizers)"
 }
 
+func (e PodDeletionTimeoutError) Cause() FailureCause {
+	return PodDeletionTimeout
+}
+
 type VolumeCleanupError struct {
 	Err error
 }
@@ -188,6 +275,27 @@ func (e VolumeCleanupError) Unwrap() error {
 	return e.Err
 }
 
+func (e VolumeCleanupError) Cause() FailureCause {
+	return VolumeCleanup
+}
+
+// PodFilterError is returned when the pods of the node cannot be filtered before the drain
+type PodFilterError struct {
+	Err error
+}
+
+func (e PodFilterError) Error() string {
+	return "cannot filter pods: " + e.Err.Error()
+}
+
+func (e PodFilterError) Unwrap() error {
+	return e.Err
+}
+
+func (e PodFilterError) Cause() FailureCause {
+	return PodFilter
+}
+
 // A Drainer drains nodes.
 type Drainer interface {
 	// Drain the supplied node. Evicts the node of all but mirror and DaemonSet pods.
@@ -281,12 +389,32 @@ type APIDrainer struct {
 
 	minEvictionTimeout         time.Duration
 	evictionHeadroom           time.Duration
+	maxPodGracePeriod          time.Duration
 	skipDrain                  bool
 	maxDrainAttemptsBeforeFail int32
+	evictDryRunFirst           bool
+	waitForReplacementPod      time.Duration
+	spotTerminationGracePeriod time.Duration
+	// evictionConfirmation is nil if the pod evictions don't need to be confirmed
+	evictionConfirmation *evictionConfirmation
+	// evictedPodsSnapshotNamespace holds the snapshots of the evicted pods, empty if they are not captured
+	evictedPodsSnapshotNamespace string
+	// stuckTerminatingAction is applied to the evicted pods still terminating after their grace period and stuckTerminatingTimeout
+	stuckTerminatingAction  StuckTerminatingAction
+	stuckTerminatingTimeout time.Duration
 
 	globalConfig GlobalConfig
 
 	storageClassesAllowingPVDeletion map[string]struct{}
+	// pvcDeletionLimiter throttles the deletion of the PVCs, it is shared by all the drainers to protect the storage backend
+	pvcDeletionLimiter flowcontrol.RateLimiter
+
+	evictionAPIVersionOnce sync.Once
+	evictionAPIVersion     string
+
+	// invalidEvictionOrders holds, per node being drained, the pods already reported with an invalid eviction order
+	invalidEvictionOrders     map[string]map[types.UID]bool
+	invalidEvictionOrdersLock sync.Mutex
 }
 
 // APIDrainerOption configures an APIDrainer.
@@ -309,6 +437,22 @@ func EvictionHeadroom(h time.Duration) APIDrainerOption {
 	}
 }
 
+// WithMaxPodGracePeriod configures a ceiling for the termination grace period given to the evicted pods,
+// regardless of the one defined in their spec. Zero means no ceiling.
+func WithMaxPodGracePeriod(m time.Duration) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.maxPodGracePeriod = m
+	}
+}
+
+// WithSpotTerminationGracePeriod configures a ceiling for the termination grace period given to the pods evicted from a node
+// that received a spot termination notice, so that they are gone before the node is reclaimed. Zero means no specific ceiling.
+func WithSpotTerminationGracePeriod(m time.Duration) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.spotTerminationGracePeriod = m
+	}
+}
+
 // WithPodFilter configures a filter that may be used to exclude certain pods
 // from eviction when draining.
 func WithPodFilter(f PodFilterFunc) APIDrainerOption {
@@ -342,6 +486,22 @@ func WithStorageClassesAllowingDeletion(storageClasses []string) APIDrainerOptio
 	}
 }
 
+// WithPVCDeletionLimiter configures the rate limiter applied to the PVC deletions, the drain waits for a token before deleting a PVC.
+// A nil limiter disables the throttling.
+func WithPVCDeletionLimiter(limiter flowcontrol.RateLimiter) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.pvcDeletionLimiter = limiter
+	}
+}
+
+// NewPVCDeletionLimiter returns a limiter allowing qps PVC deletions per second, or nil if qps is not positive
+func NewPVCDeletionLimiter(qps float32) flowcontrol.RateLimiter {
+	if qps <= 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(qps, 1)
+}
+
 // WithMaxDrainAttemptsBeforeFail configures the max count of failed drain attempts before a final fail
 func WithMaxDrainAttemptsBeforeFail(maxDrainAttemptsBeforeFail int) APIDrainerOption {
 	return func(d *APIDrainer) {
@@ -363,6 +523,23 @@ func WithGlobalConfig(globalConfig GlobalConfig) APIDrainerOption {
 	}
 }
 
+// WithEvictDryRunFirst configures the drainer to issue a dry-run eviction before the real eviction of each pod.
+// The pods whose dry-run fails are skipped and reported, the eviction of the other pods goes on.
+func WithEvictDryRunFirst(b bool) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.evictDryRunFirst = b
+	}
+}
+
+// WithWaitForReplacementPod configures the drainer to wait, up to the given timeout, for a running replacement of each pod
+// controlled by a replicaset before evicting it. The replacement must run on another node. Zero disables the wait.
+// The pods are listed from the store given with WithRuntimeObjectStore, the evictions fail without store.
+func WithWaitForReplacementPod(timeout time.Duration) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.waitForReplacementPod = timeout
+	}
+}
+
 func WithContainerRuntimeClient(client client.Client) APIDrainerOption {
 	return func(d *APIDrainer) {
 		d.crClient = client
@@ -407,7 +584,7 @@ func (d *APIDrainer) GetMaxDrainAttemptsBeforeFail(ctx context.Context, n *core.
 	customValue, useDefault, err := GetNodeRetryMaxAttempt(n)
 	if err != nil {
 		d.l.Warn(err.Error(), zap.String("node", n.Name))
-		d.eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, eventReasonBadValueForAnnotation, err.Error())
+		d.eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, EventReasonBadValueForAnnotation.String(), err.Error())
 	}
 	if useDefault {
 		return d.maxDrainAttemptsBeforeFail
@@ -596,7 +773,7 @@ func (d *APIDrainer) Drain(ctx context.Context, node *core.Node) error {
 
 	// Do nothing if draining is not enabled.
 	if d.skipDrain {
-		TracedLoggerForNode(ctx, node, d.l).Debug("Skipping drain because draining is disabled")
+		TracedLoggerForNode(ctx, node, d.globalConfig.TeamLabelKey, d.l).Debug("Skipping drain because draining is disabled")
 		return nil
 	}
 
@@ -610,7 +787,7 @@ func (d *APIDrainer) Drain(ctx context.Context, node *core.Node) error {
 	drainCandidate := hasNLATaint && taint.Value == k8sclient.TaintDraining
 
 	if !drainCandidate {
-		TracedLoggerForNode(ctx, node, d.l).Info("Aborting drain because the node is not drain-candidate")
+		TracedLoggerForNode(ctx, node, d.globalConfig.TeamLabelKey, d.l).Info("Aborting drain because the node is not drain-candidate")
 		return NodeHasNotDrainingTaintError{NodeName: node.Name}
 	}
 
@@ -619,26 +796,86 @@ func (d *APIDrainer) Drain(ctx context.Context, node *core.Node) error {
 		return fmt.Errorf("cannot get pods for node %s: %w", n.GetName(), err)
 	}
 
+	d.reportInvalidEvictionOrders(ctx, n, pods)
+
+	// the snapshot is a best effort for recovery, it doesn't prevent the drain
+	if err := d.snapshotEvictedPods(ctx, n, pods); err != nil {
+		TracedLoggerForNode(ctx, n, d.globalConfig.TeamLabelKey, d.l).Warn("Cannot snapshot the pods to evict", zap.Error(err))
+	}
+
+	// the pods are evicted in waves following their eviction order, a wave is only started once the previous one is fully evicted:
+	// a pod of the wave skipped after the dry-run eviction is still running, so the later waves wait for the next drain attempt
+	for _, wave := range GroupPodsByEvictionOrder(pods) {
+		skipped, err := d.evictPods(ctx, n, wave)
+		if err != nil {
+			return err
+		}
+		if len(skipped) > 0 {
+			return fmt.Errorf("cannot evict all pods, %d pods skipped after dry-run: %w", len(skipped), skipped[0])
+		}
+	}
+	d.invalidEvictionOrdersLock.Lock()
+	delete(d.invalidEvictionOrders, n.GetName())
+	d.invalidEvictionOrdersLock.Unlock()
+	return nil
+}
+
+// reportInvalidEvictionOrders emits an event on the pods with an invalid eviction order. A pod is reported once for the drain of the node,
+// not on every drain attempt.
+func (d *APIDrainer) reportInvalidEvictionOrders(ctx context.Context, n *core.Node, pods []*core.Pod) {
+	d.invalidEvictionOrdersLock.Lock()
+	defer d.invalidEvictionOrdersLock.Unlock()
+	reported := d.invalidEvictionOrders[n.GetName()]
+	invalid := map[types.UID]bool{}
+	for _, pod := range pods {
+		_, errOrder := GetPodEvictionOrder(pod)
+		if errOrder == nil {
+			continue
+		}
+		invalid[pod.GetUID()] = true
+		if !reported[pod.GetUID()] {
+			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonBadValueForAnnotation.String(), errOrder.Error())
+		}
+	}
+	// the pods gone or fixed since the previous attempt are forgotten
+	if len(invalid) == 0 {
+		delete(d.invalidEvictionOrders, n.GetName())
+		return
+	}
+	if d.invalidEvictionOrders == nil {
+		d.invalidEvictionOrders = map[string]map[types.UID]bool{}
+	}
+	d.invalidEvictionOrders[n.GetName()] = invalid
+}
+
+// evictPods evicts the given pods concurrently. It returns the errors of the pods skipped after the dry-run eviction.
+func (d *APIDrainer) evictPods(ctx context.Context, n *core.Node, pods []*core.Pod) (skipped []error, err error) {
 	abort := make(chan struct{})
 	errs := make(chan error, 1)
 	for i := range pods {
 		pod := pods[i]
 		go func() {
-			d.eventRecorder.NodeEventf(ctx, n, core.EventTypeNormal, eventReasonEvictionStarting, "Evicting pod %s/%s to drain node", pod.Namespace, pod.Name)
-			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, eventReasonEvictionStarting, "Evicting pod to drain node %s", n.Name)
+			d.eventRecorder.NodeEventf(ctx, n, core.EventTypeNormal, EventReasonEvictionStarting.String(), "Evicting pod %s/%s to drain node", pod.Namespace, pod.Name)
+			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, EventReasonEvictionStarting.String(), "Evicting pod to drain node %s", n.Name)
 			if err := d.evict(ctx, n, pod, abort); err != nil {
-				d.eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, eventReasonEvictionFailed, "Eviction failed for pod %s/%s: %v", pod.Namespace, pod.Name, err)
-				d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, eventReasonEvictionFailed, "Eviction failed: %v", err)
+				if errors.As(err, &PodEvictionDryRunError{}) {
+					d.eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, EventReasonEvictionFailed.String(), "Eviction skipped for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+					d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonEvictionFailed.String(), "Eviction skipped: %v", err)
+					errs <- err
+					return
+				}
+				d.eventRecorder.NodeEventf(ctx, n, core.EventTypeWarning, EventReasonEvictionFailed.String(), "Eviction failed for pod %s/%s: %v", pod.Namespace, pod.Name, err)
+				d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonEvictionFailed.String(), "Eviction failed: %v", err)
 				errs <- fmt.Errorf("cannot evict pod %s/%s: %w", pod.GetNamespace(), pod.GetName(), err)
 				return
 			}
-			d.eventRecorder.NodeEventf(ctx, n, core.EventTypeNormal, eventReasonEvictionSucceeded, "Pod %s/%s evicted from node", pod.Namespace, pod.Name)
-			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, eventReasonEvictionSucceeded, "Pod evicted from node %s", n.Name)
+			d.eventRecorder.NodeEventf(ctx, n, core.EventTypeNormal, EventReasonEvictionSucceeded.String(), "Pod %s/%s evicted from node", pod.Namespace, pod.Name)
+			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, EventReasonEvictionSucceeded.String(), "Pod evicted from node %s", n.Name)
 			errs <- nil // the for range pods below expects to receive one value per pod from the errs channel
 		}()
 	}
 	// This will _eventually_ abort evictions. Evictions may spend up to
-	// d.deleteTimeout() in d.awaitDeletion(), or 5 seconds in backoff before
+	// d.deleteTimeout() in d.awaitTermination(), or 5 seconds in backoff before
 	// noticing they've been aborted.
 	//
 	// Note(adrienjt): In addition, they may also spend up to:
@@ -649,13 +886,18 @@ func (d *APIDrainer) Drain(ctx context.Context, node *core.Node) error {
 
 	for range pods {
 		if err := <-errs; err != nil {
-			return fmt.Errorf("cannot evict all pods: %w", err)
+			// the pods failing the dry-run eviction don't abort the eviction of the other pods
+			if errors.As(err, &PodEvictionDryRunError{}) {
+				skipped = append(skipped, err)
+				continue
+			}
+			return skipped, fmt.Errorf("cannot evict all pods: %w", err)
 			// all remaining evictions are aborted and their errors ignored (aborted or otherwise)
 			// TODO(adrienjt): capture missing errors?
 			// They are registered as events on pods.
 		}
 	}
-	return nil
+	return skipped, nil
 }
 
 func (d *APIDrainer) GetPodsToDrain(ctx context.Context, node string, podStore PodStore) ([]*core.Pod, error) {
@@ -684,35 +926,167 @@ func (d *APIDrainer) GetPodsToDrain(ctx context.Context, node string, podStore P
 	for _, p := range pods {
 		passes, _, err := d.filter(*p)
 		if err != nil {
-			return nil, fmt.Errorf("cannot filter pods: %w", err)
+			return nil, PodFilterError{Err: err}
 		}
 		if passes {
 			include = append(include, p)
 		}
 	}
+	SortPodsByEvictionOrder(include)
 	return include, nil
 }
 
 func (d *APIDrainer) evict(ctx context.Context, node *core.Node, pod *core.Pod, abort <-chan struct{}) error {
-	evictionAPIURL, ok := GetEvictionAPIURL(pod, d.runtimeObjectStore)
-	if ok {
-		return d.evictWithOperatorAPI(ctx, evictionAPIURL, node, pod, abort)
+	if d.waitForReplacementPod > 0 {
+		if err := d.awaitReplacementPod(ctx, node, pod); err != nil {
+			return err
+		}
+	}
+	if d.evictDryRunFirst {
+		if err := d.evictDryRun(ctx, node, pod); err != nil {
+			return PodEvictionDryRunError{Pod: pod.Namespace + "/" + pod.Name, Err: err}
+		}
+	}
+	if err := d.confirmEviction(ctx, node, pod); err != nil {
+		return err
+	}
+	var err error
+	evictionAPIURL, customEvictor := GetEvictionAPIURL(pod, d.runtimeObjectStore)
+	if customEvictor {
+		err = d.evictWithOperatorAPI(ctx, evictionAPIURL, node, pod, abort)
+	} else {
+		err = d.evictWithKubernetesAPI(ctx, node, pod, abort)
+	}
+	if err == nil {
+		tags, _ := tag.New(ctx, tag.Upsert(TagNamespace, pod.GetNamespace()), tag.Upsert(TagCustomEvictor, strconv.FormatBool(customEvictor)))
+		stats.Record(tags, MeasurePodsEvicted.M(1))
+		if drainStats := DrainStatsFromContext(ctx); drainStats != nil {
+			drainStats.AddPodsEvicted(1)
+		}
 	}
-	return d.evictWithKubernetesAPI(ctx, node, pod, abort)
+	return err
 }
 
-func (d *APIDrainer) getGracePeriodWithEvictionHeadRoom(pod *core.Pod) time.Duration {
-	gracePeriod := int64(core.DefaultTerminationGracePeriodSeconds)
+// awaitReplacementPod waits for a running and ready pod of the same workload on another node, using the pods of the store.
+// The pods that are not controlled by a replicaset are not checked. The replacement of a workload with a single replica
+// cannot be one of its current pods: it must be created after the wait started, by a rollout with a surge for example.
+func (d *APIDrainer) awaitReplacementPod(ctx context.Context, node *core.Node, pod *core.Pod) error {
+	if d.runtimeObjectStore == nil {
+		return fmt.Errorf("cannot wait for a replacement of pod %s/%s without runtime object store", pod.Namespace, pod.Name)
+	}
+	owner, ok := getReplicaSetWorkload(pod, d.runtimeObjectStore)
+	if !ok {
+		return nil
+	}
+	// the creation timestamps have a precision of one second
+	startedAt := meta.Time{Time: time.Now().Truncate(time.Second)}
+	replicas, known := getReplicaSetWorkloadReplicas(pod, d.runtimeObjectStore)
+	singleton := known && replicas <= 1
+	waitCtx, cancel := context.WithTimeout(ctx, d.waitForReplacementPod)
+	defer cancel()
+	err := wait.PollImmediateUntilWithContext(waitCtx, replacementPodPollPeriod, func(context.Context) (bool, error) {
+		pods, err := d.runtimeObjectStore.Pods().ListPodsForNamespace(pod.GetNamespace())
+		if err != nil {
+			return false, fmt.Errorf("cannot list pods in namespace %s: %w", pod.GetNamespace(), err)
+		}
+		for _, other := range pods {
+			if other.GetUID() == pod.GetUID() || other.Spec.NodeName == node.Name || other.DeletionTimestamp != nil || !isPodRunningAndReady(other) {
+				continue
+			}
+			if singleton && other.CreationTimestamp.Before(&startedAt) {
+				continue
+			}
+			if otherOwner, ok := getReplicaSetWorkload(other, d.runtimeObjectStore); ok && otherOwner == owner {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return ReplacementPodTimeoutError{Pod: pod.Namespace + "/" + pod.Name}
+	}
+	return err
+}
+
+// getReplicaSetWorkloadReplicas returns the desired replicas of the workload of a pod controlled by a replicaset, see getReplicaSetWorkload.
+// The boolean is false if the replicaset is not found in the store.
+func getReplicaSetWorkloadReplicas(pod *core.Pod, store RuntimeObjectStore) (int32, bool) {
+	ctrl := meta.GetControllerOf(pod)
+	if ctrl == nil || ctrl.Kind != KindReplicaSet {
+		return 0, false
+	}
+	rs, err := store.ReplicaSets().Get(pod.GetNamespace(), ctrl.Name)
+	if err != nil {
+		return 0, false
+	}
+	replicas := rs.Spec.Replicas
+	if rsCtrl := meta.GetControllerOf(rs); rsCtrl != nil && rsCtrl.Kind == KindDeployment {
+		if deployment, err := store.Deployments().Get(pod.GetNamespace(), rsCtrl.Name); err == nil {
+			replicas = deployment.Spec.Replicas
+		}
+	}
+	if replicas == nil {
+		// the default number of replicas
+		return 1, true
+	}
+	return *replicas, true
+}
+
+// getReplicaSetWorkload returns the workload of a pod controlled by a replicaset: the deployment owning the replicaset if any, the replicaset otherwise.
+func getReplicaSetWorkload(pod *core.Pod, store RuntimeObjectStore) (string, bool) {
+	ctrl := meta.GetControllerOf(pod)
+	if ctrl == nil || ctrl.Kind != KindReplicaSet {
+		return "", false
+	}
+	if store != nil {
+		if rs, err := store.ReplicaSets().Get(pod.GetNamespace(), ctrl.Name); err == nil {
+			if rsCtrl := meta.GetControllerOf(rs); rsCtrl != nil && rsCtrl.Kind == KindDeployment {
+				return KindDeployment + "/" + rsCtrl.Name, true
+			}
+		}
+	}
+	return KindReplicaSet + "/" + ctrl.Name, true
+}
+
+func isPodRunningAndReady(pod *core.Pod) bool {
+	if pod.Status.Phase != core.PodRunning {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == core.PodReady {
+			return c.Status == core.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getPodGracePeriod returns the termination grace period of the pod, capped by maxPodGracePeriod.
+// The boolean is true if the grace period of the pod was clamped.
+func (d *APIDrainer) getPodGracePeriod(pod *core.Pod) (time.Duration, bool) {
+	gracePeriod := time.Duration(core.DefaultTerminationGracePeriodSeconds) * time.Second
 	if pod.Spec.TerminationGracePeriodSeconds != nil {
-		gracePeriod = *pod.Spec.TerminationGracePeriodSeconds
+		gracePeriod = time.Duration(*pod.Spec.TerminationGracePeriodSeconds) * time.Second
 	}
-	return time.Duration(gracePeriod)*time.Second + d.evictionHeadroom
+	if d.maxPodGracePeriod > 0 && gracePeriod > d.maxPodGracePeriod {
+		return d.maxPodGracePeriod, true
+	}
+	return gracePeriod, false
+}
+
+func (d *APIDrainer) getGracePeriodWithEvictionHeadRoom(pod *core.Pod) time.Duration {
+	gracePeriod, _ := d.getPodGracePeriod(pod)
+	return gracePeriod + d.evictionHeadroom
 }
 
 func (d *APIDrainer) getMinEvictionTimeoutWithEvictionHeadRoom(pod *core.Pod) time.Duration {
 	gracePeriod := d.minEvictionTimeout
-	if pod.Spec.TerminationGracePeriodSeconds != nil && time.Duration(*pod.Spec.TerminationGracePeriodSeconds)*time.Second > gracePeriod {
-		gracePeriod = time.Duration(*pod.Spec.TerminationGracePeriodSeconds) * time.Second
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		if podGracePeriod, _ := d.getPodGracePeriod(pod); podGracePeriod > gracePeriod {
+			gracePeriod = podGracePeriod
+		}
 	}
 	return gracePeriod + d.evictionHeadroom
 }
@@ -724,9 +1098,7 @@ func (d *APIDrainer) evictWithKubernetesAPI(ctx context.Context, node *core.Node
 	return d.evictionSequence(ctx, node, pod, abort,
 		// eviction function
 		func() error {
-			return d.c.CoreV1().Pods(pod.GetNamespace()).EvictV1(ctx, &policy.Eviction{
-				ObjectMeta: meta.ObjectMeta{Namespace: pod.GetNamespace(), Name: pod.GetName()},
-			})
+			return d.evictPod(ctx, node, pod)
 		},
 		// error handling function
 		func(err error) error {
@@ -742,6 +1114,83 @@ func (d *APIDrainer) evictWithKubernetesAPI(ctx context.Context, node *core.Node
 
 }
 
+// evictPod creates the eviction with the version of the eviction API served by the API server
+// If the grace period of the pod is above the configured ceiling, the eviction overrides it with the ceiling.
+// The ceiling is the spot termination grace period if the node received a spot termination notice.
+func (d *APIDrainer) evictPod(ctx context.Context, node *core.Node, pod *core.Pod) error {
+	var deleteOptions *meta.DeleteOptions
+	gracePeriod, clamped := d.getPodGracePeriod(pod)
+	if d.spotTerminationGracePeriod > 0 && gracePeriod > d.spotTerminationGracePeriod && d.globalConfig.DrainNow.IsSpotTerminationNoticed(node) {
+		gracePeriod, clamped = d.spotTerminationGracePeriod, true
+	}
+	if clamped {
+		d.l.Info("clamping pod termination grace period", zap.String("pod", pod.Namespace+"/"+pod.Name), zap.Int64p("pod_grace_period_seconds", pod.Spec.TerminationGracePeriodSeconds), zap.Duration("max_pod_grace_period", gracePeriod))
+		gracePeriodSeconds := int64(gracePeriod.Seconds())
+		deleteOptions = &meta.DeleteOptions{GracePeriodSeconds: &gracePeriodSeconds}
+	}
+
+	version, err := d.createEviction(ctx, pod, deleteOptions)
+	if err != nil {
+		return err
+	}
+	tags, _ := tag.New(ctx, tag.Upsert(TagEvictionAPIVersion, version))
+	stats.Record(tags, MeasureEvictionAPIVersionUsed.M(1))
+	return nil
+}
+
+// createEviction creates the eviction with the version of the eviction API served by the API server, it returns the version used
+func (d *APIDrainer) createEviction(ctx context.Context, pod *core.Pod, deleteOptions *meta.DeleteOptions) (string, error) {
+	version := d.getEvictionAPIVersion()
+	if version == EvictionAPIVersionV1beta1 {
+		return version, d.c.CoreV1().Pods(pod.GetNamespace()).EvictV1beta1(ctx, &policyv1beta1.Eviction{
+			ObjectMeta:    meta.ObjectMeta{Namespace: pod.GetNamespace(), Name: pod.GetName()},
+			DeleteOptions: deleteOptions,
+		})
+	}
+	return version, d.c.CoreV1().Pods(pod.GetNamespace()).EvictV1(ctx, &policy.Eviction{
+		ObjectMeta:    meta.ObjectMeta{Namespace: pod.GetNamespace(), Name: pod.GetName()},
+		DeleteOptions: deleteOptions,
+	})
+}
+
+// evictDryRun issues a dry-run eviction of the pod. The pods using eviction++ are only checked if they support the dry-run.
+func (d *APIDrainer) evictDryRun(ctx context.Context, node *core.Node, pod *core.Pod) error {
+	span, ctx := tracer.StartSpanFromContext(ctx, "evictDryRun")
+	defer span.Finish()
+
+	if url, ok := GetEvictionAPIURL(pod, d.runtimeObjectStore); ok {
+		value, ok := GetAnnotationFromPodOrController(EvictionAPIDryRunSupportedAnnotationKey, pod, d.runtimeObjectStore)
+		if !ok || strings.ToLower(value) != EvictionAPIDryRunSupportedAnnotationTrue {
+			return nil
+		}
+		conditions := GetConditionIDs(GetNodeOffendingConditions(node, d.globalConfig.SuppliedConditions))
+		logger := d.l.With(zap.String("node", node.Name)).With(zap.String("pod", pod.Namespace+"/"+pod.Name))
+		_, err := CallOperatorAPI(ctx, zapr.NewLogger(logger), url, pod, conditions, true, 1)
+		return err
+	}
+	_, err := d.createEviction(ctx, pod, &meta.DeleteOptions{DryRun: []string{meta.DryRunAll}})
+	return err
+}
+
+// getEvictionAPIVersion discovers, only once, the version of the eviction subresource advertised by the API server.
+// The policy/v1 API is used by default, the policy/v1beta1 one is only used on the clusters that do not serve policy/v1.
+func (d *APIDrainer) getEvictionAPIVersion() string {
+	d.evictionAPIVersionOnce.Do(func() {
+		d.evictionAPIVersion = EvictionAPIVersionV1
+		resources, err := d.c.Discovery().ServerResourcesForGroupVersion("v1")
+		if err != nil {
+			d.l.Warn("cannot discover the eviction API version, using the default one", zap.String("version", d.evictionAPIVersion), zap.Error(err))
+			return
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Name == "pods/eviction" && resource.Group == policy.GroupName && resource.Version == EvictionAPIVersionV1beta1 {
+				d.evictionAPIVersion = EvictionAPIVersionV1beta1
+			}
+		}
+	})
+	return d.evictionAPIVersion
+}
+
 // evictWithOperatorAPI This function calls an Operator endpoint to perform the eviction instead of the classic kubernetes eviction endpoint
 // The endpoint should support the same payload than the kubernetes eviction endpoint.
 // The expected responses are:
@@ -881,13 +1330,14 @@ func (d *APIDrainer) evictionSequence(ctx context.Context, node *core.Node, pod
 		Steps:    100, // we want the max backoff for a single step controlled by cap, not steps, so set steps arbitrarily large to effectively ignore it
 		Cap:      time.Minute,
 	}
+	var blockedByPDB bool
 	for {
 		select {
 		case <-abort:
 			return errors.New("pod eviction aborted")
 		case <-ctx.Done():
 			_, ok := GetEvictionAPIURL(pod, d.runtimeObjectStore)
-			return PodEvictionTimeoutError{isEvictionPP: ok} // this one is typed because we match it to a failure cause
+			return PodEvictionTimeoutError{isEvictionPP: ok, blockedByPDB: !ok && blockedByPDB} // this one is typed because we match it to a failure cause
 		default:
 			pvcs, err := d.getInScopePVCs(ctx, pod)
 			if err != nil {
@@ -910,14 +1360,15 @@ func (d *APIDrainer) evictionSequence(ctx context.Context, node *core.Node, pod
 			if pod.DeletionTimestamp == nil {
 				err = evictionFunc()
 			}
+			blockedByPDB = isDisruptionBudgetError(err)
 			switch {
 			// The eviction API returns 429 Too Many Requests if a pod
 			// cannot currently be evicted, for example due to a pod
 			// disruption budget.
 			case apierrors.IsTooManyRequests(err):
 				d.l.Info("received 429 while evicting pod", zap.String("node", node.Name), zap.String("pod", pod.Name), zap.String("pod_namespace", pod.Namespace), zap.Error(err))
-				d.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, eventReasonEvictionAttemptFailed, "Attempt to evict pod %s/%s failed: %v", pod.Namespace, pod.Name, err)
-				d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, eventReasonEvictionAttemptFailed, "Attempt to evict pod from node %s failed: %v", node.Name, err)
+				d.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, EventReasonEvictionAttemptFailed.String(), "Attempt to evict pod %s/%s failed: %v", pod.Namespace, pod.Name, err)
+				d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonEvictionAttemptFailed.String(), "Attempt to evict pod from node %s failed: %v", node.Name, err)
 				waitTime := backoff.Step()
 				if statErr, ok := err.(apierrors.APIStatus); ok && statErr.Status().Details != nil {
 					if proposedWaitSeconds := statErr.Status().Details.RetryAfterSeconds; proposedWaitSeconds > 0 {
@@ -942,7 +1393,7 @@ func (d *APIDrainer) evictionSequence(ctx context.Context, node *core.Node, pod
 				}
 			default: // this means the API answered 200/201, we wait for the pod deletion
 				// now that the eviction is confirmed we can only wait for the pod terminationGracePeriod (and evictionHeadroom to give some buffer)
-				err := d.awaitDeletion(ctx, pod, d.getGracePeriodWithEvictionHeadRoom(pod))
+				err := d.awaitTermination(ctx, node, pod)
 				if err != nil {
 					return fmt.Errorf("cannot confirm pod was deleted: %w", err)
 				}
@@ -956,41 +1407,60 @@ func (d *APIDrainer) evictionSequence(ctx context.Context, node *core.Node, pod
 	}
 }
 
-func (d *APIDrainer) awaitDeletion(ctx context.Context, pod *core.Pod, timeout time.Duration) error {
-	// We need to optimise the pollPeriod to maximize the chance to capture the deletion and not falling into rate limiting issue on the client side
-	pollPeriod := timeout / 10 // let's make 10 tentatives to check deletion
-	if pollPeriod < 6*time.Second {
-		pollPeriod = 6 * time.Second
+func recordPodTerminationLatency(ctx context.Context, pod *core.Pod, start, deletedAt time.Time) {
+	tags, _ := tag.New(ctx, tag.Upsert(TagNamespace, pod.GetNamespace()))
+	stats.Record(tags, MeasurePodTerminationLatency.M(deletedAt.Sub(start).Seconds()))
+}
+
+const (
+	initialDeletionPollPeriod = 500 * time.Millisecond
+	minDeletionPollPeriod     = 6 * time.Second
+	maxDeletionPollPeriod     = 2 * time.Minute
+)
+
+// waitForDeletion polls until the pod is gone or replaced by another pod with the same name. It returns the time of the first poll not seeing the pod.
+func (d *APIDrainer) waitForDeletion(ctx context.Context, pod *core.Pod, timeout time.Duration) (time.Time, error) {
+	// The poll period starts short so that the deletion time of the pods terminating quickly is precise, then it grows up to a tenth
+	// of the timeout so that the pods taking long don't fall into rate limiting issue on the client side
+	maxPollPeriod := timeout / 10
+	if maxPollPeriod < minDeletionPollPeriod {
+		maxPollPeriod = minDeletionPollPeriod
 	}
-	if pollPeriod > 2*time.Minute {
-		pollPeriod = 2 * time.Minute
+	if maxPollPeriod > maxDeletionPollPeriod {
+		maxPollPeriod = maxDeletionPollPeriod
 	}
 
-	polls := 0
-	err := wait.PollImmediate(pollPeriod, timeout, func() (bool, error) {
-		polls += 1
+	deadline := time.Now().Add(timeout)
+	pollPeriod := initialDeletionPollPeriod
+	for polls := 1; ; polls++ {
 		var got core.Pod
 		err := d.crClient.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &got)
-		if apierrors.IsNotFound(err) {
-			return true, nil
+		now := time.Now()
+		if apierrors.IsNotFound(err) || (err == nil && got.GetUID() != pod.GetUID()) {
+			return now, nil
 		}
 		if err != nil {
-			return false, fmt.Errorf("cannot get pod %s/%s: %w", pod.GetNamespace(), pod.GetName(), err)
-		}
-		if got.GetUID() != pod.GetUID() {
-			return true, nil
+			return time.Time{}, fmt.Errorf("cannot get pod %s/%s: %w", pod.GetNamespace(), pod.GetName(), err)
 		}
-		return false, nil
-	})
-	if err != nil {
-		if errors.Is(err, wait.ErrWaitTimeout) {
+		if !now.Before(deadline) {
 			d.l.With(zap.String("pod", pod.Namespace+"/"+pod.Name), zap.Duration("timeout", timeout), zap.Duration("poll", pollPeriod), zap.Int("polls", polls)).
 				Warn("pod deletion timed out")
-			return PodDeletionTimeoutError{} // this one is typed because we match it to a failure cause
+			return time.Time{}, PodDeletionTimeoutError{} // this one is typed because we match it to a failure cause
+		}
+
+		sleep := pollPeriod
+		if remaining := deadline.Sub(now); sleep > remaining {
+			sleep = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err()
+		case <-time.After(sleep):
+		}
+		if pollPeriod = pollPeriod * 3 / 2; pollPeriod > maxPollPeriod {
+			pollPeriod = maxPollPeriod
 		}
-		return err // unexpected Get error above
 	}
-	return nil
 }
 
 func (d *APIDrainer) deletePVCAndPV(ctx context.Context, pod *core.Pod, pvcs []*core.PersistentVolumeClaim) error {
@@ -999,6 +1469,9 @@ func (d *APIDrainer) deletePVCAndPV(ctx context.Context, pod *core.Pod, pvcs []*
 	span.SetTag("pod", pod.GetName())
 
 	pvcDeleted, err := d.deletePVCAssociatedWithStorageClass(ctx, pod, pvcs)
+	if drainStats := DrainStatsFromContext(ctx); drainStats != nil {
+		drainStats.AddPVCsDeleted(len(pvcDeleted))
+	}
 	if err != nil {
 		return err
 	}
@@ -1061,8 +1534,8 @@ func (d *APIDrainer) deletePVAssociatedWithDeletedPVC(ctx context.Context, pod *
 			continue // This PV was already deleted
 		}
 
-		d.eventRecorder.PersistentVolumeEventf(ctx, &pv, core.EventTypeNormal, "Eviction", fmt.Sprintf("Deletion requested due to association with evicted pvc %s/%s and pod %s/%s", claim.Namespace, claim.Name, pod.Namespace, pod.Name))
-		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, "Eviction", fmt.Sprintf("Deletion of associated PV %s", pv.Name))
+		d.eventRecorder.PersistentVolumeEventf(ctx, &pv, core.EventTypeNormal, EventReasonVolumeDeletion.String(), fmt.Sprintf("Deletion requested due to association with evicted pvc %s/%s and pod %s/%s", claim.Namespace, claim.Name, pod.Namespace, pod.Name))
+		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, EventReasonVolumeDeletion.String(), fmt.Sprintf("Deletion of associated PV %s", pv.Name))
 
 		err = d.c.CoreV1().PersistentVolumes().Delete(ctx, pv.Name, meta.DeleteOptions{})
 		if apierrors.IsNotFound(err) {
@@ -1070,8 +1543,8 @@ func (d *APIDrainer) deletePVAssociatedWithDeletedPVC(ctx context.Context, pod *
 			continue // This PV was already deleted
 		}
 		if err != nil {
-			d.eventRecorder.PersistentVolumeEventf(ctx, &pv, core.EventTypeWarning, "EvictionFailure", fmt.Sprintf("Could not delete PV: %v", err))
-			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, "EvictionFailure", fmt.Sprintf("Could not delete PV %s: %v", pv.Name, err))
+			d.eventRecorder.PersistentVolumeEventf(ctx, &pv, core.EventTypeWarning, EventReasonVolumeDeletionFailed.String(), fmt.Sprintf("Could not delete PV: %v", err))
+			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonVolumeDeletionFailed.String(), fmt.Sprintf("Could not delete PV %s: %v", pv.Name, err))
 			return fmt.Errorf("cannot delete pv %s: %w", pv.Name, err)
 		}
 		d.l.Info("deleting pv", zap.String("pv", pv.Name))
@@ -1187,9 +1660,14 @@ func (d *APIDrainer) deletePVCAssociatedWithStorageClass(ctx context.Context, po
 			d.l.Info("DELETE: PVC already replaced", zap.String("claim", pvc.Name))
 			continue
 		}
+		if d.pvcDeletionLimiter != nil {
+			if err := d.pvcDeletionLimiter.Wait(ctx); err != nil {
+				return deletedPVCs, fmt.Errorf("cannot delete pvc %s/%s, rate limited: %w", pod.GetNamespace(), pvc.Name, err)
+			}
+		}
 
-		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, "Eviction", fmt.Sprintf("Deletion of associated PVC %s/%s", pvc.Namespace, pvc.Name))
-		d.eventRecorder.PersistentVolumeClaimEventf(ctx, pvc, core.EventTypeNormal, "Eviction", fmt.Sprintf("Deletion requested due to association with evicted pod %s/%s", pod.Namespace, pod.Name))
+		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeNormal, EventReasonVolumeDeletion.String(), fmt.Sprintf("Deletion of associated PVC %s/%s", pvc.Namespace, pvc.Name))
+		d.eventRecorder.PersistentVolumeClaimEventf(ctx, pvc, core.EventTypeNormal, EventReasonVolumeDeletion.String(), fmt.Sprintf("Deletion requested due to association with evicted pod %s/%s", pod.Namespace, pod.Name))
 
 		err = d.c.CoreV1().PersistentVolumeClaims(pod.GetNamespace()).Delete(ctx, pvc.Name, meta.DeleteOptions{})
 		if apierrors.IsNotFound(err) {
@@ -1197,8 +1675,8 @@ func (d *APIDrainer) deletePVCAssociatedWithStorageClass(ctx context.Context, po
 			continue // This PVC was already deleted
 		}
 		if err != nil {
-			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, "EvictionFailure", fmt.Sprintf("Could not delete PVC %s/%s: %v", pvc.Namespace, pvc.Name, err))
-			d.eventRecorder.PersistentVolumeClaimEventf(ctx, pvc, core.EventTypeWarning, "EvictionFailure", fmt.Sprintf("Could not delete: %v", err))
+			d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonVolumeDeletionFailed.String(), fmt.Sprintf("Could not delete PVC %s/%s: %v", pvc.Namespace, pvc.Name, err))
+			d.eventRecorder.PersistentVolumeClaimEventf(ctx, pvc, core.EventTypeWarning, EventReasonVolumeDeletionFailed.String(), fmt.Sprintf("Could not delete: %v", err))
 			return deletedPVCs, fmt.Errorf("cannot delete pvc %s/%s: %w", pod.GetNamespace(), pvc.Name, err)
 		}
 		d.l.Info("deleting pvc", zap.String("pvc", pvc.Name), zap.String("namespace", pod.GetNamespace()), zap.String("pvc-uid", string(pvc.GetUID())))
@@ -1246,7 +1724,7 @@ func (d *APIDrainer) performNodeReplacement(ctx context.Context, n *core.Node, r
 		return fmt.Errorf("cannot request replacement node %s: %w", fresh.GetName(), err)
 	}
 	tags, _ := tag.New(context.Background(), tag.Upsert(TagNodeName, n.GetName()), tag.Upsert(TagReason, reason)) // nolint:gosec
-	StatRecordForNode(tags, n, MeasureNodesReplacementRequest.M(1))
+	StatRecordForNode(tags, n, d.globalConfig.TeamLabelKey, MeasureNodesReplacementRequest.M(1))
 	return nil
 }
 
@@ -24,21 +24,31 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
 	"go.uber.org/zap"
+	"golang.org/x/exp/maps"
+	appsv1 "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	policy "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	//"k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
@@ -190,6 +200,29 @@ func TestDrain(t *testing.T) {
 			name:    "PodEvictionNotAllowed",
 			node:    &core.Node{ObjectMeta: meta.ObjectMeta{Name: nodeName}, Spec: core.NodeSpec{Taints: taintDraining}},
 			options: []APIDrainerOption{MaxGracePeriod(1 * time.Second), EvictionHeadroom(1 * time.Second)},
+			reactions: []reactor{
+				reactor{
+					verb:     "list",
+					resource: "pods",
+					ret: &core.PodList{Items: []core.Pod{
+						core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName}},
+					}},
+				},
+				reactor{
+					verb:        "create",
+					resource:    "pods",
+					subresource: "eviction",
+					err:         newDisruptionBudgetError(5),
+				},
+			},
+			errFn: func(err error) bool {
+				return errors.As(err, &PodEvictionTimeoutError{}) && GetFailureCause(err) == PodDisruptionBudgetBlocked
+			},
+		},
+		{
+			name:    "PodEvictionThrottled",
+			node:    &core.Node{ObjectMeta: meta.ObjectMeta{Name: nodeName}, Spec: core.NodeSpec{Taints: taintDraining}},
+			options: []APIDrainerOption{MaxGracePeriod(1 * time.Second), EvictionHeadroom(1 * time.Second)},
 			reactions: []reactor{
 				reactor{
 					verb:     "list",
@@ -205,7 +238,9 @@ func TestDrain(t *testing.T) {
 					err:         apierrors.NewTooManyRequests("nope", 5),
 				},
 			},
-			errFn: func(err error) bool { return errors.As(err, &PodEvictionTimeoutError{}) },
+			errFn: func(err error) bool {
+				return errors.As(err, &PodEvictionTimeoutError{}) && GetFailureCause(err) == "pod_eviction_timeout_kubeapi"
+			},
 		},
 		{
 			name: "EvictedPodReplacedWithDifferentUID",
@@ -805,3 +840,765 @@ func TestAPIDrainer_MarkDrainDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestAPIDrainer_evictPodAPIVersion(t *testing.T) {
+	tests := []struct {
+		name              string
+		evictionResources []meta.APIResource
+		expectedVersion   string
+		expectedEviction  string
+	}{
+		{
+			name:              "policy/v1 served",
+			evictionResources: []meta.APIResource{{Name: "pods/eviction", Kind: "Eviction", Group: "policy", Version: "v1"}},
+			expectedVersion:   EvictionAPIVersionV1,
+			expectedEviction:  "*v1.Eviction",
+		},
+		{
+			name:              "fallback on policy/v1beta1",
+			evictionResources: []meta.APIResource{{Name: "pods/eviction", Kind: "Eviction", Group: "policy", Version: "v1beta1"}},
+			expectedVersion:   EvictionAPIVersionV1beta1,
+			expectedEviction:  "*v1beta1.Eviction",
+		},
+		{
+			name:             "discovery failure uses policy/v1",
+			expectedVersion:  EvictionAPIVersionV1,
+			expectedEviction: "*v1.Eviction",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evictionView := &view.View{
+				Name:        "test_eviction_api_version_used",
+				Measure:     MeasureEvictionAPIVersionUsed,
+				Aggregation: view.Count(),
+				TagKeys:     []tag.Key{TagEvictionAPIVersion},
+			}
+			assert.NoError(t, view.Register(evictionView))
+			defer view.Unregister(evictionView)
+
+			pod := &core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "ns"}}
+			cs := fake.NewSimpleClientset(pod)
+			if tt.evictionResources != nil {
+				cs.Resources = []*meta.APIResourceList{{GroupVersion: "v1", APIResources: tt.evictionResources}}
+			}
+			var evictions []string
+			cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				evictions = append(evictions, fmt.Sprintf("%T", a.(clienttesting.CreateAction).GetObject()))
+				return true, nil, nil
+			})
+
+			d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}))
+			assert.NoError(t, d.evictPod(context.Background(), &core.Node{}, pod))
+			assert.NoError(t, d.evictPod(context.Background(), &core.Node{}, pod))
+			assert.Equal(t, []string{tt.expectedEviction, tt.expectedEviction}, evictions)
+
+			rows, err := view.RetrieveData(evictionView.Name)
+			assert.NoError(t, err)
+			assert.Len(t, rows, 1)
+			assert.Equal(t, []tag.Tag{{Key: TagEvictionAPIVersion, Value: tt.expectedVersion}}, rows[0].Tags)
+			assert.Equal(t, int64(2), rows[0].Data.(*view.CountData).Value)
+		})
+	}
+}
+
+func TestAPIDrainer_maxPodGracePeriod(t *testing.T) {
+	twoHours := int64((2 * time.Hour).Seconds())
+	tests := []struct {
+		name                    string
+		maxPodGracePeriod       time.Duration
+		expectedGracePeriod     *int64
+		expectedAwaitDeletion   time.Duration
+		expectedEvictionTimeout time.Duration
+	}{
+		{
+			name:                    "no ceiling",
+			expectedAwaitDeletion:   2*time.Hour + DefaultEvictionOverhead,
+			expectedEvictionTimeout: 2*time.Hour + DefaultEvictionOverhead,
+		},
+		{
+			name:                    "grace period clamped to the ceiling",
+			maxPodGracePeriod:       10 * time.Minute,
+			expectedGracePeriod:     pointer.Int64(int64((10 * time.Minute).Seconds())),
+			expectedAwaitDeletion:   10*time.Minute + DefaultEvictionOverhead,
+			expectedEvictionTimeout: 10*time.Minute + DefaultEvictionOverhead,
+		},
+		{
+			name:                    "ceiling above the grace period",
+			maxPodGracePeriod:       3 * time.Hour,
+			expectedAwaitDeletion:   2*time.Hour + DefaultEvictionOverhead,
+			expectedEvictionTimeout: 2*time.Hour + DefaultEvictionOverhead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &core.Pod{
+				ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "ns"},
+				Spec:       core.PodSpec{TerminationGracePeriodSeconds: &twoHours},
+			}
+			cs := fake.NewSimpleClientset(pod)
+			var evictions []*policy.Eviction
+			cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				evictions = append(evictions, a.(clienttesting.CreateAction).GetObject().(*policy.Eviction))
+				return true, nil, nil
+			})
+
+			d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), MaxGracePeriod(time.Minute), EvictionHeadroom(DefaultEvictionOverhead), WithMaxPodGracePeriod(tt.maxPodGracePeriod))
+			assert.Equal(t, tt.expectedAwaitDeletion, d.getGracePeriodWithEvictionHeadRoom(pod))
+			assert.Equal(t, tt.expectedEvictionTimeout, d.getMinEvictionTimeoutWithEvictionHeadRoom(pod))
+
+			assert.NoError(t, d.evictPod(context.Background(), &core.Node{}, pod))
+			assert.Len(t, evictions, 1)
+			if tt.expectedGracePeriod == nil {
+				assert.Nil(t, evictions[0].DeleteOptions)
+			} else {
+				assert.Equal(t, tt.expectedGracePeriod, evictions[0].DeleteOptions.GracePeriodSeconds)
+			}
+		})
+	}
+}
+
+func TestAPIDrainer_spotTerminationGracePeriod(t *testing.T) {
+	twoHours := int64((2 * time.Hour).Seconds())
+	tests := []struct {
+		name                string
+		nodeAnnotations     map[string]string
+		expectedGracePeriod *int64
+	}{
+		{
+			name: "regular node",
+		},
+		{
+			name:                "node with a spot termination notice",
+			nodeAnnotations:     map[string]string{"spot.example.com/termination-notice": "2023-01-01T00:02:00Z"},
+			expectedGracePeriod: pointer.Int64(30),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &core.Node{ObjectMeta: meta.ObjectMeta{Name: nodeName, Annotations: tt.nodeAnnotations}}
+			pod := &core.Pod{
+				ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "ns"},
+				Spec:       core.PodSpec{TerminationGracePeriodSeconds: &twoHours},
+			}
+			cs := fake.NewSimpleClientset(pod)
+			var evictions []*policy.Eviction
+			cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				evictions = append(evictions, a.(clienttesting.CreateAction).GetObject().(*policy.Eviction))
+				return true, nil, nil
+			})
+
+			d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithSpotTerminationGracePeriod(30*time.Second),
+				WithGlobalConfig(GlobalConfig{DrainNow: DrainNowConfig{SpotTerminationAnnotationKey: "spot.example.com/termination-notice"}}))
+			assert.NoError(t, d.evictPod(context.Background(), node, pod))
+			assert.Len(t, evictions, 1)
+			if tt.expectedGracePeriod == nil {
+				assert.Nil(t, evictions[0].DeleteOptions)
+			} else {
+				assert.Equal(t, tt.expectedGracePeriod, evictions[0].DeleteOptions.GracePeriodSeconds)
+			}
+		})
+	}
+}
+
+func TestAPIDrainer_evictDryRunFirst(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	newPod := func(name string) *core.Pod {
+		return &core.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "ns"}, Spec: core.PodSpec{NodeName: nodeName}}
+	}
+	cs := fake.NewSimpleClientset(node, newPod("pod-1"), newPod("pod-2"), newPod("pod-3"))
+
+	var evicted []string
+	cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+		if eviction.DeleteOptions != nil && len(eviction.DeleteOptions.DryRun) > 0 {
+			if eviction.Name == "pod-2" {
+				return true, nil, apierrors.NewTooManyRequests("disruption budget", 10)
+			}
+			return true, nil, nil
+		}
+		evicted = append(evicted, eviction.Name)
+		return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+
+	d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithEvictDryRunFirst(true), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+	err := d.Drain(context.Background(), node)
+
+	assert.Error(t, err)
+	assert.Equal(t, EvictionDryRunFailed, GetFailureCause(err))
+	assert.ElementsMatch(t, []string{"pod-1", "pod-3"}, evicted)
+	_, errGet := cs.CoreV1().Pods("ns").Get(context.Background(), "pod-2", meta.GetOptions{})
+	assert.NoError(t, errGet, "the pod failing the dry-run should not be evicted")
+}
+
+func TestAPIDrainer_evictionWaves(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	newPod := func(name, order string) *core.Pod {
+		pod := &core.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "ns", UID: types.UID(name)}, Spec: core.PodSpec{NodeName: nodeName}}
+		if order != "" {
+			pod.Annotations = map[string]string{EvictionOrderAnnotationKey: order}
+		}
+		return pod
+	}
+	cs := fake.NewSimpleClientset(node, newPod("pod-1", EvictionOrderFirst), newPod("pod-2", "soon"), newPod("pod-3", ""))
+
+	var evicted []string
+	cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+		if eviction.DeleteOptions != nil && len(eviction.DeleteOptions.DryRun) > 0 {
+			if eviction.Name == "pod-1" {
+				return true, nil, apierrors.NewTooManyRequests("disruption budget", 10)
+			}
+			return true, nil, nil
+		}
+		evicted = append(evicted, eviction.Name)
+		return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+
+	recorder := record.NewFakeRecorder(100)
+	d := NewAPIDrainer(cs, NewEventRecorder(recorder), WithEvictDryRunFirst(true), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+	for i := 0; i < 2; i++ {
+		err := d.Drain(context.Background(), node)
+		assert.Error(t, err)
+		assert.Equal(t, EvictionDryRunFailed, GetFailureCause(err))
+	}
+	assert.Empty(t, evicted, "the later waves wait for the pod skipped in the first wave")
+
+	close(recorder.Events)
+	var invalidOrderEvents int
+	for e := range recorder.Events {
+		if strings.Contains(e, EventReasonBadValueForAnnotation.String()) {
+			invalidOrderEvents++
+		}
+	}
+	assert.Equal(t, 1, invalidOrderEvents, "the invalid eviction order is reported once for the drain of the node")
+}
+
+func TestAPIDrainer_podsEvictedMetric(t *testing.T) {
+	podsEvictedView := &view.View{
+		Name:        "test_pods_evicted",
+		Measure:     MeasurePodsEvicted,
+		Aggregation: view.Count(),
+		TagKeys:     []tag.Key{TagNamespace, TagCustomEvictor},
+	}
+	assert.NoError(t, view.Register(podsEvictedView))
+	defer view.Unregister(podsEvictedView)
+
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	newPod := func(name, namespace string) *core.Pod {
+		return &core.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: namespace}, Spec: core.PodSpec{NodeName: nodeName}}
+	}
+	cs := fake.NewSimpleClientset(node, newPod("pod-1", "ns-a"), newPod("pod-2", "ns-a"), newPod("pod-3", "ns-b"))
+	cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+		return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+
+	d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+	assert.NoError(t, d.Drain(context.Background(), node))
+
+	rows, err := view.RetrieveData(podsEvictedView.Name)
+	assert.NoError(t, err)
+	counts := map[string]int64{}
+	for _, row := range rows {
+		assert.Contains(t, row.Tags, tag.Tag{Key: TagCustomEvictor, Value: "false"})
+		for _, tg := range row.Tags {
+			if tg.Key == TagNamespace {
+				counts[tg.Value] = row.Data.(*view.CountData).Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{"ns-a": 2, "ns-b": 1}, counts)
+}
+
+func TestAPIDrainer_waitForReplacementPod(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	newPod := func(name, node string, ready bool) *core.Pod {
+		readyStatus := core.ConditionFalse
+		if ready {
+			readyStatus = core.ConditionTrue
+		}
+		return &core.Pod{
+			ObjectMeta: meta.ObjectMeta{
+				Name:            name,
+				Namespace:       "ns",
+				UID:             types.UID(name),
+				OwnerReferences: []meta.OwnerReference{{Controller: &isController, Kind: kindReplicaSet, Name: "web-abc"}},
+			},
+			Spec: core.PodSpec{NodeName: node},
+			Status: core.PodStatus{
+				Phase:      core.PodRunning,
+				Conditions: []core.PodCondition{{Type: core.PodReady, Status: readyStatus}},
+			},
+		}
+	}
+	newReplicaSet := func(replicas int32) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: meta.ObjectMeta{Name: "web-abc", Namespace: "ns"},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+		}
+	}
+	createdAt := func(pod *core.Pod, t time.Time) *core.Pod {
+		pod.CreationTimestamp = meta.NewTime(t)
+		return pod
+	}
+
+	tests := []struct {
+		name            string
+		objects         []runtime.Object
+		expectedEvicted []string
+		expectedCause   FailureCause
+	}{
+		{
+			name:            "replacement running on another node",
+			objects:         []runtime.Object{newReplicaSet(2), newPod("web-1", nodeName, true), newPod("web-2", "other-node", true)},
+			expectedEvicted: []string{"web-1"},
+		},
+		{
+			name:          "no replacement",
+			objects:       []runtime.Object{newReplicaSet(2), newPod("web-1", nodeName, true)},
+			expectedCause: ReplacementPodTimeout,
+		},
+		{
+			name:          "replacement not ready",
+			objects:       []runtime.Object{newReplicaSet(2), newPod("web-1", nodeName, true), newPod("web-2", "other-node", false)},
+			expectedCause: ReplacementPodTimeout,
+		},
+		{
+			name:          "single replica without replacement",
+			objects:       []runtime.Object{newReplicaSet(1), newPod("web-1", nodeName, true)},
+			expectedCause: ReplacementPodTimeout,
+		},
+		{
+			name:          "single replica with a pod created before the wait",
+			objects:       []runtime.Object{newReplicaSet(1), newPod("web-1", nodeName, true), createdAt(newPod("web-2", "other-node", true), time.Now().Add(-time.Hour))},
+			expectedCause: ReplacementPodTimeout,
+		},
+		{
+			name:            "single replica with a replacement created after the wait started",
+			objects:         []runtime.Object{newReplicaSet(1), newPod("web-1", nodeName, true), createdAt(newPod("web-2", "other-node", true), time.Now().Add(time.Hour))},
+			expectedEvicted: []string{"web-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs := fake.NewSimpleClientset(append(tt.objects, node)...)
+			store, closeStore := RunStoreForTest(context.Background(), cs)
+			defer closeStore()
+			var evicted []string
+			cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+				evicted = append(evicted, eviction.Name)
+				return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+			})
+
+			// the fake clientset ignores the field selector on the node name
+			onNode := func(p core.Pod) (bool, string, error) { return p.Spec.NodeName == nodeName, "", nil }
+			d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithPodFilter(onNode), WithWaitForReplacementPod(100*time.Millisecond), WithRuntimeObjectStore(store), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+			err := d.Drain(context.Background(), node)
+
+			if tt.expectedCause != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedCause, GetFailureCause(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedEvicted, evicted)
+		})
+	}
+
+	t.Run("no runtime object store", func(t *testing.T) {
+		d := NewAPIDrainer(fake.NewSimpleClientset(), NewEventRecorder(&record.FakeRecorder{}), WithWaitForReplacementPod(100*time.Millisecond))
+		assert.Error(t, d.awaitReplacementPod(context.Background(), node, newPod("web-1", nodeName, true)))
+	})
+}
+
+func TestAPIDrainer_evictionConfirmation(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	newPod := func(name string, annotated bool) *core.Pod {
+		pod := &core.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "ns"}, Spec: core.PodSpec{NodeName: nodeName}}
+		if annotated {
+			pod.Annotations = map[string]string{DefaultEvictionConfirmationAnnotationKey: "db-primary"}
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name            string
+		decision        EvictionConfirmationDecision
+		statusCode      int
+		pods            []runtime.Object
+		expectedEvicted []string
+		expectedAsked   int
+		expectedRetried bool
+		expectedCause   FailureCause
+	}{
+		{
+			name:            "approved",
+			decision:        EvictionConfirmationApprove,
+			pods:            []runtime.Object{newPod("pod-1", true)},
+			expectedEvicted: []string{"pod-1"},
+			expectedAsked:   1,
+		},
+		{
+			name:          "denied",
+			decision:      EvictionConfirmationDeny,
+			pods:          []runtime.Object{newPod("pod-1", true)},
+			expectedAsked: 1,
+			expectedCause: PodEvictionDenied,
+		},
+		{
+			name:            "not annotated",
+			decision:        EvictionConfirmationDeny,
+			pods:            []runtime.Object{newPod("pod-1", false)},
+			expectedEvicted: []string{"pod-1"},
+		},
+		{
+			name:            "no decision before the timeout",
+			decision:        EvictionConfirmationWait,
+			pods:            []runtime.Object{newPod("pod-1", true)},
+			expectedEvicted: []string{"pod-1"},
+			expectedRetried: true,
+		},
+		{
+			name:            "endpoint error until the timeout",
+			statusCode:      http.StatusInternalServerError,
+			pods:            []runtime.Object{newPod("pod-1", true)},
+			expectedEvicted: []string{"pod-1"},
+			expectedRetried: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked int32
+			server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&asked, 1)
+				var request EvictionConfirmationRequest
+				if err := json.NewDecoder(req.Body).Decode(&request); err != nil || request.Annotation != "db-primary" {
+					res.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if tt.statusCode != 0 {
+					res.WriteHeader(tt.statusCode)
+					return
+				}
+				json.NewEncoder(res).Encode(EvictionConfirmationResponse{Decision: tt.decision})
+			}))
+			defer server.Close()
+
+			cs := fake.NewSimpleClientset(append(tt.pods, node)...)
+			var evicted []string
+			cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+				evicted = append(evicted, eviction.Name)
+				return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+			})
+
+			d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithEvictionConfirmation(DefaultEvictionConfirmationAnnotationKey, server.URL, 250*time.Millisecond), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+			d.evictionConfirmation.pollPeriod = 100 * time.Millisecond
+			err := d.Drain(context.Background(), node)
+
+			if tt.expectedCause != "" {
+				assert.Error(t, err)
+				assert.Equal(t, tt.expectedCause, GetFailureCause(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedEvicted, evicted)
+			if tt.expectedRetried {
+				assert.Greater(t, int(atomic.LoadInt32(&asked)), 1)
+			} else {
+				assert.Equal(t, tt.expectedAsked, int(atomic.LoadInt32(&asked)))
+			}
+		})
+	}
+}
+
+// slowDeletionClient delays the answers of the Get calls, the pods take that long to disappear
+type slowDeletionClient struct {
+	client.Client
+	delay time.Duration
+}
+
+func (c slowDeletionClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	time.Sleep(c.delay)
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func TestAPIDrainer_podTerminationLatencyMetric(t *testing.T) {
+	podTerminationLatencyView := &view.View{
+		Name:        "test_pod_termination_latency",
+		Measure:     MeasurePodTerminationLatency,
+		Aggregation: view.Distribution(0.01, 1),
+		TagKeys:     []tag.Key{TagNamespace},
+	}
+	assert.NoError(t, view.Register(podTerminationLatencyView))
+	defer view.Unregister(podTerminationLatencyView)
+
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	pod := &core.Pod{ObjectMeta: meta.ObjectMeta{Name: "pod-1", Namespace: "ns-a"}, Spec: core.PodSpec{NodeName: nodeName}}
+	cs := fake.NewSimpleClientset(node, pod)
+	cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+		return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+
+	crClient := slowDeletionClient{Client: crfake.NewClientBuilder().Build(), delay: 50 * time.Millisecond}
+	d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithContainerRuntimeClient(crClient), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+	assert.NoError(t, d.Drain(context.Background(), node))
+
+	rows, err := view.RetrieveData(podTerminationLatencyView.Name)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 1) {
+		assert.Equal(t, []tag.Tag{{Key: TagNamespace, Value: "ns-a"}}, rows[0].Tags)
+		distribution := rows[0].Data.(*view.DistributionData)
+		assert.Equal(t, int64(1), distribution.Count)
+		assert.GreaterOrEqual(t, distribution.Min, 0.05, "the latency should include the delay of the deletion")
+		assert.Equal(t, []int64{0, 1, 0}, distribution.CountPerBucket)
+	}
+}
+
+func TestAPIDrainer_pvcDeletionLimiter(t *testing.T) {
+	pod := &core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "ns"}}
+	var pvcs []*core.PersistentVolumeClaim
+	var objects []runtime.Object
+	for i := 0; i < 3; i++ {
+		pvc := &core.PersistentVolumeClaim{ObjectMeta: meta.ObjectMeta{Name: fmt.Sprintf("data-%d", i), Namespace: "ns", UID: types.UID(fmt.Sprintf("uid-%d", i))}}
+		pvcs = append(pvcs, pvc)
+		objects = append(objects, pvc.DeepCopy())
+	}
+	crClient := crfake.NewClientBuilder().WithRuntimeObjects(objects...).Build()
+	cs := fake.NewSimpleClientset(objects...)
+	// the PVCs deleted with the clientset disappear from the controller-runtime client too
+	cs.PrependReactor("delete", "persistentvolumeclaims", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		deletion := a.(clienttesting.DeleteAction)
+		pvc := &core.PersistentVolumeClaim{ObjectMeta: meta.ObjectMeta{Name: deletion.GetName(), Namespace: deletion.GetNamespace()}}
+		return false, nil, crClient.Delete(context.Background(), pvc)
+	})
+
+	d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithContainerRuntimeClient(crClient), WithPVCDeletionLimiter(NewPVCDeletionLimiter(20)))
+
+	start := time.Now()
+	deleted, err := d.deletePVCAssociatedWithStorageClass(context.Background(), pod, pvcs)
+	assert.NoError(t, err)
+	assert.Len(t, deleted, 3)
+	// the first deletion consumes the burst, the next ones wait 50ms each for a token
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestAPIDrainer_snapshotEvictedPods(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName, UID: "node-uid"},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	pod := &core.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Name:            "pod-1",
+			Namespace:       "ns",
+			OwnerReferences: []meta.OwnerReference{{Kind: "ReplicaSet", Name: "rs"}},
+			Annotations:     map[string]string{core.LastAppliedConfigAnnotation: "{}"},
+		},
+		Spec: core.PodSpec{
+			NodeName: nodeName,
+			Containers: []core.Container{{Name: "app", Env: []core.EnvVar{
+				{Name: "PASSWORD", Value: "secret"},
+				{Name: "TOKEN", ValueFrom: &core.EnvVarSource{SecretKeyRef: &core.SecretKeySelector{LocalObjectReference: core.LocalObjectReference{Name: "app"}, Key: "token"}}},
+			}}},
+			Volumes: []core.Volume{{Name: "data", VolumeSource: core.VolumeSource{PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{ClaimName: "data"}}}},
+		},
+	}
+
+	cs := fake.NewSimpleClientset(node, pod)
+	cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+		return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+	d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithEvictedPodsSnapshot("draino"), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+	assert.NoError(t, d.Drain(context.Background(), node))
+
+	cm, err := cs.CoreV1().ConfigMaps("draino").Get(context.Background(), EvictedPodsSnapshotName(nodeName), meta.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, nodeName, cm.Labels[EvictedPodsSnapshotLabelKey])
+	assert.Equal(t, []meta.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: nodeName, UID: "node-uid"}}, cm.OwnerReferences, "the snapshots are deleted with the node")
+	assert.Len(t, cm.Data, 1, "one snapshot per drain")
+	for _, content := range cm.Data {
+		var snapshot EvictedPodsSnapshot
+		assert.NoError(t, json.Unmarshal([]byte(content), &snapshot))
+		assert.Equal(t, nodeName, snapshot.Node)
+		if assert.Len(t, snapshot.Pods, 1) {
+			p := snapshot.Pods[0]
+			assert.Equal(t, "pod-1", p.Name)
+			assert.Equal(t, pod.OwnerReferences, p.OwnerReferences)
+			assert.Equal(t, pod.Spec.Volumes, p.Spec.Volumes)
+			assert.Equal(t, redactedValue, p.Annotations[core.LastAppliedConfigAnnotation])
+			assert.Equal(t, redactedValue, p.Spec.Containers[0].Env[0].Value)
+			assert.Equal(t, pod.Spec.Containers[0].Env[1], p.Spec.Containers[0].Env[1], "secrets are kept as references")
+		}
+	}
+}
+
+func TestPruneEvictedPodsSnapshots(t *testing.T) {
+	snapshot := func(size int) string { return strings.Repeat("x", size) }
+
+	data := map[string]string{"20230101T000000Z": snapshot(10), "20230102T000000Z": snapshot(10), "20230103T000000Z": snapshot(10), "20230104T000000Z": snapshot(10)}
+	pruneEvictedPodsSnapshots(data)
+	assert.ElementsMatch(t, []string{"20230102T000000Z", "20230103T000000Z", "20230104T000000Z"}, maps.Keys(data), "the last snapshots are kept")
+
+	data = map[string]string{"20230101T000000Z": snapshot(300 * 1024), "20230102T000000Z": snapshot(300 * 1024), "20230103T000000Z": snapshot(300 * 1024)}
+	pruneEvictedPodsSnapshots(data)
+	assert.ElementsMatch(t, []string{"20230102T000000Z", "20230103T000000Z"}, maps.Keys(data), "the total size is bounded")
+
+	data = map[string]string{"20230101T000000Z": snapshot(evictedPodsSnapshotMaxTotalBytes + 1)}
+	pruneEvictedPodsSnapshots(data)
+	assert.Len(t, data, 1, "the last snapshot is always kept")
+}
+
+func TestAPIDrainer_stuckTerminatingAction(t *testing.T) {
+	tests := []struct {
+		name            string
+		action          StuckTerminatingAction
+		finalizers      []string
+		expectedErr     bool
+		expectedDeleted bool
+		expectedEvent   string
+		expectedLatency bool
+	}{
+		{
+			name:        "wait until the eviction times out",
+			action:      StuckTerminatingActionWait,
+			expectedErr: true,
+		},
+		{
+			name:            "force delete the pod",
+			action:          StuckTerminatingActionForce,
+			expectedDeleted: true,
+			expectedEvent:   "Warning PodStuckTerminating Pod ns/pod-1 still terminating after 100ms, force deleting it",
+			expectedLatency: true,
+		},
+		{
+			name:          "skip the pod held by a finalizer",
+			action:        StuckTerminatingActionSkip,
+			finalizers:    []string{"example.com/hold"},
+			expectedEvent: "Warning PodStuckTerminating Pod ns/pod-1 still terminating after 100ms (finalizers: example.com/hold), skipping it",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			podTerminationLatencyView := &view.View{
+				Name:        "test_stuck_pod_termination_latency",
+				Measure:     MeasurePodTerminationLatency,
+				Aggregation: view.Count(),
+			}
+			assert.NoError(t, view.Register(podTerminationLatencyView))
+			defer view.Unregister(podTerminationLatencyView)
+
+			node := &core.Node{
+				ObjectMeta: meta.ObjectMeta{Name: nodeName},
+				Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+			}
+			pod := &core.Pod{
+				ObjectMeta: meta.ObjectMeta{Name: "pod-1", Namespace: "ns", UID: "uid-1", Finalizers: tt.finalizers},
+				Spec:       core.PodSpec{NodeName: nodeName, TerminationGracePeriodSeconds: pointer.Int64(0)},
+			}
+			// the pod is never removed by the eviction, only a deletion with a zero grace period gets rid of it
+			crClient := crfake.NewClientBuilder().WithRuntimeObjects(pod.DeepCopy()).Build()
+			cs := fake.NewSimpleClientset(node, pod)
+			cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				if a.GetSubresource() != "eviction" {
+					return false, nil, nil
+				}
+				return true, nil, nil
+			})
+			var forceDeleted bool
+			cs.PrependReactor("delete", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+				deletion := a.(clienttesting.DeleteActionImpl)
+				if gracePeriod := deletion.DeleteOptions.GracePeriodSeconds; gracePeriod != nil && *gracePeriod == 0 {
+					forceDeleted = true
+					return false, nil, crClient.Delete(context.Background(), &core.Pod{ObjectMeta: meta.ObjectMeta{Name: deletion.GetName(), Namespace: deletion.GetNamespace()}})
+				}
+				return false, nil, nil
+			})
+
+			recorder := record.NewFakeRecorder(100)
+			d := NewAPIDrainer(cs, NewEventRecorder(recorder), WithStuckTerminatingAction(tt.action, 100*time.Millisecond), WithContainerRuntimeClient(crClient), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+			err := d.Drain(context.Background(), node)
+
+			if tt.expectedErr {
+				assert.Error(t, err)
+				assert.Equal(t, PodDeletionTimeout, GetFailureCause(err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedDeleted, forceDeleted)
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			if tt.expectedEvent != "" {
+				assert.Contains(t, events, tt.expectedEvent)
+			}
+			rows, err := view.RetrieveData(podTerminationLatencyView.Name)
+			assert.NoError(t, err)
+			if tt.expectedLatency && assert.Len(t, rows, 1) {
+				assert.Equal(t, int64(1), rows[0].Data.(*view.CountData).Value, "the latency should be recorded once per pod")
+			} else if !tt.expectedLatency {
+				assert.Empty(t, rows)
+			}
+		})
+	}
+}
+
+// newDisruptionBudgetError is the error returned by the eviction API when a disruption budget refuses the eviction
+func newDisruptionBudgetError(retryAfterSeconds int) error {
+	err := apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", retryAfterSeconds)
+	err.ErrStatus.Details.Causes = append(err.ErrStatus.Details.Causes, meta.StatusCause{Type: disruptionBudgetCause, Message: "The disruption budget my-pdb needs 1 healthy pods and has 1 currently"})
+	return err
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+// EventReason is the reason of the events emitted by draino along the lifecycle of the candidates, drains and evictions.
+// The values are stable so that the consumers of the events can match them.
+type EventReason string
+
+const (
+	// EventReasonDrainStarting is emitted when the drain of a node starts
+	EventReasonDrainStarting EventReason = "DrainStarting"
+	// EventReasonDrainSucceeded is emitted when all the pods of a node are evicted
+	EventReasonDrainSucceeded EventReason = "DrainSucceeded"
+	// EventReasonDrainFailed is emitted when a drain fails
+	EventReasonDrainFailed EventReason = "DrainFailed"
+	// EventReasonDrainDeadlineApproaching is emitted when a drain is still running close to its timeout
+	EventReasonDrainDeadlineApproaching EventReason = "DrainDeadlineApproaching"
+	// EventReasonDrainAborted is emitted when a drain is aborted because the node recovered
+	EventReasonDrainAborted EventReason = "DrainAborted"
+	// EventReasonDrainSchedulingFailed is emitted when a candidate is released after repeated failures to start its drain
+	EventReasonDrainSchedulingFailed EventReason = "DrainSchedulingFailed"
+	// EventReasonDrainSummary is emitted with --emit-drain-summary when a drain completes, it sums up what the drain did
+	EventReasonDrainSummary EventReason = "DrainSummary"
+	// EventReasonDrainNowRequested is emitted when a node is requested for an immediate drain
+	EventReasonDrainNowRequested EventReason = "DrainNowRequested"
+	// EventReasonDrainNowBlocked is emitted when the immediate drain of a node is blocked
+	EventReasonDrainNowBlocked EventReason = "DrainNowBlocked"
+	// EventReasonDrainWindowInvalid is emitted when the drain window annotation of a node cannot be parsed
+	EventReasonDrainWindowInvalid EventReason = "DrainWindowInvalid"
+	// EventReasonNodeQuarantined is emitted when a node is quarantined
+	EventReasonNodeQuarantined EventReason = "NodeQuarantined"
+	// EventReasonReplacementNotReady is emitted when the drain is completed even though some workloads have no ready replacement
+	EventReasonReplacementNotReady EventReason = "ReplacementNotReady"
+
+	// EventReasonEvictionStarting is emitted when the eviction of a pod starts
+	EventReasonEvictionStarting EventReason = "EvictionStarting"
+	// EventReasonEvictionSucceeded is emitted when a pod is evicted
+	EventReasonEvictionSucceeded EventReason = "EvictionSucceeded"
+	// EventReasonEvictionFailed is emitted when the eviction of a pod fails
+	EventReasonEvictionFailed EventReason = "EvictionFailed"
+	// EventReasonEvictionAttemptFailed is emitted when an attempt to evict a pod fails and is retried
+	EventReasonEvictionAttemptFailed EventReason = "EvictionAttemptFailed"
+	// EventReasonEvictionDenied is emitted when the eviction confirmation endpoint of a pod denies its eviction
+	EventReasonEvictionDenied EventReason = "EvictionDenied"
+	// EventReasonEvictionConfirmationTimeout is emitted when the eviction confirmation endpoint of a pod does not answer in time
+	EventReasonEvictionConfirmationTimeout EventReason = "EvictionConfirmationTimeout"
+	// EventReasonPodStuckTerminating is emitted when an evicted pod is still terminating after the stuck terminating timeout
+	EventReasonPodStuckTerminating EventReason = "PodStuckTerminating"
+	// EventReasonBadValueForAnnotation is emitted when an annotation of a pod or its controller cannot be parsed
+	EventReasonBadValueForAnnotation EventReason = "BadValueForAnnotation"
+	// EventReasonPendingPodWithLocalPV is emitted when a pod evicted from the node is still bound to it by a local PV
+	EventReasonPendingPodWithLocalPV EventReason = "PodBoundToNodeViaLocalPV"
+	// EventReasonVolumeDeletion is emitted on the PVs and PVCs deleted along with an evicted pod
+	EventReasonVolumeDeletion EventReason = "Eviction"
+	// EventReasonVolumeDeletionFailed is emitted when the PV or PVC of an evicted pod cannot be deleted
+	EventReasonVolumeDeletionFailed EventReason = "EvictionFailure"
+	// EventReasonReleasedPVCleanup is emitted when a released PV is deleted
+	EventReasonReleasedPVCleanup EventReason = "ReleasedPVCleanup"
+
+	// EventReasonGlobalBlockerActivated is emitted when a global blocker stops the drains
+	EventReasonGlobalBlockerActivated EventReason = "GlobalBlockerActivated"
+	// EventReasonGlobalBlockerDeactivated is emitted when a global blocker allows the drains again
+	EventReasonGlobalBlockerDeactivated EventReason = "GlobalBlockerDeactivated"
+)
+
+func (r EventReason) String() string {
+	return string(r)
+}
+
+// AllEventReasons returns all the reasons of the events emitted by draino
+func AllEventReasons() []EventReason {
+	return []EventReason{
+		EventReasonDrainStarting,
+		EventReasonDrainSucceeded,
+		EventReasonDrainFailed,
+		EventReasonDrainDeadlineApproaching,
+		EventReasonDrainAborted,
+		EventReasonDrainSchedulingFailed,
+		EventReasonDrainSummary,
+		EventReasonDrainNowRequested,
+		EventReasonDrainNowBlocked,
+		EventReasonDrainWindowInvalid,
+		EventReasonNodeQuarantined,
+		EventReasonReplacementNotReady,
+		EventReasonEvictionStarting,
+		EventReasonEvictionSucceeded,
+		EventReasonEvictionFailed,
+		EventReasonEvictionAttemptFailed,
+		EventReasonEvictionDenied,
+		EventReasonEvictionConfirmationTimeout,
+		EventReasonPodStuckTerminating,
+		EventReasonBadValueForAnnotation,
+		EventReasonPendingPodWithLocalPV,
+		EventReasonVolumeDeletion,
+		EventReasonVolumeDeletionFailed,
+		EventReasonReleasedPVCleanup,
+		EventReasonGlobalBlockerActivated,
+		EventReasonGlobalBlockerDeactivated,
+	}
+}
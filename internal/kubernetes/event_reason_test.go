@@ -0,0 +1,35 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllEventReasons(t *testing.T) {
+	seen := map[string]bool{}
+	for _, r := range AllEventReasons() {
+		assert.NotEmpty(t, r.String())
+		assert.Equal(t, string(r), r.String())
+		assert.False(t, seen[r.String()], "duplicated reason %s", r)
+		seen[r.String()] = true
+	}
+
+	// the values are matched by the consumers of the events, they must not change
+	for reason, value := range map[EventReason]string{
+		EventReasonDrainStarting:         "DrainStarting",
+		EventReasonDrainSucceeded:        "DrainSucceeded",
+		EventReasonDrainFailed:           "DrainFailed",
+		EventReasonDrainAborted:          "DrainAborted",
+		EventReasonDrainSchedulingFailed: "DrainSchedulingFailed",
+		EventReasonEvictionStarting:      "EvictionStarting",
+		EventReasonEvictionSucceeded:     "EvictionSucceeded",
+		EventReasonEvictionFailed:        "EvictionFailed",
+		EventReasonPendingPodWithLocalPV: "PodBoundToNodeViaLocalPV",
+		EventReasonVolumeDeletion:        "Eviction",
+		EventReasonVolumeDeletionFailed:  "EvictionFailure",
+	} {
+		assert.Equal(t, value, reason.String())
+		assert.Contains(t, AllEventReasons(), reason)
+	}
+}
@@ -30,12 +30,21 @@ type EventRecorder interface {
 
 type eventRecorder struct {
 	eventRecorder record.EventRecorder
+	// changeTicketAnnotationKey is the node annotation giving the change ticket added to the node events
+	changeTicketAnnotationKey string
 }
 
 // NewEventRecorder returns a new record.EventRecorder for the given client.
 func NewEventRecorder(k8sEventRecorder record.EventRecorder) EventRecorder {
+	return NewEventRecorderWithChangeTicket(k8sEventRecorder, DefaultChangeTicketAnnotationKey)
+}
+
+// NewEventRecorderWithChangeTicket returns a new record.EventRecorder for the given client. The change ticket given by the
+// changeTicketAnnotationKey annotation of the nodes is added to their events.
+func NewEventRecorderWithChangeTicket(k8sEventRecorder record.EventRecorder, changeTicketAnnotationKey string) EventRecorder {
 	return &eventRecorder{
-		eventRecorder: k8sEventRecorder,
+		eventRecorder:             k8sEventRecorder,
+		changeTicketAnnotationKey: changeTicketAnnotationKey,
 	}
 }
 
@@ -58,6 +67,7 @@ func (e *eventRecorder) NodeEventf(ctx context.Context, obj *core.Node, eventTyp
 	// way that command is implemented.
 	// https://github.com/kubernetes/kubernetes/blob/17740a2/pkg/printers/internalversion/describe.go#L2711
 	nodeReference := &core.ObjectReference{Kind: "Node", Name: obj.GetName(), UID: types.UID(obj.GetName())}
+	messageFmt, args = withChangeTicket(obj, e.changeTicketAnnotationKey, messageFmt, args)
 	e.eventRecorder.Eventf(nodeReference, eventType, reason, messageFmt, args...)
 }
 
@@ -82,6 +92,60 @@ func (e *eventRecorder) PersistentVolumeClaimEventf(ctx context.Context, obj *co
 	e.eventRecorder.Eventf(obj, eventType, reason, messageFmt, args...)
 }
 
+const (
+	// NodeGroupKind and NodeGroupAPIVersion identify the nodegroup object owning the nodes
+	NodeGroupKind       = "NodeGroup"
+	NodeGroupAPIVersion = "datadoghq.com/v1alpha1"
+)
+
+// nodeGroupEventReasons are the drain lifecycle events that are also reported on the nodegroup
+var nodeGroupEventReasons = map[EventReason]bool{
+	EventReasonDrainStarting:  true,
+	EventReasonDrainSucceeded: true,
+	EventReasonDrainFailed:    true,
+}
+
+// nodeGroupEventRecorder also records the drain lifecycle events of the nodes on their nodegroup
+type nodeGroupEventRecorder struct {
+	EventRecorder
+	eventRecorder             record.EventRecorder
+	changeTicketAnnotationKey string
+}
+
+// NewNodeGroupEventRecorder wraps the given EventRecorder so that the drain lifecycle events of a node are also
+// recorded on the nodegroup it belongs to. The nodegroup is resolved from the node labels. The change ticket given by
+// the changeTicketAnnotationKey annotation of the node is added to the nodegroup events.
+func NewNodeGroupEventRecorder(base EventRecorder, k8sEventRecorder record.EventRecorder, changeTicketAnnotationKey string) EventRecorder {
+	return &nodeGroupEventRecorder{
+		EventRecorder:             base,
+		eventRecorder:             k8sEventRecorder,
+		changeTicketAnnotationKey: changeTicketAnnotationKey,
+	}
+}
+
+func (e *nodeGroupEventRecorder) NodeEventf(ctx context.Context, obj *core.Node, eventType, reason, messageFmt string, args ...interface{}) {
+	e.EventRecorder.NodeEventf(ctx, obj, eventType, reason, messageFmt, args...)
+
+	if !nodeGroupEventReasons[EventReason(reason)] {
+		return
+	}
+	nodeGroupReference := GetNodeGroupReference(obj)
+	if nodeGroupReference == nil {
+		return
+	}
+	messageFmt, args = withChangeTicket(obj, e.changeTicketAnnotationKey, messageFmt, args)
+	e.eventRecorder.Eventf(nodeGroupReference, eventType, reason, "Node %s: %s", obj.GetName(), fmt.Sprintf(messageFmt, args...))
+}
+
+// GetNodeGroupReference returns the reference of the nodegroup owning the node, nil if the node has no nodegroup labels
+func GetNodeGroupReference(node *core.Node) *core.ObjectReference {
+	name, namespace := node.Labels[LabelKeyNodeGroupName], node.Labels[LabelKeyNodeGroupNamespace]
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return &core.ObjectReference{Kind: NodeGroupKind, APIVersion: NodeGroupAPIVersion, Name: name, Namespace: namespace}
+}
+
 type NoopEventRecorder struct{}
 
 func (n NoopEventRecorder) NodeEventf(ctx context.Context, obj *core.Node, eventtype, reason, messageFmt string, args ...interface{}) {
@@ -94,17 +158,46 @@ func (n NoopEventRecorder) PersistentVolumeClaimEventf(ctx context.Context, obj
 }
 
 var _ EventRecorder = &NoopEventRecorder{}
+var _ EventRecorder = &nodeGroupEventRecorder{}
 
 var computeLRUSizeOnce sync.Once
 var lruSize = int(5000 * (6 + 1) * 1.10) // default value 5000 nodes with 6 pods (with +10%)
 var lruSizeMutex sync.Mutex
 
-func BuildEventRecorderWithAggregationOnEventType(logger logr.Logger, cs *client.Clientset, aggregationPeriod time.Duration, excludedPodsPerNode int, alsoLogEvents bool) (EventRecorder, record.EventRecorder) {
+func BuildEventRecorderWithAggregationOnEventType(logger logr.Logger, cs *client.Clientset, aggregationPeriod time.Duration, excludedPodsPerNode int, alsoLogEvents bool, changeTicketAnnotationKey string) (EventRecorder, record.EventRecorder) {
 	logger = logger.WithName("EventRecorder")
 	computeLRUSize(logger, cs, excludedPodsPerNode)
 
-	options := record.CorrelatorOptions{
-		LRUCacheSize: lruSize, // used for nodes and pods
+	b := record.NewBroadcasterWithCorrelatorOptions(aggregationOnEventTypeOptions(aggregationPeriod, lruSize))
+
+	b.StartRecordingToSink(&customEventSink{
+		base:      &typedcore.EventSinkImpl{Interface: typedcore.New(cs.CoreV1().RESTClient()).Events("")},
+		logger:    logger,
+		logEvents: alsoLogEvents,
+	})
+	k8sEventRecorder := b.NewRecorder(scheme.Scheme, core.EventSource{Component: Component})
+	return NewEventRecorderWithChangeTicket(k8sEventRecorder, changeTicketAnnotationKey), k8sEventRecorder
+}
+
+func BuildEventRecorderWithAggregationOnEventTypeAndMessage(logger logr.Logger, cs *client.Clientset, aggregationPeriod time.Duration, alsoLogEvents bool, changeTicketAnnotationKey string) (EventRecorder, record.EventRecorder) {
+	logger = logger.WithName("EventRecorder")
+
+	b := record.NewBroadcasterWithCorrelatorOptions(aggregationOnEventTypeAndMessageOptions(aggregationPeriod))
+
+	b.StartRecordingToSink(&customEventSink{
+		base:      &typedcore.EventSinkImpl{Interface: typedcore.New(cs.CoreV1().RESTClient()).Events("")},
+		logger:    logger,
+		logEvents: alsoLogEvents,
+	})
+	k8sEventRecorder := b.NewRecorder(scheme.Scheme, core.EventSource{Component: Component})
+	return NewEventRecorderWithChangeTicket(k8sEventRecorder, changeTicketAnnotationKey), k8sEventRecorder
+}
+
+// aggregationOnEventTypeOptions collapses the events of the same object, node or pod, having the same type and reason:
+// only one of them is emitted per aggregation period, whatever its message.
+func aggregationOnEventTypeOptions(aggregationPeriod time.Duration, lruCacheSize int) record.CorrelatorOptions {
+	return record.CorrelatorOptions{
+		LRUCacheSize: lruCacheSize, // used for nodes and pods
 		BurstSize:    1,
 		QPS:          float32(1 / aggregationPeriod.Seconds()),
 		MessageFunc:  func(event *core.Event) string { return event.Message }, // do not put any combined notification. It is useless since it appears for each message.
@@ -116,21 +209,12 @@ func BuildEventRecorderWithAggregationOnEventType(logger logr.Logger, cs *client
 
 		SpamKeyFunc: getSpamKeyEventReason,
 	}
-	b := record.NewBroadcasterWithCorrelatorOptions(options)
-
-	b.StartRecordingToSink(&customEventSink{
-		base:      &typedcore.EventSinkImpl{Interface: typedcore.New(cs.CoreV1().RESTClient()).Events("")},
-		logger:    logger,
-		logEvents: alsoLogEvents,
-	})
-	k8sEventRecorder := b.NewRecorder(scheme.Scheme, core.EventSource{Component: Component})
-	return NewEventRecorder(k8sEventRecorder), k8sEventRecorder
 }
 
-func BuildEventRecorderWithAggregationOnEventTypeAndMessage(logger logr.Logger, cs *client.Clientset, aggregationPeriod time.Duration, alsoLogEvents bool) (EventRecorder, record.EventRecorder) {
-	logger = logger.WithName("EventRecorder")
-
-	options := record.CorrelatorOptions{
+// aggregationOnEventTypeAndMessageOptions collapses the identical events of the same object, node or pod:
+// only one of them is emitted per aggregation period, the events with other messages are still emitted.
+func aggregationOnEventTypeAndMessageOptions(aggregationPeriod time.Duration) record.CorrelatorOptions {
+	return record.CorrelatorOptions{
 		LRUCacheSize: 1000, // 1000 event per minutes for evictions activity should be enough
 		BurstSize:    1,
 		QPS:          float32(1 / aggregationPeriod.Seconds()),
@@ -143,15 +227,6 @@ func BuildEventRecorderWithAggregationOnEventTypeAndMessage(logger logr.Logger,
 
 		SpamKeyFunc: getSpamKeyEventReasonAndMessage,
 	}
-	b := record.NewBroadcasterWithCorrelatorOptions(options)
-
-	b.StartRecordingToSink(&customEventSink{
-		base:      &typedcore.EventSinkImpl{Interface: typedcore.New(cs.CoreV1().RESTClient()).Events("")},
-		logger:    logger,
-		logEvents: alsoLogEvents,
-	})
-	k8sEventRecorder := b.NewRecorder(scheme.Scheme, core.EventSource{Component: Component})
-	return NewEventRecorder(k8sEventRecorder), k8sEventRecorder
 }
 
 func computeLRUSize(logger logr.Logger, cs *client.Clientset, excludedPodsPerNode int) {
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestNodeGroupEventRecorder(t *testing.T) {
+	nodeInGroup := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node-1", Labels: map[string]string{LabelKeyNodeGroupName: "ng", LabelKeyNodeGroupNamespace: "team"}}}
+	nodeWithoutGroup := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node-2"}}
+	nodeWithTicket := &core.Node{ObjectMeta: meta.ObjectMeta{
+		Name:        "node-3",
+		Labels:      map[string]string{LabelKeyNodeGroupName: "ng", LabelKeyNodeGroupNamespace: "team"},
+		Annotations: map[string]string{DefaultChangeTicketAnnotationKey: "JIRA-123"},
+	}}
+	nodeWithInvalidTicket := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node-4", Annotations: map[string]string{DefaultChangeTicketAnnotationKey: "not a ticket"}}}
+
+	tests := []struct {
+		name           string
+		node           *core.Node
+		reason         EventReason
+		expectedEvents []string
+	}{
+		{
+			name:   "drain lifecycle event",
+			node:   nodeInGroup,
+			reason: EventReasonDrainStarting,
+			expectedEvents: []string{
+				"Normal DrainStarting Draining node involvedObject{kind=Node,apiVersion=}",
+				"Normal DrainStarting Node node-1: Draining node involvedObject{kind=NodeGroup,apiVersion=datadoghq.com/v1alpha1}",
+			},
+		},
+		{
+			name:           "other events are only recorded on the node",
+			node:           nodeInGroup,
+			reason:         EventReasonEvictionStarting,
+			expectedEvents: []string{"Normal EvictionStarting Draining node involvedObject{kind=Node,apiVersion=}"},
+		},
+		{
+			name:           "node without nodegroup",
+			node:           nodeWithoutGroup,
+			reason:         EventReasonDrainStarting,
+			expectedEvents: []string{"Normal DrainStarting Draining node involvedObject{kind=Node,apiVersion=}"},
+		},
+		{
+			name:   "node with change ticket",
+			node:   nodeWithTicket,
+			reason: EventReasonDrainStarting,
+			expectedEvents: []string{
+				"Normal DrainStarting Draining node [change ticket JIRA-123] involvedObject{kind=Node,apiVersion=}",
+				"Normal DrainStarting Node node-3: Draining node [change ticket JIRA-123] involvedObject{kind=NodeGroup,apiVersion=datadoghq.com/v1alpha1}",
+			},
+		},
+		{
+			name:           "invalid change ticket is ignored",
+			node:           nodeWithInvalidTicket,
+			reason:         EventReasonDrainStarting,
+			expectedEvents: []string{"Normal DrainStarting Draining node involvedObject{kind=Node,apiVersion=}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeRecorder := record.NewFakeRecorder(10)
+			fakeRecorder.IncludeObject = true
+			recorder := NewNodeGroupEventRecorder(NewEventRecorder(fakeRecorder), fakeRecorder, DefaultChangeTicketAnnotationKey)
+
+			recorder.NodeEventf(context.Background(), tt.node, core.EventTypeNormal, tt.reason.String(), "Draining %s", "node")
+			close(fakeRecorder.Events)
+
+			var events []string
+			for e := range fakeRecorder.Events {
+				events = append(events, e)
+			}
+			assert.Equal(t, tt.expectedEvents, events)
+		})
+	}
+}
+
+func TestEventAggregation_PodEvents(t *testing.T) {
+	const aggregationPeriod = 5 * time.Minute
+	podEvent := func(podName, message string) *core.Event {
+		return &core.Event{
+			ObjectMeta:     meta.ObjectMeta{Name: podName + ".event", Namespace: "ns"},
+			InvolvedObject: core.ObjectReference{Kind: "Pod", Namespace: "ns", Name: podName, UID: types.UID(podName)},
+			Source:         core.EventSource{Component: Component},
+			Type:           core.EventTypeWarning,
+			Reason:         "EvictionSimulationFailed",
+			Message:        message,
+			Count:          1,
+		}
+	}
+
+	type step struct {
+		wait         time.Duration
+		event        *core.Event
+		expectedSkip bool
+	}
+	tests := []struct {
+		name    string
+		options record.CorrelatorOptions
+		steps   []step
+	}{
+		{
+			name:    "aggregation on event type",
+			options: aggregationOnEventTypeOptions(aggregationPeriod, 100),
+			steps: []step{
+				{event: podEvent("pod-1", "blocked by PDB"), expectedSkip: false},
+				{wait: time.Minute, event: podEvent("pod-1", "blocked by PDB"), expectedSkip: true},
+				{event: podEvent("pod-1", "blocked by annotation"), expectedSkip: true},
+				{event: podEvent("pod-2", "blocked by PDB"), expectedSkip: false},
+				{wait: aggregationPeriod, event: podEvent("pod-1", "blocked by PDB"), expectedSkip: false},
+			},
+		},
+		{
+			name:    "aggregation on event type and message",
+			options: aggregationOnEventTypeAndMessageOptions(aggregationPeriod),
+			steps: []step{
+				{event: podEvent("pod-1", "blocked by PDB"), expectedSkip: false},
+				{wait: time.Minute, event: podEvent("pod-1", "blocked by PDB"), expectedSkip: true},
+				{event: podEvent("pod-1", "blocked by annotation"), expectedSkip: false},
+				{event: podEvent("pod-2", "blocked by PDB"), expectedSkip: false},
+				{wait: aggregationPeriod, event: podEvent("pod-1", "blocked by PDB"), expectedSkip: false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			tt.options.Clock = fakeClock
+			correlator := record.NewEventCorrelatorWithOptions(tt.options)
+			for i, s := range tt.steps {
+				fakeClock.Step(s.wait)
+				result, err := correlator.EventCorrelate(s.event)
+				assert.NoError(t, err)
+				assert.Equal(t, s.expectedSkip, result.Skip, "step %d", i)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// EvictedPodsSnapshotLabelKey is set on the ConfigMaps holding the snapshots of the evicted pods, its value is the node name
+	EvictedPodsSnapshotLabelKey = "draino/evicted-pods-snapshot"
+
+	evictedPodsSnapshotNamePrefix = "draino-evicted-pods-"
+	evictedPodsSnapshotKeyFormat  = "20060102T150405Z"
+	// a ConfigMap is limited to 1MiB, it keeps the last snapshots of the node. The total size leaves a margin for the escaping of
+	// the JSON content when the ConfigMap is serialized.
+	evictedPodsSnapshotMaxBytes      = 256 * 1024
+	evictedPodsSnapshotMaxEntries    = 3
+	evictedPodsSnapshotMaxTotalBytes = 768 * 1024
+
+	redactedValue = "<redacted>"
+)
+
+// EvictedPodsSnapshot is the manifest of the pods captured before the drain of a node
+type EvictedPodsSnapshot struct {
+	Node string      `json:"node"`
+	Time meta.Time   `json:"time"`
+	Pods []*core.Pod `json:"pods"`
+	// Truncated is the number of pods left out of the snapshot to bound its size
+	Truncated int `json:"truncated,omitempty"`
+}
+
+// WithEvictedPodsSnapshot configures the drainer to capture the manifests of the pods before evicting them, in a ConfigMap per node
+// in the given namespace. An empty namespace disables the snapshots.
+func WithEvictedPodsSnapshot(namespace string) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.evictedPodsSnapshotNamespace = namespace
+	}
+}
+
+// EvictedPodsSnapshotName returns the name of the ConfigMap holding the snapshots of the pods evicted from the node
+func EvictedPodsSnapshotName(node string) string {
+	return evictedPodsSnapshotNamePrefix + node
+}
+
+// snapshotEvictedPods records the manifests of the pods in the snapshot ConfigMap of the node, under a key formed by the current time.
+// Only the last snapshots of the node are kept. The ConfigMap is owned by the node, it is garbage collected with it.
+func (d *APIDrainer) snapshotEvictedPods(ctx context.Context, node *core.Node, pods []*core.Pod) error {
+	if d.evictedPodsSnapshotNamespace == "" || len(pods) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	content, err := json.Marshal(newEvictedPodsSnapshot(node.GetName(), now, pods))
+	if err != nil {
+		return err
+	}
+
+	configMaps := d.c.CoreV1().ConfigMaps(d.evictedPodsSnapshotNamespace)
+	name := EvictedPodsSnapshotName(node.GetName())
+	key := now.Format(evictedPodsSnapshotKeyFormat)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := configMaps.Get(ctx, name, meta.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm = &core.ConfigMap{
+				ObjectMeta: meta.ObjectMeta{
+					Name:            name,
+					Namespace:       d.evictedPodsSnapshotNamespace,
+					Labels:          map[string]string{EvictedPodsSnapshotLabelKey: node.GetName()},
+					OwnerReferences: evictedPodsSnapshotOwnerReferences(node),
+				},
+				Data: map[string]string{key: string(content)},
+			}
+			_, err = configMaps.Create(ctx, cm, meta.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[key] = string(content)
+		pruneEvictedPodsSnapshots(cm.Data)
+		// the ConfigMaps created before the owner reference was set, or for a node recreated with the same name
+		cm.OwnerReferences = evictedPodsSnapshotOwnerReferences(node)
+		_, err = configMaps.Update(ctx, cm, meta.UpdateOptions{})
+		return err
+	})
+}
+
+// evictedPodsSnapshotOwnerReferences makes the node the owner of its snapshot ConfigMap, if the UID of the node is known
+func evictedPodsSnapshotOwnerReferences(node *core.Node) []meta.OwnerReference {
+	if node.GetUID() == "" {
+		return nil
+	}
+	return []meta.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: node.GetName(), UID: node.GetUID()}}
+}
+
+// pruneEvictedPodsSnapshots removes the oldest snapshots until the number of snapshots and their total size are within bounds.
+// The last snapshot is always kept, its size is bounded by evictedPodsSnapshotMaxBytes.
+func pruneEvictedPodsSnapshots(data map[string]string) {
+	// the keys are sorted by time thanks to their format
+	keys := make([]string, 0, len(data))
+	size := 0
+	for k, v := range data {
+		keys = append(keys, k)
+		size += len(k) + len(v)
+	}
+	sort.Strings(keys)
+	for i := 0; i < len(keys)-1; i++ {
+		if len(keys)-i <= evictedPodsSnapshotMaxEntries && size <= evictedPodsSnapshotMaxTotalBytes {
+			return
+		}
+		size -= len(keys[i]) + len(data[keys[i]])
+		delete(data, keys[i])
+	}
+}
+
+// newEvictedPodsSnapshot builds the snapshot of the redacted pods, the pods beyond the size bound are left out
+func newEvictedPodsSnapshot(node string, now time.Time, pods []*core.Pod) *EvictedPodsSnapshot {
+	snapshot := &EvictedPodsSnapshot{Node: node, Time: meta.NewTime(now)}
+	size := 0
+	for _, pod := range pods {
+		redacted := redactPod(pod)
+		content, err := json.Marshal(redacted)
+		if err != nil || size+len(content) > evictedPodsSnapshotMaxBytes {
+			snapshot.Truncated++
+			continue
+		}
+		size += len(content)
+		snapshot.Pods = append(snapshot.Pods, redacted)
+	}
+	return snapshot
+}
+
+// redactPod returns a copy of the pod without its status and managed fields. The literal environment values and the last applied
+// configuration are redacted, the secrets are only kept as references.
+func redactPod(pod *core.Pod) *core.Pod {
+	p := pod.DeepCopy()
+	p.ManagedFields = nil
+	p.Status = core.PodStatus{}
+	if _, ok := p.Annotations[core.LastAppliedConfigAnnotation]; ok {
+		p.Annotations[core.LastAppliedConfigAnnotation] = redactedValue
+	}
+	redactContainers := func(containers []core.Container) {
+		for i := range containers {
+			for j := range containers[i].Env {
+				if containers[i].Env[j].Value != "" {
+					containers[i].Env[j].Value = redactedValue
+				}
+			}
+		}
+	}
+	redactContainers(p.Spec.InitContainers)
+	redactContainers(p.Spec.Containers)
+	for i := range p.Spec.EphemeralContainers {
+		for j := range p.Spec.EphemeralContainers[i].Env {
+			if p.Spec.EphemeralContainers[i].Env[j].Value != "" {
+				p.Spec.EphemeralContainers[i].Env[j].Value = redactedValue
+			}
+		}
+	}
+	return p
+}
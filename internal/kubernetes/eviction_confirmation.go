@@ -0,0 +1,173 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	DefaultEvictionConfirmationAnnotationKey = "draino/eviction-confirmation"
+
+	evictionConfirmationRequestTimeout = 20 * time.Second
+	evictionConfirmationPollPeriod     = 10 * time.Second
+)
+
+// EvictionConfirmationDecision is the answer of the confirmation endpoint
+type EvictionConfirmationDecision string
+
+const (
+	// EvictionConfirmationApprove lets the eviction of the pod proceed
+	EvictionConfirmationApprove EvictionConfirmationDecision = "approve"
+	// EvictionConfirmationDeny fails the eviction of the pod, and so the drain attempt
+	EvictionConfirmationDeny EvictionConfirmationDecision = "deny"
+	// EvictionConfirmationWait asks draino to call the endpoint again later
+	EvictionConfirmationWait EvictionConfirmationDecision = "wait"
+)
+
+// EvictionConfirmationRequest is posted as JSON to the confirmation endpoint before evicting a pod carrying the confirmation annotation
+type EvictionConfirmationRequest struct {
+	Namespace  string   `json:"namespace"`
+	Pod        string   `json:"pod"`
+	Node       string   `json:"node"`
+	Conditions []string `json:"conditions"`
+	// Annotation is the value of the confirmation annotation of the pod
+	Annotation string `json:"annotation"`
+}
+
+// EvictionConfirmationResponse is the JSON answer of the confirmation endpoint
+type EvictionConfirmationResponse struct {
+	Decision EvictionConfirmationDecision `json:"decision"`
+	Reason   string                       `json:"reason,omitempty"`
+}
+
+// PodEvictionDeniedError is returned when the confirmation endpoint denied the eviction of the pod
+type PodEvictionDeniedError struct {
+	Pod    string
+	Reason string
+}
+
+func (e PodEvictionDeniedError) Error() string {
+	return fmt.Sprintf("eviction of pod %s denied by the confirmation endpoint: %s", e.Pod, e.Reason)
+}
+
+func (e PodEvictionDeniedError) Cause() FailureCause {
+	return PodEvictionDenied
+}
+
+// evictionConfirmation is the configuration of the confirmation asked before evicting the annotated pods
+type evictionConfirmation struct {
+	annotationKey string
+	endpoint      string
+	timeout       time.Duration
+	pollPeriod    time.Duration
+	httpClient    *http.Client
+}
+
+// WithEvictionConfirmation configures the drainer to ask the endpoint to confirm the eviction of the pods carrying the annotation.
+// The endpoint is called again until it approves or denies the eviction. Without decision after the timeout, the pod is evicted.
+// An empty endpoint disables the confirmation.
+func WithEvictionConfirmation(annotationKey, endpoint string, timeout time.Duration) APIDrainerOption {
+	return func(d *APIDrainer) {
+		if endpoint == "" {
+			d.evictionConfirmation = nil
+			return
+		}
+		d.evictionConfirmation = &evictionConfirmation{
+			annotationKey: annotationKey,
+			endpoint:      endpoint,
+			timeout:       timeout,
+			pollPeriod:    evictionConfirmationPollPeriod,
+			httpClient:    &http.Client{Timeout: evictionConfirmationRequestTimeout},
+		}
+	}
+}
+
+// confirmEviction waits for the confirmation endpoint to approve the eviction of the pod if it carries the confirmation annotation.
+// It returns a PodEvictionDeniedError if the endpoint denied the eviction.
+func (d *APIDrainer) confirmEviction(ctx context.Context, node *core.Node, pod *core.Pod) error {
+	if d.evictionConfirmation == nil {
+		return nil
+	}
+	value, ok := pod.GetAnnotations()[d.evictionConfirmation.annotationKey]
+	if !ok {
+		return nil
+	}
+
+	request := &EvictionConfirmationRequest{
+		Namespace:  pod.GetNamespace(),
+		Pod:        pod.GetName(),
+		Node:       node.GetName(),
+		Conditions: GetConditionIDs(GetNodeOffendingConditions(node, d.globalConfig.SuppliedConditions)),
+		Annotation: value,
+	}
+	logger := LoggerForNode(node, d.globalConfig.TeamLabelKey, d.l).With(zap.String("pod", pod.GetNamespace()+"/"+pod.GetName()))
+	var denied *PodEvictionDeniedError
+	err := wait.PollImmediate(d.evictionConfirmation.pollPeriod, d.evictionConfirmation.timeout, func() (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		response, err := d.evictionConfirmation.ask(ctx, request)
+		if err != nil {
+			// the endpoint is called again at the next period
+			logger.Warn("cannot get the eviction confirmation", zap.Error(err))
+			return false, nil
+		}
+		switch response.Decision {
+		case EvictionConfirmationApprove:
+			logger.Info("eviction approved", zap.String("reason", response.Reason))
+			return true, nil
+		case EvictionConfirmationDeny:
+			denied = &PodEvictionDeniedError{Pod: pod.GetNamespace() + "/" + pod.GetName(), Reason: response.Reason}
+			return false, denied
+		case EvictionConfirmationWait:
+			return false, nil
+		}
+		logger.Warn("unknown decision from the eviction confirmation endpoint", zap.String("decision", string(response.Decision)))
+		return false, nil
+	})
+	if denied != nil {
+		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonEvictionDenied.String(), "Eviction denied by the confirmation endpoint: %s", denied.Reason)
+		return *denied
+	}
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonEvictionConfirmationTimeout.String(), "No eviction confirmation after %s, evicting the pod", d.evictionConfirmation.timeout)
+		return nil
+	}
+	return err
+}
+
+func (c *evictionConfirmation) ask(ctx context.Context, request *EvictionConfirmationRequest) (*EvictionConfirmationResponse, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("confirmation endpoint request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("confirmation endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var response EvictionConfirmationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("cannot decode the confirmation endpoint response: %w", err)
+	}
+	return &response, nil
+}
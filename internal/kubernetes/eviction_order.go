@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// EvictionOrderAnnotationKey lets the workload owner choose when its pod is evicted during the drain of the node.
+	// The value is "first", "last" or an integer; the pods with the lowest value are evicted first, the default is 0.
+	EvictionOrderAnnotationKey = "draino/evict-order"
+	EvictionOrderFirst         = "first"
+	EvictionOrderLast          = "last"
+
+	evictionOrderDefault = 0
+)
+
+// GetPodEvictionOrder returns the eviction rank of the pod. An invalid value falls back to the default rank and returns an error.
+func GetPodEvictionOrder(pod *core.Pod) (int, error) {
+	value, ok := pod.GetAnnotations()[EvictionOrderAnnotationKey]
+	if !ok {
+		return evictionOrderDefault, nil
+	}
+	switch value {
+	case EvictionOrderFirst:
+		return math.MinInt32, nil
+	case EvictionOrderLast:
+		return math.MaxInt32, nil
+	}
+	order, err := strconv.Atoi(value)
+	if err != nil {
+		return evictionOrderDefault, fmt.Errorf("invalid value %q for annotation %s on pod %s/%s: expecting %q, %q or an integer", value, EvictionOrderAnnotationKey, pod.Namespace, pod.Name, EvictionOrderFirst, EvictionOrderLast)
+	}
+	return order, nil
+}
+
+// SortPodsByEvictionOrder sorts the pods by eviction rank. The sort is stable so that it composes with any previous ordering of the pods.
+func SortPodsByEvictionOrder(pods []*core.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		oi, _ := GetPodEvictionOrder(pods[i])
+		oj, _ := GetPodEvictionOrder(pods[j])
+		return oi < oj
+	})
+}
+
+// GroupPodsByEvictionOrder splits the pods, already sorted by eviction rank, in waves of pods sharing the same rank.
+// The pods of a wave are evicted together, a wave starts once the previous one is evicted.
+func GroupPodsByEvictionOrder(pods []*core.Pod) [][]*core.Pod {
+	var waves [][]*core.Pod
+	previous := 0
+	for i, p := range pods {
+		order, _ := GetPodEvictionOrder(p)
+		if i == 0 || order != previous {
+			waves = append(waves, nil)
+		}
+		waves[len(waves)-1] = append(waves[len(waves)-1], p)
+		previous = order
+	}
+	return waves
+}
@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	crfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func newEvictionOrderPod(name, order string) *core.Pod {
+	pod := &core.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "ns"}, Spec: core.PodSpec{NodeName: nodeName}}
+	if order != "" {
+		pod.Annotations = map[string]string{EvictionOrderAnnotationKey: order}
+	}
+	return pod
+}
+
+func TestGetPodEvictionOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		order   int
+		wantErr bool
+	}{
+		{name: "no annotation", value: "", order: 0},
+		{name: "first", value: EvictionOrderFirst, order: math.MinInt32},
+		{name: "last", value: EvictionOrderLast, order: math.MaxInt32},
+		{name: "numeric", value: "-3", order: -3},
+		{name: "invalid", value: "later", order: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := GetPodEvictionOrder(newEvictionOrderPod("pod", tt.value))
+			assert.Equal(t, tt.order, order)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestGroupPodsByEvictionOrder(t *testing.T) {
+	pods := []*core.Pod{
+		newEvictionOrderPod("last", EvictionOrderLast),
+		newEvictionOrderPod("default-1", ""),
+		newEvictionOrderPod("ten", "10"),
+		newEvictionOrderPod("first", EvictionOrderFirst),
+		newEvictionOrderPod("default-2", "0"),
+	}
+	SortPodsByEvictionOrder(pods)
+
+	var names [][]string
+	for _, wave := range GroupPodsByEvictionOrder(pods) {
+		var waveNames []string
+		for _, p := range wave {
+			waveNames = append(waveNames, p.Name)
+		}
+		names = append(names, waveNames)
+	}
+	assert.Equal(t, [][]string{{"first"}, {"default-1", "default-2"}, {"ten"}, {"last"}}, names)
+}
+
+func TestAPIDrainer_DrainWithEvictionOrder(t *testing.T) {
+	node := &core.Node{
+		ObjectMeta: meta.ObjectMeta{Name: nodeName},
+		Spec:       core.NodeSpec{Taints: []core.Taint{{Key: k8sclient.DrainoTaintKey, Value: k8sclient.TaintDraining, Effect: core.TaintEffectNoSchedule}}},
+	}
+	cs := fake.NewSimpleClientset(node,
+		newEvictionOrderPod("pod-last", EvictionOrderLast),
+		newEvictionOrderPod("pod-1", ""),
+		newEvictionOrderPod("pod-2", ""),
+		newEvictionOrderPod("pod-3", ""),
+	)
+
+	var evicted []string
+	cs.PrependReactor("create", "pods", func(a clienttesting.Action) (bool, runtime.Object, error) {
+		if a.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := a.(clienttesting.CreateAction).GetObject().(*policy.Eviction)
+		evicted = append(evicted, eviction.Name)
+		return true, nil, cs.Tracker().Delete(core.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+
+	d := NewAPIDrainer(cs, NewEventRecorder(&record.FakeRecorder{}), WithContainerRuntimeClient(crfake.NewClientBuilder().Build()), MaxGracePeriod(time.Second), EvictionHeadroom(time.Second))
+	assert.NoError(t, d.Drain(context.Background(), node))
+
+	assert.Len(t, evicted, 4)
+	assert.ElementsMatch(t, []string{"pod-1", "pod-2", "pod-3"}, evicted[:3])
+	assert.Equal(t, "pod-last", evicted[3])
+}
@@ -1,8 +1,11 @@
 package kubernetes
 
 import (
+	"context"
 	"errors"
-	"fmt"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type FailureCause string
@@ -10,49 +13,90 @@ type FailureCause string
 const (
 	OverlappingPodDisruptionBudgets FailureCause = "overlapping_pod_disruption_budgets"
 	PodEvictionTimeout              FailureCause = "pod_eviction_timeout"
+	PodDisruptionBudgetBlocked      FailureCause = "pod_disruption_budget"
+	DrainTimeout                    FailureCause = "drain_timeout"
+	EvictionError                   FailureCause = "eviction_error"
+	EvictionDryRunFailed            FailureCause = "eviction_dry_run"
 	PodDeletionTimeout              FailureCause = "pod_deletion_timeout"
 	VolumeCleanup                   FailureCause = "volume_cleanup"
 	NodePreprovisioning             FailureCause = "node_preprovisioning_timeout"
 	AudienceNotFound                FailureCause = "audience_not_found"
+	ReplacementPodTimeout           FailureCause = "replacement_pod_timeout"
+	PodFilter                       FailureCause = "pod_filter"
+	PodEvictionDenied               FailureCause = "pod_eviction_denied"
+)
+
+// DrainError is implemented by the typed errors of the drain, each of them is matched to a failure cause
+type DrainError interface {
+	error
+	Cause() FailureCause
+}
+
+var (
+	_ DrainError = NodePreprovisioningTimeoutError{}
+	_ DrainError = OverlappingDisruptionBudgetsError{}
+	_ DrainError = PodEvictionTimeoutError{}
+	_ DrainError = PodEvictionDryRunError{}
+	_ DrainError = ReplacementPodTimeoutError{}
+	_ DrainError = PodDeletionTimeoutError{}
+	_ DrainError = VolumeCleanupError{}
+	_ DrainError = PodFilterError{}
+	_ DrainError = PodEvictionDeniedError{}
+	_ DrainError = EvictionEndpointError{}
+	_ DrainError = AudienceNotFoundError{}
 )
 
+// GetFailureCause returns the cause of the outermost DrainError of the chain. The other errors are classified as a drain
+// timeout or an eviction error, or get an empty cause.
 func GetFailureCause(err error) FailureCause {
-	if errors.As(err, &NodePreprovisioningTimeoutError{}) {
-		return NodePreprovisioning
-	}
-	if errors.As(err, &OverlappingDisruptionBudgetsError{}) {
-		return OverlappingPodDisruptionBudgets
-	}
-	var peErr PodEvictionTimeoutError
-	if errors.As(err, &peErr) {
-		cause := PodEvictionTimeout
-		if peErr.isEvictionPP {
-			cause += "_evictionpp"
-		} else {
-			cause += "_kubeapi"
-		}
-		return cause
-	}
-	if errors.As(err, &PodDeletionTimeoutError{}) {
-		return PodDeletionTimeout
-	}
-	if errors.As(err, &VolumeCleanupError{}) {
-		return VolumeCleanup
-	}
-	var eeErr EvictionEndpointError
-	if errors.As(err, &eeErr) {
-		cause := "eviction_endpoint"
-		if eeErr.IsRequestTimeout {
-			cause += "_request_timeout"
-		}
-		if eeErr.StatusCode > 0 {
-			cause += fmt.Sprintf("_%d", eeErr.StatusCode)
-		}
-		return FailureCause(cause)
-	}
-	if errors.As(err, &AudienceNotFoundError{}) {
-		return AudienceNotFound
+	var drainErr DrainError
+	if errors.As(err, &drainErr) {
+		return drainErr.Cause()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return DrainTimeout
+	}
+	var statusErr apierrors.APIStatus
+	if errors.As(err, &statusErr) {
+		return EvictionError
 	}
 
 	return ""
 }
+
+// IsTransientDrainError returns true if the drain failed because of an error that is expected to disappear by itself,
+// like a timeout or an internal error of the API server. Other errors, like a forbidden or an invalid request, are permanent.
+// The typed DrainError of the chain decides by its cause, whatever the error it wraps; the untyped errors are classified
+// by their API status. Transient errors should be retried quickly without counting as a drain attempt.
+func IsTransientDrainError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// the drain itself ran out of time, retrying right away would most likely hit the same timeout
+		return false
+	}
+	var drainErr DrainError
+	if errors.As(err, &drainErr) {
+		return isTransientDrainErrorCause(drainErr)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return apierrors.IsTimeout(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsUnexpectedServerError(err)
+}
+
+// isTransientDrainErrorCause tells if the cause of a typed drain error is transient. Only the request timeouts and the server
+// errors of the eviction endpoint are, the other causes, like a PDB blocking the eviction or a skipped dry-run eviction, are not.
+func isTransientDrainErrorCause(drainErr DrainError) bool {
+	if eeErr, ok := drainErr.(EvictionEndpointError); ok {
+		return eeErr.IsRequestTimeout || eeErr.StatusCode >= 500
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsTransientDrainError(t *testing.T) {
+	podsResource := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "no error", err: nil, transient: false},
+		{name: "api timeout", err: apierrors.NewTimeoutError("eviction", 1), transient: true},
+		{name: "wrapped server timeout", err: fmt.Errorf("evicting pod: %w", apierrors.NewServerTimeout(podsResource, "create", 1)), transient: true},
+		{name: "internal error", err: apierrors.NewInternalError(errors.New("boom")), transient: true},
+		{name: "too many requests", err: apierrors.NewTooManyRequests("slow down", 1), transient: true},
+		{name: "eviction endpoint timeout", err: EvictionEndpointError{IsRequestTimeout: true}, transient: true},
+		{name: "eviction endpoint 503", err: EvictionEndpointError{StatusCode: 503}, transient: true},
+		{name: "eviction endpoint 400", err: EvictionEndpointError{StatusCode: 400}, transient: false},
+		{name: "forbidden", err: apierrors.NewForbidden(podsResource, "my-pod", errors.New("not allowed")), transient: false},
+		{name: "invalid", err: apierrors.NewBadRequest("invalid"), transient: false},
+		{name: "pod eviction timeout", err: PodEvictionTimeoutError{}, transient: false},
+		{name: "wrapped eviction endpoint 502", err: fmt.Errorf("cannot evict pod ns/pod: %w", EvictionEndpointError{StatusCode: 502}), transient: true},
+		{name: "volume cleanup wrapping a server error", err: VolumeCleanupError{Err: apierrors.NewInternalError(errors.New("boom"))}, transient: false},
+		{name: "throttled dry-run eviction", err: fmt.Errorf("cannot evict all pods, 1 pods skipped after dry-run: %w", PodEvictionDryRunError{Pod: "ns/pod", Err: apierrors.NewTooManyRequests("slow down", 1)}), transient: false},
+		{name: "drain context deadline", err: context.DeadlineExceeded, transient: false},
+		{name: "unknown error", err: errors.New("myerr"), transient: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, IsTransientDrainError(tt.err))
+		})
+	}
+}
+
+func TestGetFailureCause(t *testing.T) {
+	podsResource := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name  string
+		err   error
+		cause FailureCause
+	}{
+		{name: "eviction refused by a pdb", err: PodEvictionTimeoutError{blockedByPDB: true}, cause: PodDisruptionBudgetBlocked},
+		{name: "eviction timeout on the kube api", err: PodEvictionTimeoutError{}, cause: "pod_eviction_timeout_kubeapi"},
+		{name: "eviction timeout on eviction++", err: PodEvictionTimeoutError{isEvictionPP: true}, cause: "pod_eviction_timeout_evictionpp"},
+		{name: "drain timeout", err: fmt.Errorf("draining: %w", context.DeadlineExceeded), cause: DrainTimeout},
+		{name: "volume cleanup", err: VolumeCleanupError{Err: errors.New("boom")}, cause: VolumeCleanup},
+		{name: "eviction error", err: apierrors.NewForbidden(podsResource, "my-pod", errors.New("not allowed")), cause: EvictionError},
+		{name: "eviction endpoint", err: EvictionEndpointError{StatusCode: 400}, cause: "eviction_endpoint_400"},
+		{name: "unknown error", err: errors.New("myerr"), cause: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.cause, GetFailureCause(tt.err))
+		})
+	}
+}
+
+func TestDrainError_As(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		cause FailureCause
+	}{
+		{name: "node pre-provisioning", err: NodePreprovisioningTimeoutError{}, cause: NodePreprovisioning},
+		{name: "overlapping pdbs", err: OverlappingDisruptionBudgetsError{}, cause: OverlappingPodDisruptionBudgets},
+		{name: "eviction refused by a pdb", err: PodEvictionTimeoutError{blockedByPDB: true}, cause: PodDisruptionBudgetBlocked},
+		{name: "eviction timeout", err: PodEvictionTimeoutError{}, cause: "pod_eviction_timeout_kubeapi"},
+		{name: "dry-run eviction", err: PodEvictionDryRunError{Pod: "ns/pod", Err: errors.New("boom")}, cause: EvictionDryRunFailed},
+		{name: "replacement pod", err: ReplacementPodTimeoutError{Pod: "ns/pod"}, cause: ReplacementPodTimeout},
+		{name: "pod deletion timeout", err: PodDeletionTimeoutError{}, cause: PodDeletionTimeout},
+		{name: "volume cleanup", err: VolumeCleanupError{Err: errors.New("boom")}, cause: VolumeCleanup},
+		{name: "pod filter", err: PodFilterError{Err: errors.New("boom")}, cause: PodFilter},
+		{name: "eviction endpoint", err: EvictionEndpointError{IsRequestTimeout: true}, cause: "eviction_endpoint_request_timeout"},
+		{name: "audience not found", err: AudienceNotFoundError{Audience: "foo"}, cause: AudienceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("cannot evict all pods: %w", fmt.Errorf("cannot evict pod ns/pod: %w", tt.err))
+			var drainErr DrainError
+			if assert.True(t, errors.As(wrapped, &drainErr)) {
+				assert.Equal(t, tt.err, drainErr)
+				assert.Equal(t, tt.cause, drainErr.Cause())
+			}
+			assert.Equal(t, tt.cause, GetFailureCause(wrapped))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+
+	core "k8s.io/api/core/v1"
+)
+
+// DrainGroupAnnotationKey holds the drain group key of the node, it is maintained by the group reconciler
+const DrainGroupAnnotationKey = "node-lifecycle.datadoghq.com/drain-group"
+
+// WithGroupConditions returns the global conditions followed by the conditions of each drain group, given in the --node-conditions format.
+// The conditions of a group are watched on the nodes of that group in place of the global conditions, the nodes of the other groups
+// keep using the global conditions.
+func WithGroupConditions(global []SuppliedCondition, groupConditions map[string][]string) ([]SuppliedCondition, error) {
+	all := append([]SuppliedCondition{}, global...)
+	groupKeys := make([]string, 0, len(groupConditions))
+	for groupKey := range groupConditions {
+		groupKeys = append(groupKeys, groupKey)
+	}
+	sort.Strings(groupKeys)
+	for _, groupKey := range groupKeys {
+		if len(groupConditions[groupKey]) == 0 {
+			return nil, fmt.Errorf("group %q: no condition defined", groupKey)
+		}
+		conditions, err := ParseConditions(groupConditions[groupKey])
+		if err != nil {
+			return nil, fmt.Errorf("group %q: %v", groupKey, err)
+		}
+		for i := range conditions {
+			conditions[i].group = groupKey
+		}
+		all = append(all, conditions...)
+	}
+	return all, nil
+}
+
+// conditionsForNode returns the conditions of the drain group of the node if that group has its own, else the global conditions
+func conditionsForNode(n *core.Node, suppliedConditions []SuppliedCondition) []SuppliedCondition {
+	hasGroupConditions := false
+	for i := range suppliedConditions {
+		if suppliedConditions[i].group != "" {
+			hasGroupConditions = true
+			break
+		}
+	}
+	if !hasGroupConditions {
+		return suppliedConditions
+	}
+
+	groupKey, hasGroupKey := n.GetAnnotations()[DrainGroupAnnotationKey]
+	var global, ofGroup []SuppliedCondition
+	for _, c := range suppliedConditions {
+		switch {
+		case c.group == "":
+			global = append(global, c)
+		case hasGroupKey && c.group == groupKey:
+			ofGroup = append(ofGroup, c)
+		}
+	}
+	if len(ofGroup) > 0 {
+		return ofGroup
+	}
+	return global
+}
@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithGroupConditions(t *testing.T) {
+	global, err := ParseConditions([]string{"KernelDeadlock"})
+	assert.NoError(t, err)
+	conditions, err := WithGroupConditions(global, map[string][]string{
+		"team-a": {"DiskPressure"},
+		"team-b": {"CustomHardwareFailure"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, conditions, 3)
+
+	newNode := func(groupKey string, conditionTypes ...core.NodeConditionType) *core.Node {
+		n := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node"}}
+		if groupKey != "" {
+			n.Annotations = map[string]string{DrainGroupAnnotationKey: groupKey}
+		}
+		for _, c := range conditionTypes {
+			n.Status.Conditions = append(n.Status.Conditions, core.NodeCondition{Type: c, Status: core.ConditionTrue})
+		}
+		return n
+	}
+
+	tests := []struct {
+		name     string
+		node     *core.Node
+		expected []string
+	}{
+		{
+			name:     "group a reacts to its condition",
+			node:     newNode("team-a", "DiskPressure", "CustomHardwareFailure"),
+			expected: []string{"DiskPressure"},
+		},
+		{
+			name:     "group b reacts to its condition",
+			node:     newNode("team-b", "DiskPressure", "CustomHardwareFailure"),
+			expected: []string{"CustomHardwareFailure"},
+		},
+		{
+			name: "group conditions replace the global ones",
+			node: newNode("team-a", "KernelDeadlock"),
+		},
+		{
+			name:     "other group falls back to the global conditions",
+			node:     newNode("team-c", "KernelDeadlock", "DiskPressure"),
+			expected: []string{"KernelDeadlock"},
+		},
+		{
+			name:     "node without group falls back to the global conditions",
+			node:     newNode("", "KernelDeadlock", "CustomHardwareFailure"),
+			expected: []string{"KernelDeadlock"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.expected, GetConditionIDs(GetNodeOffendingConditions(tt.node, conditions)))
+		})
+	}
+
+	_, err = WithGroupConditions(global, map[string][]string{"team-a": {}})
+	assert.Error(t, err, "group without condition")
+	_, err = WithGroupConditions(global, map[string][]string{"team-a": {"DiskPressure={"}})
+	assert.Error(t, err, "malformed condition")
+}
@@ -26,6 +26,8 @@ type PDBIndexer interface {
 	// GetPDBsForPods will return a map indexed by podnames
 	// with associated PDBs as value
 	GetPDBsForPods(ctx context.Context, pods []*corev1.Pod) (map[string][]*policyv1.PodDisruptionBudget, error)
+	// GetOrphanPDBs will return the PDBs whose selector does not match any pod
+	GetOrphanPDBs(ctx context.Context) ([]*policyv1.PodDisruptionBudget, error)
 }
 
 func (i *Indexer) GetPDBsBlockedByPod(ctx context.Context, podName, ns string) ([]*policyv1.PodDisruptionBudget, error) {
@@ -83,6 +85,27 @@ func (i *Indexer) GetPDBsForPods(ctx context.Context, pods []*corev1.Pod) (map[s
 	return result, nil
 }
 
+func (i *Indexer) GetOrphanPDBs(ctx context.Context) ([]*policyv1.PodDisruptionBudget, error) {
+	var pdbList policyv1.PodDisruptionBudgetList
+	if err := i.client.List(ctx, &pdbList); err != nil {
+		return nil, err
+	}
+
+	var orphans []*policyv1.PodDisruptionBudget
+	for j := range pdbList.Items {
+		pdb := &pdbList.Items[j]
+		pods, err := getAssociatedPodsForPDB(i.listPodsCached, pdb, false)
+		if err != nil {
+			i.logger.Error(err, "failed to get pods associated with pdb", "namespace", pdb.Namespace, "name", pdb.Name)
+			continue
+		}
+		if len(pods) == 0 {
+			orphans = append(orphans, pdb)
+		}
+	}
+	return orphans, nil
+}
+
 type podListFunc = func(ctx context.Context, namespace string) (*corev1.PodList, error)
 
 func initPDBIndexer(cache cachecr.Cache, podListFn podListFunc) error {
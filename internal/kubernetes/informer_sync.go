@@ -0,0 +1,55 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// informerSyncPollPeriod is the period of the checks of the informers sync done by AwaitWithSyncTimeout
+const informerSyncPollPeriod = time.Second
+
+// ErrInformerSyncTimeout is returned when the informers are not synced before the informer sync timeout, for example when RBAC forbids the list of a resource
+var ErrInformerSyncTimeout = errors.New("informers not synced before timeout")
+
+// WaitForSync polls hasSynced until it returns true. A zero timeout waits until the context is done.
+func WaitForSync(ctx context.Context, hasSynced func() bool, period, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := wait.PollImmediateUntilWithContext(ctx, period, func(context.Context) (bool, error) {
+		return hasSynced(), nil
+	})
+	if err != nil && timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrInformerSyncTimeout, timeout)
+	}
+	return err
+}
+
+// AwaitWithSyncTimeout runs the runners like Await. The runners are stopped and ErrInformerSyncTimeout is returned if hasSynced
+// is still false after the timeout. A zero timeout waits for the sync forever.
+func AwaitWithSyncTimeout(ctx context.Context, hasSynced func() bool, timeout time.Duration, rs ...Runner) error {
+	ctx, cancelFn := context.WithCancel(ctx)
+	defer cancelFn()
+
+	syncErr := make(chan error, 1)
+	go func() {
+		err := WaitForSync(ctx, hasSynced, informerSyncPollPeriod, timeout)
+		if errors.Is(err, ErrInformerSyncTimeout) {
+			cancelFn()
+		}
+		syncErr <- err
+	}()
+
+	err := Await(ctx, rs...)
+	cancelFn()
+	if errSync := <-syncErr; errors.Is(errSync, ErrInformerSyncTimeout) {
+		return errSync
+	}
+	return err
+}
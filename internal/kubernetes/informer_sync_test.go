@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingRunner struct {
+	stopped chan struct{}
+}
+
+func (r *blockingRunner) Start(ctx context.Context) {
+	<-ctx.Done()
+	close(r.stopped)
+}
+
+func TestWaitForSync(t *testing.T) {
+	err := WaitForSync(context.Background(), func() bool { return false }, 10*time.Millisecond, 50*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrInformerSyncTimeout), "never synced: %v", err)
+
+	calls := 0
+	err = WaitForSync(context.Background(), func() bool { calls++; return calls > 2 }, 10*time.Millisecond, time.Minute)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = WaitForSync(ctx, func() bool { return false }, 10*time.Millisecond, 0)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInformerSyncTimeout), "a cancelled context is not a sync timeout")
+}
+
+func TestAwaitWithSyncTimeout(t *testing.T) {
+	t.Run("sync never completes", func(t *testing.T) {
+		runner := &blockingRunner{stopped: make(chan struct{})}
+		done := make(chan error, 1)
+		go func() {
+			done <- AwaitWithSyncTimeout(context.Background(), func() bool { return false }, 100*time.Millisecond, runner)
+		}()
+
+		select {
+		case err := <-done:
+			assert.True(t, errors.Is(err, ErrInformerSyncTimeout), "unexpected error: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the sync timeout did not stop the wait")
+		}
+		select {
+		case <-runner.stopped:
+		default:
+			t.Fatal("the runner was not stopped")
+		}
+	})
+
+	t.Run("synced runners run until the context is done", func(t *testing.T) {
+		runner := &blockingRunner{stopped: make(chan struct{})}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- AwaitWithSyncTimeout(ctx, func() bool { return true }, 100*time.Millisecond, runner)
+		}()
+
+		select {
+		case err := <-done:
+			t.Fatalf("returned before the context was done: %v", err)
+		case <-time.After(300 * time.Millisecond):
+		}
+		cancel()
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the runners were not stopped")
+		}
+	})
+}
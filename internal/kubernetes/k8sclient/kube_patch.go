@@ -49,6 +49,26 @@ func (self *NodeConditionPatch) Data(obj client.Object) ([]byte, error) {
 	})
 }
 
+// NodeConditionDeletePatch removes the condition of the given type from the node status
+type NodeConditionDeletePatch struct {
+	ConditionType corev1.NodeConditionType
+}
+
+var _ client.Patch = &NodeConditionDeletePatch{}
+
+func (_ *NodeConditionDeletePatch) Type() types.PatchType {
+	return types.StrategicMergePatchType
+}
+
+func (self *NodeConditionDeletePatch) Data(obj client.Object) ([]byte, error) {
+	// the conditions are merged on their type, the directive removes the matching item only
+	return json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []map[string]interface{}{{"type": self.ConditionType, "$patch": "delete"}},
+		},
+	})
+}
+
 // --------------------------------------------
 // JSON Patches
 // --------------------------------------------
@@ -148,11 +168,19 @@ func PatchNode(ctx context.Context, kclient kubernetes.Interface, nodeName strin
 }
 
 func PatchNodeCR(ctx context.Context, client client.Client, node *corev1.Node, patch client.Patch) error {
+	_, err := PatchNodeCRWithResult(ctx, client, node, patch)
+	return err
+}
+
+// PatchNodeCRWithResult patches the node and returns the patched version. Unlike the given node, it holds the new resource version
+// and must be used for the following updates of the node, which would be rejected as conflicting otherwise.
+func PatchNodeCRWithResult(ctx context.Context, client client.Client, node *corev1.Node, patch client.Patch) (*corev1.Node, error) {
 	// The client.Patch method is automatically updating the given node.
 	// As the pointer is used in other places, it will cause concurrent map read / write panics
 	// In order to prevent this, we'll create a deep copy of the node and pass it to the client.Patch.
 	nodeCopy := node.DeepCopy()
-	return client.Patch(ctx, nodeCopy, patch)
+	err := client.Patch(ctx, nodeCopy, patch)
+	return nodeCopy, err
 }
 
 func PatchNodeAnnotationKey(ctx context.Context, kclient kubernetes.Interface, nodeName string, key string, value string) error {
@@ -0,0 +1,24 @@
+package kubernetes
+
+import (
+	"time"
+
+	core "k8s.io/api/core/v1"
+)
+
+// LastUncordonAnnotationKey records when draino last removed the candidate status of a node because it recovered
+// from its condition. It is used to avoid flapping on conditions that come and go.
+const LastUncordonAnnotationKey = "node-lifecycle.datadoghq.com/last-uncordon"
+
+// GetLastUncordonTime returns the time stored in the last-uncordon annotation of the node, if any and valid
+func GetLastUncordonTime(node *core.Node) (time.Time, bool) {
+	value, ok := node.Annotations[LastUncordonAnnotationKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,100 @@
+package kubernetes
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	coordination "k8s.io/api/coordination/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+)
+
+const (
+	// DefaultLeaderPodMaxWait is the maximum time a leader pod can prevent its node from being candidate
+	DefaultLeaderPodMaxWait = time.Hour
+
+	leaseListCacheTTL = 30 * time.Second
+)
+
+// LeaseListFunc returns the leases of a namespace
+type LeaseListFunc func(namespace string) ([]coordination.Lease, error)
+
+// NewCachedLeaseListFunc lists the leases with the given client and keeps the result for a short time, as the
+// pod filters are called for each pod of each evaluated node.
+func NewCachedLeaseListFunc(cs kubernetes.Interface) LeaseListFunc {
+	cache := utils.NewTTLCache[[]coordination.Lease](leaseListCacheTTL, leaseListCacheTTL)
+	return func(namespace string) ([]coordination.Lease, error) {
+		if leases, ok := cache.Get(namespace, time.Now()); ok {
+			return leases, nil
+		}
+		list, err := cs.CoordinationV1().Leases(namespace).List(context.Background(), meta.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		cache.Add(namespace, list.Items)
+		return list.Items, nil
+	}
+}
+
+// NewLeaderPodFilter returns a PodFilterFunc that rejects the pods currently holding a leader election lease,
+// to avoid an unnecessary failover. Only the leases of the pod namespace whose name matches the pattern are considered.
+// The pod is the leader if the holder identity of the lease is the pod name, optionally followed by "_<id>" as
+// generated by the client-go leader election. Once a pod was seen as leader for more than maxWait, it is not
+// rejected anymore so that a leadership that never transfers does not block the node forever.
+// The leader pods not evaluated for more than maxWait, because they or their node are gone, are forgotten.
+func NewLeaderPodFilter(listLeases LeaseListFunc, leaseNamePattern *regexp.Regexp, maxWait time.Duration, clock clock.Clock) PodFilterFunc {
+	type leaderPod struct {
+		firstSeen, lastSeen time.Time
+	}
+	var (
+		lock    sync.Mutex
+		leaders = map[string]*leaderPod{}
+	)
+	return func(p core.Pod) (bool, string, error) {
+		leases, err := listLeases(p.Namespace)
+		if err != nil {
+			return false, "", err
+		}
+		podKey := p.Namespace + "/" + p.Name
+		now := clock.Now()
+		lock.Lock()
+		defer lock.Unlock()
+		for key, leader := range leaders {
+			if now.Sub(leader.lastSeen) > maxWait {
+				delete(leaders, key)
+			}
+		}
+		for _, lease := range leases {
+			if !leaseNamePattern.MatchString(lease.Name) || !isLeaseHeldByPod(&lease, &p) {
+				continue
+			}
+			leader, ok := leaders[podKey]
+			if !ok {
+				leader = &leaderPod{firstSeen: now}
+				leaders[podKey] = leader
+			}
+			leader.lastSeen = now
+			if now.Sub(leader.firstSeen) > maxWait {
+				return true, "", nil
+			}
+			return false, "pod-leader", nil
+		}
+		delete(leaders, podKey)
+		return true, "", nil
+	}
+}
+
+func isLeaseHeldByPod(lease *coordination.Lease, p *core.Pod) bool {
+	if lease.Spec.HolderIdentity == nil {
+		return false
+	}
+	holder := *lease.Spec.HolderIdentity
+	return holder == p.Name || strings.HasPrefix(holder, p.Name+"_")
+}
@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordination "k8s.io/api/coordination/v1"
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+)
+
+func TestLeaderPodFilter(t *testing.T) {
+	leases := []coordination.Lease{
+		{ObjectMeta: meta.ObjectMeta{Name: "my-app-leader", Namespace: "default"}, Spec: coordination.LeaseSpec{HolderIdentity: pointer.String("leader-pod_4d5c6f")}},
+		{ObjectMeta: meta.ObjectMeta{Name: "unrelated", Namespace: "default"}, Spec: coordination.LeaseSpec{HolderIdentity: pointer.String("follower-pod")}},
+	}
+	listLeases := func(namespace string) ([]coordination.Lease, error) {
+		if namespace != "default" {
+			return nil, nil
+		}
+		return leases, nil
+	}
+	newPod := func(name string) core.Pod {
+		return core.Pod{ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	filter := NewLeaderPodFilter(listLeases, regexp.MustCompile("-leader$"), time.Hour, fakeClock)
+
+	pass, reason, err := filter(newPod("leader-pod"))
+	assert.NoError(t, err)
+	assert.False(t, pass)
+	assert.Equal(t, "pod-leader", reason)
+
+	// the lease does not match the pattern
+	pass, _, err = filter(newPod("follower-pod"))
+	assert.NoError(t, err)
+	assert.True(t, pass)
+
+	// the name is only a prefix of the holder identity
+	pass, _, err = filter(newPod("leader"))
+	assert.NoError(t, err)
+	assert.True(t, pass)
+
+	// still the leader before the max wait
+	fakeClock.Step(30 * time.Minute)
+	pass, _, _ = filter(newPod("leader-pod"))
+	assert.False(t, pass)
+
+	// the leadership did not transfer in time
+	fakeClock.Step(31 * time.Minute)
+	pass, _, _ = filter(newPod("leader-pod"))
+	assert.True(t, pass)
+
+	// the pod was not evaluated anymore, for example because its node was deleted, it is forgotten
+	fakeClock.Step(61 * time.Minute)
+	pass, _, _ = filter(newPod("follower-pod"))
+	assert.True(t, pass)
+	pass, _, _ = filter(newPod("leader-pod"))
+	assert.False(t, pass, "the pod evaluated again should get a new max wait")
+}
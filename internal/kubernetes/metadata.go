@@ -7,16 +7,6 @@ const (
 	DefaultDrainBuffer               = 10 * time.Minute
 	DefaultDurationBeforeReplacement = 1 * time.Hour
 
-	EventReasonPendingPodWithLocalPV = "PodBoundToNodeViaLocalPV"
-
-	EventReasonDrainStarting  = "DrainStarting"
-	EventReasonDrainSucceeded = "DrainSucceeded"
-	EventReasonDrainFailed    = "DrainFailed"
-	eventReasonDrainConfig    = "DrainConfig"
-
-	eventReasonNodePreprovisioning          = "NodePreprovisioning"
-	eventReasonNodePreprovisioningCompleted = "NodePreprovisioningCompleted"
-
 	tagResultSucceeded = "succeeded"
 	tagResultFailed    = "failed"
 
@@ -28,7 +18,5 @@ const (
 	drainRetryFailedAnnotationKey   = "draino/drain-retry-failed"
 	drainRetryFailedAnnotationValue = "failed"
 
-	drainoConditionsAnnotationKey = "draino.planet.com/conditions"
-
 	NodeNLAEnableLabelKey = "node-lifecycle.datadoghq.com/enabled"
 )
@@ -149,7 +149,8 @@ func (a *MetadataSearch[T]) processPod(pod *core.Pod) {
 		}
 	}
 
-	if ctrl, found := GetControllerForPod(pod, a.store); found {
+	// the closest controller carrying the metadata wins
+	for _, ctrl := range GetControllerChainForPod(pod, a.store) {
 		if values, ok := a.metadataGetter(ctrl, a.Key); ok {
 			for _, val := range values {
 				var item MetadataSearchResultItem[T]
@@ -160,6 +161,7 @@ func (a *MetadataSearch[T]) processPod(pod *core.Pod) {
 				item.setValueAndError(a.converter(val.Value))
 				a.Result[val.Value] = append(a.Result[val.Value], item)
 			}
+			return
 		}
 	}
 }
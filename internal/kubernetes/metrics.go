@@ -11,9 +11,14 @@ var (
 	MeasureNodesDrainScheduled     = stats.Int64("draino/nodes_drainScheduled", "Number of nodes drain scheduled.", stats.UnitDimensionless)
 	MeasureNodesReplacementRequest = stats.Int64("draino/nodes_replacement_request", "Number of nodes replacement requested.", stats.UnitDimensionless)
 	MeasurePreprovisioningLatency  = stats.Float64("draino/nodes_preprovisioning_latency", "Latency to get a node preprovisioned", stats.UnitMilliseconds)
+	MeasureEvictionAPIVersionUsed  = stats.Int64("draino/eviction_api_version_used", "Number of pods evicted per version of the eviction API.", stats.UnitDimensionless)
+	MeasurePodsEvicted             = stats.Int64("draino/pods_evicted", "Number of pods evicted.", stats.UnitDimensionless)
+	MeasurePodTerminationLatency   = stats.Float64("draino/pod_termination_latency", "Time between the eviction of a pod and its deletion", stats.UnitSeconds)
+	MeasureConditionAgeAtCordon    = stats.Float64("draino/condition_age_at_cordon", "Time since the last transition of the offending conditions when the node becomes drain candidate", stats.UnitSeconds)
 
 	TagNodeName, _                        = tag.NewKey("node_name")
 	TagConditions, _                      = tag.NewKey("conditions")
+	TagConditionType, _                   = tag.NewKey("condition_type")
 	TagTeam, _                            = tag.NewKey("team")
 	TagService, _                         = tag.NewKey("service")
 	TagNodegroupName, _                   = tag.NewKey("nodegroup_name")
@@ -34,4 +39,7 @@ var (
 	TagUserAllowedConditionsAnnotation, _ = tag.NewKey("user_allowed_conditions_annotation")
 	TagUserEvictionURL, _                 = tag.NewKey("eviction_url")
 	TagOverdue, _                         = tag.NewKey("overdue")
+	TagEvictionAPIVersion, _              = tag.NewKey("version")
+	TagNamespace, _                       = tag.NewKey("namespace")
+	TagCustomEvictor, _                   = tag.NewKey("custom_evictor")
 )
@@ -18,8 +18,10 @@ package kubernetes
 
 import (
 	"fmt"
+	"hash/fnv"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/antonmedv/expr"
@@ -111,6 +113,134 @@ func NewNodeAndPodsFilter(expressionStr string, log *zap.Logger) (NodeAndPodsFil
 	}, nil
 }
 
+// NewNodeStatusFilter returns a filter that returns true if the supplied node passes the given filter and if its status
+// satisfies the boolean expression, for example `status.nodeInfo.osImage startsWith "Ubuntu"`. An empty expression
+// selects all the nodes.
+func NewNodeStatusFilter(filter NodeLabelFilterFunc, expressionStr string, log *zap.Logger) (NodeLabelFilterFunc, error) {
+	if expressionStr == "" {
+		return filter, nil
+	}
+
+	expression, err := expr.Compile(expressionStr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile node status expression: %v", err)
+	}
+
+	return func(o interface{}) bool {
+		n, ok := o.(*core.Node)
+		if !ok {
+			return false
+		}
+		if !filter(o) {
+			return false
+		}
+
+		statusUnstruct, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&n.Status)
+		if err != nil {
+			log.Error("Could not convert node status to unstructured", zap.Error(err), zap.String("nodeName", n.Name))
+			return false
+		}
+
+		result, err := expr.Run(expression, map[string]interface{}{"status": statusUnstruct})
+		if err != nil {
+			log.Error("Could not run node status expression", zap.Error(err), zap.String("nodeName", n.Name))
+			return false
+		}
+		selected, ok := result.(bool)
+		return ok && selected
+	}, nil
+}
+
+// NewNodePercentageFilter returns a filter that returns true if the supplied node passes the given filter and if its name
+// is part of the given percentage of the nodes. The selection only depends on the node name, so it is stable and raising
+// the percentage only adds nodes to the selection.
+func NewNodePercentageFilter(filter NodeLabelFilterFunc, percentage int) NodeLabelFilterFunc {
+	return func(o interface{}) bool {
+		n, ok := o.(*core.Node)
+		if !ok {
+			return false
+		}
+		return IsNodeInPercentage(n.GetName(), percentage) && filter(o)
+	}
+}
+
+// DefaultProtectedNodeLabels are the labels of the control-plane and ingress nodes, draino never selects them by default
+var DefaultProtectedNodeLabels = []string{
+	"node-role.kubernetes.io/control-plane",
+	"node-role.kubernetes.io/master",
+	"node-role.kubernetes.io/ingress",
+}
+
+// NewProtectedNodeLabelsFilter returns a filter that returns true if the supplied node passes the given filter and has none
+// of the protected labels. A protected label is given as KEY, matching any value, or as KEY=VALUE.
+func NewProtectedNodeLabelsFilter(filter NodeLabelFilterFunc, protectedLabels []string) NodeLabelFilterFunc {
+	return func(o interface{}) bool {
+		n, ok := o.(*core.Node)
+		if !ok {
+			return false
+		}
+		if HasAnyProtectedNodeLabel(n, protectedLabels) {
+			return false
+		}
+		return filter(o)
+	}
+}
+
+// HasAnyProtectedNodeLabel returns true if the node has one of the protected labels, given as KEY or KEY=VALUE
+func HasAnyProtectedNodeLabel(n *core.Node, protectedLabels []string) bool {
+	for _, protected := range protectedLabels {
+		key, value, withValue := strings.Cut(protected, "=")
+		nodeValue, ok := n.GetLabels()[key]
+		if ok && (!withValue || nodeValue == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDisjointNodeLabelFilters returns the given filters, keyed by configuration name, restricted so that a node selected by
+// more than one of them is selected by none. Such a node is reported once, the configurations must be fixed to select disjoint nodes.
+func NewDisjointNodeLabelFilters(filters map[string]NodeLabelFilterFunc, log *zap.Logger) map[string]NodeLabelFilterFunc {
+	var reported sync.Map
+	selectedBy := func(o interface{}) []string {
+		var names []string
+		for name, filter := range filters {
+			if filter(o) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+	disjoint := map[string]NodeLabelFilterFunc{}
+	for name, filter := range filters {
+		filter := filter
+		disjoint[name] = func(o interface{}) bool {
+			if !filter(o) {
+				return false
+			}
+			names := selectedBy(o)
+			if len(names) == 1 {
+				return true
+			}
+			if n, ok := o.(*core.Node); ok {
+				if _, alreadyReported := reported.LoadOrStore(n.GetName(), true); !alreadyReported {
+					log.Warn("node selected by several configurations, excluded from all of them", zap.String("node", n.GetName()), zap.Strings("configurations", names))
+				}
+			}
+			return false
+		}
+	}
+	return disjoint
+}
+
+// IsNodeInPercentage returns true if the hash of the node name falls in the given percentage of the hash space
+func IsNodeInPercentage(nodeName string, percentage int) bool {
+	h := fnv.New32a()
+	h.Write([]byte(nodeName))
+	return h.Sum32()%100 < uint32(percentage)
+}
+
 // NodeProcessed tracks whether nodes have been processed before using a map.
 type NodeProcessed map[types.UID]bool
 
@@ -18,6 +18,7 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -492,6 +493,215 @@ func TestOldNodeLabelFilter(t *testing.T) {
 	}
 }
 
+func TestIsNodeInPercentage(t *testing.T) {
+	var names []string
+	for i := 0; i < 1000; i++ {
+		names = append(names, fmt.Sprintf("ip-10-0-%d-%d.ec2.internal", i/250, i%250))
+	}
+	tests := []struct {
+		percentage int
+		min, max   int
+	}{
+		{percentage: 0, min: 0, max: 0},
+		{percentage: 10, min: 70, max: 130},
+		{percentage: 50, min: 450, max: 550},
+		{percentage: 100, min: 1000, max: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d%%", tt.percentage), func(t *testing.T) {
+			count := 0
+			for _, name := range names {
+				in := IsNodeInPercentage(name, tt.percentage)
+				assert.Equal(t, in, IsNodeInPercentage(name, tt.percentage), "the selection should be stable")
+				if in {
+					count++
+					assert.True(t, IsNodeInPercentage(name, tt.percentage+10), "raising the percentage should keep the node")
+				}
+			}
+			assert.GreaterOrEqual(t, count, tt.min)
+			assert.LessOrEqual(t, count, tt.max)
+		})
+	}
+}
+
+func TestNodePercentageFilter(t *testing.T) {
+	var in, out *core.Node
+	for i := 0; in == nil || out == nil; i++ {
+		n := &core.Node{ObjectMeta: meta.ObjectMeta{Name: fmt.Sprintf("node-%d", i)}}
+		if IsNodeInPercentage(n.Name, 10) {
+			in = n
+		} else {
+			out = n
+		}
+	}
+	all := func(o interface{}) bool { return true }
+	none := func(o interface{}) bool { return false }
+
+	assert.True(t, NewNodePercentageFilter(all, 10)(in))
+	assert.False(t, NewNodePercentageFilter(all, 10)(out))
+	assert.False(t, NewNodePercentageFilter(none, 10)(in), "the other selectors still apply")
+}
+
+func TestNodeStatusFilter(t *testing.T) {
+	all := func(o interface{}) bool { return true }
+	none := func(o interface{}) bool { return false }
+	node := func(osImage, kernelVersion string) *core.Node {
+		return &core.Node{
+			ObjectMeta: meta.ObjectMeta{Name: "node"},
+			Status: core.NodeStatus{NodeInfo: core.NodeSystemInfo{
+				OSImage:       osImage,
+				KernelVersion: kernelVersion,
+			}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		node     *core.Node
+		filter   NodeLabelFilterFunc
+		expected bool
+	}{
+		{
+			name:     "no expression",
+			node:     node("Ubuntu 20.04.6 LTS", "5.4.0-1103-aws"),
+			filter:   all,
+			expected: true,
+		},
+		{
+			name:     "matching os image",
+			expr:     "status.nodeInfo.osImage startsWith 'Ubuntu'",
+			node:     node("Ubuntu 20.04.6 LTS", "5.4.0-1103-aws"),
+			filter:   all,
+			expected: true,
+		},
+		{
+			name:     "other os image",
+			expr:     "status.nodeInfo.osImage startsWith 'Ubuntu'",
+			node:     node("Bottlerocket OS 1.14.1 (aws-k8s-1.26)", "5.15.108"),
+			filter:   all,
+			expected: false,
+		},
+		{
+			name:     "matching kernel version",
+			expr:     "status.nodeInfo.osImage startsWith 'Ubuntu' && status.nodeInfo.kernelVersion startsWith '5.4.'",
+			node:     node("Ubuntu 20.04.6 LTS", "5.4.0-1103-aws"),
+			filter:   all,
+			expected: true,
+		},
+		{
+			name:     "other kernel version",
+			expr:     "status.nodeInfo.osImage startsWith 'Ubuntu' && status.nodeInfo.kernelVersion startsWith '5.4.'",
+			node:     node("Ubuntu 22.04.2 LTS", "5.15.0-1036-aws"),
+			filter:   all,
+			expected: false,
+		},
+		{
+			name:     "rejected by the label selection",
+			expr:     "status.nodeInfo.osImage startsWith 'Ubuntu'",
+			node:     node("Ubuntu 20.04.6 LTS", "5.4.0-1103-aws"),
+			filter:   none,
+			expected: false,
+		},
+		{
+			name:     "not a boolean",
+			expr:     "status.nodeInfo.kernelVersion",
+			node:     node("Ubuntu 20.04.6 LTS", "5.4.0-1103-aws"),
+			filter:   all,
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewNodeStatusFilter(tt.filter, tt.expr, zap.NewNop())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, filter(tt.node))
+		})
+	}
+
+	_, err := NewNodeStatusFilter(all, "status.nodeInfo.osImage ==", zap.NewNop())
+	assert.Error(t, err, "invalid expression")
+}
+
+func TestProtectedNodeLabelsFilter(t *testing.T) {
+	all := func(o interface{}) bool { return true }
+	none := func(o interface{}) bool { return false }
+	protected := append([]string{"dedicated=ingress"}, DefaultProtectedNodeLabels...)
+
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		filter   NodeLabelFilterFunc
+		expected bool
+	}{
+		{
+			name:     "no label",
+			filter:   all,
+			expected: true,
+		},
+		{
+			name:     "control-plane",
+			labels:   map[string]string{"node-role.kubernetes.io/control-plane": ""},
+			filter:   all,
+			expected: false,
+		},
+		{
+			name:     "legacy master",
+			labels:   map[string]string{"node-role.kubernetes.io/master": "true"},
+			filter:   all,
+			expected: false,
+		},
+		{
+			name:     "protected value",
+			labels:   map[string]string{"dedicated": "ingress"},
+			filter:   all,
+			expected: false,
+		},
+		{
+			name:     "other value",
+			labels:   map[string]string{"dedicated": "batch", "node-role.kubernetes.io/worker": ""},
+			filter:   all,
+			expected: true,
+		},
+		{
+			name:     "rejected by the other selectors",
+			labels:   map[string]string{"dedicated": "batch"},
+			filter:   none,
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node", Labels: tt.labels}}
+			assert.Equal(t, tt.expected, NewProtectedNodeLabelsFilter(tt.filter, protected)(n))
+		})
+	}
+
+	controlPlane := &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node", Labels: map[string]string{"node-role.kubernetes.io/control-plane": ""}}}
+	assert.True(t, NewProtectedNodeLabelsFilter(all, nil)(controlPlane), "no protection once explicitly allowed")
+}
+
+func TestDisjointNodeLabelFilters(t *testing.T) {
+	pool := func(name string) NodeLabelFilterFunc {
+		return func(o interface{}) bool { return o.(*core.Node).Labels["pool"] == name }
+	}
+	all := func(o interface{}) bool { return true }
+	node := func(pool string) *core.Node {
+		return &core.Node{ObjectMeta: meta.ObjectMeta{Name: "node-" + pool, Labels: map[string]string{"pool": pool}}}
+	}
+
+	disjoint := NewDisjointNodeLabelFilters(map[string]NodeLabelFilterFunc{"a": pool("a"), "b": pool("b")}, zap.NewNop())
+	assert.True(t, disjoint["a"](node("a")))
+	assert.False(t, disjoint["a"](node("b")))
+	assert.True(t, disjoint["b"](node("b")))
+	assert.False(t, disjoint["b"](node("c")))
+
+	overlapping := NewDisjointNodeLabelFilters(map[string]NodeLabelFilterFunc{"a": pool("a"), "all": all}, zap.NewNop())
+	assert.False(t, overlapping["a"](node("a")), "the node is selected by both configurations")
+	assert.False(t, overlapping["all"](node("a")), "the node is selected by both configurations")
+	assert.True(t, overlapping["all"](node("b")))
+}
+
 func TestNodeProcessedFilter(t *testing.T) {
 	cases := []struct {
 		name         string
@@ -560,6 +770,45 @@ func TestParseConditions(t *testing.T) {
 			conditions: []string{`Ready={"conditionStatus":"Unknown","delay":"30m","expectedResolutionTime":"24h"}`},
 			expect:     []SuppliedCondition{{ID: "Ready", Type: core.NodeConditionType("Ready"), Status: core.ConditionStatus("Unknown"), parsedDelay: 30 * time.Minute, Delay: "30m", parsedExpectedResolutionTime: 24 * time.Hour, ExpectedResolutionTime: "24h"}},
 		},
+		{
+			name:       "NewFormatWithWindow",
+			conditions: []string{`Noisy={"window":"30m","minFraction":0.8}`},
+			expect:     []SuppliedCondition{{ID: "Noisy", Type: core.NodeConditionType("Noisy"), Status: core.ConditionStatus("True"), Window: "30m", MinFraction: 0.8, parsedWindow: 30 * time.Minute, parsedExpectedResolutionTime: DefaultExpectedResolutionTime, history: newConditionHistory()}},
+		},
+		{
+			name:       "ShortFormatWithWeight",
+			conditions: []string{"Ready=False,5m,weight=10"},
+			expect:     []SuppliedCondition{{ID: "Ready", Type: core.NodeConditionType("Ready"), Status: core.ConditionFalse, Delay: "5m", parsedDelay: 5 * time.Minute, Priority: 10, parsedExpectedResolutionTime: DefaultExpectedResolutionTime}},
+		},
+		{
+			name:       "ShortFormatStatusOnly",
+			conditions: []string{"Ready=Unknown"},
+			expect:     []SuppliedCondition{{ID: "Ready", Type: core.NodeConditionType("Ready"), Status: core.ConditionUnknown, parsedExpectedResolutionTime: DefaultExpectedResolutionTime}},
+		},
+		{
+			name:       "ShortFormatInvalidWeight",
+			conditions: []string{"Ready=False,5m,weight=high"},
+			expect:     nil,
+			expectErr:  true,
+		},
+		{
+			name:       "WindowWithoutFraction",
+			conditions: []string{`Noisy={"window":"30m"}`},
+			expect:     nil,
+			expectErr:  true,
+		},
+		{
+			name:       "FractionWithoutWindow",
+			conditions: []string{`Noisy={"minFraction":0.8}`},
+			expect:     nil,
+			expectErr:  true,
+		},
+		{
+			name:       "WindowWithDelay",
+			conditions: []string{`Noisy={"window":"30m","minFraction":0.8,"delay":"10m"}`},
+			expect:     nil,
+			expectErr:  true,
+		},
 		{
 			name:       "FormatError",
 			conditions: []string{"Ready=Unknown;30err"},
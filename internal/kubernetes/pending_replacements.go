@@ -0,0 +1,85 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+const (
+	// PendingReplacementsAnnotationKey holds the JSON list of the workloads evicted by the drain of the node that have no ready replacement yet
+	PendingReplacementsAnnotationKey = "draino/pending-replacements"
+)
+
+// PendingReplacements is the content of the PendingReplacementsAnnotationKey annotation
+type PendingReplacements struct {
+	// Since is the time at which the evictions completed
+	Since time.Time `json:"since"`
+	// Workloads are formatted as "<namespace>/<kind>/<name>", see GetPodWorkload
+	Workloads []string `json:"workloads"`
+	// PodsEvicted and PVCsDeleted are the stats of the drain, reported once the drain is completed
+	PodsEvicted int `json:"podsEvicted,omitempty"`
+	PVCsDeleted int `json:"pvcsDeleted,omitempty"`
+}
+
+// GetPendingReplacements returns the pending replacements recorded on the node
+func GetPendingReplacements(n *core.Node) (*PendingReplacements, bool, error) {
+	value, ok := n.Annotations[PendingReplacementsAnnotationKey]
+	if !ok {
+		return nil, false, nil
+	}
+	var pending PendingReplacements
+	if err := json.Unmarshal([]byte(value), &pending); err != nil {
+		return nil, true, err
+	}
+	return &pending, true, nil
+}
+
+// SetPendingReplacements patches the node annotation with the given pending replacements
+func SetPendingReplacements(ctx context.Context, c client.Client, n *core.Node, pending PendingReplacements) error {
+	value, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return k8sclient.PatchNodeAnnotationKeyCR(ctx, c, n, PendingReplacementsAnnotationKey, string(value))
+}
+
+// GetPodWorkload returns the workload of the pod as "<namespace>/<kind>/<name>" of its controller. The pods of a replicaset
+// owned by a deployment belong to the deployment, so that the pods of a new replicaset created by a rollout are replacements.
+// The pods without controller and the DaemonSet pods have no replacement elsewhere, they are ignored.
+func GetPodWorkload(pod *core.Pod, store RuntimeObjectStore) (string, bool) {
+	if workload, ok := getReplicaSetWorkload(pod, store); ok {
+		return pod.GetNamespace() + "/" + workload, true
+	}
+	ctrl := meta.GetControllerOf(pod)
+	if ctrl == nil || ctrl.Kind == KindDaemonSet {
+		return "", false
+	}
+	return pod.GetNamespace() + "/" + ctrl.Kind + "/" + ctrl.Name, true
+}
+
+// IsPodReadyWithGates returns true if the pod is running, ready and all its readiness gates are passed
+func IsPodReadyWithGates(pod *core.Pod) bool {
+	if !isPodRunningAndReady(pod) {
+		return false
+	}
+	for _, gate := range pod.Spec.ReadinessGates {
+		passed := false
+		for _, c := range pod.Status.Conditions {
+			if c.Type == gate.ConditionType {
+				passed = c.Status == core.ConditionTrue
+				break
+			}
+		}
+		if !passed {
+			return false
+		}
+	}
+	return true
+}
@@ -20,7 +20,11 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/cel-go/cel"
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,6 +55,17 @@ func LocalStoragePodFilter(p core.Pod) (bool, string, error) {
 	return true, "", nil
 }
 
+// HostPathPodFilter returns true if the supplied pod does not use any 'host path'
+// volumes, whose data is bound to the node like the 'empty dir' volumes.
+func HostPathPodFilter(p core.Pod) (bool, string, error) {
+	for _, v := range p.Spec.Volumes {
+		if v.HostPath != nil {
+			return false, "pod-local-storage-hostpath", nil
+		}
+	}
+	return true, "", nil
+}
+
 func NewPodControlledByFilter(controlledByAPIResources []*meta.APIResource) PodFilterFunc {
 	return func(p core.Pod) (bool, string, error) {
 		for _, controlledBy := range controlledByAPIResources {
@@ -74,6 +89,76 @@ func NewPodControlledByFilter(controlledByAPIResources []*meta.APIResource) PodF
 	}
 }
 
+const (
+	// ClusterAutoscalerSafeToEvictAnnotation protects the pod from being evicted by the cluster-autoscaler
+	// https://github.com/kubernetes/autoscaler/blob/master/cluster-autoscaler/FAQ.md#what-types-of-pods-can-prevent-ca-from-removing-a-node
+	ClusterAutoscalerSafeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict=false"
+	// KarpenterDoNotDisruptAnnotation protects the pod from any voluntary disruption done by Karpenter
+	// https://karpenter.sh/docs/concepts/disruption/#pod-level-controls
+	KarpenterDoNotDisruptAnnotation = "karpenter.sh/do-not-disrupt=true"
+)
+
+// DefaultSystemNamespaces are the namespaces whose pods block the drain of their node when the system namespaces are protected
+var DefaultSystemNamespaces = []string{"kube-system"}
+
+// NewSystemNamespacePodFilter returns a FilterFunc that returns false for the pods running in one of the given namespaces.
+// The mirror and DaemonSet pods, that stay on the node, pass the filter.
+func NewSystemNamespacePodFilter(namespaces ...string) PodFilterFunc {
+	return func(p core.Pod) (bool, string, error) {
+		if _, mirrorPod := p.GetAnnotations()[core.MirrorPodAnnotationKey]; mirrorPod {
+			return true, "", nil
+		}
+		if ctrl := meta.GetControllerOf(&p); ctrl != nil && ctrl.Kind == KindDaemonSet {
+			return true, "", nil
+		}
+		for _, ns := range namespaces {
+			if p.GetNamespace() == ns {
+				return false, "pod-system-namespace", nil
+			}
+		}
+		return true, "", nil
+	}
+}
+
+// NewProtectedPodCELFilter returns a FilterFunc that returns false for the pods matching the given CEL expression.
+// The expression is compiled once and evaluated with the pod, as unstructured, in the "pod" variable. A pod on which the
+// expression cannot be evaluated, because of a missing field for example, or does not give a bool is not protected:
+// the error is logged rather than failing the drain of the node.
+func NewProtectedPodCELFilter(expression string, log *zap.Logger) (PodFilterFunc, error) {
+	env, err := cel.NewEnv(cel.Variable("pod", cel.DynType))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create the CEL environment: %v", err)
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("cannot compile protected pod CEL expression: %v", issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build protected pod CEL program: %v", err)
+	}
+	return func(p core.Pod) (bool, string, error) {
+		podUnstruct, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&p)
+		if err != nil {
+			return false, "", err
+		}
+		result, _, err := program.Eval(map[string]interface{}{"pod": podUnstruct})
+		if err != nil {
+			log.Warn("Cannot evaluate the protected pod CEL expression, the pod is not protected", zap.String("pod", p.Namespace+"/"+p.Name), zap.Error(err))
+			return true, "", nil
+		}
+		protected, ok := result.Value().(bool)
+		if !ok {
+			log.Warn("The protected pod CEL expression did not return a bool, the pod is not protected", zap.String("pod", p.Namespace+"/"+p.Name), zap.String("type", result.Type().TypeName()))
+			return true, "", nil
+		}
+		if protected {
+			return false, "pod-cel-expression", nil
+		}
+		return true, "", nil
+	}, nil
+}
+
 // UnprotectedPodFilter returns a FilterFunc that returns true if the
 // supplied pod does not have any of the user-specified annotations for
 // protection from eviction
@@ -88,7 +173,7 @@ func UnprotectedPodFilter(store RuntimeObjectStore, checkController bool, annota
 				return false, "pod-annotation", nil
 			}
 			if checkController {
-				if ctrl, found := GetControllerForPod(&p, store); found {
+				for _, ctrl := range GetControllerChainForPod(&p, store) {
 					if selector.Matches(labels.Set(ctrl.GetAnnotations())) {
 						return false, "ctrl-annotation", nil
 					}
@@ -120,7 +205,7 @@ func PodOrControllerHasAnyOfTheAnnotations(store RuntimeObjectStore, annotations
 			if selector.Matches(labels.Set(p.GetAnnotations())) {
 				return true, "pod-annotation", nil
 			}
-			if ctrl, found := GetControllerForPod(&p, store); found {
+			for _, ctrl := range GetControllerChainForPod(&p, store) {
 				if selector.Matches(labels.Set(ctrl.GetAnnotations())) {
 					return true, "ctrl-annotation", nil
 				}
@@ -183,6 +268,66 @@ func NewPodFiltersIgnoreCompletedPods(filter PodFilterFunc) PodFilterFunc {
 	}
 }
 
+const (
+	// UtilityPodsEvict evicts the utility pods even if they are protected, they don't block the node from being candidate
+	UtilityPodsEvict = "evict"
+	// UtilityPodsIgnore leaves the utility pods on the node during the drain, they don't block the node from being candidate
+	UtilityPodsIgnore = "ignore"
+)
+
+// NewPodFiltersUtilityPods short-circuits the filter for the utility pods, see IsUtilityPod. They pass the filter if evict is set,
+// else they are rejected.
+func NewPodFiltersUtilityPods(filter PodFilterFunc, sidecars []string, evict bool) PodFilterFunc {
+	return func(p core.Pod) (bool, string, error) {
+		if IsUtilityPod(&p, sidecars) {
+			return evict, "utility-pod", nil
+		}
+		return filter(p)
+	}
+}
+
+// IsUtilityPod returns true if the pod is effectively done: its init containers completed and all its containers, except the
+// sidecars given by name, completed successfully. A pod needs at least one container that is not a sidecar, and a restart policy
+// other than Always: the terminated containers of such a pod are restarted, it is crash looping rather than done.
+func IsUtilityPod(pod *core.Pod, sidecars []string) bool {
+	if pod.Spec.RestartPolicy == core.RestartPolicyAlways {
+		return false
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.State.Terminated == nil || status.State.Terminated.ExitCode != 0 {
+			return false
+		}
+	}
+	completed := 0
+	for _, status := range pod.Status.ContainerStatuses {
+		if slices.Contains(sidecars, status.Name) {
+			continue
+		}
+		if status.State.Terminated == nil || status.State.Terminated.ExitCode != 0 {
+			return false
+		}
+		completed++
+	}
+	return completed > 0
+}
+
+const (
+	nodeLifecycleEnabledLabelKey = "node-lifecycle.datadoghq.com/enabled"
+	nodeLocalStorageLabelKey     = "nodegroups.datadoghq.com/local-storage"
+)
+
+// IsNodeWithoutLocalStorage returns true if the node is labeled nodegroups.datadoghq.com/local-storage=false,
+// unless its node group is managed by the node lifecycle (label node-lifecycle.datadoghq.com/enabled=true).
+func IsNodeWithoutLocalStorage(node *core.Node) bool {
+	if node.Labels == nil {
+		return false
+	}
+	if node.Labels[nodeLifecycleEnabledLabelKey] == "true" {
+		return false
+	}
+	return node.Labels[nodeLocalStorageLabelKey] == "false"
+}
+
 // NewPodFiltersNoStatefulSetOnNodeWithoutDisk for backward compatibility with Draino v1 configurations
 // we need to exclude pods that are associated with STS and that run on a node without local-storage
 func NewPodFiltersNoStatefulSetOnNodeWithoutDisk(store RuntimeObjectStore) PodFilterFunc {
@@ -201,13 +346,7 @@ func NewPodFiltersNoStatefulSetOnNodeWithoutDisk(store RuntimeObjectStore) PodFi
 		if err != nil {
 			return false, "can't check node for the pod", err
 		}
-		if node.Labels == nil {
-			return true, "", nil
-		}
-		if node.Labels["node-lifecycle.datadoghq.com/enabled"] == "true" {
-			return true, "", nil
-		}
-		if node.Labels["nodegroups.datadoghq.com/local-storage"] == "false" {
+		if IsNodeWithoutLocalStorage(node) {
 			return false, fmt.Sprintf("StatefulSet pod %s/%s on node without local-storage", p.Namespace, p.Name), nil
 		}
 		return true, "", nil
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"testing"
 
+	"go.uber.org/zap"
 	v1 "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -80,6 +81,28 @@ func TestPodFilters(t *testing.T) {
 			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc { return LocalStoragePodFilter },
 			passesFilter:      true,
 		},
+		{
+			name: "HasHostPath",
+			pod: core.Pod{
+				ObjectMeta: meta.ObjectMeta{Name: podName},
+				Spec: core.PodSpec{
+					Volumes: []core.Volume{core.Volume{VolumeSource: core.VolumeSource{HostPath: &core.HostPathVolumeSource{Path: "/var/lib/data"}}}},
+				},
+			},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc { return HostPathPodFilter },
+			passesFilter:      false,
+		},
+		{
+			name: "DoesNotHaveHostPath",
+			pod: core.Pod{
+				ObjectMeta: meta.ObjectMeta{Name: podName},
+				Spec: core.PodSpec{
+					Volumes: []core.Volume{core.Volume{VolumeSource: core.VolumeSource{EmptyDir: &core.EmptyDirVolumeSource{}}}},
+				},
+			},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc { return HostPathPodFilter },
+			passesFilter:      true,
+		},
 		{
 			name: "Unreplicated",
 			pod:  core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName}},
@@ -296,6 +319,32 @@ func TestPodFilters(t *testing.T) {
 			},
 			passesFilter: true,
 		},
+		{
+			name: "KarpenterDoNotDisruptBlocksCandidate",
+			pod: core.Pod{
+				ObjectMeta: meta.ObjectMeta{
+					Name:        podName,
+					Annotations: map[string]string{"karpenter.sh/do-not-disrupt": "true"},
+				},
+			},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return UnprotectedPodFilter(store, true, ClusterAutoscalerSafeToEvictAnnotation, KarpenterDoNotDisruptAnnotation)
+			},
+			passesFilter: false,
+		},
+		{
+			name: "KarpenterDoNotDisruptFalse",
+			pod: core.Pod{
+				ObjectMeta: meta.ObjectMeta{
+					Name:        podName,
+					Annotations: map[string]string{"karpenter.sh/do-not-disrupt": "false"},
+				},
+			},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return UnprotectedPodFilter(store, true, ClusterAutoscalerSafeToEvictAnnotation, KarpenterDoNotDisruptAnnotation)
+			},
+			passesFilter: true,
+		},
 		{
 			name: "NoProtectionFromPodEviction",
 			pod: core.Pod{
@@ -688,6 +737,48 @@ func TestPodFilters(t *testing.T) {
 			},
 			passesFilter: true,
 		},
+		{
+			name: "SystemNamespaceNotProtectedByDefault",
+			pod:  core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "kube-system"}},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return NewPodFilters(systemNamespacePodFilters(FilterOptions{})...)
+			},
+			passesFilter: true,
+		},
+		{
+			name: "ProtectedSystemNamespace",
+			pod:  core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "kube-system"}},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return NewPodFilters(systemNamespacePodFilters(FilterOptions{ProtectSystemNamespaces: true})...)
+			},
+			passesFilter: false,
+		},
+		{
+			name: "ProtectedSystemNamespaceDaemonSet",
+			pod: core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "kube-system", OwnerReferences: []meta.OwnerReference{
+				{Controller: &isController, Kind: KindDaemonSet, Name: daemonsetName, APIVersion: "apps/v1"},
+			}}},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return NewPodFilters(systemNamespacePodFilters(FilterOptions{ProtectSystemNamespaces: true})...)
+			},
+			passesFilter: true,
+		},
+		{
+			name: "AdditionalSystemNamespace",
+			pod:  core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "monitoring"}},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return NewPodFilters(systemNamespacePodFilters(FilterOptions{ProtectSystemNamespaces: true, SystemNamespaces: []string{"monitoring"}})...)
+			},
+			passesFilter: false,
+		},
+		{
+			name: "NotSystemNamespace",
+			pod:  core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName, Namespace: "default"}},
+			filterBuilderFunc: func(store RuntimeObjectStore, obj ...runtime.Object) PodFilterFunc {
+				return NewPodFilters(systemNamespacePodFilters(FilterOptions{ProtectSystemNamespaces: true, SystemNamespaces: []string{"monitoring"}})...)
+			},
+			passesFilter: true,
+		},
 	}
 
 	for _, tc := range cases {
@@ -707,3 +798,182 @@ func TestPodFilters(t *testing.T) {
 		})
 	}
 }
+
+func TestProtectedPodCELFilter(t *testing.T) {
+	pod := core.Pod{
+		ObjectMeta: meta.ObjectMeta{
+			Name:            podName,
+			Labels:          map[string]string{"app": "db"},
+			OwnerReferences: []meta.OwnerReference{{Kind: KindStatefulSet, Name: "db"}},
+		},
+		Spec: core.PodSpec{Containers: []core.Container{{Name: "db", Image: "postgres:15"}}},
+	}
+	cases := []struct {
+		name         string
+		expression   string
+		passesFilter bool
+	}{
+		{
+			name:         "MatchingLabel",
+			expression:   `pod.metadata.labels.app == "db"`,
+			passesFilter: false,
+		},
+		{
+			name:         "NotMatchingLabel",
+			expression:   `pod.metadata.labels.app == "web"`,
+			passesFilter: true,
+		},
+		{
+			name:         "MatchingContainerImage",
+			expression:   `pod.spec.containers.exists(c, c.image.startsWith("postgres:"))`,
+			passesFilter: false,
+		},
+		{
+			name:         "NotMatchingContainerImage",
+			expression:   `pod.spec.containers.exists(c, c.image.startsWith("nginx:"))`,
+			passesFilter: true,
+		},
+		{
+			name:         "MatchingOwnerKind",
+			expression:   `pod.metadata.ownerReferences.exists(o, o.kind == "StatefulSet")`,
+			passesFilter: false,
+		},
+		{
+			name:         "NotMatchingOwnerKind",
+			expression:   `pod.metadata.ownerReferences.exists(o, o.kind == "DaemonSet")`,
+			passesFilter: true,
+		},
+		{
+			name:         "MissingLabelIsNotProtected",
+			expression:   `pod.metadata.labels.tier == "critical"`,
+			passesFilter: true,
+		},
+		{
+			name:         "MissingMapIsNotProtected",
+			expression:   `pod.metadata.annotations["example.com/protected"] == "true"`,
+			passesFilter: true,
+		},
+		{
+			name:         "MissingFieldGuardedByHas",
+			expression:   `!has(pod.spec.nodeSelector) && pod.metadata.labels.app == "db"`,
+			passesFilter: false,
+		},
+		{
+			name:         "NotBoolIsNotProtected",
+			expression:   `pod.metadata.name`,
+			passesFilter: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := NewProtectedPodCELFilter(tc.expression, zap.NewNop())
+			if err != nil {
+				t.Fatalf("NewProtectedPodCELFilter(%v): %v", tc.expression, err)
+			}
+			passesFilter, _, err := filter(pod)
+			if err != nil {
+				t.Errorf("filter(%v): %v", tc.expression, err)
+			}
+			if passesFilter != tc.passesFilter {
+				t.Errorf("filter(%v): want %v, got %v", tc.expression, tc.passesFilter, passesFilter)
+			}
+		})
+	}
+
+	if _, err := NewProtectedPodCELFilter("pod.metadata.labels.app ==", zap.NewNop()); err == nil {
+		t.Errorf("NewProtectedPodCELFilter: want an error for an invalid expression")
+	}
+}
+
+func TestUtilityPodFilter(t *testing.T) {
+	running := core.ContainerState{Running: &core.ContainerStateRunning{}}
+	completed := core.ContainerState{Terminated: &core.ContainerStateTerminated{ExitCode: 0}}
+	failed := core.ContainerState{Terminated: &core.ContainerStateTerminated{ExitCode: 1}}
+	newPod := func(initContainers, containers map[string]core.ContainerState) core.Pod {
+		pod := core.Pod{ObjectMeta: meta.ObjectMeta{Name: podName}, Spec: core.PodSpec{RestartPolicy: core.RestartPolicyNever}}
+		for name, state := range initContainers {
+			pod.Status.InitContainerStatuses = append(pod.Status.InitContainerStatuses, core.ContainerStatus{Name: name, State: state})
+		}
+		for name, state := range containers {
+			pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, core.ContainerStatus{Name: name, State: state})
+		}
+		return pod
+	}
+	protectAll := func(p core.Pod) (bool, string, error) { return false, "protected", nil }
+
+	tests := []struct {
+		name           string
+		pod            core.Pod
+		isUtilityPod   bool
+		evictedIfEvict bool
+	}{
+		{
+			name:           "sidecar-only pod",
+			pod:            newPod(map[string]core.ContainerState{"init": completed}, map[string]core.ContainerState{"job": completed, "istio-proxy": running}),
+			isUtilityPod:   true,
+			evictedIfEvict: true,
+		},
+		{
+			name:           "all containers terminated",
+			pod:            newPod(nil, map[string]core.ContainerState{"job": completed}),
+			isUtilityPod:   true,
+			evictedIfEvict: true,
+		},
+		{
+			name: "normal workload pod",
+			pod:  newPod(map[string]core.ContainerState{"init": completed}, map[string]core.ContainerState{"app": running, "istio-proxy": running}),
+		},
+		{
+			name: "init container still running",
+			pod:  newPod(map[string]core.ContainerState{"init": running}, map[string]core.ContainerState{"istio-proxy": running}),
+		},
+		{
+			name: "pod without status",
+			pod:  newPod(nil, nil),
+		},
+		{
+			name: "pod with only sidecars",
+			pod:  newPod(nil, map[string]core.ContainerState{"istio-proxy": running}),
+		},
+		{
+			name: "container terminated with an error",
+			pod:  newPod(nil, map[string]core.ContainerState{"job": failed, "istio-proxy": running}),
+		},
+		{
+			name: "crash looping pod",
+			pod: func() core.Pod {
+				pod := newPod(nil, map[string]core.ContainerState{"app": completed, "istio-proxy": running})
+				pod.Spec.RestartPolicy = core.RestartPolicyAlways
+				return pod
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsUtilityPod(&tt.pod, []string{"istio-proxy"}); got != tt.isUtilityPod {
+				t.Errorf("IsUtilityPod: want %v, got %v", tt.isUtilityPod, got)
+			}
+
+			passes, _, err := NewPodFiltersUtilityPods(protectAll, []string{"istio-proxy"}, true)(tt.pod)
+			if err != nil {
+				t.Errorf("evict treatment: %v", err)
+			}
+			if passes != tt.evictedIfEvict {
+				t.Errorf("evict treatment: want %v, got %v", tt.evictedIfEvict, passes)
+			}
+
+			wantReason := "protected"
+			if tt.isUtilityPod {
+				wantReason = "utility-pod"
+			}
+			passes, reason, err := NewPodFiltersUtilityPods(protectAll, []string{"istio-proxy"}, false)(tt.pod)
+			if err != nil {
+				t.Errorf("ignore treatment: %v", err)
+			}
+			if passes || reason != wantReason {
+				t.Errorf("ignore treatment: want false with reason %s, got %v with reason %s", wantReason, passes, reason)
+			}
+		})
+	}
+}
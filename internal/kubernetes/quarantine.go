@@ -0,0 +1,25 @@
+package kubernetes
+
+import (
+	core "k8s.io/api/core/v1"
+)
+
+const (
+	// QuarantinedLabelKey is set on the nodes kept cordoned after reaching the retry threshold of their drain failures.
+	// Draino ignores these nodes until the label is removed.
+	QuarantinedLabelKey   = "draino/quarantined"
+	QuarantinedLabelValue = "true"
+	// QuarantineCordonAnnotationKey is set on the nodes cordoned by the quarantine, so that draino uncordons them once the label is removed
+	QuarantineCordonAnnotationKey = "draino/quarantine-cordon"
+)
+
+// IsQuarantined returns true if the node holds the quarantine label
+func IsQuarantined(node *core.Node) bool {
+	return node.Labels[QuarantinedLabelKey] == QuarantinedLabelValue
+}
+
+// IsQuarantineReleased returns true if the node was cordoned by the quarantine and its quarantine label was removed since
+func IsQuarantineReleased(node *core.Node) bool {
+	_, cordoned := node.Annotations[QuarantineCordonAnnotationKey]
+	return cordoned && !IsQuarantined(node)
+}
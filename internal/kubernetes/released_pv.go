@@ -0,0 +1,81 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// DefaultReleasedPVCleanupPeriod is the period between two scans of the released persistent volumes
+	DefaultReleasedPVCleanupPeriod = 5 * time.Minute
+)
+
+// ReleasedPVCleaner periodically deletes the persistent volumes left in Released phase, for the storage classes allowing deletion.
+// Such volumes are left behind when the node or the claim was deleted without going through the drain PVC cleanup.
+type ReleasedPVCleaner struct {
+	client         client.Client
+	logger         logr.Logger
+	eventRecorder  EventRecorder
+	period         time.Duration
+	storageClasses map[string]struct{}
+}
+
+var _ manager.Runnable = &ReleasedPVCleaner{}
+
+func NewReleasedPVCleaner(client client.Client, logger logr.Logger, eventRecorder EventRecorder, period time.Duration, storageClasses []string) *ReleasedPVCleaner {
+	cleaner := &ReleasedPVCleaner{
+		client:         client,
+		logger:         logger.WithName("ReleasedPVCleaner"),
+		eventRecorder:  eventRecorder,
+		period:         period,
+		storageClasses: map[string]struct{}{},
+	}
+	for _, sc := range storageClasses {
+		cleaner.storageClasses[sc] = struct{}{}
+	}
+	return cleaner
+}
+
+// Start runs the cleaner till the context is Done, blocking call
+func (c *ReleasedPVCleaner) Start(ctx context.Context) error {
+	c.logger.Info("starting", "period", c.period)
+	wait.UntilWithContext(ctx, c.cleanup, c.period)
+	return nil
+}
+
+func (c *ReleasedPVCleaner) cleanup(ctx context.Context) {
+	var pvs core.PersistentVolumeList
+	if err := c.client.List(ctx, &pvs); err != nil {
+		c.logger.Error(err, "cannot list persistent volumes")
+		return
+	}
+
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		if !c.isEligible(pv) {
+			continue
+		}
+		if err := c.client.Delete(ctx, pv); client.IgnoreNotFound(err) != nil {
+			c.logger.Error(err, "cannot delete released persistent volume", "pv", pv.Name)
+			c.eventRecorder.PersistentVolumeEventf(ctx, pv, core.EventTypeWarning, EventReasonReleasedPVCleanup.String(), "Could not delete released PV: %v", err)
+			continue
+		}
+		c.logger.Info("deleted released persistent volume", "pv", pv.Name, "storageClass", pv.Spec.StorageClassName)
+		c.eventRecorder.PersistentVolumeEventf(ctx, pv, core.EventTypeNormal, EventReasonReleasedPVCleanup.String(), "Released PV deleted")
+	}
+}
+
+// isEligible returns true if the volume is Released and its storage class allows deletion
+func (c *ReleasedPVCleaner) isEligible(pv *core.PersistentVolume) bool {
+	if pv.Status.Phase != core.VolumeReleased {
+		return false
+	}
+	_, ok := c.storageClasses[pv.Spec.StorageClassName]
+	return ok
+}
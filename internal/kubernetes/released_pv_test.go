@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReleasedPVCleaner(t *testing.T) {
+	newPV := func(name, storageClass string, phase core.PersistentVolumePhase) *core.PersistentVolume {
+		return &core.PersistentVolume{
+			ObjectMeta: meta.ObjectMeta{Name: name},
+			Spec:       core.PersistentVolumeSpec{StorageClassName: storageClass},
+			Status:     core.PersistentVolumeStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		pv            *core.PersistentVolume
+		expectDeleted bool
+	}{
+		{
+			name:          "released in allowed class",
+			pv:            newPV("pv-allowed", "local-data", core.VolumeReleased),
+			expectDeleted: true,
+		},
+		{
+			name: "released in disallowed class",
+			pv:   newPV("pv-disallowed", "standard", core.VolumeReleased),
+		},
+		{
+			name: "bound in allowed class",
+			pv:   newPV("pv-bound", "local-data", core.VolumeBound),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kclient := fake.NewClientBuilder().WithObjects(tt.pv).Build()
+			cleaner := NewReleasedPVCleaner(kclient, logr.Discard(), NoopEventRecorder{}, DefaultReleasedPVCleanupPeriod, []string{"local-data"})
+
+			cleaner.cleanup(context.Background())
+
+			var pv core.PersistentVolume
+			err := kclient.Get(context.Background(), types.NamespacedName{Name: tt.pv.Name}, &pv)
+			if tt.expectDeleted {
+				assert.True(t, apierrors.IsNotFound(err), "pv should be deleted")
+			} else {
+				assert.NoError(t, err, "pv should not be deleted")
+			}
+		})
+	}
+}
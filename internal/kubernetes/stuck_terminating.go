@@ -0,0 +1,125 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	core "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	DefaultStuckTerminatingTimeout = time.Minute
+
+	// forcedDeletionTimeout is the time given to the API server to remove a pod deleted with a zero grace period
+	forcedDeletionTimeout = 30 * time.Second
+)
+
+// StuckTerminatingAction is what the drainer does with an evicted pod still terminating after its grace period and the stuck terminating timeout
+type StuckTerminatingAction string
+
+const (
+	// StuckTerminatingActionWait keeps waiting for the pod deletion, the eviction fails once the deletion timeout is reached
+	StuckTerminatingActionWait StuckTerminatingAction = "wait"
+	// StuckTerminatingActionForce deletes the pod with a zero grace period and waits for it to disappear
+	StuckTerminatingActionForce StuckTerminatingAction = "force"
+	// StuckTerminatingActionSkip stops waiting for the pod, its eviction is considered done
+	StuckTerminatingActionSkip StuckTerminatingAction = "skip"
+)
+
+// ParseStuckTerminatingAction validates the name of a StuckTerminatingAction
+func ParseStuckTerminatingAction(s string) (StuckTerminatingAction, error) {
+	switch a := StuckTerminatingAction(s); a {
+	case StuckTerminatingActionWait, StuckTerminatingActionForce, StuckTerminatingActionSkip:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown stuck terminating action '%s', must be '%s', '%s' or '%s'", s, StuckTerminatingActionWait, StuckTerminatingActionForce, StuckTerminatingActionSkip)
+}
+
+// WithStuckTerminatingAction configures what the drainer does with an evicted pod that is still terminating after its
+// grace period augmented by the timeout, instead of waiting for the usual deletion timeout.
+func WithStuckTerminatingAction(action StuckTerminatingAction, timeout time.Duration) APIDrainerOption {
+	return func(d *APIDrainer) {
+		d.stuckTerminatingAction = action
+		d.stuckTerminatingTimeout = timeout
+	}
+}
+
+// awaitTermination waits for the deletion of an evicted pod, applying the stuck terminating action if the pod is still there
+// after its grace period and the stuck terminating timeout. The termination latency is recorded once the pod is deleted.
+func (d *APIDrainer) awaitTermination(ctx context.Context, node *core.Node, pod *core.Pod) error {
+	start := time.Now()
+	deletedAt, err := d.waitForTermination(ctx, node, pod)
+	if err != nil {
+		return err
+	}
+	if !deletedAt.IsZero() {
+		recordPodTerminationLatency(ctx, pod, start, deletedAt)
+	}
+	return nil
+}
+
+// waitForTermination returns the time at which the pod was deleted, or a zero time if the pod stuck terminating was skipped
+func (d *APIDrainer) waitForTermination(ctx context.Context, node *core.Node, pod *core.Pod) (time.Time, error) {
+	if d.stuckTerminatingAction == "" || d.stuckTerminatingAction == StuckTerminatingActionWait {
+		return d.waitForDeletion(ctx, pod, d.getGracePeriodWithEvictionHeadRoom(pod))
+	}
+
+	gracePeriod, _ := d.getPodGracePeriod(pod)
+	stuckTimeout := gracePeriod + d.stuckTerminatingTimeout
+	deletedAt, err := d.waitForDeletion(ctx, pod, stuckTimeout)
+	if err == nil {
+		return deletedAt, nil
+	}
+	if !errors.As(err, &PodDeletionTimeoutError{}) {
+		return time.Time{}, err
+	}
+
+	// a deletion with a zero grace period only helps the pods stuck on the kubelet side, the finalizers still hold the others
+	var finalizers []string
+	var got core.Pod
+	if errGet := d.crClient.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name}, &got); errGet == nil {
+		finalizers = got.GetFinalizers()
+	}
+	logger := LoggerForNode(node, d.globalConfig.TeamLabelKey, d.l).With(zap.String("pod", pod.GetNamespace()+"/"+pod.GetName()), zap.String("action", string(d.stuckTerminatingAction)), zap.Strings("finalizers", finalizers))
+	switch d.stuckTerminatingAction {
+	case StuckTerminatingActionForce:
+		logger.Warn("force deleting pod stuck terminating")
+		d.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, EventReasonPodStuckTerminating.String(), "Pod %s/%s still terminating after %s%s, force deleting it", pod.Namespace, pod.Name, stuckTimeout, describeFinalizers(finalizers))
+		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonPodStuckTerminating.String(), "Pod still terminating after %s%s, force deleting it to drain node %s", stuckTimeout, describeFinalizers(finalizers), node.Name)
+		err := d.c.CoreV1().Pods(pod.GetNamespace()).Delete(ctx, pod.GetName(), meta.DeleteOptions{
+			GracePeriodSeconds: new(int64),
+			Preconditions:      &meta.Preconditions{UID: &pod.UID},
+		})
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			return time.Time{}, fmt.Errorf("cannot force delete pod %s/%s: %w", pod.GetNamespace(), pod.GetName(), err)
+		}
+		deletedAt, err := d.waitForDeletion(ctx, pod, forcedDeletionTimeout)
+		if err != nil {
+			if len(finalizers) > 0 {
+				return time.Time{}, fmt.Errorf("pod %s/%s held by finalizers %s: %w", pod.GetNamespace(), pod.GetName(), strings.Join(finalizers, ","), err)
+			}
+			return time.Time{}, err
+		}
+		return deletedAt, nil
+	case StuckTerminatingActionSkip:
+		logger.Warn("skipping pod stuck terminating")
+		d.eventRecorder.NodeEventf(ctx, node, core.EventTypeWarning, EventReasonPodStuckTerminating.String(), "Pod %s/%s still terminating after %s%s, skipping it", pod.Namespace, pod.Name, stuckTimeout, describeFinalizers(finalizers))
+		d.eventRecorder.PodEventf(ctx, pod, core.EventTypeWarning, EventReasonPodStuckTerminating.String(), "Pod still terminating after %s%s, skipping it to drain node %s", stuckTimeout, describeFinalizers(finalizers), node.Name)
+		return time.Time{}, nil
+	}
+	return time.Time{}, err
+}
+
+func describeFinalizers(finalizers []string) string {
+	if len(finalizers) == 0 {
+		return ""
+	}
+	return " (finalizers: " + strings.Join(finalizers, ",") + ")"
+}
@@ -31,6 +31,7 @@ import (
 	"go.opencensus.io/tag"
 	"go.uber.org/zap"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+	appsv1 "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -210,14 +211,26 @@ func GetAPIResourcesForGVK(discoveryInterface discovery.DiscoveryInterface, gvks
 	return output, nil
 }
 
+// DefaultTeamLabelKey is the label giving the team owning a node or a pod
+const DefaultTeamLabelKey = "team"
+
+// teamLabel returns the label giving the team of the nodes and pods, DefaultTeamLabelKey if the key is not configured
+func teamLabel(teamLabelKey string) string {
+	if teamLabelKey == "" {
+		return DefaultTeamLabelKey
+	}
+	return teamLabelKey
+}
+
 type NodeTagsValues struct {
 	Team, NgName, NgNamespace, Service string
 }
 
-func GetNodeTagsValues(node *core.Node) NodeTagsValues {
+// GetNodeTagsValues returns the tags of the node, its team is given by the managed_by_team label or else by the teamLabelKey label
+func GetNodeTagsValues(node *core.Node, teamLabelKey string) NodeTagsValues {
 	team := node.Labels["managed_by_team"]
 	if team == "" {
-		team = node.Labels["team"]
+		team = node.Labels[teamLabel(teamLabelKey)]
 	}
 	service := node.Labels["service"]
 	if service == "" {
@@ -236,8 +249,9 @@ type PodTagsValues struct {
 	Team, Service string
 }
 
-func GetPodTagsValues(pod *core.Pod) PodTagsValues {
-	team := pod.Labels["team"]
+// GetPodTagsValues returns the tags of the pod, its team is given by the teamLabelKey label
+func GetPodTagsValues(pod *core.Pod, teamLabelKey string) PodTagsValues {
+	team := pod.Labels[teamLabel(teamLabelKey)]
 	service := pod.Labels["service"]
 
 	return PodTagsValues{
@@ -250,22 +264,19 @@ func GetNodeGroupNamePrefix(ngName string) string {
 	return strings.Split(ngName, "-")[0]
 }
 
-func nodeTags(ctx context.Context, node *core.Node) (context.Context, error) {
-	values := GetNodeTagsValues(node)
+func nodeTags(ctx context.Context, node *core.Node, teamLabelKey string) (context.Context, error) {
+	values := GetNodeTagsValues(node, teamLabelKey)
 	return tag.New(ctx, tag.Upsert(TagNodegroupNamespace, values.NgNamespace), tag.Upsert(TagNodegroupName, values.NgName), tag.Upsert(TagNodegroupNamePrefix, GetNodeGroupNamePrefix(values.NgName)), tag.Upsert(TagTeam, values.Team), tag.Upsert(TagService, values.Service))
 }
 
-func StatRecordForNode(ctx context.Context, node *core.Node, m stats.Measurement) {
-	tagsWithNg, _ := nodeTags(ctx, node)
+func StatRecordForNode(ctx context.Context, node *core.Node, teamLabelKey string, m stats.Measurement) {
+	tagsWithNg, _ := nodeTags(ctx, node, teamLabelKey)
 	stats.Record(tagsWithNg, m)
 }
 
-func LoggerForNode(n *core.Node, logger *zap.Logger) *zap.Logger {
-	team := n.Labels["managed_by_team"]
-	if team == "" {
-		team = n.Labels["team"]
-	}
-	return logger.With(zap.String("node", n.Name), zap.String("ng_name", n.Labels[LabelKeyNodeGroupName]), zap.String("ng_namespace", n.Labels[LabelKeyNodeGroupNamespace]), zap.String("node_team", n.Labels[LabelKeyNodeGroupNamespace]))
+func LoggerForNode(n *core.Node, teamLabelKey string, logger *zap.Logger) *zap.Logger {
+	values := GetNodeTagsValues(n, teamLabelKey)
+	return logger.With(zap.String("node", n.Name), zap.String("ng_name", values.NgName), zap.String("ng_namespace", values.NgNamespace), zap.String("node_team", values.Team))
 }
 
 func TracedLogger(context context.Context, logger *zap.Logger) *zap.Logger {
@@ -278,8 +289,8 @@ func TracedLogger(context context.Context, logger *zap.Logger) *zap.Logger {
 	return logger
 }
 
-func TracedLoggerForNode(context context.Context, n *core.Node, logger *zap.Logger) *zap.Logger {
-	return TracedLogger(context, LoggerForNode(n, logger))
+func TracedLoggerForNode(context context.Context, n *core.Node, teamLabelKey string, logger *zap.Logger) *zap.Logger {
+	return TracedLogger(context, LoggerForNode(n, teamLabelKey, logger))
 }
 
 type Runner interface {
@@ -297,11 +308,12 @@ func Await(ctx context.Context, rs ...Runner) error {
 	return g.Run()
 }
 
-// GetAnnotationFromPodOrController check if an annotation is present on the pod or the associated controller object
+// GetAnnotationFromPodOrController check if an annotation is present on the pod or on one of its controllers
+// The owner chain is explored from the pod up to the top-level controller, see GetControllerChainForPod
 // Supported controller object:
 // - statefulset
+// - replicaset
 // - deployment
-// Method made generic to be able to extend to deployments and other controllers later
 func GetAnnotationFromPodOrController(annotationKey string, pod *core.Pod, store RuntimeObjectStore) (value string, found bool) {
 	// Check directly on the pod and return if any value
 	if pod.Annotations != nil {
@@ -310,9 +322,10 @@ func GetAnnotationFromPodOrController(annotationKey string, pod *core.Pod, store
 		}
 	}
 
-	if ctrl, found := GetControllerForPod(pod, store); found {
-		v, ok := ctrl.GetAnnotations()[annotationKey]
-		return v, ok
+	for _, ctrl := range GetControllerChainForPod(pod, store) {
+		if v, ok := ctrl.GetAnnotations()[annotationKey]; ok {
+			return v, ok
+		}
 	}
 	return "", false
 }
@@ -326,19 +339,70 @@ func GetEvictionAPIURL(pod *core.Pod, store RuntimeObjectStore) (value string, f
 	return evictionAPIURL, ok
 }
 
-// GetControllerForPod for the moment it handles only statefulSets and deployments controller
+// GetControllerForPod returns the top-level controller of the pod, for the moment it handles only statefulSets and deployments controller
 func GetControllerForPod(pod *core.Pod, store RuntimeObjectStore) (ctrl metav1.Object, found bool) {
-	for _, r := range pod.OwnerReferences {
-		if r.Kind == "StatefulSet" {
-			sts, err := store.StatefulSets().Get(pod.Namespace, r.Name)
+	chain := GetControllerChainForPod(pod, store)
+	for i := len(chain) - 1; i >= 0; i-- {
+		switch chain[i].(type) {
+		case *appsv1.StatefulSet, *appsv1.Deployment:
+			return chain[i], true
+		}
+	}
+	return nil, false
+}
+
+// DefaultOwnerChainMaxDepth is enough to go from a pod to its deployment: pod→replicaset→deployment
+const DefaultOwnerChainMaxDepth = 2
+
+// GetControllerChainForPod returns the owners of the pod, from the direct owner up to the top-level controller.
+// The exploration stops when an owner cannot be found in the store or after store.OwnerChainMaxDepth() owners.
+// The deployment of a replicaset is always part of the chain, as it was found before the exploration of the owner chain.
+func GetControllerChainForPod(pod *core.Pod, store RuntimeObjectStore) (chain []metav1.Object) {
+	if store == nil || len(pod.OwnerReferences) == 0 {
+		return nil
+	}
+	var current metav1.Object = pod
+	for depth := 0; depth < store.OwnerChainMaxDepth(); depth++ {
+		owner, found := getOwner(current, store)
+		if !found {
+			return chain
+		}
+		chain = append(chain, owner)
+		current = owner
+	}
+	if _, isReplicaSet := current.(*appsv1.ReplicaSet); isReplicaSet {
+		if deployment, found := getOwner(current, store); found {
+			chain = append(chain, deployment)
+		}
+	}
+	return chain
+}
+
+func getOwner(obj metav1.Object, store RuntimeObjectStore) (metav1.Object, bool) {
+	for _, r := range obj.GetOwnerReferences() {
+		switch r.Kind {
+		case KindStatefulSet:
+			sts, err := store.StatefulSets().Get(obj.GetNamespace(), r.Name)
 			if err != nil {
 				return nil, false
 			}
 			return sts, true
-		}
-		if r.Kind == "ReplicaSet" {
-			deploymentName := r.Name[:strings.LastIndex(r.Name, "-")]
-			deployment, err := store.Deployments().Get(pod.Namespace, deploymentName)
+		case KindReplicaSet:
+			if rs, err := store.ReplicaSets().Get(obj.GetNamespace(), r.Name); err == nil {
+				return rs, true
+			}
+			// the replicaset is not in the cache (yet), fallback on the naming convention to find the deployment
+			idx := strings.LastIndex(r.Name, "-")
+			if idx < 0 {
+				return nil, false
+			}
+			deployment, err := store.Deployments().Get(obj.GetNamespace(), r.Name[:idx])
+			if err != nil {
+				return nil, false
+			}
+			return deployment, true
+		case KindDeployment:
+			deployment, err := store.Deployments().Get(obj.GetNamespace(), r.Name)
 			if err != nil {
 				return nil, false
 			}
@@ -1,14 +1,19 @@
 package kubernetes
 
 import (
+	"context"
 	"reflect"
 	"testing"
 
 	openapi_v2 "github.com/google/gnostic/openapiv2"
+	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
+	fakeclient "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/openapi"
 	"k8s.io/client-go/rest"
 )
@@ -241,3 +246,108 @@ func TestGetAPIResourcesForGVK(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAnnotationFromPodOrController_OwnerChain(t *testing.T) {
+	const annotationKey = "test/protected"
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns", Annotations: map[string]string{annotationKey: "true"}},
+	}
+	// the replicaset name doesn't follow the deployment naming convention, so the deployment can only be found through the owner chain
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "rs",
+			Namespace:       "ns",
+			OwnerReferences: []metav1.OwnerReference{{Kind: KindDeployment, Name: "app"}},
+		},
+	}
+	pod := &core.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pod",
+			Namespace:       "ns",
+			OwnerReferences: []metav1.OwnerReference{{Kind: KindReplicaSet, Name: "rs"}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		maxDepth      int
+		expectedFound bool
+		expectedChain []string
+	}{
+		{
+			name:          "default depth reaches the deployment",
+			expectedFound: true,
+			expectedChain: []string{"rs", "app"},
+		},
+		{
+			name:          "depth bounded to the replicaset still reaches the deployment",
+			maxDepth:      1,
+			expectedFound: true,
+			expectedChain: []string{"rs", "app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, closeFunc := RunStoreForTest(context.Background(), fakeclient.NewSimpleClientset(deployment, replicaSet, pod))
+			defer closeFunc()
+			store.(*RuntimeObjectStoreImpl).MaxOwnerChainDepth = tt.maxDepth
+
+			chain := GetControllerChainForPod(pod, store)
+			names := make([]string, 0, len(chain))
+			for _, ctrl := range chain {
+				names = append(names, ctrl.GetName())
+			}
+			assert.Equal(t, tt.expectedChain, names)
+
+			value, found := GetAnnotationFromPodOrController(annotationKey, pod, store)
+			assert.Equal(t, tt.expectedFound, found)
+			if tt.expectedFound {
+				assert.Equal(t, "true", value)
+			}
+		})
+	}
+}
+
+func TestGetNodeTagsValues_Team(t *testing.T) {
+	tests := []struct {
+		name         string
+		teamLabelKey string
+		labels       map[string]string
+		expectedTeam string
+	}{
+		{
+			name:         "default team label",
+			teamLabelKey: DefaultTeamLabelKey,
+			labels:       map[string]string{"team": "storage"},
+			expectedTeam: "storage",
+		},
+		{
+			name:         "configured team label",
+			teamLabelKey: "example.com/owner",
+			labels:       map[string]string{"team": "storage", "example.com/owner": "compute"},
+			expectedTeam: "compute",
+		},
+		{
+			name:         "managed_by_team takes precedence",
+			teamLabelKey: "example.com/owner",
+			labels:       map[string]string{"managed_by_team": "platform", "example.com/owner": "compute"},
+			expectedTeam: "platform",
+		},
+		{
+			name:         "no team label",
+			teamLabelKey: "example.com/owner",
+			labels:       map[string]string{"team": "storage"},
+			expectedTeam: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &core.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: tt.labels}}
+			assert.Equal(t, tt.expectedTeam, GetNodeTagsValues(node, tt.teamLabelKey).Team)
+		})
+	}
+
+	pod := &core.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Labels: map[string]string{"example.com/owner": "compute", "team": "storage"}}}
+	assert.Equal(t, "compute", GetPodTagsValues(pod, "example.com/owner").Team)
+	assert.Equal(t, "storage", GetPodTagsValues(pod, "").Team, "the default team label is used when the key is not configured")
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// MaxPeriodJitterFactor is the highest jitter factor accepted, the period varies at most by ±50%
+	MaxPeriodJitterFactor = 0.5
+	// MinJitteredPeriod the jitter never brings the period below this value
+	MinJitteredPeriod = time.Second
+)
+
+// JitterBounds returns the base period and the jitter factor to give to wait.Jitter so that the effective period
+// is picked in period*(1±jitterFactor). The lower bound is never below MinJitteredPeriod.
+// A zero jitterFactor leaves the period untouched.
+func JitterBounds(period time.Duration, jitterFactor float64) (time.Duration, float64) {
+	if jitterFactor <= 0 {
+		return period, 0
+	}
+	if jitterFactor > MaxPeriodJitterFactor {
+		jitterFactor = MaxPeriodJitterFactor
+	}
+	min := time.Duration(float64(period) * (1 - jitterFactor))
+	max := time.Duration(float64(period) * (1 + jitterFactor))
+	if min < MinJitteredPeriod {
+		min = MinJitteredPeriod
+	}
+	if max <= min {
+		return min, 0
+	}
+	return min, float64(max-min) / float64(min)
+}
+
+// JitteredPeriod returns a random period within the bounds computed by JitterBounds
+func JitteredPeriod(period time.Duration, jitterFactor float64) time.Duration {
+	base, factor := JitterBounds(period, jitterFactor)
+	if factor <= 0 {
+		// wait.Jitter would use a factor of 1.0
+		return base
+	}
+	return wait.Jitter(base, factor)
+}
+
+// JitterUntilWithContext is like wait.UntilWithContext but the period is randomly picked at each iteration in the bounds computed by JitterBounds
+func JitterUntilWithContext(ctx context.Context, f func(context.Context), period time.Duration, jitterFactor float64) {
+	base, factor := JitterBounds(period, jitterFactor)
+	wait.JitterUntilWithContext(ctx, f, base, factor, true)
+}
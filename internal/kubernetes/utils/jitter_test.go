@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitteredPeriod(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Period       time.Duration
+		JitterFactor float64
+		ExpectedMin  time.Duration
+		ExpectedMax  time.Duration
+	}{
+		{
+			Name:         "no jitter",
+			Period:       10 * time.Second,
+			JitterFactor: 0,
+			ExpectedMin:  10 * time.Second,
+			ExpectedMax:  10 * time.Second,
+		},
+		{
+			Name:         "10% jitter",
+			Period:       10 * time.Second,
+			JitterFactor: 0.1,
+			ExpectedMin:  9 * time.Second,
+			ExpectedMax:  11 * time.Second,
+		},
+		{
+			Name:         "jitter factor is capped",
+			Period:       10 * time.Second,
+			JitterFactor: 2,
+			ExpectedMin:  5 * time.Second,
+			ExpectedMax:  15 * time.Second,
+		},
+		{
+			Name:         "lower bound does not go below the floor",
+			Period:       1500 * time.Millisecond,
+			JitterFactor: 0.5,
+			ExpectedMin:  MinJitteredPeriod,
+			ExpectedMax:  2250 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			periods := map[time.Duration]struct{}{}
+			for i := 0; i < 1000; i++ {
+				period := JitteredPeriod(tt.Period, tt.JitterFactor)
+				assert.GreaterOrEqual(t, period, tt.ExpectedMin)
+				assert.LessOrEqual(t, period, tt.ExpectedMax)
+				periods[period] = struct{}{}
+			}
+			if tt.ExpectedMin == tt.ExpectedMax {
+				assert.Len(t, periods, 1)
+			} else {
+				assert.Greater(t, len(periods), 1, "the period should vary")
+			}
+		})
+	}
+}
@@ -45,18 +45,24 @@ type RuntimeObjectStore interface {
 	Pods() PodStore
 	StatefulSets() StatefulSetStore
 	Deployments() DeploymentStore
+	ReplicaSets() ReplicaSetStore
 	PersistentVolumes() PersistentVolumeStore
 	PersistentVolumeClaims() PersistentVolumeClaimStore
+	// OwnerChainMaxDepth is the maximum number of owners explored above a pod when searching for its controllers
+	OwnerChainMaxDepth() int
 }
 
 type RuntimeObjectStoreImpl struct {
 	NodesStore                 *NodeWatch
 	PodsStore                  *PodWatch
 	DeploymentStore            *DeploymentWatch
+	ReplicaSetStore            *ReplicaSetWatch
 	StatefulSetsStore          *StatefulSetWatch
 	PersistentVolumeStore      *PersistentVolumeWatch
 	PersistentVolumeClaimStore *PersistentVolumeClaimWatch
-	hasSynced                  bool
+	// MaxOwnerChainDepth bounds the owner lookups, DefaultOwnerChainMaxDepth is used if not set
+	MaxOwnerChainDepth int
+	hasSynced          bool
 }
 
 func (r *RuntimeObjectStoreImpl) Nodes() NodeStore {
@@ -75,6 +81,17 @@ func (r *RuntimeObjectStoreImpl) Deployments() DeploymentStore {
 	return r.DeploymentStore
 }
 
+func (r *RuntimeObjectStoreImpl) ReplicaSets() ReplicaSetStore {
+	return r.ReplicaSetStore
+}
+
+func (r *RuntimeObjectStoreImpl) OwnerChainMaxDepth() int {
+	if r.MaxOwnerChainDepth <= 0 {
+		return DefaultOwnerChainMaxDepth
+	}
+	return r.MaxOwnerChainDepth
+}
+
 func (r *RuntimeObjectStoreImpl) PersistentVolumes() PersistentVolumeStore {
 	return r.PersistentVolumeStore
 }
@@ -90,6 +107,7 @@ func (r *RuntimeObjectStoreImpl) HasSynced() bool {
 	r.hasSynced = r.NodesStore.HasSynced() &&
 		r.StatefulSetsStore.HasSynced() &&
 		r.DeploymentStore.HasSynced() &&
+		r.ReplicaSetStore.HasSynced() &&
 		r.PodsStore.HasSynced() &&
 		r.PersistentVolumeStore.HasSynced() &&
 		r.PersistentVolumeClaimStore.HasSynced()
@@ -159,6 +177,8 @@ type PodStore interface {
 	ListPodsByStatus(podStatus string) ([]*core.Pod, error)
 	GetPodCount() (int, error)
 	ListPodsForClaim(namespace, claimName string) ([]*core.Pod, error)
+	// List all the pods of a given namespace
+	ListPodsForNamespace(namespace string) ([]*core.Pod, error)
 }
 
 // A PodWatch is a cache of pod resources that notifies registered
@@ -182,6 +202,7 @@ func NewPodWatch(ctx context.Context, c kubernetes.Interface) *PodWatch {
 	}
 
 	i := cache.NewSharedIndexInformer(lw, &core.Pod{}, 30*time.Minute, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
 		podNodeNameIndexField: func(obj interface{}) ([]string, error) {
 			p, ok := obj.(*core.Pod)
 			if !ok {
@@ -285,6 +306,26 @@ func (w *PodWatch) ListPodsForClaim(namespace, claimName string) ([]*core.Pod, e
 	return pods, nil
 }
 
+func (w *PodWatch) ListPodsForNamespace(namespace string) ([]*core.Pod, error) {
+	if !w.HasSynced() {
+		return nil, errors.New("pod informer not yet synced")
+	}
+	objs, err := w.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*core.Pod, len(objs))
+	for i := range objs {
+		p, ok := objs[i].(*core.Pod)
+		if !ok {
+			return nil, errors.New("unexpected object type in Pod store")
+		}
+		pods[i] = p
+	}
+	sort.Sort(PodsSortedByName(pods))
+	return pods, nil
+}
+
 type PodsSortedByName []*core.Pod
 
 func (a PodsSortedByName) Len() int           { return len(a) }
@@ -388,6 +429,53 @@ func (s DeploymentWatch) Get(namespace, name string) (*v1.Deployment, error) {
 	return nil, apierrors.NewNotFound(v1.Resource("deployment"), name)
 }
 
+type ReplicaSetStore interface {
+	SyncedStore
+	// Get replicaset by name
+	Get(namespace, name string) (*v1.ReplicaSet, error)
+}
+
+// A ReplicaSetWatch is a cache of replicaset resources that notifies registered
+// handlers when its contents change.
+type ReplicaSetWatch struct {
+	cache.SharedInformer
+}
+
+var _ ReplicaSetStore = &ReplicaSetWatch{}
+
+// NewReplicaSetWatch creates a watch on replicaset resources.
+func NewReplicaSetWatch(ctx context.Context, c kubernetes.Interface) *ReplicaSetWatch {
+	lw := &cache.ListWatch{
+		ListFunc:  func(o meta.ListOptions) (runtime.Object, error) { return c.AppsV1().ReplicaSets("").List(ctx, o) },
+		WatchFunc: func(o meta.ListOptions) (watch.Interface, error) { return c.AppsV1().ReplicaSets("").Watch(ctx, o) },
+	}
+
+	i := cache.NewSharedInformer(lw, &v1.ReplicaSet{}, 30*time.Minute)
+	return &ReplicaSetWatch{i}
+}
+
+func (w *ReplicaSetWatch) Start(ctx context.Context) {
+	w.Run(ctx.Done())
+}
+
+func (s ReplicaSetWatch) Get(namespace, name string) (*v1.ReplicaSet, error) {
+	obj, exists, err := s.GetStore().GetByKey(namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apierrors.NewNotFound(v1.Resource("replicaset"), name)
+	}
+	if obj != nil {
+		rs, ok := obj.(*v1.ReplicaSet)
+		if ok {
+			return rs, nil
+		}
+		return nil, errors.New("Failed to cast object from store to ReplicaSet.")
+	}
+	return nil, apierrors.NewNotFound(v1.Resource("replicaset"), name)
+}
+
 type PersistentVolumeStore interface {
 	SyncedStore
 	// Get the PV associated with a node
@@ -504,12 +592,14 @@ func RunStoreForTest(ctx context.Context, kclient kubernetes.Interface) (store R
 	stopCh := make(chan struct{})
 	stsWatch := NewStatefulsetWatch(ctx, kclient)
 	deploymentWatch := NewDeploymentWatch(ctx, kclient)
+	replicaSetWatch := NewReplicaSetWatch(ctx, kclient)
 	podWatch := NewPodWatch(ctx, kclient)
 	nodeWatch := NewNodeWatch(ctx, kclient)
 	pvWatch := NewPersistentVolumeWatch(ctx, kclient)
 	pvcWatch := NewPersistentVolumeClaimWatch(ctx, kclient)
 
 	go deploymentWatch.Run(stopCh)
+	go replicaSetWatch.Run(stopCh)
 	go stsWatch.Run(stopCh)
 	go podWatch.Run(stopCh)
 	go nodeWatch.Run(stopCh)
@@ -518,6 +608,7 @@ func RunStoreForTest(ctx context.Context, kclient kubernetes.Interface) (store R
 
 	store = &RuntimeObjectStoreImpl{
 		DeploymentStore:            deploymentWatch,
+		ReplicaSetStore:            replicaSetWatch,
 		StatefulSetsStore:          stsWatch,
 		PodsStore:                  podWatch,
 		NodesStore:                 nodeWatch,
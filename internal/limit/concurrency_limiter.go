@@ -0,0 +1,34 @@
+package limit
+
+// ConcurrencyLimiter bounds the number of operations running at the same time
+type ConcurrencyLimiter interface {
+	// TryAcquire returns true if a slot is taken immediately. Otherwise,
+	// it returns false.
+	TryAcquire() bool
+	// Release frees a slot taken by TryAcquire.
+	Release()
+}
+
+type concurrencyLimiterImpl struct {
+	slots chan struct{}
+}
+
+// NewConcurrencyLimiter returns a limiter allowing up to max operations at the same time
+func NewConcurrencyLimiter(max int) ConcurrencyLimiter {
+	return &concurrencyLimiterImpl{
+		slots: make(chan struct{}, max),
+	}
+}
+
+func (limiter *concurrencyLimiterImpl) TryAcquire() bool {
+	select {
+	case limiter.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (limiter *concurrencyLimiterImpl) Release() {
+	<-limiter.slots
+}
@@ -0,0 +1,18 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(2)
+	assert.True(t, limiter.TryAcquire())
+	assert.True(t, limiter.TryAcquire())
+	assert.False(t, limiter.TryAcquire(), "no slot left")
+
+	limiter.Release()
+	assert.True(t, limiter.TryAcquire(), "a slot was released")
+	assert.False(t, limiter.TryAcquire(), "no slot left")
+}
@@ -0,0 +1,124 @@
+package limit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/utils/clock"
+)
+
+// ScaleFunc returns the factor, between 0 and 1, applied to the configured QPS
+type ScaleFunc func() float64
+
+// LinearTaper returns a ScaleFunc that reduces the QPS linearly as the fraction returned by fractionFunc approaches limit.
+// The QPS is not reduced for a null fraction and reaches zero when the fraction is equal or above the limit.
+func LinearTaper(fractionFunc func() float64, limit float64) ScaleFunc {
+	return func() float64 {
+		if limit <= 0 {
+			return 1
+		}
+		scale := 1 - fractionFunc()/limit
+		if scale < 0 {
+			return 0
+		}
+		if scale > 1 {
+			return 1
+		}
+		return scale
+	}
+}
+
+// taperedTypedRateLimiter is a TypedRateLimiter whose QPS is scaled by a dynamic factor. The limit is adjusted at each call,
+// without resetting the tokens already consumed.
+type taperedTypedRateLimiter struct {
+	sync.Mutex
+	clock        clock.Clock
+	defaultQPS   float32
+	defaultBurst int
+	rlConfigs    map[string]RateLimiterConfiguration
+	rateLimiters map[string]*rate.Limiter
+	scale        ScaleFunc
+}
+
+func NewTaperedTypedRateLimiter(clock clock.Clock, configurations map[string]RateLimiterConfiguration, defaultQPS float32, defaultBurst int, scale ScaleFunc) TypedRateLimiter {
+	return &taperedTypedRateLimiter{
+		clock:        clock,
+		defaultQPS:   defaultQPS,
+		defaultBurst: defaultBurst,
+		rlConfigs:    configurations,
+		rateLimiters: map[string]*rate.Limiter{},
+		scale:        scale,
+	}
+}
+
+// Wait reserves a token with the clock of the limiter, rather than with rate.Limiter.Wait that reads the real time
+func (limit *taperedTypedRateLimiter) Wait(ctx context.Context, t string) error {
+	for {
+		rateLimiter := limit.getRateLimiter(t)
+		if rateLimiter == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-limit.clock.After(time.Second):
+			}
+			continue
+		}
+		now := limit.clock.Now()
+		reservation := rateLimiter.ReserveN(now, 1)
+		if !reservation.OK() {
+			return fmt.Errorf("rate limiter of %s cannot give a token with a burst of %d", t, rateLimiter.Burst())
+		}
+		delay := reservation.DelayFrom(now)
+		if delay == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			reservation.CancelAt(limit.clock.Now())
+			return ctx.Err()
+		case <-limit.clock.After(delay):
+			return nil
+		}
+	}
+}
+
+func (limit *taperedTypedRateLimiter) TryAccept(t string) bool {
+	rateLimiter := limit.getRateLimiter(t)
+	return rateLimiter != nil && rateLimiter.AllowN(limit.clock.Now(), 1)
+}
+
+// effectiveQPS returns the configured QPS of the type t scaled by the current factor
+func (limit *taperedTypedRateLimiter) effectiveQPS(t string) float64 {
+	qps := limit.defaultQPS
+	if cfg, ok := limit.rlConfigs[t]; ok && cfg.QPS != nil {
+		qps = *cfg.QPS
+	}
+	return float64(qps) * limit.scale()
+}
+
+// getRateLimiter returns the rate limiter of the type t with its limit adjusted to the current factor.
+// It returns nil when the effective QPS is null: a rate.Limiter with a null limit would consume its burst for good.
+func (limit *taperedTypedRateLimiter) getRateLimiter(t string) *rate.Limiter {
+	limit.Lock()
+	defer limit.Unlock()
+
+	qps := rate.Limit(limit.effectiveQPS(t))
+	if qps <= 0 {
+		return nil
+	}
+	rateLimiter, exist := limit.rateLimiters[t]
+	if !exist {
+		burst := limit.defaultBurst
+		if cfg, ok := limit.rlConfigs[t]; ok && cfg.Burst != nil {
+			burst = *cfg.Burst
+		}
+		rateLimiter = rate.NewLimiter(qps, burst)
+		limit.rateLimiters[t] = rateLimiter
+	} else if rateLimiter.Limit() != qps {
+		rateLimiter.SetLimitAt(limit.clock.Now(), qps)
+	}
+	return rateLimiter
+}
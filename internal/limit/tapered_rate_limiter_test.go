@@ -0,0 +1,76 @@
+package limit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	testclock "k8s.io/utils/clock/testing"
+)
+
+func TestTaperedTypedRateLimiter_effectiveQPS(t *testing.T) {
+	tests := []struct {
+		name        string
+		fraction    float64
+		expectedQPS float64
+	}{
+		{name: "all nodes ready", fraction: 0, expectedQPS: 2},
+		{name: "quarter of the limit", fraction: 0.025, expectedQPS: 1.5},
+		{name: "half of the limit", fraction: 0.05, expectedQPS: 1},
+		{name: "limit reached", fraction: 0.1, expectedQPS: 0},
+		{name: "above the limit", fraction: 0.3, expectedQPS: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewTaperedTypedRateLimiter(testclock.NewFakeClock(time.Now()), nil, 2, 1, LinearTaper(func() float64 { return tt.fraction }, 0.1)).(*taperedTypedRateLimiter)
+			assert.InDelta(t, tt.expectedQPS, limiter.effectiveQPS("cond"), 0.0001)
+		})
+	}
+}
+
+func TestTaperedTypedRateLimiter_TryAccept(t *testing.T) {
+	fraction := 0.0
+	clock := testclock.NewFakeClock(time.Now())
+	limiter := NewTaperedTypedRateLimiter(clock, nil, 1, 1, LinearTaper(func() float64 { return fraction }, 0.1))
+
+	assert.True(t, limiter.TryAccept("cond"), "first token is available")
+	assert.False(t, limiter.TryAccept("cond"), "bucket is empty")
+
+	clock.Step(time.Second)
+	assert.True(t, limiter.TryAccept("cond"), "one token per second at full speed")
+
+	fraction = 0.05
+	assert.False(t, limiter.TryAccept("cond"), "bucket is still empty")
+	clock.Step(time.Second)
+	assert.False(t, limiter.TryAccept("cond"), "half speed, the token is not yet available")
+	clock.Step(time.Second)
+	assert.True(t, limiter.TryAccept("cond"), "one token every two seconds at half speed")
+
+	fraction = 0.1
+	clock.Step(time.Hour)
+	assert.False(t, limiter.TryAccept("cond"), "no drain once the limit is reached")
+}
+
+func TestTaperedTypedRateLimiter_Wait(t *testing.T) {
+	clock := testclock.NewFakeClock(time.Now())
+	limiter := NewTaperedTypedRateLimiter(clock, nil, 1, 1, LinearTaper(func() float64 { return 0 }, 0.1))
+	assert.True(t, limiter.TryAccept("cond"), "first token is available")
+
+	done := make(chan error)
+	go func() { done <- limiter.Wait(context.Background(), "cond") }()
+	// the waiter must wait on the clock of the limiter, not on the real time
+	assert.Eventually(t, clock.HasWaiters, time.Second, 10*time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("the token should not be available before the clock moves")
+	default:
+	}
+	clock.Step(time.Second)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("the token should be available once the clock moved")
+	}
+}
@@ -21,6 +21,21 @@ var (
 		Name:      "draino_running",
 		Help:      "Indicates that draino is running.",
 	}, []string{TagComponentName, TagDryRun})
+
+	// CandidateMetrics give the conversion ratio of the drain candidates per group and team
+	CandidateMetrics = struct {
+		Created *prometheus.CounterVec
+		Drained *prometheus.CounterVec
+	}{
+		Created: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "draino_candidates_created_total",
+			Help: "Number of nodes that became drain candidate.",
+		}, []string{TagGroupKey, TagTeam}),
+		Drained: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "draino_candidates_drained_total",
+			Help: "Number of drain candidates that were successfully drained.",
+		}, []string{TagGroupKey, TagTeam}),
+	}
 )
 
 func RegisterMetrics(registry *prometheus.Registry) {
@@ -28,6 +43,8 @@ func RegisterMetrics(registry *prometheus.Registry) {
 		// Global Subsystem
 		registry.MustRegister(drainoInternalError)
 		registry.MustRegister(drainoRunning)
+		registry.MustRegister(CandidateMetrics.Created)
+		registry.MustRegister(CandidateMetrics.Drained)
 	})
 }
 
@@ -38,3 +55,11 @@ func IncInternalError(component, reason, group, node string) {
 func DrainoRunning(component string, dryrun bool) {
 	drainoRunning.WithLabelValues(component, strconv.FormatBool(dryrun)).Set(1)
 }
+
+func IncCandidatesCreated(group, team string) {
+	CandidateMetrics.Created.WithLabelValues(group, team).Inc()
+}
+
+func IncCandidatesDrained(group, team string) {
+	CandidateMetrics.Drained.WithLabelValues(group, team).Inc()
+}
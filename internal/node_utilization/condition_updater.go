@@ -0,0 +1,172 @@
+package node_utilization
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+)
+
+const (
+	// ConditionTypeHighCPUUtilization is set to True on nodes whose CPU usage is above the threshold
+	ConditionTypeHighCPUUtilization corev1.NodeConditionType = "DrainoHighCPUUtilization"
+	// ConditionTypeHighMemoryUtilization is set to True on nodes whose memory usage is above the threshold
+	ConditionTypeHighMemoryUtilization corev1.NodeConditionType = "DrainoHighMemoryUtilization"
+
+	DefaultCheckPeriod = time.Minute
+
+	conditionReason = "NodeUtilization"
+)
+
+// Thresholds are percentages of the node allocatable resources, a zero value disables the check of the resource
+type Thresholds struct {
+	CPUPercent    float64
+	MemoryPercent float64
+}
+
+// IsEnabled returns true if at least one of the thresholds is set
+func (t Thresholds) IsEnabled() bool {
+	return t.CPUPercent > 0 || t.MemoryPercent > 0
+}
+
+// Conditions returns the definitions of the conditions, in the format of the --node-conditions flag, that make the nodes
+// above a threshold for at least minDuration eligible for drain
+func (t Thresholds) Conditions(minDuration time.Duration) []string {
+	var conditions []string
+	if t.CPUPercent > 0 {
+		conditions = append(conditions, fmt.Sprintf(`%s={"delay":"%s"}`, ConditionTypeHighCPUUtilization, minDuration))
+	}
+	if t.MemoryPercent > 0 {
+		conditions = append(conditions, fmt.Sprintf(`%s={"delay":"%s"}`, ConditionTypeHighMemoryUtilization, minDuration))
+	}
+	return conditions
+}
+
+// ConditionUpdater periodically reads the nodes usage and reflects it in node conditions.
+// The condition transition time is used to know for how long the node has been above the threshold.
+type ConditionUpdater struct {
+	client        client.Client
+	metricsClient NodeMetricsClient
+	logger        logr.Logger
+	clock         clock.Clock
+	period        time.Duration
+	thresholds    Thresholds
+}
+
+var _ manager.Runnable = &ConditionUpdater{}
+
+func NewConditionUpdater(client client.Client, metricsClient NodeMetricsClient, logger logr.Logger, clock clock.Clock, period time.Duration, thresholds Thresholds) *ConditionUpdater {
+	return &ConditionUpdater{
+		client:        client,
+		metricsClient: metricsClient,
+		logger:        logger.WithName("NodeUtilization"),
+		clock:         clock,
+		period:        period,
+		thresholds:    thresholds,
+	}
+}
+
+// Start runs the updater till the context is Done, blocking call
+func (u *ConditionUpdater) Start(ctx context.Context) error {
+	u.logger.Info("starting", "cpu_threshold", u.thresholds.CPUPercent, "memory_threshold", u.thresholds.MemoryPercent)
+	wait.UntilWithContext(ctx, u.updateConditions, u.period)
+	return nil
+}
+
+func (u *ConditionUpdater) updateConditions(ctx context.Context) {
+	nodeMetrics, err := u.metricsClient.ListNodeMetrics(ctx)
+	if err != nil {
+		u.logger.Error(err, "cannot list node metrics")
+		return
+	}
+
+	for _, m := range nodeMetrics {
+		var node corev1.Node
+		if err := u.client.Get(ctx, types.NamespacedName{Name: m.Name}, &node); err != nil {
+			if client.IgnoreNotFound(err) != nil {
+				u.logger.Error(err, "cannot get node", "node", m.Name)
+			}
+			continue
+		}
+
+		checks := []struct {
+			conditionType corev1.NodeConditionType
+			resource      corev1.ResourceName
+			threshold     float64
+		}{
+			{ConditionTypeHighCPUUtilization, corev1.ResourceCPU, u.thresholds.CPUPercent},
+			{ConditionTypeHighMemoryUtilization, corev1.ResourceMemory, u.thresholds.MemoryPercent},
+		}
+		for _, check := range checks {
+			if check.threshold <= 0 {
+				continue
+			}
+			utilization, ok := GetUtilizationPercent(&node, m.Usage, check.resource)
+			if !ok {
+				continue
+			}
+			if err := u.setCondition(ctx, &node, check.conditionType, utilization > check.threshold, fmt.Sprintf("%s utilization %.1f%%, threshold %.1f%%", check.resource, utilization, check.threshold)); err != nil {
+				u.logger.Error(err, "cannot update utilization condition", "node", node.Name, "condition", check.conditionType)
+			}
+		}
+	}
+}
+
+// setCondition patches the condition only if its status changes, so that the transition time tells since when the node is above the threshold
+func (u *ConditionUpdater) setCondition(ctx context.Context, node *corev1.Node, conditionType corev1.NodeConditionType, above bool, message string) error {
+	status := corev1.ConditionFalse
+	if above {
+		status = corev1.ConditionTrue
+	}
+
+	pos, condition, found := utils.FindNodeCondition(conditionType, node)
+	if !found && !above {
+		// no need to create the condition on all the nodes that are below the threshold
+		return nil
+	}
+	if found && condition.Status == status {
+		return nil
+	}
+
+	now := metav1.NewTime(u.clock.Now())
+	newNode := node.DeepCopy()
+	newCondition := corev1.NodeCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+		Reason:             conditionReason,
+		Message:            message,
+	}
+	if found {
+		newNode.Status.Conditions[pos] = newCondition
+	} else {
+		newNode.Status.Conditions = append(newNode.Status.Conditions, newCondition)
+	}
+	u.logger.Info("utilization condition changed", "node", node.Name, "condition", conditionType, "status", status, "message", message)
+	return u.client.Status().Patch(ctx, newNode, &k8sclient.NodeConditionPatch{ConditionType: conditionType})
+}
+
+// GetUtilizationPercent returns the usage of the resource as a percentage of the node allocatable
+func GetUtilizationPercent(node *corev1.Node, usage corev1.ResourceList, resource corev1.ResourceName) (float64, bool) {
+	allocatable, ok := node.Status.Allocatable[resource]
+	if !ok || allocatable.IsZero() {
+		return 0, false
+	}
+	used, ok := usage[resource]
+	if !ok {
+		return 0, false
+	}
+	return float64(used.MilliValue()) / float64(allocatable.MilliValue()) * 100, true
+}
@@ -0,0 +1,111 @@
+package node_utilization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	testclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+)
+
+type fakeMetricsClient struct {
+	metrics []metricsv1beta1.NodeMetrics
+}
+
+func (f *fakeMetricsClient) ListNodeMetrics(context.Context) ([]metricsv1beta1.NodeMetrics, error) {
+	return f.metrics, nil
+}
+
+func TestConditionUpdater(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	thresholds := Thresholds{CPUPercent: 80, MemoryPercent: 90}
+
+	tests := []struct {
+		name              string
+		conditions        []corev1.NodeCondition
+		cpuUsage          string
+		memoryUsage       string
+		expectedCPU       corev1.ConditionStatus // empty if the condition should not exist
+		expectedMemory    corev1.ConditionStatus
+		expectedOffending []string
+	}{
+		{
+			name:        "below thresholds",
+			cpuUsage:    "1",
+			memoryUsage: "1Gi",
+		},
+		{
+			name:              "cpu above threshold",
+			cpuUsage:          "3500m",
+			memoryUsage:       "1Gi",
+			expectedCPU:       corev1.ConditionTrue,
+			expectedOffending: []string{string(ConditionTypeHighCPUUtilization)},
+		},
+		{
+			name:              "cpu and memory above thresholds",
+			cpuUsage:          "4",
+			memoryUsage:       "7900Mi",
+			expectedCPU:       corev1.ConditionTrue,
+			expectedMemory:    corev1.ConditionTrue,
+			expectedOffending: []string{string(ConditionTypeHighCPUUtilization), string(ConditionTypeHighMemoryUtilization)},
+		},
+		{
+			name:        "cpu back below threshold",
+			conditions:  []corev1.NodeCondition{{Type: ConditionTypeHighCPUUtilization, Status: corev1.ConditionTrue}},
+			cpuUsage:    "1",
+			memoryUsage: "1Gi",
+			expectedCPU: corev1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Status: corev1.NodeStatus{
+					Conditions: tt.conditions,
+					Allocatable: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("4"),
+						corev1.ResourceMemory: resource.MustParse("8Gi"),
+					},
+				},
+			}
+			metricsClient := &fakeMetricsClient{metrics: []metricsv1beta1.NodeMetrics{{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+				Usage: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(tt.cpuUsage),
+					corev1.ResourceMemory: resource.MustParse(tt.memoryUsage),
+				},
+			}}}
+			kclient := fake.NewFakeClient(node)
+			updater := NewConditionUpdater(kclient, metricsClient, logr.Discard(), testclock.NewFakeClock(now), DefaultCheckPeriod, thresholds)
+
+			updater.updateConditions(context.Background())
+
+			var updated corev1.Node
+			assert.NoError(t, kclient.Get(context.Background(), types.NamespacedName{Name: node.Name}, &updated))
+			for conditionType, expected := range map[corev1.NodeConditionType]corev1.ConditionStatus{
+				ConditionTypeHighCPUUtilization:    tt.expectedCPU,
+				ConditionTypeHighMemoryUtilization: tt.expectedMemory,
+			} {
+				_, condition, found := utils.FindNodeCondition(conditionType, &updated)
+				assert.Equal(t, expected != "", found, "condition %s", conditionType)
+				assert.Equal(t, expected, condition.Status, "condition %s", conditionType)
+			}
+
+			suppliedConditions, err := kubernetes.ParseConditions(thresholds.Conditions(0))
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expectedOffending, kubernetes.GetConditionIDs(kubernetes.GetNodeOffendingConditions(&updated, suppliedConditions)))
+		})
+	}
+}
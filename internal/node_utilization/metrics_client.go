@@ -0,0 +1,38 @@
+package node_utilization
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// NodeMetricsClient reads the resources usage of the nodes
+type NodeMetricsClient interface {
+	ListNodeMetrics(ctx context.Context) ([]metricsv1beta1.NodeMetrics, error)
+}
+
+// metricsServerClient reads the nodes usage from the metrics-server API
+type metricsServerClient struct {
+	clientset metricsclient.Interface
+}
+
+var _ NodeMetricsClient = &metricsServerClient{}
+
+func NewMetricsServerClient(config *rest.Config) (NodeMetricsClient, error) {
+	clientset, err := metricsclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsServerClient{clientset: clientset}, nil
+}
+
+func (m *metricsServerClient) ListNodeMetrics(ctx context.Context) ([]metricsv1beta1.NodeMetrics, error) {
+	list, err := m.clientset.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
@@ -12,10 +12,12 @@ import (
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
 	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
 	client "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/client-go/util/workqueue"
@@ -30,26 +32,57 @@ import (
 	"github.com/planetlabs/draino/internal/kubernetes/drain"
 	"github.com/planetlabs/draino/internal/kubernetes/index"
 	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
 	"github.com/planetlabs/draino/internal/metrics"
 )
 
 const (
-	ConfigurationLabelKey    = "node-lifecycle.datadoghq.com/draino-configuration"
-	OverdueLabelKey          = "node-lifecycle.datadoghq.com/overdue"
-	OutOfScopeLabelValue     = "out-of-scope"
-	nodeOptionsMetricName    = "node_options_nodes_total"
-	nodeOptionsCPUMetricName = "node_options_cpu_total"
+	ConfigurationLabelKey = "node-lifecycle.datadoghq.com/draino-configuration"
+	OverdueLabelKey       = "node-lifecycle.datadoghq.com/overdue"
+	OutOfScopeLabelValue  = "out-of-scope"
+	// ScopeExitReasonAnnotationKey holds the reason why the node left the scope of the configuration, it is removed once the node is back in scope
+	ScopeExitReasonAnnotationKey = "draino/scope_exit_reason"
+	// ScopeEntryTimeAnnotationKeyPrefix followed by the configuration name holds the time at which the node entered the scope of the configuration, it is removed once the node leaves the scope
+	ScopeEntryTimeAnnotationKeyPrefix = "draino/scope_entry_time."
+	// ScopeObserverFieldManager is the field manager owning the node labels when they are written with server-side apply
+	ScopeObserverFieldManager = "draino-scope-observer"
+	nodeOptionsMetricName     = "node_options_nodes_total"
+	nodeOptionsCPUMetricName  = "node_options_cpu_total"
+
+	// NodeUpdateRequeuePolicyDrop forgets the node once the max requeues is reached, the next analysis queues it again
+	NodeUpdateRequeuePolicyDrop = "drop"
+	// NodeUpdateRequeuePolicyReset resets the backoff of the node once the max requeues is reached and keeps retrying
+	NodeUpdateRequeuePolicyReset = "reset"
 )
 
+// NodeUpdateQueueConfig controls the retries of the node label updates
+type NodeUpdateQueueConfig struct {
+	// MaxRequeues is the number of failed attempts of a node update after which the RequeuePolicy applies
+	MaxRequeues int
+	// MaxBackoff caps the exponential backoff between two retries of the same node
+	MaxBackoff time.Duration
+	// RequeuePolicy is either NodeUpdateRequeuePolicyDrop or NodeUpdateRequeuePolicyReset
+	RequeuePolicy string
+}
+
+// DefaultNodeUpdateQueueConfig is the historical behavior of the scope observer
+var DefaultNodeUpdateQueueConfig = NodeUpdateQueueConfig{
+	MaxRequeues:   10,
+	MaxBackoff:    20 * time.Second,
+	RequeuePolicy: NodeUpdateRequeuePolicyDrop,
+}
+
 type DrainoConfigurationObserver interface {
 	manager.Runnable
 	IsInScope(node *v1.Node) (bool, string, error)
 	Reset()
+	TriggerAnalysis() bool
 }
 
 var (
 	MeasureNodeLabelPatchRateLimited = stats.Int64("draino/node_patch_ratelimited", "Number of rate limited patch label on nodes", stats.UnitDimensionless)
 	MeasureNodeLabelPatchFailed      = stats.Int64("draino/node_patch_failed", "Number of failure while patching label on nodes", stats.UnitDimensionless)
+	MeasureNodeLabelUpdateAbandoned  = stats.Int64("draino/node_update_abandoned", "Number of node label updates abandoned after the max requeues", stats.UnitDimensionless)
 )
 
 // metricsObjectsForObserver groups all the object required to serve the metrics
@@ -62,6 +95,7 @@ type metricsObjectsForObserver struct {
 
 	nodePatchRatelimitView *view.View
 	nodePatchFailureView   *view.View
+	nodeUpdateAbandonView  *view.View
 }
 
 // initializeQueueMetrics initialize the metrics that are used to count internal retries and rateLimit
@@ -86,6 +120,16 @@ func (g *metricsObjectsForObserver) initializeQueueMetrics() {
 		}
 		view.Register(g.nodePatchFailureView)
 	}
+	if g.nodeUpdateAbandonView == nil {
+		g.nodeUpdateAbandonView = &view.View{
+			Name:        "nodes_label_update_abandoned_total",
+			Measure:     MeasureNodeLabelUpdateAbandoned,
+			Description: "Number of node label updates abandoned after the max requeues.",
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{},
+		}
+		view.Register(g.nodeUpdateAbandonView)
+	}
 }
 
 // reset: replace existing gauges to eliminate obsolete series
@@ -155,11 +199,14 @@ type DrainoConfigurationObserverImpl struct {
 	podIndexer         index.PodIndexer
 	runtimeObjectStore kubernetes.RuntimeObjectStore
 	analysisPeriod     time.Duration
+	// periodJitterFactor: the analysis period is randomly picked in analysisPeriod*(1±periodJitterFactor)
+	periodJitterFactor float64
 
 	// queueNodeToBeUpdated: To avoid burst in node updates the work to be done is queued. This way we can pace the node updates.
 	// The consequence is that the metric is not 100% accurate when the controller starts. It converges after couple ou cycles.
 	queueNodeToBeUpdated workqueue.RateLimitingInterface
 	nodePatchLimiter     flowcontrol.RateLimiter // client side protection for APIServer
+	nodeUpdateQueue      NodeUpdateQueueConfig
 
 	globalConfig        kubernetes.GlobalConfig
 	filtersDefinitions  kubernetes.FiltersDefinitions
@@ -172,12 +219,25 @@ type DrainoConfigurationObserverImpl struct {
 
 	candidateFilter filters.Filter
 
+	// scopeSnapshotWriter: if set, a summary of the scope is written at each analysis
+	scopeSnapshotWriter ScopeSnapshotWriter
+
+	// dryRun: the label changes are only logged, they are not applied on the nodes
+	dryRun bool
+	// serverSideApply: all the label changes of a node are written in a single server-side apply request
+	serverSideApply bool
+
+	// analysisTrigger holds at most one pending on demand analysis
+	analysisTrigger chan struct{}
+	// informerSyncTimeout bounds the waits for the informers sync, zero waits forever
+	informerSyncTimeout time.Duration
+
 	metricsObjects metricsObjectsForObserver
 }
 
 var _ DrainoConfigurationObserver = &DrainoConfigurationObserverImpl{}
 
-func NewScopeObserver(client client.Interface, globalConfig kubernetes.GlobalConfig, podIndexer index.PodIndexer, runtimeObjectStore kubernetes.RuntimeObjectStore, analysisPeriod time.Duration, filterDef kubernetes.FiltersDefinitions, userOptInPodFilter, userOptOutPodFilter kubernetes.PodFilterFunc, log *zap.Logger, retryWall drain.RetryWall, groupKeyGetter groups.GroupKeyGetter, runnerInfoGetter groups.RunnerInfoGetter, candidateFilter filters.Filter) DrainoConfigurationObserver {
+func NewScopeObserver(client client.Interface, globalConfig kubernetes.GlobalConfig, podIndexer index.PodIndexer, runtimeObjectStore kubernetes.RuntimeObjectStore, analysisPeriod time.Duration, periodJitterFactor float64, filterDef kubernetes.FiltersDefinitions, userOptInPodFilter, userOptOutPodFilter kubernetes.PodFilterFunc, log *zap.Logger, retryWall drain.RetryWall, groupKeyGetter groups.GroupKeyGetter, runnerInfoGetter groups.RunnerInfoGetter, candidateFilter filters.Filter, scopeSnapshotWriter ScopeSnapshotWriter, dryRun bool, serverSideApply bool, nodeUpdateQueue NodeUpdateQueueConfig, informerSyncTimeout time.Duration) DrainoConfigurationObserver {
 
 	// We are not adding a BucketRateLimiter to that list because the same nodes are going to be appended periodically if the update fails
 	// Failing nodes will already be in the queue with a retry. Added a BucketRL proved to be a problem here is the client side is not able to dequeue
@@ -187,7 +247,7 @@ func NewScopeObserver(client client.Interface, globalConfig kubernetes.GlobalCon
 	// For this reason I have preferred to move the bucket limiter on the other side of the queue, to have a kind of client-side protection on the API-Server.
 	// See field nodePatchLimiter
 	rateLimiters := []workqueue.RateLimiter{
-		workqueue.NewItemExponentialFailureRateLimiter(500*time.Millisecond, 20*time.Second),
+		workqueue.NewItemExponentialFailureRateLimiter(500*time.Millisecond, nodeUpdateQueue.MaxBackoff),
 	}
 
 	scopeObserver := &DrainoConfigurationObserverImpl{
@@ -198,14 +258,21 @@ func NewScopeObserver(client client.Interface, globalConfig kubernetes.GlobalCon
 		userOptOutPodFilter:  userOptOutPodFilter,
 		userOptInPodFilter:   userOptInPodFilter,
 		analysisPeriod:       analysisPeriod,
+		periodJitterFactor:   periodJitterFactor,
 		logger:               log,
 		globalConfig:         globalConfig,
 		queueNodeToBeUpdated: workqueue.NewNamedRateLimitingQueue(workqueue.NewMaxOfRateLimiter(rateLimiters...), "nodeUpdater"),
 		nodePatchLimiter:     flowcontrol.NewTokenBucketRateLimiter(50, 10), // client side protection
+		nodeUpdateQueue:      nodeUpdateQueue,
 		retryWall:            retryWall,
 		groupKeyGetter:       groupKeyGetter,
 		runnerInfoGetter:     runnerInfoGetter,
 		candidateFilter:      candidateFilter,
+		scopeSnapshotWriter:  scopeSnapshotWriter,
+		dryRun:               dryRun,
+		serverSideApply:      serverSideApply,
+		analysisTrigger:      make(chan struct{}, 1),
+		informerSyncTimeout:  informerSyncTimeout,
 	}
 	scopeObserver.metricsObjects.initializeQueueMetrics()
 
@@ -244,135 +311,160 @@ type inScopeMetrics map[inScopeTags]int64
 type inScopeCPUMetrics map[inScopeCPUTags]int64
 
 func (s *DrainoConfigurationObserverImpl) Start(ctx context.Context) error {
+	// Wait for the informer to sync before starting
+	if err := kubernetes.WaitForSync(ctx, s.runtimeObjectStore.Pods().HasSynced, 10*time.Second, s.informerSyncTimeout); err != nil {
+		s.logger.Error("Pod informer not synced, scope observer not started", zap.Error(err))
+		return err
+	}
 	s.Run(ctx.Done())
 	return nil
 }
 
 func (s *DrainoConfigurationObserverImpl) Run(stop <-chan struct{}) {
-	ticker := time.NewTicker(s.analysisPeriod)
-	// Wait for the informer to sync before starting
-	wait.PollImmediateInfinite(10*time.Second, func() (done bool, err error) {
-		return s.runtimeObjectStore.Pods().HasSynced(), nil
-	})
-
+	timer := time.NewTimer(utils.JitteredPeriod(s.analysisPeriod, s.periodJitterFactor))
 	go s.processQueueForNodeUpdates()
-	defer ticker.Stop()
+	defer timer.Stop()
 	for {
 		select {
 		case <-stop:
 			s.queueNodeToBeUpdated.ShutDown()
 			return
-		case <-ticker.C:
-			// Let's print the queue size
-			s.logger.Info("queueNodeToBeUpdated", zap.Int("len", s.queueNodeToBeUpdated.Len()))
+		case <-timer.C:
+			timer.Reset(utils.JitteredPeriod(s.analysisPeriod, s.periodJitterFactor))
+			s.analyze()
+		case <-s.analysisTrigger:
+			s.logger.Info("Running the scope analysis on demand")
+			s.analyze()
+		}
+	}
+}
 
-			s.ProduceGroupRunnerMetrics()
+// TriggerAnalysis requests an analysis pass without waiting for the next period. The requests received while a pass is
+// already pending are merged with it, so the function returns false in that case.
+func (s *DrainoConfigurationObserverImpl) TriggerAnalysis() bool {
+	select {
+	case s.analysisTrigger <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
 
-			// Let's update the nodes metadata
-			nodeCfgLabelBeingUpdated := map[string]struct{}{}
-			for _, node := range s.runtimeObjectStore.Nodes().ListNodes() {
-				_, cfgLabelOutOfDate, err := s.getConfigLabelUpdate(node)
-				if err != nil {
-					s.logger.Error("Failed to check if config label was out of date", zap.Error(err), zap.String("node", node.Name))
-				}
-				if cfgLabelOutOfDate {
-					nodeCfgLabelBeingUpdated[node.Name] = struct{}{}
-				}
-				addOverdueLabel, removeOverdueLabel := s.getOverdueLabelUpdate(node)
-				if cfgLabelOutOfDate || addOverdueLabel || removeOverdueLabel {
-					s.addNodeToQueue(node)
-				}
-			}
+// analyze produces the scope metrics and queues the nodes whose labels must converge. It is only called by the Run loop, so passes never overlap.
+func (s *DrainoConfigurationObserverImpl) analyze() {
+	// Let's print the queue size
+	s.logger.Info("queueNodeToBeUpdated", zap.Int("len", s.queueNodeToBeUpdated.Len()))
 
-			newMetricsFilterValue := filteredNodeMetrics{}
-			newMetricsValue := inScopeMetrics{}
-			newMetricsCPUValue := inScopeCPUMetrics{}
-			// Let's update the metrics
-			for _, node := range s.runtimeObjectStore.Nodes().ListNodes() {
-				// skip the node if it is too recent... it does not have all the required labels/annotations yet to have relevant metrics
-				if _, found := nodeCfgLabelBeingUpdated[node.Name]; found {
-					continue
-				}
-				if IsNewNodeMissingLabel(node, 2*time.Minute) { // to cover cases where we have delay in the queue
-					continue
-				}
+	s.ProduceGroupRunnerMetrics()
+
+	// Let's update the nodes metadata
+	nodeCfgLabelBeingUpdated := map[string]struct{}{}
+	for _, node := range s.runtimeObjectStore.Nodes().ListNodes() {
+		_, cfgLabelOutOfDate, _, err := s.getConfigLabelUpdate(node)
+		if err != nil {
+			s.logger.Error("Failed to check if config label was out of date", zap.Error(err), zap.String("node", node.Name))
+		}
+		if cfgLabelOutOfDate {
+			nodeCfgLabelBeingUpdated[node.Name] = struct{}{}
+		}
+		addOverdueLabel, removeOverdueLabel := s.getOverdueLabelUpdate(node)
+		if cfgLabelOutOfDate || addOverdueLabel || removeOverdueLabel {
+			s.addNodeToQueue(node)
+		}
+	}
 
-				s.ProduceNodeMetrics(node)
-				group := s.groupKeyGetter.GetGroupKey(node) // TODO once we have cleanup legacy code, check how to integrate 'group' directly in GetNodeTagsValues
-				nodeTags := kubernetes.GetNodeTagsValues(node)
-				conditions := kubernetes.GetNodeOffendingConditions(node, s.globalConfig.SuppliedConditions)
+	newMetricsFilterValue := filteredNodeMetrics{}
+	newMetricsValue := inScopeMetrics{}
+	newMetricsCPUValue := inScopeCPUMetrics{}
+	// Let's update the metrics
+	for _, node := range s.runtimeObjectStore.Nodes().ListNodes() {
+		// skip the node if it is too recent... it does not have all the required labels/annotations yet to have relevant metrics
+		// In dry-run the labels are never updated, so there is no point waiting for the update to be done.
+		if _, found := nodeCfgLabelBeingUpdated[node.Name]; found && !s.dryRun {
+			continue
+		}
+		if IsNewNodeMissingLabel(node, 2*time.Minute) { // to cover cases where we have delay in the queue
+			continue
+		}
 
-				if node.Annotations == nil {
-					node.Annotations = map[string]string{}
-				}
+		s.ProduceNodeMetrics(node)
+		group := s.groupKeyGetter.GetGroupKey(node) // TODO once we have cleanup legacy code, check how to integrate 'group' directly in GetNodeTagsValues
+		nodeTags := kubernetes.GetNodeTagsValues(node, s.globalConfig.TeamLabelKey)
+		conditions := kubernetes.GetNodeOffendingConditions(node, s.globalConfig.SuppliedConditions)
 
-				if nodeTags.Service == "" {
-					nodeTags.Service = s.getServiceTagForNode(node)
-				}
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
 
-				_, useDefaultRetryMaxAttempt, _ := kubernetes.GetNodeRetryMaxAttempt(node)
-
-				t := inScopeTags{
-					NodeTagsValues:                  nodeTags,
-					DrainStatus:                     getDrainStatusStr(node),
-					InScope:                         NodeInScopeWithConditionCheck(conditions, node),
-					PreprovisioningEnabled:          node.Annotations[pre_processor.PreprovisioningAnnotationKey] == pre_processor.PreprovisioningAnnotationValue,
-					PVCManagementEnabled:            s.HasPodWithPVCManagementEnabled(node),
-					DrainRetryCustomMaxAttempts:     !useDefaultRetryMaxAttempt,
-					UserOptOutViaPodAnnotation:      s.HasPodWithUserOptOutAnnotation(node),
-					UserOptInViaPodAnnotation:       s.HasPodWithUserOptInAnnotation(node),
-					UserAllowedConditionsAnnotation: kubernetes.HasAllowConditionList(node),
-					TagUserEvictionURLViaAnnotation: s.HasEvictionUrlViaAnnotation(node),
-				}
+		if nodeTags.Service == "" {
+			nodeTags.Service = s.getServiceTagForNode(node)
+		}
 
-				tCPU := inScopeCPUTags{
-					NodeTagsValues: nodeTags,
-					InScope:        NodeInScopeWithConditionCheck(conditions, node),
-				}
+		_, useDefaultRetryMaxAttempt, _ := kubernetes.GetNodeRetryMaxAttempt(node)
+
+		t := inScopeTags{
+			NodeTagsValues:                  nodeTags,
+			DrainStatus:                     getDrainStatusStr(node),
+			InScope:                         NodeInScopeWithConditionCheck(conditions, node),
+			PreprovisioningEnabled:          node.Annotations[pre_processor.PreprovisioningAnnotationKey] == pre_processor.PreprovisioningAnnotationValue,
+			PVCManagementEnabled:            s.HasPodWithPVCManagementEnabled(node),
+			DrainRetryCustomMaxAttempts:     !useDefaultRetryMaxAttempt,
+			UserOptOutViaPodAnnotation:      s.HasPodWithUserOptOutAnnotation(node),
+			UserOptInViaPodAnnotation:       s.HasPodWithUserOptInAnnotation(node),
+			UserAllowedConditionsAnnotation: kubernetes.HasAllowConditionList(node),
+			TagUserEvictionURLViaAnnotation: s.HasEvictionUrlViaAnnotation(node),
+		}
 
-				overdue := map[string]bool{}
-				for _, c := range conditions {
-					isOverdue := kubernetes.IsOverdue(node, c)
-					overdue[string(c.Type)] = isOverdue
-					// If one of the conditions is overdue, we want to count the node as overdue in the "any" condition as well.
-					// With this we are able to get a count of all unique nodes that have an overdue condition.
-					if isOverdue {
-						overdue["any"] = isOverdue
-					}
-				}
+		tCPU := inScopeCPUTags{
+			NodeTagsValues: nodeTags,
+			InScope:        NodeInScopeWithConditionCheck(conditions, node),
+		}
 
-				// adding a virtual condition 'any' to be able to count the nodes whatever the condition(s) or absence of condition.
-				conditionsWithAll := append(kubernetes.GetConditionIDs(conditions), metrics.TagConditionAnyValue)
-				for _, c := range conditionsWithAll {
-					t.Condition = c
-					t.Overdue = overdue[c]
-					newMetricsValue[t] = newMetricsValue[t] + 1
+		overdue := map[string]bool{}
+		for _, c := range conditions {
+			isOverdue := kubernetes.IsOverdue(node, c)
+			overdue[string(c.Type)] = isOverdue
+			// If one of the conditions is overdue, we want to count the node as overdue in the "any" condition as well.
+			// With this we are able to get a count of all unique nodes that have an overdue condition.
+			if isOverdue {
+				overdue["any"] = isOverdue
+			}
+		}
 
-					tCPU.Condition = c
-					newMetricsCPUValue[tCPU] = newMetricsCPUValue[tCPU] + node.Status.Capacity.Cpu().Value()
-				}
+		// adding a virtual condition 'any' to be able to count the nodes whatever the condition(s) or absence of condition.
+		conditionsWithAll := append(kubernetes.GetConditionIDs(conditions), metrics.TagConditionAnyValue)
+		for _, c := range conditionsWithAll {
+			t.Condition = c
+			t.Overdue = overdue[c]
+			newMetricsValue[t] = newMetricsValue[t] + 1
 
-				//filter tags
-				filterTags := filteredNodeTags{
-					NodeTagsValues: nodeTags,
-					group:          string(group),
-				}
-				filterOutputs := s.candidateFilter.FilterNode(context.Background(), node)
-				if !filterOutputs.Keep {
-					for _, check := range filterOutputs.Checks {
-						if check.Keep {
-							continue
-						}
-						ftags := filterTags
-						ftags.filter = check.FilterName
-						newMetricsFilterValue[ftags] = newMetricsFilterValue[ftags] + 1
-					}
-				}
+			tCPU.Condition = c
+			newMetricsCPUValue[tCPU] = newMetricsCPUValue[tCPU] + node.Status.Capacity.Cpu().Value()
+		}
 
+		//filter tags
+		filterTags := filteredNodeTags{
+			NodeTagsValues: nodeTags,
+			group:          string(group),
+		}
+		filterOutputs := s.candidateFilter.FilterNode(context.Background(), node)
+		if !filterOutputs.Keep {
+			for _, check := range filterOutputs.Checks {
+				if check.Keep {
+					continue
+				}
+				ftags := filterTags
+				ftags.filter = check.FilterName
+				newMetricsFilterValue[ftags] = newMetricsFilterValue[ftags] + 1
 			}
-			s.updateGauges(newMetricsValue, newMetricsCPUValue)
-			s.updateAutoCleanupGauges(newMetricsFilterValue)
 		}
+
+	}
+	s.updateGauges(newMetricsValue, newMetricsCPUValue)
+	s.updateAutoCleanupGauges(newMetricsFilterValue)
+
+	if s.scopeSnapshotWriter != nil {
+		s.persistScopeSnapshot(context.Background(), s.runtimeObjectStore.Nodes().ListNodes())
 	}
 }
 
@@ -449,7 +541,7 @@ func (s *DrainoConfigurationObserverImpl) ProduceGroupRunnerMetrics() {
 }
 
 func (s *DrainoConfigurationObserverImpl) ProduceNodeMetrics(node *v1.Node) {
-	tags := kubernetes.GetNodeTagsValues(node)
+	tags := kubernetes.GetNodeTagsValues(node, s.globalConfig.TeamLabelKey)
 	nodeLabelValues := []string{node.Name, string(s.groupKeyGetter.GetGroupKey(node)), tags.NgName, tags.NgNamespace, tags.Team}
 	if retries := s.retryWall.GetDrainRetryAttemptsCount(node); retries != 0 {
 		nodeRetries.WithLabelValues(nodeLabelValues...).Set(float64(retries))
@@ -529,12 +621,17 @@ func (s *DrainoConfigurationObserverImpl) addNodeToQueue(node *v1.Node) {
 }
 
 // getConfigLabelUpdate returns the label value the node should have and whether or not the label
-// value is currently out of date (not equal to first return value)
-func (s *DrainoConfigurationObserverImpl) getConfigLabelUpdate(node *v1.Node) (string, bool, error) {
+// value is currently out of date (not equal to first return value). If the node is leaving the scope
+// of the configuration, the reason is returned as well.
+func (s *DrainoConfigurationObserverImpl) getConfigLabelUpdate(node *v1.Node) (string, bool, string, error) {
 	valueOriginal := node.Labels[ConfigurationLabelKey]
 	configsOriginal := strings.Split(valueOriginal, ".")
 	var configs []string
+	wasInScope := false
 	for _, config := range configsOriginal {
+		if config == s.globalConfig.ConfigName {
+			wasInScope = true
+		}
 		if config == "" || config == OutOfScopeLabelValue || config == s.globalConfig.ConfigName {
 			continue
 		}
@@ -542,7 +639,7 @@ func (s *DrainoConfigurationObserverImpl) getConfigLabelUpdate(node *v1.Node) (s
 	}
 	inScope, reason, err := s.IsInScope(node)
 	if err != nil {
-		return "", false, err
+		return "", false, "", err
 	}
 	kubernetes.LogForVerboseNode(s.logger, node, "InScope information", zap.Bool("inScope", inScope), zap.String("reason", reason))
 
@@ -558,8 +655,15 @@ func (s *DrainoConfigurationObserverImpl) getConfigLabelUpdate(node *v1.Node) (s
 			zap.Bool("inScopeNext", inScopeNext))
 	}
 
+	var exitReason string
 	if inScope {
 		configs = append(configs, s.globalConfig.ConfigName)
+	} else if wasInScope {
+		exitReason = reason
+		if exitReason == "" {
+			// the pod filters do not always give a reason
+			exitReason = "podFilter"
+		}
 	}
 	if len(configs) == 0 {
 		// add out of scope value for user visibility
@@ -567,7 +671,7 @@ func (s *DrainoConfigurationObserverImpl) getConfigLabelUpdate(node *v1.Node) (s
 	}
 	sort.Strings(configs)
 	valueDesired := strings.Join(configs, ".")
-	return valueDesired, valueDesired != valueOriginal, nil
+	return valueDesired, valueDesired != valueOriginal, exitReason, nil
 }
 
 func (s *DrainoConfigurationObserverImpl) getOverdueLabelUpdate(node *v1.Node) (addLabel bool, removeLabel bool) {
@@ -627,6 +731,14 @@ func (s *DrainoConfigurationObserverImpl) IsInScope(node *v1.Node) (inScope bool
 		if !passes {
 			return false, reason, nil
 		}
+		if s.filtersDefinitions.StatefulSetWithoutStoragePodFilter != nil {
+			if passes, reason, err = s.filtersDefinitions.StatefulSetWithoutStoragePodFilter(*p); err != nil {
+				return false, "", err
+			}
+			if !passes {
+				return false, reason, nil
+			}
+		}
 	}
 	return true, "", nil
 }
@@ -669,36 +781,43 @@ func (s *DrainoConfigurationObserverImpl) processQueueForNodeUpdates() {
 
 		// func encapsultation to benefit from defer s.queue.Done()
 		func(obj interface{}) {
-			nodeName := obj.(string)
 			defer s.queueNodeToBeUpdated.Done(obj)
-
-			// nodePatchLimiter: client side protect to avoid flooding the api-server in case of massive update
-			if s.nodePatchLimiter.TryAccept() {
-				err := s.patchNodeLabels(nodeName)
-				if err != nil {
-					if apierrors.IsNotFound(err) {
-						return // the node was deleted, no more need for update.
-					}
-					requeueCount := s.queueNodeToBeUpdated.NumRequeues(nodeName)
-					s.logger.Error("Failed to update label", zap.String("node", nodeName), zap.Int("retry", requeueCount), zap.Error(err))
-					if requeueCount > 10 {
-						s.queueNodeToBeUpdated.Forget(nodeName)
-						return
-					}
-					s.queueNodeToBeUpdated.AddRateLimited(obj) // retry with exp backoff
-					MeasureNodeLabelPatchFailed.M(1)
-					return
-				}
-				// the item was correctly processed
-				s.queueNodeToBeUpdated.Forget(nodeName)
-				return
-			}
-			s.queueNodeToBeUpdated.AddRateLimited(obj) // retry with exp backoff
-			MeasureNodeLabelPatchRateLimited.M(1)
+			s.processNodeUpdate(obj.(string))
 		}(obj)
 	}
 }
 
+func (s *DrainoConfigurationObserverImpl) processNodeUpdate(nodeName string) {
+	// nodePatchLimiter: client side protect to avoid flooding the api-server in case of massive update
+	if !s.nodePatchLimiter.TryAccept() {
+		s.queueNodeToBeUpdated.AddRateLimited(nodeName) // retry with exp backoff
+		stats.Record(context.Background(), MeasureNodeLabelPatchRateLimited.M(1))
+		return
+	}
+	err := s.patchNodeLabels(nodeName)
+	if err == nil {
+		// the item was correctly processed
+		s.queueNodeToBeUpdated.Forget(nodeName)
+		return
+	}
+	if apierrors.IsNotFound(err) {
+		return // the node was deleted, no more need for update.
+	}
+	stats.Record(context.Background(), MeasureNodeLabelPatchFailed.M(1))
+	requeueCount := s.queueNodeToBeUpdated.NumRequeues(nodeName)
+	s.logger.Error("Failed to update label", zap.String("node", nodeName), zap.Int("retry", requeueCount), zap.Error(err))
+	// the failed attempts are the requeues done so far plus the current attempt
+	if requeueCount+1 >= s.nodeUpdateQueue.MaxRequeues {
+		s.queueNodeToBeUpdated.Forget(nodeName)
+		if s.nodeUpdateQueue.RequeuePolicy != NodeUpdateRequeuePolicyReset {
+			s.logger.Error("Abandon label update after max requeues", zap.String("node", nodeName), zap.Int("maxRequeues", s.nodeUpdateQueue.MaxRequeues))
+			stats.Record(context.Background(), MeasureNodeLabelUpdateAbandoned.M(1))
+			return
+		}
+	}
+	s.queueNodeToBeUpdated.AddRateLimited(nodeName) // retry with exp backoff
+}
+
 func (s *DrainoConfigurationObserverImpl) patchNodeLabels(nodeName string) error {
 	s.logger.Info("Update node labels", zap.String("node", nodeName))
 	node, err := s.runtimeObjectStore.Nodes().Get(nodeName)
@@ -706,12 +825,39 @@ func (s *DrainoConfigurationObserverImpl) patchNodeLabels(nodeName string) error
 		return err
 	}
 
-	cfgDesiredValue, cfgOutOfDate, err := s.getConfigLabelUpdate(node)
+	cfgDesiredValue, cfgOutOfDate, exitReason, err := s.getConfigLabelUpdate(node)
 	if err != nil {
 		return err
 	}
 	addOverdueLabel, removeOverdueLabel := s.getOverdueLabelUpdate(node)
 
+	if s.dryRun {
+		if cfgOutOfDate || addOverdueLabel || removeOverdueLabel {
+			s.logger.Info("Dry-run: node labels not updated",
+				zap.String("node", nodeName),
+				zap.String("currentConfig", node.Labels[ConfigurationLabelKey]),
+				zap.String("desiredConfig", cfgDesiredValue),
+				zap.String("scopeExitReason", exitReason),
+				zap.Bool("addOverdue", addOverdueLabel),
+				zap.Bool("removeOverdue", removeOverdueLabel))
+		}
+		return nil
+	}
+
+	if s.serverSideApply {
+		if cfgOutOfDate || addOverdueLabel || removeOverdueLabel {
+			_, wasOverdue := node.Labels[OverdueLabelKey]
+			if err := s.applyNodeLabels(nodeName, cfgDesiredValue, addOverdueLabel || (wasOverdue && !removeOverdueLabel), s.getScopeEntryTime(node, cfgDesiredValue)); err != nil {
+				return err
+			}
+			return s.updateScopeExitReason(node, cfgDesiredValue, exitReason)
+		}
+		if err := s.updateScopeExitReason(node, cfgDesiredValue, exitReason); err != nil {
+			return err
+		}
+		return s.updateScopeEntryTime(node, cfgDesiredValue)
+	}
+
 	if cfgOutOfDate || addOverdueLabel {
 		var labelPatch k8sclient.LabelPatch
 		labelPatch.Metadata.Labels = map[string]string{}
@@ -740,18 +886,109 @@ func (s *DrainoConfigurationObserverImpl) patchNodeLabels(nodeName string) error
 		}
 	}
 
+	if err := s.updateScopeExitReason(node, cfgDesiredValue, exitReason); err != nil {
+		return err
+	}
+	return s.updateScopeEntryTime(node, cfgDesiredValue)
+}
+
+// updateScopeExitReason records the reason why the node left the scope of the configuration.
+// The annotation is removed once the configuration label holds the configuration again.
+func (s *DrainoConfigurationObserverImpl) updateScopeExitReason(node *v1.Node, cfgDesiredValue, exitReason string) error {
+	var err error
+	_, hasExitReason := node.Annotations[ScopeExitReasonAnnotationKey]
+	switch {
+	case exitReason != "":
+		s.logger.Info("Node left the scope of the configuration", zap.String("node", node.Name), zap.String("reason", exitReason))
+		err = k8sclient.PatchNodeAnnotationKey(s.globalConfig.Context, s.kclient, node.Name, ScopeExitReasonAnnotationKey, exitReason)
+	case hasExitReason && slices.Contains(strings.Split(cfgDesiredValue, "."), s.globalConfig.ConfigName):
+		err = k8sclient.PatchDeleteNodeAnnotationKey(s.globalConfig.Context, s.kclient, node.Name, ScopeExitReasonAnnotationKey)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// updateScopeEntryTime records the time at which the node entered the scope of the configuration, so that the candidate filters can
+// measure the time spent in scope across the restarts. The annotation is removed once the node leaves the scope.
+func (s *DrainoConfigurationObserverImpl) updateScopeEntryTime(node *v1.Node, cfgDesiredValue string) error {
+	var err error
+	key := s.scopeEntryTimeAnnotationKey()
+	current, hasEntryTime := node.Annotations[key]
+	switch desired := s.getScopeEntryTime(node, cfgDesiredValue); {
+	case desired != "" && desired != current:
+		err = k8sclient.PatchNodeAnnotationKey(s.globalConfig.Context, s.kclient, node.Name, key, desired)
+	case desired == "" && hasEntryTime:
+		err = k8sclient.PatchDeleteNodeAnnotationKey(s.globalConfig.Context, s.kclient, node.Name, key)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// getScopeEntryTime returns the value of the scope entry time annotation the node should have, empty if the node is out of scope
+func (s *DrainoConfigurationObserverImpl) getScopeEntryTime(node *v1.Node, cfgDesiredValue string) string {
+	if !slices.Contains(strings.Split(cfgDesiredValue, "."), s.globalConfig.ConfigName) {
+		return ""
+	}
+	if current, ok := node.Annotations[s.scopeEntryTimeAnnotationKey()]; ok {
+		return current
+	}
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func (s *DrainoConfigurationObserverImpl) scopeEntryTimeAnnotationKey() string {
+	return ScopeEntryTimeAnnotationKeyPrefix + s.globalConfig.ConfigName
+}
+
+// applyNodeLabels writes all the labels managed by the observer with a single server-side apply request.
+// A label that is not part of the apply configuration anymore is removed by the API server, as long as it is owned by our field manager.
+func (s *DrainoConfigurationObserverImpl) applyNodeLabels(nodeName string, cfgDesiredValue string, overdue bool, scopeEntryTime string) error {
+	labels := map[string]string{ConfigurationLabelKey: cfgDesiredValue}
+	if overdue {
+		labels[OverdueLabelKey] = "true"
+	}
+	applyConfig := corev1ac.Node(nodeName).WithLabels(labels)
+	if scopeEntryTime != "" {
+		applyConfig = applyConfig.WithAnnotations(map[string]string{s.scopeEntryTimeAnnotationKey(): scopeEntryTime})
+	}
+	updated, err := s.kclient.CoreV1().Nodes().Apply(s.globalConfig.Context, applyConfig, meta.ApplyOptions{FieldManager: ScopeObserverFieldManager, Force: true})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	// The overdue label may have been set with a patch, before server-side apply was enabled. In that case it is not owned by our field manager and must be removed explicitly.
+	if _, stillOverdue := updated.Labels[OverdueLabelKey]; stillOverdue && !overdue {
+		err := k8sclient.PatchDeleteNodeLabelKey(s.globalConfig.Context, s.kclient, nodeName, OverdueLabelKey)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	// The scope entry time may have been set with a patch as well
+	if _, stillHasEntryTime := updated.Annotations[s.scopeEntryTimeAnnotationKey()]; stillHasEntryTime && scopeEntryTime == "" {
+		err := k8sclient.PatchDeleteNodeAnnotationKey(s.globalConfig.Context, s.kclient, nodeName, s.scopeEntryTimeAnnotationKey())
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
 	return nil
 }
 
 // Reset: remove all previous persisted values in node annotations.
 // This can be useful if ever the name of the draino configuration changes
 func (s *DrainoConfigurationObserverImpl) Reset() {
-	if err := wait.PollImmediateInfinite(2*time.Second, func() (done bool, err error) {
+	if err := kubernetes.WaitForSync(context.Background(), func() bool {
 		synced := s.runtimeObjectStore.Nodes().HasSynced()
 		s.logger.Info("Wait for node informer to sync", zap.Bool("synced", synced))
-		return synced, nil
-	}); err != nil {
-		s.logger.Error("Failed to sync node informer before reset labels. Reset labels cancelled.")
+		return synced
+	}, 2*time.Second, s.informerSyncTimeout); err != nil {
+		s.logger.Error("Failed to sync node informer before reset labels. Reset labels cancelled.", zap.Error(err))
+		return
 	}
 
 	s.logger.Info("Resetting labels for configuration names")
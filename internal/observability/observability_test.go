@@ -2,22 +2,30 @@ package observability
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opencensus.io/stats/view"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/pointer"
 
+	"github.com/planetlabs/draino/internal/groups"
 	"github.com/planetlabs/draino/internal/kubernetes"
 )
 
@@ -28,15 +36,16 @@ func TestScopeObserverImpl_GetLabelUpdate(t *testing.T) {
 		}
 	}
 	tests := []struct {
-		name              string
-		configName        string
-		nodeFilterFunc    func(obj interface{}) bool
-		podFilterFunc     kubernetes.PodFilterFunc
-		objects           []runtime.Object
-		node              *v1.Node
-		expectedValue     string
-		expectedOutOfDate bool
-		expectedError     string
+		name               string
+		configName         string
+		nodeFilterFunc     func(obj interface{}) bool
+		podFilterFunc      kubernetes.PodFilterFunc
+		objects            []runtime.Object
+		node               *v1.Node
+		expectedValue      string
+		expectedOutOfDate  bool
+		expectedExitReason string
+		expectedError      string
 	}{
 		{
 			name:              "no label - not in-scope --> update to out of scope",
@@ -132,24 +141,37 @@ func TestScopeObserverImpl_GetLabelUpdate(t *testing.T) {
 			expectedOutOfDate: true,
 		},
 		{
-			name:              "label present - not in-scope --> update needed",
-			configName:        "draino1",
-			nodeFilterFunc:    func(obj interface{}) bool { return false }, // not in scope
-			podFilterFunc:     kubernetes.NewPodFilters(),
-			objects:           []runtime.Object{},
-			node:              getNode("draino1"),
-			expectedValue:     OutOfScopeLabelValue,
-			expectedOutOfDate: true,
+			name:               "label present - not in-scope --> update needed",
+			configName:         "draino1",
+			nodeFilterFunc:     func(obj interface{}) bool { return false }, // not in scope
+			podFilterFunc:      kubernetes.NewPodFilters(),
+			objects:            []runtime.Object{},
+			node:               getNode("draino1"),
+			expectedValue:      OutOfScopeLabelValue,
+			expectedOutOfDate:  true,
+			expectedExitReason: "labelSelection",
 		},
 		{
-			name:              "label present with other - not in-scope --> update needed",
-			configName:        "draino1",
-			nodeFilterFunc:    func(obj interface{}) bool { return false }, // not in scope
-			podFilterFunc:     kubernetes.NewPodFilters(),
-			objects:           []runtime.Object{},
-			node:              getNode("draino1.other-draino"),
-			expectedValue:     "other-draino",
-			expectedOutOfDate: true,
+			name:               "label present - not in-scope (pod) --> update needed",
+			configName:         "draino1",
+			nodeFilterFunc:     func(obj interface{}) bool { return true }, // in scope for node
+			podFilterFunc:      func(p v1.Pod) (pass bool, reason string, err error) { return false, "test", nil },
+			objects:            []runtime.Object{&v1.Pod{}},
+			node:               getNode("draino1"),
+			expectedValue:      OutOfScopeLabelValue,
+			expectedOutOfDate:  true,
+			expectedExitReason: "test",
+		},
+		{
+			name:               "label present with other - not in-scope --> update needed",
+			configName:         "draino1",
+			nodeFilterFunc:     func(obj interface{}) bool { return false }, // not in scope
+			podFilterFunc:      kubernetes.NewPodFilters(),
+			objects:            []runtime.Object{},
+			node:               getNode("draino1.other-draino"),
+			expectedValue:      "other-draino",
+			expectedOutOfDate:  true,
+			expectedExitReason: "labelSelection",
 		},
 		{
 			name:              "sorts values",
@@ -185,7 +207,7 @@ func TestScopeObserverImpl_GetLabelUpdate(t *testing.T) {
 				return s.runtimeObjectStore.HasSynced(), nil
 			})
 
-			actualValue, actualOutOfDate, err := s.getConfigLabelUpdate(tt.node)
+			actualValue, actualOutOfDate, actualExitReason, err := s.getConfigLabelUpdate(tt.node)
 			if tt.expectedError != "" {
 				require.Error(t, err)
 				assert.Contains(t, err.Error(), tt.expectedError)
@@ -193,6 +215,7 @@ func TestScopeObserverImpl_GetLabelUpdate(t *testing.T) {
 				require.NoError(t, err)
 				assert.Equal(t, tt.expectedValue, actualValue)
 				assert.Equal(t, tt.expectedOutOfDate, actualOutOfDate)
+				assert.Equal(t, tt.expectedExitReason, actualExitReason)
 			}
 		})
 	}
@@ -409,6 +432,365 @@ func TestScopeObserverImpl_updateNodeAnnotationsAndLabels(t *testing.T) {
 	}
 }
 
+func TestScopeObserverImpl_scopeExitReason(t *testing.T) {
+	getNode := func(labelValue string, annotations map[string]string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: meta.ObjectMeta{Name: "node1", Labels: map[string]string{ConfigurationLabelKey: labelValue}, Annotations: annotations},
+		}
+	}
+	tests := []struct {
+		name               string
+		nodeFilterFunc     func(obj interface{}) bool
+		podFilterFunc      kubernetes.PodFilterFunc
+		objects            []runtime.Object
+		expectedExitReason string
+	}{
+		{
+			name:               "exit via label selector",
+			nodeFilterFunc:     func(obj interface{}) bool { return false },
+			podFilterFunc:      kubernetes.NewPodFilters(),
+			objects:            []runtime.Object{getNode("draino1", nil)},
+			expectedExitReason: "labelSelection",
+		},
+		{
+			name:           "exit via pod filter",
+			nodeFilterFunc: func(obj interface{}) bool { return true },
+			podFilterFunc: func(p v1.Pod) (pass bool, reason string, err error) {
+				return false, "pod-controlledby-job", nil
+			},
+			objects: []runtime.Object{
+				getNode("draino1", nil),
+				&v1.Pod{ObjectMeta: meta.ObjectMeta{Name: "pod1", Namespace: "default"}, Spec: v1.PodSpec{NodeName: "node1"}},
+			},
+			expectedExitReason: "pod-controlledby-job",
+		},
+		{
+			name:           "never in scope",
+			nodeFilterFunc: func(obj interface{}) bool { return false },
+			podFilterFunc:  kubernetes.NewPodFilters(),
+			objects:        []runtime.Object{getNode(OutOfScopeLabelValue, nil)},
+		},
+		{
+			name:           "back in scope",
+			nodeFilterFunc: func(obj interface{}) bool { return true },
+			podFilterFunc:  kubernetes.NewPodFilters(),
+			objects:        []runtime.Object{getNode(OutOfScopeLabelValue, map[string]string{ScopeExitReasonAnnotationKey: "labelSelection"})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kclient := fake.NewSimpleClientset(tt.objects...)
+			runtimeObjectStore, closeFunc := kubernetes.RunStoreForTest(context.Background(), kclient)
+			defer closeFunc()
+			s := &DrainoConfigurationObserverImpl{
+				kclient:            kclient,
+				runtimeObjectStore: runtimeObjectStore,
+				globalConfig:       kubernetes.GlobalConfig{Context: context.Background(), ConfigName: "draino1"},
+				filtersDefinitions: kubernetes.FiltersDefinitions{
+					NodeLabelFilter:    tt.nodeFilterFunc,
+					CandidatePodFilter: tt.podFilterFunc,
+					NodeAndPodsFilter: func(node *v1.Node, pods []*v1.Pod) bool {
+						return true
+					},
+				},
+				logger: zap.NewNop(),
+			}
+			wait.PollImmediate(200*time.Millisecond, 5*time.Second, func() (done bool, err error) {
+				return s.runtimeObjectStore.HasSynced(), nil
+			})
+
+			require.NoError(t, s.patchNodeLabels("node1"))
+
+			node, err := kclient.CoreV1().Nodes().Get(context.Background(), "node1", meta.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedExitReason, node.Annotations[ScopeExitReasonAnnotationKey])
+		})
+	}
+}
+
+func TestScopeObserverImpl_scopeEntryTime(t *testing.T) {
+	const entryTimeKey = ScopeEntryTimeAnnotationKeyPrefix + "draino1"
+	previousEntry := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	getNode := func(labelValue string, annotations map[string]string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: meta.ObjectMeta{Name: "node1", Labels: map[string]string{ConfigurationLabelKey: labelValue}, Annotations: annotations},
+		}
+	}
+	tests := []struct {
+		name           string
+		inScope        bool
+		node           *v1.Node
+		wantEntryTime  bool
+		wantUnmodified bool
+	}{
+		{
+			name:          "enters the scope",
+			inScope:       true,
+			node:          getNode(OutOfScopeLabelValue, nil),
+			wantEntryTime: true,
+		},
+		{
+			name:           "stays in scope",
+			inScope:        true,
+			node:           getNode("draino1", map[string]string{entryTimeKey: previousEntry}),
+			wantEntryTime:  true,
+			wantUnmodified: true,
+		},
+		{
+			name:    "leaves the scope",
+			inScope: false,
+			node:    getNode("draino1", map[string]string{entryTimeKey: previousEntry}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kclient := fake.NewSimpleClientset(tt.node)
+			runtimeObjectStore, closeFunc := kubernetes.RunStoreForTest(context.Background(), kclient)
+			defer closeFunc()
+			s := &DrainoConfigurationObserverImpl{
+				kclient:            kclient,
+				runtimeObjectStore: runtimeObjectStore,
+				globalConfig:       kubernetes.GlobalConfig{Context: context.Background(), ConfigName: "draino1"},
+				filtersDefinitions: kubernetes.FiltersDefinitions{
+					NodeLabelFilter:    func(obj interface{}) bool { return tt.inScope },
+					CandidatePodFilter: kubernetes.NewPodFilters(),
+					NodeAndPodsFilter: func(node *v1.Node, pods []*v1.Pod) bool {
+						return true
+					},
+				},
+				logger: zap.NewNop(),
+			}
+			wait.PollImmediate(200*time.Millisecond, 5*time.Second, func() (done bool, err error) {
+				return s.runtimeObjectStore.HasSynced(), nil
+			})
+
+			require.NoError(t, s.patchNodeLabels("node1"))
+
+			node, err := kclient.CoreV1().Nodes().Get(context.Background(), "node1", meta.GetOptions{})
+			require.NoError(t, err)
+			entryTime, found := node.Annotations[entryTimeKey]
+			assert.Equal(t, tt.wantEntryTime, found)
+			if tt.wantUnmodified {
+				assert.Equal(t, previousEntry, entryTime)
+			}
+			if found {
+				_, err := time.Parse(time.RFC3339, entryTime)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestScopeObserverImpl_patchNodeLabelsDryRun(t *testing.T) {
+	node := &v1.Node{ObjectMeta: meta.ObjectMeta{Name: "node1"}}
+	kclient := fake.NewSimpleClientset(node)
+	runtimeObjectStore, closeFunc := kubernetes.RunStoreForTest(context.Background(), kclient)
+	defer closeFunc()
+
+	core, logs := observer.New(zap.InfoLevel)
+	s := &DrainoConfigurationObserverImpl{
+		kclient:            kclient,
+		runtimeObjectStore: runtimeObjectStore,
+		globalConfig:       kubernetes.GlobalConfig{ConfigName: "draino1"},
+		filtersDefinitions: kubernetes.FiltersDefinitions{
+			NodeLabelFilter:    func(obj interface{}) bool { return true },
+			CandidatePodFilter: kubernetes.NewPodFilters(),
+			NodeAndPodsFilter: func(node *v1.Node, pods []*v1.Pod) bool {
+				return true
+			},
+		},
+		logger: zap.New(core),
+		dryRun: true,
+	}
+	kclient.ClearActions()
+
+	require.NoError(t, s.patchNodeLabels(node.Name))
+
+	for _, action := range kclient.Actions() {
+		assert.NotEqual(t, "patch", action.GetVerb(), "no patch should be sent in dry-run")
+	}
+	dryRunLogs := logs.FilterMessage("Dry-run: node labels not updated").All()
+	require.Len(t, dryRunLogs, 1)
+	fields := dryRunLogs[0].ContextMap()
+	assert.Equal(t, "node1", fields["node"])
+	assert.Equal(t, "", fields["currentConfig"])
+	assert.Equal(t, "draino1", fields["desiredConfig"])
+}
+
+type noRunnerInfo struct{}
+
+func (noRunnerInfo) GetRunnerInfo() map[groups.GroupKey]groups.RunnerInfo { return nil }
+
+func TestScopeObserverImpl_TriggerAnalysis(t *testing.T) {
+	node := &v1.Node{ObjectMeta: meta.ObjectMeta{Name: "node1"}}
+	kclient := fake.NewSimpleClientset(node)
+	runtimeObjectStore, closeFunc := kubernetes.RunStoreForTest(context.Background(), kclient)
+	defer closeFunc()
+
+	s := &DrainoConfigurationObserverImpl{
+		kclient:            kclient,
+		runtimeObjectStore: runtimeObjectStore,
+		globalConfig:       kubernetes.GlobalConfig{Context: context.Background(), ConfigName: "draino1"},
+		filtersDefinitions: kubernetes.FiltersDefinitions{
+			NodeLabelFilter:    func(obj interface{}) bool { return true },
+			CandidatePodFilter: kubernetes.NewPodFilters(),
+			NodeAndPodsFilter: func(node *v1.Node, pods []*v1.Pod) bool {
+				return true
+			},
+		},
+		logger:               zap.NewNop(),
+		analysisPeriod:       time.Hour,
+		analysisTrigger:      make(chan struct{}, 1),
+		queueNodeToBeUpdated: workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)),
+		nodePatchLimiter:     flowcontrol.NewFakeAlwaysRateLimiter(),
+		runnerInfoGetter:     noRunnerInfo{},
+	}
+
+	assert.True(t, s.TriggerAnalysis(), "first request should queue an analysis")
+	assert.False(t, s.TriggerAnalysis(), "second request should be merged with the pending analysis")
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Run(stop)
+
+	// the analysis period is too long for a periodic pass, the node can only be labeled by the requested one
+	assert.Eventually(t, func() bool {
+		updated, err := kclient.CoreV1().Nodes().Get(context.Background(), node.Name, meta.GetOptions{})
+		return err == nil && updated.Labels[ConfigurationLabelKey] == "draino1"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, s.TriggerAnalysis(), "a new analysis can be queued once the pending one ran")
+}
+
+func TestScopeObserverImpl_patchNodeLabelsServerSideApply(t *testing.T) {
+	node := &v1.Node{ObjectMeta: meta.ObjectMeta{Name: "node1", Labels: map[string]string{ConfigurationLabelKey: "other"}}}
+	kclient := fake.NewSimpleClientset(node)
+	runtimeObjectStore, closeFunc := kubernetes.RunStoreForTest(context.Background(), kclient)
+	defer closeFunc()
+
+	// The fake clientset does not support server-side apply, the reactor captures the apply configuration
+	var appliedLabels map[string]string
+	kclient.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patchAction := action.(k8stesting.PatchAction)
+		if patchAction.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		var applied v1.Node
+		if err := json.Unmarshal(patchAction.GetPatch(), &applied); err != nil {
+			return true, nil, err
+		}
+		appliedLabels = applied.Labels
+		return true, &applied, nil
+	})
+
+	s := &DrainoConfigurationObserverImpl{
+		kclient:            kclient,
+		runtimeObjectStore: runtimeObjectStore,
+		globalConfig:       kubernetes.GlobalConfig{Context: context.Background(), ConfigName: "draino1"},
+		filtersDefinitions: kubernetes.FiltersDefinitions{
+			NodeLabelFilter:    func(obj interface{}) bool { return true },
+			CandidatePodFilter: kubernetes.NewPodFilters(),
+			NodeAndPodsFilter: func(node *v1.Node, pods []*v1.Pod) bool {
+				return true
+			},
+		},
+		logger:          zap.NewNop(),
+		serverSideApply: true,
+	}
+	kclient.ClearActions()
+
+	require.NoError(t, s.patchNodeLabels(node.Name))
+
+	var patchTypes []types.PatchType
+	for _, action := range kclient.Actions() {
+		if patchAction, ok := action.(k8stesting.PatchAction); ok {
+			patchTypes = append(patchTypes, patchAction.GetPatchType())
+		}
+	}
+	assert.Equal(t, []types.PatchType{types.ApplyPatchType}, patchTypes, "a single server-side apply request is expected")
+
+	desiredValue, _, _, err := s.getConfigLabelUpdate(node)
+	require.NoError(t, err)
+	assert.Equal(t, "draino1.other", desiredValue)
+	assert.Equal(t, map[string]string{ConfigurationLabelKey: desiredValue}, appliedLabels)
+}
+
+func TestScopeObserverImpl_processNodeUpdateMaxRequeues(t *testing.T) {
+	tests := []struct {
+		name              string
+		policy            string
+		expectedAbandoned int64
+		expectedRequeues  int
+	}{
+		{
+			name:              "drop after max requeues",
+			policy:            NodeUpdateRequeuePolicyDrop,
+			expectedAbandoned: 1,
+			expectedRequeues:  0,
+		},
+		{
+			name:              "reset after max requeues",
+			policy:            NodeUpdateRequeuePolicyReset,
+			expectedAbandoned: 0,
+			expectedRequeues:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &v1.Node{ObjectMeta: meta.ObjectMeta{Name: "node1"}}
+			kclient := fake.NewSimpleClientset(node)
+			runtimeObjectStore, closeFunc := kubernetes.RunStoreForTest(context.Background(), kclient)
+			defer closeFunc()
+			kclient.PrependReactor("patch", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, fmt.Errorf("patch failure")
+			})
+
+			queue := workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond))
+			defer queue.ShutDown()
+			s := &DrainoConfigurationObserverImpl{
+				kclient:            kclient,
+				runtimeObjectStore: runtimeObjectStore,
+				globalConfig:       kubernetes.GlobalConfig{Context: context.Background(), ConfigName: "draino1"},
+				filtersDefinitions: kubernetes.FiltersDefinitions{
+					NodeLabelFilter:    func(obj interface{}) bool { return true },
+					CandidatePodFilter: kubernetes.NewPodFilters(),
+					NodeAndPodsFilter: func(node *v1.Node, pods []*v1.Pod) bool {
+						return true
+					},
+				},
+				logger:               zap.NewNop(),
+				queueNodeToBeUpdated: queue,
+				nodePatchLimiter:     flowcontrol.NewFakeAlwaysRateLimiter(),
+				nodeUpdateQueue:      NodeUpdateQueueConfig{MaxRequeues: 3, MaxBackoff: time.Millisecond, RequeuePolicy: tt.policy},
+			}
+			s.metricsObjects.initializeQueueMetrics()
+			abandonedBefore := abandonedNodeUpdates(t)
+
+			for i := 0; i < 2; i++ {
+				s.processNodeUpdate(node.Name)
+				assert.Equal(t, i+1, queue.NumRequeues(node.Name))
+			}
+			assert.Equal(t, abandonedBefore, abandonedNodeUpdates(t), "no update should be abandoned before the max requeues")
+
+			// third failed attempt
+
+			s.processNodeUpdate(node.Name)
+			assert.Equal(t, tt.expectedRequeues, queue.NumRequeues(node.Name))
+			assert.Equal(t, abandonedBefore+tt.expectedAbandoned, abandonedNodeUpdates(t))
+		})
+	}
+}
+
+func abandonedNodeUpdates(t *testing.T) int64 {
+	rows, err := view.RetrieveData("nodes_label_update_abandoned_total")
+	require.NoError(t, err)
+	if len(rows) == 0 {
+		return 0
+	}
+	return rows[0].Data.(*view.CountData).Value
+}
+
 func TestPVCStorageClassCleanupEnabled(t *testing.T) {
 
 	tests := []struct {
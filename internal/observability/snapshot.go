@@ -0,0 +1,133 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+// ScopeSnapshotDataKey is the key of the snapshot in the data of the ConfigMap
+const ScopeSnapshotDataKey = "snapshot"
+
+// scopeSnapshotMaxNodeNamesPerGroup above this number of nodes in scope, the group summary does not list the node names to keep the snapshot size bounded
+const scopeSnapshotMaxNodeNamesPerGroup = 20
+
+// ScopeSnapshot is the summary of the scope written in the snapshot ConfigMap at each analysis
+type ScopeSnapshot struct {
+	ConfigName string                        `json:"configName"`
+	Time       time.Time                     `json:"time"`
+	Groups     map[string]*GroupScopeSummary `json:"groups"`
+}
+
+// GroupScopeSummary counts the nodes of a group per scope and drain status
+type GroupScopeSummary struct {
+	Nodes          int `json:"nodes"`
+	InScope        int `json:"inScope"`
+	WithConditions int `json:"withConditions"`
+	Candidates     int `json:"candidates"`
+	Draining       int `json:"draining"`
+	Drained        int `json:"drained"`
+	// InScopeNodes is only filled for the groups with a few nodes in scope
+	InScopeNodes []string `json:"inScopeNodes,omitempty"`
+}
+
+// ScopeSnapshotWriter publishes the summary of the scope written at each analysis
+type ScopeSnapshotWriter interface {
+	Write(ctx context.Context, snapshot ScopeSnapshot) error
+}
+
+// configMapScopeSnapshotWriter writes the snapshot as JSON in a ConfigMap, which is created if needed
+type configMapScopeSnapshotWriter struct {
+	name     string
+	clientCM corev1.ConfigMapInterface
+}
+
+var _ ScopeSnapshotWriter = &configMapScopeSnapshotWriter{}
+
+func NewConfigMapScopeSnapshotWriter(clientCM corev1.ConfigMapInterface, name string) ScopeSnapshotWriter {
+	return &configMapScopeSnapshotWriter{name: name, clientCM: clientCM}
+}
+
+func (w *configMapScopeSnapshotWriter) Write(ctx context.Context, snapshot ScopeSnapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	cm, err := w.clientCM.Get(ctx, w.name, meta.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{ObjectMeta: meta.ObjectMeta{Name: w.name}, Data: map[string]string{ScopeSnapshotDataKey: string(data)}}
+		_, err = w.clientCM.Create(ctx, cm, meta.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[ScopeSnapshotDataKey] = string(data)
+	_, err = w.clientCM.Update(ctx, cm, meta.UpdateOptions{})
+	return err
+}
+
+func (s *DrainoConfigurationObserverImpl) buildScopeSnapshot(nodes []*v1.Node, now time.Time) ScopeSnapshot {
+	snapshot := ScopeSnapshot{
+		ConfigName: s.globalConfig.ConfigName,
+		Time:       now,
+		Groups:     map[string]*GroupScopeSummary{},
+	}
+	for _, node := range nodes {
+		group := string(s.groupKeyGetter.GetGroupKey(node))
+		summary, ok := snapshot.Groups[group]
+		if !ok {
+			summary = &GroupScopeSummary{}
+			snapshot.Groups[group] = summary
+		}
+		summary.Nodes++
+
+		if cfg := node.Labels[ConfigurationLabelKey]; cfg == "" || cfg == OutOfScopeLabelValue {
+			continue
+		}
+		summary.InScope++
+		summary.InScopeNodes = append(summary.InScopeNodes, node.Name)
+		if conditions := kubernetes.GetNodeOffendingConditions(node, s.globalConfig.SuppliedConditions); len(conditions) > 0 && NodeInScopeWithConditionCheck(conditions, node) {
+			summary.WithConditions++
+		}
+		if taint, exist := k8sclient.GetNLATaint(node); exist {
+			switch taint.Value {
+			case k8sclient.TaintDrainCandidate:
+				summary.Candidates++
+			case k8sclient.TaintDraining:
+				summary.Draining++
+			case k8sclient.TaintDrained:
+				summary.Drained++
+			}
+		}
+	}
+
+	for _, summary := range snapshot.Groups {
+		if len(summary.InScopeNodes) > scopeSnapshotMaxNodeNamesPerGroup {
+			summary.InScopeNodes = nil
+			continue
+		}
+		sort.Strings(summary.InScopeNodes)
+	}
+	return snapshot
+}
+
+// persistScopeSnapshot writes the summary of the scope in the snapshot ConfigMap
+func (s *DrainoConfigurationObserverImpl) persistScopeSnapshot(ctx context.Context, nodes []*v1.Node) {
+	if err := s.scopeSnapshotWriter.Write(ctx, s.buildScopeSnapshot(nodes, time.Now())); err != nil {
+		s.logger.Error("Failed to persist scope snapshot", zap.Error(err))
+	}
+}
@@ -0,0 +1,67 @@
+package observability
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/planetlabs/draino/internal/groups"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+)
+
+func TestScopeObserverImpl_persistScopeSnapshot(t *testing.T) {
+	newNode := func(name, group, cfg string, taintValue k8sclient.DrainTaintValue) *v1.Node {
+		node := &v1.Node{ObjectMeta: meta.ObjectMeta{Name: name, Labels: map[string]string{"group": group}}}
+		if cfg != "" {
+			node.Labels[ConfigurationLabelKey] = cfg
+		}
+		if taintValue != "" {
+			node.Spec.Taints = []v1.Taint{*k8sclient.CreateNLATaint(taintValue, time.Now())}
+		}
+		return node
+	}
+	nodes := []*v1.Node{
+		newNode("a-1", "a", "draino1", k8sclient.TaintDrainCandidate),
+		newNode("a-2", "a", "draino1", k8sclient.TaintDrained),
+		newNode("a-3", "a", OutOfScopeLabelValue, ""),
+		newNode("b-1", "b", "draino1", ""),
+	}
+	for i := 0; i <= scopeSnapshotMaxNodeNamesPerGroup; i++ {
+		nodes = append(nodes, newNode(fmt.Sprintf("c-%d", i), "c", "draino1", k8sclient.TaintDraining))
+	}
+
+	kclient := fake.NewSimpleClientset()
+	s := &DrainoConfigurationObserverImpl{
+		globalConfig:        kubernetes.GlobalConfig{ConfigName: "draino1"},
+		groupKeyGetter:      groups.NewGroupKeyFromNodeMetadata(nil, logr.Discard(), kubernetes.NoopEventRecorder{}, nil, nil, []string{"group"}, nil, ""),
+		scopeSnapshotWriter: NewConfigMapScopeSnapshotWriter(kclient.CoreV1().ConfigMaps("default"), "draino-scope"),
+		logger:              zap.NewNop(),
+	}
+
+	// the configmap is created at the first analysis and then updated
+	s.persistScopeSnapshot(context.Background(), nodes[:1])
+	s.persistScopeSnapshot(context.Background(), nodes)
+
+	cm, err := kclient.CoreV1().ConfigMaps("default").Get(context.Background(), "draino-scope", meta.GetOptions{})
+	require.NoError(t, err)
+	var snapshot ScopeSnapshot
+	require.NoError(t, json.Unmarshal([]byte(cm.Data[ScopeSnapshotDataKey]), &snapshot))
+
+	assert.Equal(t, "draino1", snapshot.ConfigName)
+	assert.Equal(t, map[string]*GroupScopeSummary{
+		"a": {Nodes: 3, InScope: 2, Candidates: 1, Drained: 1, InScopeNodes: []string{"a-1", "a-2"}},
+		"b": {Nodes: 1, InScope: 1, InScopeNodes: []string{"b-1"}},
+		"c": {Nodes: scopeSnapshotMaxNodeNamesPerGroup + 1, InScope: scopeSnapshotMaxNodeNamesPerGroup + 1, Draining: scopeSnapshotMaxNodeNamesPerGroup + 1},
+	}, snapshot.Groups)
+}
@@ -0,0 +1,131 @@
+package remote_conditions
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientset "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/k8sclient"
+	"github.com/planetlabs/draino/internal/kubernetes/utils"
+)
+
+const DefaultSyncPeriod = 30 * time.Second
+
+// ConditionsMirror copies the supplied conditions reported on the nodes of a secondary cluster (for example a management cluster
+// in hub-spoke setups) to the nodes of the same name in the cluster managed by draino. This way the conditions are evaluated
+// by all the draino components as if they were reported in the primary cluster; all the actions still use the primary client.
+// The copies have their own type, see kubernetes.MirroredConditionType, and are removed once the source condition is gone.
+type ConditionsMirror struct {
+	source     clientset.Interface
+	target     client.Client
+	logger     logr.Logger
+	period     time.Duration
+	conditions []kubernetes.SuppliedCondition
+}
+
+var _ manager.Runnable = &ConditionsMirror{}
+
+func NewConditionsMirror(source clientset.Interface, target client.Client, logger logr.Logger, period time.Duration, conditions []kubernetes.SuppliedCondition) *ConditionsMirror {
+	return &ConditionsMirror{
+		source:     source,
+		target:     target,
+		logger:     logger.WithName("ConditionsMirror"),
+		period:     period,
+		conditions: conditions,
+	}
+}
+
+// Start runs the mirror till the context is Done, blocking call
+func (m *ConditionsMirror) Start(ctx context.Context) error {
+	m.logger.Info("starting", "period", m.period)
+	wait.UntilWithContext(ctx, m.sync, m.period)
+	return nil
+}
+
+func (m *ConditionsMirror) sync(ctx context.Context) {
+	sourceNodes, err := m.source.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		m.logger.Error(err, "cannot list nodes of the conditions cluster")
+		return
+	}
+	sourceNodesByName := make(map[string]*corev1.Node, len(sourceNodes.Items))
+	for i := range sourceNodes.Items {
+		sourceNodesByName[sourceNodes.Items[i].Name] = &sourceNodes.Items[i]
+	}
+
+	var nodes corev1.NodeList
+	if err := m.target.List(ctx, &nodes); err != nil {
+		m.logger.Error(err, "cannot list nodes")
+		return
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		// a node missing from the secondary cluster has no condition to mirror anymore
+		sourceNode := sourceNodesByName[node.Name]
+		for _, conditionType := range m.conditionTypes() {
+			var condition corev1.NodeCondition
+			found := false
+			if sourceNode != nil {
+				_, condition, found = utils.FindNodeCondition(conditionType, sourceNode)
+			}
+			if !found {
+				if err := m.deleteCondition(ctx, node, kubernetes.MirroredConditionType(conditionType)); err != nil {
+					m.logger.Error(err, "cannot delete mirrored condition", "node", node.Name, "condition", conditionType)
+				}
+				continue
+			}
+			condition.Type = kubernetes.MirroredConditionType(conditionType)
+			if err := m.setCondition(ctx, node, condition); err != nil {
+				m.logger.Error(err, "cannot mirror condition", "node", node.Name, "condition", conditionType)
+			}
+		}
+	}
+}
+
+// conditionTypes returns the supplied condition types without duplicates, the same type can be supplied with different status
+func (m *ConditionsMirror) conditionTypes() []corev1.NodeConditionType {
+	var result []corev1.NodeConditionType
+	seen := map[corev1.NodeConditionType]bool{}
+	for _, c := range m.conditions {
+		if seen[c.Type] {
+			continue
+		}
+		seen[c.Type] = true
+		result = append(result, c.Type)
+	}
+	return result
+}
+
+// setCondition patches the condition only if its status or transition time changes, the transition time is kept so that the condition delay is respected
+func (m *ConditionsMirror) setCondition(ctx context.Context, node *corev1.Node, condition corev1.NodeCondition) error {
+	pos, current, found := utils.FindNodeCondition(condition.Type, node)
+	if found && current.Status == condition.Status && current.LastTransitionTime.Equal(&condition.LastTransitionTime) {
+		return nil
+	}
+
+	newNode := node.DeepCopy()
+	if found {
+		newNode.Status.Conditions[pos] = condition
+	} else {
+		newNode.Status.Conditions = append(newNode.Status.Conditions, condition)
+	}
+	m.logger.Info("mirroring condition", "node", node.Name, "condition", condition.Type, "status", condition.Status)
+	return m.target.Status().Patch(ctx, newNode, &k8sclient.NodeConditionPatch{ConditionType: condition.Type})
+}
+
+// deleteCondition removes the mirrored condition whose source condition is gone, if the node has it
+func (m *ConditionsMirror) deleteCondition(ctx context.Context, node *corev1.Node, conditionType corev1.NodeConditionType) error {
+	if _, _, found := utils.FindNodeCondition(conditionType, node); !found {
+		return nil
+	}
+	m.logger.Info("deleting mirrored condition", "node", node.Name, "condition", conditionType)
+	return m.target.Status().Patch(ctx, node.DeepCopy(), &k8sclient.NodeConditionDeletePatch{ConditionType: conditionType})
+}
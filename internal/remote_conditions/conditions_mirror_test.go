@@ -0,0 +1,90 @@
+package remote_conditions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/planetlabs/draino/internal/kubernetes"
+)
+
+func TestConditionsMirror(t *testing.T) {
+	transition := metav1.NewTime(time.Now().Add(-time.Hour))
+	suppliedConditions, err := kubernetes.ParseConditions([]string{`KernelDeadlock={"delay":"30m"}`})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name              string
+		nodeConditions    []corev1.NodeCondition
+		sourceNodeName    string
+		sourceConditions  []corev1.NodeCondition
+		expectedOffending []string
+		expectedTypes     []corev1.NodeConditionType
+	}{
+		{
+			name:              "condition reported in the secondary cluster",
+			sourceNodeName:    "node-1",
+			sourceConditions:  []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: transition}},
+			expectedOffending: []string{"KernelDeadlock"},
+			expectedTypes:     []corev1.NodeConditionType{"MirroredKernelDeadlock"},
+		},
+		{
+			name:              "condition reported in both clusters",
+			nodeConditions:    []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionFalse, LastTransitionTime: transition}},
+			sourceNodeName:    "node-1",
+			sourceConditions:  []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: transition}},
+			expectedOffending: []string{"KernelDeadlock"},
+			expectedTypes:     []corev1.NodeConditionType{"KernelDeadlock", "MirroredKernelDeadlock"},
+		},
+		{
+			name:           "condition cleared in the secondary cluster",
+			nodeConditions: []corev1.NodeCondition{{Type: "MirroredKernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: transition}},
+			sourceNodeName: "node-1",
+		},
+		{
+			name:           "node removed from the secondary cluster",
+			nodeConditions: []corev1.NodeCondition{{Type: "MirroredKernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: transition}},
+			sourceNodeName: "node-2",
+		},
+		{
+			name:             "condition not supplied",
+			sourceNodeName:   "node-1",
+			sourceConditions: []corev1.NodeCondition{{Type: "OtherCondition", Status: corev1.ConditionTrue, LastTransitionTime: transition}},
+		},
+		{
+			name:             "condition on another node",
+			sourceNodeName:   "node-2",
+			sourceConditions: []corev1.NodeCondition{{Type: "KernelDeadlock", Status: corev1.ConditionTrue, LastTransitionTime: transition}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Status: corev1.NodeStatus{Conditions: tt.nodeConditions}}
+			sourceNode := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.sourceNodeName},
+				Status:     corev1.NodeStatus{Conditions: tt.sourceConditions},
+			}
+			kclient := fake.NewFakeClient(node)
+			mirror := NewConditionsMirror(fakeclientset.NewSimpleClientset(sourceNode), kclient, logr.Discard(), DefaultSyncPeriod, suppliedConditions)
+
+			mirror.sync(context.Background())
+
+			var updated corev1.Node
+			assert.NoError(t, kclient.Get(context.Background(), types.NamespacedName{Name: node.Name}, &updated))
+			assert.ElementsMatch(t, tt.expectedOffending, kubernetes.GetConditionIDs(kubernetes.GetNodeOffendingConditions(&updated, suppliedConditions)))
+			var conditionTypes []corev1.NodeConditionType
+			for _, c := range updated.Status.Conditions {
+				conditionTypes = append(conditionTypes, c.Type)
+			}
+			assert.ElementsMatch(t, tt.expectedTypes, conditionTypes)
+		})
+	}
+}
@@ -19,6 +19,7 @@ func main() {
 	}
 	root.PersistentFlags().AddFlagSet(fs)
 	root.AddCommand(TaintCmd(cfg))
+	root.AddCommand(PlanCmd(cfg))
 
 	if err := root.Execute(); err != nil {
 		fmt.Printf("root command exit with error: %#v", err)
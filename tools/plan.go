@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/DataDog/compute-go/kubeclient"
+	"github.com/DataDog/compute-go/table"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/zapr"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientgo "k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/planetlabs/draino/internal/candidate_runner"
+	"github.com/planetlabs/draino/internal/candidate_runner/sorters"
+	"github.com/planetlabs/draino/internal/cli"
+	drainplan "github.com/planetlabs/draino/internal/drain_plan"
+	"github.com/planetlabs/draino/internal/kubernetes"
+	"github.com/planetlabs/draino/internal/kubernetes/drain"
+	"github.com/planetlabs/draino/internal/kubernetes/index"
+	"github.com/planetlabs/draino/internal/limit"
+)
+
+type planCommandFlags struct {
+	planName           string
+	planNamespace      string
+	nodegroupName      string
+	nodegroupNamespace string
+	nodeSelector       string
+	conditions         []string
+	sortBy             string
+	preferredLabelKey  string
+	simulate           bool
+	syncTimeout        time.Duration
+	spacing            time.Duration
+	pollPeriod         time.Duration
+	stepTimeout        time.Duration
+	outputFormat       cli.OutputFormatType
+	tableOutputParams  table.OutputParameters
+}
+
+var planCmdFlags planCommandFlags
+
+func PlanCmd(kubecfg *kubeclient.Config) *cobra.Command {
+	planCmd := &cobra.Command{
+		Use:     "plan",
+		Aliases: []string{"plan", "plans"},
+		Args:    cobra.MinimumNArgs(1),
+		Run:     func(cmd *cobra.Command, args []string) {},
+	}
+	planCmd.PersistentFlags().VarP(&planCmdFlags.outputFormat, "output", "o", "output format (table|json)")
+	planCmd.PersistentFlags().StringVarP(&planCmdFlags.planName, "plan-name", "", "", "name of the plan")
+	planCmd.PersistentFlags().StringVarP(&planCmdFlags.planNamespace, "plan-namespace", "", "kube-system", "namespace of the configmap holding the plan")
+	cli.SetTableOutputParameters(&planCmdFlags.tableOutputParams, planCmd.PersistentFlags())
+
+	generateCmd := &cobra.Command{
+		Use:        "generate",
+		SuggestFor: []string{"generate"},
+		Args:       cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if planCmdFlags.planName == "" {
+				return fmt.Errorf("a plan-name must be set to generate a plan")
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			kclient, err := GetKubeClient(kubecfg)
+			if err != nil {
+				return err
+			}
+			nodes, err := listPlanNodes(ctx, kclient)
+			if err != nil {
+				return err
+			}
+			nodeSorters, err := buildPlanSorters()
+			if err != nil {
+				return err
+			}
+			var simulator drain.DrainSimulator
+			if planCmdFlags.simulate {
+				if simulator, err = newPlanSimulator(ctx, kubecfg); err != nil {
+					return err
+				}
+			}
+
+			plan := drainplan.Generate(ctx, nodes, drainplan.GenerateOptions{
+				Name:      planCmdFlags.planName,
+				Sorters:   nodeSorters,
+				Simulator: simulator,
+				Start:     time.Now(),
+				Spacing:   planCmdFlags.spacing,
+			})
+			if err := drainplan.Save(ctx, kclient, planCmdFlags.planNamespace, plan); err != nil {
+				return err
+			}
+			output, err := FormatPlanOutput(plan)
+			if err != nil {
+				return err
+			}
+			fmt.Println(output)
+			fmt.Printf("\nPlan saved in configmap %s/%s. Review it, then approve it with:\n  kubectl annotate configmap -n %s %s %s=true\n",
+				planCmdFlags.planNamespace, drainplan.ConfigMapName(plan.Name), planCmdFlags.planNamespace, drainplan.ConfigMapName(plan.Name), drainplan.ApprovedAnnotationKey)
+			return nil
+		},
+	}
+	generateCmd.Flags().StringVarP(&planCmdFlags.nodegroupName, "nodegroup-name", "", "", "name of the nodegroup")
+	generateCmd.Flags().StringVarP(&planCmdFlags.nodegroupNamespace, "nodegroup-namespace", "", "", "namespace of the nodegroup")
+	generateCmd.Flags().StringVarP(&planCmdFlags.nodeSelector, "node-selector", "", "", "label selector of the nodes to drain")
+	generateCmd.Flags().StringArrayVar(&planCmdFlags.conditions, "node-conditions", nil, "conditions ordering the nodes by priority, same format as the draino flag")
+	generateCmd.Flags().StringVar(&planCmdFlags.sortBy, "candidate-sort-by", "", "additional order of the nodes, 'newest' or 'preferred', same as the draino flag")
+	generateCmd.Flags().StringVar(&planCmdFlags.preferredLabelKey, "drain-preferred-label-key", sorters.DefaultDrainPreferredLabelKey, "label of the nodes drained first with --candidate-sort-by=preferred")
+	generateCmd.Flags().BoolVar(&planCmdFlags.simulate, "simulate", true, "simulate the drain of the nodes, the blocked nodes are skipped by the execution")
+	generateCmd.Flags().DurationVar(&planCmdFlags.syncTimeout, "informer-sync-timeout", 5*time.Minute, "maximum wait for the informers used by the simulation")
+	generateCmd.Flags().DurationVar(&planCmdFlags.spacing, "spacing", 10*time.Minute, "estimated time between the start of two consecutive drains")
+
+	showCmd := &cobra.Command{
+		Use:        "show",
+		SuggestFor: []string{"show"},
+		Args:       cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kclient, err := GetKubeClient(kubecfg)
+			if err != nil {
+				return err
+			}
+			plan, approved, err := drainplan.Load(context.Background(), kclient, planCmdFlags.planNamespace, planCmdFlags.planName)
+			if err != nil {
+				return err
+			}
+			output, err := FormatPlanOutput(plan)
+			if err != nil {
+				return err
+			}
+			fmt.Println(output)
+			fmt.Printf("\nApproved: %v\n", approved)
+			return nil
+		},
+	}
+
+	executeCmd := &cobra.Command{
+		Use:        "execute",
+		SuggestFor: []string{"execute"},
+		Args:       cobra.MaximumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kclient, err := GetKubeClient(kubecfg)
+			if err != nil {
+				return err
+			}
+			zlog, err := zap.NewDevelopment()
+			if err != nil {
+				return err
+			}
+			executor := &drainplan.Executor{
+				Client:      kclient,
+				Namespace:   planCmdFlags.planNamespace,
+				Logger:      zapr.NewLogger(zlog),
+				PollPeriod:  planCmdFlags.pollPeriod,
+				StepTimeout: planCmdFlags.stepTimeout,
+			}
+			if err := executor.Execute(context.Background(), planCmdFlags.planName); err != nil {
+				return err
+			}
+			plan, _, err := drainplan.Load(context.Background(), kclient, planCmdFlags.planNamespace, planCmdFlags.planName)
+			if err != nil {
+				return err
+			}
+			output, err := FormatPlanOutput(plan)
+			if err != nil {
+				return err
+			}
+			fmt.Println(output)
+			return nil
+		},
+	}
+	executeCmd.Flags().DurationVar(&planCmdFlags.pollPeriod, "poll-period", 30*time.Second, "period of the checks of the drain of the current node")
+	executeCmd.Flags().DurationVar(&planCmdFlags.stepTimeout, "step-timeout", time.Hour, "maximum time given to draino to drain a node")
+
+	planCmd.AddCommand(generateCmd, showCmd, executeCmd)
+	return planCmd
+}
+
+func listPlanNodes(ctx context.Context, kclient client.Client) ([]*v1.Node, error) {
+	selector, err := labels.Parse(planCmdFlags.nodeSelector)
+	if err != nil {
+		return nil, err
+	}
+	var listOfNodes v1.NodeList
+	if err := kclient.List(ctx, &listOfNodes, &client.ListOptions{LabelSelector: selector}); err != nil {
+		return nil, err
+	}
+	var result []*v1.Node
+	for i := range listOfNodes.Items {
+		node := &listOfNodes.Items[i]
+		ng, ngNs := NGValues(node)
+		if planCmdFlags.nodegroupNamespace != "" && planCmdFlags.nodegroupNamespace != ngNs {
+			continue
+		}
+		if planCmdFlags.nodegroupName != "" && planCmdFlags.nodegroupName != ng {
+			continue
+		}
+		result = append(result, node)
+	}
+	return result, nil
+}
+
+// buildPlanSorters uses the sorters of the candidate runner, the drain priority annotations excepted
+func buildPlanSorters() (candidate_runner.NodeSorters, error) {
+	conditions, err := kubernetes.ParseConditions(planCmdFlags.conditions)
+	if err != nil {
+		return nil, err
+	}
+	nodeSorters := candidate_runner.NodeSorters{sorters.NewConditionComparator(conditions)}
+	switch planCmdFlags.sortBy {
+	case "":
+	case "newest":
+		nodeSorters = append(nodeSorters, sorters.SortByCreationTimestampDesc)
+	case "preferred":
+		nodeSorters = append(nodeSorters, sorters.NewDrainPreferredComparator(planCmdFlags.preferredLabelKey))
+	default:
+		return nil, fmt.Errorf("unsupported candidate-sort-by '%s', must be 'newest' or 'preferred'", planCmdFlags.sortBy)
+	}
+	return nodeSorters, nil
+}
+
+// newPlanSimulator builds the drain simulator of draino on top of informers started for the duration of the command
+func newPlanSimulator(ctx context.Context, kubecfg *kubeclient.Config) (drain.DrainSimulator, error) {
+	cfgRest, err := kubeclient.NewKubeConfig(kubecfg)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build client config: %#v\n", err)
+	}
+	cs, err := clientgo.NewForConfig(cfgRest)
+	if err != nil {
+		return nil, err
+	}
+	kclient, err := client.New(cfgRest, client.Options{})
+	if err != nil {
+		return nil, err
+	}
+	crCache, err := cache.New(cfgRest, cache.Options{})
+	if err != nil {
+		return nil, err
+	}
+	indexer, err := index.New(ctx, kclient, crCache, logr.Discard())
+	if err != nil {
+		return nil, err
+	}
+	go crCache.Start(ctx)
+
+	nodes := kubernetes.NewNodeWatch(ctx, cs)
+	pods := kubernetes.NewPodWatch(ctx, cs)
+	statefulSets := kubernetes.NewStatefulsetWatch(ctx, cs)
+	deployments := kubernetes.NewDeploymentWatch(ctx, cs)
+	replicaSets := kubernetes.NewReplicaSetWatch(ctx, cs)
+	persistentVolumes := kubernetes.NewPersistentVolumeWatch(ctx, cs)
+	persistentVolumeClaims := kubernetes.NewPersistentVolumeClaimWatch(ctx, cs)
+	store := &kubernetes.RuntimeObjectStoreImpl{
+		DeploymentStore:            deployments,
+		ReplicaSetStore:            replicaSets,
+		StatefulSetsStore:          statefulSets,
+		PodsStore:                  pods,
+		PersistentVolumeStore:      persistentVolumes,
+		PersistentVolumeClaimStore: persistentVolumeClaims,
+		NodesStore:                 nodes,
+	}
+	go kubernetes.Await(ctx, nodes, pods, statefulSets, deployments, replicaSets, persistentVolumes, persistentVolumeClaims)
+
+	if !crCache.WaitForCacheSync(ctx) {
+		return nil, fmt.Errorf("cannot sync the cache")
+	}
+	if err := kubernetes.WaitForSync(ctx, store.HasSynced, time.Second, planCmdFlags.syncTimeout); err != nil {
+		return nil, err
+	}
+
+	// the pods of the daemonsets and the mirror pods are not evicted by draino
+	skipPodFilter := kubernetes.NewPodFilters(kubernetes.MirrorPodFilter, func(p v1.Pod) (bool, string, error) {
+		if ref := metav1.GetControllerOf(&p); ref != nil && ref.Kind == "DaemonSet" {
+			return false, "pod-daemonset", nil
+		}
+		return true, "", nil
+	})
+	rateLimiter := limit.NewRateLimiter(clock.RealClock{}, cfgRest.QPS, cfgRest.Burst)
+	return drain.NewDrainSimulator(ctx, kclient, indexer, skipPodFilter, kubernetes.NoopEventRecorder{}, rateLimiter, logr.Discard(), store, kubernetes.GlobalConfig{}), nil
+}
+
+func FormatPlanOutput(plan drainplan.Plan) (string, error) {
+	if planCmdFlags.outputFormat == cli.FormatJSON {
+		b, err := json.MarshalIndent(plan, "", " ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	columns := []string{
+		"Order", "Node", "Group", "EstimatedStart", "Status", "Reasons",
+	}
+	table := table.NewTable(columns,
+		func(obj interface{}) []string {
+			step := obj.(drainplan.Step)
+			estimatedStart := ""
+			if !step.EstimatedStart.IsZero() {
+				estimatedStart = step.EstimatedStart.Format(time.RFC3339)
+			}
+			reasons := step.Message
+			if step.Blocked {
+				reasons = fmt.Sprintf("%v", step.Reasons)
+			}
+			return []string{
+				fmt.Sprintf("%d", step.Order),
+				step.Node,
+				step.Group,
+				estimatedStart,
+				string(step.Status),
+				reasons,
+			}
+		})
+	for _, step := range plan.Steps {
+		table.Add(step)
+	}
+	planCmdFlags.tableOutputParams.Apply(table)
+	buf := bytes.NewBufferString("")
+	err := table.Display(buf)
+	return buf.String(), err
+}